@@ -0,0 +1,67 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMigrateUpEmptyDatabase runs every migration against a new, empty database on the server in
+// STREAMARR_TEST_DATABASE_URL (a user allowed to create databases), then once more to check nothing is left to apply
+func TestMigrateUpEmptyDatabase(t *testing.T) {
+	serverURL := os.Getenv("STREAMARR_TEST_DATABASE_URL")
+	if serverURL == "" {
+		t.Skip("STREAMARR_TEST_DATABASE_URL not set")
+	}
+	admin, err := sql.Open("postgres", serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	name := fmt.Sprintf("streamarr_migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer admin.Exec("DROP DATABASE IF EXISTS " + name)
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + name
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Migrations are read from migrations/ relative to the working directory
+	wd, _ := os.Getwd()
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := migrateUp(db); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied["013a_add_media_streams_movie_id"] || !applied["029_hash_stremio_tokens"] {
+		t.Errorf("applied migrations = %v", applied)
+	}
+	// The server refuses to start without its signing keys table
+	if _, err := db.Exec("SELECT id, secret, purpose FROM auth_signing_keys LIMIT 1"); err != nil {
+		t.Errorf("auth_signing_keys: %v", err)
+	}
+
+	if err := migrateUp(db); err != nil {
+		t.Fatalf("second migrate up: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/config"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/secrets"
+	"github.com/Zerr0-C00L/StreamArr/internal/settings"
+)
+
+func main() {
+	// Load .env file
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	log.Println("StreamArr Secrets Tool")
+
+	if len(os.Args) < 2 || os.Args[1] != "rotate" {
+		log.Fatal("Usage: secrets rotate [new-key]")
+	}
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Connect to database
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Test connection
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	if err := rotate(db, os.Args[2:]); err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+}
+
+// rotate re-encrypts the settings secrets, signing keys and TOTP secrets with a new master key. The previous key stays
+// accepted for decryption, so a server still running with it keeps working until restarted.
+func rotate(db *sql.DB, args []string) error {
+	current, source, err := secrets.Load()
+	if err != nil {
+		return err
+	}
+
+	newKey := ""
+	if len(args) > 0 {
+		newKey = args[0]
+	} else if newKey, err = secrets.GenerateKey(); err != nil {
+		return err
+	}
+	next, err := secrets.NewKeyring(newKey, current.Keys()[0])
+	if err != nil {
+		return err
+	}
+
+	// Every secret must decrypt before anything is rewritten
+	manager := settings.NewManager(db)
+	manager.SetKeyring(current)
+	if err := manager.Load(); err != nil {
+		return err
+	}
+
+	// Keep the new key before the database depends on it
+	fromEnv := source == "STREAMARR_SECRET_KEY"
+	if !fromEnv {
+		if err := secrets.WriteKeyFile(source, next.Keys()...); err != nil {
+			return err
+		}
+	}
+
+	authService := auth.NewService(database.NewAuthStore(db))
+	authService.SetKeyring(current)
+	if err := authService.Load(context.Background()); err != nil {
+		return err
+	}
+
+	if err := manager.Rekey(next); err != nil {
+		return err
+	}
+	if err := authService.Rekey(context.Background(), next); err != nil {
+		return err
+	}
+	log.Printf("Settings secrets, signing keys and TOTP secrets re-encrypted with key %s (previously %s)", next.KeyID(), current.KeyID())
+
+	if fromEnv {
+		fmt.Printf("\nSet this before restarting StreamArr:\n\nSTREAMARR_SECRET_KEY=%s\n\n", strings.Join(next.Keys(), ","))
+	} else {
+		log.Printf("New key written to %s; restart StreamArr to use it", source)
+	}
+	return nil
+}
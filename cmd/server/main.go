@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,19 +18,32 @@ import (
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/activity"
 	"github.com/Zerr0-C00L/StreamArr/internal/api"
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
 	"github.com/Zerr0-C00L/StreamArr/internal/cache"
 	"github.com/Zerr0-C00L/StreamArr/internal/config"
 	"github.com/Zerr0-C00L/StreamArr/internal/database"
 	"github.com/Zerr0-C00L/StreamArr/internal/epg"
+	"github.com/Zerr0-C00L/StreamArr/internal/hdhomerun"
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
 	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+	"github.com/Zerr0-C00L/StreamArr/internal/logging"
+	"github.com/Zerr0-C00L/StreamArr/internal/localmedia"
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
 	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/Zerr0-C00L/StreamArr/internal/netaccess"
+	"github.com/Zerr0-C00L/StreamArr/internal/notifications"
 	"github.com/Zerr0-C00L/StreamArr/internal/playlist"
 	"github.com/Zerr0-C00L/StreamArr/internal/providers"
+	"github.com/Zerr0-C00L/StreamArr/internal/secrets"
 	"github.com/Zerr0-C00L/StreamArr/internal/services"
 	"github.com/Zerr0-C00L/StreamArr/internal/services/debrid"
 	"github.com/Zerr0-C00L/StreamArr/internal/services/streams"
 	"github.com/Zerr0-C00L/StreamArr/internal/settings"
+	"github.com/Zerr0-C00L/StreamArr/internal/strmexport"
+	"github.com/Zerr0-C00L/StreamArr/internal/subtitles"
+	"github.com/Zerr0-C00L/StreamArr/internal/tracing"
 	"github.com/Zerr0-C00L/StreamArr/internal/xtream"
 )
 
@@ -75,11 +90,61 @@ func main() {
 
 	// Initialize settings manager and load from database
 	settingsManager := settings.NewManager(db)
+	keyring, keySource, err := secrets.Load()
+	if err != nil {
+		log.Fatalf("Failed to load the secrets master key: %v", err)
+	}
+	settingsManager.SetKeyring(keyring)
+	log.Printf("✓ Settings secrets encrypted with key %s from %s", keyring.KeyID(), keySource)
 	if err := settingsManager.Load(); err != nil {
 		log.Printf("Warning: Could not load settings: %v, using defaults", err)
 	}
 	log.Println("Settings manager initialized")
 
+	// Log lines go through the logging package from here on, filtered by the log levels and with secrets redacted
+	logging.Init(func() logging.Options {
+		s := settingsManager.Get()
+		level, _ := logging.ParseLevel(s.LogLevel)
+		if s.Debug {
+			level = slog.LevelDebug
+		}
+		return logging.Options{
+			Level:     level,
+			Levels:    logging.ParseLevels(s.LogLevels),
+			File:      s.LogFile,
+			MaxSizeMB: s.LogFileMaxSizeMB,
+			MaxFiles:  s.LogFileMaxFiles,
+			Secrets:   s.SecretValues(),
+		}
+	})
+
+	// Traces go to the OTLP collector from the tracing settings; exported until after jobs have stopped
+	tracing.Init(func() tracing.Options {
+		s := settingsManager.Get()
+		return tracing.Options{
+			Enabled:     s.TracingEnabled,
+			Endpoint:    s.TracingOTLPEndpoint,
+			Headers:     tracing.ParseHeaders(s.TracingOTLPHeaders),
+			ServiceName: s.TracingServiceName,
+			SampleRatio: s.TracingSampleRatio,
+		}
+	})
+	tracingCtx, tracingCancel := context.WithCancel(context.Background())
+	tracingDone := make(chan struct{})
+	go func() {
+		tracing.Run(tracingCtx)
+		close(tracingDone)
+	}()
+
+	// Access tokens and stream URLs are signed with keys kept in the database, encrypted like the
+	// settings secrets, so sessions and URLs survive restarts
+	authStore := database.NewAuthStore(db)
+	authService := auth.NewService(authStore)
+	authService.SetKeyring(keyring)
+	if err := authService.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load token signing keys: %v", err)
+	}
+
 	// Set up callback for when Balkan VOD is disabled - clean up all Balkan VOD content
 	settingsManager.SetOnBalkanVODDisabledCallback(func() error {
 		ctx := context.Background()
@@ -199,13 +264,14 @@ func main() {
 
 	log.Println("✓ All settings loaded from database")
 
-	// Initialize service scheduler
-	services.InitializeDefaultServices()
-	log.Println("Service scheduler initialized")
-
 	// Initialize service clients
 	tmdbClient := services.NewTMDBClient(cfg.TMDBAPIKey)
 	rdClient := services.NewRealDebridClient(cfg.RealDebridAPIKey)
+	rdClient.Torrents().SetStore(database.NewRDTorrentStore(db))
+	rdClient.Torrents().SetLimits(func() (int, time.Duration) {
+		s := settingsManager.Get()
+		return s.RDTorrentQuota, time.Duration(s.RDTorrentStaleDays) * 24 * time.Hour
+	})
 
 	// Initialize Live TV channel manager
 	channelManager := livetv.NewChannelManager()
@@ -315,17 +381,30 @@ func main() {
 		return phase1Streams
 	}
 
+	// Initialize cache manager
+	cacheManager := cache.NewManager(db)
+
+	// Activity feed: library changes, imports, stream upgrades, playback and the audit log
+	activityRecorder := activity.NewRecorder(database.NewActivityStore(db))
+	activityRecorder.SetPlaybackEnabled(func() bool {
+		return settingsManager.Get().ActivityRecordPlayback
+	})
+
+	// Resolved debrid/addon links are reused until they expire
+	linkCache := cache.NewLinkCache(cacheManager.GetRDURLCache())
+	rdClient.SetLinkCache(linkCache)
+
 	var debridService debrid.DebridService
 	var streamService *streams.StreamService
 	var streamChecker *streams.StreamChecker
 
 	if cfg.RealDebridAPIKey != "" {
 		// Initialize Real-Debrid service
-		debridService = debrid.NewRealDebrid(cfg.RealDebridAPIKey, slog.Default())
+		debridService = debrid.WithLinkCache(debrid.NewRealDebrid(cfg.RealDebridAPIKey, logging.Logger("debrid")), linkCache)
 		log.Println("✓ Real-Debrid service initialized for Phase 1 caching")
 
 		// Initialize stream service
-		streamService = streams.NewStreamService(debridService, slog.Default())
+		streamService = streams.NewStreamService(debridService, logging.Logger("streams"))
 		log.Println("✓ Stream service initialized with quality scoring")
 
 		// Note: streamChecker will be initialized after multiProvider is created
@@ -393,6 +472,86 @@ func main() {
 
 	// Create MultiProvider
 	multiProvider := providers.NewMultiProviderWithConfig(cfg.RealDebridAPIKey, stremioAddons, tmdbClient, proxies)
+
+	// Add Torznab indexers (Jackett/Prowlarr) as direct torrent providers
+	for _, indexer := range settingsManager.Get().TorznabIndexers {
+		if !indexer.Enabled || indexer.URL == "" {
+			continue
+		}
+		multiProvider.AddProvider(indexer.Name, providers.NewTorznabProvider(indexer.Name, indexer.URL, indexer.APIKey, debridService, tmdbClient))
+	}
+
+	// Local media library: files on disk rank above every remote provider
+	var localLibrary *localmedia.Library
+	if current := settingsManager.Get(); current.LocalMediaEnabled && len(current.LocalMediaPaths) > 0 {
+		localLibrary = localmedia.NewLibrary(
+			database.NewLocalMediaStore(db),
+			movieStore,
+			seriesStore,
+			tmdbClient,
+			func() []string { return settingsManager.Get().LocalMediaPaths },
+			authService.URLSecret(), // Persisted, so stream URLs stay valid across restarts
+		)
+		multiProvider.AddProvider(providers.LocalSource, providers.NewLocalMediaProvider(localLibrary))
+	}
+
+	// Library export: .strm/NFO folder tree for Jellyfin, Emby and Kodi
+	var strmExporter *strmexport.Exporter
+	if settingsManager.Get().StrmExportEnabled {
+		strmExporter = strmexport.NewExporter(movieStore, seriesStore, episodeStore, blacklistStore, func() strmexport.Options {
+			current := settingsManager.Get()
+			serverURL := current.StrmExportServerURL
+			if serverURL == "" {
+				host := current.UserSetHost
+				if host == "" {
+					host = "localhost"
+				}
+				serverURL = fmt.Sprintf("http://%s:%d", host, cfg.ServerPort)
+			}
+			return strmexport.Options{
+				Path:      current.StrmExportPath,
+				ServerURL: serverURL,
+				Username:  current.XtreamUsername,
+				Password:  current.XtreamPassword,
+				Artwork:   current.StrmExportArtwork,
+			}
+		})
+	}
+
+	// Subtitles: always created so enabling them in settings takes effect without a restart
+	subtitleService := subtitles.NewService(database.NewSubtitleStore(db), func() subtitles.Options {
+		current := settingsManager.Get()
+		languages := subtitles.ParseLanguages(current.SubtitleLanguages)
+		if len(languages) == 0 {
+			languages = []string{"en"}
+		}
+		cacheDir := current.SubtitleCachePath
+		if cacheDir == "" {
+			cacheDir = "cache/subtitles"
+		}
+		return subtitles.Options{
+			Enabled:   current.SubtitlesEnabled,
+			APIURL:    current.OpenSubtitlesURL,
+			APIKey:    current.OpenSubtitlesAPIKey,
+			Languages: languages,
+			CacheDir:  cacheDir,
+			CacheTTL:  time.Duration(current.SubtitleCacheHours) * time.Hour,
+		}
+	}, authService.URLSecret()) // Persisted, so subtitle URLs stay valid across restarts
+
+	// Usenet sources (NZB-based), merged into provider output with Source "usenet"
+	var usenetProviders []*providers.UsenetProvider
+	if current := settingsManager.Get(); current.EasynewsEnabled && current.EasynewsUsername != "" {
+		usenetProviders = append(usenetProviders, providers.NewUsenetProvider(
+			debrid.NewEasynews(current.EasynewsUsername, current.EasynewsPassword, logging.Logger("debrid")), tmdbClient, authService.URLSecret()))
+	}
+	if current := settingsManager.Get(); current.TorBoxEnabled && current.TorBoxAPIKey != "" {
+		usenetProviders = append(usenetProviders, providers.NewUsenetProvider(
+			debrid.NewTorBox(current.TorBoxAPIKey, logging.Logger("debrid")), tmdbClient, authService.URLSecret()))
+	}
+	for _, p := range usenetProviders {
+		multiProvider.AddProvider(p.Slug(), p)
+	}
 	log.Printf("✓ Stream providers enabled: %v", multiProvider.ProviderNames)
 
 	// Phase 1: Initialize stream checker with provider integration
@@ -423,7 +582,7 @@ func main() {
 			}
 
 			// Fetch streams from providers
-			providerStreams, err := multiProvider.GetMovieStreamsWithYear(imdbID, releaseYear)
+			providerStreams, err := multiProvider.GetMovieStreamsWithYear(ctx, imdbID, releaseYear)
 			if err != nil {
 				return nil, fmt.Errorf("provider fetch failed: %w", err)
 			}
@@ -446,9 +605,40 @@ func main() {
 			streamService,
 			debridService,
 			indexerSearchFunc,
-			slog.Default(),
+			logging.Logger("streams"),
 		)
 
+		// Episode streams are re-checked in the same batches as movies
+		streamChecker.SetEpisodeIndexerFunc(func(ctx context.Context, seriesID, season, episode int) ([]models.TorrentStream, error) {
+			series, err := seriesStore.Get(ctx, int64(seriesID))
+			if err != nil {
+				return nil, fmt.Errorf("series not found: %w", err)
+			}
+
+			// Prefer the imdb_id column, then metadata (external_ids first)
+			imdbID := series.IMDBID
+			if imdbID == "" && series.Metadata != nil {
+				if extIDs, ok := series.Metadata["external_ids"].(map[string]interface{}); ok {
+					imdbID, _ = extIDs["imdb_id"].(string)
+				}
+				if imdbID == "" {
+					imdbID, _ = series.Metadata["imdb_id"].(string)
+				}
+			}
+			if imdbID == "" {
+				return nil, fmt.Errorf("series has no IMDB ID")
+			}
+
+			providerStreams, err := multiProvider.GetSeriesStreams(ctx, imdbID, season, episode)
+			if err != nil {
+				return nil, fmt.Errorf("provider fetch failed: %w", err)
+			}
+
+			return convertProviderStreamsToPhase1(providerStreams), nil
+		})
+
+		streamChecker.SetActivity(activityRecorder)
+
 		// Wire up filter settings for stream checker
 		streamChecker.SetSettingsGetter(func() (string, string, string, bool) {
 			s := settingsManager.Get()
@@ -501,11 +691,30 @@ func main() {
 		},
 	)
 
+	if localLibrary != nil {
+		xtreamHandler.SetLocalMediaLibrary(localLibrary)
+	}
+	xtreamHandler.SetUsenetProviders(usenetProviders...)
+
 	// Initialize playlist generator
 	playlistGen := playlist.NewEnhancedGenerator(cfg, db, tmdbClient, multiProvider)
-
-	// Initialize cache manager
-	cacheManager := cache.NewManager(db)
+	playlistStore := playlist.NewArtifactStore(playlist.DefaultArtifactDir)
+	playlistGen.SetArtifactStore(playlistStore)
+	playlistGen.SetChannelManager(channelManager)
+	xtreamHandler.SetPlaylistStore(playlistStore)
+
+	xtreamHandler.SetLinkCache(linkCache)
+	xtreamHandler.SetSubtitleService(subtitleService)
+	xtreamHandler.SetActivity(activityRecorder)
+
+	// Season packs: play episodes from cached packs through the Real-Debrid torrent manager
+	if debridService != nil {
+		xtreamHandler.SetSeasonPackResolver(streams.NewSeasonPackResolver(rdClient, cacheManager.GetEpisodeCache(), logging.Logger("streams")))
+		log.Println("✓ Season pack resolver initialized")
+
+		// Episode streams are only served from the cache while the checker can re-validate them
+		xtreamHandler.SetStreamCacheStore(streamCacheStore)
+	}
 
 	// Initialize MDBList sync service
 	mdbSyncService := services.NewMDBListSyncService(db, cfg.MDBListAPIKey, cfg.TMDBAPIKey)
@@ -515,200 +724,20 @@ func main() {
 	workerCtx, workerCancel := context.WithCancel(context.Background())
 	_ = workerCancel // Used on shutdown
 
-	// ============ BACKGROUND WORKERS (integrated for shared GlobalScheduler) ============
-
-	// Worker: Playlist Regeneration (every 12 hours)
-	go func() {
-		interval := 12 * time.Hour
-		log.Printf("📋 Playlist Worker: Starting (interval: %v)", interval)
-
-		// Run immediately on startup
-		services.GlobalScheduler.MarkRunning(services.ServicePlaylist)
-		err := playlistGen.GenerateComplete(workerCtx)
-		services.GlobalScheduler.MarkComplete(services.ServicePlaylist, err, interval)
-		if err != nil {
-			log.Printf("❌ Playlist generation error: %v", err)
-		} else {
-			log.Println("✅ Initial playlist generation complete")
-		}
-
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-workerCtx.Done():
-				return
-			case <-ticker.C:
-				services.GlobalScheduler.MarkRunning(services.ServicePlaylist)
-				err := playlistGen.GenerateComplete(workerCtx)
-				services.GlobalScheduler.MarkComplete(services.ServicePlaylist, err, interval)
-			}
-		}
-	}()
-
-	// Worker: Cache Cleanup (every hour)
-	go func() {
-		interval := 1 * time.Hour
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-workerCtx.Done():
-				return
-			case <-ticker.C:
-				services.GlobalScheduler.MarkRunning(services.ServiceCacheCleanup)
-				cacheManager.Cleanup()
-				services.GlobalScheduler.MarkComplete(services.ServiceCacheCleanup, nil, interval)
-			}
-		}
-	}()
+	// ============ BACKGROUND WORKERS ============
+	// Scheduled services run from the shared job queue, started once the API handler is ready
 
-	// Worker: EPG Update (every 6 hours)
-	go func() {
-		interval := 6 * time.Hour
-		log.Printf("📺 EPG Update Worker: Starting (interval: %v)", interval)
-
-		// Run immediately
-		services.GlobalScheduler.MarkRunning(services.ServiceEPGUpdate)
-		channels := channelManager.GetAllChannels()
-		channelList := make([]livetv.Channel, len(channels))
-		for i, ch := range channels {
-			channelList[i] = *ch
-		}
-		err := epgManager.UpdateEPG(channelList)
-		services.GlobalScheduler.MarkComplete(services.ServiceEPGUpdate, err, interval)
-
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-workerCtx.Done():
-				return
-			case <-ticker.C:
-				services.GlobalScheduler.MarkRunning(services.ServiceEPGUpdate)
-				channels := channelManager.GetAllChannels()
-				channelList := make([]livetv.Channel, len(channels))
-				for i, ch := range channels {
-					channelList[i] = *ch
-				}
-				err := epgManager.UpdateEPG(channelList)
-				services.GlobalScheduler.MarkComplete(services.ServiceEPGUpdate, err, interval)
-			}
-		}
-	}()
-
-	// Worker: Channel Refresh (every hour)
-	go func() {
-		interval := 1 * time.Hour
-		log.Printf("📡 Channel Refresh Worker: Starting (interval: %v)", interval)
-
-		// Initial load already done above, just mark complete
-		services.GlobalScheduler.MarkComplete(services.ServiceChannelRefresh, nil, interval)
-
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-workerCtx.Done():
-				return
-			case <-ticker.C:
-				services.GlobalScheduler.MarkRunning(services.ServiceChannelRefresh)
-				err := channelManager.LoadChannels()
-				services.GlobalScheduler.MarkComplete(services.ServiceChannelRefresh, err, interval)
-			}
-		}
-	}()
-
-	// Worker: MDBList Sync (every 6 hours)
-	go func() {
-		interval := 6 * time.Hour
-		log.Printf("📋 MDBList Sync Worker: Starting (interval: %v)", interval)
-
-		// Run immediately
-		services.GlobalScheduler.MarkRunning(services.ServiceMDBListSync)
-		err := mdbSyncService.SyncAllLists(workerCtx)
-		services.GlobalScheduler.MarkComplete(services.ServiceMDBListSync, err, interval)
-		if err != nil {
-			log.Printf("❌ MDBList sync error: %v", err)
-		}
-
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-workerCtx.Done():
-				return
-			case <-ticker.C:
-				services.GlobalScheduler.MarkRunning(services.ServiceMDBListSync)
-				err := mdbSyncService.SyncAllLists(workerCtx)
-				services.GlobalScheduler.MarkComplete(services.ServiceMDBListSync, err, interval)
-			}
-		}
-	}()
-
-	// Worker: IPTV VOD Sync (configurable interval)
-	go func() {
-		for {
-			select {
-			case <-workerCtx.Done():
-				return
-			default:
-			}
-
-			current := settingsManager.Get()
-			mode := strings.ToLower(current.IPTVImportMode)
-			includesVOD := mode == "vod_only" || mode == "both"
-			intervalHours := current.IPTVVODSyncIntervalHours
-			if intervalHours <= 0 {
-				intervalHours = 6
-			}
-			interval := time.Duration(intervalHours) * time.Hour
-
-			if includesVOD && cfg.TMDBAPIKey != "" {
-				services.GlobalScheduler.MarkRunning(services.ServiceIPTVVODSync)
-				_, err := services.ImportIPTVVOD(workerCtx, current, tmdbClient, movieStore, seriesStore)
-				if err != nil {
-					log.Printf("[Scheduler] IPTV VOD import error: %v", err)
-				}
-				_ = services.CleanupIPTVVOD(workerCtx, current, movieStore, seriesStore)
-				services.GlobalScheduler.MarkComplete(services.ServiceIPTVVODSync, err, interval)
+	// Worker: watch local media folders for new files; full scans run as a scheduled job
+	if localLibrary != nil && settingsManager.Get().LocalMediaWatch {
+		go func() {
+			if err := localLibrary.Watch(workerCtx); err != nil {
+				log.Printf("❌ Local media watcher stopped: %v", err)
 			}
+		}()
+	}
 
-			time.Sleep(interval)
-		}
-	}()
-
-	// Worker: Balkan VOD Sync (every 24 hours)
-	go func() {
-		interval := 24 * time.Hour
-		log.Printf("🇧🇦 Balkan VOD Sync Worker: Starting (interval: %v)", interval)
-
-		// Run immediately
-		current := settingsManager.Get()
-		if current.BalkanVODEnabled && current.BalkanVODAutoSync {
-			services.GlobalScheduler.MarkRunning(services.ServiceBalkanVODSync)
-			importer := services.NewBalkanVODImporter(movieStore, seriesStore, tmdbClient, current)
-			err := importer.ImportBalkanVOD(workerCtx)
-			services.GlobalScheduler.MarkComplete(services.ServiceBalkanVODSync, err, interval)
-		}
-
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-workerCtx.Done():
-				return
-			case <-ticker.C:
-				current := settingsManager.Get()
-				if current.BalkanVODEnabled && current.BalkanVODAutoSync {
-					services.GlobalScheduler.MarkRunning(services.ServiceBalkanVODSync)
-					importer := services.NewBalkanVODImporter(movieStore, seriesStore, tmdbClient, current)
-					err := importer.ImportBalkanVOD(workerCtx)
-					services.GlobalScheduler.MarkComplete(services.ServiceBalkanVODSync, err, interval)
-				}
-			}
-		}
-	}()
+	// Worker: keep resolved links of recently played items fresh
+	go linkCache.Run(workerCtx)
 
 	// Worker: Phase 1 Stream Checker (every hour)
 	if streamChecker != nil {
@@ -775,6 +804,164 @@ func main() {
 		cacheScanner,
 	)
 
+	handler.SetLocalMediaLibrary(localLibrary)
+	handler.SetPlaylistGenerator(playlistGen)
+	handler.SetStrmExporter(strmExporter)
+	handler.SetStremioUserStore(database.NewStremioUserStore(db))
+	handler.SetActivity(activityRecorder)
+	handler.SetLinkCache(linkCache)
+	handler.SetSubtitleService(subtitleService)
+
+	handler.SetAuthService(authService)
+
+	// Gauges read from the database and the channel list when /metrics is scraped
+	metrics.NewGaugeFunc("streamarr_active_sessions", "Signed-in sessions that are neither revoked nor expired", func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		count, err := authStore.CountActiveSessions(ctx)
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(count)
+	})
+	metrics.NewGaugeVecFunc("streamarr_live_channels", "Loaded live TV channels by source", "source", func() map[string]float64 {
+		values := make(map[string]float64)
+		for source, count := range channelManager.CountBySource() {
+			if source == "" {
+				source = "unknown"
+			}
+			values[source] += float64(count)
+		}
+		return values
+	})
+
+	// Single sign-on through an OpenID Connect provider or an authenticating reverse proxy
+	sso := auth.NewSSO(authService, userStore, func() auth.SSOOptions {
+		s := settingsManager.Get()
+		return auth.SSOOptions{
+			OIDCEnabled:       s.OIDCEnabled,
+			ProviderName:      s.OIDCProviderName,
+			IssuerURL:         s.OIDCIssuerURL,
+			ClientID:          s.OIDCClientID,
+			ClientSecret:      s.OIDCClientSecret,
+			RedirectURL:       s.OIDCRedirectURL,
+			Scopes:            strings.Fields(s.OIDCScopes),
+			UsernameClaim:     s.OIDCUsernameClaim,
+			GroupsClaim:       s.OIDCGroupsClaim,
+			ProxyEnabled:      s.ProxyAuthEnabled,
+			ProxyUserHeader:   s.ProxyAuthHeader,
+			ProxyGroupsHeader: s.ProxyAuthGroupsHeader,
+			RoleMapping:       auth.ParseRoleMapping(s.SSORoleMapping),
+			DefaultRole:       s.SSODefaultRole,
+			AutoProvision:     s.SSOAutoProvision,
+			LinkVerifiedEmail: s.SSOLinkVerifiedEmail,
+		}
+	})
+	authService.SetSSO(sso)
+	handler.SetSSO(sso)
+
+	// Per-surface IP allow/deny lists; STREAMARR_IP_WHITELIST still restricts every surface when set
+	legacyAllow := auth.ParseNetworks(os.Getenv("STREAMARR_IP_WHITELIST"))
+	handler.SetNetworkPolicy(netaccess.NewPolicy(func() netaccess.Options {
+		s := settingsManager.Get()
+		rules := make(map[netaccess.Surface]netaccess.Rule, len(s.NetworkAccessRules))
+		for surface, rule := range s.NetworkAccessRules {
+			rules[netaccess.Surface(surface)] = netaccess.ParseRule(rule.Allow, rule.Deny, rule.AllowCountries, rule.DenyCountries)
+		}
+		return netaccess.Options{
+			Enabled:        s.NetworkAccessEnabled,
+			TrustedProxies: auth.ParseNetworks(s.NetworkTrustedProxies),
+			GeoIPDatabase:  s.NetworkGeoIPDatabase,
+			Rules:          rules,
+			LegacyAllow:    legacyAllow,
+		}
+	}))
+
+	// Alerts go to the Discord webhook / Telegram chat from the notification settings
+	notifier := notifications.NewNotifier(func() notifications.Options {
+		s := settingsManager.Get()
+		return notifications.Options{
+			Enabled:           s.EnableNotifications,
+			DiscordWebhookURL: s.DiscordWebhookURL,
+			TelegramBotToken:  s.TelegramBotToken,
+			TelegramChatID:    s.TelegramChatID,
+		}
+	})
+	handler.SetNotifier(notifier)
+
+	// Web sign-ins and Xtream credential checks share one throttle, so an IP locked out on one is locked out on both
+	loginGuard := auth.NewLoginGuard()
+	loginGuard.SetLockoutHandler(func(l auth.Lockout) {
+		who := "IP " + l.IP
+		if l.Account != "" {
+			who = "account " + l.Account
+		}
+		log.Printf("[AUTH] 🔒 Locked out %s for %v after %d failed sign-ins", who, l.Duration, l.Failures)
+		notifier.Notify("Sign-in lockout", fmt.Sprintf("Locked out %s for %v after %d failed sign-in attempts", who, l.Duration, l.Failures))
+	})
+	handler.SetLoginGuard(loginGuard)
+	xtreamHandler.SetLoginGuard(loginGuard, authService)
+	xtreamHandler.SetCredentials(func() (string, string) {
+		s := settingsManager.Get()
+		return s.XtreamUsername, s.XtreamPassword
+	})
+
+	// Background jobs come from a Postgres queue shared with cmd/worker, so no job runs twice
+	jobRunners := handler.JobRunners()
+	jobRunners[services.ServiceCacheCleanup] = func(ctx context.Context) error {
+		cacheManager.Cleanup()
+		return nil
+	}
+	jobRunners[services.ServiceAuthMaintenance] = authService.Maintain
+	jobQueue := jobs.NewQueue(database.NewJobStore(db), jobs.WorkerID("server"))
+	for _, job := range services.Jobs(settingsManager.Get(), jobRunners) {
+		jobQueue.Register(job)
+	}
+	handler.SetJobQueue(jobQueue)
+	if err := jobQueue.Start(workerCtx); err != nil {
+		log.Printf("❌ Job queue failed to start: %v", err)
+	} else {
+		log.Println("✓ Job queue started")
+		// EPG data lives in memory, so every start needs a fresh load
+		if _, err := jobQueue.Trigger(workerCtx, services.ServiceEPGUpdate); err != nil && !errors.Is(err, database.ErrJobBusy) {
+			log.Printf("⚠️ Cannot queue EPG update: %v", err)
+		}
+	}
+
+	// HDHomeRun tuner emulation for Plex/Jellyfin Live TV
+	if settingsManager.Get().HDHomeRunEnabled {
+		hdhrServer := hdhomerun.NewServer(channelManager, epgManager, func() hdhomerun.Config {
+			current := settingsManager.Get()
+			hdhrConfig := hdhomerun.Config{
+				EnabledSources:    current.LiveTVEnabledSources,
+				EnabledCategories: current.LiveTVEnabledCategories,
+				AdvertiseURL:      current.HDHomeRunAdvertiseURL,
+				Port:              cfg.ServerPort,
+			}
+			for _, t := range current.HDHomeRunTuners {
+				if t.Enabled && t.ID != "" {
+					hdhrConfig.Tuners = append(hdhrConfig.Tuners, hdhomerun.Tuner{
+						ID:         t.ID,
+						Name:       t.Name,
+						Categories: t.Categories,
+						TunerCount: t.TunerCount,
+					})
+				}
+			}
+			return hdhrConfig
+		})
+		handler.SetHDHomeRunServer(hdhrServer)
+		log.Println("✓ HDHomeRun tuners enabled at /hdhr/{id}/discover.json")
+
+		if settingsManager.Get().HDHomeRunSSDP {
+			go func() {
+				if err := hdhrServer.RunSSDP(workerCtx); err != nil {
+					log.Printf("❌ HDHomeRun SSDP discovery stopped: %v", err)
+				}
+			}()
+		}
+	}
+
 	// Create router and setup REST API routes
 	router := api.SetupRoutesWithXtream(handler, xtreamHandler)
 
@@ -833,5 +1020,12 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Let interrupted jobs record their result so another process can pick them up
+	jobQueue.Wait(ctx)
+
+	// Export the spans of the requests and jobs that just finished
+	tracingCancel()
+	<-tracingDone
+
 	log.Println("Server stopped")
 }
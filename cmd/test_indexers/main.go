@@ -1,12 +1,51 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/providers"
 )
 
+// torznabStandIn is a canned Torznab response covering magnet links, infohash attrs and a season pack
+const torznabStandIn = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+<channel>
+<title>Local Torznab</title>
+<item>
+	<title>Inception.2010.2160p.UHD.BluRay.REMUX.HDR.HEVC.TrueHD.Atmos.7.1-GROUP</title>
+	<size>64424509440</size>
+	<link>magnet:?xt=urn:btih:1111111111111111111111111111111111111111&amp;dn=Inception</link>
+	<torznab:attr name="seeders" value="120"/>
+</item>
+<item>
+	<title>Inception.2010.1080p.BluRay.x264.DTS-HD.MA.5.1-FGT</title>
+	<size>15032385536</size>
+	<torznab:attr name="infohash" value="2222222222222222222222222222222222222222"/>
+	<torznab:attr name="seeders" value="45"/>
+</item>
+<item>
+	<title>Show.Name.S01.1080p.WEB-DL.DDP5.1.H.264-NTb</title>
+	<size>32212254720</size>
+	<torznab:attr name="magneturl" value="magnet:?xt=urn:btih:3333333333333333333333333333333333333333"/>
+	<torznab:attr name="seeders" value="80"/>
+</item>
+<item>
+	<title>Show.Name.S01E02.720p.HDTV.x264-LOL</title>
+	<size>734003200</size>
+	<torznab:attr name="infohash" value="4444444444444444444444444444444444444444"/>
+</item>
+<item>
+	<title>No hash here</title>
+	<link>https://indexer.example/download/5</link>
+</item>
+</channel>
+</rss>`
+
 func testIndexer(indexer string, imdbID string) int {
 	// Construct Comet URL with single indexer
 	encodedIndexers := fmt.Sprintf(`["%s"]`, indexer)
@@ -26,7 +65,58 @@ func testIndexer(indexer string, imdbID string) int {
 	return resp.StatusCode
 }
 
+// testTorznab runs the Torznab provider against url, or against a local stand-in if url is empty
+func testTorznab(url, apiKey, imdbID string) {
+	if url == "" {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Printf("  stand-in request: %s\n", r.URL.RawQuery)
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, torznabStandIn)
+		}))
+		defer server.Close()
+		url = server.URL + "/api"
+		fmt.Printf("Using local Torznab stand-in at %s\n", url)
+	}
+
+	provider := providers.NewTorznabProvider("Torznab", url, apiKey, nil, nil)
+
+	movieStreams, err := provider.GetMovieStreams(imdbID)
+	if err != nil {
+		fmt.Printf("❌ Movie search failed: %v\n", err)
+	} else {
+		fmt.Printf("✅ Movie search: %d streams\n", len(movieStreams))
+		for _, s := range movieStreams {
+			fmt.Printf("  [%s] %s (%d MB, %d seeders)\n", s.Quality, s.Title, s.Size/(1024*1024), s.Seeders)
+		}
+	}
+
+	seriesStreams, err := provider.GetSeriesStreams(imdbID, 1, 2)
+	if err != nil {
+		fmt.Printf("❌ Series search failed: %v\n", err)
+	} else {
+		fmt.Printf("✅ Series search S01E02: %d streams\n", len(seriesStreams))
+		for _, s := range seriesStreams {
+			fmt.Printf("  [%s] %s\n", s.Quality, s.Title)
+		}
+	}
+}
+
 func main() {
+	torznabURL := flag.String("torznab-url", "", "Torznab endpoint to test (default: local stand-in)")
+	torznabKey := flag.String("torznab-key", "", "Torznab API key")
+	skipComet := flag.Bool("skip-comet", false, "Skip the Comet indexer checks")
+	flag.Parse()
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("Testing Torznab Provider")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	testTorznab(*torznabURL, *torznabKey, "tt1375666")
+	fmt.Println()
+
+	if *skipComet {
+		return
+	}
+
 	indexers := []string{"bitorrent", "therarbg", "yts", "eztv", "thepiratebay", "kickasstorrent", "galaxy", "magnetdl"}
 	imdbID := "tt1375666" // Inception
 
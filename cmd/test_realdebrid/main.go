@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/services"
+)
+
+const (
+	movieHash = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" // already in the account
+	packHash  = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb" // season pack, added on demand
+	badHash   = "cccccccccccccccccccccccccccccccccccccccc" // fails with magnet_error
+)
+
+type standInFile struct {
+	ID       int    `json:"id"`
+	Path     string `json:"path"`
+	Bytes    int64  `json:"bytes"`
+	Selected int    `json:"selected"`
+}
+
+type standInTorrent struct {
+	ID       string        `json:"id"`
+	Filename string        `json:"filename"`
+	Hash     string        `json:"hash"`
+	Bytes    int64         `json:"bytes"`
+	Status   string        `json:"status"`
+	Progress float64       `json:"progress"`
+	Files    []standInFile `json:"files"`
+	Links    []string      `json:"links"`
+	polls    int
+}
+
+// realDebridStandIn keeps an in-memory account and mimics the torrent endpoints StreamArr uses
+type realDebridStandIn struct {
+	mu       sync.Mutex
+	torrents map[string]*standInTorrent
+	nextID   int
+	added    int
+}
+
+func newRealDebridStandIn() *realDebridStandIn {
+	s := &realDebridStandIn{torrents: make(map[string]*standInTorrent), nextID: 1}
+	s.torrents["EXISTING1"] = &standInTorrent{
+		ID: "EXISTING1", Filename: "Inception.2010.1080p.BluRay.x264", Hash: movieHash, Bytes: 8589934592,
+		Status: "downloaded", Progress: 100,
+		Files: []standInFile{
+			{ID: 1, Path: "/Inception.2010.1080p.BluRay.x264/Sample.mkv", Bytes: 52428800, Selected: 0},
+			{ID: 2, Path: "/Inception.2010.1080p.BluRay.x264/Inception.2010.1080p.BluRay.x264.mkv", Bytes: 8589934592, Selected: 1},
+		},
+		Links: []string{"https://real-debrid.com/d/EXISTING1-2"},
+	}
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("USER%d", i)
+		s.torrents[id] = &standInTorrent{ID: id, Hash: strings.Repeat(fmt.Sprint(i), 40), Status: "downloaded", Progress: 100}
+	}
+	return s
+}
+
+func (s *realDebridStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("  stand-in request: %s %s\n", r.Method, r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer test-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	respond := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	r.ParseForm()
+
+	switch {
+	case r.URL.Path == "/user":
+		respond(map[string]interface{}{"username": "test", "type": "premium"})
+	case r.URL.Path == "/torrents":
+		if r.URL.Query().Get("page") != "1" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		list := []*standInTorrent{}
+		for _, t := range s.torrents {
+			list = append(list, t)
+		}
+		respond(list)
+	case r.URL.Path == "/torrents/addMagnet":
+		hash := strings.TrimPrefix(r.PostForm.Get("magnet"), "magnet:?xt=urn:btih:")
+		id := fmt.Sprintf("NEW%d", s.nextID)
+		s.nextID++
+		s.added++
+		t := &standInTorrent{ID: id, Hash: hash, Status: "magnet_conversion"}
+		if hash == packHash {
+			t.Filename = "Show.Name.S01.1080p.WEB-DL"
+			t.Files = []standInFile{
+				{ID: 1, Path: "/Show.Name.S01.1080p.WEB-DL/Show.Name.S01E01.1080p.WEB-DL.mkv", Bytes: 2147483648},
+				{ID: 2, Path: "/Show.Name.S01.1080p.WEB-DL/Show.Name.S01E02.1080p.WEB-DL.mkv", Bytes: 2147483648},
+				{ID: 3, Path: "/Show.Name.S01.1080p.WEB-DL/Show.Name.S01E03.1080p.WEB-DL.mkv", Bytes: 2147483648},
+				{ID: 4, Path: "/Show.Name.S01.1080p.WEB-DL/Show.Name.S01.nfo", Bytes: 1024},
+			}
+		}
+		s.torrents[id] = t
+		w.WriteHeader(http.StatusCreated)
+		respond(map[string]interface{}{"id": id, "uri": "https://api.real-debrid.com/rest/1.0/torrents/info/" + id})
+	case strings.HasPrefix(r.URL.Path, "/torrents/info/"):
+		t, ok := s.torrents[strings.TrimPrefix(r.URL.Path, "/torrents/info/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// Magnet conversion takes one poll
+		if t.Status == "magnet_conversion" {
+			if t.polls++; t.polls > 1 {
+				t.Status = "waiting_files_selection"
+				if t.Hash == badHash {
+					t.Status = "magnet_error"
+				}
+			}
+		}
+		respond(t)
+	case strings.HasPrefix(r.URL.Path, "/torrents/selectFiles/"):
+		t, ok := s.torrents[strings.TrimPrefix(r.URL.Path, "/torrents/selectFiles/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		selected := make(map[string]bool)
+		for _, id := range strings.Split(r.PostForm.Get("files"), ",") {
+			selected[id] = true
+		}
+		t.Links = nil
+		for i := range t.Files {
+			if selected[fmt.Sprint(t.Files[i].ID)] {
+				t.Files[i].Selected = 1
+				t.Bytes += t.Files[i].Bytes
+				t.Links = append(t.Links, fmt.Sprintf("https://real-debrid.com/d/%s-%d", t.ID, t.Files[i].ID))
+			}
+		}
+		t.Status, t.Progress = "downloaded", 100
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(r.URL.Path, "/torrents/delete/"):
+		delete(s.torrents, strings.TrimPrefix(r.URL.Path, "/torrents/delete/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/unrestrict/link":
+		link := r.PostForm.Get("link")
+		respond(map[string]interface{}{
+			"id":       "UNRESTRICTED",
+			"link":     link,
+			"download": strings.Replace(link, "real-debrid.com/d/", "download.real-debrid.com/stream/", 1) + ".mkv",
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *realDebridStandIn) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.torrents)
+}
+
+func main() {
+	apiKey := flag.String("key", "", "Real-Debrid API key (default: local stand-in)")
+	hash := flag.String("hash", "", "Infohash to stream from the real account")
+	season := flag.Int("season", 0, "Season of the episode to pick from -hash (0 = largest video file)")
+	episode := flag.Int("episode", 0, "Episode to pick from -hash")
+	databaseURL := flag.String("database-url", "", "PostgreSQL URL for torrent tracking and garbage collection (optional)")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var store *database.RDTorrentStore
+	if *databaseURL != "" {
+		db, err := sql.Open("postgres", *databaseURL)
+		if err != nil {
+			fmt.Printf("❌ Database: %v\n", err)
+			return
+		}
+		defer db.Close()
+		store = database.NewRDTorrentStore(db)
+	}
+
+	if *apiKey != "" {
+		client := services.NewRealDebridClient(*apiKey)
+		client.Torrents().SetStore(store)
+		if *hash == "" {
+			torrents, err := client.ListTorrents(ctx)
+			if err != nil {
+				fmt.Printf("❌ List failed: %v\n", err)
+				return
+			}
+			fmt.Printf("✅ Account has %d torrents\n", len(torrents))
+			return
+		}
+		streamURL, err := client.GetEpisodeStreamURL(ctx, *hash, *season, *episode)
+		if err != nil {
+			fmt.Printf("❌ Stream failed: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ %s -> %s\n", *hash, streamURL)
+		return
+	}
+
+	standIn := newRealDebridStandIn()
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+	fmt.Printf("Using local Real-Debrid stand-in at %s\n", server.URL)
+
+	client := services.NewRealDebridClient("test-key")
+	client.SetBaseURL(server.URL)
+	manager := client.Torrents()
+	manager.SetStore(store)
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("Reusing a torrent already in the account")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	streamURL, err := client.GetStreamURL(ctx, strings.ToUpper(movieHash))
+	if err != nil {
+		fmt.Printf("❌ Movie failed: %v\n", err)
+	} else {
+		fmt.Printf("✅ Movie -> %s (torrents added: %d)\n", streamURL, standIn.added)
+	}
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("Picking an episode from a season pack")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	for _, ep := range []int{2, 2, 3} {
+		streamURL, err := client.GetEpisodeStreamURL(ctx, packHash, 1, ep)
+		if err != nil {
+			fmt.Printf("❌ S01E%02d failed: %v\n", ep, err)
+			continue
+		}
+		fmt.Printf("✅ S01E%02d -> %s (torrents added: %d)\n", ep, streamURL, standIn.added)
+	}
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("Failing torrent is removed again")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	before := standIn.count()
+	if _, err := client.GetStreamURL(ctx, badHash); err != nil {
+		fmt.Printf("✅ Failed as expected: %v (torrents before: %d, after: %d)\n", err, before, standIn.count())
+	} else {
+		fmt.Println("❌ Expected a failure")
+	}
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("Garbage collection")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	if store == nil {
+		fmt.Println("Skipped: pass -database-url to track torrents")
+		return
+	}
+	manager.SetLimits(func() (int, time.Duration) { return 4, 0 })
+	deleted, err := manager.CollectGarbage(ctx)
+	if err != nil {
+		fmt.Printf("❌ Garbage collection failed: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Deleted %d tracked torrents, %d left in account (quota 4, untracked torrents kept)\n", deleted, standIn.count())
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/providers"
+	"github.com/Zerr0-C00L/StreamArr/internal/services/debrid"
+	"github.com/Zerr0-C00L/StreamArr/internal/services/streams"
+)
+
+// easynewsStandIn mimics the solr-search response, including a passworded and a non-video result
+const easynewsStandIn = `{
+	"data": [
+		{"0": "aaa111", "10": "Inception.2010.2160p.UHD.BluRay.REMUX.HDR.HEVC.TrueHD.Atmos.7.1-GROUP", "11": ".mkv", "rawSize": 64424509440, "5": "2024-01-10 12:00:00", "type": "VIDEO", "passwd": false, "virus": false},
+		{"0": "bbb222", "10": "Inception.2010.1080p.WEB-DL.DDP5.1.H.264-NTb", "11": "mkv", "rawSize": 7516192768, "type": "VIDEO", "passwd": false, "virus": false},
+		{"0": "ccc333", "10": "Inception.2010.1080p.BluRay.x264-PW", "11": ".mkv", "rawSize": 1000, "type": "VIDEO", "passwd": true, "virus": false},
+		{"0": "ddd444", "10": "Inception.2010.Soundtrack", "11": ".flac", "rawSize": 1000, "type": "AUDIO", "passwd": false, "virus": false}
+	],
+	"dlFarm": "auto",
+	"dlPort": 443
+}`
+
+// newTorBoxStandIn serves search, cache check, download creation and link requests
+func newTorBoxStandIn() *httptest.Server {
+	respond := func(w http.ResponseWriter, data interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": data})
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("  stand-in request: %s %s\n", r.Method, r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer test-key" && r.URL.Query().Get("token") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "detail": "bad token"})
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/usenet/imdb:"):
+			nzbs := []map[string]interface{}{
+				{"hash": "E1E1", "raw_title": "Show.Name.S01E02.1080p.WEB-DL.DDP5.1.H.264-NTb", "size": 2147483648, "nzb": "http://nzb/1", "cached": true, "age": "3d"},
+				{"hash": "E2E2", "raw_title": "Show.Name.S01.2160p.WEB-DL.DV.HEVC-GROUP", "size": 53687091200, "nzb": "http://nzb/2", "cached": false, "age": "30d"},
+				{"hash": "E3E3", "raw_title": "Show.Name.S01E03.720p.HDTV.x264-LOL", "size": 734003200, "nzb": "http://nzb/3", "cached": true},
+			}
+			respond(w, map[string]interface{}{"nzbs": nzbs})
+		case r.URL.Path == "/v1/api/usenet/checkcached":
+			respond(w, map[string]interface{}{"e2e2": map[string]interface{}{"name": "pack"}})
+		case r.URL.Path == "/v1/api/usenet/createusenetdownload":
+			respond(w, map[string]interface{}{"usenetdownload_id": 42})
+		case r.URL.Path == "/v1/api/usenet/mylist":
+			respond(w, map[string]interface{}{
+				"id": 42, "download_present": true,
+				"files": []map[string]interface{}{
+					{"id": 0, "name": "Show.Name.S01E02.nfo", "size": 100},
+					{"id": 1, "name": "Show.Name.S01E02.1080p.mkv", "size": 2147000000, "mimetype": "video/x-matroska"},
+				},
+			})
+		case r.URL.Path == "/v1/api/usenet/requestdl":
+			respond(w, "https://cdn.torbox.example/dl/42/1")
+		case r.URL.Path == "/v1/api/user/me":
+			respond(w, map[string]interface{}{"email": "test@example.com"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func testEasynews(username, password string) {
+	service := debrid.NewEasynews(username, password, nil)
+	if username == "" {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Printf("  stand-in request: %s?gps=%s\n", r.URL.Path, r.URL.Query().Get("gps"))
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, easynewsStandIn)
+		}))
+		defer server.Close()
+		service = debrid.NewEasynews("user", "pass", nil)
+		service.SetBaseURL(server.URL)
+		fmt.Printf("Using local Easynews stand-in at %s\n", server.URL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fmt.Printf("Authenticated: %v\n", service.IsAuthenticated(ctx))
+
+	results, err := service.Search(ctx, debrid.UsenetQuery{IMDBID: "tt1375666", Title: "Inception", Year: 2010})
+	if err != nil {
+		fmt.Printf("❌ Search failed: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Search: %d results\n", len(results))
+	for _, r := range results {
+		streamURL, _ := service.GetStreamURL(ctx, r)
+		fmt.Printf("  %s (%d MB, available=%v)\n    -> %s\n", r.Title, r.Size/(1024*1024), r.Available, streamURL)
+	}
+}
+
+func testTorBox(apiKey string) {
+	service := debrid.NewTorBox(apiKey, nil)
+	if apiKey == "" {
+		server := newTorBoxStandIn()
+		defer server.Close()
+		service = debrid.NewTorBox("test-key", nil)
+		service.SetBaseURLs(server.URL+"/v1/api", server.URL)
+		fmt.Printf("Using local TorBox stand-in at %s\n", server.URL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	fmt.Printf("Authenticated: %v\n", service.IsAuthenticated(ctx))
+
+	provider := providers.NewUsenetProvider(service, nil, "test-secret")
+	episodeStreams, err := provider.GetSeriesStreams("tt0903747", 1, 2)
+	if err != nil {
+		fmt.Printf("❌ Series search failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✅ Series search S01E02: %d streams\n", len(episodeStreams))
+	for _, s := range episodeStreams {
+		quality := streams.ParseQualityFromTorrentName(s.Title)
+		quality.SizeGB = float64(s.Size) / (1024 * 1024 * 1024)
+		quality.Usenet = s.Source == streams.UsenetIndexer
+		score := streams.CalculateScore(quality)
+		fmt.Printf("  [%s] %s cached=%v score=%d url=%s\n", s.Quality, s.Title, s.Cached, score.TotalScore, s.URL)
+	}
+
+	if len(episodeStreams) > 0 {
+		token := strings.TrimSuffix(episodeStreams[0].URL[strings.LastIndex(episodeStreams[0].URL, "/")+1:], ".mkv")
+		streamURL, err := provider.Resolve(ctx, token)
+		if err != nil {
+			fmt.Printf("❌ Resolve failed: %v\n", err)
+		} else {
+			fmt.Printf("✅ Resolved %s -> %s\n", episodeStreams[0].Title, streamURL)
+		}
+	}
+}
+
+func main() {
+	easynewsUser := flag.String("easynews-user", "", "Easynews username (default: local stand-in)")
+	easynewsPass := flag.String("easynews-pass", "", "Easynews password")
+	torboxKey := flag.String("torbox-key", "", "TorBox API key (default: local stand-in)")
+	flag.Parse()
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("Testing Easynews")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	testEasynews(*easynewsUser, *easynewsPass)
+	fmt.Println()
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("Testing TorBox Usenet")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	testTorBox(*torboxKey)
+}
@@ -2,8 +2,8 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,13 +14,15 @@ import (
 	"github.com/Zerr0-C00L/StreamArr/internal/cache"
 	"github.com/Zerr0-C00L/StreamArr/internal/config"
 	"github.com/Zerr0-C00L/StreamArr/internal/database"
-	"github.com/Zerr0-C00L/StreamArr/internal/epg"
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
 	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
-	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/Zerr0-C00L/StreamArr/internal/logging"
 	"github.com/Zerr0-C00L/StreamArr/internal/playlist"
 	"github.com/Zerr0-C00L/StreamArr/internal/providers"
+	"github.com/Zerr0-C00L/StreamArr/internal/secrets"
 	"github.com/Zerr0-C00L/StreamArr/internal/services"
 	"github.com/Zerr0-C00L/StreamArr/internal/settings"
+	"github.com/Zerr0-C00L/StreamArr/internal/tracing"
 )
 
 func main() {
@@ -39,10 +41,48 @@ func main() {
 
 	// Initialize settings manager and load from database
 	settingsManager := settings.NewManager(db)
+	keyring, keySource, err := secrets.Load()
+	if err != nil {
+		log.Fatalf("Failed to load the secrets master key: %v", err)
+	}
+	settingsManager.SetKeyring(keyring)
+	log.Printf("✓ Settings secrets encrypted with key %s from %s", keyring.KeyID(), keySource)
 	if err := settingsManager.Load(); err != nil {
 		log.Printf("Warning: Could not load settings: %v, using defaults", err)
 	}
 
+	// The server writes the log file; the worker only logs to the console
+	logging.Init(func() logging.Options {
+		s := settingsManager.Get()
+		level, _ := logging.ParseLevel(s.LogLevel)
+		if s.Debug {
+			level = slog.LevelDebug
+		}
+		return logging.Options{
+			Level:   level,
+			Levels:  logging.ParseLevels(s.LogLevels),
+			Secrets: s.SecretValues(),
+		}
+	})
+
+	// Job runs are traced like the server's, to the OTLP collector from the tracing settings
+	tracing.Init(func() tracing.Options {
+		s := settingsManager.Get()
+		return tracing.Options{
+			Enabled:     s.TracingEnabled,
+			Endpoint:    s.TracingOTLPEndpoint,
+			Headers:     tracing.ParseHeaders(s.TracingOTLPHeaders),
+			ServiceName: s.TracingServiceName + "-worker",
+			SampleRatio: s.TracingSampleRatio,
+		}
+	})
+	tracingCtx, tracingCancel := context.WithCancel(context.Background())
+	tracingDone := make(chan struct{})
+	go func() {
+		tracing.Run(tracingCtx)
+		close(tracingDone)
+	}()
+
 	// Override config with ALL settings from database
 	appSettings := settingsManager.Get()
 
@@ -119,54 +159,59 @@ func main() {
 	// Initialize cache manager
 	cacheManager := cache.NewManager(db)
 
-	// Initialize playlist generator
-	playlistGen := playlist.NewEnhancedGenerator(cfg, db, tmdbClient, multiProvider)
-
 	// Initialize channel manager
 	channelManager := livetv.NewChannelManager()
 
-	// Initialize EPG manager
-	epgManager := epg.NewEPGManager()
+	// Initialize playlist generator; the server serves what it writes from get.php
+	playlistGen := playlist.NewEnhancedGenerator(cfg, db, tmdbClient, multiProvider)
+	playlistGen.SetArtifactStore(playlist.NewArtifactStore(playlist.DefaultArtifactDir))
+	playlistGen.SetChannelManager(channelManager)
 
 	// Initialize MDBList sync service
 	mdbSyncService := services.NewMDBListSyncService(db, cfg.MDBListAPIKey, cfg.TMDBAPIKey)
 
-	// Initialize stores for collection worker
+	// Initialize stores
 	movieStore := database.NewMovieStore(db)
 	seriesStore := database.NewSeriesStore(db)
 	episodeStore := database.NewEpisodeStore(db)
-	collectionStore := database.NewCollectionStore(db)
 
 	// Create context for workers
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start workers
-	log.Println("Starting workers...")
-
-	// Worker 1: Playlist Regeneration (every 12 hours)
-	go playlistWorker(ctx, playlistGen, 12*time.Hour)
-
-	// Worker 2: Cache Cleanup (every hour)
-	go cacheCleanupWorker(ctx, cacheManager, 1*time.Hour)
-
-	// Worker 3: EPG Update (every 6 hours)
-	go epgUpdateWorker(ctx, epgManager, channelManager, 6*time.Hour)
-
-	// Worker 4: Channel Refresh (every hour)
-	go channelRefreshWorker(ctx, channelManager, 1*time.Hour)
-
-	// Worker 5: MDBList Sync (every 6 hours)
-	go mdblistSyncWorker(ctx, mdbSyncService, db, 6*time.Hour)
-
-	// Worker 6: Collection Sync (every 24 hours)
-	go collectionSyncWorker(ctx, collectionStore, movieStore, tmdbClient, settingsManager, 24*time.Hour)
-
-	// Worker 7: Episode Scan (every 24 hours)
-	go episodeScanWorker(ctx, seriesStore, episodeStore, tmdbClient, 24*time.Hour)
-
-	// Worker 8: Balkan VOD Sync (every 24 hours)
-	go balkanVODSyncWorker(ctx, movieStore, seriesStore, tmdbClient, settingsManager, 24*time.Hour)
+	// Jobs come from the Postgres queue shared with the server, so no job runs twice.
+	// Live TV jobs keep their state in the server's memory and run there only.
+	runners := map[string]jobs.RunFunc{
+		services.ServicePlaylist: func(ctx context.Context) error {
+			// The live section needs the current channel list
+			if err := channelManager.LoadChannels(); err != nil {
+				jobs.Logf(ctx, "⚠️ Channel load error: %v", err)
+			}
+			return playlistGen.GenerateComplete(ctx)
+		},
+		services.ServiceCacheCleanup: func(ctx context.Context) error {
+			cacheManager.Cleanup()
+			return nil
+		},
+		services.ServiceMDBListSync: func(ctx context.Context) error {
+			return runMDBListSync(ctx, mdbSyncService)
+		},
+		services.ServiceEpisodeScan: func(ctx context.Context) error {
+			return services.ScanEpisodes(ctx, seriesStore, episodeStore, tmdbClient)
+		},
+		services.ServiceBalkanVODSync: func(ctx context.Context) error {
+			return runBalkanVODSync(ctx, movieStore, seriesStore, tmdbClient, settingsManager)
+		},
+	}
+
+	queue := jobs.NewQueue(database.NewJobStore(db), jobs.WorkerID("worker"))
+	for _, job := range services.Jobs(settingsManager.Get(), runners) {
+		queue.Register(job)
+		log.Printf("✓ Job: %s (%s)", job.Name, job.Schedule)
+	}
+	if err := queue.Start(ctx); err != nil {
+		log.Fatalf("Failed to start job queue: %v", err)
+	}
 
 	log.Println("✅ All workers started successfully")
 	log.Println("========================================")
@@ -178,394 +223,45 @@ func main() {
 
 	log.Println("\n🛑 Shutting down workers...")
 	cancel()
-	time.Sleep(2 * time.Second)
-	log.Println("✅ Shutdown complete")
-}
-
-func playlistWorker(ctx context.Context, gen *playlist.EnhancedGenerator, interval time.Duration) {
-	log.Printf("📋 Playlist Worker: Starting (interval: %v)", interval)
-
-	// Run immediately on startup
-	if err := gen.GenerateComplete(ctx); err != nil {
-		log.Printf("❌ Playlist generation error: %v", err)
-	} else {
-		log.Println("✅ Initial playlist generation complete")
-	}
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("📋 Playlist Worker: Stopping")
-			return
-		case <-ticker.C:
-			log.Println("📋 Playlist Worker: Starting playlist regeneration...")
-			if err := gen.GenerateComplete(ctx); err != nil {
-				log.Printf("❌ Playlist generation error: %v", err)
-			} else {
-				log.Println("✅ Playlist regeneration complete")
-			}
-		}
-	}
-}
-
-func cacheCleanupWorker(ctx context.Context, manager *cache.Manager, interval time.Duration) {
-	log.Printf("🧹 Cache Cleanup Worker: Starting (interval: %v)", interval)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("🧹 Cache Cleanup Worker: Stopping")
-			return
-		case <-ticker.C:
-			log.Println("🧹 Cache Cleanup Worker: Running cleanup...")
-			manager.Cleanup()
-			log.Println("✅ Cache cleanup complete")
-		}
-	}
-}
 
-func epgUpdateWorker(ctx context.Context, epgManager *epg.Manager, channelManager *livetv.ChannelManager, interval time.Duration) {
-	log.Printf("📺 EPG Update Worker: Starting (interval: %v)", interval)
-
-	// Run immediately on startup
-	updateEPG(epgManager, channelManager)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("📺 EPG Update Worker: Stopping")
-			return
-		case <-ticker.C:
-			updateEPG(epgManager, channelManager)
-		}
-	}
-}
-
-func updateEPG(epgManager *epg.Manager, channelManager *livetv.ChannelManager) {
-	log.Println("📺 EPG Update Worker: Updating EPG data...")
-	channels := channelManager.GetAllChannels()
-	channelList := make([]livetv.Channel, len(channels))
-	for i, ch := range channels {
-		channelList[i] = *ch
-	}
-
-	if err := epgManager.UpdateEPG(channelList); err != nil {
-		log.Printf("❌ EPG update error: %v", err)
-	} else {
-		log.Println("✅ EPG update complete")
-	}
-}
-
-func channelRefreshWorker(ctx context.Context, manager *livetv.ChannelManager, interval time.Duration) {
-	log.Printf("📡 Channel Refresh Worker: Starting (interval: %v)", interval)
-
-	// Run immediately on startup
-	if err := manager.LoadChannels(); err != nil {
-		log.Printf("❌ Initial channel load error: %v", err)
-	} else {
-		log.Println("✅ Initial channel load complete")
-	}
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("📡 Channel Refresh Worker: Stopping")
-			return
-		case <-ticker.C:
-			log.Println("📡 Channel Refresh Worker: Refreshing channels...")
-			if err := manager.LoadChannels(); err != nil {
-				log.Printf("❌ Channel refresh error: %v", err)
-			} else {
-				log.Println("✅ Channel refresh complete")
-			}
-		}
-	}
+	// Let interrupted jobs record their result so another process can pick them up
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer waitCancel()
+	queue.Wait(waitCtx)
+	tracingCancel()
+	<-tracingDone
+	log.Println("✅ Shutdown complete")
 }
 
-func mdblistSyncWorker(ctx context.Context, syncService *services.MDBListSyncService, db *sql.DB, interval time.Duration) {
-	log.Printf("📋 MDBList Sync Worker: Starting (interval: %v)", interval)
-
-	// Run immediately on startup
-	log.Println("📋 MDBList Sync Worker: Running initial sync...")
+func runMDBListSync(ctx context.Context, syncService *services.MDBListSyncService) error {
 	if err := syncService.SyncAllLists(ctx); err != nil {
-		log.Printf("❌ Initial MDBList sync error: %v", err)
-	} else {
-		movies, series, _ := syncService.GetSyncStats(ctx)
-		log.Printf("✅ Initial MDBList sync complete - Library: %d movies, %d series", movies, series)
+		return err
 	}
+	movies, series, _ := syncService.GetSyncStats(ctx)
+	jobs.Logf(ctx, "✅ MDBList sync complete - Library: %d movies, %d series", movies, series)
 
-	// Enrich any existing items missing artwork
-	log.Println("📋 MDBList Sync Worker: Enriching items with TMDB artwork...")
+	// Enrich any new items missing artwork
 	if err := syncService.EnrichExistingItems(ctx); err != nil {
-		log.Printf("⚠️ MDBList enrichment error: %v", err)
-	}
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("📋 MDBList Sync Worker: Stopping")
-			return
-		case <-ticker.C:
-			log.Println("📋 MDBList Sync Worker: Syncing MDBList lists...")
-			if err := syncService.SyncAllLists(ctx); err != nil {
-				log.Printf("❌ MDBList sync error: %v", err)
-			} else {
-				movies, series, _ := syncService.GetSyncStats(ctx)
-				log.Printf("✅ MDBList sync complete - Library: %d movies, %d series", movies, series)
-			}
-			// Enrich any new items missing artwork
-			if err := syncService.EnrichExistingItems(ctx); err != nil {
-				log.Printf("⚠️ MDBList enrichment error: %v", err)
-			}
-		}
-	}
-}
-
-func collectionSyncWorker(ctx context.Context, collectionStore *database.CollectionStore, movieStore *database.MovieStore, tmdbClient *services.TMDBClient, settingsManager *settings.Manager, interval time.Duration) {
-	log.Printf("📦 Collection Sync Worker: Starting (interval: %v)", interval)
-
-	// Run immediately on startup
-	runCollectionSync(ctx, collectionStore, movieStore, tmdbClient, settingsManager)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("📦 Collection Sync Worker: Stopping")
-			return
-		case <-ticker.C:
-			runCollectionSync(ctx, collectionStore, movieStore, tmdbClient, settingsManager)
-		}
-	}
-}
-
-func runCollectionSync(ctx context.Context, collectionStore *database.CollectionStore, movieStore *database.MovieStore, tmdbClient *services.TMDBClient, settingsManager *settings.Manager) {
-	log.Println("📦 Collection Sync Worker: Phase 1 - Scanning movies for collections...")
-
-	// Phase 1: Scan and link movies to collections
-	movies, err := movieStore.ListUncheckedForCollection(ctx)
-	if err != nil {
-		log.Printf("❌ Collection Sync Phase 1 error: %v", err)
-		return
-	}
-
-	totalMovies := len(movies)
-	if totalMovies == 0 {
-		log.Println("✅ Collection Sync Phase 1: All movies already checked")
-	} else {
-		log.Printf("📦 Scanning %d unchecked movies...\n", totalMovies)
-		linked := 0
-
-		for i, movie := range movies {
-			if i%10 == 0 {
-				log.Printf("📦 Progress: %d/%d movies scanned\n", i, totalMovies)
-			}
-
-			_, collection, err := tmdbClient.GetMovieWithCollection(ctx, movie.TMDBID)
-			if err != nil {
-				movieStore.MarkCollectionChecked(ctx, movie.ID)
-				continue
-			}
-
-			if collection != nil {
-				fullCollection, _, err := tmdbClient.GetCollection(ctx, collection.TMDBID)
-				if err != nil {
-					movieStore.MarkCollectionChecked(ctx, movie.ID)
-					continue
-				}
-
-				if err := collectionStore.Create(ctx, fullCollection); err != nil {
-					movieStore.MarkCollectionChecked(ctx, movie.ID)
-					continue
-				}
-
-				if err := collectionStore.UpdateMovieCollection(ctx, movie.ID, fullCollection.ID); err != nil {
-					movieStore.MarkCollectionChecked(ctx, movie.ID)
-					continue
-				}
-
-				linked++
-			}
-
-			movieStore.MarkCollectionChecked(ctx, movie.ID)
-		}
-
-		log.Printf("✅ Collection Sync Phase 1 complete: %d movies linked to collections\n", linked)
-	}
-
-	// Phase 2: Sync incomplete collections if auto-add is enabled
-	settings := settingsManager.Get()
-	if settings.AutoAddCollections {
-		log.Println("📦 Collection Sync Phase 2: Adding missing movies from incomplete collections...")
-
-		collections, _, _ := collectionStore.GetCollectionsWithProgress(ctx, 1000, 0)
-		var incompleteColls []*models.Collection
-		for _, coll := range collections {
-			if coll.MoviesInLibrary < coll.TotalMovies {
-				incompleteColls = append(incompleteColls, coll)
-			}
-		}
-
-		if len(incompleteColls) == 0 {
-			log.Println("✅ Collection Sync Phase 2: All collections complete!")
-		} else {
-			log.Printf("📦 Found %d incomplete collections - skipping auto-add (requires stream search)\n", len(incompleteColls))
-			log.Println("ℹ️  Use 'Add Collection' button in UI to manually add missing movies")
-		}
-	} else {
-		log.Println("📦 Collection Sync Phase 2 skipped: AutoAddCollections is disabled")
-	}
-}
-func episodeScanWorker(ctx context.Context, seriesStore *database.SeriesStore, episodeStore *database.EpisodeStore, tmdbClient *services.TMDBClient, interval time.Duration) {
-	log.Printf("📺 Episode Scan Worker: Starting (interval: %v)", interval)
-
-	// Run immediately on startup
-	runEpisodeScan(ctx, seriesStore, episodeStore, tmdbClient)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("📺 Episode Scan Worker: Stopping")
-			return
-		case <-ticker.C:
-			runEpisodeScan(ctx, seriesStore, episodeStore, tmdbClient)
-		}
-	}
-}
-
-func runEpisodeScan(ctx context.Context, seriesStore *database.SeriesStore, episodeStore *database.EpisodeStore, tmdbClient *services.TMDBClient) {
-	log.Println("📺 Episode Scan Worker: Scanning episodes for all series...")
-
-	allSeries, err := seriesStore.List(ctx, 0, 10000, nil)
-	if err != nil {
-		log.Printf("❌ Episode Scan error: %v", err)
-		return
-	}
-
-	totalSeries := len(allSeries)
-	if totalSeries == 0 {
-		log.Println("✅ Episode Scan: No series in library")
-		return
-	}
-
-	log.Printf("📺 Found %d series to scan\n", totalSeries)
-	totalEpisodes := 0
-	errors := 0
-
-	for i, series := range allSeries {
-		if i%5 == 0 {
-			log.Printf("📺 Progress: %d/%d series scanned\n", i, totalSeries)
-		}
-
-		// Get series details from TMDB
-		tmdbSeries, err := tmdbClient.GetSeries(ctx, series.TMDBID)
-		if err != nil {
-			errors++
-			continue
-		}
-
-		numSeasons := tmdbSeries.Seasons
-		if numSeasons == 0 {
-			continue
-		}
-
-		// Get all episodes for this series
-		episodes, err := tmdbClient.GetEpisodes(ctx, series.ID, series.TMDBID, numSeasons)
-		if err != nil {
-			errors++
-			continue
-		}
-
-		// Set the series ID for all episodes
-		for _, ep := range episodes {
-			ep.SeriesID = series.ID
-			ep.Monitored = series.Monitored
-		}
-
-		// Add episodes to database (batch insert)
-		if len(episodes) > 0 {
-			if err := episodeStore.AddBatch(ctx, episodes); err == nil {
-				totalEpisodes += len(episodes)
-			}
-		}
-
-		time.Sleep(200 * time.Millisecond) // Rate limit
-	}
-
-	log.Printf("✅ Episode Scan complete: %d episodes for %d series (%d errors)\n", totalEpisodes, totalSeries, errors)
-}
-
-func balkanVODSyncWorker(ctx context.Context, movieStore *database.MovieStore, seriesStore *database.SeriesStore, tmdbClient *services.TMDBClient, settingsManager *settings.Manager, interval time.Duration) {
-	log.Printf("🇧🇦 Balkan VOD Sync Worker: Starting (interval: %v)", interval)
-
-	// Run initial sync
-	log.Println("🇧🇦 Balkan VOD Sync Worker: Running initial sync...")
-	runBalkanVODSync(ctx, movieStore, seriesStore, tmdbClient, settingsManager)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("🇧🇦 Balkan VOD Sync Worker: Stopping")
-			return
-		case <-ticker.C:
-			runBalkanVODSync(ctx, movieStore, seriesStore, tmdbClient, settingsManager)
-		}
+		jobs.Logf(ctx, "⚠️ MDBList enrichment error: %v", err)
 	}
+	return nil
 }
 
-func runBalkanVODSync(ctx context.Context, movieStore *database.MovieStore, seriesStore *database.SeriesStore, tmdbClient *services.TMDBClient, settingsManager *settings.Manager) {
+func runBalkanVODSync(ctx context.Context, movieStore *database.MovieStore, seriesStore *database.SeriesStore, tmdbClient *services.TMDBClient, settingsManager *settings.Manager) error {
 	appSettings := settingsManager.Get()
 
 	if !appSettings.BalkanVODEnabled {
-		log.Println("🇧🇦 Balkan VOD Sync: Disabled in settings")
-		return
+		jobs.Logf(ctx, "🇧🇦 Balkan VOD Sync: Disabled in settings")
+		return nil
 	}
 
-	if !appSettings.BalkanVODAutoSync {
-		log.Println("🇧🇦 Balkan VOD Sync: Auto-sync disabled")
-		return
+	// Scheduled runs respect the auto-sync switch; manual triggers always import
+	if !appSettings.BalkanVODAutoSync && !jobs.IsManual(ctx) {
+		jobs.Logf(ctx, "🇧🇦 Balkan VOD Sync: Auto-sync disabled")
+		return nil
 	}
 
 	log.Println("🇧🇦 Balkan VOD Sync: Starting import from GitHub repos...")
-	services.GlobalScheduler.MarkRunning(services.ServiceBalkanVODSync)
-
 	importer := services.NewBalkanVODImporter(movieStore, seriesStore, tmdbClient, appSettings)
-	err := importer.ImportBalkanVOD(ctx)
-
-	// Get configured interval from settings
-	syncInterval := time.Duration(appSettings.BalkanVODSyncIntervalHours) * time.Hour
-	if syncInterval < 1*time.Hour {
-		syncInterval = 24 * time.Hour // Default to 24 hours if invalid
-	}
-
-	services.GlobalScheduler.MarkComplete(services.ServiceBalkanVODSync, err, syncInterval)
-
-	if err != nil {
-		log.Printf("❌ Balkan VOD Sync error: %v", err)
-	} else {
-		log.Println("✅ Balkan VOD Sync complete")
-	}
+	return importer.ImportBalkanVOD(ctx)
 }
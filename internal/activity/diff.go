@@ -0,0 +1,92 @@
+package activity
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Redacted replaces secret values in diffs
+const Redacted = "[redacted]"
+
+// secretKeys are substrings of JSON keys whose values are never written to the activity log.
+// URLs of sources and addons are included because they often carry credentials.
+var secretKeys = []string{"key", "secret", "password", "token", "webhook", "url", "cookie"}
+
+// Change is a field's value before and after an edit
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Diff returns the top-level JSON fields that differ between before and after, with secret
+// values redacted. A changed secret still shows up, so the log tells that it was changed.
+func Diff(before, after interface{}) map[string]Change {
+	oldFields, newFields := jsonFields(before), jsonFields(after)
+	changes := make(map[string]Change)
+	for key, newValue := range newFields {
+		oldValue, existed := oldFields[key]
+		if existed && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes[key] = Change{From: redact(key, oldValue), To: redact(key, newValue)}
+	}
+	for key, oldValue := range oldFields {
+		if _, ok := newFields[key]; !ok {
+			changes[key] = Change{From: redact(key, oldValue)}
+		}
+	}
+	return changes
+}
+
+func jsonFields(v interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact hides the value of a secret key, and of secret keys inside lists and objects
+func redact(key string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if isSecret(key) {
+		switch value.(type) {
+		case string, map[string]interface{}, []interface{}:
+			if value == "" {
+				return ""
+			}
+			return Redacted
+		}
+		return value // Switches such as proxy_auth_enabled
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = redact(k, item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redact("", item)
+		}
+		return out
+	}
+	return value
+}
@@ -0,0 +1,165 @@
+// Package activity records what happens in StreamArr in the activity log: library changes,
+// blacklist and settings edits, user management, imports, stream upgrades and playback.
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+)
+
+// playbackWindow is how long further starts of the same item by the same viewer count as one
+// playback; players open several connections while seeking or buffering
+const playbackWindow = 30 * time.Minute
+
+// Event is one activity
+type Event struct {
+	Type        string // database.Event*
+	ContentType string // e.g. "movie", "series", "user", "settings"
+	ContentID   int64
+	Message     string
+	Data        map[string]interface{}
+
+	// Who did it; taken from the signed-in user in the context when empty
+	UserID   int
+	Username string
+}
+
+// Recorder writes events to the activity log. A nil Recorder records nothing.
+type Recorder struct {
+	store           *database.ActivityStore
+	playbackEnabled func() bool // Nil records playback
+
+	mu       sync.Mutex
+	playback map[string]time.Time // Viewer and item → last recorded start
+}
+
+// NewRecorder creates a recorder backed by the activity store
+func NewRecorder(store *database.ActivityStore) *Recorder {
+	return &Recorder{store: store, playback: make(map[string]time.Time)}
+}
+
+// SetPlaybackEnabled sets the switch for recording playback starts, read on every playback
+func (r *Recorder) SetPlaybackEnabled(enabled func() bool) {
+	r.playbackEnabled = enabled
+}
+
+// Store returns the underlying activity store, or nil
+func (r *Recorder) Store() *database.ActivityStore {
+	if r == nil {
+		return nil
+	}
+	return r.store
+}
+
+// Record writes an event. Failures are logged rather than returned, since the action itself succeeded.
+func (r *Recorder) Record(ctx context.Context, e Event) {
+	if r == nil || r.store == nil {
+		return
+	}
+	entry := &models.ActivityLog{
+		EventType:   e.Type,
+		ContentType: e.ContentType,
+		ContentID:   e.ContentID,
+		Message:     e.Message,
+		Username:    e.Username,
+	}
+	if e.UserID == 0 && e.Username == "" {
+		if claims, ok := auth.GetUserFromContext(ctx); ok {
+			e.UserID, entry.Username = claims.UserID, claims.Username
+		}
+	}
+	if e.UserID != 0 {
+		entry.UserID = &e.UserID
+	}
+	if len(e.Data) > 0 {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			log.Printf("[ACTIVITY] ⚠️ Cannot encode %s data: %v", e.Type, err)
+		} else {
+			entry.Data = string(data)
+		}
+	}
+	// Requests may be cancelled once the response is written
+	if err := r.store.Add(context.WithoutCancel(ctx), entry); err != nil {
+		log.Printf("[ACTIVITY] ⚠️ %v", err)
+	}
+}
+
+// Playback records a playback start, unless the same viewer started the same item within playbackWindow
+func (r *Recorder) Playback(ctx context.Context, e Event) {
+	if r == nil || (r.playbackEnabled != nil && !r.playbackEnabled()) {
+		return
+	}
+	viewer := e.Username
+	if viewer == "" {
+		if claims, ok := auth.GetUserFromContext(ctx); ok {
+			viewer = claims.Username
+		}
+	}
+	key := viewer + "|" + e.ContentType + "|" + strconv.FormatInt(e.ContentID, 10)
+	if e.ContentID == 0 {
+		key += "|" + e.Message
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	if last, ok := r.playback[key]; ok && now.Sub(last) < playbackWindow {
+		r.mu.Unlock()
+		return
+	}
+	r.playback[key] = now
+	if len(r.playback) > 1000 {
+		for k, last := range r.playback {
+			if now.Sub(last) >= playbackWindow {
+				delete(r.playback, k)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	e.Type = database.EventPlayback
+	r.Record(ctx, e)
+}
+
+// Retention is how many days activities are kept; 0 keeps them forever
+type Retention struct {
+	Days       int            // Event types without their own entry
+	DaysByType map[string]int // Per event type
+}
+
+// Prune deletes activities older than the retention allows and returns how many it deleted
+func (r *Recorder) Prune(ctx context.Context, retention Retention) (int64, error) {
+	if r == nil || r.store == nil {
+		return 0, nil
+	}
+	now := time.Now()
+	var total int64
+	var own []string
+	for eventType, days := range retention.DaysByType {
+		own = append(own, eventType)
+		if days <= 0 {
+			continue
+		}
+		n, err := r.store.Prune(ctx, eventType, now.AddDate(0, 0, -days))
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	if retention.Days > 0 {
+		n, err := r.store.Prune(ctx, "", now.AddDate(0, 0, -retention.Days), own...)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
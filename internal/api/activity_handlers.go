@@ -0,0 +1,318 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/activity"
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/Zerr0-C00L/StreamArr/internal/services"
+	"github.com/Zerr0-C00L/StreamArr/internal/settings"
+)
+
+// maxActivityExport caps how many activities one export returns
+const maxActivityExport = 50000
+
+// recordLibrary adds a library addition or removal to the activity feed
+func (h *Handler) recordLibrary(ctx context.Context, eventType, contentType string, id int64, tmdbID int, title string) {
+	verb := "Added"
+	if eventType == database.EventLibraryRemove {
+		verb = "Removed"
+	}
+	h.activity.Record(ctx, activity.Event{
+		Type:        eventType,
+		ContentType: contentType,
+		ContentID:   id,
+		Message:     fmt.Sprintf("%s %s %q", verb, contentType, title),
+		Data:        map[string]interface{}{"tmdb_id": tmdbID, "title": title},
+	})
+}
+
+// recordPlayback adds a playback start from the web UI or API to the activity feed
+func (h *Handler) recordPlayback(r *http.Request, contentType string, id int64, title string) {
+	h.activity.Playback(r.Context(), activity.Event{
+		ContentType: contentType,
+		ContentID:   id,
+		Message:     fmt.Sprintf("Started %s %q", contentType, title),
+		Data: map[string]interface{}{
+			"client":     "web",
+			"title":      title,
+			"remote_ip":  auth.ClientIP(r),
+			"user_agent": r.UserAgent(),
+		},
+	})
+}
+
+// recordUser adds user management to the activity feed; action is "create", "update" or "delete"
+func (h *Handler) recordUser(ctx context.Context, action string, userID int, username string, details map[string]interface{}) {
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["action"] = action
+	details["target"] = username
+	verbs := map[string]string{"create": "Created", "update": "Updated", "delete": "Deleted"}
+	h.activity.Record(ctx, activity.Event{
+		Type:        database.EventUser,
+		ContentType: "user",
+		ContentID:   int64(userID),
+		Message:     fmt.Sprintf("%s user %s", verbs[action], username),
+		Data:        details,
+	})
+}
+
+// recordImport adds a finished import or sync to the activity feed
+func (h *Handler) recordImport(ctx context.Context, source, message string, details map[string]interface{}) {
+	h.activity.Record(ctx, activity.Event{
+		Type:        database.EventImport,
+		ContentType: source,
+		Message:     message,
+		Data:        details,
+	})
+}
+
+// recordIPTVImport adds an IPTV VOD import to the activity feed
+func (h *Handler) recordIPTVImport(ctx context.Context, summary *services.IPTVVODImportSummary) {
+	if summary == nil {
+		return
+	}
+	h.recordImport(ctx, "iptv_vod",
+		fmt.Sprintf("IPTV VOD import: %d movies, %d series from %d sources", summary.MoviesImported, summary.SeriesImported, summary.SourcesChecked),
+		map[string]interface{}{
+			"sources_checked": summary.SourcesChecked,
+			"items_found":     summary.ItemsFound,
+			"movies_imported": summary.MoviesImported,
+			"series_imported": summary.SeriesImported,
+			"skipped":         summary.Skipped,
+			"errors":          summary.Errors,
+		})
+}
+
+// recordSettingsChange adds a settings edit to the activity feed, with secrets redacted
+func (h *Handler) recordSettingsChange(ctx context.Context, before, after *settings.Settings) {
+	changes := activity.Diff(before, after)
+	if len(changes) == 0 {
+		return
+	}
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	message := "Changed " + strings.Join(fields, ", ")
+	if len(fields) > 5 {
+		message = fmt.Sprintf("Changed %s and %d more settings", strings.Join(fields[:5], ", "), len(fields)-5)
+	}
+	h.activity.Record(ctx, activity.Event{
+		Type:        database.EventSettings,
+		ContentType: "settings",
+		Message:     message,
+		Data:        map[string]interface{}{"changes": changes},
+	})
+}
+
+// pruneActivity is the activity_retention job: deletes activities older than the retention settings
+func (h *Handler) pruneActivity(ctx context.Context) error {
+	if h.activity.Store() == nil || h.settingsManager == nil {
+		jobs.Logf(ctx, "[ACTIVITY] Retention skipped: activity feed is not initialized")
+		return nil
+	}
+	current := h.settingsManager.Get()
+	pruned, err := h.activity.Prune(ctx, activity.Retention{
+		Days:       current.ActivityRetentionDays,
+		DaysByType: current.ActivityRetentionDaysByType,
+	})
+	if err != nil {
+		return err
+	}
+	jobs.Logf(ctx, "[ACTIVITY] Pruned %d old activities", pruned)
+	return nil
+}
+
+// activityFilter reads the activity feed filters from the query string:
+// type (comma-separated), content_type, content_id, user_id, q, since and until (RFC 3339 or YYYY-MM-DD)
+func activityFilter(r *http.Request) (database.ActivityFilter, error) {
+	query := r.URL.Query()
+	filter := database.ActivityFilter{
+		ContentType: query.Get("content_type"),
+		Search:      strings.TrimSpace(query.Get("q")),
+	}
+	for _, eventType := range strings.Split(query.Get("type"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType == "" {
+			continue
+		}
+		if !slices.Contains(database.ActivityEventTypes, eventType) {
+			return filter, fmt.Errorf("unknown type %q", eventType)
+		}
+		filter.EventTypes = append(filter.EventTypes, eventType)
+	}
+	var err error
+	if v := query.Get("content_id"); v != "" {
+		if filter.ContentID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filter, fmt.Errorf("invalid content_id")
+		}
+	}
+	if v := query.Get("user_id"); v != "" {
+		if filter.UserID, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("invalid user_id")
+		}
+	}
+	if filter.Since, err = parseActivityTime(query.Get("since"), false); err != nil {
+		return filter, fmt.Errorf("invalid since")
+	}
+	if filter.Until, err = parseActivityTime(query.Get("until"), true); err != nil {
+		return filter, fmt.Errorf("invalid until")
+	}
+	return filter, nil
+}
+
+// parseActivityTime parses RFC 3339 or a date; a date as the end of a range includes that whole day
+func parseActivityTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err == nil && end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, err
+}
+
+// activityJSON is an activity as returned by the API, with its data as a JSON object
+func activityJSON(e *models.ActivityLog) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":           e.ID,
+		"event_type":   e.EventType,
+		"content_type": e.ContentType,
+		"content_id":   e.ContentID,
+		"message":      e.Message,
+		"user_id":      e.UserID,
+		"username":     e.Username,
+		"created_at":   e.CreatedAt,
+	}
+	if e.Data != "" {
+		item["data"] = json.RawMessage(e.Data)
+	}
+	return item
+}
+
+// GetActivity handles GET /api/v1/activity: the activity feed, newest first, with the activityFilter
+// filters and limit/offset pagination
+func (h *Handler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	if h.activity.Store() == nil {
+		respondError(w, http.StatusServiceUnavailable, "activity feed not available")
+		return
+	}
+	filter, err := activityFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	filter.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+	if filter.Limit <= 0 || filter.Limit > 1000 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	entries, total, err := h.activity.Store().Query(r.Context(), filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	items := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, activityJSON(e))
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"activity": items,
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+		"types":    database.ActivityEventTypes,
+	})
+}
+
+// ExportActivity handles GET /api/v1/activity/export?format=csv|json with the same filters as
+// GetActivity, returning every match (up to maxActivityExport) as a download
+func (h *Handler) ExportActivity(w http.ResponseWriter, r *http.Request) {
+	if h.activity.Store() == nil {
+		respondError(w, http.StatusServiceUnavailable, "activity feed not available")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		respondError(w, http.StatusBadRequest, "format must be csv or json")
+		return
+	}
+	filter, err := activityFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit = maxActivityExport
+
+	entries, _, err := h.activity.Store().Query(r.Context(), filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filename := "streamarr-activity-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "json" {
+		items := make([]map[string]interface{}, 0, len(entries))
+		for _, e := range entries {
+			items = append(items, activityJSON(e))
+		}
+		respondJSON(w, http.StatusOK, items)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "created_at", "event_type", "content_type", "content_id", "user_id", "username", "message", "data"})
+	for _, e := range entries {
+		userID := ""
+		if e.UserID != nil {
+			userID = strconv.Itoa(*e.UserID)
+		}
+		out.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.Format(time.RFC3339),
+			e.EventType,
+			e.ContentType,
+			strconv.FormatInt(e.ContentID, 10),
+			userID,
+			csvSafe(e.Username),
+			csvSafe(e.Message),
+			e.Data,
+		})
+	}
+	out.Flush()
+}
+
+// csvSafe stops spreadsheet apps from running titles or usernames that start like a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
 	"github.com/gorilla/mux"
 )
 
@@ -26,31 +28,33 @@ func NewAdminHandler(handler *Handler) *AdminHandler {
 // RegisterAdminRoutes registers admin API routes
 func (a *AdminHandler) RegisterAdminRoutes(r *mux.Router) {
 	admin := r.PathPrefix("/api/admin").Subrouter()
+	admin.Use(requireRouteGuard)
+	h := a.handler
 
 	// System status and control
-	admin.HandleFunc("/status", a.GetSystemStatus).Methods("GET")
-	admin.HandleFunc("/daemon/start", a.StartDaemon).Methods("POST")
-	admin.HandleFunc("/daemon/stop", a.StopDaemon).Methods("POST")
-	admin.HandleFunc("/sync/now", a.SyncNow).Methods("POST")
-	admin.HandleFunc("/playlist/generate", a.GeneratePlaylist).Methods("POST")
-	admin.HandleFunc("/cache/episodes", a.CacheEpisodes).Methods("POST")
+	admin.Handle("/status", h.can(auth.PermSystemRead, a.GetSystemStatus)).Methods("GET")
+	admin.Handle("/daemon/start", h.audited(auth.PermSystemControl, "system.daemon_start", a.StartDaemon)).Methods("POST")
+	admin.Handle("/daemon/stop", h.audited(auth.PermSystemControl, "system.daemon_stop", a.StopDaemon)).Methods("POST")
+	admin.Handle("/sync/now", h.can(auth.PermServicesRun, a.SyncNow)).Methods("POST")
+	admin.Handle("/playlist/generate", h.can(auth.PermServicesRun, a.GeneratePlaylist)).Methods("POST")
+	admin.Handle("/cache/episodes", h.can(auth.PermServicesRun, a.CacheEpisodes)).Methods("POST")
 
 	// Logs
-	admin.HandleFunc("/logs/{file}", a.GetLogs).Methods("GET")
+	admin.Handle("/logs/{file}", h.can(auth.PermSystemRead, a.GetLogs)).Methods("GET")
 
 	// Settings management
-	admin.HandleFunc("/settings", a.GetAdminSettings).Methods("GET")
-	admin.HandleFunc("/settings", a.SaveAdminSettings).Methods("POST")
+	admin.Handle("/settings", h.can(auth.PermSettingsRead, a.GetAdminSettings)).Methods("GET")
+	admin.Handle("/settings", h.audited(auth.PermSettingsWrite, "settings.update", a.SaveAdminSettings)).Methods("POST")
 
 	// User management
-	admin.HandleFunc("/users", a.GetUsers).Methods("GET")
-	admin.HandleFunc("/users", a.CreateUser).Methods("POST")
-	admin.HandleFunc("/users/{id}", a.UpdateUser).Methods("PUT")
-	admin.HandleFunc("/users/{id}", a.DeleteUser).Methods("DELETE")
+	admin.Handle("/users", h.can(auth.PermUsersManage, a.GetUsers)).Methods("GET")
+	admin.Handle("/users", h.audited(auth.PermUsersManage, "user.create", a.CreateUser)).Methods("POST")
+	admin.Handle("/users/{id}", h.audited(auth.PermUsersManage, "user.update", a.UpdateUser)).Methods("PUT")
+	admin.Handle("/users/{id}", h.audited(auth.PermUsersManage, "user.delete", a.DeleteUser)).Methods("DELETE")
 
 	// System info
-	admin.HandleFunc("/info", a.GetSystemInfo).Methods("GET")
-	admin.HandleFunc("/stats", a.GetStatistics).Methods("GET")
+	admin.Handle("/info", h.can(auth.PermSystemRead, a.GetSystemInfo)).Methods("GET")
+	admin.Handle("/stats", h.can(auth.PermLibraryRead, a.GetStatistics)).Methods("GET")
 }
 
 // GetSystemStatus returns system status information
@@ -222,6 +226,7 @@ func (a *AdminHandler) SaveAdminSettings(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Save settings
+	before := a.handler.settingsManager.Get()
 	if err := a.handler.settingsManager.SetAll(settings); err != nil {
 		respondJSON(w, http.StatusOK, map[string]interface{}{
 			"success": false,
@@ -229,6 +234,7 @@ func (a *AdminHandler) SaveAdminSettings(w http.ResponseWriter, r *http.Request)
 		})
 		return
 	}
+	a.handler.recordSettingsChange(r.Context(), before, a.handler.settingsManager.Get())
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
@@ -276,7 +282,14 @@ func (a *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Role == "" {
-		req.Role = "user"
+		req.Role = auth.DefaultRole
+	}
+	if !auth.ValidRole(req.Role) {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": false,
+			"error":   invalidRoleMessage(),
+		})
+		return
 	}
 
 	if a.handler.userStore == nil {
@@ -297,6 +310,8 @@ func (a *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	a.handler.recordUser(r.Context(), "create", user, req.Username, map[string]interface{}{"role": req.Role})
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "User created successfully",
@@ -331,6 +346,24 @@ func (a *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if value, ok := updates["role"]; ok {
+		role, _ := value.(string)
+		if !auth.ValidRole(role) {
+			respondJSON(w, http.StatusOK, map[string]interface{}{
+				"success": false,
+				"error":   invalidRoleMessage(),
+			})
+			return
+		}
+		if role != auth.RoleAdmin && a.isLastAdmin(userID) {
+			respondJSON(w, http.StatusOK, map[string]interface{}{
+				"success": false,
+				"error":   "Cannot demote the last admin",
+			})
+			return
+		}
+	}
+
 	// Update user
 	if err := a.handler.userStore.UpdateUser(userID, updates); err != nil {
 		respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -340,6 +373,21 @@ func (a *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	details := map[string]interface{}{"fields": fields}
+	if role, ok := updates["role"]; ok {
+		details["role"] = role
+	}
+	var username string
+	if user, err := a.handler.userStore.GetUserByID(userID); err == nil {
+		username = user.Username
+	}
+	a.handler.recordUser(r.Context(), "update", userID, username, details)
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "User updated successfully",
@@ -367,6 +415,19 @@ func (a *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a.isLastAdmin(userID) {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": false,
+			"error":   "Cannot delete the last admin",
+		})
+		return
+	}
+
+	var username string
+	if user, err := a.handler.userStore.GetUserByID(userID); err == nil {
+		username = user.Username
+	}
+
 	// Delete user
 	if err := a.handler.userStore.DeleteUser(userID); err != nil {
 		respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -376,12 +437,28 @@ func (a *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	a.handler.recordUser(r.Context(), "delete", userID, username, nil)
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "User deleted successfully",
 	})
 }
 
+// isLastAdmin reports whether userID is the only admin left
+func (a *AdminHandler) isLastAdmin(userID int) bool {
+	role, err := a.handler.userStore.GetRole(userID)
+	if err != nil || role != auth.RoleAdmin {
+		return false
+	}
+	count, err := a.handler.userStore.CountUsersWithRole(auth.RoleAdmin)
+	return err == nil && count <= 1
+}
+
+func invalidRoleMessage() string {
+	return "Invalid role; must be one of: " + strings.Join(auth.Roles(), ", ")
+}
+
 // GetSystemInfo returns system information
 func (a *AdminHandler) GetSystemInfo(w http.ResponseWriter, r *http.Request) {
 	// Get system info using uname
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/subtitles"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,15 +21,44 @@ type LoginRequest struct {
 	RememberMe bool   `json:"remember_me"`
 }
 
-// LoginResponse contains the JWT token
+// LoginResponse contains a short-lived access token and the refresh token that renews it
 type LoginResponse struct {
-	Token     string    `json:"token"`
-	Username  string    `json:"username"`
-	IsAdmin   bool      `json:"is_admin"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token            string            `json:"token"`
+	ExpiresAt        time.Time         `json:"expires_at"`
+	RefreshToken     string            `json:"refresh_token"`
+	RefreshExpiresAt time.Time         `json:"refresh_expires_at"`
+	Username         string            `json:"username"`
+	Role             string            `json:"role"`
+	Permissions      []auth.Permission `json:"permissions"`
+	IsAdmin          bool              `json:"is_admin"`
 }
 
-// Login handles user authentication
+// TwoFactorLoginRequest completes a sign-in that returned a login challenge
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // Authenticator code or recovery code
+}
+
+// RefreshRequest carries a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func loginResponse(session *auth.Session) LoginResponse {
+	return LoginResponse{
+		Token:            session.AccessToken,
+		ExpiresAt:        session.AccessExpiresAt,
+		RefreshToken:     session.RefreshToken,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+		Username:         session.Username,
+		Role:             session.Role,
+		Permissions:      auth.RolePermissions(session.Role),
+		IsAdmin:          session.Role == auth.RoleAdmin,
+	}
+}
+
+// Login handles user authentication. Accounts with two-factor authentication get a login challenge
+// instead of tokens, completed by LoginTwoFactor.
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -39,6 +71,11 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if wait, err := h.loginGuard.Allow(auth.ClientIP(r), req.Username); err != nil {
+		respondTooManyAttempts(w, wait, err)
+		return
+	}
+
 	// Query user from database using the users table schema
 	var userID int
 	var hashedPassword string
@@ -51,6 +88,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	`, req.Username).Scan(&userID, &hashedPassword, &role)
 
 	if err == sql.ErrNoRows {
+		h.loginFailed(r, 0, req.Username, database.LoginUnknownUser)
 		respondError(w, http.StatusUnauthorized, "invalid username or password")
 		return
 	}
@@ -62,33 +100,119 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(req.Password)); err != nil {
+		h.loginFailed(r, userID, req.Username, database.LoginBadPassword)
 		respondError(w, http.StatusUnauthorized, "invalid username or password")
 		return
 	}
 
-	// Check if user is admin
-	isAdmin := (role == "admin")
+	twoFactor, err := h.authService.TwoFactorEnabled(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error loading two-factor state: %v", err)
+		respondError(w, http.StatusInternalServerError, "authentication failed")
+		return
+	}
+	if twoFactor {
+		challenge, expiresAt, err := h.authService.IssueLoginChallenge(r.Context(), userID, req.RememberMe)
+		if err != nil {
+			log.Printf("Error generating login challenge: %v", err)
+			respondError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"two_factor_required":  true,
+			"challenge_token":      challenge,
+			"challenge_expires_at": expiresAt,
+		})
+		return
+	}
+
+	h.completeLogin(w, r, userID, req.Username, auth.NormalizeRole(role), req.RememberMe)
+}
+
+// LoginTwoFactor handles POST /api/v1/auth/login/2fa: finishes a sign-in with an authenticator or recovery code
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		respondError(w, http.StatusBadRequest, "challenge_token and code required")
+		return
+	}
+
+	userID, rememberMe, err := h.authService.ParseLoginChallenge(req.ChallengeToken)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "login expired, sign in again")
+		return
+	}
+	user, err := h.userStore.GetUserByID(userID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusUnauthorized, "login expired, sign in again")
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying user: %v", err)
+		respondError(w, http.StatusInternalServerError, "authentication failed")
+		return
+	}
+
+	if wait, err := h.loginGuard.Allow(auth.ClientIP(r), user.Username); err != nil {
+		respondTooManyAttempts(w, wait, err)
+		return
+	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(userID, req.Username, isAdmin, req.RememberMe)
+	usedRecoveryCode, err := h.authService.VerifySecondFactor(r.Context(), userID, req.Code)
+	switch {
+	case err == auth.ErrInvalidCode:
+		h.loginFailed(r, userID, user.Username, database.LoginBadCode)
+		respondError(w, http.StatusUnauthorized, "invalid code")
+		return
+	case err == auth.ErrTwoFactorNotEnabled:
+		// Turned off since the password was checked; the password alone is enough now
+	case err != nil:
+		log.Printf("Error verifying second factor: %v", err)
+		respondError(w, http.StatusInternalServerError, "authentication failed")
+		return
+	}
+	if usedRecoveryCode {
+		log.Printf("[AUTH] %s signed in with a recovery code", user.Username)
+	}
+
+	h.completeLogin(w, r, userID, user.Username, auth.NormalizeRole(user.Role), rememberMe)
+}
+
+// completeLogin issues an access token and a refresh token for a user who passed every check
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, userID int, username, role string, rememberMe bool) {
+	h.loginGuard.Success(username)
+	h.recordLogin(r, database.LoginSourceWeb, userID, username, "")
+
+	session, err := h.authService.IssueSession(r.Context(), userID, username, role, rememberMe, r.UserAgent(), auth.ClientIP(r))
 	if err != nil {
 		log.Printf("Error generating token: %v", err)
 		respondError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
 
-	// Calculate expiration
-	expiration := time.Now().Add(24 * time.Hour)
-	if req.RememberMe {
-		expiration = time.Now().Add(30 * 24 * time.Hour)
+	respondJSON(w, http.StatusOK, loginResponse(session))
+}
+
+// RefreshSession handles POST /api/v1/auth/refresh: exchanges a refresh token for a new token pair
+func (h *Handler) RefreshSession(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "refresh_token required")
+		return
+	}
+
+	session, err := h.authService.Refresh(r.Context(), req.RefreshToken, r.UserAgent(), auth.ClientIP(r))
+	if err == auth.ErrInvalidRefreshToken {
+		respondError(w, http.StatusUnauthorized, "invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error refreshing session: %v", err)
+		respondError(w, http.StatusInternalServerError, "failed to refresh session")
+		return
 	}
 
-	respondJSON(w, http.StatusOK, LoginResponse{
-		Token:     token,
-		Username:  req.Username,
-		IsAdmin:   isAdmin,
-		ExpiresAt: expiration,
-	})
+	respondJSON(w, http.StatusOK, loginResponse(session))
 }
 
 // VerifyToken validates the current token
@@ -104,24 +228,39 @@ func (h *Handler) VerifyToken(w http.ResponseWriter, r *http.Request) {
 		tokenString = tokenString[7:]
 	}
 
-	claims, err := auth.ValidateToken(tokenString)
+	claims, err := h.authService.ValidateAccessToken(tokenString)
 	if err != nil {
 		respondError(w, http.StatusUnauthorized, "invalid token")
 		return
 	}
+	if claims, err = h.currentClaims(claims); err != nil {
+		respondError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"valid":    true,
-		"user_id":  claims.UserID,
-		"username": claims.Username,
-		"is_admin": claims.IsAdmin,
+		"valid":       true,
+		"user_id":     claims.UserID,
+		"username":    claims.Username,
+		"role":        claims.Role,
+		"permissions": auth.RolePermissions(claims.Role),
+		"is_admin":    claims.IsAdmin,
 	})
 }
 
-// Logout invalidates the current session (client-side only for JWT)
+// Logout ends the session behind the refresh token in the body; the short-lived access token
+// simply expires
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
-	// With JWT, logout is client-side (delete token)
-	// We just acknowledge the request
+	var req RefreshRequest
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+	if req.RefreshToken != "" {
+		if err := h.authService.Revoke(r.Context(), req.RefreshToken); err != nil {
+			log.Printf("Error revoking session: %v", err)
+		}
+	}
+
 	respondJSON(w, http.StatusOK, map[string]string{
 		"message": "logged out successfully",
 	})
@@ -140,9 +279,13 @@ func (h *Handler) AuthStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sso := h.sso.Options()
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"setup_required": count == 0,
-		"user_count":     count,
+		"setup_required":     count == 0,
+		"user_count":         count,
+		"oidc_enabled":       sso.OIDCEnabled,
+		"oidc_provider_name": sso.ProviderName,
+		"proxy_auth_enabled": sso.ProxyEnabled,
 	})
 }
 
@@ -206,7 +349,7 @@ func (h *Handler) CreateFirstUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// UpdateProfile handles user profile updates (username, email, profile_picture)
+// UpdateProfile handles user profile updates (username, email, profile_picture, subtitle_languages)
 func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	log.Printf("UpdateProfile: request received")
 
@@ -223,6 +366,8 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		Username       *string `json:"username"`
 		Email          *string `json:"email"`
 		ProfilePicture *string `json:"profile_picture"`
+		// Comma-separated subtitle languages in preference order; empty uses the server default
+		SubtitleLanguages *string `json:"subtitle_languages"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -246,6 +391,9 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		updates["profile_picture"] = *req.ProfilePicture
 		log.Printf("UpdateProfile: picture length=%d", len(*req.ProfilePicture))
 	}
+	if req.SubtitleLanguages != nil {
+		updates["subtitle_languages"] = strings.Join(subtitles.ParseLanguages(*req.SubtitleLanguages), ",")
+	}
 
 	if len(updates) == 0 {
 		log.Printf("UpdateProfile: no fields to update")
@@ -351,11 +499,16 @@ func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	subtitleLanguages, _ := h.userStore.GetSubtitleLanguages(claims.UserID)
+	role := auth.NormalizeRole(user.Role)
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"id":              user.ID,
-		"username":        user.Username,
-		"email":           user.Email,
-		"role":            user.Role,
-		"profile_picture": user.ProfilePicture,
+		"id":                 user.ID,
+		"username":           user.Username,
+		"email":              user.Email,
+		"role":               role,
+		"permissions":        auth.RolePermissions(role),
+		"profile_picture":    user.ProfilePicture,
+		"subtitle_languages": subtitleLanguages,
 	})
 }
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/activity"
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/gorilla/mux"
+)
+
+// routeGuard wraps an API handler with the permission it needs. Every /api route is registered
+// through one (see requireRouteGuard), so a new route can't be added without deciding who may call it.
+type routeGuard struct {
+	h           *Handler
+	public      bool            // No session required (login, setup, health)
+	sessionOnly bool            // Not available to API keys (credential management)
+	perm        auth.Permission // Empty: any signed-in user
+	action      string          // Recorded in the audit log when set, e.g. "movie.delete"
+	next        http.HandlerFunc
+}
+
+// errSessionRevoked is returned for access tokens issued before the user signed out all sessions
+var errSessionRevoked = errors.New("session revoked")
+
+// public allows a route without a session
+func (h *Handler) public(fn http.HandlerFunc) http.Handler {
+	return &routeGuard{h: h, public: true, next: fn}
+}
+
+// signedIn allows a route for any signed-in user, e.g. their own profile
+func (h *Handler) signedIn(fn http.HandlerFunc) http.Handler {
+	return &routeGuard{h: h, next: fn}
+}
+
+// sessionOnly allows a route for any user signed in with a session, not with an API key,
+// so a scoped key can't be used to mint broader credentials
+func (h *Handler) sessionOnly(fn http.HandlerFunc) http.Handler {
+	return &routeGuard{h: h, sessionOnly: true, next: fn}
+}
+
+// can allows a route for users whose role grants perm
+func (h *Handler) can(perm auth.Permission, fn http.HandlerFunc) http.Handler {
+	return &routeGuard{h: h, perm: perm, next: fn}
+}
+
+// audited is can for destructive routes; each call, allowed or not, is written to the audit log
+func (h *Handler) audited(perm auth.Permission, action string, fn http.HandlerFunc) http.Handler {
+	return &routeGuard{h: h, perm: perm, action: action, next: fn}
+}
+
+func (g *routeGuard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.public {
+		g.next(w, r)
+		return
+	}
+
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if g.sessionOnly && claims.APIKeyID != 0 {
+		respondError(w, http.StatusForbidden, "not available to API keys")
+		return
+	}
+	// The role is read on every request so role changes and deleted accounts apply immediately
+	current, err := g.h.currentClaims(claims)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusUnauthorized, "account no longer exists")
+		return
+	}
+	if err == errSessionRevoked {
+		respondError(w, http.StatusUnauthorized, "session revoked")
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] ⚠️ Cannot load role of user %d: %v", claims.UserID, err)
+		respondError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserContextKey, current))
+
+	if g.perm != "" && !current.Can(g.perm) {
+		log.Printf("[AUTH] 🚫 %s (%s) denied %s %s: needs %s", current.Username, current.Role, r.Method, r.URL.Path, g.perm)
+		if g.action != "" {
+			g.h.audit(r, current, g.action, http.StatusForbidden)
+		}
+		respondError(w, http.StatusForbidden, fmt.Sprintf("forbidden: %s permission required", g.perm))
+		return
+	}
+
+	if g.action == "" {
+		g.next(w, r)
+		return
+	}
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	g.next(rec, r)
+	g.h.audit(r, current, g.action, rec.status)
+}
+
+// requireRouteGuard rejects routes registered without a routeGuard
+func requireRouteGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if _, ok := route.GetHandler().(*routeGuard); !ok {
+				log.Printf("[AUTH] ⚠️ %s %s has no permission policy; denying", r.Method, r.URL.Path)
+				respondError(w, http.StatusForbidden, "forbidden")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentClaims returns claims carrying the user's current role, or errSessionRevoked
+func (h *Handler) currentClaims(claims *auth.Claims) (*auth.Claims, error) {
+	if h.userStore == nil {
+		return claims, nil
+	}
+	role, revokedAt, err := h.userStore.GetAuthState(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// JWT timestamps have second precision
+	if revokedAt != nil && claims.IssuedAt != nil && claims.IssuedAt.Time.Before(revokedAt.Truncate(time.Second)) {
+		return nil, errSessionRevoked
+	}
+	current := *claims
+	current.Role = auth.NormalizeRole(role)
+	current.IsAdmin = current.Role == auth.RoleAdmin
+	return &current, nil
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to lift write deadlines
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// audit records who performed an action, on what and with which outcome
+func (h *Handler) audit(r *http.Request, claims *auth.Claims, action string, status int) {
+	vars := mux.Vars(r)
+	contentType, _, _ := strings.Cut(action, ".")
+	contentID, _ := strconv.ParseInt(vars["id"], 10, 64)
+
+	log.Printf("[AUDIT] %s (%s) %s %s %s → %d", claims.Username, claims.Role, action, r.Method, r.URL.Path, status)
+	h.activity.Record(r.Context(), activity.Event{
+		Type:        database.EventAudit,
+		ContentType: contentType,
+		ContentID:   contentID,
+		Message:     fmt.Sprintf("%s %s %s %s (%d)", claims.Username, action, r.Method, r.URL.Path, status),
+		Data: map[string]interface{}{
+			"action":    action,
+			"user_id":   claims.UserID,
+			"username":  claims.Username,
+			"role":      claims.Role,
+			"method":    r.Method,
+			"path":      r.URL.Path,
+			"params":    vars,
+			"status":    status,
+			"remote_ip": auth.ClientIP(r),
+		},
+		UserID:   claims.UserID,
+		Username: claims.Username,
+	})
+}
+
+// GetAuditLog handles GET /api/v1/audit?limit=N
+func (h *Handler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if h.activity.Store() == nil {
+		respondError(w, http.StatusServiceUnavailable, "audit log not available")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	entries, err := h.activity.Store().List(r.Context(), database.EventAudit, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, activityJSON(e))
+	}
+	respondJSON(w, http.StatusOK, result)
+}
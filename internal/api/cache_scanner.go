@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"log"
+	"regexp"
 	"strings"
 	"time"
 
@@ -151,7 +152,7 @@ func (cs *CacheScanner) ScanAndUpgrade(ctx context.Context) error {
 			// which handles RD checking internally via their proxy
 			time.Sleep(2 * time.Second) // Rate limit protection
 
-			providerStreams, err := cs.provider.GetMovieStreamsWithYear(imdbID, releaseYear)
+			providerStreams, err := cs.provider.GetMovieStreamsWithYear(ctx, imdbID, releaseYear)
 			if err != nil {
 				log.Printf("[CACHE-SCANNER] Error fetching streams for %s (%s): %v", movie.Title, imdbID, err)
 				// On error, wait longer before continuing
@@ -202,6 +203,9 @@ func (cs *CacheScanner) ScanAndUpgrade(ctx context.Context) error {
 			bestScore := 0
 
 			for i := range providerStreams {
+				if streamInfoHash(&providerStreams[i]) == "" || providerStreams[i].URL == "" {
+					continue
+				}
 				// Parse and score
 				parsed := cs.streamService.ParseStreamFromTorrentName(
 					providerStreams[i].Title,
@@ -229,18 +233,7 @@ func (cs *CacheScanner) ScanAndUpgrade(ctx context.Context) error {
 
 			// Cache or upgrade if we found a better stream
 			if bestStream != nil {
-				// Extract hash from URL if needed
-				hash := bestStream.InfoHash
-				if hash == "" && bestStream.URL != "" {
-					parts := []rune(bestStream.URL)
-					for i := 0; i < len(parts)-40; i++ {
-						candidate := string(parts[i : i+40])
-						if len(candidate) == 40 {
-							hash = candidate
-							break
-						}
-					}
-				}
+				hash := streamInfoHash(bestStream)
 
 				stream := models.TorrentStream{
 					Hash:        hash,
@@ -342,14 +335,18 @@ func (cs *CacheScanner) scanSeries(ctx context.Context) (int, int, int) {
 			season, episode := 1, 1
 
 			// Check if already cached
-			existsQuery := `SELECT COUNT(*) FROM media_streams WHERE series_id = $1 AND season = $2 AND episode = $3`
-			var count int
-			if err := cs.cacheStore.GetDB().QueryRowContext(ctx, existsQuery, s.ID, season, episode).Scan(&count); err == nil && count > 0 {
-				continue // Already cached
+			existingCache, err := cs.cacheStore.GetCachedEpisodeStream(ctx, int(s.ID), season, episode)
+			if err != nil {
+				log.Printf("[CACHE-SCANNER] Error checking cache for series %d S%02dE%02d: %v", s.ID, season, episode, err)
+				errors++
+				continue
+			}
+			if existingCache != nil {
+				continue // Already cached; the stream checker keeps it fresh
 			}
 
 			// Fetch streams for this episode
-			providerStreams, err := cs.provider.GetSeriesStreams(imdbID, season, episode)
+			providerStreams, err := cs.provider.GetSeriesStreams(ctx, imdbID, season, episode)
 			if err != nil || len(providerStreams) == 0 {
 				continue
 			}
@@ -371,24 +368,12 @@ func (cs *CacheScanner) scanSeries(ctx context.Context) (int, int, int) {
 				continue
 			}
 
-			// Extract hashes from URL if InfoHash is empty (happens with Torrentio+RD)
+			// Only torrents can be cached; InfoHash is filled in from the URL when the addon left it out
 			hashes := make([]string, 0)
 			for i := range providerStreams {
-				hash := providerStreams[i].InfoHash
-				if hash == "" && providerStreams[i].URL != "" {
-					// Extract hash from URL (40-character hex string)
-					parts := []rune(providerStreams[i].URL)
-					for j := 0; j < len(parts)-40; j++ {
-						candidate := string(parts[j : j+40])
-						if len(candidate) == 40 {
-							hash = candidate
-							providerStreams[i].InfoHash = hash
-							break
-						}
-					}
-				}
-				if hash != "" {
-					hashes = append(hashes, hash)
+				providerStreams[i].InfoHash = streamInfoHash(&providerStreams[i])
+				if providerStreams[i].InfoHash != "" {
+					hashes = append(hashes, providerStreams[i].InfoHash)
 				}
 			}
 
@@ -405,6 +390,9 @@ func (cs *CacheScanner) scanSeries(ctx context.Context) (int, int, int) {
 
 			for i := range providerStreams {
 				// All streams from Torrentio+RD are already cached
+				if providerStreams[i].InfoHash == "" || providerStreams[i].URL == "" {
+					continue
+				}
 
 				parsed := cs.streamService.ParseStreamFromTorrentName(
 					providerStreams[i].Title,
@@ -432,18 +420,7 @@ func (cs *CacheScanner) scanSeries(ctx context.Context) (int, int, int) {
 				continue
 			}
 
-			// Extract hash
 			hash := bestStream.InfoHash
-			if hash == "" && bestStream.URL != "" {
-				parts := []rune(bestStream.URL)
-				for i := 0; i < len(parts)-40; i++ {
-					candidate := string(parts[i : i+40])
-					if len(candidate) == 40 {
-						hash = candidate
-						break
-					}
-				}
-			}
 
 			// Parse quality details
 			parsed := cs.streamService.ParseStreamFromTorrentName(bestStream.Title, hash, bestStream.Source, 0)
@@ -457,33 +434,22 @@ func (cs *CacheScanner) scanSeries(ctx context.Context) (int, int, int) {
 			}
 			qualityScore := streams.CalculateScore(quality).TotalScore
 
-			// Insert into media_streams for series
-			insertQuery := `
-				INSERT INTO media_streams (
-					series_id, season, episode, stream_url, stream_hash, 
-					quality_score, resolution, hdr_type, audio_format, 
-					source_type, file_size_gb, codec, indexer
-				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-				ON CONFLICT (series_id, season, episode) 
-				DO UPDATE SET 
-					stream_url = EXCLUDED.stream_url,
-					stream_hash = EXCLUDED.stream_hash,
-					quality_score = EXCLUDED.quality_score,
-					resolution = EXCLUDED.resolution,
-					hdr_type = EXCLUDED.hdr_type,
-					audio_format = EXCLUDED.audio_format,
-					source_type = EXCLUDED.source_type,
-					file_size_gb = EXCLUDED.file_size_gb,
-					codec = EXCLUDED.codec,
-					indexer = EXCLUDED.indexer,
-					updated_at = NOW()
-			`
-
-			_, err = cs.cacheStore.GetDB().ExecContext(ctx, insertQuery,
-				s.ID, season, episode, bestStream.URL, hash,
-				qualityScore, parsed.Resolution, parsed.HDRType, parsed.AudioFormat,
-				parsed.Source, parsed.SizeGB, parsed.Codec, bestStream.Source,
-			)
+			stream := models.TorrentStream{
+				Hash:         hash,
+				Title:        bestStream.Name,
+				TorrentName:  bestStream.Title,
+				Resolution:   parsed.Resolution,
+				HDRType:      parsed.HDRType,
+				AudioFormat:  parsed.AudioFormat,
+				Source:       parsed.Source,
+				Codec:        parsed.Codec,
+				SizeGB:       parsed.SizeGB,
+				QualityScore: qualityScore,
+				Indexer:      bestStream.Source,
+			}
+
+			// Save to the episode cache (played instantly by Xtream and re-checked by the stream checker)
+			err = cs.cacheStore.CacheEpisodeStream(ctx, int(s.ID), season, episode, stream, bestStream.URL)
 
 			if err != nil {
 				log.Printf("[CACHE-SCANNER] ❌ Error caching series %s S%02dE%02d: %v", s.Title, season, episode, err)
@@ -515,6 +481,20 @@ func isValidHash(hash string) bool {
 	return true
 }
 
+// infoHashRe finds a BitTorrent info hash in an addon's resolve URL
+var infoHashRe = regexp.MustCompile(`\b[0-9a-fA-F]{40}\b`)
+
+// streamInfoHash returns a stream's info hash, taken from its URL when the addon left it out
+// (Torrentio with Real-Debrid). Streams without one, such as usenet results, have links that
+// expire within hours, so they are never cached; nor are indexer results, which have no URL
+// until playback resolves them through debrid.
+func streamInfoHash(stream *providers.TorrentioStream) string {
+	if stream.InfoHash != "" {
+		return stream.InfoHash
+	}
+	return infoHashRe.FindString(stream.URL)
+}
+
 // CleanupUnreleasedCache removes cached streams for unreleased movies
 func (cs *CacheScanner) CleanupUnreleasedCache(ctx context.Context) (int, error) {
 	log.Println("[CACHE-SCANNER] Starting cleanup of unreleased content cache...")
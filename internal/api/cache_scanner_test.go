@@ -0,0 +1,26 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/providers"
+)
+
+func TestStreamInfoHash(t *testing.T) {
+	const hash = "0123456789abcdef0123456789abcdef01234567"
+	for _, tt := range []struct {
+		name   string
+		stream providers.TorrentioStream
+		want   string
+	}{
+		{"info hash", providers.TorrentioStream{InfoHash: hash, URL: "https://addon.example/x"}, hash},
+		{"resolve URL", providers.TorrentioStream{URL: "https://torrentio.example/resolve/realdebrid/KEY/" + hash + "/null/1/Movie.mkv"}, hash},
+		// A usenet link's token expires, and isn't a hash however long the path is
+		{"usenet", providers.TorrentioStream{URL: "/usenet/easynews/0123456789abcdef0123456789abcdef.mkv", Source: providers.UsenetSource}, ""},
+		{"direct link", providers.TorrentioStream{URL: "https://cdn.example/some/long/path/to/a/movie/file/Movie.2020.1080p.mkv"}, ""},
+	} {
+		if got := streamInfoHash(&tt.stream); got != tt.want {
+			t.Errorf("%s: streamInfoHash = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
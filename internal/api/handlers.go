@@ -15,14 +15,25 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/activity"
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/cache"
 	"github.com/Zerr0-C00L/StreamArr/internal/database"
 	"github.com/Zerr0-C00L/StreamArr/internal/epg"
+	"github.com/Zerr0-C00L/StreamArr/internal/hdhomerun"
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
 	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+	"github.com/Zerr0-C00L/StreamArr/internal/localmedia"
 	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/Zerr0-C00L/StreamArr/internal/netaccess"
+	"github.com/Zerr0-C00L/StreamArr/internal/notifications"
+	"github.com/Zerr0-C00L/StreamArr/internal/playlist"
 	"github.com/Zerr0-C00L/StreamArr/internal/providers"
 	"github.com/Zerr0-C00L/StreamArr/internal/services"
 	"github.com/Zerr0-C00L/StreamArr/internal/services/streams"
 	"github.com/Zerr0-C00L/StreamArr/internal/settings"
+	"github.com/Zerr0-C00L/StreamArr/internal/strmexport"
+	"github.com/Zerr0-C00L/StreamArr/internal/subtitles"
 	"github.com/gorilla/mux"
 )
 
@@ -53,6 +64,35 @@ type Handler struct {
 	streamCacheStore *database.StreamCacheStore
 	streamService    interface{} // Will be *streams.StreamService if initialized
 	cacheScanner     *CacheScanner
+	// Local media library (nil when disabled)
+	localMedia *localmedia.Library
+	// Playlist generator for manual regeneration
+	playlistGen *playlist.EnhancedGenerator
+	// Library export for Jellyfin/Emby/Kodi (nil when disabled)
+	strmExporter *strmexport.Exporter
+	// HDHomeRun tuner emulation (nil when disabled)
+	hdhrServer *hdhomerun.Server
+	// Per-user Stremio addon tokens, preferences and catalogs
+	stremioUserStore *database.StremioUserStore
+	// Resolved-link cache and play URL mapping for Stremio streams routed through StreamArr
+	linkCache    *cache.LinkCache
+	stremioLinks stremioLinkMap
+	// Subtitle search for Stremio, the web player and Xtream (nil when not configured)
+	subtitles *subtitles.Service
+	// Shared background job queue behind /api/v1/services
+	jobQueue *jobs.Queue
+	// Activity feed and audit log of destructive actions (nil disables recording)
+	activity *activity.Recorder
+	// Access/refresh tokens and API keys
+	authService *auth.Service
+	// Sign-in throttling and lockout
+	loginGuard *auth.LoginGuard
+	// Discord/Telegram alerts
+	notifier *notifications.Notifier
+	// OpenID Connect and reverse-proxy single sign-on
+	sso *auth.SSO
+	// Per-surface IP allow/deny lists and client address resolution (nil allows everything)
+	networkPolicy *netaccess.Policy
 }
 
 func NewHandler(
@@ -120,6 +160,77 @@ func NewHandlerWithComponents(
 	}
 }
 
+// SetLocalMediaLibrary enables manual local media scans
+func (h *Handler) SetLocalMediaLibrary(library *localmedia.Library) {
+	h.localMedia = library
+}
+
+// SetPlaylistGenerator enables manual playlist regeneration
+func (h *Handler) SetPlaylistGenerator(gen *playlist.EnhancedGenerator) {
+	h.playlistGen = gen
+}
+
+// SetStrmExporter enables manual .strm library export syncs
+func (h *Handler) SetStrmExporter(exporter *strmexport.Exporter) {
+	h.strmExporter = exporter
+}
+
+// SetHDHomeRunServer enables the virtual HDHomeRun tuner endpoints; tuners stream through the live TV proxy
+func (h *Handler) SetHDHomeRunServer(server *hdhomerun.Server) {
+	server.SetRelay(http.HandlerFunc(h.ProxyChannelStream))
+	h.hdhrServer = server
+}
+
+// SetLinkCache lets Stremio play URLs reuse resolved debrid links
+func (h *Handler) SetLinkCache(links *cache.LinkCache) {
+	h.linkCache = links
+}
+
+// SetSubtitleService enables subtitle search
+func (h *Handler) SetSubtitleService(service *subtitles.Service) {
+	h.subtitles = service
+}
+
+// SetJobQueue connects the background job queue
+func (h *Handler) SetJobQueue(queue *jobs.Queue) {
+	h.jobQueue = queue
+}
+
+// SetAuthService connects the token service behind sign-in, sessions and API keys
+func (h *Handler) SetAuthService(service *auth.Service) {
+	h.authService = service
+}
+
+// SetLoginGuard enables sign-in rate limiting and lockout
+func (h *Handler) SetLoginGuard(guard *auth.LoginGuard) {
+	h.loginGuard = guard
+}
+
+// SetNotifier enables alerts such as suspicious sign-ins
+func (h *Handler) SetNotifier(notifier *notifications.Notifier) {
+	h.notifier = notifier
+}
+
+// SetSSO enables single sign-on through OpenID Connect or an authenticating reverse proxy
+func (h *Handler) SetSSO(sso *auth.SSO) {
+	h.sso = sso
+}
+
+// SetNetworkPolicy enables the network access policy
+func (h *Handler) SetNetworkPolicy(policy *netaccess.Policy) {
+	h.networkPolicy = policy
+}
+
+// SetActivity enables the activity feed and the audit log of destructive actions
+func (h *Handler) SetActivity(recorder *activity.Recorder) {
+	h.activity = recorder
+}
+
+// SetStremioUserStore enables per-user Stremio addon tokens
+func (h *Handler) SetStremioUserStore(store *database.StremioUserStore) {
+	h.stremioUserStore = store
+}
+
 // Response helpers
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -273,6 +384,7 @@ func (h *Handler) AddMovie(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "failed to add movie")
 		return
 	}
+	h.recordLibrary(ctx, database.EventLibraryAdd, "movie", movie.ID, movie.TMDBID, movie.Title)
 
 	// Handle auto-add collection setting
 	shouldAddCollection := false
@@ -288,8 +400,8 @@ func (h *Handler) AddMovie(w http.ResponseWriter, r *http.Request) {
 		if isIPTVVODMovie(movie) {
 			log.Printf("[Collection Sync] Skipping auto-add for IPTV VOD movie %s", movie.Title)
 		} else {
-			// Use background context since request context will be canceled after response
-			go h.addCollectionMovies(context.Background(), collection.TMDBID, req.Monitored, req.QualityProfile)
+			// Request context will be canceled after response; keep its user for the activity feed
+			go h.addCollectionMovies(context.WithoutCancel(ctx), collection.TMDBID, req.Monitored, req.QualityProfile)
 		}
 	}
 
@@ -306,11 +418,11 @@ func (h *Handler) addCollectionMovies(ctx context.Context, collectionTMDBID int,
 	// Get collection details with all movie IDs
 	collection, movieIDs, err := h.tmdbClient.GetCollection(ctx, collectionTMDBID)
 	if err != nil {
-		fmt.Printf("[Collection Sync] Failed to get collection %d: %v\n", collectionTMDBID, err)
+		log.Printf("[Collection Sync] Failed to get collection %d: %v", collectionTMDBID, err)
 		return
 	}
 
-	fmt.Printf("[Collection Sync] Adding missing movies from '%s' (%d movies total)\n", collection.Name, len(movieIDs))
+	log.Printf("[Collection Sync] Adding missing movies from '%s' (%d movies total)", collection.Name, len(movieIDs))
 	added := 0
 
 	for _, tmdbID := range movieIDs {
@@ -338,17 +450,26 @@ func (h *Handler) addCollectionMovies(ctx context.Context, collectionTMDBID int,
 		}
 
 		if err := h.movieStore.Add(ctx, movie); err != nil {
-			fmt.Printf("[Collection Sync] Failed to add movie '%s': %v\n", movie.Title, err)
+			log.Printf("[Collection Sync] Failed to add movie '%s': %v", movie.Title, err)
 		} else {
 			added++
-			fmt.Printf("[Collection Sync] Added '%s' from '%s'\n", movie.Title, collection.Name)
+			log.Printf("[Collection Sync] Added '%s' from '%s'", movie.Title, collection.Name)
 		}
 
 		// Small delay to avoid hitting API rate limits
 		time.Sleep(250 * time.Millisecond)
 	}
 
-	fmt.Printf("[Collection Sync] Finished '%s': %d new movies added\n", collection.Name, added)
+	log.Printf("[Collection Sync] Finished '%s': %d new movies added", collection.Name, added)
+	if added > 0 {
+		h.activity.Record(ctx, activity.Event{
+			Type:        database.EventLibraryAdd,
+			ContentType: "collection",
+			ContentID:   collection.ID,
+			Message:     fmt.Sprintf("Added %d movies from collection %q", added, collection.Name),
+			Data:        map[string]interface{}{"tmdb_id": collectionTMDBID, "title": collection.Name, "added": added},
+		})
+	}
 }
 
 // scanAndLinkCollections scans all movies without a collection and checks if they belong to one
@@ -356,27 +477,29 @@ func (h *Handler) scanAndLinkCollections(ctx context.Context) error {
 	// Get only movies that haven't been checked yet
 	movies, err := h.movieStore.ListUncheckedForCollection(ctx)
 	if err != nil {
-		fmt.Printf("[Collection Sync] Failed to list unchecked movies: %v\n", err)
+		log.Printf("[Collection Sync] Failed to list unchecked movies: %v", err)
 		return err
 	}
 
 	totalMovies := len(movies)
 	if totalMovies == 0 {
-		fmt.Println("[Collection Sync] All movies have been checked for collections")
-		services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, 0, 0, "All movies already checked")
+		log.Println("[Collection Sync] All movies have been checked for collections")
+		jobs.Progress(ctx, 0, 0, "All movies already checked")
 		return nil
 	}
 
-	fmt.Printf("[Collection Sync] Starting scan of %d unchecked movies...\n", totalMovies)
-	services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, 0, totalMovies, "Scanning movies for collections...")
+	log.Printf("[Collection Sync] Starting scan of %d unchecked movies...", totalMovies)
+	jobs.Progress(ctx, 0, totalMovies, "Scanning movies for collections...")
 
 	linked := 0
 	noCollection := 0
 	errors := 0
 
 	for i, movie := range movies {
-		// Update progress
-		services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, i+1, totalMovies,
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		jobs.Progress(ctx, i+1, totalMovies,
 			fmt.Sprintf("Checking: %s", movie.Title))
 
 		// Fetch movie with collection info from TMDB
@@ -393,7 +516,7 @@ func (h *Handler) scanAndLinkCollections(ctx context.Context) error {
 			// Get full collection details
 			fullCollection, _, err := h.tmdbClient.GetCollection(ctx, collection.TMDBID)
 			if err != nil {
-				fmt.Printf("[Collection Sync] Failed to get collection %d for movie %s: %v\n", collection.TMDBID, movie.Title, err)
+				log.Printf("[Collection Sync] Failed to get collection %d for movie %s: %v", collection.TMDBID, movie.Title, err)
 				errors++
 				h.movieStore.MarkCollectionChecked(ctx, movie.ID)
 				continue
@@ -401,7 +524,7 @@ func (h *Handler) scanAndLinkCollections(ctx context.Context) error {
 
 			// Create or update collection in database
 			if err := h.collectionStore.Create(ctx, fullCollection); err != nil {
-				fmt.Printf("[Collection Sync] Failed to create collection %s: %v\n", fullCollection.Name, err)
+				log.Printf("[Collection Sync] Failed to create collection %s: %v", fullCollection.Name, err)
 				errors++
 				h.movieStore.MarkCollectionChecked(ctx, movie.ID)
 				continue
@@ -409,7 +532,7 @@ func (h *Handler) scanAndLinkCollections(ctx context.Context) error {
 
 			// Link movie to collection - fullCollection.ID is populated by Create's RETURNING clause
 			if err := h.collectionStore.UpdateMovieCollection(ctx, movie.ID, fullCollection.ID); err != nil {
-				fmt.Printf("[Collection Sync] Failed to link movie %s (ID:%d) to collection %s (ID:%d): %v\n",
+				log.Printf("[Collection Sync] Failed to link movie %s (ID:%d) to collection %s (ID:%d): %v",
 					movie.Title, movie.ID, fullCollection.Name, fullCollection.ID, err)
 				errors++
 				h.movieStore.MarkCollectionChecked(ctx, movie.ID)
@@ -417,10 +540,10 @@ func (h *Handler) scanAndLinkCollections(ctx context.Context) error {
 			}
 
 			linked++
-			fmt.Printf("[Collection Sync] Linked '%s' to '%s'\n", movie.Title, fullCollection.Name)
+			log.Printf("[Collection Sync] Linked '%s' to '%s'", movie.Title, fullCollection.Name)
 
 			// Update progress message with linked info
-			services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, i+1, totalMovies,
+			jobs.Progress(ctx, i+1, totalMovies,
 				fmt.Sprintf("Linked: %s → %s", movie.Title, fullCollection.Name))
 		} else {
 			noCollection++
@@ -431,7 +554,7 @@ func (h *Handler) scanAndLinkCollections(ctx context.Context) error {
 
 		// Progress log every 100 movies
 		if (i+1)%100 == 0 {
-			fmt.Printf("[Collection Sync] Progress: %d/%d movies processed, %d linked, %d no collection, %d errors\n",
+			log.Printf("[Collection Sync] Progress: %d/%d movies processed, %d linked, %d no collection, %d errors",
 				i+1, totalMovies, linked, noCollection, errors)
 		}
 
@@ -439,107 +562,14 @@ func (h *Handler) scanAndLinkCollections(ctx context.Context) error {
 		time.Sleep(200 * time.Millisecond)
 	}
 
-	services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, totalMovies, totalMovies,
+	jobs.Progress(ctx, totalMovies, totalMovies,
 		fmt.Sprintf("Complete: %d linked, %d no collection, %d errors", linked, noCollection, errors))
 
-	fmt.Printf("[Collection Sync] Complete: %d movies linked, %d have no collection, %d errors\n",
+	log.Printf("[Collection Sync] Complete: %d movies linked, %d have no collection, %d errors",
 		linked, noCollection, errors)
 	return nil
 }
 
-// scanEpisodesForAllSeries fetches episode metadata from TMDB for all series in the library
-func (h *Handler) scanEpisodesForAllSeries(ctx context.Context) error {
-	fmt.Println("[Episode Scan] Starting episode scan for all series...")
-	services.GlobalScheduler.UpdateProgress(services.ServiceEpisodeScan, 0, 0, "Loading series list...")
-
-	// Get all series from the library
-	allSeries, err := h.seriesStore.List(ctx, 0, 10000, nil)
-	if err != nil {
-		fmt.Printf("[Episode Scan] Failed to list series: %v\n", err)
-		return err
-	}
-
-	totalSeries := len(allSeries)
-	if totalSeries == 0 {
-		fmt.Println("[Episode Scan] No series in library")
-		services.GlobalScheduler.UpdateProgress(services.ServiceEpisodeScan, 0, 0, "No series in library")
-		return nil
-	}
-
-	fmt.Printf("[Episode Scan] Found %d series to scan\n", totalSeries)
-	services.GlobalScheduler.UpdateProgress(services.ServiceEpisodeScan, 0, totalSeries,
-		fmt.Sprintf("Scanning %d series...", totalSeries))
-
-	totalEpisodes := 0
-	seriesProcessed := 0
-	errors := 0
-
-	for i, series := range allSeries {
-		// Update progress
-		services.GlobalScheduler.UpdateProgress(services.ServiceEpisodeScan, i+1, totalSeries,
-			fmt.Sprintf("Scanning: %s", series.Title))
-
-		// Get series details from TMDB to get number of seasons
-		tmdbSeries, err := h.tmdbClient.GetSeries(ctx, series.TMDBID)
-		if err != nil {
-			fmt.Printf("[Episode Scan] Failed to get details for '%s' (TMDB:%d): %v\n", series.Title, series.TMDBID, err)
-			errors++
-			continue
-		}
-
-		numSeasons := tmdbSeries.Seasons
-		if numSeasons == 0 {
-			fmt.Printf("[Episode Scan] '%s' has 0 seasons, skipping\n", series.Title)
-			continue
-		}
-
-		// Get all episodes for this series
-		episodes, err := h.tmdbClient.GetEpisodes(ctx, series.ID, series.TMDBID, numSeasons)
-		if err != nil {
-			fmt.Printf("[Episode Scan] Failed to get episodes for '%s': %v\n", series.Title, err)
-			errors++
-			continue
-		}
-
-		// Set the series ID for all episodes
-		for _, ep := range episodes {
-			ep.SeriesID = series.ID
-			ep.Monitored = series.Monitored
-		}
-
-		// Add episodes to database (batch insert)
-		if len(episodes) > 0 {
-			if err := h.episodeStore.AddBatch(ctx, episodes); err != nil {
-				// Check if it's a duplicate error, if so, skip silently
-				if err.Error() != "" && !containsDuplicateError(err.Error()) {
-					fmt.Printf("[Episode Scan] Failed to add episodes for '%s': %v\n", series.Title, err)
-					errors++
-				}
-			} else {
-				totalEpisodes += len(episodes)
-				fmt.Printf("[Episode Scan] Added %d episodes for '%s'\n", len(episodes), series.Title)
-			}
-		}
-
-		seriesProcessed++
-
-		// Rate limit TMDB requests (40 per 10s limit)
-		time.Sleep(300 * time.Millisecond)
-	}
-
-	services.GlobalScheduler.UpdateProgress(services.ServiceEpisodeScan, totalSeries, totalSeries,
-		fmt.Sprintf("Complete: %d episodes from %d series", totalEpisodes, seriesProcessed))
-
-	fmt.Printf("[Episode Scan] Complete: %d episodes added from %d series (%d errors)\n",
-		totalEpisodes, seriesProcessed, errors)
-	return nil
-}
-
-// containsDuplicateError checks if an error message indicates a duplicate key violation
-func containsDuplicateError(errMsg string) bool {
-	return strings.Contains(errMsg, "duplicate key") || strings.Contains(errMsg, "UNIQUE constraint")
-}
-
 // UpdateMovie handles PUT /api/movies/{id}
 func (h *Handler) UpdateMovie(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -591,10 +621,14 @@ func (h *Handler) DeleteMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	movie, _ := h.movieStore.Get(ctx, id)
 	if err := h.movieStore.Delete(ctx, id); err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to delete movie")
 		return
 	}
+	if movie != nil {
+		h.recordLibrary(ctx, database.EventLibraryRemove, "movie", id, movie.TMDBID, movie.Title)
+	}
 
 	respondJSON(w, http.StatusOK, map[string]string{"message": "movie deleted"})
 }
@@ -904,7 +938,7 @@ func (h *Handler) GetMovieStreams(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[STREAM-FETCH] Movie %s (%s) release year: %d", movie.Title, imdbID, releaseYear)
 
 	// Use the new year-aware method
-	providerStreams, err := h.streamProvider.GetMovieStreamsWithYear(imdbID, releaseYear)
+	providerStreams, err := h.streamProvider.GetMovieStreamsWithYear(r.Context(), imdbID, releaseYear)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get streams for movie %d (%s, %s): %v", id, movie.Title, imdbID, err)
 		respondJSON(w, http.StatusOK, []interface{}{}) // Return empty array instead of error
@@ -964,7 +998,8 @@ func (h *Handler) GetMovieStreams(w http.ResponseWriter, r *http.Request) {
 		// Find the best cached stream
 		var bestCached *providers.TorrentioStream
 		for i := range providerStreams {
-			if providerStreams[i].Cached {
+			// Usenet links expire and indexer results without a URL are resolved at play time
+			if providerStreams[i].Cached && providerStreams[i].URL != "" && streamInfoHash(&providerStreams[i]) != "" {
 				bestCached = &providerStreams[i]
 				log.Printf("[CACHE-PHASE1] Found best cached stream: %s (source: %s, hash: %s)",
 					bestCached.Name, bestCached.Source, bestCached.InfoHash)
@@ -973,22 +1008,12 @@ func (h *Handler) GetMovieStreams(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if bestCached != nil {
-			// Extract hash from URL if InfoHash is empty (Torrentio case)
-			hash := bestCached.InfoHash
+			// Torrentio URLs contain the hash in the path when InfoHash is empty
+			// Example: /resolve/realdebrid/APIKEY/HASH/null/1/filename.mp4
+			hash := streamInfoHash(bestCached)
 			// For Torrentio streams, Title contains the actual filename (set in stremio_generic.go)
 			// Name contains the formatted display text like "[RD+] Torrentio\n1080p"
 			torrentName := bestCached.Title
-			if hash == "" && bestCached.URL != "" {
-				// Torrentio URLs contain the hash in the path
-				// Example: /resolve/realdebrid/APIKEY/HASH/null/1/filename.mp4
-				parts := strings.Split(bestCached.URL, "/")
-				for _, part := range parts {
-					if len(part) == 40 { // Torrent hashes are 40 chars
-						hash = part
-						break
-					}
-				}
-			}
 
 			log.Printf("[CACHE-PHASE1] Processing stream for caching: hash=%s, torrentName=%s",
 				hash, torrentName)
@@ -1245,7 +1270,7 @@ func (h *Handler) GetEpisodeStreams(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Fetching streams for series %s S%02dE%02d", imdbID, season, episode)
-	providerStreams, err := h.streamProvider.GetSeriesStreams(imdbID, season, episode)
+	providerStreams, err := h.streamProvider.GetSeriesStreams(r.Context(), imdbID, season, episode)
 	if err != nil {
 		log.Printf("Failed to get streams for series %s S%02dE%02d: %v", imdbID, season, episode, err)
 		respondJSON(w, http.StatusOK, []interface{}{}) // Return empty array instead of error
@@ -1335,6 +1360,7 @@ func (h *Handler) PlayMovie(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Playing movie %s - selected stream: %s (Quality: %s)", movie.Title, stream.Title, stream.Quality)
+	h.recordPlayback(r, "movie", movie.ID, movie.Title)
 
 	respondJSON(w, http.StatusOK, map[string]string{
 		"stream_url": streamURL,
@@ -1465,6 +1491,7 @@ func (h *Handler) AddSeries(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, fmt.Sprintf("failed to add series: %v", err))
 		return
 	}
+	h.recordLibrary(ctx, database.EventLibraryAdd, "series", series.ID, series.TMDBID, series.Title)
 
 	respondJSON(w, http.StatusCreated, series)
 }
@@ -1523,10 +1550,14 @@ func (h *Handler) DeleteSeries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	series, _ := h.seriesStore.Get(ctx, id)
 	if err := h.seriesStore.Delete(ctx, id); err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to delete series")
 		return
 	}
+	if series != nil {
+		h.recordLibrary(ctx, database.EventLibraryRemove, "series", id, series.TMDBID, series.Title)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -1787,7 +1818,7 @@ func (h *Handler) PlayEpisode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	streamURL, err := h.rdClient.GetStreamURL(ctx, stream.InfoHash)
+	streamURL, err := h.rdClient.GetEpisodeStreamURL(ctx, stream.InfoHash, episode.SeasonNumber, episode.EpisodeNumber)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to get stream URL")
 		return
@@ -1799,6 +1830,7 @@ func (h *Handler) PlayEpisode(w http.ResponseWriter, r *http.Request) {
 	h.episodeStore.UpdateAvailability(ctx, episode.ID, true, &streamURL)
 
 	log.Printf("Playing episode S%02dE%02d - selected stream: %s (Quality: %s)", episode.SeasonNumber, episode.EpisodeNumber, stream.Title, stream.Quality)
+	h.recordPlayback(r, "series", series.ID, fmt.Sprintf("%s S%02dE%02d", series.Title, episode.SeasonNumber, episode.EpisodeNumber))
 
 	respondJSON(w, http.StatusOK, map[string]string{
 		"stream_url": streamURL,
@@ -1852,6 +1884,48 @@ func (h *Handler) SearchSeries(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, results)
 }
 
+// filterEnabledChannels applies the Live TV source and category selection from settings
+func (h *Handler) filterEnabledChannels(channels []*livetv.Channel) []*livetv.Channel {
+	if h.settingsManager == nil {
+		return channels
+	}
+	settings := h.settingsManager.Get()
+
+	// Filter by enabled sources (if any are specified)
+	if len(settings.LiveTVEnabledSources) > 0 {
+		enabledSourcesMap := make(map[string]bool)
+		for _, s := range settings.LiveTVEnabledSources {
+			enabledSourcesMap[s] = true
+		}
+
+		filtered := make([]*livetv.Channel, 0, len(channels))
+		for _, ch := range channels {
+			if enabledSourcesMap[ch.Source] {
+				filtered = append(filtered, ch)
+			}
+		}
+		channels = filtered
+	}
+
+	// Filter by enabled categories (if any are specified)
+	if len(settings.LiveTVEnabledCategories) > 0 {
+		enabledCategoriesMap := make(map[string]bool)
+		for _, c := range settings.LiveTVEnabledCategories {
+			enabledCategoriesMap[c] = true
+		}
+
+		filtered := make([]*livetv.Channel, 0, len(channels))
+		for _, ch := range channels {
+			if enabledCategoriesMap[ch.Category] {
+				filtered = append(filtered, ch)
+			}
+		}
+		channels = filtered
+	}
+
+	return channels
+}
+
 // ListChannels handles GET /api/channels
 func (h *Handler) ListChannels(w http.ResponseWriter, r *http.Request) {
 	if h.channelManager == nil {
@@ -1874,41 +1948,7 @@ func (h *Handler) ListChannels(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Apply source and category filters from settings
-	if h.settingsManager != nil {
-		settings := h.settingsManager.Get()
-
-		// Filter by enabled sources (if any are specified)
-		if len(settings.LiveTVEnabledSources) > 0 {
-			enabledSourcesMap := make(map[string]bool)
-			for _, s := range settings.LiveTVEnabledSources {
-				enabledSourcesMap[s] = true
-			}
-
-			filtered := make([]*livetv.Channel, 0, len(channels))
-			for _, ch := range channels {
-				if enabledSourcesMap[ch.Source] {
-					filtered = append(filtered, ch)
-				}
-			}
-			channels = filtered
-		}
-
-		// Filter by enabled categories (if any are specified)
-		if len(settings.LiveTVEnabledCategories) > 0 {
-			enabledCategoriesMap := make(map[string]bool)
-			for _, c := range settings.LiveTVEnabledCategories {
-				enabledCategoriesMap[c] = true
-			}
-
-			filtered := make([]*livetv.Channel, 0, len(channels))
-			for _, ch := range channels {
-				if enabledCategoriesMap[ch.Category] {
-					filtered = append(filtered, ch)
-				}
-			}
-			channels = filtered
-		}
-	}
+	channels = h.filterEnabledChannels(channels)
 
 	// Create enriched response with EPG data
 	type EnrichedChannel struct {
@@ -2140,18 +2180,18 @@ func (h *Handler) ProxyChannelStream(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Proxying stream: %s", streamURL)
 
-	// Create HTTP client with timeout
+	// Create HTTP client; live streams never end, so only the wait for the upstream's headers is limited
 	client := &http.Client{
-		Timeout: 30 * time.Second,
 		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 100,
-			IdleConnTimeout:     90 * time.Second,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   100,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
 		},
 	}
 
-	// Create request to stream server
-	req, err := http.NewRequest("GET", streamURL, nil)
+	// Create request to stream server; it ends when the client goes away
+	req, err := http.NewRequestWithContext(r.Context(), "GET", streamURL, nil)
 	if err != nil {
 		log.Printf("Failed to create request: %v", err)
 		http.Error(w, "failed to create request", http.StatusInternalServerError)
@@ -2388,9 +2428,8 @@ func (h *Handler) GetChannelEPG(w http.ResponseWriter, r *http.Request) {
 // GetSettings handles GET /api/settings
 func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
 	if h.settingsManager != nil {
-		// Use new settings manager
-		settings := h.settingsManager.Get()
-		respondJSON(w, http.StatusOK, settings)
+		// Use new settings manager; secrets are write-only
+		respondJSON(w, http.StatusOK, h.settingsManager.Get().Masked())
 		return
 	}
 
@@ -2423,6 +2462,7 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusInternalServerError, "failed to update settings")
 			return
 		}
+		h.recordSettingsChange(r.Context(), oldSettings, h.settingsManager.Get())
 
 		// Log playlist filter changes
 		if oldSettings.OnlyCachedStreams != newSettings.OnlyCachedStreams {
@@ -2516,13 +2556,11 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 
 		// Trigger MDBList sync in background when MDBList lists are configured
 		if h.mdbSyncService != nil && newSettings.MDBListLists != "" && newSettings.MDBListLists != "[]" {
-			go func() {
-				log.Println("[Settings] MDBList lists updated, triggering sync...")
-				h.runService(services.ServiceMDBListSync)
-			}()
+			log.Println("[Settings] MDBList lists updated, triggering sync...")
+			h.triggerJob(r.Context(), services.ServiceMDBListSync)
 		}
 
-		respondJSON(w, http.StatusOK, newSettings)
+		respondJSON(w, http.StatusOK, newSettings.Masked())
 		return
 	}
 
@@ -2857,8 +2895,8 @@ func (h *Handler) AddCollectionByTMDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Start background sync to add all movies (use background context since request context will be canceled)
-	go h.addCollectionMovies(context.Background(), collection.TMDBID, req.Monitored, req.QualityProfile)
+	// Start background sync to add all movies (request context will be canceled; keep its user for the activity feed)
+	go h.addCollectionMovies(context.WithoutCancel(ctx), collection.TMDBID, req.Monitored, req.QualityProfile)
 
 	respondJSON(w, http.StatusCreated, map[string]interface{}{
 		"message":    "Collection added successfully",
@@ -2898,8 +2936,8 @@ func (h *Handler) SyncCollection(w http.ResponseWriter, r *http.Request) {
 		req.QualityProfile = "default"
 	}
 
-	// Start background sync (use background context since request context will be canceled after response)
-	go h.addCollectionMovies(context.Background(), collection.TMDBID, req.Monitored, req.QualityProfile)
+	// Start background sync (request context will be canceled after response; keep its user for the activity feed)
+	go h.addCollectionMovies(context.WithoutCancel(ctx), collection.TMDBID, req.Monitored, req.QualityProfile)
 
 	respondJSON(w, http.StatusAccepted, map[string]string{
 		"message": "Collection sync started",
@@ -3204,230 +3242,6 @@ func (h *Handler) BrowseCollections(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetServices handles GET /api/services - returns status of all background services
-func (h *Handler) GetServices(w http.ResponseWriter, r *http.Request) {
-	statuses := services.GlobalScheduler.GetAllStatus()
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"services": statuses,
-	})
-}
-
-// TriggerService handles POST /api/services/{name}/trigger - manually triggers a service
-func (h *Handler) TriggerService(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	serviceName := vars["name"]
-	if serviceName == "" {
-		respondError(w, http.StatusBadRequest, "service name required")
-		return
-	}
-
-	status := services.GlobalScheduler.GetStatus(serviceName)
-	if status == nil {
-		respondError(w, http.StatusNotFound, "service not found")
-		return
-	}
-
-	if status.Running {
-		respondError(w, http.StatusConflict, "service is already running")
-		return
-	}
-
-	// Trigger the service based on name
-	go h.runService(serviceName)
-
-	respondJSON(w, http.StatusAccepted, map[string]string{
-		"message": "Service triggered",
-		"service": serviceName,
-		"status":  "running",
-	})
-}
-
-// runService executes a specific service
-func (h *Handler) runService(serviceName string) {
-	ctx := context.Background()
-	services.GlobalScheduler.MarkRunning(serviceName)
-
-	var err error
-	var interval time.Duration
-
-	switch serviceName {
-	case services.ServiceEPGUpdate:
-		interval = 6 * time.Hour
-		if h.channelManager != nil && h.epgManager != nil {
-			channels := h.channelManager.GetAllChannels()
-			channelList := make([]livetv.Channel, len(channels))
-			for i, ch := range channels {
-				channelList[i] = *ch
-			}
-			err = h.epgManager.UpdateEPG(channelList)
-		}
-
-	case services.ServiceChannelRefresh:
-		interval = 1 * time.Hour
-		if h.channelManager != nil {
-			err = h.channelManager.LoadChannels()
-		}
-
-	case services.ServiceCacheCleanup:
-		interval = 1 * time.Hour
-		// Cache cleanup would be handled by cache manager
-		// For now just mark as complete
-
-	case services.ServicePlaylist:
-		interval = 12 * time.Hour
-		// Playlist generation requires the playlist generator
-		// For now just mark as complete
-
-	case services.ServiceMDBListSync:
-		interval = 6 * time.Hour
-		if h.mdbSyncService != nil {
-			services.GlobalScheduler.UpdateProgress(services.ServiceMDBListSync, 0, 0, "Starting MDBList sync...")
-			err = h.mdbSyncService.SyncAllLists(ctx)
-			if err == nil {
-				// Also enrich existing items
-				_ = h.mdbSyncService.EnrichExistingItems(ctx)
-			}
-		} else {
-			services.GlobalScheduler.UpdateProgress(services.ServiceMDBListSync, 0, 0, "MDBList sync service not initialized")
-		}
-
-	case services.ServiceCollectionSync:
-		interval = 24 * time.Hour
-		// Phase 1: Scan existing movies for collections and link them
-		if h.collectionStore != nil && h.movieStore != nil {
-			err = h.scanAndLinkCollections(ctx)
-		}
-		// Phase 2: Sync incomplete collections (add missing movies)
-		if h.collectionStore != nil && h.settingsManager != nil {
-			settings := h.settingsManager.Get()
-			if settings.AutoAddCollections {
-				fmt.Println("[Collection Sync] Phase 2: Adding missing movies from incomplete collections...")
-				services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, 0, 0, "Phase 2: Checking incomplete collections...")
-
-				collections, _, _ := h.collectionStore.GetCollectionsWithProgress(ctx, 1000, 0)
-				fmt.Printf("[Collection Sync] Found %d collections to check\n", len(collections))
-
-				// Find incomplete collections; treat unknown totals (0) as incomplete to force refresh
-				var incompleteColls []*models.Collection
-				for _, coll := range collections {
-					if coll.TotalMovies == 0 || coll.MoviesInLibrary < coll.TotalMovies {
-						incompleteColls = append(incompleteColls, coll)
-					}
-				}
-
-				totalIncomplete := len(incompleteColls)
-				if totalIncomplete == 0 {
-					fmt.Println("[Collection Sync] Phase 2: All collections are complete!")
-					services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, 0, 0, "All collections complete!")
-				} else {
-					fmt.Printf("[Collection Sync] Found %d incomplete collections\n", totalIncomplete)
-
-					for i, coll := range incompleteColls {
-						services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, i+1, totalIncomplete,
-							fmt.Sprintf("Adding movies to: %s (%d/%d)", coll.Name, coll.MoviesInLibrary, coll.TotalMovies))
-
-						// Skip auto-adding collections that are seeded only by IPTV VOD imports
-						if h.collectionStore != nil {
-							if movies, err := h.collectionStore.GetMoviesInCollection(ctx, coll.ID); err == nil {
-								if !collectionHasNonIPTVVOD(movies) {
-									fmt.Printf("[Collection Sync] Skipping collection '%s' (only IPTV VOD items)\n", coll.Name)
-									continue
-								}
-							}
-						}
-
-						fmt.Printf("[Collection Sync] '%s' is incomplete (%d/%d), adding missing movies...\n",
-							coll.Name, coll.MoviesInLibrary, coll.TotalMovies)
-						h.addCollectionMovies(ctx, coll.TMDBID, true, "default")
-						time.Sleep(500 * time.Millisecond) // Rate limit
-					}
-
-					services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, totalIncomplete, totalIncomplete,
-						fmt.Sprintf("Phase 2 complete: %d collections updated", totalIncomplete))
-					fmt.Printf("[Collection Sync] Phase 2 complete: processed %d incomplete collections\n", totalIncomplete)
-				}
-			} else {
-				fmt.Println("[Collection Sync] Phase 2 skipped: AutoAddCollections is disabled")
-				services.GlobalScheduler.UpdateProgress(services.ServiceCollectionSync, 0, 0, "Phase 2 skipped (AutoAddCollections disabled)")
-			}
-		} else {
-			fmt.Println("[Collection Sync] Phase 2 skipped: collectionStore or settingsManager is nil")
-		}
-
-	case services.ServiceEpisodeScan:
-		interval = 24 * time.Hour
-		if h.seriesStore != nil && h.episodeStore != nil && h.tmdbClient != nil {
-			err = h.scanEpisodesForAllSeries(ctx)
-		} else {
-			fmt.Println("[Episode Scan] Skipped: required stores not initialized")
-		}
-
-	case services.ServiceIPTVVODSync:
-		interval = 12 * time.Hour
-		// Only run when VOD mode is enabled and TMDB key is present
-		if h.settingsManager != nil && h.tmdbClient != nil && h.movieStore != nil && h.seriesStore != nil {
-			current := h.settingsManager.Get()
-			mode := strings.ToLower(current.IPTVImportMode)
-			includesVOD := mode == "vod_only" || mode == "both"
-			if includesVOD && current.TMDBAPIKey != "" {
-				_, err = services.ImportIPTVVOD(ctx, current, h.tmdbClient, h.movieStore, h.seriesStore)
-				_ = services.CleanupIPTVVOD(ctx, current, h.movieStore, h.seriesStore)
-			} else {
-				err = nil
-			}
-		}
-
-	case services.ServiceBalkanVODSync:
-		interval = 24 * time.Hour
-		if h.settingsManager != nil && h.tmdbClient != nil && h.movieStore != nil && h.seriesStore != nil {
-			current := h.settingsManager.Get()
-			if current.BalkanVODEnabled {
-				log.Println("[BalkanVOD] Manual trigger: Starting import...")
-				importer := services.NewBalkanVODImporter(h.movieStore, h.seriesStore, h.tmdbClient, current)
-				err = importer.ImportBalkanVOD(ctx)
-				if err != nil {
-					log.Printf("[BalkanVOD] Import error: %v", err)
-				} else {
-					log.Println("[BalkanVOD] Import completed successfully")
-				}
-			} else {
-				log.Println("[BalkanVOD] Import disabled in settings")
-				err = nil
-			}
-		}
-
-	default:
-		interval = 1 * time.Hour
-	}
-
-	services.GlobalScheduler.MarkComplete(serviceName, err, interval)
-}
-
-// UpdateServiceEnabled handles PUT /api/services/{name} - enables/disables a service
-func (h *Handler) UpdateServiceEnabled(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	serviceName := vars["name"]
-	if serviceName == "" {
-		respondError(w, http.StatusBadRequest, "service name required")
-		return
-	}
-
-	var req struct {
-		Enabled bool `json:"enabled"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-
-	services.GlobalScheduler.SetEnabled(serviceName, req.Enabled)
-
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"service": serviceName,
-		"enabled": req.Enabled,
-	})
-}
-
 // GetDatabaseStats handles GET /api/database/stats
 func (h *Handler) GetDatabaseStats(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
@@ -3603,7 +3417,7 @@ func (h *Handler) ExecuteDatabaseAction(w http.ResponseWriter, r *http.Request)
 					err = h.movieStore.ResetCollectionChecked(ctx)
 				}
 				// Trigger collection sync service
-				go h.runService(services.ServiceCollectionSync)
+				h.triggerJob(ctx, services.ServiceCollectionSync)
 				message = "Collections cleared and re-sync triggered"
 			}
 		}
@@ -4069,6 +3883,8 @@ func (h *Handler) ImportAdultVOD(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.recordImport(ctx, "adult_vod", fmt.Sprintf("Adult VOD import: %d imported, %d skipped, %d errors", imported, skipped, errs),
+		map[string]interface{}{"imported": imported, "skipped": skipped, "errors": errs})
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success":  true,
 		"imported": imported,
@@ -4260,6 +4076,7 @@ func (h *Handler) PreviewM3UCategories(w http.ResponseWriter, r *http.Request) {
 // PreviewXtreamCategories handles POST /api/v1/iptv-vod/preview-xtream-categories
 func (h *Handler) PreviewXtreamCategories(w http.ResponseWriter, r *http.Request) {
 	var req struct {
+		ID         string `json:"id"` // Saved source whose password is sent masked
 		ServerURL  string `json:"server_url"`
 		Username   string `json:"username"`
 		Password   string `json:"password"`
@@ -4271,6 +4088,16 @@ func (h *Handler) PreviewXtreamCategories(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Saved sources only have their masked password in the browser
+	if req.Password == settings.SecretMask && h.settingsManager != nil {
+		req.Password = ""
+		for _, src := range h.settingsManager.Get().XtreamSources {
+			if src.ID == req.ID || (req.ID == "" && src.ServerURL == req.ServerURL && src.Username == req.Username) {
+				req.Password = src.Password
+			}
+		}
+	}
+
 	if req.ServerURL == "" || req.Username == "" || req.Password == "" {
 		respondError(w, http.StatusBadRequest, "Server URL, username, and password are required")
 		return
@@ -4535,6 +4362,8 @@ func (h *Handler) ImportIPTVVOD(w http.ResponseWriter, r *http.Request) {
 	summary, err := services.ImportIPTVVOD(ctx, cfg, h.tmdbClient, h.movieStore, h.seriesStore)
 	if err != nil {
 		log.Printf("[IPTV VOD Import] error: %v", err)
+	} else {
+		h.recordIPTVImport(ctx, summary)
 	}
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success":         err == nil,
@@ -4980,9 +4809,19 @@ func (h *Handler) RemoveAndBlacklist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordLibrary(ctx, database.EventLibraryRemove, itemType, id, tmdbID, title)
+
 	// Add to blacklist
 	if err := h.blacklistStore.Add(ctx, tmdbID, itemType, title, req.Reason); err != nil {
 		log.Printf("Warning: Failed to add to blacklist: %v", err)
+	} else {
+		h.activity.Record(ctx, activity.Event{
+			Type:        database.EventBlacklist,
+			ContentType: itemType,
+			ContentID:   id,
+			Message:     fmt.Sprintf("Blacklisted %s %q", itemType, title),
+			Data:        map[string]interface{}{"action": "add", "tmdb_id": tmdbID, "title": title, "reason": req.Reason},
+		})
 	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -5033,6 +4872,13 @@ func (h *Handler) RemoveFromBlacklist(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove from blacklist: %v", err))
 		return
 	}
+	h.activity.Record(ctx, activity.Event{
+		Type:        database.EventBlacklist,
+		ContentType: "blacklist",
+		ContentID:   id,
+		Message:     fmt.Sprintf("Removed blacklist entry %d", id),
+		Data:        map[string]interface{}{"action": "remove"},
+	})
 
 	respondJSON(w, http.StatusOK, map[string]string{
 		"message": "Removed from blacklist",
@@ -5047,6 +4893,12 @@ func (h *Handler) ClearBlacklist(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to clear blacklist: %v", err))
 		return
 	}
+	h.activity.Record(ctx, activity.Event{
+		Type:        database.EventBlacklist,
+		ContentType: "blacklist",
+		Message:     "Cleared the blacklist",
+		Data:        map[string]interface{}{"action": "clear"},
+	})
 
 	respondJSON(w, http.StatusOK, map[string]string{
 		"message": "Blacklist cleared",
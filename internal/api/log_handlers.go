@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/logging"
+)
+
+// logStreamHeartbeat is how often an idle log stream sends a comment, so proxies keep it open
+const logStreamHeartbeat = 30 * time.Second
+
+// logFilter reads the log filters from the query string: level (minimum; every line kept by
+// default), subsystem (comma-separated), q, since and until (RFC 3339 or YYYY-MM-DD)
+func logFilter(r *http.Request) (logging.Filter, error) {
+	query := r.URL.Query()
+	filter := logging.Filter{Level: slog.LevelDebug, Search: strings.TrimSpace(query.Get("q"))}
+	var err error
+	if v := query.Get("level"); v != "" {
+		if filter.Level, err = logging.ParseLevel(v); err != nil {
+			return filter, err
+		}
+	}
+	for _, subsystem := range strings.Split(query.Get("subsystem"), ",") {
+		if subsystem = strings.ToLower(strings.TrimSpace(subsystem)); subsystem != "" {
+			filter.Subsystems = append(filter.Subsystems, subsystem)
+		}
+	}
+	if filter.Since, err = parseActivityTime(query.Get("since"), false); err != nil {
+		return filter, fmt.Errorf("invalid since")
+	}
+	if filter.Until, err = parseActivityTime(query.Get("until"), true); err != nil {
+		return filter, fmt.Errorf("invalid until")
+	}
+	return filter, nil
+}
+
+// GetLogs handles GET /api/v1/logs: the most recent log lines kept in memory, oldest first, with
+// the logFilter filters; limit defaults to 200
+func (h *Handler) GetLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := logFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 5000 {
+		limit = 200
+	}
+	entries := logging.Recent().Query(filter, limit)
+	if entries == nil {
+		entries = []*logging.Entry{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"logs":       entries,
+		"limit":      limit,
+		"subsystems": logging.Subsystems(),
+	})
+}
+
+// StreamLogs handles GET /api/v1/logs/stream: new log lines as server-sent events, with the
+// logFilter filters. It starts with the last tail lines (default 100), or the lines after
+// Last-Event-ID when an EventSource reconnects.
+func (h *Handler) StreamLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := logFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	// Subscribe before reading the backlog, so no line falls between the two
+	buffer := logging.Recent()
+	lines, cancel := buffer.Subscribe()
+	defer cancel()
+
+	tail := 100
+	if v := r.URL.Query().Get("tail"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			tail = n
+		}
+	}
+	var backlog []*logging.Entry
+	// An ID beyond the newest line was handed out before a restart, so it is treated like a fresh start
+	if lastID, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64); err == nil && lastID <= buffer.LastID() {
+		filter.AfterID = lastID
+		backlog = buffer.Query(filter, 0)
+	} else if tail > 0 {
+		backlog = buffer.Query(filter, tail)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// The stream stays open far longer than the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	var lastSent int64
+	send := func(e *logging.Entry) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil
+		}
+		lastSent = e.ID
+		_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.ID, data)
+		return err
+	}
+	for _, e := range backlog {
+		if send(e) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e := <-lines:
+			if e.ID <= lastSent || !filter.Match(e) {
+				continue
+			}
+			if send(e) != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// GetLogLevels handles GET /api/v1/logs/levels: the default level, the per-subsystem overrides
+// and the subsystems that can be given a level of their own
+func (h *Handler) GetLogLevels(w http.ResponseWriter, r *http.Request) {
+	if h.settingsManager == nil {
+		respondError(w, http.StatusServiceUnavailable, "settings not available")
+		return
+	}
+	current := h.settingsManager.Get()
+	levels := current.LogLevels
+	if levels == nil {
+		levels = map[string]string{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"level":      current.LogLevel,
+		"debug":      current.Debug,
+		"levels":     levels,
+		"subsystems": logging.Subsystems(),
+	})
+}
+
+// UpdateLogLevels handles PUT /api/v1/logs/levels with {"level": "info", "levels": {"providers": "debug"}}.
+// levels replaces every override, so a subsystem left out goes back to the default level;
+// a missing level keeps the current default. Changes apply immediately.
+func (h *Handler) UpdateLogLevels(w http.ResponseWriter, r *http.Request) {
+	if h.settingsManager == nil {
+		respondError(w, http.StatusServiceUnavailable, "settings not available")
+		return
+	}
+	var req struct {
+		Level  *string           `json:"level"`
+		Levels map[string]string `json:"levels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	before := h.settingsManager.Get()
+	updated := *before
+	if req.Level != nil {
+		level, err := logging.ParseLevel(*req.Level)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updated.LogLevel = logging.LevelName(level)
+	}
+	if req.Levels != nil {
+		updated.LogLevels = make(map[string]string, len(req.Levels))
+		for subsystem, name := range req.Levels {
+			subsystem = strings.ToLower(strings.TrimSpace(subsystem))
+			level, err := logging.ParseLevel(name)
+			if err != nil || subsystem == "" || strings.TrimSpace(name) == "" {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid level %q for subsystem %q", name, subsystem))
+				return
+			}
+			updated.LogLevels[subsystem] = logging.LevelName(level)
+		}
+	}
+
+	if err := h.settingsManager.Update(&updated); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to update log levels")
+		return
+	}
+	h.recordSettingsChange(r.Context(), before, h.settingsManager.Get())
+	logging.Refresh()
+	h.GetLogLevels(w, r)
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginFailed counts a failed sign-in towards the IP's and account's lockout and records it
+func (h *Handler) loginFailed(r *http.Request, userID int, username, reason string) {
+	h.loginGuard.Failure(auth.ClientIP(r), username)
+	h.recordLogin(r, database.LoginSourceWeb, userID, username, reason)
+}
+
+// recordLogin adds a sign-in to the login history and alerts when a successful one looks suspicious
+func (h *Handler) recordLogin(r *http.Request, source string, userID int, username, failure string) {
+	attempt := &database.LoginAttempt{
+		Username:      username,
+		Source:        source,
+		IP:            auth.ClientIP(r),
+		UserAgent:     r.UserAgent(),
+		Success:       failure == "",
+		FailureReason: failure,
+	}
+	if userID != 0 {
+		attempt.UserID = &userID
+	}
+
+	reasons := h.authService.RecordLogin(context.WithoutCancel(r.Context()), attempt)
+	if len(reasons) == 0 {
+		return
+	}
+	why := strings.Join(reasons, "; ")
+	log.Printf("[AUTH] ⚠️ Suspicious sign-in by %s from %s: %s", username, attempt.IP, why)
+	h.notifier.Notify("Suspicious sign-in",
+		fmt.Sprintf("%s signed in from %s (%s): %s", username, attempt.IP, attempt.UserAgent, why))
+}
+
+// respondTooManyAttempts rejects a throttled or locked-out sign-in
+func respondTooManyAttempts(w http.ResponseWriter, wait time.Duration, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondError(w, http.StatusTooManyRequests, err.Error())
+}
+
+// GetTwoFactorStatus handles GET /api/v1/auth/2fa
+func (h *Handler) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	status, err := h.authService.TwoFactorStatus(r.Context(), claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+// SetupTwoFactor handles POST /api/v1/auth/2fa/setup: returns a new secret for the authenticator app
+func (h *Handler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	secret, uri, err := h.authService.BeginTOTP(r.Context(), claims.UserID, claims.Username)
+	if err == auth.ErrTwoFactorEnabled {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{
+		"secret":      secret,
+		"otpauth_url": uri,
+	})
+}
+
+// EnableTwoFactor handles POST /api/v1/auth/2fa/enable: confirms a code from the app and returns
+// the recovery codes, which are only shown once
+func (h *Handler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		respondError(w, http.StatusBadRequest, "code required")
+		return
+	}
+
+	codes, err := h.authService.EnableTOTP(r.Context(), claims.UserID, req.Code)
+	switch {
+	case err == auth.ErrInvalidCode:
+		respondError(w, http.StatusBadRequest, "invalid code, check your device's clock")
+		return
+	case err == auth.ErrTwoFactorEnabled:
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("[AUTH] %s enabled two-factor authentication", claims.Username)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor handles POST /api/v1/auth/2fa/disable; needs the password and a current code
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		respondError(w, http.StatusBadRequest, "password and code required")
+		return
+	}
+	if !h.confirmSecondFactor(w, r, claims, req.Password, req.Code) {
+		return
+	}
+
+	if err := h.authService.DisableTOTP(r.Context(), claims.UserID); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf("[AUTH] %s disabled two-factor authentication", claims.Username)
+	h.notifier.Notify("Two-factor authentication disabled",
+		fmt.Sprintf("%s turned off two-factor authentication from %s", claims.Username, auth.ClientIP(r)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles POST /api/v1/auth/2fa/recovery-codes; the old codes stop working
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		respondError(w, http.StatusBadRequest, "password and code required")
+		return
+	}
+	if !h.confirmSecondFactor(w, r, claims, req.Password, req.Code) {
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// confirmSecondFactor re-checks the caller's password and second factor before a sensitive change,
+// counting failures like sign-in attempts. It writes the error response and returns false when they don't match.
+func (h *Handler) confirmSecondFactor(w http.ResponseWriter, r *http.Request, claims *auth.Claims, password, code string) bool {
+	if wait, err := h.loginGuard.Allow(auth.ClientIP(r), claims.Username); err != nil {
+		respondTooManyAttempts(w, wait, err)
+		return false
+	}
+	user, err := h.userStore.GetUserByID(claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "user not found")
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		h.loginGuard.Failure(auth.ClientIP(r), claims.Username)
+		// 403, not 401: the session is fine, and clients renew sessions on 401
+		respondError(w, http.StatusForbidden, "password is incorrect")
+		return false
+	}
+
+	_, err = h.authService.VerifySecondFactor(r.Context(), claims.UserID, code)
+	switch {
+	case err == auth.ErrInvalidCode:
+		h.loginGuard.Failure(auth.ClientIP(r), claims.Username)
+		respondError(w, http.StatusForbidden, "invalid code")
+		return false
+	case err == auth.ErrTwoFactorNotEnabled:
+		respondError(w, http.StatusConflict, err.Error())
+		return false
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return true
+}
+
+// ResetUserTwoFactor handles DELETE /api/v1/auth/2fa/users/{id}: turns off 2FA for a user who lost
+// their device and recovery codes
+func (h *Handler) ResetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	if _, err := h.userStore.GetUserByID(id); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := h.authService.DisableTOTP(r.Context(), id); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetLoginHistory handles GET /api/v1/auth/login-history?limit=N (users with users.manage may pass
+// ?all=true for every user's and Xtream's sign-ins)
+func (h *Handler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	userID := claims.UserID
+	if claims.Can(auth.PermUsersManage) && r.URL.Query().Get("all") == "true" {
+		userID = 0
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	history, err := h.authService.LoginHistory(r.Context(), userID, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if history == nil {
+		history = []*database.LoginAttempt{}
+	}
+	respondJSON(w, http.StatusOK, history)
+}
@@ -67,35 +67,3 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 	})
 }
-
-// IPWhitelistMiddleware restricts access to whitelisted IPs
-func IPWhitelistMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		whitelist := os.Getenv("STREAMARR_IP_WHITELIST")
-		if whitelist == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Get client IP (handle X-Forwarded-For from reverse proxy)
-		clientIP := r.Header.Get("X-Forwarded-For")
-		if clientIP == "" {
-			clientIP = r.Header.Get("X-Real-IP")
-		}
-		if clientIP == "" {
-			clientIP = strings.Split(r.RemoteAddr, ":")[0]
-		}
-
-		// Check if IP is in whitelist
-		allowedIPs := strings.Split(whitelist, ",")
-		for _, allowedIP := range allowedIPs {
-			if strings.TrimSpace(allowedIP) == strings.TrimSpace(clientIP) {
-				next.ServeHTTP(w, r)
-				return
-			}
-		}
-
-		log.Printf("🚫 Blocked request from unauthorized IP: %s", clientIP)
-		http.Error(w, "Forbidden", http.StatusForbidden)
-	})
-}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"slices"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/netaccess"
+)
+
+// TestNetworkAccess handles GET /api/v1/network/test?ip=1.2.3.4&surface=xtream: shows how the network
+// access policy treats an address (the caller's own when ip is omitted) on one or every surface
+func (h *Handler) TestNetworkAccess(w http.ResponseWriter, r *http.Request) {
+	if h.networkPolicy == nil {
+		respondError(w, http.StatusServiceUnavailable, "network access policy not available")
+		return
+	}
+
+	var ip net.IP
+	if value := r.URL.Query().Get("ip"); value != "" {
+		if ip = net.ParseIP(value); ip == nil {
+			respondError(w, http.StatusBadRequest, "invalid ip")
+			return
+		}
+	} else {
+		ip = h.networkPolicy.ResolveClientIP(r)
+	}
+
+	surfaces := netaccess.Surfaces
+	if value := r.URL.Query().Get("surface"); value != "" {
+		surface := netaccess.Surface(value)
+		if !slices.Contains(netaccess.Surfaces, surface) {
+			respondError(w, http.StatusBadRequest, "unknown surface")
+			return
+		}
+		surfaces = []netaccess.Surface{surface}
+	}
+
+	decisions := make([]netaccess.Decision, 0, len(surfaces))
+	for _, surface := range surfaces {
+		decisions = append(decisions, h.networkPolicy.Evaluate(ip, surface))
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"ip":        ip.String(),
+		"decisions": decisions,
+	})
+}
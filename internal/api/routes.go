@@ -1,13 +1,18 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
+	"github.com/Zerr0-C00L/StreamArr/internal/tracing"
 	"github.com/gorilla/mux"
 )
 
@@ -154,166 +159,290 @@ func corsMiddleware(next http.Handler) http.Handler {
 // loggingMiddleware logs all HTTP requests
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("[HTTP] %s %s", r.Method, r.URL.Path)
+		log.Printf("[HTTP] %s %s", r.Method, redactStremioToken(r.URL.Path))
 		next.ServeHTTP(w, r)
 	})
 }
 
+// telemetryMiddleware records request latency by route template, so IDs and tokens in paths
+// don't become labels, and starts the request's trace span
+func telemetryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		ctx, span := tracing.StartRequest(r, r.Method+" "+route)
+		span.Set("http.request.method", r.Method)
+		span.Set("http.route", route)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Observe(metrics.Since(start))
+
+		span.Set("http.response.status_code", rec.status)
+		if rec.status >= http.StatusInternalServerError {
+			span.Fail(errors.New(http.StatusText(rec.status)))
+		}
+	})
+}
+
+// redactStremioToken keeps addon tokens in /stremio/{token}/... paths out of the logs
+func redactStremioToken(path string) string {
+	rest, ok := strings.CutPrefix(path, "/stremio/")
+	if !ok {
+		return path
+	}
+	if i := strings.IndexByte(rest, '/'); i == 64 {
+		return "/stremio/<token>" + rest[i:]
+	}
+	return path
+}
+
 // SetupRoutesWithXtream configures API routes with Xtream Codes handler integration
 func SetupRoutesWithXtream(handler *Handler, xtreamHandler interface{ RegisterRoutes(*mux.Router) }) http.Handler {
 	r := mux.NewRouter()
 
-	// Security middleware - Use Session-based auth instead of Basic Auth
-	r.Use(IPWhitelistMiddleware)
-	r.Use(auth.SessionMiddleware)
+	// Metrics and tracing first, so requests rejected by the policies below are counted too
+	r.Use(telemetryMiddleware)
+
+	// Security middleware - Network access policy, then session-based auth
+	r.Use(handler.networkPolicy.Middleware)
+	r.Use(handler.authService.SessionMiddleware)
 	r.Use(loggingMiddleware)
 
 	// Register Stremio poster proxy FIRST (before Xtream generic routes)
 	// This prevents it from matching Xtream's /{username}/{password}/{id}.{ext} pattern
 	r.HandleFunc("/stremio/poster/{path:.+}", handler.StremioPostersProxyHandler).Methods("GET", "HEAD")
 
+	// Token-in-path Stremio addon (/stremio/{token}/manifest.json), also ahead of the Xtream routes
+	stremioToken := r.PathPrefix("/stremio/{token:[0-9a-f]{64}}").Subrouter()
+	stremioToken.HandleFunc("/manifest.json", handler.StremioManifestHandler).Methods("GET")
+	stremioToken.HandleFunc("/catalog/{type}/{id}.json", handler.StremioCatalogHandler).Methods("GET")
+	stremioToken.HandleFunc("/catalog/{type}/{id}/{extra}.json", handler.StremioCatalogHandler).Methods("GET")
+	stremioToken.HandleFunc("/meta/{type}/{id}.json", handler.StremioMetaHandler).Methods("GET")
+	stremioToken.HandleFunc("/stream/{type}/{id}.json", handler.StremioStreamHandler).Methods("GET")
+	stremioToken.HandleFunc("/subtitles/{type}/{id}.json", handler.StremioSubtitlesHandler).Methods("GET")
+	stremioToken.HandleFunc("/subtitles/{type}/{id}/{extra}.json", handler.StremioSubtitlesHandler).Methods("GET")
+	stremioToken.HandleFunc("/play/{type}/{id}/{key}", handler.StremioPlayHandler).Methods("GET", "HEAD")
+	r.HandleFunc("/stremio/play/{type}/{id}/{key}", handler.StremioPlayHandler).Methods("GET", "HEAD")
+
+	// Signed subtitle files for Stremio, the web player and Xtream clients
+	r.HandleFunc("/subtitles/{id:[0-9]+}/{sig:[0-9a-f]+}.{ext:srt|vtt}", handler.ServeSubtitleFile).Methods("GET")
+
+	// HDHomeRun tuner emulation, also before the Xtream routes: /hdhr/{id}/discover.json
+	// would otherwise match /{username}/{password}/{id}.{ext}
+	if handler.hdhrServer != nil {
+		handler.hdhrServer.RegisterRoutes(r)
+	}
+
+	// Prometheus metrics; scrape with an API key granted system.read as the bearer token
+	r.Handle("/metrics", handler.can(auth.PermSystemRead, metrics.Handler().ServeHTTP)).Methods("GET")
+
 	// Register Xtream Codes API routes
 	xtreamHandler.RegisterRoutes(r)
 
-	// API v1 routes
+	// API v1 routes; every route is registered through a permission guard (see authz.go)
 	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Use(requireRouteGuard)
 
 	// Authentication endpoints (public)
-	api.HandleFunc("/auth/login", handler.Login).Methods("POST")
-	api.HandleFunc("/auth/logout", handler.Logout).Methods("POST")
-	api.HandleFunc("/auth/verify", handler.VerifyToken).Methods("GET")
-	api.HandleFunc("/auth/status", handler.AuthStatus).Methods("GET")
-	api.HandleFunc("/auth/setup", handler.CreateFirstUser).Methods("POST")
+	api.Handle("/auth/login", handler.public(handler.Login)).Methods("POST")
+	api.Handle("/auth/login/2fa", handler.public(handler.LoginTwoFactor)).Methods("POST")
+	api.Handle("/auth/refresh", handler.public(handler.RefreshSession)).Methods("POST")
+	api.Handle("/auth/logout", handler.public(handler.Logout)).Methods("POST")
+	api.Handle("/auth/verify", handler.public(handler.VerifyToken)).Methods("GET")
+	api.Handle("/auth/status", handler.public(handler.AuthStatus)).Methods("GET")
+	api.Handle("/auth/setup", handler.public(handler.CreateFirstUser)).Methods("POST")
+
+	// Single sign-on; the proxy session is for users the reverse proxy already signed in
+	api.Handle("/auth/oidc/login", handler.public(handler.OIDCLogin)).Methods("GET")
+	api.Handle("/auth/oidc/callback", handler.public(handler.OIDCCallback)).Methods("GET")
+	api.Handle("/auth/oidc/link", handler.sessionOnly(handler.OIDCLink)).Methods("POST")
+	api.Handle("/auth/proxy/session", handler.signedIn(handler.ProxySession)).Methods("POST")
 
 	// Protected auth endpoints (require authentication via token in context)
-	api.HandleFunc("/auth/profile", handler.GetCurrentUser).Methods("GET")
-	api.HandleFunc("/auth/profile", handler.UpdateProfile).Methods("PUT")
-	api.HandleFunc("/auth/password", handler.ChangePassword).Methods("PUT")
+	api.Handle("/auth/profile", handler.signedIn(handler.GetCurrentUser)).Methods("GET")
+	api.Handle("/auth/profile", handler.sessionOnly(handler.UpdateProfile)).Methods("PUT")
+	api.Handle("/auth/password", handler.sessionOnly(handler.ChangePassword)).Methods("PUT")
+
+	// Sessions and API keys; API keys can't manage credentials themselves
+	api.Handle("/auth/sessions", handler.sessionOnly(handler.ListSessions)).Methods("GET")
+	api.Handle("/auth/sessions/revoke-all", handler.sessionOnly(handler.RevokeAllSessions)).Methods("POST")
+	api.Handle("/auth/sessions/{id:[0-9]+}", handler.sessionOnly(handler.RevokeSession)).Methods("DELETE")
+	api.Handle("/auth/api-keys", handler.sessionOnly(handler.ListAPIKeys)).Methods("GET")
+	api.Handle("/auth/api-keys", handler.sessionOnly(handler.CreateAPIKey)).Methods("POST")
+	api.Handle("/auth/api-keys/{id:[0-9]+}", handler.sessionOnly(handler.RevokeAPIKey)).Methods("DELETE")
+
+	// Two-factor authentication and login history
+	api.Handle("/auth/2fa", handler.sessionOnly(handler.GetTwoFactorStatus)).Methods("GET")
+	api.Handle("/auth/2fa/setup", handler.sessionOnly(handler.SetupTwoFactor)).Methods("POST")
+	api.Handle("/auth/2fa/enable", handler.sessionOnly(handler.EnableTwoFactor)).Methods("POST")
+	api.Handle("/auth/2fa/disable", handler.sessionOnly(handler.DisableTwoFactor)).Methods("POST")
+	api.Handle("/auth/2fa/recovery-codes", handler.sessionOnly(handler.RegenerateRecoveryCodes)).Methods("POST")
+	api.Handle("/auth/2fa/users/{id:[0-9]+}", handler.audited(auth.PermUsersManage, "auth.reset_2fa", handler.ResetUserTwoFactor)).Methods("DELETE")
+	api.Handle("/auth/login-history", handler.sessionOnly(handler.GetLoginHistory)).Methods("GET")
+	api.Handle("/auth/keys/rotate", handler.audited(auth.PermSystemControl, "auth.rotate_keys", handler.RotateSigningKeys)).Methods("POST")
 
 	// Health check
-	api.HandleFunc("/health", handler.HealthCheck).Methods("GET")
+	api.Handle("/health", handler.public(handler.HealthCheck)).Methods("GET")
 
 	// Movies
-	api.HandleFunc("/movies", handler.ListMovies).Methods("GET")
-	api.HandleFunc("/movies", handler.AddMovie).Methods("POST")
-	api.HandleFunc("/movies/{id}", handler.GetMovie).Methods("GET")
-	api.HandleFunc("/movies/{id}", handler.UpdateMovie).Methods("PUT")
-	api.HandleFunc("/movies/{id}", handler.DeleteMovie).Methods("DELETE")
-	api.HandleFunc("/movies/{id}/streams", handler.GetMovieStreams).Methods("GET")
-	api.HandleFunc("/movies/{id}/play", handler.PlayMovie).Methods("GET")
-	api.HandleFunc("/movies/{id}/videos", handler.GetMediaVideos).Methods("GET")
+	api.Handle("/movies", handler.can(auth.PermLibraryRead, handler.ListMovies)).Methods("GET")
+	api.Handle("/movies", handler.can(auth.PermLibraryRequest, handler.AddMovie)).Methods("POST")
+	api.Handle("/movies/{id}", handler.can(auth.PermLibraryRead, handler.GetMovie)).Methods("GET")
+	api.Handle("/movies/{id}", handler.can(auth.PermLibraryWrite, handler.UpdateMovie)).Methods("PUT")
+	api.Handle("/movies/{id}", handler.audited(auth.PermLibraryDelete, "movie.delete", handler.DeleteMovie)).Methods("DELETE")
+	api.Handle("/movies/{id}/streams", handler.can(auth.PermPlayback, handler.GetMovieStreams)).Methods("GET")
+	api.Handle("/movies/{id}/play", handler.can(auth.PermPlayback, handler.PlayMovie)).Methods("GET")
+	api.Handle("/movies/{id}/videos", handler.can(auth.PermLibraryRead, handler.GetMediaVideos)).Methods("GET")
+	api.Handle("/movies/{id}/subtitles", handler.can(auth.PermPlayback, handler.GetMovieSubtitles)).Methods("GET")
 
 	// Series
-	api.HandleFunc("/series", handler.ListSeries).Methods("GET")
-	api.HandleFunc("/series", handler.AddSeries).Methods("POST")
-	api.HandleFunc("/series/{id}", handler.GetSeries).Methods("GET")
-	api.HandleFunc("/series/{id}", handler.UpdateSeries).Methods("PUT")
-	api.HandleFunc("/series/{id}", handler.DeleteSeries).Methods("DELETE")
-	api.HandleFunc("/series/{id}/episodes", handler.GetSeriesEpisodes).Methods("GET")
-	api.HandleFunc("/series/{id}/videos", handler.GetMediaVideos).Methods("GET")
+	api.Handle("/series", handler.can(auth.PermLibraryRead, handler.ListSeries)).Methods("GET")
+	api.Handle("/series", handler.can(auth.PermLibraryRequest, handler.AddSeries)).Methods("POST")
+	api.Handle("/series/{id}", handler.can(auth.PermLibraryRead, handler.GetSeries)).Methods("GET")
+	api.Handle("/series/{id}", handler.can(auth.PermLibraryWrite, handler.UpdateSeries)).Methods("PUT")
+	api.Handle("/series/{id}", handler.audited(auth.PermLibraryDelete, "series.delete", handler.DeleteSeries)).Methods("DELETE")
+	api.Handle("/series/{id}/episodes", handler.can(auth.PermLibraryRead, handler.GetSeriesEpisodes)).Methods("GET")
+	api.Handle("/series/{id}/videos", handler.can(auth.PermLibraryRead, handler.GetMediaVideos)).Methods("GET")
+	api.Handle("/series/{id}/subtitles", handler.can(auth.PermPlayback, handler.GetEpisodeSubtitles)).Methods("GET")
 
 	// Episodes
-	api.HandleFunc("/episodes/{id}/play", handler.PlayEpisode).Methods("GET")
-	api.HandleFunc("/stream/series/{stream_id}", handler.GetEpisodeStreams).Methods("GET")
+	api.Handle("/episodes/{id}/play", handler.can(auth.PermPlayback, handler.PlayEpisode)).Methods("GET")
+	api.Handle("/stream/series/{stream_id}", handler.can(auth.PermPlayback, handler.GetEpisodeStreams)).Methods("GET")
 
 	// Channels (Live TV)
-	api.HandleFunc("/channels", handler.ListChannels).Methods("GET")
-	api.HandleFunc("/channels/categories", handler.GetChannelCategories).Methods("GET")
-	api.HandleFunc("/channels/stats", handler.GetChannelStats).Methods("GET")
-	api.HandleFunc("/channels/check-source", handler.CheckM3USourceStatus).Methods("POST")
-	api.HandleFunc("/channels/epg/guide", handler.GetTVGuide).Methods("GET")
-	api.HandleFunc("/channels/{id}", handler.GetChannel).Methods("GET")
-	api.HandleFunc("/channels/{id}/stream", handler.GetChannelStream).Methods("GET")
-	api.HandleFunc("/channels/proxy", handler.ProxyChannelStream).Methods("GET")
+	api.Handle("/channels", handler.can(auth.PermLiveTVRead, handler.ListChannels)).Methods("GET")
+	api.Handle("/channels/categories", handler.can(auth.PermLiveTVRead, handler.GetChannelCategories)).Methods("GET")
+	api.Handle("/channels/stats", handler.can(auth.PermLiveTVRead, handler.GetChannelStats)).Methods("GET")
+	api.Handle("/channels/check-source", handler.can(auth.PermSettingsWrite, handler.CheckM3USourceStatus)).Methods("POST")
+	api.Handle("/channels/epg/guide", handler.can(auth.PermLiveTVRead, handler.GetTVGuide)).Methods("GET")
+	api.Handle("/channels/{id}", handler.can(auth.PermLiveTVRead, handler.GetChannel)).Methods("GET")
+	api.Handle("/channels/{id}/stream", handler.can(auth.PermPlayback, handler.GetChannelStream)).Methods("GET")
+	api.Handle("/channels/proxy", handler.can(auth.PermPlayback, handler.ProxyChannelStream)).Methods("GET")
 
 	// Search
-	api.HandleFunc("/search/movies", handler.SearchMovies).Methods("GET")
-	api.HandleFunc("/search/series", handler.SearchSeries).Methods("GET")
-	api.HandleFunc("/search/collections", handler.SearchCollections).Methods("GET")
+	api.Handle("/search/movies", handler.can(auth.PermLibraryRead, handler.SearchMovies)).Methods("GET")
+	api.Handle("/search/series", handler.can(auth.PermLibraryRead, handler.SearchSeries)).Methods("GET")
+	api.Handle("/search/collections", handler.can(auth.PermLibraryRead, handler.SearchCollections)).Methods("GET")
 
 	// TMDB Details (for Discover page)
-	api.HandleFunc("/tmdb/{type}/{id}", handler.GetTMDBDetails).Methods("GET")
+	api.Handle("/tmdb/{type}/{id}", handler.can(auth.PermLibraryRead, handler.GetTMDBDetails)).Methods("GET")
 
 	// Discover / Trending
-	api.HandleFunc("/discover/trending", handler.GetTrending).Methods("GET")
-	api.HandleFunc("/discover/popular", handler.GetPopular).Methods("GET")
-	api.HandleFunc("/discover/now-playing", handler.GetNowPlaying).Methods("GET")
-	api.HandleFunc("/discover/collections", handler.GetPopularCollections).Methods("GET")
-	api.HandleFunc("/discover/collections/browse", handler.BrowseCollections).Methods("GET")
+	api.Handle("/discover/trending", handler.can(auth.PermLibraryRead, handler.GetTrending)).Methods("GET")
+	api.Handle("/discover/popular", handler.can(auth.PermLibraryRead, handler.GetPopular)).Methods("GET")
+	api.Handle("/discover/now-playing", handler.can(auth.PermLibraryRead, handler.GetNowPlaying)).Methods("GET")
+	api.Handle("/discover/collections", handler.can(auth.PermLibraryRead, handler.GetPopularCollections)).Methods("GET")
+	api.Handle("/discover/collections/browse", handler.can(auth.PermLibraryRead, handler.BrowseCollections)).Methods("GET")
 
 	// Collections
-	api.HandleFunc("/collections", handler.ListCollections).Methods("GET")
-	api.HandleFunc("/collections/add", handler.AddCollectionByTMDB).Methods("POST")
-	api.HandleFunc("/collections/{id}", handler.GetCollection).Methods("GET")
-	api.HandleFunc("/collections/{id}/sync", handler.SyncCollection).Methods("POST")
-	api.HandleFunc("/collections/{id}/movies", handler.GetCollectionMovies).Methods("GET")
+	api.Handle("/collections", handler.can(auth.PermLibraryRead, handler.ListCollections)).Methods("GET")
+	api.Handle("/collections/add", handler.can(auth.PermLibraryRequest, handler.AddCollectionByTMDB)).Methods("POST")
+	api.Handle("/collections/{id}", handler.can(auth.PermLibraryRead, handler.GetCollection)).Methods("GET")
+	api.Handle("/collections/{id}/sync", handler.can(auth.PermLibraryWrite, handler.SyncCollection)).Methods("POST")
+	api.Handle("/collections/{id}/movies", handler.can(auth.PermLibraryRead, handler.GetCollectionMovies)).Methods("GET")
 
 	// Blacklist
-	api.HandleFunc("/blacklist", handler.GetBlacklist).Methods("GET")
-	api.HandleFunc("/blacklist/clear", handler.ClearBlacklist).Methods("POST")
-	api.HandleFunc("/blacklist/{id}", handler.RemoveFromBlacklist).Methods("DELETE")
-	api.HandleFunc("/{type}/{id}/remove-and-blacklist", handler.RemoveAndBlacklist).Methods("POST")
+	api.Handle("/blacklist", handler.can(auth.PermLibraryRead, handler.GetBlacklist)).Methods("GET")
+	api.Handle("/blacklist/clear", handler.audited(auth.PermLibraryDelete, "blacklist.clear", handler.ClearBlacklist)).Methods("POST")
+	api.Handle("/blacklist/{id}", handler.audited(auth.PermLibraryDelete, "blacklist.remove", handler.RemoveFromBlacklist)).Methods("DELETE")
+	api.Handle("/{type}/{id}/remove-and-blacklist", handler.audited(auth.PermLibraryDelete, "library.remove_and_blacklist", handler.RemoveAndBlacklist)).Methods("POST")
 
 	// Services
-	api.HandleFunc("/services", handler.GetServices).Methods("GET")
-	api.HandleFunc("/services/{name}", handler.UpdateServiceEnabled).Methods("PUT")
-	api.HandleFunc("/services/{name}/trigger", handler.TriggerService).Methods("POST")
+	api.Handle("/services", handler.can(auth.PermServicesRead, handler.GetServices)).Methods("GET")
+	api.Handle("/services/{name}", handler.can(auth.PermServicesRun, handler.UpdateService)).Methods("PUT")
+	api.Handle("/services/{name}/trigger", handler.can(auth.PermServicesRun, handler.TriggerService)).Methods("POST")
+	api.Handle("/services/{name}/cancel", handler.can(auth.PermServicesRun, handler.CancelService)).Methods("POST")
+	api.Handle("/services/{name}/runs", handler.can(auth.PermServicesRead, handler.GetServiceRuns)).Methods("GET")
+	api.Handle("/services/{name}/runs/{id:[0-9]+}", handler.can(auth.PermServicesRead, handler.GetServiceRun)).Methods("GET")
 
 	// Settings
-	api.HandleFunc("/settings", handler.GetSettings).Methods("GET")
-	api.HandleFunc("/settings", handler.UpdateSettings).Methods("PUT")
+	api.Handle("/settings", handler.can(auth.PermSettingsRead, handler.GetSettings)).Methods("GET")
+	api.Handle("/settings", handler.audited(auth.PermSettingsWrite, "settings.update", handler.UpdateSettings)).Methods("PUT")
 
 	// Admin - System control
-	api.HandleFunc("/admin/restart", handler.Restart).Methods("POST")
+	api.Handle("/admin/restart", handler.audited(auth.PermSystemControl, "system.restart", handler.Restart)).Methods("POST")
+
+	// Audit log of destructive actions
+	api.Handle("/audit", handler.can(auth.PermAuditRead, handler.GetAuditLog)).Methods("GET")
+	api.Handle("/activity", handler.can(auth.PermAuditRead, handler.GetActivity)).Methods("GET")
+	api.Handle("/activity/export", handler.can(auth.PermAuditRead, handler.ExportActivity)).Methods("GET")
+
+	// Logs: recent lines kept in memory, a live stream and the per-subsystem levels
+	api.Handle("/logs", handler.can(auth.PermSystemRead, handler.GetLogs)).Methods("GET")
+	api.Handle("/logs/stream", handler.can(auth.PermSystemRead, handler.StreamLogs)).Methods("GET")
+	api.Handle("/logs/levels", handler.can(auth.PermSystemRead, handler.GetLogLevels)).Methods("GET")
+	api.Handle("/logs/levels", handler.audited(auth.PermSettingsWrite, "logs.levels", handler.UpdateLogLevels)).Methods("PUT")
+
+	// Network access policy: how a client address would be treated on each surface
+	api.Handle("/network/test", handler.can(auth.PermSettingsRead, handler.TestNetworkAccess)).Methods("GET")
 
 	// Calendar
-	api.HandleFunc("/calendar", handler.GetCalendar).Methods("GET")
+	api.Handle("/calendar", handler.can(auth.PermLibraryRead, handler.GetCalendar)).Methods("GET")
 
 	// MDBList
-	api.HandleFunc("/mdblist/user-lists", handler.GetMDBListUserLists).Methods("GET")
+	api.Handle("/mdblist/user-lists", handler.can(auth.PermSettingsRead, handler.GetMDBListUserLists)).Methods("GET")
 
 	// Stats (for dashboard)
-	api.HandleFunc("/stats", handler.GetStats).Methods("GET")
+	api.Handle("/stats", handler.can(auth.PermLibraryRead, handler.GetStats)).Methods("GET")
 
 	// Database management
-	api.HandleFunc("/database/stats", handler.GetDatabaseStats).Methods("GET")
-	api.HandleFunc("/database/{action}", handler.ExecuteDatabaseAction).Methods("POST")
+	api.Handle("/database/stats", handler.can(auth.PermSystemRead, handler.GetDatabaseStats)).Methods("GET")
+	api.Handle("/database/{action}", handler.audited(auth.PermSystemControl, "database.action", handler.ExecuteDatabaseAction)).Methods("POST")
 
 	// Version / Updates
-	api.HandleFunc("/version", handler.GetVersion).Methods("GET")
-	api.HandleFunc("/version/check", handler.CheckForUpdates).Methods("GET")
-	api.HandleFunc("/update/install", handler.InstallUpdate).Methods("POST")
+	api.Handle("/version", handler.public(handler.GetVersion)).Methods("GET")
+	api.Handle("/version/check", handler.can(auth.PermSystemRead, handler.CheckForUpdates)).Methods("GET")
+	api.Handle("/update/install", handler.audited(auth.PermSystemControl, "system.update", handler.InstallUpdate)).Methods("POST")
 
 	// Adult VOD Import
 
 	// Balkan VOD Import
-	api.HandleFunc("/balkan-vod/preview-categories", handler.PreviewBalkanCategories).Methods("POST")
-	api.HandleFunc("/adult-vod/import", handler.ImportAdultVOD).Methods("POST")
-	api.HandleFunc("/adult-vod/stats", handler.GetAdultVODStats).Methods("GET")
+	api.Handle("/balkan-vod/preview-categories", handler.can(auth.PermLibraryWrite, handler.PreviewBalkanCategories)).Methods("POST")
+	api.Handle("/adult-vod/import", handler.can(auth.PermLibraryWrite, handler.ImportAdultVOD)).Methods("POST")
+	api.Handle("/adult-vod/stats", handler.can(auth.PermLibraryRead, handler.GetAdultVODStats)).Methods("GET")
 
 	// IPTV VOD Import (from configured M3U/Xtream)
-	api.HandleFunc("/iptv-vod/preview-categories", handler.PreviewM3UCategories).Methods("POST")
-	api.HandleFunc("/iptv-vod/preview-xtream-categories", handler.PreviewXtreamCategories).Methods("POST")
-	api.HandleFunc("/iptv-vod/import", handler.ImportIPTVVOD).Methods("POST")
+	api.Handle("/iptv-vod/preview-categories", handler.can(auth.PermLibraryWrite, handler.PreviewM3UCategories)).Methods("POST")
+	api.Handle("/iptv-vod/preview-xtream-categories", handler.can(auth.PermLibraryWrite, handler.PreviewXtreamCategories)).Methods("POST")
+	api.Handle("/iptv-vod/import", handler.can(auth.PermLibraryWrite, handler.ImportIPTVVOD)).Methods("POST")
 	// Fallbacks for clients hitting /api/iptv-vod/import or trailing slash
-	r.HandleFunc("/api/iptv-vod/import", handler.ImportIPTVVOD).Methods("POST")
-	api.HandleFunc("/iptv-vod/import/", handler.ImportIPTVVOD).Methods("POST")
+	r.Handle("/api/iptv-vod/import", handler.can(auth.PermLibraryWrite, handler.ImportIPTVVOD)).Methods("POST")
+	api.Handle("/iptv-vod/import/", handler.can(auth.PermLibraryWrite, handler.ImportIPTVVOD)).Methods("POST")
 
 	// Maintenance
-	api.HandleFunc("/maintenance/cleanup-bollywood", handler.CleanupBollywoodLibrary).Methods("POST")
-
-	// Stremio Addon Management
-	api.HandleFunc("/stremio/generate-token", handler.GenerateStremioToken).Methods("POST")
-	api.HandleFunc("/stremio/manifest-url", handler.GetStremioManifestURL).Methods("GET")
+	api.Handle("/maintenance/cleanup-bollywood", handler.audited(auth.PermLibraryDelete, "library.cleanup_bollywood", handler.CleanupBollywoodLibrary)).Methods("POST")
+
+	// Stremio Addon Management; personal tokens, preferences and lists belong to the signed-in user
+	api.Handle("/stremio/generate-token", handler.audited(auth.PermSettingsWrite, "stremio.shared_token", handler.GenerateStremioToken)).Methods("POST")
+	api.Handle("/stremio/manifest-url", handler.can(auth.PermPlayback, handler.GetStremioManifestURL)).Methods("GET")
+	api.Handle("/stremio/tokens", handler.signedIn(handler.ListStremioTokens)).Methods("GET")
+	api.Handle("/stremio/tokens", handler.signedIn(handler.CreateStremioToken)).Methods("POST")
+	api.Handle("/stremio/tokens/{id}", handler.signedIn(handler.RevokeStremioToken)).Methods("DELETE")
+	api.Handle("/stremio/preferences", handler.signedIn(handler.GetStremioPreferences)).Methods("GET")
+	api.Handle("/stremio/preferences", handler.signedIn(handler.UpdateStremioPreferences)).Methods("PUT")
+	api.Handle("/stremio/library", handler.signedIn(handler.GetStremioLibrary)).Methods("GET")
+	api.Handle("/stremio/watchlist/{type}/{id}", handler.signedIn(handler.UpdateStremioWatchlist)).Methods("PUT", "DELETE")
+	api.Handle("/stremio/continue/{type}/{id}", handler.signedIn(handler.DismissStremioContinue)).Methods("DELETE")
 
 	// Debug endpoints (for troubleshooting updates)
-	api.HandleFunc("/debug/update-status", handler.GetUpdateStatus).Methods("GET")
-	api.HandleFunc("/debug/update-log", handler.GetUpdateLog).Methods("GET")
+	api.Handle("/debug/update-status", handler.can(auth.PermSystemRead, handler.GetUpdateStatus)).Methods("GET")
+	api.Handle("/debug/update-log", handler.can(auth.PermSystemRead, handler.GetUpdateLog)).Methods("GET")
 
 	// Stremio Addon Endpoints (public with token auth)
 	r.HandleFunc("/stremio/manifest.json", handler.StremioManifestHandler).Methods("GET")
 	r.HandleFunc("/stremio/catalog/{type}/{id}.json", handler.StremioCatalogHandler).Methods("GET")
+	r.HandleFunc("/stremio/catalog/{type}/{id}/{extra}.json", handler.StremioCatalogHandler).Methods("GET")
+	r.HandleFunc("/stremio/meta/{type}/{id}.json", handler.StremioMetaHandler).Methods("GET")
 	r.HandleFunc("/stremio/stream/{type}/{id}.json", handler.StremioStreamHandler).Methods("GET")
+	r.HandleFunc("/stremio/subtitles/{type}/{id}.json", handler.StremioSubtitlesHandler).Methods("GET")
+	r.HandleFunc("/stremio/subtitles/{type}/{id}/{extra}.json", handler.StremioSubtitlesHandler).Methods("GET")
 	r.HandleFunc("/stremio/poster/{path:.+}", handler.StremioPostersProxyHandler).Methods("GET", "HEAD")
 
 	// Serve static UI files (SPA)
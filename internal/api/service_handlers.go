@@ -0,0 +1,480 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
+	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/Zerr0-C00L/StreamArr/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// serviceStatus is a background service as shown on the Services page
+type serviceStatus struct {
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Enabled         bool       `json:"enabled"`
+	Running         bool       `json:"running"`
+	Queued          bool       `json:"queued"`
+	Schedule        string     `json:"schedule"`
+	Interval        string     `json:"interval"` // Same as schedule, for older clients
+	LastRun         *time.Time `json:"last_run,omitempty"`
+	NextRun         *time.Time `json:"next_run,omitempty"`
+	LastStatus      string     `json:"last_status,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastDurationMS  int64      `json:"last_duration_ms"`
+	RunCount        int64      `json:"run_count"`
+	Worker          string     `json:"worker,omitempty"` // Process running the job
+	CancelRequested bool       `json:"cancel_requested"`
+	Progress        int        `json:"progress"` // 0-100 percentage
+	ProgressMessage string     `json:"progress_message"`
+	ItemsProcessed  int        `json:"items_processed"`
+	ItemsTotal      int        `json:"items_total"`
+}
+
+func newServiceStatus(job *database.Job) serviceStatus {
+	status := serviceStatus{
+		Name:            job.Name,
+		Description:     job.Description,
+		Enabled:         job.Enabled,
+		Schedule:        job.Schedule,
+		Interval:        job.Schedule,
+		NextRun:         job.NextRunAt,
+		RunCount:        job.RunCount,
+		Worker:          job.LockedBy,
+		CancelRequested: job.CancelRequested,
+	}
+	if run := job.LastRun; run != nil {
+		status.Running = run.Status == database.JobRunning
+		status.Queued = run.Status == database.JobQueued
+		status.LastStatus = run.Status
+		status.LastError = run.Error
+		status.LastDurationMS = run.DurationMS
+		status.LastRun = run.FinishedAt
+		if status.LastRun == nil {
+			status.LastRun = run.StartedAt
+		}
+		if status.Running {
+			status.Progress = run.Progress
+			status.ProgressMessage = run.ProgressMessage
+			status.ItemsProcessed = run.ItemsProcessed
+			status.ItemsTotal = run.ItemsTotal
+		}
+	}
+	return status
+}
+
+// GetServices handles GET /api/services - returns status of all background services
+func (h *Handler) GetServices(w http.ResponseWriter, r *http.Request) {
+	statuses := []serviceStatus{}
+	if h.jobQueue != nil {
+		list, err := h.jobQueue.Jobs(r.Context())
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, job := range list {
+			statuses = append(statuses, newServiceStatus(job))
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"services": statuses,
+	})
+}
+
+// TriggerService handles POST /api/services/{name}/trigger - queues a manual run
+func (h *Handler) TriggerService(w http.ResponseWriter, r *http.Request) {
+	serviceName := mux.Vars(r)["name"]
+	if h.jobQueue == nil {
+		respondError(w, http.StatusServiceUnavailable, "job queue not available")
+		return
+	}
+
+	run, err := h.jobQueue.Trigger(r.Context(), serviceName)
+	switch {
+	case errors.Is(err, jobs.ErrUnknownJob):
+		respondError(w, http.StatusNotFound, "service not found")
+		return
+	case errors.Is(err, database.ErrJobBusy):
+		respondError(w, http.StatusConflict, "service is already queued or running")
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Service triggered",
+		"service": serviceName,
+		"status":  run.Status,
+		"run_id":  run.ID,
+	})
+}
+
+// CancelService handles POST /api/services/{name}/cancel - cancels a queued or running service
+func (h *Handler) CancelService(w http.ResponseWriter, r *http.Request) {
+	serviceName := mux.Vars(r)["name"]
+	if h.jobQueue == nil {
+		respondError(w, http.StatusServiceUnavailable, "job queue not available")
+		return
+	}
+
+	if err := h.jobQueue.Cancel(r.Context(), serviceName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusConflict, "service is not queued or running")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]string{
+		"message": "Cancellation requested",
+		"service": serviceName,
+	})
+}
+
+// UpdateService handles PUT /api/services/{name} - enables/disables a service and/or changes its schedule
+func (h *Handler) UpdateService(w http.ResponseWriter, r *http.Request) {
+	serviceName := mux.Vars(r)["name"]
+	if h.jobQueue == nil {
+		respondError(w, http.StatusServiceUnavailable, "job queue not available")
+		return
+	}
+
+	var req struct {
+		Enabled  *bool   `json:"enabled"`
+		Schedule *string `json:"schedule"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Enabled == nil && req.Schedule == nil {
+		respondError(w, http.StatusBadRequest, "enabled or schedule is required")
+		return
+	}
+	if req.Schedule != nil {
+		schedule := strings.TrimSpace(*req.Schedule)
+		if _, err := jobs.ParseSchedule(schedule); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Schedule = &schedule
+	}
+
+	if err := h.jobQueue.Update(r.Context(), serviceName, req.Enabled, req.Schedule); err != nil {
+		if errors.Is(err, jobs.ErrUnknownJob) {
+			respondError(w, http.StatusNotFound, "service not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	list, err := h.jobQueue.Jobs(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, job := range list {
+		if job.Name == serviceName {
+			respondJSON(w, http.StatusOK, newServiceStatus(job))
+			return
+		}
+	}
+	respondError(w, http.StatusNotFound, "service not found")
+}
+
+// GetServiceRuns handles GET /api/services/{name}/runs - run history, newest first
+func (h *Handler) GetServiceRuns(w http.ResponseWriter, r *http.Request) {
+	serviceName := mux.Vars(r)["name"]
+	if h.jobQueue == nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{"runs": []*database.JobRun{}})
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	runs, err := h.jobQueue.Runs(r.Context(), serviceName, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if runs == nil {
+		runs = []*database.JobRun{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service": serviceName,
+		"runs":    runs,
+	})
+}
+
+// GetServiceRun handles GET /api/services/{name}/runs/{id} - a single run with its logs
+func (h *Handler) GetServiceRun(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+	if h.jobQueue == nil {
+		respondError(w, http.StatusNotFound, "run not found")
+		return
+	}
+
+	run, err := h.jobQueue.Run(r.Context(), id)
+	if err != nil || run.JobName != vars["name"] {
+		respondError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, run)
+}
+
+// triggerJob queues a background run; one already queued or running is left to finish
+func (h *Handler) triggerJob(ctx context.Context, name string) {
+	if h.jobQueue == nil {
+		return
+	}
+	if _, err := h.jobQueue.Trigger(ctx, name); err != nil && !errors.Is(err, database.ErrJobBusy) {
+		log.Printf("[JOBS] ⚠️ Cannot queue %s: %v", name, err)
+	}
+}
+
+// JobRunners returns the background services the server can run, keyed by service name
+func (h *Handler) JobRunners() map[string]jobs.RunFunc {
+	return map[string]jobs.RunFunc{
+		services.ServiceEPGUpdate: func(ctx context.Context) error {
+			if h.channelManager == nil || h.epgManager == nil {
+				jobs.Logf(ctx, "[EPG] Update skipped: Live TV is not initialized")
+				return nil
+			}
+			channels := h.channelManager.GetAllChannels()
+			channelList := make([]livetv.Channel, len(channels))
+			for i, ch := range channels {
+				channelList[i] = *ch
+			}
+			return h.epgManager.UpdateEPG(channelList)
+		},
+
+		services.ServiceChannelRefresh: func(ctx context.Context) error {
+			if h.channelManager == nil {
+				jobs.Logf(ctx, "[Live TV] Channel refresh skipped: Live TV is not initialized")
+				return nil
+			}
+			if err := h.channelManager.LoadChannels(); err != nil {
+				return err
+			}
+			// Live stream IDs follow the channel list
+			if h.playlistGen != nil {
+				return h.playlistGen.RefreshLive()
+			}
+			return nil
+		},
+
+		services.ServicePlaylist: func(ctx context.Context) error {
+			if h.playlistGen == nil {
+				jobs.Logf(ctx, "[Playlist] Generation skipped: playlist generator is not initialized")
+				return nil
+			}
+			return h.playlistGen.GenerateComplete(ctx)
+		},
+
+		services.ServiceMDBListSync: func(ctx context.Context) error {
+			if h.mdbSyncService == nil {
+				jobs.Logf(ctx, "[MDBList] Sync skipped: MDBList sync service not initialized")
+				return nil
+			}
+			if err := h.mdbSyncService.SyncAllLists(ctx); err != nil {
+				return err
+			}
+			if movies, series, err := h.mdbSyncService.GetSyncStats(ctx); err == nil {
+				h.recordImport(ctx, "mdblist", fmt.Sprintf("MDBList sync: library has %d movies, %d series", movies, series),
+					map[string]interface{}{"movies": movies, "series": series})
+			}
+			// Also enrich existing items
+			if err := h.mdbSyncService.EnrichExistingItems(ctx); err != nil {
+				jobs.Logf(ctx, "[MDBList] ⚠️ Enrichment error: %v", err)
+			}
+			return nil
+		},
+
+		services.ServiceCollectionSync: h.syncCollections,
+
+		services.ServiceEpisodeScan: func(ctx context.Context) error {
+			if h.seriesStore == nil || h.episodeStore == nil || h.tmdbClient == nil {
+				jobs.Logf(ctx, "[Episode Scan] Skipped: required stores not initialized")
+				return nil
+			}
+			return services.ScanEpisodes(ctx, h.seriesStore, h.episodeStore, h.tmdbClient)
+		},
+
+		services.ServiceIPTVVODSync: func(ctx context.Context) error {
+			if h.settingsManager == nil || h.tmdbClient == nil || h.movieStore == nil || h.seriesStore == nil {
+				return nil
+			}
+			// Only run when VOD mode is enabled and TMDB key is present
+			current := h.settingsManager.Get()
+			mode := strings.ToLower(current.IPTVImportMode)
+			if (mode != "vod_only" && mode != "both") || current.TMDBAPIKey == "" {
+				jobs.Logf(ctx, "[IPTV VOD] Import skipped: VOD import is disabled or TMDB key is missing")
+				return nil
+			}
+			summary, err := services.ImportIPTVVOD(ctx, current, h.tmdbClient, h.movieStore, h.seriesStore)
+			if err != nil {
+				return err
+			}
+			jobs.Logf(ctx, "[IPTV VOD] Import: sources=%d items=%d movies=%d series=%d skipped=%d errors=%d",
+				summary.SourcesChecked, summary.ItemsFound, summary.MoviesImported, summary.SeriesImported, summary.Skipped, summary.Errors)
+			h.recordIPTVImport(ctx, summary)
+			return services.CleanupIPTVVOD(ctx, current, h.movieStore, h.seriesStore)
+		},
+
+		services.ServiceBalkanVODSync: func(ctx context.Context) error {
+			if h.settingsManager == nil || h.tmdbClient == nil || h.movieStore == nil || h.seriesStore == nil {
+				return nil
+			}
+			current := h.settingsManager.Get()
+			if !current.BalkanVODEnabled {
+				jobs.Logf(ctx, "[BalkanVOD] Import disabled in settings")
+				return nil
+			}
+			// Scheduled runs respect the auto-sync switch; manual triggers always import
+			if !current.BalkanVODAutoSync && !jobs.IsManual(ctx) {
+				jobs.Logf(ctx, "[BalkanVOD] Auto-sync disabled in settings")
+				return nil
+			}
+			importer := services.NewBalkanVODImporter(h.movieStore, h.seriesStore, h.tmdbClient, current)
+			importer.SetActivity(h.activity)
+			return importer.ImportBalkanVOD(ctx)
+		},
+
+		services.ServiceLocalMediaScan: func(ctx context.Context) error {
+			if h.localMedia == nil {
+				jobs.Logf(ctx, "[Local Media] Scan skipped: local media is disabled")
+				return nil
+			}
+			// Pick up library items added since the last scan
+			h.localMedia.ResetMatches()
+			result, err := h.localMedia.Scan(ctx)
+			if err != nil {
+				return err
+			}
+			jobs.Logf(ctx, "[Local Media] Scan: %d files, %d matched, %d unmatched, %d removed",
+				result.Files, result.Matched, result.Unmatched, result.Removed)
+			return nil
+		},
+
+		services.ServiceRDTorrentCleanup: func(ctx context.Context) error {
+			if h.rdClient == nil || h.settingsManager == nil {
+				return nil
+			}
+			if current := h.settingsManager.Get(); !current.UseRealDebrid || current.RealDebridAPIKey == "" {
+				jobs.Logf(ctx, "[RD] Torrent cleanup skipped: Real-Debrid is not configured")
+				return nil
+			}
+			removed, err := h.rdClient.Torrents().CollectGarbage(ctx)
+			if err != nil {
+				return err
+			}
+			jobs.Logf(ctx, "[RD] Removed %d stale torrents", removed)
+			return nil
+		},
+
+		services.ServiceActivityRetention: h.pruneActivity,
+
+		services.ServiceStrmExport: func(ctx context.Context) error {
+			if h.strmExporter == nil {
+				jobs.Logf(ctx, "[STRM-EXPORT] Sync skipped: library export is disabled")
+				return nil
+			}
+			result, err := h.strmExporter.Sync(ctx)
+			if err != nil {
+				return err
+			}
+			jobs.Logf(ctx, "[STRM-EXPORT] Sync: %d written, %d unchanged, %d removed", result.Written, result.Unchanged, result.Removed)
+			return nil
+		},
+	}
+}
+
+// syncCollections links movies to their collections, then adds missing movies of incomplete collections
+func (h *Handler) syncCollections(ctx context.Context) error {
+	if h.collectionStore == nil || h.movieStore == nil {
+		jobs.Logf(ctx, "[Collection Sync] Skipped: collection store is not initialized")
+		return nil
+	}
+
+	// Phase 1: Scan existing movies for collections and link them
+	if err := h.scanAndLinkCollections(ctx); err != nil {
+		return err
+	}
+
+	// Phase 2: Sync incomplete collections (add missing movies)
+	if h.settingsManager == nil || !h.settingsManager.Get().AutoAddCollections {
+		jobs.Logf(ctx, "[Collection Sync] Phase 2 skipped: AutoAddCollections is disabled")
+		jobs.Progress(ctx, 0, 0, "Phase 2 skipped (AutoAddCollections disabled)")
+		return nil
+	}
+
+	log.Println("[Collection Sync] Phase 2: Adding missing movies from incomplete collections...")
+	jobs.Progress(ctx, 0, 0, "Phase 2: Checking incomplete collections...")
+
+	collections, _, _ := h.collectionStore.GetCollectionsWithProgress(ctx, 1000, 0)
+	log.Printf("[Collection Sync] Found %d collections to check", len(collections))
+
+	// Find incomplete collections; treat unknown totals (0) as incomplete to force refresh
+	var incompleteColls []*models.Collection
+	for _, coll := range collections {
+		if coll.TotalMovies == 0 || coll.MoviesInLibrary < coll.TotalMovies {
+			incompleteColls = append(incompleteColls, coll)
+		}
+	}
+
+	totalIncomplete := len(incompleteColls)
+	if totalIncomplete == 0 {
+		log.Println("[Collection Sync] Phase 2: All collections are complete!")
+		jobs.Progress(ctx, 0, 0, "All collections complete!")
+		return nil
+	}
+	log.Printf("[Collection Sync] Found %d incomplete collections", totalIncomplete)
+
+	for i, coll := range incompleteColls {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		jobs.Progress(ctx, i+1, totalIncomplete,
+			fmt.Sprintf("Adding movies to: %s (%d/%d)", coll.Name, coll.MoviesInLibrary, coll.TotalMovies))
+
+		// Skip auto-adding collections that are seeded only by IPTV VOD imports
+		if movies, err := h.collectionStore.GetMoviesInCollection(ctx, coll.ID); err == nil {
+			if !collectionHasNonIPTVVOD(movies) {
+				log.Printf("[Collection Sync] Skipping collection '%s' (only IPTV VOD items)", coll.Name)
+				continue
+			}
+		}
+
+		log.Printf("[Collection Sync] '%s' is incomplete (%d/%d), adding missing movies...",
+			coll.Name, coll.MoviesInLibrary, coll.TotalMovies)
+		h.addCollectionMovies(ctx, coll.TMDBID, true, "default")
+		time.Sleep(500 * time.Millisecond) // Rate limit
+	}
+
+	jobs.Progress(ctx, totalIncomplete, totalIncomplete,
+		fmt.Sprintf("Phase 2 complete: %d collections updated", totalIncomplete))
+	jobs.Logf(ctx, "[Collection Sync] Phase 2 complete: processed %d incomplete collections", totalIncomplete)
+	return nil
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/gorilla/mux"
+)
+
+// ListSessions handles GET /api/v1/auth/sessions: the caller's signed-in devices
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	sessions, err := h.authService.Sessions(r.Context(), claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /api/v1/auth/sessions/{id}
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid session id")
+		return
+	}
+	if err := h.authService.RevokeSession(r.Context(), claims.UserID, id); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAllSessions handles POST /api/v1/auth/sessions/revoke-all: signs the caller out everywhere,
+// including this session. API keys keep working.
+func (h *Handler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	if err := h.authService.RevokeAllSessions(r.Context(), claims.UserID); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{
+		"message": "signed out of all sessions",
+	})
+}
+
+// ListAPIKeys handles GET /api/v1/auth/api-keys (users with users.manage may pass ?all=true)
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	userID := claims.UserID
+	if claims.Can(auth.PermUsersManage) && r.URL.Query().Get("all") == "true" {
+		userID = 0
+	}
+	keys, err := h.authService.APIKeys(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey handles POST /api/v1/auth/api-keys; the key is only shown in this response
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+
+	var req struct {
+		Name          string            `json:"name"`
+		Scopes        []auth.Permission `json:"scopes"`
+		ExpiresInDays int               `json:"expires_in_days"` // 0 = never
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Name == "" {
+		respondError(w, http.StatusBadRequest, "name required")
+		return
+	}
+	// A key can't do more than its owner
+	for _, scope := range req.Scopes {
+		if auth.IsPermission(scope) && !claims.Can(scope) {
+			respondError(w, http.StatusForbidden, "your role does not grant "+string(scope))
+			return
+		}
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	key, stored, err := h.authService.CreateAPIKey(r.Context(), claims.UserID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"key":     key,
+		"api_key": stored,
+	})
+}
+
+// RevokeAPIKey handles DELETE /api/v1/auth/api-keys/{id}
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid api key id")
+		return
+	}
+	key, err := h.authService.APIKey(r.Context(), id)
+	if err != nil || (key.UserID != claims.UserID && !claims.Can(auth.PermUsersManage)) {
+		respondError(w, http.StatusNotFound, "api key not found")
+		return
+	}
+	if err := h.authService.RevokeAPIKey(r.Context(), id); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusConflict, "api key already revoked")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateSigningKeys handles POST /api/v1/auth/keys/rotate; signed-in users stay signed in
+func (h *Handler) RotateSigningKeys(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.RotateKeys(r.Context()); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{
+		"message": "signing key rotated",
+	})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+)
+
+const (
+	oidcStateCookie = "streamarr_oidc"
+	oidcCookiePath  = "/api/v1/auth/oidc"
+)
+
+// OIDCLogin handles GET /api/v1/auth/oidc/login: sends the browser to the identity provider
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.sso.BeginOIDC(r.Context(), requestBaseURL(r)+oidcCookiePath+"/callback")
+	if err != nil {
+		log.Printf("[AUTH] ⚠️ Cannot start single sign-on: %v", err)
+		ssoRedirect(w, r, "sso_error", "single sign-on is unavailable")
+		return
+	}
+	setOIDCStateCookie(w, r, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCLink handles POST /api/v1/auth/oidc/link: starts linking the signed-in user to their account
+// at the identity provider. The web UI sends the browser to the returned URL; single sign-on then
+// signs in as this user.
+func (h *Handler) OIDCLink(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	authURL, state, err := h.sso.BeginOIDCLink(r.Context(), requestBaseURL(r)+oidcCookiePath+"/callback", claims.UserID)
+	if err == auth.ErrSSODisabled {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("[AUTH] ⚠️ Cannot start single sign-on linking: %v", err)
+		respondError(w, http.StatusBadGateway, "single sign-on is unavailable")
+		return
+	}
+	setOIDCStateCookie(w, r, state)
+	respondJSON(w, http.StatusOK, map[string]string{"url": authURL})
+}
+
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcCookiePath,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode, // Sent on the provider's top-level redirect back
+	})
+}
+
+// OIDCCallback handles GET /api/v1/auth/oidc/callback: the provider redirects here after sign-in.
+// The new session's refresh token is handed to the web UI in the URL fragment, which never reaches
+// a server; the UI exchanges it at once, so it can't be used again.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: oidcCookiePath, MaxAge: -1, HttpOnly: true, Secure: isHTTPS(r)})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		log.Printf("[AUTH] Single sign-on refused by the provider: %s %s", providerErr, query.Get("error_description"))
+		ssoRedirect(w, r, "sso_error", "sign-in was cancelled or refused")
+		return
+	}
+	var state string
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		state = cookie.Value
+	}
+
+	login, err := h.sso.CompleteOIDC(r.Context(), state, query.Get("state"), query.Get("code"))
+	switch {
+	case err == auth.ErrOIDCState:
+		ssoRedirect(w, r, "sso_error", err.Error())
+		return
+	case err == auth.ErrSSONoAccount || err == auth.ErrSSONoRole || err == auth.ErrSSOAccountExists || err == auth.ErrSSOLinkedElsewhere:
+		log.Printf("[AUTH] Single sign-on refused: %v", err)
+		ssoRedirect(w, r, "sso_error", err.Error())
+		return
+	case err != nil:
+		log.Printf("[AUTH] ⚠️ Single sign-on failed: %v", err)
+		ssoRedirect(w, r, "sso_error", "single sign-on failed")
+		return
+	}
+
+	h.recordLogin(r, database.LoginSourceOIDC, login.UserID, login.Username, "")
+	session, err := h.authService.IssueSession(r.Context(), login.UserID, login.Username, login.Role, false, r.UserAgent(), auth.ClientIP(r))
+	if err != nil {
+		log.Printf("Error generating token: %v", err)
+		ssoRedirect(w, r, "sso_error", "failed to start session")
+		return
+	}
+	ssoRedirect(w, r, "sso_token", session.RefreshToken)
+}
+
+// ProxySession handles POST /api/v1/auth/proxy/session: gives the web UI a session for the user the
+// authenticating reverse proxy signed in
+func (h *Handler) ProxySession(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.GetUserFromContext(r.Context())
+	if !claims.Proxy {
+		respondError(w, http.StatusBadRequest, "not signed in through the reverse proxy")
+		return
+	}
+	h.recordLogin(r, database.LoginSourceProxy, claims.UserID, claims.Username, "")
+	session, err := h.authService.IssueSession(r.Context(), claims.UserID, claims.Username, claims.Role, false, r.UserAgent(), auth.ClientIP(r))
+	if err != nil {
+		log.Printf("Error generating token: %v", err)
+		respondError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	respondJSON(w, http.StatusOK, loginResponse(session))
+}
+
+// ssoRedirect sends the browser back to the web UI's sign-in page with a result in the fragment
+func ssoRedirect(w http.ResponseWriter, r *http.Request, key, value string) {
+	http.Redirect(w, r, "/login#"+url.Values{key: {value}}.Encode(), http.StatusFound)
+}
+
+// requestBaseURL returns the scheme and host the client used to reach this server. The forwarding
+// headers are only read from trusted proxies, or anyone could point the OIDC redirect at their own host.
+func requestBaseURL(r *http.Request) string {
+	proto := "http"
+	if isHTTPS(r) {
+		proto = "https"
+	}
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" && auth.FromTrustedProxy(r) {
+		host = forwarded
+	}
+	return proto + "://" + host
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || (r.Header.Get("X-Forwarded-Proto") == "https" && auth.FromTrustedProxy(r))
+}
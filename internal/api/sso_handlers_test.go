@@ -0,0 +1,42 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+)
+
+func TestRequestBaseURL(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		trusted bool
+		tls     bool
+		headers map[string]string
+		want    string
+	}{
+		{"direct", false, false, nil, "http://streamarr.local:8080"},
+		{"direct TLS", false, true, nil, "https://streamarr.local:8080"},
+		{"forwarded by anyone", false, false, map[string]string{"X-Forwarded-Host": "evil.example", "X-Forwarded-Proto": "https"}, "http://streamarr.local:8080"},
+		{"forwarded by a trusted proxy", true, false, map[string]string{"X-Forwarded-Host": "streamarr.example", "X-Forwarded-Proto": "https"}, "https://streamarr.example"},
+		{"trusted proxy without headers", true, false, nil, "http://streamarr.local:8080"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://streamarr.local:8080/api/v1/auth/oidc/login", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.trusted {
+				r = r.WithContext(auth.WithTrustedProxy(r.Context()))
+			}
+			if got := requestBaseURL(r); got != tt.want {
+				t.Errorf("requestBaseURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
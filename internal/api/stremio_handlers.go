@@ -1,28 +1,42 @@
 package api
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
 	"github.com/Zerr0-C00L/StreamArr/internal/providers"
 	"github.com/gorilla/mux"
 )
 
+// Built-in catalogs that exist regardless of the configured catalog list
+const (
+	stremioSearchMoviesCatalog = "streamarr_search_movies"
+	stremioSearchSeriesCatalog = "streamarr_search_series"
+	stremioLiveTVCatalog       = "streamarr_tv"
+	// stremioChannelPrefix prefixes Live TV channel IDs (base64url of the channel ID)
+	stremioChannelPrefix = "streamarr_tv:"
+)
+
 // StremioManifest represents the Stremio addon manifest
 type StremioManifest struct {
 	ID          string           `json:"id"`
 	Version     string           `json:"version"`
 	Name        string           `json:"name"`
 	Description string           `json:"description"`
-	Resources   []string         `json:"resources"`
+	Resources   []interface{}    `json:"resources"` // Resource names or StremioResource descriptors
 	Types       []string         `json:"types"`
 	Catalogs    []StremioCatalog `json:"catalogs,omitempty"`
 	IDPrefixes  []string         `json:"idPrefixes"`
@@ -30,6 +44,13 @@ type StremioManifest struct {
 	Logo        string           `json:"logo,omitempty"`
 }
 
+// StremioResource describes a resource limited to some types and ID prefixes
+type StremioResource struct {
+	Name       string   `json:"name"`
+	Types      []string `json:"types"`
+	IDPrefixes []string `json:"idPrefixes,omitempty"`
+}
+
 // StremioCatalog represents a catalog in Stremio
 type StremioCatalog struct {
 	Type  string                `json:"type"`
@@ -49,7 +70,7 @@ type StremioCatalogExtra struct {
 type StremioStream struct {
 	Name          string                     `json:"name,omitempty"`
 	Description   string                     `json:"description,omitempty"`
-	URL           string                     `json:"url"`
+	URL           string                     `json:"url,omitempty"`
 	InfoHash      string                     `json:"infoHash,omitempty"`
 	FileIdx       int                        `json:"fileIdx,omitempty"`
 	BehaviorHints StremioStreamBehaviorHints `json:"behaviorHints,omitempty"`
@@ -57,9 +78,17 @@ type StremioStream struct {
 
 // StremioStreamBehaviorHints provides hints to Stremio about stream behavior
 type StremioStreamBehaviorHints struct {
-	NotWebReady bool   `json:"notWebReady,omitempty"`
-	BingeGroup  string `json:"bingeGroup,omitempty"`
-	Filename    string `json:"filename,omitempty"`
+	NotWebReady  bool                 `json:"notWebReady,omitempty"`
+	BingeGroup   string               `json:"bingeGroup,omitempty"`
+	Filename     string               `json:"filename,omitempty"`
+	VideoSize    int64                `json:"videoSize,omitempty"`
+	ProxyHeaders *StremioProxyHeaders `json:"proxyHeaders,omitempty"`
+}
+
+// StremioProxyHeaders are headers Stremio's streaming server sends upstream (only for notWebReady streams)
+type StremioProxyHeaders struct {
+	Request  map[string]string `json:"request,omitempty"`
+	Response map[string]string `json:"response,omitempty"`
 }
 
 // filterValidStreams removes streams with invalid or empty URLs to prevent infinite loading
@@ -138,26 +167,8 @@ func getStremioProxyPosterURL(r *http.Request, posterPath string) string {
 
 // StremioManifestHandler serves the Stremio addon manifest
 func (h *Handler) StremioManifestHandler(w http.ResponseWriter, r *http.Request) {
-	if h.settingsManager == nil {
-		log.Printf("[Stremio] StremioManifestHandler: settingsManager is nil")
-		respondError(w, http.StatusServiceUnavailable, "settings not configured")
-		return
-	}
-
-	settings := h.settingsManager.Get()
-	log.Printf("[Stremio] StremioManifestHandler: enabled=%v, token=%s, query_token=%s", settings.StremioAddon.Enabled, settings.StremioAddon.SharedToken, r.URL.Query().Get("token"))
-
-	// Allow if either: addon is explicitly enabled OR a token has been generated
-	if !settings.StremioAddon.Enabled && settings.StremioAddon.SharedToken == "" {
-		respondError(w, http.StatusNotFound, "Stremio addon is not configured")
-		return
-	}
-
-	// Validate token if provided
-	token := r.URL.Query().Get("token")
-	if settings.StremioAddon.SharedToken != "" && token != settings.StremioAddon.SharedToken {
-		log.Printf("[Stremio] Token validation failed: expected %s, got %s", settings.StremioAddon.SharedToken, token)
-		respondError(w, http.StatusUnauthorized, "invalid token")
+	settings, user, ok := h.stremioAuth(w, r)
+	if !ok {
 		return
 	}
 
@@ -166,45 +177,82 @@ func (h *Handler) StremioManifestHandler(w http.ResponseWriter, r *http.Request)
 		Version:     "1.0.0",
 		Name:        settings.StremioAddon.AddonName,
 		Description: "Stream movies and series from your StreamArr Pro library",
-		Resources:   []string{"stream"},
+		Resources:   []interface{}{"catalog", "stream"},
 		Types:       []string{"movie", "series"},
 		IDPrefixes:  []string{"tt"},
 	}
 
-	// Add catalogs based on configuration
-	if len(settings.StremioAddon.Catalogs) > 0 {
-		catalogs := []StremioCatalog{}
+	// Genre filter options come from the library itself
+	genreOptions := map[string][]string{}
+	if genres, err := h.movieStore.Genres(r.Context()); err == nil {
+		genreOptions["movie"] = genres
+	}
+	if genres, err := h.seriesStore.Genres(r.Context()); err == nil {
+		genreOptions["series"] = genres
+	}
 
-		placement := settings.StremioAddon.CatalogPlacement
-		extra := []StremioCatalogExtra{}
+	placement := settings.StremioAddon.CatalogPlacement
+	extra := []StremioCatalogExtra{}
 
-		// Add skip parameter for pagination
-		if placement == "home" || placement == "both" {
-			extra = append(extra, StremioCatalogExtra{
-				Name: "skip",
-			})
-		}
+	// Add skip parameter for pagination
+	if placement == "home" || placement == "both" {
+		extra = append(extra, StremioCatalogExtra{
+			Name: "skip",
+		})
+	}
 
-		// Add enabled catalogs
-		for _, cat := range settings.StremioAddon.Catalogs {
-			if cat.Enabled {
-				catalog := StremioCatalog{
-					Type:  cat.Type,
-					ID:    cat.ID,
-					Name:  cat.Name,
-					Extra: extra,
-				}
-				catalogs = append(catalogs, catalog)
+	// Add enabled catalogs
+	catalogs := []StremioCatalog{}
+	for _, cat := range settings.StremioAddon.Catalogs {
+		if cat.Enabled {
+			catalogExtra := append([]StremioCatalogExtra{}, extra...)
+			if options := genreOptions[cat.Type]; len(options) > 0 {
+				catalogExtra = append(catalogExtra, StremioCatalogExtra{Name: "genre", Options: options})
 			}
+			catalogs = append(catalogs, StremioCatalog{
+				Type:  cat.Type,
+				ID:    cat.ID,
+				Name:  cat.Name,
+				Extra: catalogExtra,
+			})
 		}
+	}
 
-		if len(catalogs) > 0 {
-			manifest.Catalogs = catalogs
-			// Add catalog resource
-			manifest.Resources = append([]string{"catalog"}, manifest.Resources...)
+	// Library search; search-only catalogs never show up on the board
+	searchExtra := []StremioCatalogExtra{{Name: "search", IsRequired: true}}
+	catalogs = append(catalogs,
+		StremioCatalog{Type: "movie", ID: stremioSearchMoviesCatalog, Name: settings.StremioAddon.AddonName, Extra: searchExtra},
+		StremioCatalog{Type: "series", ID: stremioSearchSeriesCatalog, Name: settings.StremioAddon.AddonName, Extra: searchExtra},
+	)
+
+	metaResource := StremioResource{Name: "meta", Types: []string{"movie", "series"}, IDPrefixes: []string{"tt"}}
+
+	// Live TV channels as a "tv" catalog filtered by channel category
+	if settings.StremioAddon.LiveTVCatalog && h.channelManager != nil {
+		if channels := h.filterEnabledChannels(h.channelManager.GetAllChannels()); len(channels) > 0 {
+			tvExtra := append([]StremioCatalogExtra{}, extra...)
+			tvExtra = append(tvExtra, StremioCatalogExtra{Name: "genre", Options: channelCategories(channels)})
+			catalogs = append(catalogs, StremioCatalog{Type: "tv", ID: stremioLiveTVCatalog, Name: "Live TV", Extra: tvExtra})
+
+			manifest.Types = append(manifest.Types, "tv")
+			manifest.IDPrefixes = append(manifest.IDPrefixes, stremioChannelPrefix)
+			metaResource.Types = append(metaResource.Types, "tv")
+			metaResource.IDPrefixes = append(metaResource.IDPrefixes, stremioChannelPrefix)
 		}
 	}
 
+	// Personal catalogs for per-user tokens
+	if user != nil {
+		manifest.Name = fmt.Sprintf("%s (%s)", settings.StremioAddon.AddonName, user.Username)
+		catalogs = append(stremioUserCatalogs(extra), catalogs...)
+	}
+
+	manifest.Catalogs = catalogs
+	manifest.Resources = append(manifest.Resources, metaResource)
+	if h.subtitles != nil && h.subtitles.Enabled() {
+		manifest.Resources = append(manifest.Resources, StremioResource{Name: "subtitles", Types: []string{"movie", "series"}, IDPrefixes: []string{"tt"}})
+	}
+
 	// Set proper headers
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -220,24 +268,15 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 
 	catalogID := vars["id"] // catalog ID
 
-	log.Printf("[Stremio] StremioCatalogHandler: catalogID=%s, token=%s", catalogID, r.URL.Query().Get("token"))
-
-	if h.settingsManager == nil {
-		log.Printf("[Stremio] StremioCatalogHandler: settingsManager is nil")
-		respondError(w, http.StatusServiceUnavailable, "settings not configured")
-		return
-	}
-
-	settings := h.settingsManager.Get()
-	// Allow if either: addon is explicitly enabled OR a token has been generated
-	if !settings.StremioAddon.Enabled && settings.StremioAddon.SharedToken == "" {
-		log.Printf("[Stremio] StremioCatalogHandler: addon not configured (enabled=%v, token=%s)", settings.StremioAddon.Enabled, settings.StremioAddon.SharedToken)
-		respondError(w, http.StatusNotFound, "catalogs not configured")
+	settings, user, ok := h.stremioAuth(w, r)
+	if !ok {
 		return
 	}
 
-	// Check if this catalog is enabled
-	catalogEnabled := false
+	// Check if this catalog is enabled (search catalogs are always on)
+	catalogEnabled := catalogID == stremioSearchMoviesCatalog || catalogID == stremioSearchSeriesCatalog ||
+		(catalogID == stremioLiveTVCatalog && settings.StremioAddon.LiveTVCatalog) ||
+		(user != nil && (catalogID == stremioWatchlistCatalog || catalogID == stremioContinueCatalog))
 	for _, cat := range settings.StremioAddon.Catalogs {
 		if cat.ID == catalogID && cat.Enabled {
 			catalogEnabled = true
@@ -252,281 +291,24 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Validate token
-	token := r.URL.Query().Get("token")
-	if settings.StremioAddon.SharedToken != "" && token != settings.StremioAddon.SharedToken {
-		log.Printf("[Stremio] StremioCatalogHandler: token validation failed")
+	// Parse extras (skip, search, genre)
+	extra := stremioExtra(r)
+	skip, _ := strconv.Atoi(extra.Get("skip"))
+	limit := 100 // Stremio default
 
-		respondError(w, http.StatusUnauthorized, "invalid token")
+	// Watchlist and continue-watching belong to the token's user
+	if user != nil && (catalogID == stremioWatchlistCatalog || catalogID == stremioContinueCatalog) {
+		writeStremioMetas(w, catalogID, h.stremioUserMetas(ctx, user.UserID, vars["type"], catalogID, skip, limit))
 		return
 	}
 
-	// Parse pagination
-	skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
-	limit := 100 // Stremio default
-
-	var metas []map[string]interface{}
-
-	// Helper function to build movie meta
-	buildMovieMeta := func(movie interface{}) map[string]interface{} {
-		var m struct {
-			ID          int64
-			Title       string
-			PosterPath  string
-			ReleaseDate *time.Time
-			Overview    string
-			Genres      []string
-			Runtime     int
-			Metadata    map[string]interface{}
-		}
-
-		// Type assertion based on actual movie type - will be models.Movie from database
-		if mv, err := json.Marshal(movie); err == nil {
-			json.Unmarshal(mv, &m)
-		}
-
-		// Extract poster path from metadata if not at top level
-		if m.PosterPath == "" && m.Metadata != nil {
-			if poster, ok := m.Metadata["poster_path"].(string); ok {
-				m.PosterPath = poster
-			}
-		}
-
-		// Extract overview from metadata if not at top level
-		if m.Overview == "" && m.Metadata != nil {
-			if overview, ok := m.Metadata["overview"].(string); ok {
-				m.Overview = overview
-			}
-		}
-
-		// Extract genres from metadata if not at top level
-		if len(m.Genres) == 0 && m.Metadata != nil {
-			if genres, ok := m.Metadata["genres"].([]interface{}); ok {
-				m.Genres = make([]string, len(genres))
-				for i, g := range genres {
-					if gs, ok := g.(string); ok {
-						m.Genres[i] = gs
-					}
-				}
-			}
-		}
-
-		// Extract runtime from metadata if not at top level
-		if m.Runtime == 0 && m.Metadata != nil {
-			if runtime, ok := m.Metadata["runtime"].(float64); ok {
-				m.Runtime = int(runtime)
-			}
-		}
-
-		imdbID := ""
-		if m.Metadata != nil {
-			if imdb, ok := m.Metadata["imdb_id"].(string); ok {
-				imdbID = imdb
-			}
-		}
-		if imdbID == "" {
-			return nil
-		}
-
-		// Return metadata with Cinemeta poster URL - minimal for Stremio to fetch full details
-		meta := map[string]interface{}{
-			"id":     imdbID,
-			"type":   "movie",
-			"name":   m.Title,
-			"poster": fmt.Sprintf("https://images.metahub.space/poster/medium/%s/img", imdbID),
-		}
-
-		// Include year if available
-		if m.ReleaseDate != nil {
-			meta["releaseInfo"] = m.ReleaseDate.Format("2006")
-		}
-
-		// Include description
-		if m.Overview != "" {
-			meta["description"] = m.Overview
-		}
-
-		// Include genres
-		if len(m.Genres) > 0 {
-			meta["genres"] = m.Genres
-		}
-
-		// Include runtime
-		if m.Runtime > 0 {
-			meta["runtime"] = fmt.Sprintf("%d min", m.Runtime)
-		}
-
-		// Try to extract extended metadata from JSONB if available
-		if m.Metadata != nil {
-			// IMDB Rating
-			if rating, ok := m.Metadata["vote_average"].(float64); ok && rating > 0 {
-				meta["imdbRating"] = fmt.Sprintf("%.1f", rating)
-			}
-
-			// Cast
-			if cast, ok := m.Metadata["cast"].([]interface{}); ok && len(cast) > 0 {
-				castNames := []string{}
-				for i, c := range cast {
-					if i >= 5 {
-						break
-					}
-					if castMap, ok := c.(map[string]interface{}); ok {
-						if name, ok := castMap["name"].(string); ok && name != "" {
-							castNames = append(castNames, name)
-						}
-					}
-				}
-				if len(castNames) > 0 {
-					meta["cast"] = castNames
-				}
-			}
-
-			// Director
-			if crew, ok := m.Metadata["crew"].([]interface{}); ok && len(crew) > 0 {
-				directors := []string{}
-				for _, c := range crew {
-					if crewMap, ok := c.(map[string]interface{}); ok {
-						if job, ok := crewMap["job"].(string); ok && job == "Director" {
-							if name, ok := crewMap["name"].(string); ok && name != "" {
-								directors = append(directors, name)
-								if len(directors) >= 3 {
-									break
-								}
-							}
-						}
-					}
-				}
-				if len(directors) > 0 {
-					meta["director"] = directors
-				}
-			}
-		}
-
-		return meta
+	// Live TV, search and genre requests are answered from the matching store instead of the catalog's list
+	if search, genre := extra.Get("search"), extra.Get("genre"); catalogID == stremioLiveTVCatalog || search != "" || genre != "" {
+		writeStremioMetas(w, catalogID, h.stremioFilteredMetas(ctx, vars["type"], catalogID, search, genre, skip, limit))
+		return
 	}
 
-	// Helper function to build series meta
-	buildSeriesMeta := func(series interface{}) map[string]interface{} {
-		var s struct {
-			ID           int64
-			Title        string
-			PosterPath   string
-			FirstAirDate *time.Time
-			Overview     string
-			Genres       []string
-			Metadata     map[string]interface{}
-		}
-
-		// Type assertion - will be models.Series from database
-		if sv, err := json.Marshal(series); err == nil {
-			json.Unmarshal(sv, &s)
-		}
-
-		// Extract poster path from metadata if not at top level
-		if s.PosterPath == "" && s.Metadata != nil {
-			if poster, ok := s.Metadata["poster_path"].(string); ok {
-				s.PosterPath = poster
-			}
-		}
-
-		// Extract overview from metadata if not at top level
-		if s.Overview == "" && s.Metadata != nil {
-			if overview, ok := s.Metadata["overview"].(string); ok {
-				s.Overview = overview
-			}
-		}
-
-		// Extract genres from metadata if not at top level
-		if len(s.Genres) == 0 && s.Metadata != nil {
-			if genres, ok := s.Metadata["genres"].([]interface{}); ok {
-				s.Genres = make([]string, len(genres))
-				for i, g := range genres {
-					if gs, ok := g.(string); ok {
-						s.Genres[i] = gs
-					}
-				}
-			}
-		}
-
-		imdbID := ""
-		if s.Metadata != nil {
-			if imdb, ok := s.Metadata["imdb_id"].(string); ok {
-				imdbID = imdb
-			}
-		}
-		if imdbID == "" {
-			return nil
-		}
-
-		// Return metadata with Cinemeta poster URL - minimal for Stremio to fetch full details
-		meta := map[string]interface{}{
-			"id":     imdbID,
-			"type":   "series",
-			"name":   s.Title,
-			"poster": fmt.Sprintf("https://images.metahub.space/poster/medium/%s/img", imdbID),
-		}
-
-		// Include year if available
-		if s.FirstAirDate != nil {
-			meta["releaseInfo"] = s.FirstAirDate.Format("2006")
-		}
-
-		// Include description
-		if s.Overview != "" {
-			meta["description"] = s.Overview
-		}
-
-		// Include genres
-		if len(s.Genres) > 0 {
-			meta["genres"] = s.Genres
-		}
-
-		// Try to extract extended metadata from JSONB if available
-		if s.Metadata != nil {
-			// IMDB Rating
-			if rating, ok := s.Metadata["vote_average"].(float64); ok && rating > 0 {
-				meta["imdbRating"] = fmt.Sprintf("%.1f", rating)
-			}
-
-			// Cast
-			if cast, ok := s.Metadata["cast"].([]interface{}); ok && len(cast) > 0 {
-				castNames := []string{}
-				for i, c := range cast {
-					if i >= 5 {
-						break
-					}
-					if castMap, ok := c.(map[string]interface{}); ok {
-						if name, ok := castMap["name"].(string); ok && name != "" {
-							castNames = append(castNames, name)
-						}
-					}
-				}
-				if len(castNames) > 0 {
-					meta["cast"] = castNames
-				}
-			}
-
-			// Creator (for series)
-			if creators, ok := s.Metadata["created_by"].([]interface{}); ok && len(creators) > 0 {
-				creatorNames := []string{}
-				for i, c := range creators {
-					if i >= 3 {
-						break
-					}
-					if creatorMap, ok := c.(map[string]interface{}); ok {
-						if name, ok := creatorMap["name"].(string); ok && name != "" {
-							creatorNames = append(creatorNames, name)
-						}
-					}
-				}
-				if len(creatorNames) > 0 {
-					meta["director"] = creatorNames
-				}
-			}
-		}
-
-		return meta
-	}
+	var metas []map[string]interface{}
 
 	// Handle different catalog types
 	switch catalogID {
@@ -539,7 +321,7 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		for _, movie := range movies {
-			if meta := buildMovieMeta(movie); meta != nil {
+			if meta := stremioMovieMeta(movie); meta != nil {
 				metas = append(metas, meta)
 			}
 		}
@@ -553,7 +335,7 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		for _, s := range series {
-			if meta := buildSeriesMeta(s); meta != nil {
+			if meta := stremioSeriesMeta(s); meta != nil {
 				metas = append(metas, meta)
 			}
 		}
@@ -568,7 +350,7 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		for _, movie := range movies {
-			if meta := buildMovieMeta(movie); meta != nil {
+			if meta := stremioMovieMeta(movie); meta != nil {
 				metas = append(metas, meta)
 			}
 		}
@@ -582,7 +364,7 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		for _, s := range series {
-			if meta := buildSeriesMeta(s); meta != nil {
+			if meta := stremioSeriesMeta(s); meta != nil {
 				metas = append(metas, meta)
 			}
 		}
@@ -596,7 +378,7 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		for _, movie := range movies {
-			if meta := buildMovieMeta(movie); meta != nil {
+			if meta := stremioMovieMeta(movie); meta != nil {
 				metas = append(metas, meta)
 			}
 		}
@@ -610,7 +392,7 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		for _, s := range series {
-			if meta := buildSeriesMeta(s); meta != nil {
+			if meta := stremioSeriesMeta(s); meta != nil {
 				metas = append(metas, meta)
 			}
 		}
@@ -624,7 +406,7 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		for _, movie := range movies {
-			if meta := buildMovieMeta(movie); meta != nil {
+			if meta := stremioMovieMeta(movie); meta != nil {
 				metas = append(metas, meta)
 			}
 		}
@@ -635,7 +417,15 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	writeStremioMetas(w, catalogID, metas)
+}
+
+// writeStremioMetas writes a catalog response
+func writeStremioMetas(w http.ResponseWriter, catalogID string, metas []map[string]interface{}) {
 	log.Printf("[Stremio] StremioCatalogHandler: returning %d metas for catalog %s", len(metas), catalogID)
+	if metas == nil {
+		metas = []map[string]interface{}{}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -643,113 +433,646 @@ func (h *Handler) StremioCatalogHandler(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// StremioStreamHandler serves streams for Stremio
-func (h *Handler) StremioStreamHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+// stremioExtra merges catalog extras from the path (/catalog/{type}/{id}/{extra}.json, the form
+// Stremio uses) with query parameters
+func stremioExtra(r *http.Request) url.Values {
+	values := r.URL.Query()
+	if raw := mux.Vars(r)["extra"]; raw != "" {
+		if parsed, err := url.ParseQuery(raw); err == nil {
+			for key, v := range parsed {
+				values[key] = v
+			}
+		}
+	}
+	return values
+}
 
-	contentType := vars["type"] // movie or series
-	id := vars["id"]            // IMDB ID (tt123456 or tt123456:1:2 for series)
+// stremioFilteredMetas answers Live TV, search and genre catalog requests
+func (h *Handler) stremioFilteredMetas(ctx context.Context, contentType, catalogID, search, genre string, skip, limit int) []map[string]interface{} {
+	if catalogID == stremioLiveTVCatalog {
+		return h.stremioChannelMetas(search, genre, skip, limit)
+	}
 
-	if h.settingsManager == nil {
-		respondError(w, http.StatusServiceUnavailable, "settings not configured")
-		return
+	// Search results are a single ranked page
+	if search != "" && skip > 0 {
+		return nil
 	}
 
-	settings := h.settingsManager.Get()
-	// Allow if either: addon is explicitly enabled OR a token has been generated
-	if !settings.StremioAddon.Enabled && settings.StremioAddon.SharedToken == "" {
-		respondError(w, http.StatusNotFound, "Stremio addon is not configured")
-		return
+	var metas []map[string]interface{}
+	switch contentType {
+	case "movie":
+		var movies []*models.Movie
+		var err error
+		if search != "" {
+			movies, err = h.movieStore.Search(ctx, search, limit)
+		} else {
+			movies, err = h.movieStore.ListByGenre(ctx, genre, skip, limit)
+		}
+		if err != nil {
+			log.Printf("[Stremio] Failed to fetch movies (search=%q, genre=%q): %v", search, genre, err)
+			return nil
+		}
+		for _, movie := range movies {
+			if search != "" && genre != "" && !containsFold(movie.Genres, genre) {
+				continue
+			}
+			if meta := stremioMovieMeta(movie); meta != nil {
+				metas = append(metas, meta)
+			}
+		}
+
+	case "series":
+		var seriesList []*models.Series
+		var err error
+		if search != "" {
+			seriesList, err = h.seriesStore.Search(ctx, search, limit)
+		} else {
+			seriesList, err = h.seriesStore.ListByGenre(ctx, genre, skip, limit)
+		}
+		if err != nil {
+			log.Printf("[Stremio] Failed to fetch series (search=%q, genre=%q): %v", search, genre, err)
+			return nil
+		}
+		for _, series := range seriesList {
+			if search != "" && genre != "" && !containsFold(metadataStrings(series.Metadata, "genres"), genre) {
+				continue
+			}
+			if meta := stremioSeriesMeta(series); meta != nil {
+				metas = append(metas, meta)
+			}
+		}
 	}
+	return metas
+}
 
-	// Validate token
-	token := r.URL.Query().Get("token")
-	if settings.StremioAddon.SharedToken != "" && token != settings.StremioAddon.SharedToken {
-		respondError(w, http.StatusUnauthorized, "invalid token")
-		return
+// stremioChannelMetas lists Live TV channels, optionally filtered by category (genre) or name
+func (h *Handler) stremioChannelMetas(search, genre string, skip, limit int) []map[string]interface{} {
+	if h.channelManager == nil {
+		return nil
 	}
 
-	// Parse ID
-	parts := strings.Split(id, ":")
-	imdbID := parts[0]
+	search = strings.ToLower(search)
+	var matched []*livetv.Channel
+	for _, ch := range h.filterEnabledChannels(h.channelManager.GetAllChannels()) {
+		if genre != "" && !strings.EqualFold(ch.Category, genre) {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(ch.Name), search) {
+			continue
+		}
+		matched = append(matched, ch)
+	}
+	if skip >= len(matched) {
+		return nil
+	}
+	matched = matched[skip:]
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
 
-	if h.streamProvider == nil {
-		log.Printf("[Stremio] Stream provider not configured, returning empty streams")
-		respondJSON(w, http.StatusOK, map[string]interface{}{"streams": []interface{}{}})
-		return
+	metas := make([]map[string]interface{}, 0, len(matched))
+	for _, ch := range matched {
+		metas = append(metas, h.stremioChannelMeta(ch))
 	}
+	return metas
+}
 
-	var providerStreams []interface{}
-	var err error
+// stremioChannelMeta builds the meta for a Live TV channel with the guide's now/next as description
+func (h *Handler) stremioChannelMeta(ch *livetv.Channel) map[string]interface{} {
+	meta := map[string]interface{}{
+		"id":          stremioChannelPrefix + base64.RawURLEncoding.EncodeToString([]byte(ch.ID)),
+		"type":        "tv",
+		"name":        ch.Name,
+		"posterShape": "square",
+	}
+	if ch.Logo != "" {
+		meta["poster"] = ch.Logo
+		meta["logo"] = ch.Logo
+	}
+	if ch.Category != "" {
+		meta["genres"] = []string{ch.Category}
+	}
+	if description := h.channelGuideDescription(ch); description != "" {
+		meta["description"] = description
+	}
+	return meta
+}
 
-	if contentType == "movie" {
-		log.Printf("[Stremio] Fetching streams for movie %s", imdbID)
-		streams, streamErr := h.streamProvider.GetMovieStreams(imdbID)
-		err = streamErr
-		if err != nil {
-			log.Printf("[Stremio] GetMovieStreams error: %v", err)
-		} else {
-			log.Printf("[Stremio] GetMovieStreams returned %d streams", len(streams))
-		}
-		if err == nil && len(streams) > 0 {
-			// No app-side filtering: use streams as provided by the addon
-			for _, ps := range streams {
-				stream := StremioStream{
-					Name:        fmt.Sprintf("StreamArr - %s", ps.Quality),
-					Description: fmt.Sprintf("%s • %.2f GB", ps.Source, float64(ps.Size)/(1024*1024*1024)),
-					URL:         ps.URL,
-					BehaviorHints: StremioStreamBehaviorHints{
-						NotWebReady: false,
-						Filename:    ps.Title,
-					},
+// channelGuideDescription summarises what is on now and next from the EPG
+func (h *Handler) channelGuideDescription(ch *livetv.Channel) string {
+	if h.epgManager == nil {
+		return ""
+	}
+
+	now := time.Now()
+	current := h.epgManager.GetCurrentProgramWithFallback(ch.ID, ch.Name)
+	var next *livetv.EPGProgram
+	for _, day := range []time.Time{now, now.Add(24 * time.Hour)} {
+		for _, p := range h.epgManager.GetEPGWithFallback(ch.ID, ch.Name, day) {
+			if p.StartTime.After(now) {
+				program := p
+				next = &program
+				break
+			}
+		}
+		if next != nil {
+			break
+		}
+	}
+
+	var lines []string
+	if current != nil {
+		line := fmt.Sprintf("Now: %s (until %s)", current.Title, current.EndTime.Local().Format("15:04"))
+		if current.Description != "" {
+			line += "\n" + current.Description
+		}
+		lines = append(lines, line)
+	}
+	if next != nil {
+		lines = append(lines, fmt.Sprintf("Next: %s (%s)", next.Title, next.StartTime.Local().Format("15:04")))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// stremioChannel resolves a Live TV channel from its Stremio ID
+func (h *Handler) stremioChannel(id string) (*livetv.Channel, bool) {
+	if h.channelManager == nil || !strings.HasPrefix(id, stremioChannelPrefix) {
+		return nil, false
+	}
+	channelID, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(id, stremioChannelPrefix))
+	if err != nil {
+		return nil, false
+	}
+	channel, err := h.channelManager.GetChannel(string(channelID))
+	if err != nil {
+		return nil, false
+	}
+	return channel, true
+}
+
+// channelCategories returns the sorted distinct categories of channels
+func channelCategories(channels []*livetv.Channel) []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, ch := range channels {
+		if ch.Category != "" && !seen[ch.Category] {
+			seen[ch.Category] = true
+			categories = append(categories, ch.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func metadataStrings(metadata map[string]interface{}, key string) []string {
+	values, ok := metadata[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// stremioMovieMeta builds a catalog meta preview for a library movie; nil when it has no IMDB ID
+func stremioMovieMeta(movie interface{}) map[string]interface{} {
+	var m struct {
+		ID          int64
+		Title       string
+		PosterPath  string
+		ReleaseDate *time.Time
+		Overview    string
+		Genres      []string
+		Runtime     int
+		Metadata    map[string]interface{}
+	}
+
+	// Type assertion based on actual movie type - will be models.Movie from database
+	if mv, err := json.Marshal(movie); err == nil {
+		json.Unmarshal(mv, &m)
+	}
+
+	// Extract poster path from metadata if not at top level
+	if m.PosterPath == "" && m.Metadata != nil {
+		if poster, ok := m.Metadata["poster_path"].(string); ok {
+			m.PosterPath = poster
+		}
+	}
+
+	// Extract overview from metadata if not at top level
+	if m.Overview == "" && m.Metadata != nil {
+		if overview, ok := m.Metadata["overview"].(string); ok {
+			m.Overview = overview
+		}
+	}
+
+	// Extract genres from metadata if not at top level
+	if len(m.Genres) == 0 && m.Metadata != nil {
+		if genres, ok := m.Metadata["genres"].([]interface{}); ok {
+			m.Genres = make([]string, len(genres))
+			for i, g := range genres {
+				if gs, ok := g.(string); ok {
+					m.Genres[i] = gs
 				}
+			}
+		}
+	}
+
+	// Extract runtime from metadata if not at top level
+	if m.Runtime == 0 && m.Metadata != nil {
+		if runtime, ok := m.Metadata["runtime"].(float64); ok {
+			m.Runtime = int(runtime)
+		}
+	}
+
+	imdbID := ""
+	if m.Metadata != nil {
+		if imdb, ok := m.Metadata["imdb_id"].(string); ok {
+			imdbID = imdb
+		}
+	}
+	if imdbID == "" {
+		return nil
+	}
 
-				if ps.Cached {
-					stream.Name += " ⚡"
+	// Return metadata with Cinemeta poster URL - minimal for Stremio to fetch full details
+	meta := map[string]interface{}{
+		"id":     imdbID,
+		"type":   "movie",
+		"name":   m.Title,
+		"poster": fmt.Sprintf("https://images.metahub.space/poster/medium/%s/img", imdbID),
+	}
+
+	// Include year if available
+	if m.ReleaseDate != nil {
+		meta["releaseInfo"] = m.ReleaseDate.Format("2006")
+	}
+
+	// Include description
+	if m.Overview != "" {
+		meta["description"] = m.Overview
+	}
+
+	// Include genres
+	if len(m.Genres) > 0 {
+		meta["genres"] = m.Genres
+	}
+
+	// Include runtime
+	if m.Runtime > 0 {
+		meta["runtime"] = fmt.Sprintf("%d min", m.Runtime)
+	}
+
+	// Try to extract extended metadata from JSONB if available
+	if m.Metadata != nil {
+		// IMDB Rating
+		if rating, ok := m.Metadata["vote_average"].(float64); ok && rating > 0 {
+			meta["imdbRating"] = fmt.Sprintf("%.1f", rating)
+		}
+
+		// Cast
+		if cast, ok := m.Metadata["cast"].([]interface{}); ok && len(cast) > 0 {
+			castNames := []string{}
+			for i, c := range cast {
+				if i >= 5 {
+					break
+				}
+				if castMap, ok := c.(map[string]interface{}); ok {
+					if name, ok := castMap["name"].(string); ok && name != "" {
+						castNames = append(castNames, name)
+					}
 				}
+			}
+			if len(castNames) > 0 {
+				meta["cast"] = castNames
+			}
+		}
 
-				providerStreams = append(providerStreams, stream)
+		// Director
+		if crew, ok := m.Metadata["crew"].([]interface{}); ok && len(crew) > 0 {
+			directors := []string{}
+			for _, c := range crew {
+				if crewMap, ok := c.(map[string]interface{}); ok {
+					if job, ok := crewMap["job"].(string); ok && job == "Director" {
+						if name, ok := crewMap["name"].(string); ok && name != "" {
+							directors = append(directors, name)
+							if len(directors) >= 3 {
+								break
+							}
+						}
+					}
+				}
+			}
+			if len(directors) > 0 {
+				meta["director"] = directors
 			}
 		}
-	} else if contentType == "series" && len(parts) == 3 {
-		season, _ := strconv.Atoi(parts[1])
-		episode, _ := strconv.Atoi(parts[2])
+	}
 
-		log.Printf("[Stremio] Fetching streams for series %s S%02dE%02d", imdbID, season, episode)
-		streams, streamErr := h.streamProvider.GetSeriesStreams(imdbID, season, episode)
-		err = streamErr
-		if err != nil {
-			log.Printf("[Stremio] GetSeriesStreams error: %v", err)
-		} else {
-			log.Printf("[Stremio] GetSeriesStreams returned %d streams", len(streams))
-		}
-		if err == nil && len(streams) > 0 {
-			// No app-side filtering: use streams as provided by the addon
-			for _, ps := range streams {
-				stream := StremioStream{
-					Name:        fmt.Sprintf("StreamArr - %s", ps.Quality),
-					Description: fmt.Sprintf("%s • %.2f GB", ps.Source, float64(ps.Size)/(1024*1024*1024)),
-					URL:         ps.URL,
-					BehaviorHints: StremioStreamBehaviorHints{
-						NotWebReady: false,
-						Filename:    ps.Title,
-						BingeGroup:  fmt.Sprintf("streamarr-%s", imdbID),
-					},
+	return meta
+}
+
+// stremioSeriesMeta builds a catalog meta preview for a library series; nil when it has no IMDB ID
+func stremioSeriesMeta(series interface{}) map[string]interface{} {
+	var s struct {
+		ID           int64
+		IMDBID       string `json:"imdb_id"`
+		Title        string
+		PosterPath   string
+		FirstAirDate *time.Time
+		Overview     string
+		Genres       []string
+		Metadata     map[string]interface{}
+	}
+
+	// Type assertion - will be models.Series from database
+	if sv, err := json.Marshal(series); err == nil {
+		json.Unmarshal(sv, &s)
+	}
+
+	// Extract poster path from metadata if not at top level
+	if s.PosterPath == "" && s.Metadata != nil {
+		if poster, ok := s.Metadata["poster_path"].(string); ok {
+			s.PosterPath = poster
+		}
+	}
+
+	// Extract overview from metadata if not at top level
+	if s.Overview == "" && s.Metadata != nil {
+		if overview, ok := s.Metadata["overview"].(string); ok {
+			s.Overview = overview
+		}
+	}
+
+	// Extract genres from metadata if not at top level
+	if len(s.Genres) == 0 && s.Metadata != nil {
+		if genres, ok := s.Metadata["genres"].([]interface{}); ok {
+			s.Genres = make([]string, len(genres))
+			for i, g := range genres {
+				if gs, ok := g.(string); ok {
+					s.Genres[i] = gs
 				}
+			}
+		}
+	}
+
+	imdbID := s.IMDBID
+	if s.Metadata != nil {
+		if imdb, ok := s.Metadata["imdb_id"].(string); ok && imdb != "" {
+			imdbID = imdb
+		}
+	}
+	if imdbID == "" {
+		return nil
+	}
+
+	// Return metadata with Cinemeta poster URL - minimal for Stremio to fetch full details
+	meta := map[string]interface{}{
+		"id":     imdbID,
+		"type":   "series",
+		"name":   s.Title,
+		"poster": fmt.Sprintf("https://images.metahub.space/poster/medium/%s/img", imdbID),
+	}
+
+	// Include year if available
+	if s.FirstAirDate != nil {
+		meta["releaseInfo"] = s.FirstAirDate.Format("2006")
+	}
+
+	// Include description
+	if s.Overview != "" {
+		meta["description"] = s.Overview
+	}
+
+	// Include genres
+	if len(s.Genres) > 0 {
+		meta["genres"] = s.Genres
+	}
 
-				if ps.Cached {
-					stream.Name += " ⚡"
+	// Try to extract extended metadata from JSONB if available
+	if s.Metadata != nil {
+		// IMDB Rating
+		if rating, ok := s.Metadata["vote_average"].(float64); ok && rating > 0 {
+			meta["imdbRating"] = fmt.Sprintf("%.1f", rating)
+		}
+
+		// Cast
+		if cast, ok := s.Metadata["cast"].([]interface{}); ok && len(cast) > 0 {
+			castNames := []string{}
+			for i, c := range cast {
+				if i >= 5 {
+					break
+				}
+				if castMap, ok := c.(map[string]interface{}); ok {
+					if name, ok := castMap["name"].(string); ok && name != "" {
+						castNames = append(castNames, name)
+					}
 				}
+			}
+			if len(castNames) > 0 {
+				meta["cast"] = castNames
+			}
+		}
+
+		// Creator (for series)
+		if creators, ok := s.Metadata["created_by"].([]interface{}); ok && len(creators) > 0 {
+			creatorNames := []string{}
+			for i, c := range creators {
+				if i >= 3 {
+					break
+				}
+				if creatorMap, ok := c.(map[string]interface{}); ok {
+					if name, ok := creatorMap["name"].(string); ok && name != "" {
+						creatorNames = append(creatorNames, name)
+					}
+				}
+			}
+			if len(creatorNames) > 0 {
+				meta["director"] = creatorNames
+			}
+		}
+	}
+
+	return meta
+}
+
+// StremioMetaHandler serves item details for library movies, series (with episodes) and Live TV channels.
+// Items not in the library return 404 so Stremio falls back to Cinemeta.
+func (h *Handler) StremioMetaHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	contentType := vars["type"]
+	id := vars["id"]
+
+	if _, _, ok := h.stremioAuth(w, r); !ok {
+		return
+	}
+
+	var meta map[string]interface{}
+	switch contentType {
+	case "movie":
+		movie, err := h.movieStore.GetByIMDBID(ctx, id)
+		if err != nil {
+			break
+		}
+		if meta = stremioMovieMeta(movie); meta != nil {
+			meta["id"] = id
+			if movie.BackdropPath != "" {
+				meta["background"] = "https://image.tmdb.org/t/p/original" + movie.BackdropPath
+			}
+			meta["behaviorHints"] = map[string]interface{}{"defaultVideoId": id}
+		}
 
-				providerStreams = append(providerStreams, stream)
+	case "series":
+		series, err := h.seriesStore.GetByIMDBID(ctx, id)
+		if err != nil {
+			break
+		}
+		if meta = stremioSeriesMeta(series); meta != nil {
+			meta["id"] = id
+			if series.BackdropPath != "" {
+				meta["background"] = "https://image.tmdb.org/t/p/original" + series.BackdropPath
 			}
+			meta["videos"] = h.stremioEpisodeVideos(ctx, id, series.ID)
 		}
+
+	case "tv":
+		if channel, ok := h.stremioChannel(id); ok {
+			meta = h.stremioChannelMeta(channel)
+		}
+	}
+
+	if meta == nil {
+		respondError(w, http.StatusNotFound, "meta not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"meta": meta,
+	})
+}
+
+// stremioEpisodeVideos lists a series' stored episodes as Stremio videos (tt123:season:episode)
+func (h *Handler) stremioEpisodeVideos(ctx context.Context, imdbID string, seriesID int64) []map[string]interface{} {
+	videos := []map[string]interface{}{}
+	if h.episodeStore == nil {
+		return videos
 	}
 
+	episodes, err := h.episodeStore.ListBySeries(ctx, seriesID)
 	if err != nil {
-		log.Printf("[Stremio] Failed to get streams: %v", err)
+		log.Printf("[Stremio] Failed to list episodes for series %d: %v", seriesID, err)
+		return videos
 	}
 
+	for _, ep := range episodes {
+		title := ep.Title
+		if title == "" {
+			title = fmt.Sprintf("Episode %d", ep.EpisodeNumber)
+		}
+		video := map[string]interface{}{
+			"id":      fmt.Sprintf("%s:%d:%d", imdbID, ep.SeasonNumber, ep.EpisodeNumber),
+			"title":   title,
+			"season":  ep.SeasonNumber,
+			"episode": ep.EpisodeNumber,
+		}
+		if ep.AirDate != nil {
+			video["released"] = ep.AirDate.UTC().Format(time.RFC3339)
+		}
+		if ep.Overview != "" {
+			video["overview"] = ep.Overview
+		}
+		if ep.StillPath != "" {
+			video["thumbnail"] = "https://image.tmdb.org/t/p/w500" + ep.StillPath
+		}
+		videos = append(videos, video)
+	}
+	return videos
+}
+
+// StremioStreamHandler serves streams for Stremio
+func (h *Handler) StremioStreamHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	contentType := vars["type"] // movie or series
+	id := vars["id"]            // IMDB ID (tt123456 or tt123456:1:2 for series)
+
+	settings, user, ok := h.stremioAuth(w, r)
+	if !ok {
+		return
+	}
+
+	// Live TV channels stream straight from the provider
+	if contentType == "tv" {
+		var streams []StremioStream
+		if channel, ok := h.stremioChannel(id); ok && channel.StreamURL != "" {
+			streams = append(streams, StremioStream{
+				Name:        "StreamArr - Live",
+				Description: channel.Name,
+				URL:         channel.StreamURL,
+				BehaviorHints: StremioStreamBehaviorHints{
+					NotWebReady: true,
+				},
+			})
+		}
+		respondJSON(w, http.StatusOK, map[string]interface{}{"streams": streams})
+		return
+	}
+
+	// Parse ID
+	parts := strings.Split(id, ":")
+	imdbID := parts[0]
+
+	// Local media streams carry server-relative paths
+	baseURL := stremioBaseURL(r, settings.StremioAddon.PublicServerURL)
+
+	if h.streamProvider == nil {
+		log.Printf("[Stremio] Stream provider not configured, returning empty streams")
+		respondJSON(w, http.StatusOK, map[string]interface{}{"streams": []interface{}{}})
+		return
+	}
+
+	// Per-user tokens feed continue watching and rank streams by the user's preferences
+	var prefs *database.StremioPreferences
+	if user != nil {
+		h.recordStremioWatch(r.Context(), user.UserID, contentType, parts)
+		prefs = h.stremioPreferences(r.Context(), user.UserID)
+	}
+
+	streams, season, episode := h.fetchStremioStreams(r.Context(), contentType, parts)
+	streams = applyStremioPreferences(streams, prefs)
+
+	opts := stremioStreamOptions{
+		baseURL:  baseURL,
+		item:     contentType + "/" + id,
+		playURL:  stremioPlayURLFunc(r, baseURL, contentType, id),
+		delivery: settings.StremioAddon.StreamProxy,
+		debrid:   h.rdClient != nil && settings.UseRealDebrid && settings.RealDebridAPIKey != "",
+		season:   season,
+		episode:  episode,
+	}
+	if contentType == "series" {
+		opts.bingeGroup = "streamarr-" + imdbID
+	}
+	choice := h.stremioLibraryChoice(r.Context(), contentType, imdbID, season, episode)
+	providerStreams := h.buildStremioStreams(streams, choice, opts)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -760,22 +1083,22 @@ func (h *Handler) StremioStreamHandler(w http.ResponseWriter, r *http.Request) {
 // GenerateStremioToken generates a new random token for Stremio addon access
 func (h *Handler) GenerateStremioToken(w http.ResponseWriter, r *http.Request) {
 	// Generate a secure random token
-	tokenBytes := make([]byte, 32)
-	if _, err := rand.Read(tokenBytes); err != nil {
+	token, err := generateStremioToken()
+	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
 
-	token := hex.EncodeToString(tokenBytes)
-
 	// Update settings with new token
 	if h.settingsManager != nil {
+		before := h.settingsManager.Get()
 		settings := h.settingsManager.Get()
 		settings.StremioAddon.SharedToken = token
 		if err := h.settingsManager.Update(settings); err != nil {
 			respondError(w, http.StatusInternalServerError, "failed to save token")
 			return
 		}
+		h.recordSettingsChange(r.Context(), before, settings)
 	}
 
 	respondJSON(w, http.StatusOK, map[string]string{
@@ -792,38 +1115,23 @@ func (h *Handler) GetStremioManifestURL(w http.ResponseWriter, r *http.Request)
 	}
 
 	settings := h.settingsManager.Get()
-	log.Printf("[Stremio] GetStremioManifestURL: enabled=%v, token=%s, addon=%+v", settings.StremioAddon.Enabled, settings.StremioAddon.SharedToken, settings.StremioAddon)
 
 	// Allow if either: addon is explicitly enabled OR a token has been generated
-	if !settings.StremioAddon.Enabled && settings.StremioAddon.SharedToken == "" {
+	if !settings.StremioAddon.Enabled && settings.StremioAddon.SharedToken == "" && !settings.StremioAddon.PerUserTokens {
 		respondError(w, http.StatusBadRequest, "Stremio addon is not configured - generate a token first")
 		return
 	}
 
 	// Determine base URL: use PublicServerURL if set, otherwise auto-detect from request
-	baseURL := settings.StremioAddon.PublicServerURL
-	if baseURL == "" {
-		// Auto-detect from request host
-		scheme := "http"
-		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
-			scheme = "https"
-		}
-		host := r.Host
-		baseURL = fmt.Sprintf("%s://%s", scheme, host)
-		log.Printf("[Stremio] Auto-detected base URL: %s", baseURL)
-	} else {
-		log.Printf("[Stremio] Using configured base URL: %s", baseURL)
-		// Ensure proper scheme if not provided
-		if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
-			baseURL = "http://" + baseURL
-		}
-	}
+	baseURL := stremioBaseURL(r, settings.StremioAddon.PublicServerURL)
+	log.Printf("[Stremio] Using base URL: %s", baseURL)
 
-	// Build manifest URL
-	manifestURL := fmt.Sprintf("%s/stremio/manifest.json", strings.TrimRight(baseURL, "/"))
-	if settings.StremioAddon.SharedToken != "" {
-		manifestURL += "?token=" + settings.StremioAddon.SharedToken
+	// Per-user tokens are only stored as hashes; creating one returns its manifest URL
+	if settings.StremioAddon.PerUserTokens {
+		respondError(w, http.StatusBadRequest, "per-user Stremio tokens are only shown once - create a new token to get its manifest URL")
+		return
 	}
+	manifestURL := stremioManifestURL(baseURL, settings.StremioAddon.SharedToken)
 
 	respondJSON(w, http.StatusOK, map[string]string{
 		"manifest_url": manifestURL,
@@ -876,3 +1184,20 @@ func (h *Handler) StremioPostersProxyHandler(w http.ResponseWriter, r *http.Requ
 		log.Printf("[Stremio] Failed to write poster response: %v", err)
 	}
 }
+
+// stremioBaseURL returns the public server URL, auto-detected from the request when not configured
+func stremioBaseURL(r *http.Request, configured string) string {
+	if configured == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		return fmt.Sprintf("%s://%s", scheme, r.Host)
+	}
+
+	// Ensure proper scheme if not provided
+	if !strings.HasPrefix(configured, "http://") && !strings.HasPrefix(configured, "https://") {
+		configured = "http://" + configured
+	}
+	return strings.TrimRight(configured, "/")
+}
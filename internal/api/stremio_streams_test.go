@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/Zerr0-C00L/StreamArr/internal/providers"
+)
+
+func TestStremioStreamsNeverHandOutMagnets(t *testing.T) {
+	const hash = "0123456789abcdef0123456789abcdef01234567"
+	streams := []providers.TorrentioStream{
+		{Title: "Movie.2020.1080p.mkv", URL: "magnet:?xt=urn:btih:" + strings.ToUpper(hash) + "&dn=Movie", Cached: true},
+		{Title: "Movie.2020.720p.mkv", URL: "magnet:?dn=no-hash"},
+	}
+	play := func(key string) string { return "http://streamarr/play/" + key }
+
+	for _, delivery := range []string{stremioDeliveryRedirect, stremioDeliveryProxy} {
+		h := &Handler{}
+		opts := stremioStreamOptions{item: "movie/tt1", playURL: play, delivery: delivery, debrid: true}
+		result := h.buildStremioStreams(streams, nil, opts)
+		if len(result) != 1 {
+			t.Fatalf("%s: got %d streams, want only the one naming a torrent", delivery, len(result))
+		}
+		if result[0].URL != play(hash+"-0") {
+			t.Errorf("%s: URL = %q, want the play URL", delivery, result[0].URL)
+		}
+		link, ok := h.stremioLinks.get("movie/tt1/" + hash + "-0")
+		if !ok || link.url != "" || !strings.EqualFold(link.infoHash, hash) {
+			t.Errorf("%s: play URL stands for %+v, want the torrent", delivery, link)
+		}
+
+		// Without debrid, Stremio streams the torrent itself
+		opts.debrid = false
+		result = h.buildStremioStreams(streams, nil, opts)
+		if len(result) != 1 || result[0].URL != "" || result[0].InfoHash != hash {
+			t.Errorf("%s without debrid: got %+v, want a plain torrent", delivery, result)
+		}
+	}
+}
+
+func TestStremioCachedChoiceDropsExpiredLinks(t *testing.T) {
+	const hash = "0123456789abcdef0123456789abcdef01234567"
+	for _, tt := range []struct {
+		name    string
+		choice  models.CachedStream
+		debrid  bool
+		wantURL string
+		ok      bool
+	}{
+		{"usenet token", models.CachedStream{StreamURL: "/usenet/easynews/abc123.mkv"}, false, "", false},
+		{"usenet token with hash", models.CachedStream{StreamURL: "/usenet/easynews/abc123.mkv", StreamHash: hash}, false, "", true},
+		{"magnet", models.CachedStream{StreamURL: "magnet:?xt=urn:btih:" + hash}, false, "", true},
+		{"debrid link", models.CachedStream{StreamURL: "https://rd.example/d/abc", StreamHash: hash}, true, "", true},
+		{"local file", models.CachedStream{StreamURL: "/local/1/ab.mkv"}, true, "/local/1/ab.mkv", true},
+	} {
+		ps, ok := stremioCachedChoice(&tt.choice, stremioStreamOptions{debrid: tt.debrid})
+		if ok != tt.ok || ps.URL != tt.wantURL {
+			t.Errorf("%s: got URL %q, %v; want %q, %v", tt.name, ps.URL, ok, tt.wantURL, tt.ok)
+		}
+	}
+}
+
+func TestStremioLinkMapIsCapped(t *testing.T) {
+	var m stremioLinkMap
+	for i := 0; i < stremioPlayLinkLimit+100; i++ {
+		m.put(fmt.Sprintf("movie/tt%d", i), stremioLink{url: "https://addon.example/resolve"})
+	}
+	if len(m.links) > stremioPlayLinkLimit {
+		t.Errorf("map holds %d links, want at most %d", len(m.links), stremioPlayLinkLimit)
+	}
+	if _, ok := m.get(fmt.Sprintf("movie/tt%d", stremioPlayLinkLimit+99)); !ok {
+		t.Error("the newest link was evicted")
+	}
+}
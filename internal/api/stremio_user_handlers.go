@@ -0,0 +1,520 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/providers"
+	"github.com/Zerr0-C00L/StreamArr/internal/settings"
+	"github.com/gorilla/mux"
+)
+
+const (
+	stremioWatchlistCatalog = "streamarr_watchlist"
+	stremioContinueCatalog  = "streamarr_continue"
+)
+
+// stremioAuth checks the addon is configured and resolves the caller's token, taken from the path
+// (/stremio/{token}/manifest.json) or the legacy ?token= query. The returned user token is nil for
+// shared-token and open access.
+func (h *Handler) stremioAuth(w http.ResponseWriter, r *http.Request) (*settings.Settings, *database.StremioToken, bool) {
+	if h.settingsManager == nil {
+		respondError(w, http.StatusServiceUnavailable, "settings not configured")
+		return nil, nil, false
+	}
+
+	cfg := h.settingsManager.Get()
+	addon := cfg.StremioAddon
+	// Allow if either: addon is explicitly enabled OR a token has been generated
+	if !addon.Enabled && addon.SharedToken == "" && !addon.PerUserTokens {
+		respondError(w, http.StatusNotFound, "Stremio addon is not configured")
+		return nil, nil, false
+	}
+
+	token := mux.Vars(r)["token"]
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	if !addon.PerUserTokens && addon.SharedToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(addon.SharedToken)) == 1 {
+		return cfg, nil, true
+	}
+	if token != "" && h.stremioUserStore != nil {
+		if userToken, err := h.stremioUserStore.Authenticate(r.Context(), token); err == nil {
+			return cfg, userToken, true
+		}
+	}
+	if !addon.PerUserTokens && addon.SharedToken == "" {
+		return cfg, nil, true
+	}
+
+	log.Printf("[Stremio] Rejected addon request from %s: missing, invalid or revoked token", r.RemoteAddr)
+	respondError(w, http.StatusUnauthorized, "invalid token")
+	return nil, nil, false
+}
+
+// generateStremioToken returns a random 64-character hex token
+func generateStremioToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tokenBytes), nil
+}
+
+// stremioManifestURL puts the token in the path: Stremio derives catalog, meta and stream URLs from
+// the manifest URL minus /manifest.json, so a query-string token would not reach them
+func stremioManifestURL(baseURL, token string) string {
+	if token == "" {
+		return baseURL + "/stremio/manifest.json"
+	}
+	return fmt.Sprintf("%s/stremio/%s/manifest.json", baseURL, token)
+}
+
+// stremioUserCatalogs are the personal catalogs offered to per-user tokens
+func stremioUserCatalogs(extra []StremioCatalogExtra) []StremioCatalog {
+	return []StremioCatalog{
+		{Type: "movie", ID: stremioContinueCatalog, Name: "Continue Watching", Extra: extra},
+		{Type: "series", ID: stremioContinueCatalog, Name: "Continue Watching", Extra: extra},
+		{Type: "movie", ID: stremioWatchlistCatalog, Name: "My Watchlist", Extra: extra},
+		{Type: "series", ID: stremioWatchlistCatalog, Name: "My Watchlist", Extra: extra},
+	}
+}
+
+// stremioUserMetas lists a user's watchlist or continue-watching items of one type
+func (h *Handler) stremioUserMetas(ctx context.Context, userID int, contentType, catalogID string, skip, limit int) []map[string]interface{} {
+	if h.stremioUserStore == nil || (contentType != "movie" && contentType != "series") {
+		return nil
+	}
+
+	var entries []*database.StremioLibraryEntry
+	var err error
+	if catalogID == stremioWatchlistCatalog {
+		entries, err = h.stremioUserStore.Watchlist(ctx, userID, contentType, skip, limit)
+	} else {
+		entries, err = h.stremioUserStore.ContinueWatching(ctx, userID, contentType, skip, limit)
+	}
+	if err != nil {
+		log.Printf("[Stremio] Failed to load %s for user %d: %v", catalogID, userID, err)
+		return nil
+	}
+
+	var metas []map[string]interface{}
+	for _, entry := range entries {
+		var meta map[string]interface{}
+		if contentType == "movie" {
+			if movie, err := h.movieStore.Get(ctx, entry.MediaID); err == nil {
+				meta = stremioMovieMeta(movie)
+			}
+		} else if series, err := h.seriesStore.Get(ctx, entry.MediaID); err == nil {
+			meta = stremioSeriesMeta(series)
+			if meta != nil && catalogID == stremioContinueCatalog && entry.Season > 0 {
+				meta["releaseInfo"] = fmt.Sprintf("S%02dE%02d", entry.Season, entry.Episode)
+			}
+		}
+		if meta != nil {
+			metas = append(metas, meta)
+		}
+	}
+	return metas
+}
+
+// recordStremioWatch puts a library item requested for playback on the user's continue-watching list
+func (h *Handler) recordStremioWatch(ctx context.Context, userID int, contentType string, parts []string) {
+	if h.stremioUserStore == nil {
+		return
+	}
+
+	var err error
+	switch {
+	case contentType == "movie":
+		movie, lookupErr := h.movieStore.GetByIMDBID(ctx, parts[0])
+		if lookupErr != nil {
+			return
+		}
+		err = h.stremioUserStore.RecordWatch(ctx, userID, "movie", movie.ID, 0, 0)
+	case contentType == "series" && len(parts) == 3:
+		series, lookupErr := h.seriesStore.GetByIMDBID(ctx, parts[0])
+		if lookupErr != nil {
+			return
+		}
+		season, _ := strconv.Atoi(parts[1])
+		episode, _ := strconv.Atoi(parts[2])
+		err = h.stremioUserStore.RecordWatch(ctx, userID, "series", series.ID, season, episode)
+	}
+	if err != nil {
+		log.Printf("[Stremio] Failed to record watch for user %d: %v", userID, err)
+	}
+}
+
+// stremioPreferences returns the user's stream ranking preferences, or nil when none are set
+func (h *Handler) stremioPreferences(ctx context.Context, userID int) *database.StremioPreferences {
+	if h.stremioUserStore == nil {
+		return nil
+	}
+	prefs, err := h.stremioUserStore.GetPreferences(ctx, userID)
+	if err != nil {
+		log.Printf("[Stremio] Failed to load preferences for user %d: %v", userID, err)
+		return nil
+	}
+	return &prefs
+}
+
+// applyStremioPreferences drops streams outside the user's limits and moves preferred languages
+// (then cached streams, if asked) to the top, keeping the provider's order otherwise
+func applyStremioPreferences(streams []providers.TorrentioStream, prefs *database.StremioPreferences) []providers.TorrentioStream {
+	if prefs == nil || len(streams) == 0 {
+		return streams
+	}
+
+	languageMatch := func(ps providers.TorrentioStream) bool {
+		text := strings.ToLower(ps.Name + " " + ps.Title)
+		for _, lang := range prefs.PreferredLanguages {
+			if lang != "" && strings.Contains(text, strings.ToLower(lang)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	filtered := make([]providers.TorrentioStream, 0, len(streams))
+	for _, ps := range streams {
+		resolution := parseQualityValue(ps.Quality)
+		if resolution == 0 {
+			resolution = parseQualityValue(ps.Title)
+		}
+		if resolution > 0 && prefs.MaxResolution > 0 && resolution > prefs.MaxResolution {
+			continue
+		}
+		if resolution > 0 && prefs.MinResolution > 0 && resolution < prefs.MinResolution {
+			continue
+		}
+		if prefs.MaxSizeGB > 0 && ps.Size > 0 && float64(ps.Size) > prefs.MaxSizeGB*(1<<30) {
+			continue
+		}
+		if containsKeyword(ps.Name+" "+ps.Title, prefs.ExcludeKeywords) {
+			continue
+		}
+		filtered = append(filtered, ps)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		li, lj := languageMatch(filtered[i]), languageMatch(filtered[j])
+		if li != lj {
+			return li
+		}
+		if prefs.PreferCached && filtered[i].Cached != filtered[j].Cached {
+			return filtered[i].Cached
+		}
+		return false
+	})
+
+	if prefs.MaxStreams > 0 && len(filtered) > prefs.MaxStreams {
+		filtered = filtered[:prefs.MaxStreams]
+	}
+	return filtered
+}
+
+func containsKeyword(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListStremioTokens handles GET /api/stremio/tokens (users with users.manage may pass ?all=true)
+func (h *Handler) ListStremioTokens(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if h.stremioUserStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "per-user tokens not available")
+		return
+	}
+
+	userID := claims.UserID
+	if claims.Can(auth.PermUsersManage) && r.URL.Query().Get("all") == "true" {
+		userID = 0
+	}
+	tokens, err := h.stremioUserStore.ListTokens(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	baseURL := stremioBaseURL(r, h.settingsManager.Get().StremioAddon.PublicServerURL)
+	result := make([]map[string]interface{}, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, stremioTokenResponse(t, baseURL, t.UserID == claims.UserID))
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// CreateStremioToken handles POST /api/stremio/tokens; users with users.manage may issue tokens for other users
+func (h *Handler) CreateStremioToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if h.stremioUserStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "per-user tokens not available")
+		return
+	}
+
+	var req struct {
+		Name   string `json:"name"`
+		UserID int    `json:"user_id"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	userID := claims.UserID
+	if req.UserID != 0 && req.UserID != claims.UserID {
+		if !claims.Can(auth.PermUsersManage) {
+			respondError(w, http.StatusForbidden, "users.manage permission required")
+			return
+		}
+		if _, err := h.userStore.GetUserByID(req.UserID); err != nil {
+			respondError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		userID = req.UserID
+	}
+	if req.Name == "" {
+		req.Name = "Stremio"
+	}
+
+	token, err := generateStremioToken()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	created, err := h.stremioUserStore.CreateToken(r.Context(), userID, token, req.Name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("[Stremio] %s issued addon token %d (%q) for %s", claims.Username, created.ID, created.Name, created.Username)
+	baseURL := stremioBaseURL(r, h.settingsManager.Get().StremioAddon.PublicServerURL)
+	respondJSON(w, http.StatusCreated, stremioTokenResponse(created, baseURL, true))
+}
+
+// RevokeStremioToken handles DELETE /api/stremio/tokens/{id}
+func (h *Handler) RevokeStremioToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if h.stremioUserStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "per-user tokens not available")
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid token id")
+		return
+	}
+	token, err := h.stremioUserStore.GetToken(r.Context(), id)
+	if err != nil || (token.UserID != claims.UserID && !claims.Can(auth.PermUsersManage)) {
+		respondError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	if err := h.stremioUserStore.RevokeToken(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusConflict, "token already revoked")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("[Stremio] %s revoked addon token %d of %s", claims.Username, id, token.Username)
+	respondJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// stremioTokenResponse only includes the token itself (and its manifest URL) for the token's owner,
+// and only when it was just created: afterwards just its hash is stored
+func stremioTokenResponse(t *database.StremioToken, baseURL string, owner bool) map[string]interface{} {
+	resp := map[string]interface{}{
+		"id":           t.ID,
+		"user_id":      t.UserID,
+		"username":     t.Username,
+		"prefix":       t.Prefix,
+		"name":         t.Name,
+		"created_at":   t.CreatedAt,
+		"last_used_at": t.LastUsedAt,
+		"revoked_at":   t.RevokedAt,
+		"revoked":      t.RevokedAt != nil,
+	}
+	if owner && t.RevokedAt == nil && t.Token != "" {
+		resp["token"] = t.Token
+		resp["manifest_url"] = stremioManifestURL(baseURL, t.Token)
+	}
+	return resp
+}
+
+// GetStremioPreferences handles GET /api/stremio/preferences
+func (h *Handler) GetStremioPreferences(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if h.stremioUserStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "per-user tokens not available")
+		return
+	}
+
+	prefs, err := h.stremioUserStore.GetPreferences(r.Context(), claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, prefs)
+}
+
+// UpdateStremioPreferences handles PUT /api/stremio/preferences
+func (h *Handler) UpdateStremioPreferences(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if h.stremioUserStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "per-user tokens not available")
+		return
+	}
+
+	var prefs database.StremioPreferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if prefs.MaxResolution < 0 || prefs.MinResolution < 0 || prefs.MaxSizeGB < 0 || prefs.MaxStreams < 0 {
+		respondError(w, http.StatusBadRequest, "limits must not be negative")
+		return
+	}
+	if err := h.stremioUserStore.SavePreferences(r.Context(), claims.UserID, prefs); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, prefs)
+}
+
+// GetStremioLibrary handles GET /api/stremio/library: the current user's watchlist and continue watching
+func (h *Handler) GetStremioLibrary(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if h.stremioUserStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "per-user tokens not available")
+		return
+	}
+
+	ctx := r.Context()
+	result := map[string]interface{}{}
+	for _, mediaType := range []string{"movie", "series"} {
+		watchlist, err := h.stremioUserStore.Watchlist(ctx, claims.UserID, mediaType, 0, 500)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		continueWatching, err := h.stremioUserStore.ContinueWatching(ctx, claims.UserID, mediaType, 0, 100)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		result[mediaType] = map[string]interface{}{
+			"watchlist":         watchlist,
+			"continue_watching": continueWatching,
+		}
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// UpdateStremioWatchlist handles PUT and DELETE /api/stremio/watchlist/{type}/{id} (library ID)
+func (h *Handler) UpdateStremioWatchlist(w http.ResponseWriter, r *http.Request) {
+	claims, mediaType, mediaID, ok := h.stremioLibraryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	inWatchlist := r.Method == http.MethodPut
+	if err := h.stremioUserStore.SetWatchlist(r.Context(), claims.UserID, mediaType, mediaID, inWatchlist); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"in_watchlist": inWatchlist})
+}
+
+// DismissStremioContinue handles DELETE /api/stremio/continue/{type}/{id} (library ID)
+func (h *Handler) DismissStremioContinue(w http.ResponseWriter, r *http.Request) {
+	claims, mediaType, mediaID, ok := h.stremioLibraryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.stremioUserStore.ClearWatch(r.Context(), claims.UserID, mediaType, mediaID); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+// stremioLibraryRequest validates the user and the {type}/{id} library item of a watchlist request
+func (h *Handler) stremioLibraryRequest(w http.ResponseWriter, r *http.Request) (*auth.Claims, string, int64, bool) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return nil, "", 0, false
+	}
+	if h.stremioUserStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "per-user tokens not available")
+		return nil, "", 0, false
+	}
+
+	vars := mux.Vars(r)
+	mediaType := vars["type"]
+	mediaID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil || (mediaType != "movie" && mediaType != "series") {
+		respondError(w, http.StatusBadRequest, "expected /movie/{id} or /series/{id}")
+		return nil, "", 0, false
+	}
+
+	if mediaType == "movie" {
+		_, err = h.movieStore.Get(r.Context(), mediaID)
+	} else {
+		_, err = h.seriesStore.Get(r.Context(), mediaID)
+	}
+	if err != nil {
+		respondError(w, http.StatusNotFound, mediaType+" not found")
+		return nil, "", 0, false
+	}
+	return claims, mediaType, mediaID, true
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/subtitles"
+	"github.com/gorilla/mux"
+)
+
+// StremioSubtitle is one entry of a Stremio subtitles response
+type StremioSubtitle struct {
+	ID   string `json:"id"`
+	URL  string `json:"url"`
+	Lang string `json:"lang"` // ISO 639-2 code
+}
+
+// subtitleTrack is a subtitle offered to the web player
+type subtitleTrack struct {
+	ID              int64  `json:"id"`
+	Language        string `json:"language"` // OpenSubtitles code (ISO 639-1)
+	Lang            string `json:"lang"`     // ISO 639-2 code
+	Release         string `json:"release"`
+	HashMatch       bool   `json:"hash_match"`
+	HearingImpaired bool   `json:"hearing_impaired"`
+	URL             string `json:"url"`     // WebVTT, for <track> elements
+	SRTURL          string `json:"srt_url"` // SubRip, for download
+}
+
+// subtitleLanguages returns a user's subtitle languages (nil uses the server default)
+func (h *Handler) subtitleLanguages(userID int) []string {
+	if h.userStore == nil || userID == 0 {
+		return nil
+	}
+	langs, err := h.userStore.GetSubtitleLanguages(userID)
+	if err != nil {
+		log.Printf("[SUBTITLES] ⚠️ Cannot load languages for user %d: %v", userID, err)
+		return nil
+	}
+	return subtitles.ParseLanguages(langs)
+}
+
+// localSubtitleHash hashes the item's local file, when there is one, so synced subtitles rank first
+func (h *Handler) localSubtitleHash(ctx context.Context, q *subtitles.Query) {
+	if h.localMedia == nil || q.Hash != "" {
+		return
+	}
+	files, err := h.localMedia.MovieFiles(ctx, q.IMDBID)
+	if q.Season > 0 || q.Episode > 0 {
+		files, err = h.localMedia.EpisodeFiles(ctx, q.IMDBID, q.Season, q.Episode)
+	}
+	if err != nil {
+		return
+	}
+	hash, filename := subtitles.HashLocalFile(files)
+	q.Hash = hash
+	if q.Filename == "" {
+		q.Filename = filename
+	}
+}
+
+// searchSubtitles runs a search, treating a disabled service as no results
+func (h *Handler) searchSubtitles(ctx context.Context, q subtitles.Query) ([]subtitles.Result, error) {
+	if h.subtitles == nil || !h.subtitles.Enabled() {
+		return nil, nil
+	}
+	h.localSubtitleHash(ctx, &q)
+	results, err := h.subtitles.Search(ctx, q)
+	if errors.Is(err, subtitles.ErrDisabled) {
+		return nil, nil
+	}
+	return results, err
+}
+
+// respondSubtitleTracks writes search results for the web player
+func (h *Handler) respondSubtitleTracks(w http.ResponseWriter, r *http.Request, q subtitles.Query) {
+	if langs := r.URL.Query().Get("languages"); langs != "" {
+		q.Languages = subtitles.ParseLanguages(langs)
+	} else if claims, ok := auth.GetUserFromContext(r.Context()); ok {
+		q.Languages = h.subtitleLanguages(claims.UserID)
+	}
+
+	results, err := h.searchSubtitles(r.Context(), q)
+	if err != nil {
+		log.Printf("[SUBTITLES] ❌ Search for %s failed: %v", q.IMDBID, err)
+		respondError(w, http.StatusBadGateway, "subtitle search failed")
+		return
+	}
+
+	tracks := make([]subtitleTrack, 0, len(results))
+	for _, res := range results {
+		tracks = append(tracks, subtitleTrack{
+			ID:              res.FileID,
+			Language:        res.Language,
+			Lang:            subtitles.ToISO6392(res.Language),
+			Release:         res.Release,
+			HashMatch:       res.HashMatch,
+			HearingImpaired: res.HearingImpaired,
+			URL:             h.subtitles.FilePath(res.FileID, "vtt"),
+			SRTURL:          h.subtitles.FilePath(res.FileID, "srt"),
+		})
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"subtitles": tracks,
+	})
+}
+
+// GetMovieSubtitles handles GET /api/v1/movies/{id}/subtitles
+func (h *Handler) GetMovieSubtitles(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid movie ID")
+		return
+	}
+	movie, err := h.movieStore.Get(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "movie not found")
+		return
+	}
+	imdbID, _ := movie.Metadata["imdb_id"].(string)
+	if imdbID == "" {
+		respondJSON(w, http.StatusOK, map[string]interface{}{"subtitles": []subtitleTrack{}})
+		return
+	}
+	h.respondSubtitleTracks(w, r, subtitles.Query{IMDBID: imdbID, Filename: r.URL.Query().Get("filename")})
+}
+
+// GetEpisodeSubtitles handles GET /api/v1/series/{id}/subtitles?season=1&episode=2
+func (h *Handler) GetEpisodeSubtitles(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid series ID")
+		return
+	}
+	season, err1 := strconv.Atoi(r.URL.Query().Get("season"))
+	episode, err2 := strconv.Atoi(r.URL.Query().Get("episode"))
+	if err1 != nil || err2 != nil {
+		respondError(w, http.StatusBadRequest, "season and episode are required")
+		return
+	}
+	series, err := h.seriesStore.Get(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "series not found")
+		return
+	}
+	imdbID := series.IMDBID
+	if imdb, ok := series.Metadata["imdb_id"].(string); ok && imdb != "" {
+		imdbID = imdb
+	}
+	if imdbID == "" {
+		respondJSON(w, http.StatusOK, map[string]interface{}{"subtitles": []subtitleTrack{}})
+		return
+	}
+	h.respondSubtitleTracks(w, r, subtitles.Query{
+		IMDBID:   imdbID,
+		Season:   season,
+		Episode:  episode,
+		Filename: r.URL.Query().Get("filename"),
+	})
+}
+
+// StremioSubtitlesHandler handles the Stremio subtitles resource for library items.
+// Stremio passes the playing file's videoHash and filename as extras
+func (h *Handler) StremioSubtitlesHandler(w http.ResponseWriter, r *http.Request) {
+	settings, user, ok := h.stremioAuth(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	contentType := vars["type"]
+	parts := strings.Split(vars["id"], ":")
+	subs := []StremioSubtitle{}
+
+	q := subtitles.Query{IMDBID: parts[0]}
+	inLibrary := false
+	switch contentType {
+	case "movie":
+		_, err := h.movieStore.GetByIMDBID(r.Context(), q.IMDBID)
+		inLibrary = err == nil
+	case "series":
+		if len(parts) == 3 {
+			q.Season, _ = strconv.Atoi(parts[1])
+			q.Episode, _ = strconv.Atoi(parts[2])
+			_, err := h.seriesStore.GetByIMDBID(r.Context(), q.IMDBID)
+			inLibrary = err == nil
+		}
+	}
+	if !inLibrary {
+		respondJSON(w, http.StatusOK, map[string]interface{}{"subtitles": subs})
+		return
+	}
+
+	extra := stremioExtra(r)
+	q.Hash = extra.Get("videoHash")
+	q.Filename = extra.Get("filename")
+	if user != nil {
+		q.Languages = h.subtitleLanguages(user.UserID)
+	}
+
+	results, err := h.searchSubtitles(r.Context(), q)
+	if err != nil {
+		log.Printf("[Stremio] Subtitle search for %s failed: %v", vars["id"], err)
+	}
+
+	baseURL := stremioBaseURL(r, settings.StremioAddon.PublicServerURL)
+	for _, res := range results {
+		subs = append(subs, StremioSubtitle{
+			ID:   strconv.FormatInt(res.FileID, 10),
+			URL:  baseURL + h.subtitles.FilePath(res.FileID, "vtt"),
+			Lang: subtitles.ToISO6392(res.Language),
+		})
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"subtitles": subs})
+}
+
+// ServeSubtitleFile serves a signed subtitle file as WebVTT or SRT
+func (h *Handler) ServeSubtitleFile(w http.ResponseWriter, r *http.Request) {
+	if h.subtitles == nil {
+		http.NotFound(w, r)
+		return
+	}
+	vars := mux.Vars(r)
+	data, err := h.subtitles.Open(r.Context(), vars["id"], vars["sig"], vars["ext"])
+	if err != nil {
+		log.Printf("[SUBTITLES] ❌ Cannot serve subtitle %s: %v", vars["id"], err)
+		http.Error(w, "Subtitle not available", http.StatusNotFound)
+		return
+	}
+
+	contentType := "application/x-subrip; charset=utf-8"
+	if vars["ext"] == "vtt" {
+		contentType = "text/vtt; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(data)
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLoginLocked      = errors.New("too many failed sign-in attempts, try again later")
+	ErrLoginRateLimited = errors.New("too many sign-in attempts, slow down")
+)
+
+// loginPolicy limits sign-in attempts for one kind of key (client IP or account)
+type loginPolicy struct {
+	attemptsPerMinute int
+	freeFailures      int           // Consecutive failures before the first lockout
+	baseLockout       time.Duration // Doubles with every failure after that
+	maxLockout        time.Duration
+}
+
+var (
+	// An IP may try a few accounts (a household behind one address), so it gets more room than an account
+	ipLoginPolicy      = loginPolicy{attemptsPerMinute: 20, freeFailures: 10, baseLockout: time.Minute, maxLockout: time.Hour}
+	accountLoginPolicy = loginPolicy{attemptsPerMinute: 10, freeFailures: 5, baseLockout: time.Minute, maxLockout: time.Hour}
+)
+
+const (
+	failureMemory    = 24 * time.Hour // Failures are forgotten after this long without another one
+	guardSweepPeriod = 10 * time.Minute
+)
+
+// Lockout describes an IP address or account that has just been locked out
+type Lockout struct {
+	IP       string // Set for IP lockouts
+	Account  string // Set for account lockouts
+	Failures int
+	Duration time.Duration
+}
+
+type loginState struct {
+	windowStart time.Time // Start of the current one-minute rate window
+	attempts    int
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginGuard throttles sign-in attempts per client IP and per account, and locks either out for a
+// period that doubles with every further consecutive failure. State is kept in memory.
+// A nil guard allows everything.
+type LoginGuard struct {
+	mu        sync.Mutex
+	entries   map[string]*loginState
+	lastSweep time.Time
+	onLockout func(Lockout)
+}
+
+// NewLoginGuard creates a login guard
+func NewLoginGuard() *LoginGuard {
+	return &LoginGuard{entries: make(map[string]*loginState), lastSweep: time.Now()}
+}
+
+// SetLockoutHandler sets a function called (outside the guard's lock) whenever an IP or account is locked out
+func (g *LoginGuard) SetLockoutHandler(fn func(Lockout)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onLockout = fn
+}
+
+func ipKey(ip string) string           { return "ip|" + ip }
+func accountKey(account string) string { return "account|" + strings.ToLower(account) }
+
+// Allow counts a sign-in attempt. It returns ErrLoginLocked or ErrLoginRateLimited, with how long to
+// wait, when the IP or account may not try now.
+func (g *LoginGuard) Allow(ip, account string) (time.Duration, error) {
+	if g == nil {
+		return 0, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	g.sweep(now)
+
+	if wait := g.lockedFor(ip, account, now); wait > 0 {
+		return wait, ErrLoginLocked
+	}
+
+	var wait time.Duration
+	check := func(key string, policy loginPolicy) {
+		st := g.state(key)
+		if now.Sub(st.windowStart) >= time.Minute {
+			st.windowStart = now
+			st.attempts = 0
+		}
+		st.attempts++
+		if st.attempts > policy.attemptsPerMinute {
+			if w := st.windowStart.Add(time.Minute).Sub(now); w > wait {
+				wait = w
+			}
+		}
+	}
+	if ip != "" {
+		check(ipKey(ip), ipLoginPolicy)
+	}
+	if account != "" {
+		check(accountKey(account), accountLoginPolicy)
+	}
+	if wait > 0 {
+		return wait, ErrLoginRateLimited
+	}
+	return 0, nil
+}
+
+// Locked returns how long the IP or account is still locked out, without counting an attempt.
+// Used where clients retry valid credentials often (Xtream), so only failures are limited.
+func (g *LoginGuard) Locked(ip, account string) time.Duration {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lockedFor(ip, account, time.Now())
+}
+
+// Failure records a failed sign-in, locking out the IP or account once it has failed too often
+func (g *LoginGuard) Failure(ip, account string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	now := time.Now()
+	g.sweep(now)
+	var lockouts []Lockout
+	fail := func(key string, policy loginPolicy) (time.Duration, int) {
+		st := g.state(key)
+		if now.Sub(st.lastFailure) > failureMemory {
+			st.failures = 0
+		}
+		st.failures++
+		st.lastFailure = now
+		if st.failures <= policy.freeFailures {
+			return 0, st.failures
+		}
+		d := policy.maxLockout
+		if shift := st.failures - policy.freeFailures - 1; shift < 16 {
+			d = min(policy.baseLockout<<shift, policy.maxLockout)
+		}
+		st.lockedUntil = now.Add(d)
+		return d, st.failures
+	}
+	if ip != "" {
+		if d, n := fail(ipKey(ip), ipLoginPolicy); d > 0 {
+			lockouts = append(lockouts, Lockout{IP: ip, Failures: n, Duration: d})
+		}
+	}
+	if account != "" {
+		if d, n := fail(accountKey(account), accountLoginPolicy); d > 0 {
+			lockouts = append(lockouts, Lockout{Account: account, Failures: n, Duration: d})
+		}
+	}
+	onLockout := g.onLockout
+	g.mu.Unlock()
+
+	if onLockout != nil {
+		for _, l := range lockouts {
+			onLockout(l)
+		}
+	}
+}
+
+// Success clears an account's failures. The IP's failures stand, so signing in to one account
+// doesn't reset a password spray against others.
+func (g *LoginGuard) Success(account string) {
+	if g == nil || account == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if st, ok := g.entries[accountKey(account)]; ok {
+		st.failures = 0
+		st.lockedUntil = time.Time{}
+	}
+}
+
+func (g *LoginGuard) state(key string) *loginState {
+	st, ok := g.entries[key]
+	if !ok {
+		st = &loginState{}
+		g.entries[key] = st
+	}
+	return st
+}
+
+func (g *LoginGuard) lockedFor(ip, account string, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, key := range []string{ipKey(ip), accountKey(account)} {
+		if st, ok := g.entries[key]; ok {
+			if w := st.lockedUntil.Sub(now); w > wait {
+				wait = w
+			}
+		}
+	}
+	return wait
+}
+
+// sweep drops entries with nothing left to remember; the caller holds the lock
+func (g *LoginGuard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < guardSweepPeriod {
+		return
+	}
+	g.lastSweep = now
+	for key, st := range g.entries {
+		if now.After(st.lockedUntil) && now.Sub(st.lastFailure) > failureMemory && now.Sub(st.windowStart) > time.Minute {
+			delete(g.entries, key)
+		}
+	}
+}
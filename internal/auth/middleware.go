@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"log"
+	"net"
 	"net/http"
 	"strings"
 )
@@ -12,25 +14,65 @@ const (
 	UserContextKey contextKey = "user"
 )
 
-// SessionMiddleware validates JWT tokens from requests
-func SessionMiddleware(next http.Handler) http.Handler {
+// SessionMiddleware authenticates /api requests with an API key (X-API-Key), the user header of a trusted
+// reverse proxy when proxy authentication is on, or an access token (Authorization: Bearer)
+func (s *Service) SessionMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 
 		// Skip auth for login/public endpoints
 		if path == "/api/v1/auth/login" ||
+			path == "/api/v1/auth/login/2fa" ||
+			path == "/api/v1/auth/refresh" ||
+			path == "/api/v1/auth/logout" ||
 			path == "/api/v1/auth/setup" ||
 			path == "/api/v1/auth/status" ||
 			path == "/api/v1/auth/verify" ||
+			path == "/api/v1/auth/oidc/login" ||
+			path == "/api/v1/auth/oidc/callback" ||
 			path == "/api/v1/health" ||
 			path == "/api/v1/version" ||
-			path == "/api/v1/admin/restart" ||
 			strings.HasPrefix(path, "/player_api.php") ||
 			strings.HasPrefix(path, "/get.php") ||
-			!strings.HasPrefix(path, "/api/") {
+			(!strings.HasPrefix(path, "/api/") && path != "/metrics") {
 			next.ServeHTTP(w, r)
 			return
 		}
+
+		// Scrapers such as Prometheus can only send API keys as bearer tokens
+		apiKey := r.Header.Get("X-API-Key")
+		if bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); apiKey == "" && strings.HasPrefix(bearer, APIKeyPrefix) {
+			apiKey = bearer
+		}
+		if apiKey != "" {
+			claims, err := s.AuthenticateAPIKey(r.Context(), apiKey)
+			if err != nil {
+				if err != ErrInvalidAPIKey {
+					log.Printf("[AUTH] ⚠️ API key lookup failed: %v", err)
+				}
+				http.Error(w, "Unauthorized - Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserContextKey, claims)))
+			return
+		}
+
+		// The proxy has already signed the user in, so its header wins over a token from an earlier session
+		proxyClaims, err := s.sso.ProxyClaims(r)
+		if err == ErrSSONoAccount || err == ErrSSONoRole {
+			http.Error(w, "Forbidden - "+err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			log.Printf("[AUTH] ⚠️ Proxy sign-on failed: %v", err)
+			http.Error(w, "Proxy sign-on failed", http.StatusInternalServerError)
+			return
+		}
+		if proxyClaims != nil {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserContextKey, proxyClaims)))
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			http.Error(w, "Unauthorized - No token provided", http.StatusUnauthorized)
@@ -45,7 +87,7 @@ func SessionMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Validate token
-		claims, err := ValidateToken(tokenString)
+		claims, err := s.ValidateAccessToken(tokenString)
 		if err != nil {
 			if err == ErrExpiredToken {
 				http.Error(w, "Unauthorized - Token expired", http.StatusUnauthorized)
@@ -69,12 +111,53 @@ func GetUserFromContext(ctx context.Context) (*Claims, bool) {
 
 // RequireAdmin middleware ensures user is an admin
 func RequireAdmin(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		claims, ok := GetUserFromContext(r.Context())
-		if !ok || !claims.IsAdmin {
-			http.Error(w, "Forbidden - Admin access required", http.StatusForbidden)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+	return RequirePermission(PermUsersManage)(next)
+}
+
+// RequirePermission middleware ensures the user's role grants perm
+func RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetUserFromContext(r.Context())
+			if !ok || !claims.Can(perm) {
+				http.Error(w, "Forbidden - "+string(perm)+" permission required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type clientIPKey struct{}
+
+// WithClientIP records the client address resolved by the network access policy, which only believes
+// forwarding headers from trusted proxies
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+type trustedProxyKey struct{}
+
+// WithTrustedProxy records that the network access policy found the request's peer among the trusted proxies
+func WithTrustedProxy(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedProxyKey{}, true)
+}
+
+// FromTrustedProxy reports whether the request came straight from a trusted proxy, so its forwarding
+// and proxy sign-on headers can be believed. Without a network access policy no peer is trusted.
+func FromTrustedProxy(r *http.Request) bool {
+	trusted, _ := r.Context().Value(trustedProxyKey{}).(bool)
+	return trusted
+}
+
+// ClientIP returns the client's IP address as resolved by the network access policy; without one it
+// is the peer address, since forwarding headers can only be believed from trusted proxies
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
 }
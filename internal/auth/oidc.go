@@ -0,0 +1,452 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcStateAud      = "oidc-state"
+	oidcStateTTL      = 10 * time.Minute // Time allowed for signing in at the provider
+	oidcDiscoveryTTL  = time.Hour
+	oidcKeysMinReload = 30 * time.Second // Unknown key IDs refetch the JWKS at most this often
+)
+
+// ErrOIDCState is returned when the callback doesn't belong to a sign-in started by this browser
+var ErrOIDCState = errors.New("sign-in expired or was started elsewhere, try again")
+
+// oidcProvider is an issuer's discovery document and signing keys
+type oidcProvider struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+
+	configured string // Issuer URL from the settings this was discovered for
+	fetchedAt  time.Time
+	keys       map[string]interface{} // Key ID → *rsa.PublicKey or *ecdsa.PublicKey
+	keysAt     time.Time
+}
+
+// oidcStateClaims travel in a signed cookie from the start of a sign-in to the callback
+type oidcStateClaims struct {
+	State       string `json:"state"`
+	Nonce       string `json:"nonce"`
+	Verifier    string `json:"verifier"` // PKCE code verifier
+	RedirectURI string `json:"redirect_uri"`
+	LinkUserID  int    `json:"link_user_id,omitempty"` // Signed-in user linking the account to themselves
+	jwt.RegisteredClaims
+}
+
+func (c *oidcStateClaims) registered() *jwt.RegisteredClaims { return &c.RegisteredClaims }
+
+// SSOLogin is a user signed in through single sign-on
+type SSOLogin struct {
+	UserID   int
+	Username string
+	Role     string
+}
+
+// BeginOIDC starts a sign-in at the provider. It returns the URL to send the browser to and a state
+// token to keep in a cookie until the callback; redirectURI is used when none is configured.
+func (s *SSO) BeginOIDC(ctx context.Context, redirectURI string) (authURL, stateToken string, err error) {
+	return s.beginOIDC(ctx, redirectURI, 0)
+}
+
+// BeginOIDCLink starts linking the signed-in user to their account at the provider; the callback
+// links the account instead of looking up its user
+func (s *SSO) BeginOIDCLink(ctx context.Context, redirectURI string, userID int) (authURL, stateToken string, err error) {
+	return s.beginOIDC(ctx, redirectURI, userID)
+}
+
+func (s *SSO) beginOIDC(ctx context.Context, redirectURI string, linkUserID int) (authURL, stateToken string, err error) {
+	opts := s.Options()
+	if !opts.OIDCEnabled {
+		return "", "", ErrSSODisabled
+	}
+	provider, err := s.discover(ctx, opts)
+	if err != nil {
+		return "", "", err
+	}
+	if opts.RedirectURL != "" {
+		redirectURI = opts.RedirectURL
+	}
+
+	claims := &oidcStateClaims{RedirectURI: redirectURI, LinkUserID: linkUserID}
+	for _, v := range []*string{&claims.State, &claims.Nonce, &claims.Verifier} {
+		if *v, err = randomString(32); err != nil {
+			return "", "", err
+		}
+	}
+	if stateToken, err = s.service.signPurposeToken(ctx, claims, oidcStateAud, time.Now().Add(oidcStateTTL)); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(claims.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {opts.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(opts.Scopes, " ")},
+		"state":                 {claims.State},
+		"nonce":                 {claims.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return provider.AuthorizationEndpoint + sep + params.Encode(), stateToken, nil
+}
+
+// CompleteOIDC finishes a sign-in at the callback: it checks the state against the cookie's, exchanges
+// the code for an ID token, verifies it and returns the StreamArr user it belongs to
+func (s *SSO) CompleteOIDC(ctx context.Context, stateToken, state, code string) (*SSOLogin, error) {
+	opts := s.Options()
+	if !opts.OIDCEnabled {
+		return nil, ErrSSODisabled
+	}
+	claims := &oidcStateClaims{}
+	if stateToken == "" || s.service.parsePurposeToken(stateToken, claims, oidcStateAud) != nil ||
+		subtle.ConstantTimeCompare([]byte(claims.State), []byte(state)) != 1 {
+		return nil, ErrOIDCState
+	}
+	provider, err := s.discover(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.exchangeCode(ctx, provider, opts, code, claims)
+	if err != nil {
+		return nil, err
+	}
+	idClaims, err := s.verifyIDToken(ctx, provider, opts, tokens.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if nonce, _ := idClaims["nonce"].(string); subtle.ConstantTimeCompare([]byte(nonce), []byte(claims.Nonce)) != 1 {
+		return nil, errors.New("id token nonce mismatch")
+	}
+	subject, _ := idClaims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	// Many providers only put groups and profile claims in the userinfo response
+	if provider.UserinfoEndpoint != "" && tokens.AccessToken != "" &&
+		(idClaims[opts.GroupsClaim] == nil || idClaims[opts.UsernameClaim] == nil) {
+		info, err := s.userinfo(ctx, provider, tokens.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if infoSub, _ := info["sub"].(string); infoSub != subject {
+			return nil, errors.New("userinfo subject does not match the id token")
+		}
+		for k, v := range info {
+			if _, ok := idClaims[k]; !ok {
+				idClaims[k] = v
+			}
+		}
+	}
+
+	identity := ssoIdentity{
+		Issuer:   provider.Issuer,
+		Subject:  subject,
+		Username: claimString(idClaims, opts.UsernameClaim, "preferred_username", "email"),
+		Email:    claimString(idClaims, "email"),
+		Groups:   claimStrings(idClaims[opts.GroupsClaim]),
+	}
+	identity.EmailVerified, _ = idClaims["email_verified"].(bool)
+	if identity.Username == "" {
+		return nil, fmt.Errorf("id token has no %s claim", opts.UsernameClaim)
+	}
+	if claims.LinkUserID != 0 {
+		if err := s.linkUser(ctx, identity, claims.LinkUserID); err != nil {
+			return nil, err
+		}
+	}
+	user, err := s.resolveUser(ctx, identity, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SSOLogin{UserID: user.ID, Username: user.Username, Role: NormalizeRole(user.Role)}, nil
+}
+
+// discover returns the provider's discovery document, cached for an hour
+func (s *SSO) discover(ctx context.Context, opts SSOOptions) (*oidcProvider, error) {
+	issuer := strings.TrimSuffix(opts.IssuerURL, "/")
+	if issuer == "" || opts.ClientID == "" {
+		return nil, errors.New("OIDC issuer URL and client ID are required")
+	}
+	s.mu.Lock()
+	cached := s.provider
+	s.mu.Unlock()
+	if cached != nil && cached.configured == issuer && time.Since(cached.fetchedAt) < oidcDiscoveryTTL {
+		return cached, nil
+	}
+
+	provider := &oidcProvider{}
+	if err := s.getJSON(ctx, issuer+"/.well-known/openid-configuration", "", provider); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing endpoints")
+	}
+	provider.configured = issuer
+	provider.fetchedAt = time.Now()
+
+	s.mu.Lock()
+	// Keep the keys fetched so far when the key set is unchanged
+	if old := s.provider; old != nil && old.configured == issuer && old.JWKSURI == provider.JWKSURI {
+		provider.keys, provider.keysAt = old.keys, old.keysAt
+	}
+	s.provider = provider
+	s.mu.Unlock()
+	return provider, nil
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeCode redeems the authorization code with the PKCE verifier
+func (s *SSO) exchangeCode(ctx context.Context, provider *oidcProvider, opts SSOOptions, code string, state *oidcStateClaims) (*oidcTokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {state.RedirectURI},
+		"code_verifier": {state.Verifier},
+		"client_id":     {opts.ClientID},
+	}
+	// client_secret_basic unless the provider only takes client_secret_post
+	postSecret := opts.ClientSecret != "" && len(provider.TokenAuthMethods) > 0 &&
+		!slices.Contains(provider.TokenAuthMethods, "client_secret_basic") &&
+		slices.Contains(provider.TokenAuthMethods, "client_secret_post")
+	if postSecret {
+		form.Set("client_secret", opts.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if opts.ClientSecret != "" && !postSecret {
+		req.SetBasicAuth(url.QueryEscape(opts.ClientID), url.QueryEscape(opts.ClientSecret))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	tokens := &oidcTokenResponse{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(tokens); err != nil {
+		return nil, fmt.Errorf("OIDC token response: %w", err)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("OIDC token request failed: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC token request failed: HTTP %d", resp.StatusCode)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("OIDC token response has no id_token")
+	}
+	return tokens, nil
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience and expiry and returns its claims
+func (s *SSO) verifyIDToken(ctx context.Context, provider *oidcProvider, opts SSOOptions, idToken string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	methods := []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+	if opts.ClientSecret != "" {
+		// Providers without a signing key configured sign with the client secret
+		methods = append(methods, "HS256")
+	}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return []byte(opts.ClientSecret), nil
+		}
+		keyID, _ := token.Header["kid"].(string)
+		return s.providerKey(ctx, provider, keyID)
+	},
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(opts.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	// With several audiences, the token must have been issued to us
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != opts.ClientID {
+			return nil, errors.New("invalid id token: issued to another client")
+		}
+	}
+	return claims, nil
+}
+
+// providerKey returns the provider's public key with the ID, refetching the JWKS for unknown IDs
+func (s *SSO) providerKey(ctx context.Context, provider *oidcProvider, keyID string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key := lookupProviderKey(provider.keys, keyID); key != nil {
+		return key, nil
+	}
+	if time.Since(provider.keysAt) < oidcKeysMinReload {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	provider.keysAt = time.Now()
+	if err := s.getJSON(ctx, provider.JWKSURI, "", &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) > 4 {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	provider.keys = keys
+	if key := lookupProviderKey(keys, keyID); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+// lookupProviderKey finds a key by ID; tokens without a key ID match a provider's only key
+func lookupProviderKey(keys map[string]interface{}, keyID string) interface{} {
+	if key, ok := keys[keyID]; ok {
+		return key
+	}
+	if keyID == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// userinfo fetches the user's claims with the access token
+func (s *SSO) userinfo(ctx context.Context, provider *oidcProvider, accessToken string) (map[string]interface{}, error) {
+	info := map[string]interface{}{}
+	if err := s.getJSON(ctx, provider.UserinfoEndpoint, accessToken, &info); err != nil {
+		return nil, fmt.Errorf("OIDC userinfo request failed: %w", err)
+	}
+	return info, nil
+}
+
+func (s *SSO) getJSON(ctx context.Context, endpoint, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// claimString returns the first of the claims that is a non-empty string
+func claimString(claims map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		if v, ok := claims[name].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// claimStrings reads a claim that is a list of strings, or a single comma- or space-separated string
+func claimStrings(value interface{}) []string {
+	var list []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+	case string:
+		list = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return list
+}
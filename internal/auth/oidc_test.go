@@ -0,0 +1,377 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcStandIn is an OpenID Connect provider: discovery, a JWKS, a token endpoint that checks the
+// PKCE verifier, and userinfo. Codes are handed out by authorize, as the provider would after sign-in.
+type oidcStandIn struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	keyID  string
+
+	mu          sync.Mutex
+	codes       map[string]oidcGrant
+	userinfo    map[string]interface{}
+	tokenAuth   []string // token_endpoint_auth_methods_supported
+	tokenForms  []url.Values
+	basicAuth   []string
+	jwksFetches int
+}
+
+type oidcGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newOIDCStandIn(t *testing.T) *oidcStandIn {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &oidcStandIn{key: key, keyID: "key-1", codes: map[string]oidcGrant{}}
+	p.server = httptest.NewServer(p)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *oidcStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	respond := func(status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		respond(http.StatusOK, map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize?prompt=login",
+			"token_endpoint":                        p.server.URL + "/token",
+			"userinfo_endpoint":                     p.server.URL + "/userinfo",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"token_endpoint_auth_methods_supported": p.tokenAuth,
+		})
+	case "/jwks":
+		p.jwksFetches++
+		respond(http.StatusOK, map[string]interface{}{"keys": []interface{}{
+			map[string]string{"kty": "EC", "kid": "ignored", "crv": "P-192"},
+			map[string]string{
+				"kty": "RSA", "kid": p.keyID, "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			},
+		}})
+	case "/token":
+		r.ParseForm()
+		p.tokenForms = append(p.tokenForms, r.PostForm)
+		if user, pass, ok := r.BasicAuth(); ok {
+			p.basicAuth = append(p.basicAuth, user+":"+pass)
+		}
+		grant, ok := p.codes[r.PostForm.Get("code")]
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+			respond(http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+			return
+		}
+		delete(p.codes, r.PostForm.Get("code"))
+		respond(http.StatusOK, map[string]string{"id_token": p.sign(grant.claims), "access_token": "access-" + r.PostForm.Get("code")})
+	case "/userinfo":
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer access-") || p.userinfo == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		respond(http.StatusOK, p.userinfo)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (p *oidcStandIn) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.keyID
+	signed, _ := token.SignedString(p.key)
+	return signed
+}
+
+// authorize stands in for the user signing in at the provider: it returns the code the provider
+// would send back to the redirect URI, issuing an ID token with extra claims on top of the defaults
+func (p *oidcStandIn) authorize(t *testing.T, authURL string, extra jwt.MapClaims) (state, code string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"sub":   "user-123",
+		"aud":   query.Get("client_id"),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code = "code-" + query.Get("state")[:8]
+	p.codes[code] = oidcGrant{challenge: query.Get("code_challenge"), claims: claims}
+	return query.Get("state"), code
+}
+
+func newTestSSO(t *testing.T, opts SSOOptions) *SSO {
+	service := NewService(nil)
+	service.keys = []database.SigningKey{{ID: "test", Secret: []byte("0123456789abcdef0123456789abcdef")}}
+	service.loadedAt = time.Now()
+	return NewSSO(service, nil, func() SSOOptions { return opts })
+}
+
+func oidcOptions(issuer string) SSOOptions {
+	return SSOOptions{
+		OIDCEnabled:   true,
+		IssuerURL:     issuer + "/",
+		ClientID:      "streamarr",
+		ClientSecret:  "client secret",
+		Scopes:        []string{"openid", "profile", "groups"},
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+	}
+}
+
+func TestBeginOIDC(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	sso := newTestSSO(t, oidcOptions(provider.server.URL))
+
+	authURL, stateToken, err := sso.BeginOIDC(context.Background(), "https://streamarr.example/api/v1/auth/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, provider.server.URL+"/authorize?prompt=login&") {
+		t.Errorf("auth URL %q doesn't extend the provider's endpoint", authURL)
+	}
+	u, _ := url.Parse(authURL)
+	query := u.Query()
+	for param, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             "streamarr",
+		"redirect_uri":          "https://streamarr.example/api/v1/auth/oidc/callback",
+		"scope":                 "openid profile groups",
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+
+	claims := &oidcStateClaims{}
+	if err := sso.service.parsePurposeToken(stateToken, claims, oidcStateAud); err != nil {
+		t.Fatal(err)
+	}
+	challenge := sha256.Sum256([]byte(claims.Verifier))
+	if claims.State != query.Get("state") || claims.Nonce != query.Get("nonce") ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != query.Get("code_challenge") {
+		t.Error("the state cookie doesn't match the authorization request")
+	}
+	if sso.service.parsePurposeToken(stateToken, &oidcStateClaims{}, "access") == nil {
+		t.Error("the state token is accepted for another purpose")
+	}
+}
+
+func TestCompleteOIDC(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	provider.userinfo = map[string]interface{}{"sub": "user-123", "groups": []interface{}{"media", "family"}}
+	opts := oidcOptions(provider.server.URL)
+	sso := newTestSSO(t, opts)
+	ctx := context.Background()
+
+	authURL, stateToken, err := sso.BeginOIDC(ctx, "http://localhost/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := provider.authorize(t, authURL, jwt.MapClaims{"preferred_username": "alice"})
+
+	// No group is mapped and there is no default role, so the user is refused once every check
+	// has passed: code exchange, ID token signature and claims, nonce and the userinfo groups
+	if _, err := sso.CompleteOIDC(ctx, stateToken, state, code); !errors.Is(err, ErrSSONoRole) {
+		t.Fatalf("CompleteOIDC = %v, want ErrSSONoRole", err)
+	}
+	if len(provider.basicAuth) != 1 || provider.basicAuth[0] != "streamarr:client+secret" {
+		t.Errorf("client authentication = %q, want client_secret_basic with form-encoded credentials", provider.basicAuth)
+	}
+	form := provider.tokenForms[0]
+	if form.Get("redirect_uri") != "http://localhost/callback" || form.Get("client_secret") != "" {
+		t.Errorf("token request form = %v", form)
+	}
+
+	// The code was redeemed, and a forged state never reaches the provider
+	if _, err := sso.CompleteOIDC(ctx, stateToken, state, code); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("redeeming a code twice = %v, want invalid_grant", err)
+	}
+	if _, err := sso.CompleteOIDC(ctx, stateToken, "forged", code); !errors.Is(err, ErrOIDCState) {
+		t.Errorf("forged state = %v, want ErrOIDCState", err)
+	}
+	if _, err := sso.CompleteOIDC(ctx, "", state, code); !errors.Is(err, ErrOIDCState) {
+		t.Errorf("missing state cookie = %v, want ErrOIDCState", err)
+	}
+	if len(provider.tokenForms) != 2 {
+		t.Errorf("%d token requests, want 2", len(provider.tokenForms))
+	}
+}
+
+func TestCompleteOIDCRejectsTokens(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	provider.userinfo = map[string]interface{}{"sub": "someone-else", "preferred_username": "mallory"}
+
+	for _, tt := range []struct {
+		name  string
+		extra jwt.MapClaims
+		want  string
+	}{
+		{"wrong audience", jwt.MapClaims{"aud": "another-app"}, "invalid id token"},
+		{"other client among audiences", jwt.MapClaims{"aud": []string{"streamarr", "other"}, "azp": "other"}, "issued to another client"},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example"}, "invalid id token"},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, "invalid id token"},
+		{"replayed nonce", jwt.MapClaims{"nonce": "old"}, "nonce mismatch"},
+		{"no subject", jwt.MapClaims{"sub": ""}, "no subject"},
+		{"userinfo for another user", nil, "userinfo subject"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sso := newTestSSO(t, oidcOptions(provider.server.URL))
+			authURL, stateToken, err := sso.BeginOIDC(context.Background(), "http://localhost/callback")
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, code := provider.authorize(t, authURL, tt.extra)
+			if _, err := sso.CompleteOIDC(context.Background(), stateToken, state, code); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CompleteOIDC = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCClientSecretPost(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	provider.tokenAuth = []string{"client_secret_post"}
+	provider.userinfo = map[string]interface{}{"sub": "user-123"}
+	sso := newTestSSO(t, oidcOptions(provider.server.URL))
+
+	authURL, stateToken, _ := sso.BeginOIDC(context.Background(), "http://localhost/callback")
+	state, code := provider.authorize(t, authURL, jwt.MapClaims{"preferred_username": "alice", "groups": "media"})
+	if _, err := sso.CompleteOIDC(context.Background(), stateToken, state, code); !errors.Is(err, ErrSSONoRole) {
+		t.Fatalf("CompleteOIDC = %v, want ErrSSONoRole", err)
+	}
+	if len(provider.basicAuth) != 0 || provider.tokenForms[0].Get("client_secret") != "client secret" {
+		t.Error("the client secret wasn't posted in the form")
+	}
+}
+
+func TestOIDCSigningKeyRotation(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	sso := newTestSSO(t, oidcOptions(provider.server.URL))
+	ctx := context.Background()
+
+	discovered, err := sso.discover(ctx, sso.Options())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sso.providerKey(ctx, discovered, "key-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider rotates its key; unknown IDs are refetched, but not more often than oidcKeysMinReload
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	provider.mu.Lock()
+	provider.key, provider.keyID = key, "key-2"
+	provider.mu.Unlock()
+	if _, err := sso.providerKey(ctx, discovered, "key-2"); err == nil {
+		t.Error("the key set was refetched right after the last fetch")
+	}
+	discovered.keysAt = time.Now().Add(-oidcKeysMinReload)
+	if _, err := sso.providerKey(ctx, discovered, "key-2"); err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+	if provider.jwksFetches != 2 {
+		t.Errorf("%d JWKS fetches, want 2", provider.jwksFetches)
+	}
+
+	// Rediscovery keeps the keys, so a new discovery doesn't mean a new JWKS fetch
+	sso.provider.fetchedAt = time.Time{}
+	if rediscovered, err := sso.discover(ctx, sso.Options()); err != nil || lookupProviderKey(rediscovered.keys, "key-2") == nil {
+		t.Errorf("rediscovery lost the keys (%v)", err)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	opts := oidcOptions(provider.server.URL)
+	opts.IssuerURL = provider.server.URL + "/realms/other"
+	sso := newTestSSO(t, opts)
+
+	// The stand-in has no document at that path
+	if _, _, err := sso.BeginOIDC(context.Background(), "http://localhost/callback"); err == nil || !strings.Contains(err.Error(), "discovery failed") {
+		t.Errorf("BeginOIDC = %v, want a discovery failure", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": provider.server.URL, "authorization_endpoint": "x", "token_endpoint": "x", "jwks_uri": "x"})
+	})
+	impostor := httptest.NewServer(mux)
+	defer impostor.Close()
+	sso = newTestSSO(t, oidcOptions(impostor.URL))
+	if _, _, err := sso.BeginOIDC(context.Background(), "http://localhost/callback"); err == nil || !strings.Contains(err.Error(), "returned issuer") {
+		t.Errorf("BeginOIDC = %v, want an issuer mismatch", err)
+	}
+}
+
+func TestProxyClaimsOnlyFromTrustedProxy(t *testing.T) {
+	sso := newTestSSO(t, SSOOptions{ProxyEnabled: true, ProxyUserHeader: "Remote-User"})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/proxy", nil)
+	r.Header.Set("Remote-User", "admin")
+	// Not from a trusted proxy, so the header is ignored before any account is looked up
+	if claims, err := sso.ProxyClaims(r); claims != nil || err != nil {
+		t.Errorf("ProxyClaims = %v, %v; want nothing", claims, err)
+	}
+
+	// From a trusted proxy the user is resolved; with no role mapping and no default role they're refused
+	r = r.WithContext(WithTrustedProxy(r.Context()))
+	if _, err := sso.ProxyClaims(r); !errors.Is(err, ErrSSONoRole) {
+		t.Errorf("trusted ProxyClaims = %v, want ErrSSONoRole", err)
+	}
+}
+
+func TestClaimStrings(t *testing.T) {
+	for _, tt := range []struct {
+		value interface{}
+		want  string
+	}{
+		{[]interface{}{"a", "", 3, "b"}, "a|b"},
+		{"a, b c", "a|b|c"},
+		{nil, ""},
+	} {
+		if got := strings.Join(claimStrings(tt.value), "|"); got != tt.want {
+			t.Errorf("claimStrings(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
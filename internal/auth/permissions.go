@@ -0,0 +1,129 @@
+package auth
+
+import "sort"
+
+// Permission is a single capability checked by the REST API
+type Permission string
+
+const (
+	PermLibraryRead    Permission = "library.read"     // Browse movies, series, collections, search and discover
+	PermLibraryRequest Permission = "library.request"  // Add movies, series and collections
+	PermLibraryWrite   Permission = "library.write"    // Edit library items, run imports and sync collections
+	PermLibraryDelete  Permission = "library.delete"   // Delete library items and manage the blacklist
+	PermPlayback       Permission = "playback"         // Resolve streams, play and fetch subtitles
+	PermLiveTVRead     Permission = "livetv.read"      // Browse channels and the TV guide
+	PermSettingsRead   Permission = "settings.read"    // View settings, including API keys
+	PermSettingsWrite  Permission = "settings.write"   // Change settings
+	PermServicesRead   Permission = "services.read"    // View background services and their runs
+	PermServicesRun    Permission = "services.control" // Trigger, cancel and reschedule background services
+	PermSystemRead     Permission = "system.read"      // View version, database and update status
+	PermSystemControl  Permission = "system.control"   // Restart, install updates and run database actions
+	PermUsersManage    Permission = "users.manage"     // Manage users and their Stremio tokens
+	PermAuditRead      Permission = "audit.read"       // View the audit log
+)
+
+// Roles
+const (
+	RoleAdmin     = "admin"
+	RoleManager   = "manager"
+	RoleRequester = "requester"
+	RoleViewer    = "viewer"
+
+	// DefaultRole is given to new users when no role is specified
+	DefaultRole = RoleRequester
+)
+
+// legacyUserRole is the role users had before roles were introduced
+const legacyUserRole = "user"
+
+var allPermissions = []Permission{
+	PermLibraryRead, PermLibraryRequest, PermLibraryWrite, PermLibraryDelete,
+	PermPlayback, PermLiveTVRead,
+	PermSettingsRead, PermSettingsWrite,
+	PermServicesRead, PermServicesRun,
+	PermSystemRead, PermSystemControl,
+	PermUsersManage, PermAuditRead,
+}
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: allPermissions,
+	RoleManager: {
+		PermLibraryRead, PermLibraryRequest, PermLibraryWrite, PermLibraryDelete,
+		PermPlayback, PermLiveTVRead,
+		PermSettingsRead,
+		PermServicesRead, PermServicesRun,
+		PermSystemRead, PermAuditRead,
+	},
+	RoleRequester: {PermLibraryRead, PermLibraryRequest, PermPlayback, PermLiveTVRead},
+	RoleViewer:    {PermLibraryRead, PermPlayback, PermLiveTVRead},
+}
+
+// NormalizeRole maps the pre-RBAC "user" role to DefaultRole; other roles are returned as is
+func NormalizeRole(role string) string {
+	if role == legacyUserRole || role == "" {
+		return DefaultRole
+	}
+	return role
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Roles returns the known roles, sorted
+func Roles() []string {
+	roles := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// RolePermissions returns the permissions granted to a role; unknown roles get none
+func RolePermissions(role string) []Permission {
+	return rolePermissions[NormalizeRole(role)]
+}
+
+// RoleHasPermission reports whether role grants perm
+func RoleHasPermission(role string, perm Permission) bool {
+	for _, p := range RolePermissions(role) {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPermission reports whether perm is a known permission
+func IsPermission(perm Permission) bool {
+	for _, p := range allPermissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the token's role grants perm; API keys are further limited to their scopes
+func (c *Claims) Can(perm Permission) bool {
+	role := c.Role
+	if role == "" && c.IsAdmin {
+		// Tokens issued before roles only carry is_admin
+		role = RoleAdmin
+	}
+	if !RoleHasPermission(role, perm) {
+		return false
+	}
+	if c.APIKeyID == 0 {
+		return true
+	}
+	for _, scope := range c.Scopes {
+		if scope == perm {
+			return true
+		}
+	}
+	return false
+}
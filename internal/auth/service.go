@@ -0,0 +1,567 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/secrets"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessTokenTTL = 15 * time.Minute
+	SessionTTL     = 24 * time.Hour      // Refresh token lifetime
+	RememberMeTTL  = 30 * 24 * time.Hour // Refresh token lifetime with "remember me"
+
+	keyRotationAge    = 30 * 24 * time.Hour
+	retiredKeyTTL     = 24 * time.Hour // Must outlive AccessTokenTTL so tokens signed before a rotation stay valid
+	keyReloadInterval = 5 * time.Minute
+	unknownKeyBackoff = 10 * time.Second
+	refreshReuseGrace = 30 * time.Second // Concurrent refreshes from two tabs aren't treated as token theft
+	sessionRetention  = 7 * 24 * time.Hour
+
+	// APIKeyPrefix starts every API key so they are easy to recognise in scripts and secret scanners
+	APIKeyPrefix = "sa_"
+
+	// urlKeyID is the signing key of stream and download URLs. It is never rotated: the URLs end up
+	// in playlists, .strm files and player caches that can't be re-signed.
+	urlKeyID = "url"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrInvalidAPIKey       = errors.New("invalid api key")
+)
+
+// Session is a signed-in session's token pair
+type Session struct {
+	UserID           int
+	Username         string
+	Role             string
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Service issues and validates access tokens, refresh tokens and API keys.
+// Access tokens are short-lived JWTs signed with keys kept in the database, so they stay valid across
+// restarts and server instances; refresh tokens and API keys are opaque and stored as hashes.
+type Service struct {
+	store        *database.AuthStore
+	keyring      *secrets.Keyring // Encrypts signing keys and TOTP secrets at rest; nil stores them as they are
+	legacySecret []byte           // JWT_SECRET; verifies tokens issued before signing keys were persisted
+	sso          *SSO             // Reverse-proxy sign-on in SessionMiddleware; nil when not set up
+	urlSecret    []byte
+
+	mu       sync.RWMutex
+	keys     []database.SigningKey // Newest first, decrypted
+	loadedAt time.Time
+}
+
+// NewService creates a token service; call Load before use
+func NewService(store *database.AuthStore) *Service {
+	s := &Service{store: store}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		s.legacySecret = []byte(secret)
+	}
+	return s
+}
+
+// SetSSO enables single sign-on through an authenticating reverse proxy in SessionMiddleware
+func (s *Service) SetSSO(sso *SSO) {
+	s.sso = sso
+}
+
+// SetKeyring sets the keyring signing keys and TOTP secrets are encrypted with; call it before Load
+func (s *Service) SetKeyring(keyring *secrets.Keyring) {
+	s.keyring = keyring
+}
+
+// Load reads the signing keys, creating the first ones on a fresh install, and encrypts TOTP secrets
+// stored before encryption was added
+func (s *Service) Load(ctx context.Context) error {
+	if err := s.resealTOTPSecrets(ctx, nil); err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return err
+	}
+	urlKey, err := s.store.EnsureSigningKey(ctx, urlKeyID, database.SigningKeyURL, sealed)
+	if err != nil {
+		return err
+	}
+	if err := s.open(ctx, urlKey); err != nil {
+		return err
+	}
+	s.urlSecret = urlKey.Secret
+
+	keys, err := s.store.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return s.RotateKeys(ctx)
+	}
+	return s.setKeys(ctx, keys)
+}
+
+// URLSecret returns the persisted secret that signs stream and download URLs, so they stay valid
+// across restarts and are accepted by every instance; Load must have succeeded
+func (s *Service) URLSecret() string {
+	return string(s.urlSecret)
+}
+
+// Rekey stores every signing key and TOTP secret again encrypted with keyring
+func (s *Service) Rekey(ctx context.Context, keyring *secrets.Keyring) error {
+	s.mu.RLock()
+	keys := append([]database.SigningKey{{ID: urlKeyID, Secret: s.urlSecret}}, s.keys...)
+	s.mu.RUnlock()
+
+	previous := s.keyring
+	s.keyring = keyring
+	if err := s.resealTOTPSecrets(ctx, previous); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		sealed, err := s.seal(key.Secret)
+		if err != nil {
+			return err
+		}
+		if err := s.store.UpdateSigningKeySecret(ctx, key.ID, sealed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seal encrypts a signing key for storage
+func (s *Service) seal(secret []byte) ([]byte, error) {
+	if s.keyring == nil {
+		return secret, nil
+	}
+	encrypted, err := s.keyring.Encrypt(base64.StdEncoding.EncodeToString(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+	return []byte(encrypted), nil
+}
+
+// open decrypts a stored signing key in place. Keys stored before encryption was added are
+// encrypted now.
+func (s *Service) open(ctx context.Context, key *database.SigningKey) error {
+	stored := string(key.Secret)
+	if !secrets.IsEncrypted(stored) {
+		if s.keyring == nil {
+			return nil
+		}
+		sealed, err := s.seal(key.Secret)
+		if err != nil {
+			return err
+		}
+		return s.store.UpdateSigningKeySecret(ctx, key.ID, sealed)
+	}
+	if s.keyring == nil {
+		return fmt.Errorf("signing key %s is encrypted but no master key is configured", key.ID)
+	}
+	decrypted, err := s.keyring.Decrypt(stored)
+	if err != nil {
+		return fmt.Errorf("signing key %s: %w", key.ID, err)
+	}
+	secret, err := base64.StdEncoding.DecodeString(decrypted)
+	if err != nil {
+		return fmt.Errorf("signing key %s: %w", key.ID, err)
+	}
+	key.Secret = secret
+	return nil
+}
+
+// RotateKeys starts signing with a new key; tokens signed with the previous key stay valid until they expire
+func (s *Service) RotateKeys(ctx context.Context) error {
+	id, err := randomString(8)
+	if err != nil {
+		return err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return err
+	}
+	if err := s.store.AddSigningKey(ctx, id, sealed); err != nil {
+		return err
+	}
+	log.Printf("[AUTH] 🔑 New token signing key %s", id)
+	return s.reload(ctx)
+}
+
+// Maintain rotates the signing key when it is due and prunes retired keys, old sessions and old login history
+func (s *Service) Maintain(ctx context.Context) error {
+	if err := s.reload(ctx); err != nil {
+		return err
+	}
+	if key := s.signingKey(ctx); key == nil || time.Since(key.CreatedAt) > keyRotationAge {
+		if err := s.RotateKeys(ctx); err != nil {
+			return err
+		}
+	}
+	if _, err := s.store.PruneSigningKeys(ctx, time.Now().Add(-retiredKeyTTL)); err != nil {
+		return err
+	}
+	pruned, err := s.store.PruneRefreshTokens(ctx, time.Now().Add(-sessionRetention))
+	if err != nil {
+		return err
+	}
+	if pruned > 0 {
+		log.Printf("[AUTH] Pruned %d expired sessions", pruned)
+	}
+	if _, err := s.store.PruneLoginHistory(ctx, time.Now().Add(-loginHistoryRetention)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setKeys decrypts keys and starts using them
+func (s *Service) setKeys(ctx context.Context, keys []database.SigningKey) error {
+	for i := range keys {
+		if err := s.open(ctx, &keys[i]); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+	return nil
+}
+
+func (s *Service) reload(ctx context.Context) error {
+	keys, err := s.store.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	return s.setKeys(ctx, keys)
+}
+
+// signingKey returns the newest active key, picking up rotations made by other instances
+func (s *Service) signingKey(ctx context.Context) *database.SigningKey {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > keyReloadInterval
+	s.mu.RUnlock()
+	if stale {
+		if err := s.reload(ctx); err != nil {
+			log.Printf("[AUTH] ⚠️ Cannot reload signing keys: %v", err)
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.keys {
+		if s.keys[i].RetiredAt == nil {
+			key := s.keys[i]
+			return &key
+		}
+	}
+	if len(s.keys) > 0 {
+		key := s.keys[0]
+		return &key
+	}
+	return nil
+}
+
+// verificationKey returns the secret for a key ID, reloading once in a while for keys created elsewhere
+func (s *Service) verificationKey(keyID string) []byte {
+	if keyID == "" {
+		return s.legacySecret
+	}
+	if secret := s.lookupKey(keyID); secret != nil {
+		return secret
+	}
+
+	s.mu.RLock()
+	recent := time.Since(s.loadedAt) < unknownKeyBackoff
+	s.mu.RUnlock()
+	if recent {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.reload(ctx); err != nil {
+		log.Printf("[AUTH] ⚠️ Cannot reload signing keys: %v", err)
+		return nil
+	}
+	return s.lookupKey(keyID)
+}
+
+func (s *Service) lookupKey(keyID string) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == keyID {
+			return key.Secret
+		}
+	}
+	return nil
+}
+
+// ValidateAccessToken validates an access token and returns its claims
+func (s *Service) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, s.verificationKey)
+}
+
+func (s *Service) accessToken(ctx context.Context, userID int, username, role string) (string, time.Time, error) {
+	key := s.signingKey(ctx)
+	if key == nil {
+		return "", time.Time{}, errors.New("no signing key loaded")
+	}
+	return signToken(key.ID, key.Secret, userID, username, role, AccessTokenTTL)
+}
+
+// purposeClaims are the claims of a token bound to one purpose by its audience
+type purposeClaims interface {
+	jwt.Claims
+	registered() *jwt.RegisteredClaims
+}
+
+// signPurposeToken signs a short-lived token for one purpose (login challenge, OIDC state) with the
+// current key. The audience keeps it from being accepted as an access token or for another purpose.
+func (s *Service) signPurposeToken(ctx context.Context, claims purposeClaims, audience string, expiresAt time.Time) (string, error) {
+	key := s.signingKey(ctx)
+	if key == nil {
+		return "", errors.New("no signing key loaded")
+	}
+	*claims.registered() = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    "streamarr",
+		Audience:  jwt.ClaimStrings{audience},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Secret)
+}
+
+// parsePurposeToken validates a token from signPurposeToken for audience into claims
+func (s *Service) parsePurposeToken(tokenString string, claims purposeClaims, audience string) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		if keyID == "" {
+			return nil, ErrInvalidToken
+		}
+		if secret := s.verificationKey(keyID); secret != nil {
+			return secret, nil
+		}
+		return nil, ErrInvalidToken
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(audience), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// IssueSession signs a user in: a short-lived access token plus a refresh token stored server-side
+func (s *Service) IssueSession(ctx context.Context, userID int, username, role string, rememberMe bool, userAgent, ip string) (*Session, error) {
+	refresh, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.store.CreateRefreshToken(ctx, userID, hashSecret(refresh), rememberMe, time.Now().Add(sessionTTL(rememberMe)), userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+	return s.session(ctx, stored, refresh, username, role)
+}
+
+// Refresh exchanges a refresh token for a new token pair; the old refresh token stops working.
+// Presenting an already-rotated token again signs the user out everywhere, since it was probably stolen.
+func (s *Service) Refresh(ctx context.Context, refreshToken, userAgent, ip string) (*Session, error) {
+	current, err := s.store.GetRefreshToken(ctx, hashSecret(refreshToken))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedBy != nil && time.Since(*current.RevokedAt) > refreshReuseGrace {
+			log.Printf("[AUTH] ⚠️ Reused refresh token for %s; signing out all sessions", current.Username)
+			if err := s.store.RevokeAllSessions(ctx, current.UserID); err != nil {
+				log.Printf("[AUTH] ⚠️ %v", err)
+			}
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	refresh, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.store.RotateRefreshToken(ctx, current.ID, hashSecret(refresh), time.Now().Add(sessionTTL(current.RememberMe)), userAgent, ip)
+	if errors.Is(err, database.ErrRefreshTokenUsed) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.session(ctx, rotated, refresh, rotated.Username, NormalizeRole(rotated.Role))
+}
+
+func (s *Service) session(ctx context.Context, stored *database.RefreshToken, refresh, username, role string) (*Session, error) {
+	access, expiresAt, err := s.accessToken(ctx, stored.UserID, username, role)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		UserID:           stored.UserID,
+		Username:         username,
+		Role:             role,
+		AccessToken:      access,
+		AccessExpiresAt:  expiresAt,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// Revoke ends the session behind a refresh token; unknown tokens are ignored
+func (s *Service) Revoke(ctx context.Context, refreshToken string) error {
+	current, err := s.store.GetRefreshToken(ctx, hashSecret(refreshToken))
+	if err == sql.ErrNoRows || (err == nil && current.RevokedAt != nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.store.RevokeRefreshToken(ctx, current.UserID, current.ID); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// CreateAPIKey issues an API key limited to scopes; the key is only returned here
+func (s *Service) CreateAPIKey(ctx context.Context, userID int, name string, scopes []Permission, expiresAt *time.Time) (string, *database.APIKey, error) {
+	if len(scopes) == 0 {
+		return "", nil, errors.New("at least one scope is required")
+	}
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		if !IsPermission(scope) {
+			return "", nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		names[i] = string(scope)
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+	key := APIKeyPrefix + secret
+	stored, err := s.store.CreateAPIKey(ctx, userID, name, key[:len(APIKeyPrefix)+8], hashSecret(key), names, expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return key, stored, nil
+}
+
+// AuthenticateAPIKey returns claims for an active API key
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (*Claims, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	stored, err := s.store.UseAPIKey(ctx, hashSecret(key))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role := NormalizeRole(stored.Role)
+	scopes := make([]Permission, len(stored.Scopes))
+	for i, scope := range stored.Scopes {
+		scopes[i] = Permission(scope)
+	}
+	return &Claims{
+		UserID:   stored.UserID,
+		Username: stored.Username,
+		Role:     role,
+		IsAdmin:  role == RoleAdmin,
+		APIKeyID: stored.ID,
+		Scopes:   scopes,
+	}, nil
+}
+
+func sessionTTL(rememberMe bool) time.Duration {
+	if rememberMe {
+		return RememberMeTTL
+	}
+	return SessionTTL
+}
+
+// randomString returns n random bytes, base64url encoded
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sessions returns a user's active sessions
+func (s *Service) Sessions(ctx context.Context, userID int) ([]*database.RefreshToken, error) {
+	return s.store.ListRefreshTokens(ctx, userID)
+}
+
+// RevokeSession ends one of a user's sessions; sql.ErrNoRows when it isn't active
+func (s *Service) RevokeSession(ctx context.Context, userID int, id int64) error {
+	return s.store.RevokeRefreshToken(ctx, userID, id)
+}
+
+// RevokeAllSessions signs a user out everywhere: refresh tokens stop working and access tokens
+// issued so far are rejected. API keys are not affected.
+func (s *Service) RevokeAllSessions(ctx context.Context, userID int) error {
+	return s.store.RevokeAllSessions(ctx, userID)
+}
+
+// APIKeys returns a user's API keys (every user's when userID is 0)
+func (s *Service) APIKeys(ctx context.Context, userID int) ([]*database.APIKey, error) {
+	return s.store.ListAPIKeys(ctx, userID)
+}
+
+// APIKey returns an API key by ID
+func (s *Service) APIKey(ctx context.Context, id int64) (*database.APIKey, error) {
+	return s.store.GetAPIKey(ctx, id)
+}
+
+// RevokeAPIKey revokes an API key; sql.ErrNoRows when it was already revoked
+func (s *Service) RevokeAPIKey(ctx context.Context, id int64) error {
+	return s.store.RevokeAPIKey(ctx, id)
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+)
+
+var (
+	ErrSSODisabled  = errors.New("single sign-on is not enabled")
+	ErrSSONoAccount = errors.New("no StreamArr account for this user")
+	ErrSSONoRole    = errors.New("not in a group allowed to use StreamArr")
+	// ErrSSOAccountExists is returned for an unlinked identity whose name belongs to a local account
+	ErrSSOAccountExists = errors.New("an account with this name already exists; sign in and link single sign-on from your profile")
+	// ErrSSOLinkedElsewhere is returned when linking an identity that is already linked to another user
+	ErrSSOLinkedElsewhere = errors.New("this single sign-on account is linked to another user")
+)
+
+// roleRank orders roles by how much they grant, so a user in several mapped groups gets the broadest role
+var roleRank = map[string]int{RoleViewer: 1, RoleRequester: 2, RoleManager: 3, RoleAdmin: 4}
+
+// SSOOptions configures single sign-on
+type SSOOptions struct {
+	// OpenID Connect (authorization code flow with PKCE)
+	OIDCEnabled   bool
+	ProviderName  string
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string // Empty for public clients
+	RedirectURL   string // Empty: derived from the request
+	Scopes        []string
+	UsernameClaim string
+	GroupsClaim   string
+
+	// Authenticating reverse proxy
+	ProxyEnabled      bool
+	ProxyUserHeader   string
+	ProxyGroupsHeader string // The headers are only read from trusted proxies (see FromTrustedProxy)
+
+	RoleMapping   map[string]string // Group → role
+	DefaultRole   string            // For users in no mapped group; empty refuses them
+	AutoProvision bool              // Create users on their first sign-on
+	// Link an unlinked OIDC identity to the local account with its email, when the provider verified
+	// the email. Only safe when the provider's users can't claim others' addresses.
+	LinkVerifiedEmail bool
+}
+
+// ssoIdentity is a user as the identity provider or proxy describes them
+type ssoIdentity struct {
+	Issuer        string // OIDC only; with Subject, links the account to its StreamArr user
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// ssoUsers is the part of the user store single sign-on uses
+type ssoUsers interface {
+	GetUserByID(userID int) (*database.User, error)
+	GetUserByUsername(username string) (*database.User, error)
+	GetUserByEmail(email string) (*database.User, error)
+	CreateUser(username, email, password, role string) (int, error)
+	UpdateUser(userID int, updates map[string]interface{}) error
+}
+
+// ssoIdentities links OIDC accounts to users
+type ssoIdentities interface {
+	FindIdentity(ctx context.Context, issuer, subject string) (int, error)
+	LinkIdentity(ctx context.Context, issuer, subject string, userID int) error
+}
+
+// SSO signs users in through an OpenID Connect provider or an authenticating reverse proxy, creating
+// their StreamArr account on first use and keeping its role in line with their groups.
+// Options are read on every use so settings changes apply without a restart. A nil SSO is disabled.
+type SSO struct {
+	service    *Service
+	users      ssoUsers
+	identities ssoIdentities
+	getOptions func() SSOOptions
+	client     *http.Client
+
+	mu       sync.Mutex
+	provider *oidcProvider // Discovery document and keys of the configured issuer
+}
+
+// NewSSO creates single sign-on backed by the service's signing keys and the user store
+func NewSSO(service *Service, users *database.UserStore, getOptions func() SSOOptions) *SSO {
+	return &SSO{
+		service:    service,
+		users:      users,
+		identities: service.store,
+		getOptions: getOptions,
+		client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Options returns the current single sign-on settings
+func (s *SSO) Options() SSOOptions {
+	if s == nil {
+		return SSOOptions{}
+	}
+	return s.getOptions()
+}
+
+// ParseRoleMapping parses comma-separated group=role pairs; pairs with unknown roles are skipped
+func ParseRoleMapping(value string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.ToLower(strings.TrimSpace(role))
+		if !ok || group == "" || !ValidRole(role) {
+			continue
+		}
+		mapping[group] = role
+	}
+	return mapping
+}
+
+// ParseNetworks parses comma-separated IP addresses and CIDR ranges; invalid entries are skipped
+func ParseNetworks(value string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// mappedRole returns the broadest role the groups map to, or "" when none is mapped
+func mappedRole(groups []string, mapping map[string]string) string {
+	role := ""
+	for _, group := range groups {
+		if r, ok := mapping[group]; ok && roleRank[r] > roleRank[role] {
+			role = r
+		}
+	}
+	return role
+}
+
+// ProxyClaims returns claims for the user named in the reverse proxy's user header. It returns nil
+// when proxy authentication is off, the header is missing, or the request didn't come straight from
+// a trusted proxy; the header is ignored then, since anyone can send it.
+func (s *SSO) ProxyClaims(r *http.Request) (*Claims, error) {
+	opts := s.Options()
+	if !opts.ProxyEnabled || opts.ProxyUserHeader == "" {
+		return nil, nil
+	}
+	username := strings.TrimSpace(r.Header.Get(opts.ProxyUserHeader))
+	if username == "" {
+		return nil, nil
+	}
+	if !FromTrustedProxy(r) {
+		return nil, nil
+	}
+
+	identity := ssoIdentity{Username: username}
+	if opts.ProxyGroupsHeader != "" {
+		for _, group := range strings.Split(r.Header.Get(opts.ProxyGroupsHeader), ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				identity.Groups = append(identity.Groups, group)
+			}
+		}
+	}
+	user, err := s.resolveUser(r.Context(), identity, opts)
+	if err != nil {
+		return nil, err
+	}
+	role := NormalizeRole(user.Role)
+	return &Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     role,
+		IsAdmin:  role == RoleAdmin,
+		Proxy:    true,
+	}, nil
+}
+
+// resolveUser finds the StreamArr user for an identity, creating it when allowed, and applies the
+// role its groups map to. Users in no mapped group keep their role, or are refused without a default role.
+func (s *SSO) resolveUser(ctx context.Context, identity ssoIdentity, opts SSOOptions) (*database.User, error) {
+	role := mappedRole(identity.Groups, opts.RoleMapping)
+	if role == "" && opts.DefaultRole == "" {
+		return nil, ErrSSONoRole
+	}
+
+	var user *database.User
+	var err error
+	if identity.Issuer != "" {
+		user, err = s.oidcUser(ctx, identity, opts)
+	} else {
+		// The trusted proxy vouches for the username itself
+		user, err = s.users.GetUserByUsername(identity.Username)
+	}
+	if err == sql.ErrNoRows {
+		if !opts.AutoProvision {
+			return nil, ErrSSONoAccount
+		}
+		newRole := role
+		if newRole == "" {
+			newRole = NormalizeRole(opts.DefaultRole)
+		}
+		if user, err = s.provision(identity, newRole); err == nil && identity.Issuer != "" {
+			err = s.identities.LinkIdentity(ctx, identity.Issuer, identity.Subject, user.ID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if role != "" && NormalizeRole(user.Role) != role {
+		if err := s.users.UpdateUser(user.ID, map[string]interface{}{"role": role}); err != nil {
+			return nil, fmt.Errorf("failed to update role: %w", err)
+		}
+		log.Printf("[AUTH] %s's role is now %s from their single sign-on groups", user.Username, role)
+		user.Role = role
+	}
+	return user, nil
+}
+
+// oidcUser returns the user an OIDC identity is linked to, or sql.ErrNoRows when there's no account
+// for it yet. An unlinked identity is never matched to a local account by name, which the provider's
+// users pick themselves; only by a verified email when the admin allows it.
+func (s *SSO) oidcUser(ctx context.Context, identity ssoIdentity, opts SSOOptions) (*database.User, error) {
+	userID, err := s.identities.FindIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return s.users.GetUserByID(userID)
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if opts.LinkVerifiedEmail && identity.EmailVerified && identity.Email != "" {
+		user, err := s.users.GetUserByEmail(identity.Email)
+		if err == nil {
+			if err := s.identities.LinkIdentity(ctx, identity.Issuer, identity.Subject, user.ID); err != nil {
+				return nil, err
+			}
+			log.Printf("[AUTH] Linked single sign-on to %s by their verified email", user.Username)
+			return user, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	if _, err := s.users.GetUserByUsername(identity.Username); err == nil {
+		log.Printf("[AUTH] ⚠️ Refused single sign-on as existing user %s: the account isn't linked", identity.Username)
+		return nil, ErrSSOAccountExists
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+	return nil, sql.ErrNoRows
+}
+
+// linkUser links an OIDC identity to a signed-in user who asked for it
+func (s *SSO) linkUser(ctx context.Context, identity ssoIdentity, userID int) error {
+	linkedID, err := s.identities.FindIdentity(ctx, identity.Issuer, identity.Subject)
+	switch {
+	case err == nil && linkedID != userID:
+		return ErrSSOLinkedElsewhere
+	case err == nil:
+		return nil
+	case err != sql.ErrNoRows:
+		return err
+	}
+	if err := s.identities.LinkIdentity(ctx, identity.Issuer, identity.Subject, userID); err != nil {
+		return err
+	}
+	log.Printf("[AUTH] Linked single sign-on account %s to user %d", identity.Username, userID)
+	return nil
+}
+
+// provision creates a user for a first single sign-on. The password is random and never shown, so
+// the account can only be used through single sign-on until an admin sets one.
+func (s *SSO) provision(identity ssoIdentity, role string) (*database.User, error) {
+	email := identity.Email
+	if email == "" {
+		email = identity.Username + "@sso.invalid"
+	} else if _, err := s.users.GetUserByEmail(email); err == nil {
+		email = identity.Username + "@sso.invalid"
+	}
+	password, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := s.users.CreateUser(identity.Username, email, password, role)
+	if err != nil {
+		// Concurrent first requests through the proxy race to create the same user. An OIDC identity
+		// doesn't get to take over an account that appeared meanwhile.
+		if identity.Issuer == "" {
+			if user, lookupErr := s.users.GetUserByUsername(identity.Username); lookupErr == nil {
+				return user, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	log.Printf("[AUTH] Created %s user %s on first single sign-on", role, identity.Username)
+	return s.users.GetUserByID(userID)
+}
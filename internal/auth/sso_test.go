@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeDirectory stands in for the user store and the identity links
+type fakeDirectory struct {
+	users map[int]*database.User
+	links map[string]int // issuer + " " + subject → user ID
+}
+
+func newFakeDirectory(users ...database.User) *fakeDirectory {
+	d := &fakeDirectory{users: map[int]*database.User{}, links: map[string]int{}}
+	for i := range users {
+		d.users[users[i].ID] = &users[i]
+	}
+	return d
+}
+
+func (d *fakeDirectory) GetUserByID(userID int) (*database.User, error) {
+	if user, ok := d.users[userID]; ok {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (d *fakeDirectory) find(match func(*database.User) bool) (*database.User, error) {
+	for id, user := range d.users {
+		if match(user) {
+			return d.GetUserByID(id)
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (d *fakeDirectory) GetUserByUsername(username string) (*database.User, error) {
+	return d.find(func(u *database.User) bool { return u.Username == username })
+}
+
+func (d *fakeDirectory) GetUserByEmail(email string) (*database.User, error) {
+	return d.find(func(u *database.User) bool { return u.Email == email })
+}
+
+func (d *fakeDirectory) CreateUser(username, email, password, role string) (int, error) {
+	if _, err := d.GetUserByUsername(username); err == nil {
+		return 0, errors.New("duplicate username")
+	}
+	id := len(d.users) + 1
+	d.users[id] = &database.User{ID: id, Username: username, Email: email, Role: role}
+	return id, nil
+}
+
+func (d *fakeDirectory) UpdateUser(userID int, updates map[string]interface{}) error {
+	if role, ok := updates["role"].(string); ok {
+		d.users[userID].Role = role
+	}
+	return nil
+}
+
+func (d *fakeDirectory) FindIdentity(ctx context.Context, issuer, subject string) (int, error) {
+	if id, ok := d.links[issuer+" "+subject]; ok {
+		return id, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (d *fakeDirectory) LinkIdentity(ctx context.Context, issuer, subject string, userID int) error {
+	d.links[issuer+" "+subject] = userID
+	return nil
+}
+
+func newTestSSOWithUsers(t *testing.T, opts SSOOptions, directory *fakeDirectory) *SSO {
+	sso := newTestSSO(t, opts)
+	sso.users, sso.identities = directory, directory
+	return sso
+}
+
+// signInOIDC runs a whole sign-in at the stand-in provider as a user with the claims
+func signInOIDC(t *testing.T, sso *SSO, provider *oidcStandIn, linkUserID int, claims jwt.MapClaims) (*SSOLogin, error) {
+	authURL, stateToken, err := sso.beginOIDC(context.Background(), "http://localhost/callback", linkUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := provider.authorize(t, authURL, claims)
+	return sso.CompleteOIDC(context.Background(), stateToken, state, code)
+}
+
+func TestOIDCRefusesTakeoverByUsername(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	provider.userinfo = map[string]interface{}{"sub": "user-123"}
+	opts := oidcOptions(provider.server.URL)
+	opts.DefaultRole, opts.AutoProvision = RoleRequester, true
+	directory := newFakeDirectory(database.User{ID: 1, Username: "admin", Email: "admin@example.com", Role: RoleAdmin})
+	sso := newTestSSOWithUsers(t, opts, directory)
+
+	// Anyone at the provider can call themselves admin
+	if _, err := signInOIDC(t, sso, provider, 0, jwt.MapClaims{"preferred_username": "admin"}); !errors.Is(err, ErrSSOAccountExists) {
+		t.Fatalf("CompleteOIDC = %v, want ErrSSOAccountExists", err)
+	}
+	// Nor does a verified email link the account unless the admin allowed it
+	claims := jwt.MapClaims{"preferred_username": "admin", "email": "admin@example.com", "email_verified": true}
+	if _, err := signInOIDC(t, sso, provider, 0, claims); !errors.Is(err, ErrSSOAccountExists) {
+		t.Fatalf("CompleteOIDC with a verified email = %v, want ErrSSOAccountExists", err)
+	}
+	if len(directory.links) != 0 || len(directory.users) != 1 {
+		t.Errorf("refused sign-ins left links %v and %d users", directory.links, len(directory.users))
+	}
+}
+
+func TestOIDCLinkVerifiedEmail(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	provider.userinfo = map[string]interface{}{"sub": "user-123"}
+	opts := oidcOptions(provider.server.URL)
+	opts.DefaultRole, opts.LinkVerifiedEmail = RoleRequester, true
+	directory := newFakeDirectory(database.User{ID: 1, Username: "alice", Email: "alice@example.com", Role: RoleManager})
+	sso := newTestSSOWithUsers(t, opts, directory)
+
+	unverified := jwt.MapClaims{"preferred_username": "alice", "email": "alice@example.com", "email_verified": false}
+	if _, err := signInOIDC(t, sso, provider, 0, unverified); !errors.Is(err, ErrSSOAccountExists) {
+		t.Fatalf("CompleteOIDC with an unverified email = %v, want ErrSSOAccountExists", err)
+	}
+
+	verified := jwt.MapClaims{"preferred_username": "al", "email": "alice@example.com", "email_verified": true}
+	login, err := signInOIDC(t, sso, provider, 0, verified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.UserID != 1 || login.Role != RoleManager || directory.links[provider.server.URL+" user-123"] != 1 {
+		t.Errorf("login = %+v, links = %v; want alice keeping her role, linked", login, directory.links)
+	}
+}
+
+func TestOIDCExplicitLink(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	provider.userinfo = map[string]interface{}{"sub": "user-123"}
+	opts := oidcOptions(provider.server.URL)
+	opts.DefaultRole = RoleRequester
+	directory := newFakeDirectory(
+		database.User{ID: 1, Username: "admin", Role: RoleAdmin},
+		database.User{ID: 2, Username: "bob", Role: RoleViewer},
+	)
+	sso := newTestSSOWithUsers(t, opts, directory)
+
+	// The signed-in admin links their provider account, whatever it's called there
+	login, err := signInOIDC(t, sso, provider, 1, jwt.MapClaims{"preferred_username": "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.UserID != 1 || login.Role != RoleAdmin {
+		t.Errorf("login = %+v, want the admin", login)
+	}
+	// Later sign-ins find the link
+	if login, err := signInOIDC(t, sso, provider, 0, jwt.MapClaims{"preferred_username": "root"}); err != nil || login.UserID != 1 {
+		t.Errorf("sign-in after linking = %+v, %v", login, err)
+	}
+	// Another user can't take the linked account over
+	if _, err := signInOIDC(t, sso, provider, 2, jwt.MapClaims{"preferred_username": "root"}); !errors.Is(err, ErrSSOLinkedElsewhere) {
+		t.Errorf("linking to a second user = %v, want ErrSSOLinkedElsewhere", err)
+	}
+}
+
+func TestOIDCProvisionsNewUsers(t *testing.T) {
+	provider := newOIDCStandIn(t)
+	provider.userinfo = map[string]interface{}{"sub": "user-123"}
+	opts := oidcOptions(provider.server.URL)
+	opts.DefaultRole = RoleRequester
+	directory := newFakeDirectory()
+	sso := newTestSSOWithUsers(t, opts, directory)
+
+	if _, err := signInOIDC(t, sso, provider, 0, jwt.MapClaims{"preferred_username": "carol"}); !errors.Is(err, ErrSSONoAccount) {
+		t.Fatalf("CompleteOIDC without auto-provisioning = %v, want ErrSSONoAccount", err)
+	}
+	opts.AutoProvision = true
+	sso = newTestSSOWithUsers(t, opts, directory)
+	login, err := signInOIDC(t, sso, provider, 0, jwt.MapClaims{"preferred_username": "carol", "groups": []string{"admins"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.Username != "carol" || login.Role != RoleRequester || directory.links[provider.server.URL+" user-123"] != login.UserID {
+		t.Errorf("login = %+v, links = %v", login, directory.links)
+	}
+}
+
+func TestProxyClaimsMatchUsername(t *testing.T) {
+	directory := newFakeDirectory(database.User{ID: 1, Username: "admin", Role: RoleAdmin})
+	sso := newTestSSOWithUsers(t, SSOOptions{ProxyEnabled: true, ProxyUserHeader: "Remote-User", DefaultRole: RoleViewer}, directory)
+
+	// The trusted proxy authenticated the user, so its username is the account's
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/proxy", nil)
+	r.Header.Set("Remote-User", "admin")
+	claims, err := sso.ProxyClaims(r.WithContext(WithTrustedProxy(r.Context())))
+	if err != nil || claims == nil || claims.UserID != 1 || claims.Role != RoleAdmin {
+		t.Errorf("ProxyClaims = %+v, %v; want the admin", claims, err)
+	}
+}
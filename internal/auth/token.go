@@ -1,10 +1,7 @@
 package auth
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,57 +16,53 @@ var (
 type Claims struct {
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
 	IsAdmin  bool   `json:"is_admin"`
 	jwt.RegisteredClaims
-}
-
-// GetJWTSecret returns the JWT secret from environment or generates one
-func GetJWTSecret() string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// Generate a random secret if not set (for backward compatibility)
-		// In production, JWT_SECRET should always be set
-		b := make([]byte, 32)
-		rand.Read(b)
-		secret = base64.StdEncoding.EncodeToString(b)
-	}
-	return secret
-}
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(userID int, username string, isAdmin bool, rememberMe bool) (string, error) {
-	secret := GetJWTSecret()
+	// Set when the request was authenticated with an API key instead of a session
+	APIKeyID int64        `json:"-"`
+	Scopes   []Permission `json:"-"`
 
-	// Set expiration: 24 hours for normal, 30 days for remember me
-	expiration := time.Hour * 24
-	if rememberMe {
-		expiration = time.Hour * 24 * 30
-	}
+	// Set when a trusted reverse proxy's user header authenticated the request
+	Proxy bool `json:"-"`
+}
 
+// signToken creates an access token signed with the given key
+func signToken(keyID string, secret []byte, userID int, username, role string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
-		IsAdmin:  isAdmin,
+		Role:     role,
+		IsAdmin:  role == RoleAdmin,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "streamarr",
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(secret)
+	return signed, expiresAt, err
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString string) (*Claims, error) {
-	secret := GetJWTSecret()
-
+// parseToken validates an access token; keyFor returns the secret for a "kid" header ("" for tokens
+// issued before signing keys were persisted), or nil when the key is unknown
+func parseToken(tokenString string, keyFor func(keyID string) []byte) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrInvalidToken
 		}
-		return []byte(secret), nil
+		keyID, _ := token.Header["kid"].(string)
+		secret := keyFor(keyID)
+		if secret == nil {
+			return nil, ErrInvalidToken
+		}
+		return secret, nil
 	})
 
 	if err != nil {
@@ -79,20 +72,10 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Access tokens carry no audience; login challenges (and any other purpose-bound token) do
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
 	return nil, ErrInvalidToken
 }
-
-// RefreshToken generates a new token from an existing valid token
-func RefreshToken(tokenString string) (string, error) {
-	claims, err := ValidateToken(tokenString)
-	if err != nil {
-		return "", err
-	}
-
-	// Generate new token with same user info
-	return GenerateToken(claims.UserID, claims.Username, claims.IsAdmin, true)
-}
@@ -0,0 +1,313 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/secrets"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	loginChallengeTTL     = 5 * time.Minute
+	loginChallengeAud     = "login-2fa"
+	recoveryCodeCount     = 10
+	loginHistoryRetention = 90 * 24 * time.Hour
+
+	// A successful sign-in after this many failures within suspiciousWindow raises an alert
+	suspiciousFailures = 3
+	suspiciousWindow   = time.Hour
+)
+
+var (
+	ErrInvalidChallenge    = errors.New("invalid or expired login challenge")
+	ErrInvalidCode         = errors.New("invalid code")
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+)
+
+// TwoFactorStatus is a user's two-factor enrolment as shown to them
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// challengeClaims identify a user who passed the password check and still owes a second factor
+type challengeClaims struct {
+	UserID     int  `json:"user_id"`
+	RememberMe bool `json:"remember_me"`
+	jwt.RegisteredClaims
+}
+
+func (c *challengeClaims) registered() *jwt.RegisteredClaims { return &c.RegisteredClaims }
+
+// TwoFactorEnabled reports whether a user must enter a second factor to sign in
+func (s *Service) TwoFactorEnabled(ctx context.Context, userID int) (bool, error) {
+	tf, err := s.store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return tf.Enabled, nil
+}
+
+// TwoFactorStatus returns whether a user has 2FA enabled and how many recovery codes are left
+func (s *Service) TwoFactorStatus(ctx context.Context, userID int) (*TwoFactorStatus, error) {
+	tf, err := s.store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: tf.Enabled}
+	if tf.Enabled {
+		if status.RecoveryCodesLeft, err = s.store.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTP starts enrolment with a new secret; it only takes effect once EnableTOTP confirms a code
+func (s *Service) BeginTOTP(ctx context.Context, userID int, username string) (secret, uri string, err error) {
+	if secret, err = newTOTPSecret(); err != nil {
+		return "", "", err
+	}
+	sealed, err := s.sealTOTPSecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.store.SetTOTPSecret(ctx, userID, sealed); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", ErrTwoFactorEnabled
+		}
+		return "", "", err
+	}
+	return secret, totpURI(secret, username), nil
+}
+
+// EnableTOTP turns on 2FA once the user proves their app produces codes for the pending secret.
+// It returns the recovery codes, which are only shown this once.
+func (s *Service) EnableTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	tf, err := s.twoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if tf.Secret == "" {
+		return nil, errors.New("start two-factor setup first")
+	}
+	step, ok := matchTOTP(tf.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTwoFactorEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns off 2FA for a user
+func (s *Service) DisableTOTP(ctx context.Context, userID int) error {
+	return s.store.DisableTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes, invalidating the old ones
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	tf, err := s.store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !tf.Enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifySecondFactor checks an authenticator code, or else a recovery code, which is used up.
+// Each authenticator code is accepted once.
+func (s *Service) VerifySecondFactor(ctx context.Context, userID int, code string) (usedRecoveryCode bool, err error) {
+	tf, err := s.twoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !tf.Enabled {
+		return false, ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := matchTOTP(tf.Secret, code, time.Now()); ok {
+		fresh, err := s.store.UseTOTPStep(ctx, userID, step)
+		if err != nil {
+			return false, err
+		}
+		if !fresh {
+			return false, ErrInvalidCode
+		}
+		return false, nil
+	}
+
+	used, err := s.store.UseRecoveryCode(ctx, userID, hashSecret(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if !used {
+		return false, ErrInvalidCode
+	}
+	return true, nil
+}
+
+// twoFactor returns a user's enrolment with the TOTP secret decrypted
+func (s *Service) twoFactor(ctx context.Context, userID int) (*database.TwoFactor, error) {
+	tf, err := s.store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !secrets.IsEncrypted(tf.Secret) {
+		return tf, nil
+	}
+	if s.keyring == nil {
+		return nil, fmt.Errorf("totp secret of user %d is encrypted but no master key is configured", userID)
+	}
+	if tf.Secret, err = s.keyring.Decrypt(tf.Secret); err != nil {
+		return nil, fmt.Errorf("totp secret of user %d: %w", userID, err)
+	}
+	return tf, nil
+}
+
+// sealTOTPSecret encrypts a TOTP secret for storage
+func (s *Service) sealTOTPSecret(secret string) (string, error) {
+	if s.keyring == nil {
+		return secret, nil
+	}
+	sealed, err := s.keyring.Encrypt(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+	return sealed, nil
+}
+
+// resealTOTPSecrets stores every TOTP secret again encrypted with the current keyring, decrypting
+// it with previous first. With a nil previous keyring only secrets stored before encryption was
+// added are sealed.
+func (s *Service) resealTOTPSecrets(ctx context.Context, previous *secrets.Keyring) error {
+	if s.keyring == nil {
+		return nil
+	}
+	stored, err := s.store.ListTOTPSecrets(ctx)
+	if err != nil {
+		return err
+	}
+	for userID, value := range stored {
+		secret := value
+		if secrets.IsEncrypted(value) {
+			if previous == nil {
+				continue
+			}
+			if secret, err = previous.Decrypt(value); err != nil {
+				return fmt.Errorf("totp secret of user %d: %w", userID, err)
+			}
+		}
+		sealed, err := s.sealTOTPSecret(secret)
+		if err != nil {
+			return err
+		}
+		if err := s.store.ReplaceTOTPSecret(ctx, userID, value, sealed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IssueLoginChallenge returns a short-lived token that lets a user who passed the password check
+// finish signing in with their second factor
+func (s *Service) IssueLoginChallenge(ctx context.Context, userID int, rememberMe bool) (string, time.Time, error) {
+	expiresAt := time.Now().Add(loginChallengeTTL)
+	signed, err := s.signPurposeToken(ctx, &challengeClaims{
+		UserID:     userID,
+		RememberMe: rememberMe,
+	}, loginChallengeAud, expiresAt)
+	return signed, expiresAt, err
+}
+
+// ParseLoginChallenge validates a login challenge and returns who it was issued to
+func (s *Service) ParseLoginChallenge(tokenString string) (userID int, rememberMe bool, err error) {
+	claims := &challengeClaims{}
+	if err := s.parsePurposeToken(tokenString, claims, loginChallengeAud); err != nil {
+		return 0, false, ErrInvalidChallenge
+	}
+	return claims.UserID, claims.RememberMe, nil
+}
+
+// RecordLogin stores a login attempt. A successful sign-in is first compared with the user's history;
+// when it looks unusual it is marked suspicious and the reasons are returned.
+func (s *Service) RecordLogin(ctx context.Context, attempt *database.LoginAttempt) []string {
+	var reasons []string
+	if attempt.Success && attempt.UserID != nil {
+		total, fromIP, err := s.store.LoginFootprint(ctx, *attempt.UserID, attempt.IP)
+		if err != nil {
+			log.Printf("[AUTH] ⚠️ %v", err)
+		} else if total > 0 && fromIP == 0 {
+			reasons = append(reasons, "first sign-in from "+attempt.IP)
+		}
+		failures, err := s.store.CountFailedLogins(ctx, attempt.Username, time.Now().Add(-suspiciousWindow))
+		if err != nil {
+			log.Printf("[AUTH] ⚠️ %v", err)
+		} else if failures >= suspiciousFailures {
+			reasons = append(reasons, fmt.Sprintf("%d failed attempts in the last hour", failures))
+		}
+		attempt.Suspicious = len(reasons) > 0
+	}
+	if err := s.store.AddLoginAttempt(ctx, attempt); err != nil {
+		log.Printf("[AUTH] ⚠️ %v", err)
+	}
+	return reasons
+}
+
+// LoginHistory returns a user's newest login attempts (everyone's when userID is 0)
+func (s *Service) LoginHistory(ctx context.Context, userID, limit int) ([]*database.LoginAttempt, error) {
+	return s.store.ListLoginHistory(ctx, userID, limit)
+}
+
+// newRecoveryCodes returns fresh recovery codes and their hashes
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789" // No 0/o, 1/l/i
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		for j := range b {
+			b[j] = alphabet[int(b[j])%len(alphabet)]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashSecret(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
+)
+
+const (
+	// defaultLinkTTL applies when a link carries no expiry of its own;
+	// debrid CDN links stay valid for at least this long
+	defaultLinkTTL = 6 * time.Hour
+	// linkExpiryMargin treats links this close to expiry as expired so playback doesn't start on a dying link
+	linkExpiryMargin = 5 * time.Minute
+	// linkVerifyInterval skips the probe for links checked this recently
+	linkVerifyInterval = 2 * time.Minute
+	// recentPlayWindow is how long played links are kept fresh for resumed playback
+	recentPlayWindow = 48 * time.Hour
+	maxRecentPlays   = 200
+)
+
+// ResolveFunc produces a playable URL; called only when no valid cached link exists
+type ResolveFunc func(ctx context.Context) (string, error)
+
+// LinkCache caches resolved direct URLs (debrid CDN links, addon redirects) with their expiry.
+// Cached links are probed before reuse, concurrent resolutions of the same key share one call,
+// and recently played links are re-resolved before they expire so resumed playback starts instantly.
+type LinkCache struct {
+	urls   *RDURLCache
+	client *http.Client
+
+	mu       sync.Mutex
+	verified map[string]time.Time // key -> last successful probe
+	inflight map[string]*linkCall
+	recent   map[string]*recentLink
+}
+
+type linkCall struct {
+	done chan struct{}
+	url  string
+	err  error
+}
+
+type recentLink struct {
+	hash      string
+	fileID    string
+	resolve   ResolveFunc
+	playedAt  time.Time
+	expiresAt time.Time
+}
+
+// NewLinkCache creates a link cache backed by the rd_url_cache table
+func NewLinkCache(urls *RDURLCache) *LinkCache {
+	return &LinkCache{
+		urls: urls,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		verified: make(map[string]time.Time),
+		inflight: make(map[string]*linkCall),
+		recent:   make(map[string]*recentLink),
+	}
+}
+
+// URLKey returns a cache key for a URL that may embed credentials (e.g. addon URLs with debrid API keys)
+func URLKey(rawURL string) string {
+	sum := sha1.Sum([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// Resolve returns the cached link for hash/fileID if it is still valid, otherwise resolves and caches a new one.
+// Use for playback: the link is also kept fresh for a while in case playback is resumed.
+func (lc *LinkCache) Resolve(ctx context.Context, hash, fileID string, resolve ResolveFunc) (string, error) {
+	link, err := lc.Get(ctx, hash, fileID, resolve)
+	if err != nil {
+		return "", err
+	}
+	lc.rememberPlay(hash, fileID, resolve)
+	return link, nil
+}
+
+// Prefetch resolves a link in the background so a later Resolve is served from the cache
+func (lc *LinkCache) Prefetch(hash, fileID string, resolve ResolveFunc) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if _, err := lc.Get(ctx, hash, fileID, resolve); err != nil {
+			log.Printf("[LINK-CACHE] Prefetch of %s failed: %v", shortKey(hash), err)
+		}
+	}()
+}
+
+// Run keeps links for recently played items fresh until ctx is done
+func (lc *LinkCache) Run(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lc.refreshRecent(ctx)
+		}
+	}
+}
+
+// Get is Resolve without counting as a play; for background work such as stream checks
+func (lc *LinkCache) Get(ctx context.Context, hash, fileID string, resolve ResolveFunc) (string, error) {
+	if link, ok := lc.lookup(ctx, hash, fileID); ok {
+		metrics.LinkCacheLookups.WithLabelValues("hit").Inc()
+		return link, nil
+	}
+	metrics.LinkCacheLookups.WithLabelValues("miss").Inc()
+	return lc.resolveShared(ctx, hash, fileID, resolve)
+}
+
+// lookup returns a cached link that is not about to expire and still answers a probe
+func (lc *LinkCache) lookup(ctx context.Context, hash, fileID string) (string, bool) {
+	data, err := lc.urls.Get(hash, fileID)
+	if err != nil || data == nil {
+		return "", false
+	}
+	if time.Until(data.ExpiresAt) < linkExpiryMargin {
+		return "", false
+	}
+
+	key := hash + ":" + fileID
+	lc.mu.Lock()
+	checkedAt := lc.verified[key]
+	lc.mu.Unlock()
+	if time.Since(checkedAt) < linkVerifyInterval {
+		return data.URL, true
+	}
+
+	if !lc.probe(ctx, data.URL) {
+		log.Printf("[LINK-CACHE] Cached link for %s no longer answers, resolving again", shortKey(hash))
+		lc.forget(hash, fileID)
+		return "", false
+	}
+
+	lc.mu.Lock()
+	lc.verified[key] = time.Now()
+	lc.mu.Unlock()
+	return data.URL, true
+}
+
+// resolveShared resolves a link, sharing the result with concurrent callers for the same key
+func (lc *LinkCache) resolveShared(ctx context.Context, hash, fileID string, resolve ResolveFunc) (string, error) {
+	key := hash + ":" + fileID
+
+	lc.mu.Lock()
+	if call, ok := lc.inflight[key]; ok {
+		lc.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.url, call.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	call := &linkCall{done: make(chan struct{})}
+	lc.inflight[key] = call
+	lc.mu.Unlock()
+
+	call.url, call.err = resolve(ctx)
+	if call.err == nil {
+		lc.store(hash, fileID, call.url)
+	}
+
+	lc.mu.Lock()
+	delete(lc.inflight, key)
+	lc.mu.Unlock()
+	close(call.done)
+
+	return call.url, call.err
+}
+
+func (lc *LinkCache) store(hash, fileID, link string) {
+	expiresAt, ok := LinkExpiry(link)
+	if !ok {
+		expiresAt = time.Now().Add(defaultLinkTTL)
+	}
+
+	if err := lc.urls.Set(&RDURLData{Hash: hash, FileID: fileID, URL: link, ExpiresAt: expiresAt}); err != nil {
+		log.Printf("[LINK-CACHE] Failed to cache link for %s: %v", shortKey(hash), err)
+	}
+
+	key := hash + ":" + fileID
+	lc.mu.Lock()
+	lc.verified[key] = time.Now()
+	if r, ok := lc.recent[key]; ok {
+		r.expiresAt = expiresAt
+	}
+	lc.mu.Unlock()
+}
+
+func (lc *LinkCache) forget(hash, fileID string) {
+	if err := lc.urls.Delete(hash, fileID); err != nil {
+		log.Printf("[LINK-CACHE] Failed to drop link for %s: %v", shortKey(hash), err)
+	}
+	lc.mu.Lock()
+	delete(lc.verified, hash+":"+fileID)
+	lc.mu.Unlock()
+}
+
+// probe checks that a link still serves content with a one-byte range request
+// (cheaper than HEAD on CDNs that answer HEAD with 405)
+func (lc *LinkCache) probe(ctx context.Context, link string) bool {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := lc.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent
+}
+
+func (lc *LinkCache) rememberPlay(hash, fileID string, resolve ResolveFunc) {
+	var expiresAt time.Time
+	if data, err := lc.urls.Get(hash, fileID); err == nil && data != nil {
+		expiresAt = data.ExpiresAt
+	}
+
+	key := hash + ":" + fileID
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	r, ok := lc.recent[key]
+	if !ok {
+		r = &recentLink{hash: hash, fileID: fileID}
+		lc.recent[key] = r
+	}
+	r.resolve = resolve
+	r.playedAt = time.Now()
+	r.expiresAt = expiresAt
+
+	// Drop the oldest play once the list is full
+	if len(lc.recent) > maxRecentPlays {
+		var oldest string
+		for k, v := range lc.recent {
+			if oldest == "" || v.playedAt.Before(lc.recent[oldest].playedAt) {
+				oldest = k
+			}
+		}
+		delete(lc.recent, oldest)
+	}
+}
+
+// refreshRecent re-resolves links of recently played items that expire before the next refresh
+func (lc *LinkCache) refreshRecent(ctx context.Context) {
+	var due []recentLink
+	lc.mu.Lock()
+	for key, r := range lc.recent {
+		if time.Since(r.playedAt) > recentPlayWindow {
+			delete(lc.recent, key)
+			continue
+		}
+		if time.Until(r.expiresAt) < 15*time.Minute+linkExpiryMargin {
+			due = append(due, *r)
+		}
+	}
+	lc.mu.Unlock()
+
+	for _, r := range due {
+		rctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		if _, err := lc.resolveShared(rctx, r.hash, r.fileID, r.resolve); err != nil {
+			log.Printf("[LINK-CACHE] Refresh of %s failed: %v", shortKey(r.hash), err)
+		}
+		cancel()
+	}
+	if len(due) > 0 {
+		log.Printf("[LINK-CACHE] Refreshed %d links for recently played items", len(due))
+	}
+}
+
+// LinkExpiry reads the expiry embedded in a signed URL (expires/exp/e unix timestamps or S3-style
+// X-Amz-Date + X-Amz-Expires). Returns false if the URL carries none.
+func LinkExpiry(link string) (time.Time, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return time.Time{}, false
+	}
+	q := u.Query()
+
+	for _, name := range []string{"expires", "Expires", "exp", "e"} {
+		ts, err := strconv.ParseInt(q.Get(name), 10, 64)
+		if err != nil || ts < 1e9 {
+			continue
+		}
+		if ts > 1e12 {
+			return time.UnixMilli(ts), true
+		}
+		return time.Unix(ts, 0), true
+	}
+
+	if signed, err := time.Parse("20060102T150405Z", q.Get("X-Amz-Date")); err == nil {
+		if secs, err := strconv.Atoi(q.Get("X-Amz-Expires")); err == nil {
+			return signed.Add(time.Duration(secs) * time.Second), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// shortKey shortens hashes for log lines
+func shortKey(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
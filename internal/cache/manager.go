@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 )
@@ -158,17 +159,17 @@ func (m *Manager) Cleanup() {
 
 	// Clean RD URLs
 	if _, err := m.db.ExecContext(ctx, "DELETE FROM rd_url_cache WHERE expires_at < NOW()"); err != nil {
-		fmt.Printf("Error cleaning RD URL cache: %v\n", err)
+		log.Printf("Error cleaning RD URL cache: %v", err)
 	}
 
 	// Clean requests
 	if _, err := m.db.ExecContext(ctx, "DELETE FROM request_cache WHERE expires_at < NOW()"); err != nil {
-		fmt.Printf("Error cleaning request cache: %v\n", err)
+		log.Printf("Error cleaning request cache: %v", err)
 	}
 
 	// Clean old episode cache (>30 days)
 	if _, err := m.db.ExecContext(ctx, "DELETE FROM episode_cache WHERE last_updated < NOW() - INTERVAL '30 days'"); err != nil {
-		fmt.Printf("Error cleaning episode cache: %v\n", err)
+		log.Printf("Error cleaning episode cache: %v", err)
 	}
 }
 
@@ -342,6 +343,15 @@ func (rc *RDURLCache) Set(data *RDURLData) error {
 	return nil
 }
 
+func (rc *RDURLCache) Delete(hash, fileID string) error {
+	rc.mu.Lock()
+	delete(rc.memory, fmt.Sprintf("%s:%s", hash, fileID))
+	rc.mu.Unlock()
+
+	_, err := rc.db.Exec("DELETE FROM rd_url_cache WHERE hash = $1 AND file_id = $2", hash, fileID)
+	return err
+}
+
 // RequestCache implementation
 
 func NewRequestCache(db *sql.DB) *RequestCache {
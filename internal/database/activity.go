@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/lib/pq"
+)
+
+// activity_log event types
+const (
+	EventAudit         = "audit"          // Actions recorded by the API's audit trail
+	EventLibraryAdd    = "library_add"    // Movies, series and collections added to the library
+	EventLibraryRemove = "library_remove" // Movies and series removed from the library
+	EventBlacklist     = "blacklist"      // Items blacklisted, or removed from the blacklist
+	EventSettings      = "settings"       // Settings edits, with a redacted diff
+	EventUser          = "user"           // Users created, changed or deleted
+	EventImport        = "import"         // VOD imports and list syncs
+	EventStreamUpgrade = "stream_upgrade" // Cached streams replaced by better ones
+	EventPlayback      = "playback"       // Playback starts
+)
+
+// ActivityEventTypes lists every event type
+var ActivityEventTypes = []string{
+	EventAudit, EventLibraryAdd, EventLibraryRemove, EventBlacklist, EventSettings,
+	EventUser, EventImport, EventStreamUpgrade, EventPlayback,
+}
+
+// ActivityFilter selects activities; zero fields don't filter
+type ActivityFilter struct {
+	EventTypes  []string
+	ContentType string
+	ContentID   int64
+	UserID      int
+	Search      string // Case-insensitive match on the message or username
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+	Offset      int
+}
+
+// ActivityStore handles the activity log
+type ActivityStore struct {
+	db *sql.DB
+}
+
+// NewActivityStore creates a new activity store
+func NewActivityStore(db *sql.DB) *ActivityStore {
+	return &ActivityStore{db: db}
+}
+
+const activityColumns = `id, event_type, content_type, content_id, COALESCE(message, ''), COALESCE(data::text, ''), user_id, COALESCE(username, ''), created_at`
+
+func scanActivity(row rowScanner) (*models.ActivityLog, error) {
+	a := &models.ActivityLog{}
+	var userID sql.NullInt64
+	if err := row.Scan(&a.ID, &a.EventType, &a.ContentType, &a.ContentID, &a.Message, &a.Data, &userID, &a.Username, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		a.UserID = &id
+	}
+	return a, nil
+}
+
+// Add records an activity; Data must be empty or a JSON document
+func (s *ActivityStore) Add(ctx context.Context, entry *models.ActivityLog) error {
+	var data, username interface{}
+	if entry.Data != "" {
+		data = entry.Data
+	}
+	if entry.Username != "" {
+		username = entry.Username
+	}
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO activity_log (event_type, content_type, content_id, message, data, user_id, username, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING id, created_at
+	`, entry.EventType, entry.ContentType, entry.ContentID, entry.Message, data, entry.UserID, username).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
+// List returns the newest activities of an event type (all types when empty)
+func (s *ActivityStore) List(ctx context.Context, eventType string, limit int) ([]*models.ActivityLog, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	filter := ActivityFilter{Limit: limit}
+	if eventType != "" {
+		filter.EventTypes = []string{eventType}
+	}
+	list, _, err := s.Query(ctx, filter)
+	return list, err
+}
+
+// Query returns the newest activities matching the filter, and how many match in total
+func (s *ActivityStore) Query(ctx context.Context, filter ActivityFilter) ([]*models.ActivityLog, int, error) {
+	var where []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if len(filter.EventTypes) > 0 {
+		placeholders := make([]string, len(filter.EventTypes))
+		for i, eventType := range filter.EventTypes {
+			placeholders[i] = arg(eventType)
+		}
+		where = append(where, "event_type IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if filter.ContentType != "" {
+		where = append(where, "content_type = "+arg(filter.ContentType))
+	}
+	if filter.ContentID != 0 {
+		where = append(where, "content_id = "+arg(filter.ContentID))
+	}
+	if filter.UserID != 0 {
+		where = append(where, "user_id = "+arg(filter.UserID))
+	}
+	if filter.Search != "" {
+		pattern := arg("%" + filter.Search + "%")
+		where = append(where, "(message ILIKE "+pattern+" OR username ILIKE "+pattern+")")
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= "+arg(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < "+arg(filter.Until))
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM activity_log `+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count activity: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+activityColumns+`
+		FROM activity_log
+		`+clause+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT `+arg(limit)+` OFFSET `+arg(filter.Offset), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list activity: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.ActivityLog
+	for rows.Next() {
+		a, err := scanActivity(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		list = append(list, a)
+	}
+	return list, total, rows.Err()
+}
+
+// Prune deletes activities older than before; an empty eventType prunes every type except those in keep
+func (s *ActivityStore) Prune(ctx context.Context, eventType string, before time.Time, keep ...string) (int64, error) {
+	var res sql.Result
+	var err error
+	if eventType != "" {
+		res, err = s.db.ExecContext(ctx, `DELETE FROM activity_log WHERE event_type = $1 AND created_at < $2`, eventType, before)
+	} else {
+		res, err = s.db.ExecContext(ctx, `DELETE FROM activity_log WHERE created_at < $1 AND NOT (event_type = ANY($2))`, before, pq.Array(keep))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune activity: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,407 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrRefreshTokenUsed is returned when a refresh token that was already rotated or revoked is presented again
+var ErrRefreshTokenUsed = errors.New("refresh token already used")
+
+// Signing key purposes: access tokens rotate, the URL key signs links handed to players and never does
+const (
+	SigningKeyToken = "token"
+	SigningKeyURL   = "url"
+)
+
+// SigningKey is an HMAC key; access token keys are identified by the JWT "kid" header.
+// Secret is stored encrypted; the auth service encrypts and decrypts it.
+type SigningKey struct {
+	ID        string
+	Secret    []byte
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+// RefreshToken is a server-side session; only the token's hash is stored
+type RefreshToken struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"-"`
+	Role       string     `json:"-"`
+	RememberMe bool       `json:"remember_me"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy *int64     `json:"-"`
+}
+
+// APIKey is a named, scoped key for scripts and integrations; only the key's hash is stored
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"username"`
+	Role       string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// AuthStore handles signing keys, refresh tokens and API keys
+type AuthStore struct {
+	db *sql.DB
+}
+
+// NewAuthStore creates a new auth store
+func NewAuthStore(db *sql.DB) *AuthStore {
+	return &AuthStore{db: db}
+}
+
+// ListSigningKeys returns all access token signing keys, newest first
+func (s *AuthStore) ListSigningKeys(ctx context.Context) ([]SigningKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, secret, created_at, retired_at FROM auth_signing_keys
+		WHERE purpose = $1 ORDER BY created_at DESC
+	`, SigningKeyToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []SigningKey
+	for rows.Next() {
+		var k SigningKey
+		if err := rows.Scan(&k.ID, &k.Secret, &k.CreatedAt, &k.RetiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// AddSigningKey stores a new signing key and retires the others
+func (s *AuthStore) AddSigningKey(ctx context.Context, id string, secret []byte) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE auth_signing_keys SET retired_at = NOW() WHERE retired_at IS NULL AND purpose = $1
+	`, SigningKeyToken); err != nil {
+		return fmt.Errorf("failed to retire signing keys: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO auth_signing_keys (id, secret, purpose, created_at) VALUES ($1, $2, $3, NOW())
+	`, id, secret, SigningKeyToken); err != nil {
+		return fmt.Errorf("failed to add signing key: %w", err)
+	}
+	return tx.Commit()
+}
+
+// EnsureSigningKey returns the key stored under id, storing secret there first when there is none.
+// Concurrent callers all get the key that was stored first.
+func (s *AuthStore) EnsureSigningKey(ctx context.Context, id, purpose string, secret []byte) (*SigningKey, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO auth_signing_keys (id, secret, purpose, created_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (id) DO NOTHING
+	`, id, secret, purpose); err != nil {
+		return nil, fmt.Errorf("failed to add signing key: %w", err)
+	}
+	k := &SigningKey{}
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT id, secret, created_at, retired_at FROM auth_signing_keys WHERE id = $1
+	`, id).Scan(&k.ID, &k.Secret, &k.CreatedAt, &k.RetiredAt); err != nil {
+		return nil, fmt.Errorf("failed to get signing key: %w", err)
+	}
+	return k, nil
+}
+
+// UpdateSigningKeySecret replaces a key's stored secret, e.g. with the same key encrypted
+func (s *AuthStore) UpdateSigningKeySecret(ctx context.Context, id string, secret []byte) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE auth_signing_keys SET secret = $2 WHERE id = $1
+	`, id, secret); err != nil {
+		return fmt.Errorf("failed to update signing key: %w", err)
+	}
+	return nil
+}
+
+// PruneSigningKeys deletes keys retired before the cutoff
+func (s *AuthStore) PruneSigningKeys(ctx context.Context, retiredBefore time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM auth_signing_keys WHERE retired_at IS NOT NULL AND retired_at < $1 AND purpose = $2
+	`, retiredBefore, SigningKeyToken)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune signing keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+const refreshTokenColumns = `t.id, t.user_id, u.username, COALESCE(u.role, ''), t.remember_me, t.user_agent, t.ip,
+	t.created_at, t.last_used_at, t.expires_at, t.revoked_at, t.replaced_by`
+
+func scanRefreshToken(row rowScanner) (*RefreshToken, error) {
+	t := &RefreshToken{}
+	if err := row.Scan(&t.ID, &t.UserID, &t.Username, &t.Role, &t.RememberMe, &t.UserAgent, &t.IP,
+		&t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt, &t.RevokedAt, &t.ReplacedBy); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// CreateRefreshToken stores a new session
+func (s *AuthStore) CreateRefreshToken(ctx context.Context, userID int, tokenHash string, rememberMe bool, expiresAt time.Time, userAgent, ip string) (*RefreshToken, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO auth_refresh_tokens (user_id, token_hash, remember_me, user_agent, ip, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), $6)
+		RETURNING id
+	`, userID, tokenHash, rememberMe, userAgent, ip, expiresAt).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return s.getRefreshToken(ctx, `t.id = $1`, id)
+}
+
+// GetRefreshToken returns a session by its token hash, including revoked and expired ones
+func (s *AuthStore) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	return s.getRefreshToken(ctx, `t.token_hash = $1`, tokenHash)
+}
+
+func (s *AuthStore) getRefreshToken(ctx context.Context, where string, arg interface{}) (*RefreshToken, error) {
+	return scanRefreshToken(s.db.QueryRowContext(ctx, `
+		SELECT `+refreshTokenColumns+`
+		FROM auth_refresh_tokens t
+		JOIN users u ON u.user_id = t.user_id
+		WHERE `+where, arg))
+}
+
+// RotateRefreshToken revokes a session and issues its replacement in one step.
+// It returns ErrRefreshTokenUsed when the session was already rotated or revoked.
+func (s *AuthStore) RotateRefreshToken(ctx context.Context, id int64, newHash string, expiresAt time.Time, userAgent, ip string) (*RefreshToken, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var rememberMe bool
+	err = tx.QueryRowContext(ctx, `
+		UPDATE auth_refresh_tokens SET revoked_at = NOW(), last_used_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING user_id, remember_me
+	`, id).Scan(&userID, &rememberMe)
+	if err == sql.ErrNoRows {
+		return nil, ErrRefreshTokenUsed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	var newID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO auth_refresh_tokens (user_id, token_hash, remember_me, user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW(), $6)
+		RETURNING id
+	`, userID, newHash, rememberMe, userAgent, ip, expiresAt).Scan(&newID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE auth_refresh_tokens SET replaced_by = $1 WHERE id = $2`, newID, id); err != nil {
+		return nil, fmt.Errorf("failed to link refresh token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return s.getRefreshToken(ctx, `t.id = $1`, newID)
+}
+
+// ListRefreshTokens returns a user's active sessions, newest first
+func (s *AuthStore) ListRefreshTokens(ctx context.Context, userID int) ([]*RefreshToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+refreshTokenColumns+`
+		FROM auth_refresh_tokens t
+		JOIN users u ON u.user_id = t.user_id
+		WHERE t.user_id = $1 AND t.revoked_at IS NULL AND t.expires_at > NOW()
+		ORDER BY COALESCE(t.last_used_at, t.created_at) DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*RefreshToken
+	for rows.Next() {
+		t, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refresh token: %w", err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// RevokeRefreshToken ends one of a user's sessions; sql.ErrNoRows when it isn't active
+func (s *AuthStore) RevokeRefreshToken(ctx context.Context, userID int, id int64) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE auth_refresh_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every refresh token of a user and rejects access tokens issued so far
+func (s *AuthStore) RevokeAllSessions(ctx context.Context, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE auth_refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET sessions_revoked_at = NOW() WHERE user_id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return tx.Commit()
+}
+
+// PruneRefreshTokens deletes sessions that expired or were revoked before the cutoff
+func (s *AuthStore) PruneRefreshTokens(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM auth_refresh_tokens WHERE expires_at < $1 OR revoked_at < $1
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune refresh tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// CountActiveSessions counts refresh tokens that are neither revoked nor expired
+func (s *AuthStore) CountActiveSessions(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM auth_refresh_tokens WHERE revoked_at IS NULL AND expires_at > NOW()
+	`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	return count, nil
+}
+
+const apiKeyColumns = `k.id, k.user_id, u.username, COALESCE(u.role, ''), k.name, k.prefix, k.scopes,
+	k.created_at, k.last_used_at, k.expires_at, k.revoked_at`
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	k := &APIKey{}
+	if err := row.Scan(&k.ID, &k.UserID, &k.Username, &k.Role, &k.Name, &k.Prefix, pq.Array(&k.Scopes),
+		&k.CreatedAt, &k.LastUsedAt, &k.ExpiresAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// CreateAPIKey stores a new API key
+func (s *AuthStore) CreateAPIKey(ctx context.Context, userID int, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*APIKey, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), $6)
+		RETURNING id
+	`, userID, name, prefix, keyHash, pq.Array(scopes), expiresAt).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return s.GetAPIKey(ctx, id)
+}
+
+// GetAPIKey returns an API key by ID
+func (s *AuthStore) GetAPIKey(ctx context.Context, id int64) (*APIKey, error) {
+	return scanAPIKey(s.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		JOIN users u ON u.user_id = k.user_id
+		WHERE k.id = $1
+	`, id))
+}
+
+// UseAPIKey returns the active API key with the given hash and records its use
+func (s *AuthStore) UseAPIKey(ctx context.Context, keyHash string) (*APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		JOIN users u ON u.user_id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+	`, keyHash))
+	if err != nil {
+		return nil, err
+	}
+	// At most one write a minute per key
+	s.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, key.ID)
+	return key, nil
+}
+
+// ListAPIKeys returns a user's API keys (all users' when userID is 0), newest first
+func (s *AuthStore) ListAPIKeys(ctx context.Context, userID int) ([]*APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		JOIN users u ON u.user_id = k.user_id
+		WHERE $1 = 0 OR k.user_id = $1
+		ORDER BY k.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+// RevokeAPIKey revokes an API key; sql.ErrNoRows when it was already revoked
+func (s *AuthStore) RevokeAPIKey(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
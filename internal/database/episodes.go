@@ -173,7 +173,7 @@ func (e *EpisodeStore) GetBySeriesAndNumber(ctx context.Context, seriesID int64,
 // ListBySeries returns all episodes for a series
 func (e *EpisodeStore) ListBySeries(ctx context.Context, seriesID int64) ([]*models.Episode, error) {
 	query := `
-		SELECT id, series_id, season_number, episode_number, title,
+		SELECT id, series_id, COALESCE(tmdb_id, 0), season_number, episode_number, title,
 			overview, air_date, still_path, monitored, available, last_checked
 		FROM library_episodes
 		WHERE series_id = $1
@@ -191,7 +191,7 @@ func (e *EpisodeStore) ListBySeries(ctx context.Context, seriesID int64) ([]*mod
 		var episode models.Episode
 
 		err := rows.Scan(
-			&episode.ID, &episode.SeriesID, &episode.SeasonNumber,
+			&episode.ID, &episode.SeriesID, &episode.TMDBID, &episode.SeasonNumber,
 			&episode.EpisodeNumber, &episode.Title, &episode.Overview, &episode.AirDate,
 			&episode.StillPath, &episode.Monitored, &episode.Available, &episode.LastChecked,
 		)
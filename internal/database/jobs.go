@@ -0,0 +1,393 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Job run statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job run triggers
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+var (
+	// ErrJobBusy is returned when a job is already queued or running
+	ErrJobBusy = errors.New("job is already queued or running")
+	// ErrLeaseLost is returned when another process took over a job whose lease expired
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// runHistoryLimit is how many runs are kept per job
+const runHistoryLimit = 100
+
+// Job is a scheduled background job and the state of its latest run
+type Job struct {
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Schedule        string     `json:"schedule"`
+	Enabled         bool       `json:"enabled"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty"`
+	LockedBy        string     `json:"locked_by,omitempty"`
+	CancelRequested bool       `json:"cancel_requested"`
+	RunCount        int64      `json:"run_count"`
+	LastRun         *JobRun    `json:"last_run,omitempty"`
+}
+
+// JobRun is one execution of a job
+type JobRun struct {
+	ID              int64      `json:"id"`
+	JobName         string     `json:"job_name"`
+	Trigger         string     `json:"trigger"`
+	Worker          string     `json:"worker,omitempty"`
+	Status          string     `json:"status"`
+	QueuedAt        time.Time  `json:"queued_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	DurationMS      int64      `json:"duration_ms"`
+	Progress        int        `json:"progress"`
+	ProgressMessage string     `json:"progress_message"`
+	ItemsProcessed  int        `json:"items_processed"`
+	ItemsTotal      int        `json:"items_total"`
+	Error           string     `json:"error,omitempty"`
+	Logs            string     `json:"logs,omitempty"`
+}
+
+// JobStore persists the job queue and run history
+type JobStore struct {
+	db *sql.DB
+}
+
+// NewJobStore creates a new job store
+func NewJobStore(db *sql.DB) *JobStore {
+	return &JobStore{db: db}
+}
+
+const jobRunColumns = `id, job_name, trigger, COALESCE(worker, ''), status, queued_at, started_at, finished_at,
+	COALESCE(duration_ms, 0), progress, progress_message, items_processed, items_total, COALESCE(error, '')`
+
+func scanJobRun(row rowScanner) (*JobRun, error) {
+	r := &JobRun{}
+	err := row.Scan(&r.ID, &r.JobName, &r.Trigger, &r.Worker, &r.Status, &r.QueuedAt, &r.StartedAt, &r.FinishedAt,
+		&r.DurationMS, &r.Progress, &r.ProgressMessage, &r.ItemsProcessed, &r.ItemsTotal, &r.Error)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// EnsureJob registers a job; an existing job keeps its (possibly edited) schedule and enabled flag
+func (s *JobStore) EnsureJob(ctx context.Context, name, description, schedule string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO jobs (name, description, schedule, next_run_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description
+	`, name, description, schedule)
+	if err != nil {
+		return fmt.Errorf("failed to register job %s: %w", name, err)
+	}
+	return nil
+}
+
+// ListJobs returns all jobs with their latest run
+func (s *JobStore) ListJobs(ctx context.Context) ([]*Job, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT j.name, j.description, j.schedule, j.enabled, j.next_run_at, COALESCE(j.locked_by, ''), j.cancel_requested,
+			(SELECT COUNT(*) FROM job_runs r WHERE r.job_name = j.name AND r.status <> 'queued'),
+			(SELECT id FROM job_runs r WHERE r.job_name = j.name ORDER BY id DESC LIMIT 1)
+		FROM jobs j
+		ORDER BY j.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	var lastRunIDs []sql.NullInt64
+	for rows.Next() {
+		j := &Job{}
+		var lastRunID sql.NullInt64
+		if err := rows.Scan(&j.Name, &j.Description, &j.Schedule, &j.Enabled, &j.NextRunAt, &j.LockedBy, &j.CancelRequested,
+			&j.RunCount, &lastRunID); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, j)
+		lastRunIDs = append(lastRunIDs, lastRunID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i, id := range lastRunIDs {
+		if !id.Valid {
+			continue
+		}
+		if run, err := s.GetRun(ctx, id.Int64, false); err == nil {
+			jobs[i].LastRun = run
+		}
+	}
+	return jobs, nil
+}
+
+// UpdateJob changes a job's enabled flag and/or schedule; nextRun is recomputed by the caller for a new schedule
+func (s *JobStore) UpdateJob(ctx context.Context, name string, enabled *bool, schedule *string, nextRun *time.Time) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE jobs SET
+			enabled = COALESCE($2, enabled),
+			schedule = COALESCE($3, schedule),
+			next_run_at = COALESCE($4, next_run_at),
+			updated_at = NOW()
+		WHERE name = $1
+	`, name, enabled, schedule, nextRun)
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Enqueue queues a manual run of a job
+func (s *JobStore) Enqueue(ctx context.Context, name string) (*JobRun, error) {
+	run, err := scanJobRun(s.db.QueryRowContext(ctx, `
+		INSERT INTO job_runs (job_name, trigger, status, queued_at)
+		SELECT $1, $2, $3, NOW()
+		WHERE EXISTS (SELECT 1 FROM jobs WHERE name = $1)
+		  AND NOT EXISTS (SELECT 1 FROM job_runs WHERE job_name = $1 AND status IN ('queued', 'running'))
+		RETURNING `+jobRunColumns, name, JobTriggerManual, JobQueued))
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM jobs WHERE name = $1)`, name).Scan(&exists); err == nil && !exists {
+			return nil, sql.ErrNoRows
+		}
+		return nil, ErrJobBusy
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			// Lost a race with another trigger
+			return nil, ErrJobBusy
+		}
+		return nil, fmt.Errorf("failed to enqueue job %s: %w", name, err)
+	}
+	return run, nil
+}
+
+// Claim takes the lease on one due or manually queued job among names and starts a run for it.
+// Returns nil when nothing is due. Rows locked by another claimer are skipped, so concurrent
+// processes never pick the same job.
+func (s *JobStore) Claim(ctx context.Context, names []string, worker string, lease time.Duration) (*Job, *JobRun, error) {
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	job := &Job{}
+	err = tx.QueryRowContext(ctx, `
+		SELECT j.name, j.description, j.schedule, j.enabled
+		FROM jobs j
+		WHERE j.name = ANY($1)
+		  AND (j.locked_until IS NULL OR j.locked_until < NOW())
+		  AND ((j.enabled AND j.next_run_at <= NOW())
+		       OR EXISTS (SELECT 1 FROM job_runs r WHERE r.job_name = j.name AND r.status = 'queued'))
+		ORDER BY j.next_run_at NULLS FIRST
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`, pq.Array(names)).Scan(&job.Name, &job.Description, &job.Schedule, &job.Enabled)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	// A run still marked running belonged to a process that died without releasing its lease
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE job_runs SET status = $2, finished_at = NOW(), error = 'worker stopped before the run finished (lease expired)',
+			duration_ms = (EXTRACT(EPOCH FROM (NOW() - started_at)) * 1000)::BIGINT
+		WHERE job_name = $1 AND status = $3
+	`, job.Name, JobFailed, JobRunning); err != nil {
+		return nil, nil, fmt.Errorf("failed to expire stale run: %w", err)
+	}
+
+	// Prefer a manually queued run over a scheduled one
+	run, err := scanJobRun(tx.QueryRowContext(ctx, `
+		UPDATE job_runs SET status = $2, worker = $3, started_at = NOW()
+		WHERE id = (SELECT id FROM job_runs WHERE job_name = $1 AND status = $4 ORDER BY id LIMIT 1)
+		RETURNING `+jobRunColumns, job.Name, JobRunning, worker, JobQueued))
+	if err == sql.ErrNoRows {
+		run, err = scanJobRun(tx.QueryRowContext(ctx, `
+			INSERT INTO job_runs (job_name, trigger, worker, status, queued_at, started_at)
+			VALUES ($1, $2, $3, $4, NOW(), NOW())
+			RETURNING `+jobRunColumns, job.Name, JobTriggerSchedule, worker, JobRunning))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start run: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE jobs SET locked_by = $2, locked_until = NOW() + make_interval(secs => $3),
+			current_run_id = $4, cancel_requested = FALSE
+		WHERE name = $1
+	`, job.Name, worker, lease.Seconds(), run.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	job.LockedBy = worker
+	return job, run, nil
+}
+
+// Heartbeat renews a running job's lease and saves its progress and logs.
+// Returns whether cancellation was requested, or ErrLeaseLost if another process owns the job now.
+func (s *JobStore) Heartbeat(ctx context.Context, run *JobRun, worker string, lease time.Duration) (bool, error) {
+	var cancel bool
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE jobs SET locked_until = NOW() + make_interval(secs => $3)
+		WHERE name = $1 AND locked_by = $2 AND current_run_id = $4
+		RETURNING cancel_requested
+	`, run.JobName, worker, lease.Seconds(), run.ID).Scan(&cancel)
+	if err == sql.ErrNoRows {
+		return false, ErrLeaseLost
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+
+	if err := s.saveProgress(ctx, s.db, run); err != nil {
+		return cancel, err
+	}
+	return cancel, nil
+}
+
+// Finish records a run's outcome, releases the lease and schedules the next run
+func (s *JobStore) Finish(ctx context.Context, run *JobRun, worker string, nextRun time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.saveProgress(ctx, tx, run); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE job_runs SET status = $2, finished_at = NOW(), error = NULLIF($3, ''),
+			duration_ms = (EXTRACT(EPOCH FROM (NOW() - started_at)) * 1000)::BIGINT
+		WHERE id = $1 AND status = 'running'
+	`, run.ID, run.Status, run.Error); err != nil {
+		return fmt.Errorf("failed to finish run: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE jobs SET locked_by = NULL, locked_until = NULL, current_run_id = NULL,
+			cancel_requested = FALSE, next_run_at = $3
+		WHERE name = $1 AND locked_by = $2
+	`, run.JobName, worker, nextRun); err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM job_runs WHERE job_name = $1 AND id NOT IN (
+			SELECT id FROM job_runs WHERE job_name = $1 ORDER BY id DESC LIMIT $2
+		)
+	`, run.JobName, runHistoryLimit); err != nil {
+		return fmt.Errorf("failed to prune run history: %w", err)
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *JobStore) saveProgress(ctx context.Context, db execer, run *JobRun) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE job_runs SET progress = $2, progress_message = $3, items_processed = $4, items_total = $5, logs = $6
+		WHERE id = $1
+	`, run.ID, run.Progress, run.ProgressMessage, run.ItemsProcessed, run.ItemsTotal, run.Logs)
+	if err != nil {
+		return fmt.Errorf("failed to save run progress: %w", err)
+	}
+	return nil
+}
+
+// RequestCancel cancels a queued run, or asks the process running the job to stop it.
+// Returns sql.ErrNoRows when the job is neither queued nor running.
+func (s *JobStore) RequestCancel(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE job_runs SET status = $2, finished_at = NOW(), error = 'cancelled before it started'
+		WHERE job_name = $1 AND status = $3
+	`, name, JobCancelled, JobQueued)
+	if err != nil {
+		return fmt.Errorf("failed to cancel queued run: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	result, err = s.db.ExecContext(ctx, `
+		UPDATE jobs SET cancel_requested = TRUE WHERE name = $1 AND current_run_id IS NOT NULL
+	`, name)
+	if err != nil {
+		return fmt.Errorf("failed to request cancellation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListRuns returns a job's run history, newest first, without logs
+func (s *JobStore) ListRuns(ctx context.Context, name string, limit int) ([]*JobRun, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+jobRunColumns+` FROM job_runs WHERE job_name = $1 ORDER BY id DESC LIMIT $2`, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*JobRun
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetRun returns a single run, optionally with its logs
+func (s *JobStore) GetRun(ctx context.Context, id int64, withLogs bool) (*JobRun, error) {
+	run, err := scanJobRun(s.db.QueryRowContext(ctx, `SELECT `+jobRunColumns+` FROM job_runs WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	if withLogs {
+		if err := s.db.QueryRowContext(ctx, `SELECT logs FROM job_runs WHERE id = $1`, id).Scan(&run.Logs); err != nil {
+			return nil, err
+		}
+	}
+	return run, nil
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LocalMediaFile is a file on disk matched to a library movie or episode
+type LocalMediaFile struct {
+	ID            int64     `json:"id"`
+	Path          string    `json:"path"`
+	MediaType     string    `json:"media_type"` // "movie" or "series"
+	MovieID       *int64    `json:"movie_id,omitempty"`
+	SeriesID      *int64    `json:"series_id,omitempty"`
+	SeasonNumber  int       `json:"season_number,omitempty"`
+	EpisodeNumber int       `json:"episode_number,omitempty"`
+	SizeBytes     int64     `json:"size_bytes"`
+	Container     string    `json:"container"`
+	Resolution    string    `json:"resolution"`
+	Codec         string    `json:"codec"`
+	SourceType    string    `json:"source_type"`
+	HDRType       string    `json:"hdr_type"`
+	AudioFormat   string    `json:"audio_format"`
+	ModifiedAt    time.Time `json:"modified_at"`
+	ScannedAt     time.Time `json:"scanned_at"`
+}
+
+// LocalMediaStore handles local_media_files database operations
+type LocalMediaStore struct {
+	db *sql.DB
+}
+
+// NewLocalMediaStore creates a new local media store
+func NewLocalMediaStore(db *sql.DB) *LocalMediaStore {
+	return &LocalMediaStore{db: db}
+}
+
+const localMediaColumns = `
+	f.id, f.path, f.media_type, f.movie_id, f.series_id,
+	COALESCE(f.season_number, 0), COALESCE(f.episode_number, 0), f.size_bytes,
+	COALESCE(f.container, ''), COALESCE(f.resolution, ''), COALESCE(f.codec, ''),
+	COALESCE(f.source_type, ''), COALESCE(f.hdr_type, ''), COALESCE(f.audio_format, ''),
+	COALESCE(f.modified_at, f.scanned_at), f.scanned_at`
+
+func scanLocalMediaFile(row interface{ Scan(...interface{}) error }) (*LocalMediaFile, error) {
+	f := &LocalMediaFile{}
+	var movieID, seriesID sql.NullInt64
+	err := row.Scan(
+		&f.ID, &f.Path, &f.MediaType, &movieID, &seriesID,
+		&f.SeasonNumber, &f.EpisodeNumber, &f.SizeBytes,
+		&f.Container, &f.Resolution, &f.Codec,
+		&f.SourceType, &f.HDRType, &f.AudioFormat,
+		&f.ModifiedAt, &f.ScannedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if movieID.Valid {
+		f.MovieID = &movieID.Int64
+	}
+	if seriesID.Valid {
+		f.SeriesID = &seriesID.Int64
+	}
+	return f, nil
+}
+
+// Upsert inserts or updates a file by path
+func (s *LocalMediaStore) Upsert(ctx context.Context, f *LocalMediaFile) error {
+	query := `
+		INSERT INTO local_media_files (
+			path, media_type, movie_id, series_id, season_number, episode_number,
+			size_bytes, container, resolution, codec, source_type, hdr_type, audio_format,
+			modified_at, scanned_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW())
+		ON CONFLICT (path) DO UPDATE SET
+			media_type = EXCLUDED.media_type,
+			movie_id = EXCLUDED.movie_id,
+			series_id = EXCLUDED.series_id,
+			season_number = EXCLUDED.season_number,
+			episode_number = EXCLUDED.episode_number,
+			size_bytes = EXCLUDED.size_bytes,
+			container = EXCLUDED.container,
+			resolution = EXCLUDED.resolution,
+			codec = EXCLUDED.codec,
+			source_type = EXCLUDED.source_type,
+			hdr_type = EXCLUDED.hdr_type,
+			audio_format = EXCLUDED.audio_format,
+			modified_at = EXCLUDED.modified_at,
+			scanned_at = NOW()
+		RETURNING id, scanned_at
+	`
+
+	var season, episode sql.NullInt64
+	if f.MediaType == "series" {
+		season = sql.NullInt64{Int64: int64(f.SeasonNumber), Valid: true}
+		episode = sql.NullInt64{Int64: int64(f.EpisodeNumber), Valid: true}
+	}
+
+	err := s.db.QueryRowContext(ctx, query,
+		f.Path, f.MediaType, f.MovieID, f.SeriesID, season, episode,
+		f.SizeBytes, f.Container, f.Resolution, f.Codec, f.SourceType, f.HDRType, f.AudioFormat,
+		f.ModifiedAt,
+	).Scan(&f.ID, &f.ScannedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert local media file: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a file by ID
+func (s *LocalMediaStore) Get(ctx context.Context, id int64) (*LocalMediaFile, error) {
+	query := `SELECT ` + localMediaColumns + ` FROM local_media_files f WHERE f.id = $1`
+	f, err := scanLocalMediaFile(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("local media file not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get local media file: %w", err)
+	}
+	return f, nil
+}
+
+// FindMovie returns the files for a library movie by IMDB ID, largest first
+func (s *LocalMediaStore) FindMovie(ctx context.Context, imdbID string) ([]*LocalMediaFile, error) {
+	query := `
+		SELECT ` + localMediaColumns + `
+		FROM local_media_files f
+		JOIN library_movies m ON m.id = f.movie_id
+		WHERE m.imdb_id = $1 OR m.metadata->>'imdb_id' = $1
+		ORDER BY f.size_bytes DESC
+	`
+	return s.list(ctx, query, imdbID)
+}
+
+// FindEpisode returns the files for a library episode by series IMDB ID, largest first
+func (s *LocalMediaStore) FindEpisode(ctx context.Context, imdbID string, season, episode int) ([]*LocalMediaFile, error) {
+	query := `
+		SELECT ` + localMediaColumns + `
+		FROM local_media_files f
+		JOIN library_series sr ON sr.id = f.series_id
+		WHERE (sr.imdb_id = $1 OR sr.metadata->>'imdb_id' = $1 OR sr.metadata->'external_ids'->>'imdb_id' = $1)
+		  AND f.season_number = $2 AND f.episode_number = $3
+		ORDER BY f.size_bytes DESC
+	`
+	return s.list(ctx, query, imdbID, season, episode)
+}
+
+// ListModified returns the modification time of every known file keyed by path
+func (s *LocalMediaStore) ListModified(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT path, COALESCE(modified_at, scanned_at) FROM local_media_files`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local media files: %w", err)
+	}
+	defer rows.Close()
+
+	modified := make(map[string]time.Time)
+	for rows.Next() {
+		var path string
+		var mod time.Time
+		if err := rows.Scan(&path, &mod); err != nil {
+			return nil, fmt.Errorf("failed to scan local media file: %w", err)
+		}
+		modified[path] = mod
+	}
+	return modified, rows.Err()
+}
+
+// DeleteByPath removes a file that no longer exists on disk
+func (s *LocalMediaStore) DeleteByPath(ctx context.Context, path string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM local_media_files WHERE path = $1`, path)
+	if err != nil {
+		return fmt.Errorf("failed to delete local media file: %w", err)
+	}
+	return nil
+}
+
+// DeleteUnder removes every file at or below a directory path
+func (s *LocalMediaStore) DeleteUnder(ctx context.Context, dir string) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM local_media_files WHERE path = $1 OR path LIKE $2`,
+		dir, escapeLike(dir)+"/%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete local media files: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Count returns the number of matched local files
+func (s *LocalMediaStore) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM local_media_files`).Scan(&count)
+	return count, err
+}
+
+func (s *LocalMediaStore) list(ctx context.Context, query string, args ...interface{}) ([]*LocalMediaFile, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query local media files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*LocalMediaFile
+	for rows.Next() {
+		f, err := scanLocalMediaFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan local media file: %w", err)
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards in user input or a literal prefix
+func escapeLike(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r == '%' || r == '_' || r == '\\' {
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Login sources and failure reasons recorded in the login history
+const (
+	LoginSourceWeb    = "web"
+	LoginSourceXtream = "xtream"
+	LoginSourceOIDC   = "oidc"
+	LoginSourceProxy  = "proxy"
+
+	LoginUnknownUser = "unknown_user"
+	LoginBadPassword = "bad_password"
+	LoginBadCode     = "bad_code"
+)
+
+// TwoFactor is a user's TOTP enrolment
+type TwoFactor struct {
+	Secret   string // Base32, as stored (encrypted when a keyring is configured); empty when never enrolled
+	Enabled  bool
+	LastStep *int64 // Time step of the last accepted code
+}
+
+// LoginAttempt is one entry in the login history
+type LoginAttempt struct {
+	ID            int64     `json:"id"`
+	UserID        *int      `json:"user_id,omitempty"`
+	Username      string    `json:"username"`
+	Source        string    `json:"source"`
+	IP            string    `json:"ip"`
+	UserAgent     string    `json:"user_agent"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	Suspicious    bool      `json:"suspicious"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// GetTwoFactor returns a user's TOTP enrolment
+func (s *AuthStore) GetTwoFactor(ctx context.Context, userID int) (*TwoFactor, error) {
+	var secret sql.NullString
+	tf := &TwoFactor{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE user_id = $1
+	`, userID).Scan(&secret, &tf.Enabled, &tf.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	tf.Secret = secret.String
+	return tf, nil
+}
+
+// SetTOTPSecret stores the secret of a pending enrolment; sql.ErrNoRows when 2FA is already enabled
+func (s *AuthStore) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users SET totp_secret = $2, totp_last_step = NULL
+		WHERE user_id = $1 AND totp_enabled = FALSE
+	`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to store totp secret: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListTOTPSecrets returns every stored TOTP secret by user ID
+func (s *AuthStore) ListTOTPSecrets(ctx context.Context) (map[int]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id, totp_secret FROM users WHERE totp_secret IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list totp secrets: %w", err)
+	}
+	defer rows.Close()
+
+	secrets := make(map[int]string)
+	for rows.Next() {
+		var userID int
+		var secret string
+		if err := rows.Scan(&userID, &secret); err != nil {
+			return nil, err
+		}
+		secrets[userID] = secret
+	}
+	return secrets, rows.Err()
+}
+
+// ReplaceTOTPSecret stores a re-encrypted TOTP secret, unless the user's secret changed since it was read
+func (s *AuthStore) ReplaceTOTPSecret(ctx context.Context, userID int, old, secret string) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE users SET totp_secret = $3 WHERE user_id = $1 AND totp_secret = $2
+	`, userID, old, secret); err != nil {
+		return fmt.Errorf("failed to store totp secret: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP turns on 2FA for the pending secret and replaces the user's recovery codes
+func (s *AuthStore) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users SET totp_enabled = TRUE, totp_last_step = $2
+		WHERE user_id = $1 AND totp_enabled = FALSE AND totp_secret IS NOT NULL
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DisableTOTP turns off 2FA and deletes the secret and recovery codes
+func (s *AuthStore) DisableTOTP(ctx context.Context, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = NULL WHERE user_id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM auth_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records an accepted code's time step; false when that step (or a later one) was already used
+func (s *AuthStore) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users SET totp_last_step = $2
+		WHERE user_id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ReplaceRecoveryCodes swaps a user's recovery codes for new ones
+func (s *AuthStore) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM auth_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO auth_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, NOW())
+		`, userID, hash); err != nil {
+			return fmt.Errorf("failed to add recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used; false when there is none with that hash
+func (s *AuthStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE auth_recovery_codes SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM auth_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		) AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (s *AuthStore) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM auth_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// AddLoginAttempt records a login attempt
+func (s *AuthStore) AddLoginAttempt(ctx context.Context, a *LoginAttempt) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO login_history (user_id, username, source, ip, user_agent, success, failure_reason, suspicious, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING id, created_at
+	`, a.UserID, a.Username, a.Source, a.IP, a.UserAgent, a.Success, a.FailureReason, a.Suspicious).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	return nil
+}
+
+// ListLoginHistory returns a user's newest login attempts (every user's and Xtream's when userID is 0)
+func (s *AuthStore) ListLoginHistory(ctx context.Context, userID, limit int) ([]*LoginAttempt, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, username, source, ip, user_agent, success, failure_reason, suspicious, created_at
+		FROM login_history
+		WHERE $1 = 0 OR user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login history: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*LoginAttempt
+	for rows.Next() {
+		a := &LoginAttempt{}
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Username, &a.Source, &a.IP, &a.UserAgent,
+			&a.Success, &a.FailureReason, &a.Suspicious, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan login attempt: %w", err)
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// LoginFootprint counts a user's earlier successful sign-ins, in total and from ip
+func (s *AuthStore) LoginFootprint(ctx context.Context, userID int, ip string) (total, fromIP int, err error) {
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE ip = $2)
+		FROM login_history
+		WHERE user_id = $1 AND success
+	`, userID, ip).Scan(&total, &fromIP)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read login history: %w", err)
+	}
+	return total, fromIP, nil
+}
+
+// CountFailedLogins returns the failed attempts for a username since a time
+func (s *AuthStore) CountFailedLogins(ctx context.Context, username string, since time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM login_history WHERE username = $1 AND NOT success AND created_at >= $2
+	`, username, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count failed logins: %w", err)
+	}
+	return count, nil
+}
+
+// PruneLoginHistory deletes login attempts older than the cutoff
+func (s *AuthStore) PruneLoginHistory(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM login_history WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune login history: %w", err)
+	}
+	return res.RowsAffected()
+}
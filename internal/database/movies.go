@@ -174,6 +174,29 @@ func (s *MovieStore) Get(ctx context.Context, id int64) (*models.Movie, error) {
 	return scanMovie(rows)
 }
 
+// GetByIMDBID retrieves a movie by IMDB ID
+func (s *MovieStore) GetByIMDBID(ctx context.Context, imdbID string) (*models.Movie, error) {
+	query := `
+		SELECT id, tmdb_id, title, year, monitored, available,
+			preferred_quality, metadata, added_at, last_checked, collection_id
+		FROM library_movies
+		WHERE imdb_id = $1 OR metadata->>'imdb_id' = $1
+		LIMIT 1
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, imdbID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, fmt.Errorf("movie not found")
+	}
+
+	return scanMovie(rows)
+}
+
 // GetByTMDBID retrieves a movie by TMDB ID
 func (s *MovieStore) GetByTMDBID(ctx context.Context, tmdbID int) (*models.Movie, error) {
 	query := `
@@ -231,18 +254,19 @@ func (s *MovieStore) List(ctx context.Context, offset, limit int, monitored *boo
 	return movies, nil
 }
 
-// Search searches movies by title
+// Search searches movies by title, falling back to substring matches so
+// partially typed titles still find results
 func (s *MovieStore) Search(ctx context.Context, query string, limit int) ([]*models.Movie, error) {
 	sqlQuery := `
 		SELECT id, tmdb_id, title, year, monitored, available,
 			preferred_quality, metadata, added_at, last_checked, collection_id
 		FROM library_movies
-		WHERE title_vector @@ plainto_tsquery('english', $1)
-		ORDER BY ts_rank(title_vector, plainto_tsquery('english', $1)) DESC
+		WHERE title_vector @@ plainto_tsquery('english', $1) OR title ILIKE $3 ESCAPE '\'
+		ORDER BY ts_rank(title_vector, plainto_tsquery('english', $1)) DESC, title
 		LIMIT $2
 	`
 
-	rows, err := s.db.QueryContext(ctx, sqlQuery, query, limit)
+	rows, err := s.db.QueryContext(ctx, sqlQuery, query, limit, "%"+escapeLike(query)+"%")
 	if err != nil {
 		return nil, fmt.Errorf("failed to search movies: %w", err)
 	}
@@ -260,6 +284,69 @@ func (s *MovieStore) Search(ctx context.Context, query string, limit int) ([]*mo
 	return movies, nil
 }
 
+// ListByGenre returns movies whose metadata lists the given genre
+func (s *MovieStore) ListByGenre(ctx context.Context, genre string, offset, limit int) ([]*models.Movie, error) {
+	query := `
+		SELECT id, tmdb_id, title, year, monitored, available,
+			preferred_quality, metadata, added_at, last_checked, collection_id
+		FROM library_movies
+		WHERE metadata->'genres' ? $1
+		ORDER BY added_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, genre, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list movies by genre: %w", err)
+	}
+	defer rows.Close()
+
+	movies := []*models.Movie{}
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		}
+		movies = append(movies, movie)
+	}
+
+	return movies, nil
+}
+
+// Genres returns the distinct genres of all library movies
+func (s *MovieStore) Genres(ctx context.Context) ([]string, error) {
+	return listGenres(ctx, s.db, "library_movies")
+}
+
+// listGenres collects the distinct values of metadata.genres in a library table
+func listGenres(ctx context.Context, db *sql.DB, table string) ([]string, error) {
+	query := `
+		SELECT DISTINCT genre
+		FROM ` + table + `, jsonb_array_elements_text(
+			CASE WHEN jsonb_typeof(metadata->'genres') = 'array' THEN metadata->'genres' ELSE '[]'::jsonb END
+		) AS genre
+		WHERE genre <> ''
+		ORDER BY genre
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list genres: %w", err)
+	}
+	defer rows.Close()
+
+	var genres []string
+	for rows.Next() {
+		var genre string
+		if err := rows.Scan(&genre); err != nil {
+			return nil, fmt.Errorf("failed to scan genre: %w", err)
+		}
+		genres = append(genres, genre)
+	}
+
+	return genres, rows.Err()
+}
+
 // Update updates movie settings
 func (s *MovieStore) Update(ctx context.Context, movie *models.Movie) error {
 	metadataJSON, err := json.Marshal(movie.Metadata)
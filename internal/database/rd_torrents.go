@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RDTorrent is a torrent StreamArr added to the Real-Debrid account
+type RDTorrent struct {
+	ID         int64     `json:"id"`
+	TorrentID  string    `json:"torrent_id"`
+	Hash       string    `json:"hash"`
+	Filename   string    `json:"filename"`
+	Bytes      int64     `json:"bytes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// RDTorrentStore handles rd_torrents database operations
+type RDTorrentStore struct {
+	db *sql.DB
+}
+
+// NewRDTorrentStore creates a new Real-Debrid torrent store
+func NewRDTorrentStore(db *sql.DB) *RDTorrentStore {
+	return &RDTorrentStore{db: db}
+}
+
+const rdTorrentColumns = `id, torrent_id, hash, COALESCE(filename, ''), bytes, created_at, last_used_at`
+
+func scanRDTorrent(row rowScanner) (*RDTorrent, error) {
+	t := &RDTorrent{}
+	if err := row.Scan(&t.ID, &t.TorrentID, &t.Hash, &t.Filename, &t.Bytes, &t.CreatedAt, &t.LastUsedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Track records a torrent added to the account, or refreshes it if already tracked
+func (s *RDTorrentStore) Track(ctx context.Context, torrentID, hash, filename string, bytes int64) error {
+	query := `
+		INSERT INTO rd_torrents (torrent_id, hash, filename, bytes, created_at, last_used_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (torrent_id) DO UPDATE SET
+			filename = COALESCE(NULLIF(EXCLUDED.filename, ''), rd_torrents.filename),
+			bytes = GREATEST(EXCLUDED.bytes, rd_torrents.bytes),
+			last_used_at = NOW()
+	`
+	if _, err := s.db.ExecContext(ctx, query, torrentID, strings.ToLower(hash), filename, bytes); err != nil {
+		return fmt.Errorf("failed to track torrent: %w", err)
+	}
+	return nil
+}
+
+// Touch marks a torrent as just used so garbage collection keeps it longest
+func (s *RDTorrentStore) Touch(ctx context.Context, torrentID string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE rd_torrents SET last_used_at = NOW() WHERE torrent_id = $1`, torrentID); err != nil {
+		return fmt.Errorf("failed to touch torrent: %w", err)
+	}
+	return nil
+}
+
+// FindByHash returns tracked torrents for an infohash, most recently used first
+func (s *RDTorrentStore) FindByHash(ctx context.Context, hash string) ([]*RDTorrent, error) {
+	query := `SELECT ` + rdTorrentColumns + ` FROM rd_torrents WHERE hash = $1 ORDER BY last_used_at DESC`
+	return s.list(ctx, query, strings.ToLower(hash))
+}
+
+// ListLeastRecentlyUsed returns all tracked torrents, least recently used first
+func (s *RDTorrentStore) ListLeastRecentlyUsed(ctx context.Context) ([]*RDTorrent, error) {
+	query := `SELECT ` + rdTorrentColumns + ` FROM rd_torrents ORDER BY last_used_at ASC`
+	return s.list(ctx, query)
+}
+
+// Delete stops tracking a torrent
+func (s *RDTorrentStore) Delete(ctx context.Context, torrentID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM rd_torrents WHERE torrent_id = $1`, torrentID); err != nil {
+		return fmt.Errorf("failed to delete tracked torrent: %w", err)
+	}
+	return nil
+}
+
+func (s *RDTorrentStore) list(ctx context.Context, query string, args ...interface{}) ([]*RDTorrent, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked torrents: %w", err)
+	}
+	defer rows.Close()
+
+	var torrents []*RDTorrent
+	for rows.Next() {
+		t, err := scanRDTorrent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tracked torrent: %w", err)
+		}
+		torrents = append(torrents, t)
+	}
+	return torrents, rows.Err()
+}
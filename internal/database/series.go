@@ -193,7 +193,8 @@ func (s *SeriesStore) GetByIMDBID(ctx context.Context, imdbID string) (*models.S
 		SELECT id, tmdb_id, imdb_id, title, year, monitored, clean_title,
 			metadata, added_at, last_checked, preferred_quality
 		FROM library_series
-		WHERE imdb_id = $1
+		WHERE imdb_id = $1 OR metadata->>'imdb_id' = $1
+		LIMIT 1
 	`
 
 	var series models.Series
@@ -280,6 +281,55 @@ func (s *SeriesStore) List(ctx context.Context, offset, limit int, monitored *bo
 	}
 	defer rows.Close()
 
+	return scanSeriesList(rows)
+}
+
+// Search performs full-text search on series titles, falling back to substring
+// matches so partially typed titles still find results
+func (s *SeriesStore) Search(ctx context.Context, query string, limit int) ([]*models.Series, error) {
+	searchQuery := `
+		SELECT id, tmdb_id, imdb_id, title, year, monitored, metadata, added_at, last_checked, preferred_quality
+		FROM library_series
+		WHERE title_vector @@ plainto_tsquery('english', $1) OR title ILIKE $3 ESCAPE '\'
+		ORDER BY ts_rank(title_vector, plainto_tsquery('english', $1)) DESC, title
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, searchQuery, query, limit, "%"+escapeLike(query)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to search series: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSeriesList(rows)
+}
+
+// ListByGenre returns series whose metadata lists the given genre
+func (s *SeriesStore) ListByGenre(ctx context.Context, genre string, offset, limit int) ([]*models.Series, error) {
+	query := `
+		SELECT id, tmdb_id, imdb_id, title, year, monitored, metadata, added_at, last_checked, preferred_quality
+		FROM library_series
+		WHERE metadata->'genres' ? $1
+		ORDER BY added_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, genre, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series by genre: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSeriesList(rows)
+}
+
+// Genres returns the distinct genres of all library series
+func (s *SeriesStore) Genres(ctx context.Context) ([]string, error) {
+	return listGenres(ctx, s.db, "library_series")
+}
+
+// scanSeriesList scans rows selected with the List column set
+func scanSeriesList(rows *sql.Rows) ([]*models.Series, error) {
 	var seriesList []*models.Series
 	for rows.Next() {
 		var series models.Series
@@ -332,52 +382,6 @@ func (s *SeriesStore) List(ctx context.Context, offset, limit int, monitored *bo
 	return seriesList, nil
 }
 
-// Search performs full-text search on series
-func (s *SeriesStore) Search(ctx context.Context, query string, limit int) ([]*models.Series, error) {
-	searchQuery := `
-		SELECT id, tmdb_id, title, original_title, overview, poster_path, backdrop_path,
-			first_air_date, status, seasons, total_episodes, genres, metadata,
-			monitored, quality_profile, search_status, last_checked,
-			created_at, updated_at, added_at
-		FROM library_series
-		WHERE search_vector @@ plainto_tsquery('english', $1)
-		ORDER BY ts_rank(search_vector, plainto_tsquery('english', $1)) DESC
-		LIMIT $2
-	`
-
-	rows, err := s.db.QueryContext(ctx, searchQuery, query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search series: %w", err)
-	}
-	defer rows.Close()
-
-	var seriesList []*models.Series
-	for rows.Next() {
-		var series models.Series
-		var metadataJSON []byte
-
-		err := rows.Scan(
-			&series.ID, &series.TMDBID, &series.Title, &series.OriginalTitle,
-			&series.Overview, &series.PosterPath, &series.BackdropPath,
-			&series.FirstAirDate, &series.Status, &series.Seasons, &series.TotalEpisodes,
-			&series.Genres, &metadataJSON, &series.Monitored, &series.QualityProfile,
-			&series.SearchStatus, &series.LastChecked, &series.CreatedAt,
-			&series.UpdatedAt, &series.AddedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan series: %w", err)
-		}
-
-		if err := json.Unmarshal(metadataJSON, &series.Metadata); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
-		}
-
-		seriesList = append(seriesList, &series)
-	}
-
-	return seriesList, nil
-}
-
 // Update updates an existing series
 func (s *SeriesStore) Update(ctx context.Context, series *models.Series) error {
 	metadataJSON, err := json.Marshal(series.Metadata)
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// FindIdentity returns the user linked to an OIDC account and notes the sign-in; sql.ErrNoRows when
+// the account was never linked
+func (s *AuthStore) FindIdentity(ctx context.Context, issuer, subject string) (int, error) {
+	var userID int
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE auth_identities SET last_login_at = NOW()
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`, issuer, subject).Scan(&userID)
+	return userID, err
+}
+
+// LinkIdentity links an OIDC account to a user, replacing any earlier link of that account
+func (s *AuthStore) LinkIdentity(ctx context.Context, issuer, subject string, userID int) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO auth_identities (issuer, subject, user_id, created_at, last_login_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (issuer, subject) DO UPDATE SET user_id = EXCLUDED.user_id, last_login_at = NOW()
+	`, issuer, subject, userID)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
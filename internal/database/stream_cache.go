@@ -23,22 +23,28 @@ func (s *StreamCacheStore) GetDB() *sql.DB {
 	return s.db
 }
 
-// GetCachedStream retrieves the cached stream for a movie
-// Returns nil if no cached stream exists
-func (s *StreamCacheStore) GetCachedStream(ctx context.Context, movieID int) (*models.CachedStream, error) {
-	query := `
-		SELECT id, movie_id, stream_url, stream_hash, quality_score,
+// cachedStreamColumns is the select list read by scanCachedStream
+// Movie rows have no series key and episode rows no movie key, so both are read as 0 when unset
+const cachedStreamColumns = `id, COALESCE(movie_id, 0), COALESCE(series_id, 0), COALESCE(season, 0), COALESCE(episode, 0),
+		       stream_url, stream_hash, quality_score,
 		       resolution, hdr_type, audio_format, source_type, file_size_gb,
 		       codec, indexer, cached_at, last_checked, check_count,
-		       is_available, upgrade_available, next_check_at, created_at, updated_at
-		FROM media_streams
-		WHERE movie_id = $1
-	`
-	
+		       is_available, upgrade_available, next_check_at, created_at, updated_at`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCachedStream scans a row selected with cachedStreamColumns
+func scanCachedStream(row rowScanner) (*models.CachedStream, error) {
 	cached := &models.CachedStream{}
-	err := s.db.QueryRowContext(ctx, query, movieID).Scan(
+	err := row.Scan(
 		&cached.ID,
 		&cached.MovieID,
+		&cached.SeriesID,
+		&cached.Season,
+		&cached.Episode,
 		&cached.StreamURL,
 		&cached.StreamHash,
 		&cached.QualityScore,
@@ -58,6 +64,22 @@ func (s *StreamCacheStore) GetCachedStream(ctx context.Context, movieID int) (*m
 		&cached.CreatedAt,
 		&cached.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// GetCachedStream retrieves the cached stream for a movie
+// Returns nil if no cached stream exists
+func (s *StreamCacheStore) GetCachedStream(ctx context.Context, movieID int) (*models.CachedStream, error) {
+	query := `
+		SELECT ` + cachedStreamColumns + `
+		FROM media_streams
+		WHERE movie_id = $1
+	`
+	
+	cached, err := scanCachedStream(s.db.QueryRowContext(ctx, query, movieID))
 	
 	if err == sql.ErrNoRows {
 		return nil, nil // No cached stream
@@ -184,13 +206,147 @@ func (s *StreamCacheStore) UpdateNextCheck(ctx context.Context, movieID int, day
 	return nil
 }
 
+// GetCachedEpisodeStream retrieves the cached stream for a series episode
+// Returns nil if no cached stream exists
+func (s *StreamCacheStore) GetCachedEpisodeStream(ctx context.Context, seriesID, season, episode int) (*models.CachedStream, error) {
+	query := `
+		SELECT ` + cachedStreamColumns + `
+		FROM media_streams
+		WHERE series_id = $1 AND season = $2 AND episode = $3
+	`
+	
+	cached, err := scanCachedStream(s.db.QueryRowContext(ctx, query, seriesID, season, episode))
+	if err == sql.ErrNoRows {
+		return nil, nil // No cached stream
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached episode stream: %w", err)
+	}
+	
+	return cached, nil
+}
+
+// CacheEpisodeStream stores or updates the cached stream for a series episode
+// Replaces existing stream if one exists (one stream per episode)
+func (s *StreamCacheStore) CacheEpisodeStream(ctx context.Context, seriesID, season, episode int, stream models.TorrentStream, streamURL string) error {
+	query := `
+		INSERT INTO media_streams (
+			series_id, season, episode, stream_url, stream_hash, quality_score,
+			resolution, hdr_type, audio_format, source_type, file_size_gb,
+			codec, indexer, cached_at, last_checked, check_count,
+			is_available, upgrade_available, next_check_at, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+			NOW(), NOW(), 0, true, false, NOW() + INTERVAL '7 days', NOW(), NOW()
+		)
+		ON CONFLICT (series_id, season, episode) WHERE series_id IS NOT NULL DO UPDATE SET
+			stream_url = EXCLUDED.stream_url,
+			stream_hash = EXCLUDED.stream_hash,
+			quality_score = EXCLUDED.quality_score,
+			resolution = EXCLUDED.resolution,
+			hdr_type = EXCLUDED.hdr_type,
+			audio_format = EXCLUDED.audio_format,
+			source_type = EXCLUDED.source_type,
+			file_size_gb = EXCLUDED.file_size_gb,
+			codec = EXCLUDED.codec,
+			indexer = EXCLUDED.indexer,
+			cached_at = NOW(),
+			last_checked = NOW(),
+			check_count = 0,
+			is_available = true,
+			upgrade_available = false,
+			next_check_at = NOW() + INTERVAL '7 days',
+			updated_at = NOW()
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		seriesID,
+		season,
+		episode,
+		streamURL,
+		stream.Hash,
+		stream.QualityScore,
+		stream.Resolution,
+		stream.HDRType,
+		stream.AudioFormat,
+		stream.Source,
+		stream.SizeGB,
+		stream.Codec,
+		stream.Indexer,
+	)
+	
+	if err != nil {
+		return fmt.Errorf("failed to cache episode stream: %w", err)
+	}
+	
+	return nil
+}
+
+// MarkEpisodeUnavailable marks an episode stream as unavailable (debrid cache expired)
+func (s *StreamCacheStore) MarkEpisodeUnavailable(ctx context.Context, seriesID, season, episode int) error {
+	query := `
+		UPDATE media_streams
+		SET is_available = false,
+		    last_checked = NOW(),
+		    check_count = check_count + 1,
+		    next_check_at = NOW() + INTERVAL '1 day',
+		    updated_at = NOW()
+		WHERE series_id = $1 AND season = $2 AND episode = $3
+	`
+	
+	result, err := s.db.ExecContext(ctx, query, seriesID, season, episode)
+	if err != nil {
+		return fmt.Errorf("failed to mark episode unavailable: %w", err)
+	}
+	
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("no stream found for series_id %d S%02dE%02d", seriesID, season, episode)
+	}
+	
+	return nil
+}
+
+// MarkEpisodeUpgradeAvailable marks that a better quality stream is available for an episode
+func (s *StreamCacheStore) MarkEpisodeUpgradeAvailable(ctx context.Context, seriesID, season, episode int, available bool) error {
+	query := `
+		UPDATE media_streams
+		SET upgrade_available = $1,
+		    updated_at = NOW()
+		WHERE series_id = $2 AND season = $3 AND episode = $4
+	`
+	
+	_, err := s.db.ExecContext(ctx, query, available, seriesID, season, episode)
+	if err != nil {
+		return fmt.Errorf("failed to mark episode upgrade available: %w", err)
+	}
+	
+	return nil
+}
+
+// UpdateEpisodeNextCheck schedules the next availability check for an episode stream
+func (s *StreamCacheStore) UpdateEpisodeNextCheck(ctx context.Context, seriesID, season, episode int, daysUntilCheck int) error {
+	query := `
+		UPDATE media_streams
+		SET last_checked = NOW(),
+		    check_count = check_count + 1,
+		    next_check_at = NOW() + INTERVAL '1 day' * $1,
+		    updated_at = NOW()
+		WHERE series_id = $2 AND season = $3 AND episode = $4
+	`
+	
+	_, err := s.db.ExecContext(ctx, query, daysUntilCheck, seriesID, season, episode)
+	if err != nil {
+		return fmt.Errorf("failed to update episode next check: %w", err)
+	}
+	
+	return nil
+}
+
 // GetStreamsDueForCheck retrieves streams that need availability checking
 func (s *StreamCacheStore) GetStreamsDueForCheck(ctx context.Context, limit int) ([]*models.CachedStream, error) {
 	query := `
-		SELECT id, movie_id, stream_url, stream_hash, quality_score,
-		       resolution, hdr_type, audio_format, source_type, file_size_gb,
-		       codec, indexer, cached_at, last_checked, check_count,
-		       is_available, upgrade_available, next_check_at, created_at, updated_at
+		SELECT ` + cachedStreamColumns + `
 		FROM media_streams
 		WHERE next_check_at <= NOW()
 		  AND is_available = true
@@ -206,29 +362,7 @@ func (s *StreamCacheStore) GetStreamsDueForCheck(ctx context.Context, limit int)
 	
 	var streams []*models.CachedStream
 	for rows.Next() {
-		cached := &models.CachedStream{}
-		err := rows.Scan(
-			&cached.ID,
-			&cached.MovieID,
-			&cached.StreamURL,
-			&cached.StreamHash,
-			&cached.QualityScore,
-			&cached.Resolution,
-			&cached.HDRType,
-			&cached.AudioFormat,
-			&cached.SourceType,
-			&cached.FileSizeGB,
-			&cached.Codec,
-			&cached.Indexer,
-			&cached.CachedAt,
-			&cached.LastChecked,
-			&cached.CheckCount,
-			&cached.IsAvailable,
-			&cached.UpgradeAvailable,
-			&cached.NextCheckAt,
-			&cached.CreatedAt,
-			&cached.UpdatedAt,
-		)
+		cached, err := scanCachedStream(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan stream: %w", err)
 		}
@@ -246,10 +380,7 @@ func (s *StreamCacheStore) GetStreamsDueForCheck(ctx context.Context, limit int)
 // Useful for finding upgrade candidates
 func (s *StreamCacheStore) GetStreamsByQualityScore(ctx context.Context, maxScore int, limit int) ([]*models.CachedStream, error) {
 	query := `
-		SELECT id, movie_id, stream_url, stream_hash, quality_score,
-		       resolution, hdr_type, audio_format, source_type, file_size_gb,
-		       codec, indexer, cached_at, last_checked, check_count,
-		       is_available, upgrade_available, next_check_at, created_at, updated_at
+		SELECT ` + cachedStreamColumns + `
 		FROM media_streams
 		WHERE quality_score <= $1
 		  AND is_available = true
@@ -265,29 +396,7 @@ func (s *StreamCacheStore) GetStreamsByQualityScore(ctx context.Context, maxScor
 	
 	var streams []*models.CachedStream
 	for rows.Next() {
-		cached := &models.CachedStream{}
-		err := rows.Scan(
-			&cached.ID,
-			&cached.MovieID,
-			&cached.StreamURL,
-			&cached.StreamHash,
-			&cached.QualityScore,
-			&cached.Resolution,
-			&cached.HDRType,
-			&cached.AudioFormat,
-			&cached.SourceType,
-			&cached.FileSizeGB,
-			&cached.Codec,
-			&cached.Indexer,
-			&cached.CachedAt,
-			&cached.LastChecked,
-			&cached.CheckCount,
-			&cached.IsAvailable,
-			&cached.UpgradeAvailable,
-			&cached.NextCheckAt,
-			&cached.CreatedAt,
-			&cached.UpdatedAt,
-		)
+		cached, err := scanCachedStream(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan stream: %w", err)
 		}
@@ -304,10 +413,7 @@ func (s *StreamCacheStore) GetStreamsByQualityScore(ctx context.Context, maxScor
 // GetUnavailableStreams retrieves streams marked as unavailable
 func (s *StreamCacheStore) GetUnavailableStreams(ctx context.Context, limit int) ([]*models.CachedStream, error) {
 	query := `
-		SELECT id, movie_id, stream_url, stream_hash, quality_score,
-		       resolution, hdr_type, audio_format, source_type, file_size_gb,
-		       codec, indexer, cached_at, last_checked, check_count,
-		       is_available, upgrade_available, next_check_at, created_at, updated_at
+		SELECT ` + cachedStreamColumns + `
 		FROM media_streams
 		WHERE is_available = false
 		ORDER BY last_checked ASC
@@ -322,29 +428,7 @@ func (s *StreamCacheStore) GetUnavailableStreams(ctx context.Context, limit int)
 	
 	var streams []*models.CachedStream
 	for rows.Next() {
-		cached := &models.CachedStream{}
-		err := rows.Scan(
-			&cached.ID,
-			&cached.MovieID,
-			&cached.StreamURL,
-			&cached.StreamHash,
-			&cached.QualityScore,
-			&cached.Resolution,
-			&cached.HDRType,
-			&cached.AudioFormat,
-			&cached.SourceType,
-			&cached.FileSizeGB,
-			&cached.Codec,
-			&cached.Indexer,
-			&cached.CachedAt,
-			&cached.LastChecked,
-			&cached.CheckCount,
-			&cached.IsAvailable,
-			&cached.UpgradeAvailable,
-			&cached.NextCheckAt,
-			&cached.CreatedAt,
-			&cached.UpdatedAt,
-		)
+		cached, err := scanCachedStream(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan stream: %w", err)
 		}
@@ -371,7 +455,8 @@ func (s *StreamCacheStore) GetCacheStats(ctx context.Context) (map[string]interf
 			COUNT(*) FILTER (WHERE resolution = '1080p') as count_1080p,
 			COUNT(*) FILTER (WHERE resolution = '720p') as count_720p,
 			COUNT(*) FILTER (WHERE hdr_type = 'DV') as count_dolby_vision,
-			COUNT(*) FILTER (WHERE source_type = 'REMUX') as count_remux
+			COUNT(*) FILTER (WHERE source_type = 'REMUX') as count_remux,
+			COUNT(*) FILTER (WHERE series_id IS NOT NULL) as count_episodes
 		FROM media_streams
 	`
 	
@@ -386,6 +471,7 @@ func (s *StreamCacheStore) GetCacheStats(ctx context.Context) (map[string]interf
 		Count720p          int
 		CountDolbyVision   int
 		CountRemux         int
+		CountEpisodes      int
 	}
 	
 	err := s.db.QueryRowContext(ctx, query).Scan(
@@ -399,6 +485,7 @@ func (s *StreamCacheStore) GetCacheStats(ctx context.Context) (map[string]interf
 		&stats.Count720p,
 		&stats.CountDolbyVision,
 		&stats.CountRemux,
+		&stats.CountEpisodes,
 	)
 	
 	if err != nil {
@@ -421,6 +508,7 @@ func (s *StreamCacheStore) GetCacheStats(ctx context.Context) (map[string]interf
 		"720p_streams":        stats.Count720p,
 		"dolby_vision_streams": stats.CountDolbyVision,
 		"remux_streams":       stats.CountRemux,
+		"episode_streams":     stats.CountEpisodes,
 	}, nil
 }
 
@@ -439,10 +527,7 @@ func (s *StreamCacheStore) DeleteCachedStream(ctx context.Context, movieID int)
 // GetStreamsWithUpgradesAvailable retrieves streams that have upgrades available
 func (s *StreamCacheStore) GetStreamsWithUpgradesAvailable(ctx context.Context, limit int) ([]*models.CachedStream, error) {
 	query := `
-		SELECT id, movie_id, stream_url, stream_hash, quality_score,
-		       resolution, hdr_type, audio_format, source_type, file_size_gb,
-		       codec, indexer, cached_at, last_checked, check_count,
-		       is_available, upgrade_available, next_check_at, created_at, updated_at
+		SELECT ` + cachedStreamColumns + `
 		FROM media_streams
 		WHERE upgrade_available = true
 		  AND is_available = true
@@ -458,29 +543,7 @@ func (s *StreamCacheStore) GetStreamsWithUpgradesAvailable(ctx context.Context,
 	
 	var streams []*models.CachedStream
 	for rows.Next() {
-		cached := &models.CachedStream{}
-		err := rows.Scan(
-			&cached.ID,
-			&cached.MovieID,
-			&cached.StreamURL,
-			&cached.StreamHash,
-			&cached.QualityScore,
-			&cached.Resolution,
-			&cached.HDRType,
-			&cached.AudioFormat,
-			&cached.SourceType,
-			&cached.FileSizeGB,
-			&cached.Codec,
-			&cached.Indexer,
-			&cached.CachedAt,
-			&cached.LastChecked,
-			&cached.CheckCount,
-			&cached.IsAvailable,
-			&cached.UpgradeAvailable,
-			&cached.NextCheckAt,
-			&cached.CreatedAt,
-			&cached.UpdatedAt,
-		)
+		cached, err := scanCachedStream(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan stream: %w", err)
 		}
@@ -0,0 +1,268 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StremioToken is a per-user Stremio addon token
+type StremioToken struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"username"`
+	Token      string     `json:"-"` // Only set when the token is created; the database stores its hash
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// StremioPreferences controls how streams are ranked for a user's addon
+type StremioPreferences struct {
+	MaxResolution      int      `json:"max_resolution"`      // Drop streams above this height (0 = no limit)
+	MinResolution      int      `json:"min_resolution"`      // Drop streams below this height (0 = no limit)
+	MaxSizeGB          float64  `json:"max_size_gb"`         // Drop larger files (0 = no limit)
+	PreferCached       bool     `json:"prefer_cached"`       // Debrid-cached streams first
+	PreferredLanguages []string `json:"preferred_languages"` // Streams mentioning these rank higher (e.g. "multi", "german")
+	ExcludeKeywords    []string `json:"exclude_keywords"`    // Drop streams whose title contains any of these (e.g. "cam", "hdts")
+	MaxStreams         int      `json:"max_streams"`         // Return at most this many streams (0 = all)
+}
+
+// StremioLibraryEntry is a library item on a user's watchlist or continue-watching list
+type StremioLibraryEntry struct {
+	MediaType     string     `json:"media_type"`
+	MediaID       int64      `json:"media_id"`
+	InWatchlist   bool       `json:"in_watchlist"`
+	AddedAt       *time.Time `json:"added_at,omitempty"`
+	LastWatchedAt *time.Time `json:"last_watched_at,omitempty"`
+	Season        int        `json:"season"`
+	Episode       int        `json:"episode"`
+}
+
+// StremioUserStore handles per-user Stremio addon tokens, preferences and catalogs
+type StremioUserStore struct {
+	db *sql.DB
+}
+
+// NewStremioUserStore creates a new Stremio user store
+func NewStremioUserStore(db *sql.DB) *StremioUserStore {
+	return &StremioUserStore{db: db}
+}
+
+const stremioTokenColumns = `t.id, t.user_id, u.username, t.prefix, t.name, t.created_at, t.last_used_at, t.revoked_at`
+
+func scanStremioToken(row rowScanner) (*StremioToken, error) {
+	t := &StremioToken{}
+	if err := row.Scan(&t.ID, &t.UserID, &t.Username, &t.Prefix, &t.Name, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// hashStremioToken is what's stored for a token: its SHA-256, like API keys
+func hashStremioToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken issues a token for a user. The token is only stored as a hash, so the returned
+// StremioToken is the one time it can be shown.
+func (s *StremioUserStore) CreateToken(ctx context.Context, userID int, token, name string) (*StremioToken, error) {
+	prefix := token
+	if len(prefix) > 8 {
+		prefix = prefix[:8]
+	}
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO stremio_tokens (user_id, token_hash, prefix, name, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id
+	`, userID, hashStremioToken(token), prefix, name).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stremio token: %w", err)
+	}
+	created, err := s.GetToken(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	created.Token = token
+	return created, nil
+}
+
+// GetToken returns a token by ID
+func (s *StremioUserStore) GetToken(ctx context.Context, id int64) (*StremioToken, error) {
+	query := `SELECT ` + stremioTokenColumns + ` FROM stremio_tokens t JOIN users u ON u.user_id = t.user_id WHERE t.id = $1`
+	t, err := scanStremioToken(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stremio token: %w", err)
+	}
+	return t, nil
+}
+
+// Authenticate resolves an unrevoked token and records its use
+func (s *StremioUserStore) Authenticate(ctx context.Context, token string) (*StremioToken, error) {
+	query := `
+		UPDATE stremio_tokens t SET last_used_at = NOW()
+		FROM users u
+		WHERE u.user_id = t.user_id AND t.token_hash = $1 AND t.revoked_at IS NULL
+		RETURNING ` + stremioTokenColumns
+	t, err := scanStremioToken(s.db.QueryRowContext(ctx, query, hashStremioToken(token)))
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListTokens returns a user's tokens (all users when userID is 0), newest first
+func (s *StremioUserStore) ListTokens(ctx context.Context, userID int) ([]*StremioToken, error) {
+	query := `SELECT ` + stremioTokenColumns + ` FROM stremio_tokens t JOIN users u ON u.user_id = t.user_id
+		WHERE $1 = 0 OR t.user_id = $1 ORDER BY t.created_at DESC`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stremio tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*StremioToken
+	for rows.Next() {
+		t, err := scanStremioToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stremio token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken revokes a token; installed addons using it stop working immediately
+func (s *StremioUserStore) RevokeToken(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE stremio_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke stremio token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPreferences returns a user's stream ranking preferences (zero value when unset)
+func (s *StremioUserStore) GetPreferences(ctx context.Context, userID int) (StremioPreferences, error) {
+	var prefs StremioPreferences
+	var raw []byte
+	err := s.db.QueryRowContext(ctx, `SELECT preferences FROM stremio_user_preferences WHERE user_id = $1`, userID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, fmt.Errorf("failed to get stremio preferences: %w", err)
+	}
+	if err := json.Unmarshal(raw, &prefs); err != nil {
+		return prefs, fmt.Errorf("failed to decode stremio preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// SavePreferences stores a user's stream ranking preferences
+func (s *StremioUserStore) SavePreferences(ctx context.Context, userID int, prefs StremioPreferences) error {
+	raw, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO stremio_user_preferences (user_id, preferences, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET preferences = EXCLUDED.preferences, updated_at = NOW()
+	`, userID, raw)
+	if err != nil {
+		return fmt.Errorf("failed to save stremio preferences: %w", err)
+	}
+	return nil
+}
+
+// SetWatchlist adds or removes a library item on a user's watchlist
+func (s *StremioUserStore) SetWatchlist(ctx context.Context, userID int, mediaType string, mediaID int64, on bool) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO stremio_user_library (user_id, media_type, media_id, in_watchlist, added_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $4 THEN NOW() END)
+		ON CONFLICT (user_id, media_type, media_id) DO UPDATE SET
+			in_watchlist = EXCLUDED.in_watchlist,
+			added_at = CASE WHEN EXCLUDED.in_watchlist THEN COALESCE(stremio_user_library.added_at, NOW()) END
+	`, userID, mediaType, mediaID, on)
+	if err != nil {
+		return fmt.Errorf("failed to update watchlist: %w", err)
+	}
+	return nil
+}
+
+// RecordWatch marks a library item (and episode for series) as just played by a user
+func (s *StremioUserStore) RecordWatch(ctx context.Context, userID int, mediaType string, mediaID int64, season, episode int) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO stremio_user_library (user_id, media_type, media_id, last_watched_at, season, episode)
+		VALUES ($1, $2, $3, NOW(), $4, $5)
+		ON CONFLICT (user_id, media_type, media_id) DO UPDATE SET
+			last_watched_at = NOW(), season = EXCLUDED.season, episode = EXCLUDED.episode
+	`, userID, mediaType, mediaID, season, episode)
+	if err != nil {
+		return fmt.Errorf("failed to record watch: %w", err)
+	}
+	return nil
+}
+
+// ClearWatch removes a library item from a user's continue-watching list
+func (s *StremioUserStore) ClearWatch(ctx context.Context, userID int, mediaType string, mediaID int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE stremio_user_library SET last_watched_at = NULL
+		WHERE user_id = $1 AND media_type = $2 AND media_id = $3
+	`, userID, mediaType, mediaID)
+	if err != nil {
+		return fmt.Errorf("failed to clear watch: %w", err)
+	}
+	return nil
+}
+
+// Watchlist returns a user's watchlist for a media type, most recently added first
+func (s *StremioUserStore) Watchlist(ctx context.Context, userID int, mediaType string, offset, limit int) ([]*StremioLibraryEntry, error) {
+	return s.listEntries(ctx, `
+		SELECT media_type, media_id, in_watchlist, added_at, last_watched_at, season, episode
+		FROM stremio_user_library
+		WHERE user_id = $1 AND media_type = $2 AND in_watchlist
+		ORDER BY added_at DESC
+		OFFSET $3 LIMIT $4
+	`, userID, mediaType, offset, limit)
+}
+
+// ContinueWatching returns what a user played most recently for a media type
+func (s *StremioUserStore) ContinueWatching(ctx context.Context, userID int, mediaType string, offset, limit int) ([]*StremioLibraryEntry, error) {
+	return s.listEntries(ctx, `
+		SELECT media_type, media_id, in_watchlist, added_at, last_watched_at, season, episode
+		FROM stremio_user_library
+		WHERE user_id = $1 AND media_type = $2 AND last_watched_at IS NOT NULL
+		ORDER BY last_watched_at DESC
+		OFFSET $3 LIMIT $4
+	`, userID, mediaType, offset, limit)
+}
+
+func (s *StremioUserStore) listEntries(ctx context.Context, query string, args ...interface{}) ([]*StremioLibraryEntry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user library: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*StremioLibraryEntry
+	for rows.Next() {
+		e := &StremioLibraryEntry{}
+		if err := rows.Scan(&e.MediaType, &e.MediaID, &e.InWatchlist, &e.AddedAt, &e.LastWatchedAt, &e.Season, &e.Episode); err != nil {
+			return nil, fmt.Errorf("failed to scan user library entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SubtitleStore caches subtitle search results so repeat lookups don't spend API quota
+type SubtitleStore struct {
+	db *sql.DB
+}
+
+// NewSubtitleStore creates a new subtitle store
+func NewSubtitleStore(db *sql.DB) *SubtitleStore {
+	return &SubtitleStore{db: db}
+}
+
+// GetResults decodes cached results for a key into dest; false when missing or older than maxAge
+func (s *SubtitleStore) GetResults(ctx context.Context, key string, maxAge time.Duration, dest interface{}) (bool, error) {
+	var raw []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT results FROM subtitle_search_cache
+		WHERE cache_key = $1 AND fetched_at > NOW() - make_interval(secs => $2)
+	`, key, maxAge.Seconds()).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get subtitle results: %w", err)
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return false, fmt.Errorf("failed to decode subtitle results: %w", err)
+	}
+	return true, nil
+}
+
+// SaveResults stores search results for a key
+func (s *SubtitleStore) SaveResults(ctx context.Context, key string, results interface{}) error {
+	raw, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO subtitle_search_cache (cache_key, results, fetched_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (cache_key) DO UPDATE SET results = EXCLUDED.results, fetched_at = NOW()
+	`, key, raw)
+	if err != nil {
+		return fmt.Errorf("failed to save subtitle results: %w", err)
+	}
+	return nil
+}
@@ -87,7 +87,7 @@ func (s *UserStore) initTables() error {
 			username VARCHAR(255) UNIQUE NOT NULL,
 			email VARCHAR(255) UNIQUE NOT NULL,
 			password_hash VARCHAR(255) NOT NULL,
-			role VARCHAR(50) DEFAULT 'user',
+			role VARCHAR(50) DEFAULT 'requester',
 			status VARCHAR(50) DEFAULT 'active',
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			last_active TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -202,6 +202,34 @@ func (s *UserStore) GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// GetRole returns a user's current role
+func (s *UserStore) GetRole(userID int) (string, error) {
+	var role sql.NullString
+	err := s.db.QueryRow(`SELECT role FROM users WHERE user_id = $1`, userID).Scan(&role)
+	if err != nil {
+		return "", err
+	}
+	return role.String, nil
+}
+
+// GetAuthState returns a user's current role and when all their sessions were last revoked
+func (s *UserStore) GetAuthState(userID int) (string, *time.Time, error) {
+	var role sql.NullString
+	var revokedAt *time.Time
+	err := s.db.QueryRow(`SELECT role, sessions_revoked_at FROM users WHERE user_id = $1`, userID).Scan(&role, &revokedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return role.String, revokedAt, nil
+}
+
+// CountUsersWithRole returns how many users have a role
+func (s *UserStore) CountUsersWithRole(role string) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = $1`, role).Scan(&count)
+	return count, err
+}
+
 // GetAllUsers retrieves all users with statistics
 func (s *UserStore) GetAllUsers() ([]map[string]interface{}, error) {
 	rows, err := s.db.Query(`
@@ -258,9 +286,19 @@ func (s *UserStore) GetAllUsers() ([]map[string]interface{}, error) {
 	return users, nil
 }
 
+// GetSubtitleLanguages returns a user's comma-separated subtitle languages (empty when unset)
+func (s *UserStore) GetSubtitleLanguages(userID int) (string, error) {
+	var langs sql.NullString
+	err := s.db.QueryRow(`SELECT subtitle_languages FROM users WHERE user_id = $1`, userID).Scan(&langs)
+	if err != nil {
+		return "", err
+	}
+	return langs.String, nil
+}
+
 // UpdateUser updates user fields
 func (s *UserStore) UpdateUser(userID int, updates map[string]interface{}) error {
-	allowedFields := []string{"username", "email", "role", "password_hash", "profile_picture"}
+	allowedFields := []string{"username", "email", "role", "password_hash", "profile_picture", "subtitle_languages"}
 	
 	query := "UPDATE users SET "
 	args := []interface{}{}
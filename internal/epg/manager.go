@@ -6,6 +6,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"sort"
 	"strings"
@@ -128,7 +129,7 @@ func (e *Manager) UpdateEPG(channels []livetv.Channel) error {
 		}
 	}
 
-	fmt.Printf("EPG: Loaded programs for %d channels\n", len(e.programs))
+	log.Printf("EPG: Loaded programs for %d channels", len(e.programs))
 	e.lastUpdate = time.Now()
 	return nil
 }
@@ -144,7 +145,7 @@ func (e *Manager) Clear() {
 	defer e.mu.Unlock()
 	e.programs = make(map[string][]livetv.EPGProgram)
 	e.lastUpdate = time.Time{}
-	fmt.Println("EPG: Cache cleared")
+	log.Println("EPG: Cache cleared")
 }
 
 // GenerateXMLTV generates XMLTV format EPG
@@ -275,7 +276,7 @@ func (e *Manager) AddCustomEPGURLs(urls []string) {
 			for _, url := range urls {
 				if url != "" {
 					xmltvSource.AddCustomURL(url)
-					fmt.Printf("EPG: Added custom EPG URL: %s\n", url)
+					log.Printf("EPG: Added custom EPG URL: %s", url)
 				}
 			}
 			return
@@ -290,10 +291,10 @@ func (x *XMLTVSource) Name() string {
 // BulkLoadEPG loads all EPG data from all URLs into the programs map
 func (x *XMLTVSource) BulkLoadEPG(programs map[string][]livetv.EPGProgram) {
 	for _, url := range x.urls {
-		fmt.Printf("EPG: Loading from %s\n", url)
+		log.Printf("EPG: Loading from %s", url)
 		resp, err := x.client.Get(url)
 		if err != nil {
-			fmt.Printf("EPG: Error fetching %s: %v\n", url, err)
+			log.Printf("EPG: Error fetching %s: %v", url, err)
 			continue
 		}
 
@@ -303,7 +304,7 @@ func (x *XMLTVSource) BulkLoadEPG(programs map[string][]livetv.EPGProgram) {
 		if strings.HasSuffix(url, ".gz") {
 			gzReader, err := gzip.NewReader(resp.Body)
 			if err != nil {
-				fmt.Printf("EPG: Error decompressing %s: %v\n", url, err)
+				log.Printf("EPG: Error decompressing %s: %v", url, err)
 				resp.Body.Close()
 				continue
 			}
@@ -314,13 +315,13 @@ func (x *XMLTVSource) BulkLoadEPG(programs map[string][]livetv.EPGProgram) {
 
 		body, err := io.ReadAll(reader)
 		if err != nil {
-			fmt.Printf("EPG: Error reading %s: %v\n", url, err)
+			log.Printf("EPG: Error reading %s: %v", url, err)
 			continue
 		}
 
 		var tv XMLTV
 		if err := xml.Unmarshal(body, &tv); err != nil {
-			fmt.Printf("EPG: Error parsing %s: %v\n", url, err)
+			log.Printf("EPG: Error parsing %s: %v", url, err)
 			continue
 		}
 
@@ -359,7 +360,7 @@ func (x *XMLTVSource) BulkLoadEPG(programs map[string][]livetv.EPGProgram) {
 			programs[prog.Channel] = append(programs[prog.Channel], program)
 			programCount++
 		}
-		fmt.Printf("EPG: Loaded %d programs for %d channels from %s\n", programCount, channelCount, url)
+		log.Printf("EPG: Loaded %d programs for %d channels from %s", programCount, channelCount, url)
 	}
 }
 
@@ -0,0 +1,354 @@
+package hdhomerun
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/epg"
+	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+	"github.com/gorilla/mux"
+)
+
+// Tuner is a virtual HDHomeRun device exposing a subset of the live channels
+type Tuner struct {
+	ID         string   // URL path segment: /hdhr/{id}/discover.json
+	Name       string   // Friendly name shown in Plex/Jellyfin
+	Categories []string // Channel categories on this tuner (empty = all enabled categories)
+	TunerCount int      // Concurrent streams allowed
+}
+
+// Config is read on every request so settings changes apply without a restart
+type Config struct {
+	Tuners            []Tuner
+	EnabledSources    []string // Live TV sources enabled in settings (empty = all)
+	EnabledCategories []string // Live TV categories enabled in settings (empty = all)
+	AdvertiseURL      string   // Base URL announced over SSDP, e.g. http://192.168.1.10:8080 (default: detected LAN address)
+	Port              int      // Server port used with the detected LAN address
+}
+
+// Server emulates HDHomeRun network tuners so Plex and Jellyfin Live TV can use StreamArr's channels
+type Server struct {
+	channels  *livetv.ChannelManager
+	epg       *epg.Manager
+	getConfig func() Config
+	relay     http.Handler // Live TV proxy serving a channel given ?id=
+
+	mu     sync.Mutex
+	active map[string]int // tuner ID -> streams in use
+}
+
+// lineupEntry is one channel in lineup.json
+type lineupEntry struct {
+	GuideNumber string `json:"GuideNumber"`
+	GuideName   string `json:"GuideName"`
+	URL         string `json:"URL"`
+	HD          int    `json:"HD,omitempty"`
+}
+
+// NewServer creates the HDHomeRun emulator
+func NewServer(channels *livetv.ChannelManager, epgManager *epg.Manager, getConfig func() Config) *Server {
+	return &Server{
+		channels:  channels,
+		epg:       epgManager,
+		getConfig: getConfig,
+		active:    make(map[string]int),
+	}
+}
+
+// SetRelay sets the live TV proxy tuner streams are served through
+func (s *Server) SetRelay(relay http.Handler) {
+	s.relay = relay
+}
+
+// RegisterRoutes adds the device endpoints; must be registered before the Xtream
+// /{username}/{password}/{id}.{ext} route, which would otherwise match them
+func (s *Server) RegisterRoutes(r *mux.Router) {
+	d := r.PathPrefix("/hdhr/{tuner}").Subrouter()
+	d.HandleFunc("/discover.json", s.handleDiscover).Methods("GET")
+	d.HandleFunc("/lineup.json", s.handleLineup).Methods("GET")
+	d.HandleFunc("/lineup_status.json", s.handleLineupStatus).Methods("GET")
+	d.HandleFunc("/lineup.post", s.handleLineupPost).Methods("GET", "POST")
+	d.HandleFunc("/device.xml", s.handleDeviceXML).Methods("GET")
+	d.HandleFunc("/guide.xml", s.handleGuide).Methods("GET")
+	d.HandleFunc("/auto/v{num}", s.handleStream).Methods("GET", "HEAD")
+}
+
+// tuner returns the configured tuner for the request
+func (s *Server) tuner(r *http.Request) (Tuner, bool) {
+	id := mux.Vars(r)["tuner"]
+	for _, t := range s.getConfig().Tuners {
+		if strings.EqualFold(t.ID, id) {
+			if t.TunerCount <= 0 {
+				t.TunerCount = 2
+			}
+			if t.Name == "" {
+				t.Name = "StreamArr " + t.ID
+			}
+			return t, true
+		}
+	}
+	return Tuner{}, false
+}
+
+// lineup returns the tuner's channels; guide numbers are 1-based positions in this list,
+// the same positional scheme Xtream live stream IDs use
+func (s *Server) lineup(t Tuner) []*livetv.Channel {
+	if s.channels == nil {
+		return nil
+	}
+	cfg := s.getConfig()
+	sources := toSet(cfg.EnabledSources)
+	categories := toSet(cfg.EnabledCategories)
+	tunerCategories := toSet(t.Categories)
+
+	all := s.channels.GetAllChannels()
+	channels := make([]*livetv.Channel, 0, len(all))
+	for _, ch := range all {
+		if len(sources) > 0 && !sources[ch.Source] {
+			continue
+		}
+		if len(categories) > 0 && !categories[ch.Category] {
+			continue
+		}
+		if len(tunerCategories) > 0 && !tunerCategories[ch.Category] {
+			continue
+		}
+		channels = append(channels, ch)
+	}
+	return channels
+}
+
+func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tuner(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	base := baseURL(r, t)
+	writeJSON(w, map[string]interface{}{
+		"FriendlyName":    t.Name,
+		"Manufacturer":    "Silicondust",
+		"ModelNumber":     "HDTC-2US",
+		"FirmwareName":    "hdhomeruntc_atsc",
+		"FirmwareVersion": "20200101",
+		"DeviceID":        DeviceID(t.ID),
+		"DeviceAuth":      "streamarr",
+		"BaseURL":         base,
+		"LineupURL":       base + "/lineup.json",
+		"TunerCount":      t.TunerCount,
+	})
+}
+
+func (s *Server) handleLineup(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tuner(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	base := baseURL(r, t)
+	channels := s.lineup(t)
+	lineup := make([]lineupEntry, 0, len(channels))
+	for i, ch := range channels {
+		entry := lineupEntry{
+			GuideNumber: fmt.Sprint(i + 1),
+			GuideName:   ch.Name,
+			URL:         fmt.Sprintf("%s/auto/v%d", base, i+1),
+		}
+		if strings.Contains(strings.ToUpper(ch.Name), "HD") {
+			entry.HD = 1
+		}
+		lineup = append(lineup, entry)
+	}
+	writeJSON(w, lineup)
+}
+
+func (s *Server) handleLineupStatus(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.tuner(r); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"ScanInProgress": 0,
+		"ScanPossible":   1,
+		"Source":         "Cable",
+		"SourceList":     []string{"Cable"},
+	})
+}
+
+// handleLineupPost accepts channel scan requests; the lineup is always current so there is nothing to scan
+func (s *Server) handleLineupPost(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.tuner(r); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// UPnP device description fetched from the SSDP LOCATION
+type deviceDescription struct {
+	XMLName     xml.Name `xml:"urn:schemas-upnp-org:device-1-0 root"`
+	SpecVersion struct {
+		Major int `xml:"major"`
+		Minor int `xml:"minor"`
+	} `xml:"specVersion"`
+	URLBase string `xml:"URLBase"`
+	Device  struct {
+		DeviceType   string `xml:"deviceType"`
+		FriendlyName string `xml:"friendlyName"`
+		Manufacturer string `xml:"manufacturer"`
+		ModelName    string `xml:"modelName"`
+		ModelNumber  string `xml:"modelNumber"`
+		SerialNumber string `xml:"serialNumber"`
+		UDN          string `xml:"UDN"`
+	} `xml:"device"`
+}
+
+func (s *Server) handleDeviceXML(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tuner(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var desc deviceDescription
+	desc.SpecVersion.Major = 1
+	desc.URLBase = baseURL(r, t)
+	desc.Device.DeviceType = deviceType
+	desc.Device.FriendlyName = t.Name
+	desc.Device.Manufacturer = "Silicondust"
+	desc.Device.ModelName = "HDTC-2US"
+	desc.Device.ModelNumber = "HDTC-2US"
+	desc.Device.SerialNumber = DeviceID(t.ID)
+	desc.Device.UDN = "uuid:" + deviceUUID(t.ID)
+
+	output, err := xml.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	w.Write(output)
+}
+
+// handleGuide serves XMLTV for the tuner's channels. Each channel also gets its guide number
+// as a display-name, which is how Plex and Jellyfin map guide channels to lineup entries.
+func (s *Server) handleGuide(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tuner(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if s.epg == nil {
+		http.Error(w, "EPG not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	channels := s.lineup(t)
+	channelList := make([]livetv.Channel, len(channels))
+	numbers := make(map[string]string, len(channels))
+	for i, ch := range channels {
+		channelList[i] = *ch
+		numbers[ch.ID] = fmt.Sprint(i + 1)
+	}
+
+	generated, err := s.epg.GenerateXMLTV(channelList)
+	if err != nil {
+		log.Printf("[HDHR] Error generating XMLTV: %v", err)
+		http.Error(w, "failed to generate guide", http.StatusInternalServerError)
+		return
+	}
+
+	var tv epg.XMLTV
+	if err := xml.Unmarshal([]byte(generated), &tv); err != nil {
+		log.Printf("[HDHR] Error reading XMLTV: %v", err)
+		http.Error(w, "failed to generate guide", http.StatusInternalServerError)
+		return
+	}
+	for i := range tv.Channels {
+		if number, ok := numbers[tv.Channels[i].ID]; ok {
+			tv.Channels[i].DisplayNames = append(tv.Channels[i].DisplayNames, epg.DisplayName{Value: number})
+		}
+	}
+	programs := tv.Programs[:0]
+	for _, p := range tv.Programs {
+		if _, ok := numbers[p.Channel]; ok {
+			programs = append(programs, p)
+		}
+	}
+	tv.Programs = programs
+
+	output, err := xml.MarshalIndent(tv, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	w.Write(output)
+}
+
+// DeviceID derives a stable 8-hex-digit HDHomeRun device ID from the tuner ID.
+// The last digit is chosen so the ID passes the HDHomeRun checksum clients validate.
+func DeviceID(tunerID string) string {
+	sum := sha1.Sum([]byte("streamarr-hdhr:" + tunerID))
+	id := binary.BigEndian.Uint32(sum[:4]) &^ 0x0F
+	id |= uint32(deviceIDChecksum(id))
+	return fmt.Sprintf("%08X", id)
+}
+
+var checksumLookup = [16]uint32{0xA, 0x5, 0xF, 0x6, 0x7, 0xC, 0x1, 0xB, 0x9, 0x2, 0x8, 0xD, 0x4, 0x3, 0xE, 0x0}
+
+// deviceIDChecksum returns the low nibble that makes the ID's checksum zero
+func deviceIDChecksum(id uint32) uint32 {
+	var checksum uint32
+	checksum ^= checksumLookup[(id>>28)&0x0F]
+	checksum ^= (id >> 24) & 0x0F
+	checksum ^= checksumLookup[(id>>20)&0x0F]
+	checksum ^= (id >> 16) & 0x0F
+	checksum ^= checksumLookup[(id>>12)&0x0F]
+	checksum ^= (id >> 8) & 0x0F
+	checksum ^= checksumLookup[(id>>4)&0x0F]
+	return checksum
+}
+
+// deviceUUID is the UPnP UDN; it starts with the device ID so both identify the same tuner
+func deviceUUID(tunerID string) string {
+	sum := sha1.Sum([]byte("streamarr-hdhr-udn:" + tunerID))
+	return fmt.Sprintf("%s-%x-%x-%x-%x", strings.ToLower(DeviceID(tunerID)), sum[0:2], sum[2:4], sum[4:6], sum[6:12])
+}
+
+// baseURL is where clients reach this tuner, taken from the request so it matches the
+// address the user added in Plex/Jellyfin (or the proxy in front of StreamArr). The forwarding
+// headers are only read from trusted proxies, or anyone on the network could rewrite the lineup.
+func baseURL(r *http.Request, t Tuner) string {
+	scheme := "http"
+	if r.TLS != nil || (r.Header.Get("X-Forwarded-Proto") == "https" && auth.FromTrustedProxy(r)) {
+		scheme = "https"
+	}
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" && auth.FromTrustedProxy(r) {
+		host = forwarded
+	}
+	return fmt.Sprintf("%s://%s/hdhr/%s", scheme, host, t.ID)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
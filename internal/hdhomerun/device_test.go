@@ -0,0 +1,184 @@
+package hdhomerun
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+	"github.com/gorilla/mux"
+)
+
+// validDeviceID is the check HDHomeRun clients run on a device ID
+func validDeviceID(deviceID string) bool {
+	id, err := strconv.ParseUint(deviceID, 16, 32)
+	if err != nil || len(deviceID) != 8 {
+		return false
+	}
+	var checksum uint64
+	for shift := 28; shift >= 0; shift -= 4 {
+		nibble := (id >> shift) & 0x0F
+		if shift%8 == 4 {
+			nibble = uint64(checksumLookup[nibble])
+		}
+		checksum ^= nibble
+	}
+	return checksum == 0
+}
+
+func TestDeviceID(t *testing.T) {
+	seen := map[string]string{}
+	for _, tuner := range []string{"main", "sports", "kids", "news", "1", "a-much-longer-tuner-id"} {
+		id := DeviceID(tuner)
+		if !validDeviceID(id) {
+			t.Errorf("DeviceID(%q) = %s fails the HDHomeRun checksum", tuner, id)
+		}
+		n, _ := strconv.ParseUint(id, 16, 32)
+		if tampered := fmt.Sprintf("%08X", n^1); validDeviceID(tampered) {
+			t.Errorf("checksum accepts %s as well as %s", tampered, id)
+		}
+		if DeviceID(tuner) != id {
+			t.Errorf("DeviceID(%q) isn't stable", tuner)
+		}
+		if other, ok := seen[id]; ok {
+			t.Errorf("tuners %q and %q share device ID %s", tuner, other, id)
+		}
+		seen[id] = tuner
+		if !strings.HasPrefix(deviceUUID(tuner), strings.ToLower(id)+"-") {
+			t.Errorf("deviceUUID(%q) = %s doesn't start with the device ID", tuner, deviceUUID(tuner))
+		}
+	}
+}
+
+// newTestServer serves the channels of an M3U playlist on a tuner limited to news
+func newTestServer(t *testing.T) *Server {
+	playlist := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n"+
+			`#EXTINF:-1 tvg-id="bbc" group-title="News",BBC News HD`+"\nhttp://iptv.example/user/pass/1.ts\n"+
+			`#EXTINF:-1 tvg-id="cnn" group-title="News",CNN`+"\nhttp://iptv.example/user/pass/2.m3u8\n"+
+			`#EXTINF:-1 tvg-id="espn" group-title="Sports",ESPN`+"\nhttp://iptv.example/user/pass/3.ts\n")
+	}))
+	t.Cleanup(playlist.Close)
+
+	channels := livetv.NewChannelManager()
+	channels.SetIncludeLiveTV(true)
+	channels.SetM3USources([]livetv.M3USource{{Name: "test", URL: playlist.URL, Enabled: true}})
+	if err := channels.LoadChannels(); err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(channels, nil, func() Config {
+		return Config{
+			Tuners:       []Tuner{{ID: "news", Name: "News", Categories: []string{"News"}}},
+			AdvertiseURL: "192.168.1.10:8080",
+		}
+	})
+}
+
+func TestLineup(t *testing.T) {
+	s := newTestServer(t)
+	router := mux.NewRouter()
+	s.RegisterRoutes(router)
+
+	lineup := func(trusted bool) []lineupEntry {
+		r := httptest.NewRequest(http.MethodGet, "http://192.168.1.10:8080/hdhr/news/lineup.json", nil)
+		r.Header.Set("X-Forwarded-Host", "attacker.example")
+		r.Header.Set("X-Forwarded-Proto", "https")
+		if trusted {
+			r = r.WithContext(auth.WithTrustedProxy(r.Context()))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		var entries []lineupEntry
+		if err := json.NewDecoder(w.Body).Decode(&entries); err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	want := []lineupEntry{
+		{GuideNumber: "1", GuideName: "BBC News HD", URL: "http://192.168.1.10:8080/hdhr/news/auto/v1", HD: 1},
+		{GuideNumber: "2", GuideName: "CNN", URL: "http://192.168.1.10:8080/hdhr/news/auto/v2"},
+	}
+	got := lineup(false)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("lineup = %+v, want %+v", got, want)
+	}
+	// Only a trusted proxy gets to say where clients reach the tuner
+	if got := lineup(true); len(got) != 2 || got[0].URL != "https://attacker.example/hdhr/news/auto/v1" {
+		t.Errorf("lineup behind a trusted proxy = %+v", got)
+	}
+}
+
+func TestStreamNeverRevealsUpstream(t *testing.T) {
+	s := newTestServer(t)
+	var relayed string
+	s.SetRelay(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		relayed = r.URL.Query().Get("id")
+	}))
+	ffmpegOnce.Do(func() {}) // Act as if ffmpeg isn't installed
+	router := mux.NewRouter()
+	s.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hdhr/news/auto/v1", nil))
+	if relayed != "bbc" {
+		t.Errorf("relayed channel %q, want bbc through the live TV proxy", relayed)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hdhr/news/auto/v2", nil))
+	if w.Code != http.StatusServiceUnavailable || strings.Contains(w.Body.String()+w.Header().Get("Location"), "iptv.example") {
+		t.Errorf("HLS channel without ffmpeg = %d %q, want an error that hides the upstream", w.Code, w.Body.String())
+	}
+}
+
+func TestSSDPReply(t *testing.T) {
+	s := newTestServer(t)
+	client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("no loopback UDP: %v", err)
+	}
+	defer client.Close()
+	to := client.LocalAddr().(*net.UDPAddr)
+
+	read := func() string {
+		buf := make([]byte, 2048)
+		client.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := client.ReadFromUDP(buf)
+		if err != nil {
+			return ""
+		}
+		return string(buf[:n])
+	}
+
+	s.respond(to, "ssdp:all")
+	reply := read()
+	usn := "uuid:" + deviceUUID("news")
+	for _, line := range []string{
+		"HTTP/1.1 200 OK\r\n",
+		"LOCATION: http://192.168.1.10:8080/hdhr/news/device.xml\r\n",
+		"ST: upnp:rootdevice\r\n",
+		"USN: " + usn + "::upnp:rootdevice\r\n",
+	} {
+		if !strings.Contains(reply, line) {
+			t.Errorf("reply lacks %q:\n%s", line, reply)
+		}
+	}
+
+	s.respond(to, deviceType)
+	if reply := read(); !strings.Contains(reply, "ST: "+deviceType+"\r\n") {
+		t.Errorf("reply to a device type search:\n%s", reply)
+	}
+
+	s.respond(to, "urn:schemas-upnp-org:device:Printer:1")
+	if reply := read(); reply != "" {
+		t.Errorf("answered a search for another device:\n%s", reply)
+	}
+}
@@ -0,0 +1,160 @@
+package hdhomerun
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	deviceType    = "urn:schemas-upnp-org:device:MediaServer:1"
+	ssdpAddr      = "239.255.255.250:1900"
+	ssdpMaxAge    = 1800
+	notifyEvery   = 15 * time.Minute
+	ssdpServerTag = "StreamArr/1.0 UPnP/1.0 HDHomeRun/1.0"
+)
+
+// RunSSDP answers SSDP M-SEARCH requests and announces every tuner until ctx is done,
+// so Plex and Jellyfin find the tuners without entering their address by hand
+func (s *Server) RunSSDP(ctx context.Context) error {
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("failed to join SSDP multicast group: %w", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go s.announce(ctx, group)
+
+	log.Printf("[HDHR] SSDP discovery listening on %s", ssdpAddr)
+
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" || req.Header.Get("Man") != `"ssdp:discover"` {
+			continue
+		}
+		s.respond(from, req.Header.Get("St"))
+	}
+}
+
+// respond sends a unicast search response for each tuner matching the search target
+func (s *Server) respond(to *net.UDPAddr, st string) {
+	base := s.advertiseURL(to)
+	if base == "" {
+		return
+	}
+
+	conn, err := net.DialUDP("udp4", nil, to)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for _, t := range s.getConfig().Tuners {
+		usn := "uuid:" + deviceUUID(t.ID)
+		var target string
+		switch st {
+		case "ssdp:all", "upnp:rootdevice":
+			target = "upnp:rootdevice"
+		case deviceType, usn:
+			target = st
+		default:
+			continue
+		}
+
+		fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\n"+
+			"CACHE-CONTROL: max-age=%d\r\n"+
+			"EXT:\r\n"+
+			"LOCATION: %s/hdhr/%s/device.xml\r\n"+
+			"SERVER: %s\r\n"+
+			"ST: %s\r\n"+
+			"USN: %s::%s\r\n\r\n",
+			ssdpMaxAge, base, t.ID, ssdpServerTag, target, usn, target)
+	}
+}
+
+// announce multicasts ssdp:alive for every tuner on start and periodically after
+func (s *Server) announce(ctx context.Context, group *net.UDPAddr) {
+	ticker := time.NewTicker(notifyEvery)
+	defer ticker.Stop()
+	for {
+		s.notify(group)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) notify(group *net.UDPAddr) {
+	base := s.advertiseURL(group)
+	if base == "" {
+		return
+	}
+	conn, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for _, t := range s.getConfig().Tuners {
+		usn := "uuid:" + deviceUUID(t.ID)
+		for _, nt := range []string{"upnp:rootdevice", deviceType} {
+			fmt.Fprintf(conn, "NOTIFY * HTTP/1.1\r\n"+
+				"HOST: %s\r\n"+
+				"CACHE-CONTROL: max-age=%d\r\n"+
+				"LOCATION: %s/hdhr/%s/device.xml\r\n"+
+				"NT: %s\r\n"+
+				"NTS: ssdp:alive\r\n"+
+				"SERVER: %s\r\n"+
+				"USN: %s::%s\r\n\r\n",
+				ssdpAddr, ssdpMaxAge, base, t.ID, nt, ssdpServerTag, usn, nt)
+		}
+	}
+}
+
+// advertiseURL is the configured advertise URL, or this host's address on the interface
+// that reaches the client combined with the server port
+func (s *Server) advertiseURL(client *net.UDPAddr) string {
+	cfg := s.getConfig()
+	if cfg.AdvertiseURL != "" {
+		if !strings.Contains(cfg.AdvertiseURL, "://") {
+			return "http://" + strings.TrimRight(cfg.AdvertiseURL, "/")
+		}
+		return strings.TrimRight(cfg.AdvertiseURL, "/")
+	}
+
+	// Connecting a UDP socket sends nothing but picks the outgoing interface
+	probe, err := net.DialUDP("udp4", nil, client)
+	if err != nil {
+		return ""
+	}
+	defer probe.Close()
+	local := probe.LocalAddr().(*net.UDPAddr)
+	if local.IP.IsUnspecified() {
+		return ""
+	}
+	return fmt.Sprintf("http://%s:%d", local.IP, cfg.Port)
+}
@@ -0,0 +1,156 @@
+package hdhomerun
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	ffmpegOnce sync.Once
+	ffmpegPath string
+)
+
+// handleStream relays a channel as the continuous MPEG-TS stream a tuner would deliver.
+// HLS sources are remuxed with ffmpeg, so they need it installed; other sources go through the
+// live TV proxy. Clients never see the upstream URL, which carries the provider's credentials.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tuner(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	num, err := strconv.Atoi(mux.Vars(r)["num"])
+	channels := s.lineup(t)
+	if err != nil || num < 1 || num > len(channels) {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+	channel := channels[num-1]
+	if channel.StreamURL == "" {
+		http.Error(w, "Stream URL not available", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", "video/mp2t")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !s.acquire(t) {
+		log.Printf("[HDHR] %s: all %d tuners in use, rejecting %s", t.Name, t.TunerCount, channel.Name)
+		w.Header().Set("X-HDHomeRun-Error", "805 All Tuners In Use")
+		http.Error(w, "All tuners in use", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.release(t)
+
+	// Live streams run far longer than the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	log.Printf("[HDHR] %s: streaming %s (v%d) to %s", t.Name, channel.Name, num, r.RemoteAddr)
+	start := time.Now()
+
+	if isHLS(channel.StreamURL) {
+		ffmpegOnce.Do(func() {
+			ffmpegPath, _ = exec.LookPath("ffmpeg")
+		})
+		if ffmpegPath == "" {
+			log.Printf("[HDHR] ⚠️ %s: %s is an HLS stream; install ffmpeg on the StreamArr host to tune it", t.Name, channel.Name)
+			http.Error(w, "HLS channels need ffmpeg on the server", http.StatusServiceUnavailable)
+			return
+		}
+		err = s.remux(r.Context(), w, channel.StreamURL)
+	} else {
+		if s.relay == nil {
+			http.Error(w, "Live TV proxy not available", http.StatusServiceUnavailable)
+			return
+		}
+		relayed := r.Clone(r.Context())
+		relayed.URL.RawQuery = url.Values{"id": {channel.ID}}.Encode()
+		s.relay.ServeHTTP(w, relayed)
+	}
+
+	if err != nil && r.Context().Err() == nil {
+		log.Printf("[HDHR] %s: stream %s ended: %v", t.Name, channel.Name, err)
+	}
+	log.Printf("[HDHR] %s: stopped %s after %v", t.Name, channel.Name, time.Since(start).Round(time.Second))
+}
+
+// remux turns an HLS playlist into a single MPEG-TS stream without re-encoding
+func (s *Server) remux(ctx context.Context, w http.ResponseWriter, streamURL string) error {
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-user_agent", "Mozilla/5.0",
+		"-i", streamURL,
+		"-map", "0", "-c", "copy",
+		"-f", "mpegts", "pipe:1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		http.Error(w, "failed to start remux", http.StatusInternalServerError)
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		http.Error(w, "failed to start remux", http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.WriteHeader(http.StatusOK)
+	_, copyErr := io.Copy(flushWriter{w}, stdout)
+	waitErr := cmd.Wait()
+	if copyErr != nil {
+		return copyErr
+	}
+	return waitErr
+}
+
+func (s *Server) acquire(t Tuner) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[t.ID] >= t.TunerCount {
+		return false
+	}
+	s.active[t.ID]++
+	return true
+}
+
+func (s *Server) release(t Tuner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[t.ID]--; s.active[t.ID] <= 0 {
+		delete(s.active, t.ID)
+	}
+}
+
+func isHLS(streamURL string) bool {
+	path := strings.ToLower(streamURL)
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return strings.HasSuffix(path, ".m3u8") || strings.HasSuffix(path, ".m3u")
+}
+
+// flushWriter pushes each chunk to the client immediately so players start without buffering delays
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
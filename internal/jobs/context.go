@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+)
+
+// maxLogSize caps the log kept per run; older lines are dropped first
+const maxLogSize = 64 << 10
+
+type runKey struct{}
+
+// runState is the in-progress state of a run, shared between the job and its heartbeat
+type runState struct {
+	mu        sync.Mutex
+	run       *database.JobRun
+	cancel    context.CancelFunc
+	cancelled bool // Cancellation was requested through the API
+	leaseLost bool // Another process took the job over
+}
+
+func (s *runState) snapshot() database.JobRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.run
+}
+
+func fromContext(ctx context.Context) *runState {
+	state, _ := ctx.Value(runKey{}).(*runState)
+	return state
+}
+
+// Progress records how far the current run is; it does nothing outside a job
+func Progress(ctx context.Context, processed, total int, message string) {
+	state := fromContext(ctx)
+	if state == nil {
+		return
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.run.ItemsProcessed = processed
+	state.run.ItemsTotal = total
+	state.run.ProgressMessage = message
+	if total > 0 {
+		state.run.Progress = processed * 100 / total
+	}
+}
+
+// Logf writes to the process log and, inside a job, to the run's log
+func Logf(ctx context.Context, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Print(msg)
+
+	state := fromContext(ctx)
+	if state == nil {
+		return
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	logs := state.run.Logs + time.Now().Format("15:04:05") + " " + msg + "\n"
+	if len(logs) > maxLogSize {
+		logs = logs[len(logs)-maxLogSize:]
+		if i := strings.IndexByte(logs, '\n'); i >= 0 {
+			logs = logs[i+1:]
+		}
+	}
+	state.run.Logs = logs
+}
+
+// IsManual reports whether the current run was triggered by a user rather than the schedule
+func IsManual(ctx context.Context) bool {
+	state := fromContext(ctx)
+	return state != nil && state.run.Trigger == database.JobTriggerManual
+}
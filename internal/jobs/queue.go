@@ -0,0 +1,402 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
+	"github.com/Zerr0-C00L/StreamArr/internal/tracing"
+)
+
+// RunFunc does one run of a job; it should return soon after ctx is cancelled
+type RunFunc func(ctx context.Context) error
+
+// Job is a background job a process can run
+type Job struct {
+	Name        string
+	Description string
+	Schedule    string // Default schedule; edits made through the API are kept in the database
+	Run         RunFunc
+}
+
+const (
+	pollInterval      = 5 * time.Second
+	heartbeatInterval = 10 * time.Second
+	leaseDuration     = time.Minute
+	maxConcurrent     = 4
+)
+
+// ErrUnknownJob is returned for jobs that were never registered
+var ErrUnknownJob = errors.New("unknown job")
+
+// Queue runs jobs from the shared Postgres queue. Every process (server and worker) runs its own
+// Queue for the jobs it can execute; leases guarantee a job runs in one process at a time.
+type Queue struct {
+	store  *database.JobStore
+	worker string
+
+	mu      sync.Mutex
+	jobs    map[string]Job
+	running map[string]*runState
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewQueue creates a queue; worker identifies this process in leases and run history
+func NewQueue(store *database.JobStore, worker string) *Queue {
+	return &Queue{
+		store:   store,
+		worker:  worker,
+		jobs:    make(map[string]Job),
+		running: make(map[string]*runState),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// WorkerID returns an identifier for this process, e.g. "server@host:1234"
+func WorkerID(role string) string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s:%d", role, host, os.Getpid())
+}
+
+// Register adds a job this process can run; call before Start
+func (q *Queue) Register(job Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[job.Name] = job
+}
+
+// Start registers the jobs in the database and runs due and queued jobs until ctx is cancelled
+func (q *Queue) Start(ctx context.Context) error {
+	for _, job := range q.registered() {
+		if _, err := ParseSchedule(job.Schedule); err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		if err := q.store.EnsureJob(ctx, job.Name, job.Description, job.Schedule); err != nil {
+			return err
+		}
+	}
+	go q.loop(ctx)
+	log.Printf("[JOBS] Queue started as %s (%d jobs)", q.worker, len(q.jobs))
+	return nil
+}
+
+// Wait blocks until running jobs have stopped and recorded their result, or until ctx is done.
+// Cancel the context passed to Start first.
+func (q *Queue) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// Trigger queues a manual run; it starts in whichever process claims it first
+func (q *Queue) Trigger(ctx context.Context, name string) (*database.JobRun, error) {
+	run, err := q.store.Enqueue(ctx, name)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownJob
+	}
+	if err != nil {
+		return nil, err
+	}
+	q.poke()
+	return run, nil
+}
+
+// Cancel cancels a queued run or stops a running one. A run in another process stops at its next heartbeat.
+func (q *Queue) Cancel(ctx context.Context, name string) error {
+	if err := q.store.RequestCancel(ctx, name); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	state := q.running[name]
+	q.mu.Unlock()
+	if state != nil {
+		state.mu.Lock()
+		state.cancelled = true
+		state.mu.Unlock()
+		state.cancel()
+	}
+	return nil
+}
+
+// Jobs returns every job known to the database with its latest run; runs executing in this process
+// include their live progress rather than the last heartbeat's
+func (q *Queue) Jobs(ctx context.Context) ([]*database.Job, error) {
+	list, err := q.store.ListJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, job := range list {
+		state := q.running[job.Name]
+		if state == nil || job.LastRun == nil {
+			continue
+		}
+		if live := state.snapshot(); live.ID == job.LastRun.ID {
+			live.Status = job.LastRun.Status
+			live.Logs = ""
+			job.LastRun = &live
+		}
+	}
+	return list, nil
+}
+
+// Update enables/disables a job and/or changes its schedule; a new schedule takes effect from now
+func (q *Queue) Update(ctx context.Context, name string, enabled *bool, schedule *string) error {
+	var nextRun *time.Time
+	if schedule != nil {
+		sched, err := ParseSchedule(*schedule)
+		if err != nil {
+			return err
+		}
+		next := sched.Next(time.Now())
+		nextRun = &next
+	}
+	err := q.store.UpdateJob(ctx, name, enabled, schedule, nextRun)
+	if err == sql.ErrNoRows {
+		return ErrUnknownJob
+	}
+	if err == nil {
+		q.poke()
+	}
+	return err
+}
+
+// Runs returns a job's run history, newest first
+func (q *Queue) Runs(ctx context.Context, name string, limit int) ([]*database.JobRun, error) {
+	return q.store.ListRuns(ctx, name, limit)
+}
+
+// Run returns a single run with its logs; a run executing in this process includes its live progress
+func (q *Queue) Run(ctx context.Context, id int64) (*database.JobRun, error) {
+	run, err := q.store.GetRun(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	state := q.running[run.JobName]
+	q.mu.Unlock()
+	if state != nil {
+		if live := state.snapshot(); live.ID == run.ID {
+			live.Status = run.Status
+			return &live, nil
+		}
+	}
+	return run, nil
+}
+
+func (q *Queue) registered() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+func (q *Queue) poke() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) loop(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		q.claimDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// claimDue starts due jobs until none are left or the concurrency limit is reached
+func (q *Queue) claimDue(ctx context.Context) {
+	for {
+		q.mu.Lock()
+		if len(q.running) >= maxConcurrent {
+			q.mu.Unlock()
+			return
+		}
+		var names []string
+		for name := range q.jobs {
+			if q.running[name] == nil {
+				names = append(names, name)
+			}
+		}
+		q.mu.Unlock()
+
+		claimed, run, err := q.store.Claim(ctx, names, q.worker, leaseDuration)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[JOBS] ⚠️ Claim failed: %v", err)
+			}
+			return
+		}
+		if claimed == nil {
+			return
+		}
+		q.start(ctx, claimed, run)
+	}
+}
+
+func (q *Queue) start(ctx context.Context, claimed *database.Job, run *database.JobRun) {
+	q.mu.Lock()
+	job := q.jobs[claimed.Name]
+	runCtx, cancel := context.WithCancel(ctx)
+	state := &runState{run: run, cancel: cancel}
+	q.running[job.Name] = state
+	q.wg.Add(1)
+	q.mu.Unlock()
+
+	go q.execute(ctx, context.WithValue(runCtx, runKey{}, state), job, claimed.Schedule, state)
+}
+
+// execute runs a claimed job, keeps its lease alive and records the outcome
+func (q *Queue) execute(ctx, runCtx context.Context, job Job, schedule string, state *runState) {
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.Name)
+		q.mu.Unlock()
+		q.poke()
+		q.wg.Done()
+	}()
+
+	log.Printf("[JOBS] ▶️ %s started (%s run #%d)", job.Name, state.run.Trigger, state.run.ID)
+	done := make(chan struct{})
+	go q.heartbeat(ctx, state, done)
+
+	// Each run is the root of a trace, so its provider lookups and debrid calls are grouped under it
+	runCtx, span := tracing.Start(runCtx, "job "+job.Name, tracing.KindInternal)
+	span.Set("job.trigger", state.run.Trigger)
+	span.Set("job.run_id", state.run.ID)
+	defer span.End()
+
+	err := runSafely(runCtx, job.Run)
+	span.Fail(err)
+	close(done)
+	state.cancel()
+
+	state.mu.Lock()
+	run := state.run
+	shutdown := ctx.Err() != nil
+	now := time.Now()
+	nextRun := nextScheduled(schedule, job.Schedule, now)
+	switch {
+	case state.leaseLost:
+		state.mu.Unlock()
+		log.Printf("[JOBS] ⚠️ %s lost its lease; another process owns it now", job.Name)
+		return
+	case state.cancelled:
+		run.Status = database.JobCancelled
+		run.Error = "cancelled by user"
+	case shutdown:
+		// Interrupted runs are picked up again as soon as a process is available
+		run.Status = database.JobCancelled
+		run.Error = "interrupted by shutdown"
+		nextRun = now
+	case err != nil:
+		run.Status = database.JobFailed
+		run.Error = err.Error()
+	default:
+		run.Status = database.JobSucceeded
+	}
+	if run.Status == database.JobSucceeded && run.ItemsTotal > 0 {
+		run.Progress = 100
+	}
+	final := *run
+	state.mu.Unlock()
+	span.Set("job.status", string(final.Status))
+	metrics.JobDuration.WithLabelValues(job.Name, string(final.Status)).Observe(metrics.Since(*final.StartedAt))
+
+	finishCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := q.store.Finish(finishCtx, &final, q.worker, nextRun); err != nil {
+		log.Printf("[JOBS] ❌ Cannot record result of %s: %v", job.Name, err)
+		return
+	}
+	if final.Status == database.JobFailed {
+		log.Printf("[JOBS] ❌ %s failed: %s", job.Name, final.Error)
+	} else {
+		log.Printf("[JOBS] ⏹️ %s %s in %v", job.Name, final.Status, time.Since(*final.StartedAt).Round(time.Second))
+	}
+}
+
+// heartbeat renews the lease, saves progress and stops the run when cancelled elsewhere
+func (q *Queue) heartbeat(ctx context.Context, state *runState, done <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		snapshot := state.snapshot()
+		cancelRequested, err := q.store.Heartbeat(ctx, &snapshot, q.worker, leaseDuration)
+		switch {
+		case errors.Is(err, database.ErrLeaseLost):
+			state.mu.Lock()
+			state.leaseLost = true
+			state.mu.Unlock()
+			state.cancel()
+			return
+		case err != nil:
+			if ctx.Err() == nil {
+				log.Printf("[JOBS] ⚠️ Heartbeat for %s failed: %v", snapshot.JobName, err)
+			}
+		case cancelRequested:
+			state.mu.Lock()
+			state.cancelled = true
+			state.mu.Unlock()
+			state.cancel()
+		}
+	}
+}
+
+func runSafely(ctx context.Context, run RunFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if run == nil {
+		return nil
+	}
+	return run(ctx)
+}
+
+// nextScheduled returns the next run time for the stored schedule, falling back to the job's default
+func nextScheduled(stored, fallback string, now time.Time) time.Time {
+	for _, spec := range []string{stored, fallback} {
+		if sched, err := ParseSchedule(spec); err == nil {
+			return sched.Next(now)
+		}
+	}
+	return now.Add(time.Hour)
+}
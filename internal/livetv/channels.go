@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"sort"
@@ -261,14 +262,14 @@ func (cm *ChannelManager) LoadChannels() error {
 	// This flag should be set from settings.IncludeLiveTV
 	if !cm.isLiveTVEnabled() {
 		cm.channels = make(map[string]*Channel)
-		fmt.Println("Live TV: Disabled, no channels loaded")
+		log.Println("Live TV: Disabled, no channels loaded")
 		return nil
 	}
 
 	// If IPTV import mode is VOD-only, do not load Live TV channels
 	if strings.EqualFold(cm.iptvImportMode, "vod_only") {
 		cm.channels = make(map[string]*Channel)
-		fmt.Println("Live TV: VOD-only mode; no live channels loaded")
+		log.Println("Live TV: VOD-only mode; no live channels loaded")
 		return nil
 	}
 
@@ -281,14 +282,14 @@ func (cm *ChannelManager) LoadChannels() error {
 		if !source.Enabled {
 			continue
 		}
-		fmt.Printf("[DEBUG] Loading %s with selected categories: %v (count: %d)\n", source.Name, source.SelectedCategories, len(source.SelectedCategories))
+		log.Printf("[DEBUG] Loading %s with selected categories: %v (count: %d)", source.Name, source.SelectedCategories, len(source.SelectedCategories))
 		channels, err := cm.loadFromM3UURLWithCategories(source.URL, source.Name, source.SelectedCategories)
 		if err != nil {
-			fmt.Printf("Error loading channels from %s: %v\n", source.Name, err)
+			log.Printf("Error loading channels from %s: %v", source.Name, err)
 			continue
 		}
 		allChannels = append(allChannels, channels...)
-		fmt.Printf("Loaded %d channels from %s\n", len(channels), source.Name)
+		log.Printf("Loaded %d channels from %s", len(channels), source.Name)
 	}
 
 	// Load from custom Xtream sources (user-configured)
@@ -298,18 +299,18 @@ func (cm *ChannelManager) LoadChannels() error {
 		}
 		channels, err := cm.loadFromXtreamSource(source)
 		if err != nil {
-			fmt.Printf("Error loading channels from Xtream %s: %v\n", source.Name, err)
+			log.Printf("Error loading channels from Xtream %s: %v", source.Name, err)
 			continue
 		}
 		allChannels = append(allChannels, channels...)
-		fmt.Printf("Loaded %d channels from Xtream %s\n", len(channels), source.Name)
+		log.Printf("Loaded %d channels from Xtream %s", len(channels), source.Name)
 	}
 
 	// Check if we have any channels at all
 	if len(allChannels) == 0 {
-		fmt.Println("Live TV: No channels loaded")
+		log.Println("Live TV: No channels loaded")
 		if len(cm.m3uSources) == 0 && len(cm.xtreamSources) == 0 {
-			fmt.Println("Add Custom M3U/Xtream Sources in Settings → Live TV")
+			log.Println("Add Custom M3U/Xtream Sources in Settings → Live TV")
 		}
 		cm.channels = make(map[string]*Channel)
 		return nil
@@ -346,8 +347,8 @@ func (cm *ChannelManager) LoadChannels() error {
 	for _, ch := range cm.channels {
 		categoryCount[ch.Category]++
 	}
-	fmt.Printf("Live TV: Loaded %d unique channels (merged from %d total)\n", len(cm.channels), len(allChannels))
-	fmt.Printf("[DEBUG] Channel count per category: %v\n", categoryCount)
+	log.Printf("Live TV: Loaded %d unique channels (merged from %d total)", len(cm.channels), len(allChannels))
+	log.Printf("[DEBUG] Channel count per category: %v", categoryCount)
 	
 	// Debug: Find which sources have uncategorized channels
 	uncategorizedBySource := make(map[string]int)
@@ -357,7 +358,7 @@ func (cm *ChannelManager) LoadChannels() error {
 		}
 	}
 	if len(uncategorizedBySource) > 0 {
-		fmt.Printf("[DEBUG] Uncategorized channels by source: %v\n", uncategorizedBySource)
+		log.Printf("[DEBUG] Uncategorized channels by source: %v", uncategorizedBySource)
 	}
 	
 	return nil
@@ -1090,7 +1091,7 @@ func (cm *ChannelManager) parseM3UWithCategories(content string, sourceName stri
 	channels := make([]*Channel, 0)
 	scanner := bufio.NewScanner(strings.NewReader(content))
 	
-	fmt.Printf("[DEBUG] parseM3UWithCategories: sourceName=%s, selectedCategories=%v, count=%d\n", sourceName, selectedCategories, len(selectedCategories))
+	log.Printf("[DEBUG] parseM3UWithCategories: sourceName=%s, selectedCategories=%v, count=%d", sourceName, selectedCategories, len(selectedCategories))
 	
 	var currentChannel *Channel
 	var currentIsVOD bool
@@ -1221,7 +1222,7 @@ func (cm *ChannelManager) parseM3UWithCategories(content string, sourceName stri
 		channels = cm.validateChannelsConcurrent(channels, 100) // 100 concurrent validations
 		totalFiltered := totalParsed - len(channels)
 		if totalFiltered > 0 {
-			fmt.Printf("Filtered %d broken channels from %s (%d valid out of %d total)\n", 
+			log.Printf("Filtered %d broken channels from %s (%d valid out of %d total)", 
 				totalFiltered, sourceName, len(channels), totalParsed)
 		}
 	}
@@ -1343,7 +1344,7 @@ func (cm *ChannelManager) parseM3UWithProviders(content string) ([]*Channel, err
 		channels = cm.validateChannelsConcurrent(channels, 100) // 100 concurrent validations
 		totalFiltered := totalParsed - len(channels)
 		if totalFiltered > 0 {
-			fmt.Printf("Filtered %d broken channels from local M3U (%d valid out of %d total)\n", 
+			log.Printf("Filtered %d broken channels from local M3U (%d valid out of %d total)", 
 				totalFiltered, len(channels), totalParsed)
 		}
 	}
@@ -1530,6 +1531,18 @@ func mapChannelToCategory(channelName string) string {
 	return "General"
 }
 
+// CountBySource returns the number of loaded channels per source
+func (cm *ChannelManager) CountBySource() map[string]int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, ch := range cm.channels {
+		counts[ch.Source]++
+	}
+	return counts
+}
+
 func (cm *ChannelManager) GetAllChannels() []*Channel {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
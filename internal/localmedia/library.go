@@ -0,0 +1,450 @@
+package localmedia
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
+	"github.com/Zerr0-C00L/StreamArr/internal/services"
+)
+
+// Library scans configured directories, matches files to library movies and
+// episodes and serves them over HTTP
+type Library struct {
+	store       *database.LocalMediaStore
+	movieStore  *database.MovieStore
+	seriesStore *database.SeriesStore
+	tmdb        *services.TMDBClient
+	getPaths    func() []string
+	secret      []byte
+
+	// TMDB lookups are cached per title so a season folder costs one search
+	matchMu    sync.Mutex
+	matchCache map[string]int64
+	scanMu     sync.Mutex
+}
+
+// ScanResult summarises a directory scan
+type ScanResult struct {
+	Files     int `json:"files"`
+	Matched   int `json:"matched"`
+	Unmatched int `json:"unmatched"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+}
+
+// NewLibrary creates a local media library
+// secret signs the stream URLs handed to clients so file IDs cannot be enumerated
+func NewLibrary(store *database.LocalMediaStore, movieStore *database.MovieStore, seriesStore *database.SeriesStore, tmdb *services.TMDBClient, getPaths func() []string, secret string) *Library {
+	return &Library{
+		store:       store,
+		movieStore:  movieStore,
+		seriesStore: seriesStore,
+		tmdb:        tmdb,
+		getPaths:    getPaths,
+		secret:      []byte(secret),
+		matchCache:  make(map[string]int64),
+	}
+}
+
+// Paths returns the configured directories that currently exist
+func (l *Library) Paths() []string {
+	var paths []string
+	for _, p := range l.getPaths() {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if info, err := os.Stat(p); err != nil || !info.IsDir() {
+			log.Printf("[LOCAL] ⚠️ Skipping media path %s: not a readable directory", p)
+			continue
+		}
+		paths = append(paths, filepath.Clean(p))
+	}
+	return paths
+}
+
+// Scan walks every configured directory, upserting new or changed files and
+// removing files that have disappeared
+func (l *Library) Scan(ctx context.Context) (ScanResult, error) {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	var result ScanResult
+
+	known, err := l.store.ListModified(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	roots := l.Paths()
+	seen := make(map[string]bool)
+
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				log.Printf("[LOCAL] ⚠️ Cannot read %s: %v", path, err)
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if d.IsDir() || !isVideoFile(path) {
+				return nil
+			}
+
+			result.Files++
+			seen[path] = true
+
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if mod, ok := known[path]; ok && mod.Equal(info.ModTime().UTC().Truncate(time.Microsecond)) {
+				result.Unchanged++
+				return nil
+			}
+
+			if l.indexFile(ctx, root, path, info) {
+				result.Matched++
+			} else {
+				result.Unmatched++
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// Drop files under scanned roots that no longer exist
+	for path := range known {
+		if seen[path] || !underAny(path, roots) {
+			continue
+		}
+		if err := l.store.DeleteByPath(ctx, path); err == nil {
+			result.Removed++
+		}
+	}
+
+	log.Printf("[LOCAL] Scan complete: %d files, %d matched, %d unmatched, %d unchanged, %d removed",
+		result.Files, result.Matched, result.Unmatched, result.Unchanged, result.Removed)
+	return result, nil
+}
+
+// ProcessPath handles a single created, changed or removed path reported by the watcher
+func (l *Library) ProcessPath(ctx context.Context, path string) {
+	root := rootFor(path, l.Paths())
+	if root == "" {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if n, err := l.store.DeleteUnder(ctx, path); err == nil && n > 0 {
+				log.Printf("[LOCAL] Removed %d file(s) under %s", n, path)
+			}
+		}
+		return
+	}
+
+	if !info.IsDir() {
+		if isVideoFile(path) {
+			l.indexFile(ctx, root, path, info)
+		}
+		return
+	}
+
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isVideoFile(p) {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			l.indexFile(ctx, root, p, fi)
+		}
+		return nil
+	})
+}
+
+// indexFile matches one file to the library and stores it; returns false if unmatched
+func (l *Library) indexFile(ctx context.Context, root, path string, info fs.FileInfo) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+
+	file := &database.LocalMediaFile{
+		Path:       path,
+		SizeBytes:  info.Size(),
+		Container:  strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."),
+		ModifiedAt: info.ModTime().UTC().Truncate(time.Microsecond),
+	}
+
+	name := release.Parse(filepath.Base(path))
+	file.Resolution = name.Resolution
+	file.Codec = name.Codec
+	file.SourceType = name.Source
+	file.HDRType = name.HDR
+	file.AudioFormat = name.Audio
+	probeFile(ctx, file)
+
+	if season, episode, ok := episodeForPath(rel); ok {
+		title, year := seriesTitleForPath(rel)
+		seriesID := l.matchSeries(ctx, title, year)
+		if seriesID == 0 {
+			log.Printf("[LOCAL] No library series for %s (title %q)", rel, title)
+			return false
+		}
+		file.MediaType = "series"
+		file.SeriesID = &seriesID
+		file.SeasonNumber = season
+		file.EpisodeNumber = episode
+	} else {
+		title, year := movieTitleForPath(rel)
+		movieID := l.matchMovie(ctx, title, year)
+		if movieID == 0 {
+			log.Printf("[LOCAL] No library movie for %s (title %q, year %d)", rel, title, year)
+			return false
+		}
+		file.MediaType = "movie"
+		file.MovieID = &movieID
+	}
+
+	if err := l.store.Upsert(ctx, file); err != nil {
+		log.Printf("[LOCAL] ❌ %v", err)
+		return false
+	}
+	return true
+}
+
+// matchMovie resolves a parsed title to a library movie ID via TMDB search
+func (l *Library) matchMovie(ctx context.Context, title string, year int) int64 {
+	if title == "" || l.tmdb == nil {
+		return 0
+	}
+	key := fmt.Sprintf("movie|%s|%d", strings.ToLower(title), year)
+	if id, ok := l.cachedMatch(key); ok {
+		return id
+	}
+
+	var id int64
+	results, err := l.tmdb.SearchMovies(ctx, title, 1)
+	if err == nil && len(results) > 0 {
+		// Take the first result released in the parsed year, else the top result
+		best := results[0]
+		for _, movie := range results {
+			if year > 0 && movie.Year == year {
+				best = movie
+				break
+			}
+		}
+		if existing, err := l.movieStore.GetByTMDBID(ctx, best.TMDBID); err == nil {
+			id = existing.ID
+		}
+	}
+
+	l.storeMatch(key, id)
+	return id
+}
+
+// matchSeries resolves a parsed title to a library series ID via TMDB search
+func (l *Library) matchSeries(ctx context.Context, title string, year int) int64 {
+	if title == "" || l.tmdb == nil {
+		return 0
+	}
+	key := fmt.Sprintf("series|%s|%d", strings.ToLower(title), year)
+	if id, ok := l.cachedMatch(key); ok {
+		return id
+	}
+
+	var id int64
+	results, err := l.tmdb.SearchSeries(ctx, title, 1)
+	if err == nil {
+		for _, series := range results {
+			if year > 0 && series.Year != 0 && series.Year != year {
+				continue
+			}
+			if existing, err := l.seriesStore.GetByTMDBID(ctx, series.TMDBID); err == nil {
+				id = existing.ID
+				break
+			}
+		}
+	}
+
+	l.storeMatch(key, id)
+	return id
+}
+
+func (l *Library) cachedMatch(key string) (int64, bool) {
+	l.matchMu.Lock()
+	defer l.matchMu.Unlock()
+	id, ok := l.matchCache[key]
+	return id, ok
+}
+
+func (l *Library) storeMatch(key string, id int64) {
+	l.matchMu.Lock()
+	defer l.matchMu.Unlock()
+	l.matchCache[key] = id
+}
+
+// ResetMatches clears cached TMDB lookups so newly added library items are picked up
+func (l *Library) ResetMatches() {
+	l.matchMu.Lock()
+	defer l.matchMu.Unlock()
+	l.matchCache = make(map[string]int64)
+}
+
+// MovieFiles returns the local files for a movie, largest first
+func (l *Library) MovieFiles(ctx context.Context, imdbID string) ([]*database.LocalMediaFile, error) {
+	return l.store.FindMovie(ctx, imdbID)
+}
+
+// EpisodeFiles returns the local files for an episode, largest first
+func (l *Library) EpisodeFiles(ctx context.Context, imdbID string, season, episode int) ([]*database.LocalMediaFile, error) {
+	return l.store.FindEpisode(ctx, imdbID, season, episode)
+}
+
+// StreamPath returns the signed, server-relative URL path for a file
+func (l *Library) StreamPath(file *database.LocalMediaFile) string {
+	ext := file.Container
+	if ext == "" {
+		ext = "mkv"
+	}
+	return fmt.Sprintf("/local/%d/%s.%s", file.ID, l.sign(file.ID), ext)
+}
+
+// Open validates a signed file reference and returns the file record
+func (l *Library) Open(ctx context.Context, idStr, signature string) (*database.LocalMediaFile, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file id")
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(id))) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	return l.store.Get(ctx, id)
+}
+
+// Serve streams a file with HTTP range support
+func (l *Library) Serve(w http.ResponseWriter, r *http.Request, file *database.LocalMediaFile) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		log.Printf("[LOCAL] ❌ Cannot open %s: %v", file.Path, err)
+		http.Error(w, "File not available", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.Error(w, "File not available", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	// ServeContent handles Range, If-Range and HEAD requests
+	http.ServeContent(w, r, filepath.Base(file.Path), info.ModTime(), f)
+}
+
+func (l *Library) sign(id int64) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "local-media:%d", id)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// episodeForPath returns the season/episode of a file, using parent folders for a missing season
+func episodeForPath(rel string) (int, int, bool) {
+	rel = filepath.ToSlash(rel)
+	base := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	info := release.Parse(base)
+
+	seasons := info.Seasons
+	episodes := info.Episodes
+	dir := filepath.Dir(rel)
+	for len(seasons) == 0 && dir != "." && dir != "/" {
+		seasons = release.Parse(filepath.Base(dir)).Seasons
+		dir = filepath.Dir(dir)
+	}
+	if len(episodes) == 0 && len(seasons) == 1 {
+		if n, ok := release.LeadingEpisode(base); ok {
+			episodes = []int{n}
+		}
+	}
+
+	if len(seasons) == 0 || len(episodes) == 0 {
+		return 0, 0, false
+	}
+	return seasons[0], episodes[0], true
+}
+
+// seriesTitleForPath takes the show title from the file name, or the nearest
+// parent folder that is not a season folder
+func seriesTitleForPath(rel string) (string, int) {
+	rel = filepath.ToSlash(rel)
+	info := release.Parse(strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel)))
+	// Names like "05 - Pilot.mkv" carry the episode title, not the show title
+	if info.Title != "" && len(info.Seasons) > 0 {
+		return info.Title, info.Year
+	}
+	for dir := filepath.Dir(rel); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+		if parsed := release.Parse(filepath.Base(dir)); parsed.Title != "" {
+			return parsed.Title, parsed.Year
+		}
+	}
+	return info.Title, info.Year
+}
+
+// movieTitleForPath prefers the file name and falls back to the parent folder
+// for names like "Movie (2010)/movie.mkv" where the file carries no year
+func movieTitleForPath(rel string) (string, int) {
+	rel = filepath.ToSlash(rel)
+	info := release.Parse(strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel)))
+	if info.Title != "" && info.Year > 0 {
+		return info.Title, info.Year
+	}
+	if dir := filepath.Dir(rel); dir != "." && dir != "/" {
+		if parsed := release.Parse(filepath.Base(dir)); parsed.Title != "" && parsed.Year > 0 {
+			return parsed.Title, parsed.Year
+		}
+	}
+	return info.Title, info.Year
+}
+
+// isVideoFile reports whether path is a playable video; only the file and its
+// folder are checked for extras so unrelated folder names higher up are ignored
+func isVideoFile(path string) bool {
+	if !release.IsVideoFile(path) {
+		return false
+	}
+	tail := filepath.Base(filepath.Dir(path)) + "/" + filepath.Base(path)
+	return !release.IsExtra(tail)
+}
+
+// rootFor returns the configured root containing path, or "" if none does
+func rootFor(path string, roots []string) string {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return ""
+}
+
+func underAny(path string, roots []string) bool {
+	return rootFor(path, roots) != ""
+}
@@ -0,0 +1,146 @@
+package localmedia
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+)
+
+var (
+	ffprobeOnce sync.Once
+	ffprobePath string
+)
+
+// ffprobeOutput is the subset of `ffprobe -show_streams` we read
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType     string `json:"codec_type"`
+		CodecName     string `json:"codec_name"`
+		Profile       string `json:"profile"`
+		Width         int    `json:"width"`
+		Height        int    `json:"height"`
+		ColorTransfer string `json:"color_transfer"`
+		SideDataList  []struct {
+			SideDataType string `json:"side_data_type"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+// probeFile fills technical metadata from the file itself when ffprobe is installed
+// Values parsed from the file name are kept when probing is unavailable or fails
+func probeFile(ctx context.Context, file *database.LocalMediaFile) {
+	ffprobeOnce.Do(func() {
+		ffprobePath, _ = exec.LookPath("ffprobe")
+	})
+	if ffprobePath == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, ffprobePath,
+		"-v", "quiet", "-print_format", "json", "-show_streams", file.Path).Output()
+	if err != nil {
+		return
+	}
+
+	var probe ffprobeOutput
+	if json.Unmarshal(out, &probe) != nil {
+		return
+	}
+
+	videoSeen, audioSeen := false, false
+	for _, stream := range probe.Streams {
+		switch {
+		case stream.CodecType == "video" && !videoSeen:
+			videoSeen = true
+			if res := resolutionFromSize(stream.Width, stream.Height); res != "" {
+				file.Resolution = res
+			}
+			if codec := videoCodecName(stream.CodecName); codec != "" {
+				file.Codec = codec
+			}
+			for _, side := range stream.SideDataList {
+				if strings.Contains(side.SideDataType, "DOVI") {
+					file.HDRType = "DV"
+				}
+			}
+			if file.HDRType == "" && (stream.ColorTransfer == "smpte2084" || stream.ColorTransfer == "arib-std-b67") {
+				file.HDRType = "HDR10"
+			}
+		case stream.CodecType == "audio" && !audioSeen:
+			audioSeen = true
+			if audio := audioFormatName(stream.CodecName, stream.Profile); audio != "" && file.AudioFormat != "Atmos" {
+				file.AudioFormat = audio
+			}
+		}
+	}
+}
+
+// resolutionFromSize maps frame dimensions to the resolution labels used by the scorer
+// Width is checked as well so scope (2.40:1) encodes are not under-rated
+func resolutionFromSize(width, height int) string {
+	switch {
+	case width >= 3200 || height >= 2000:
+		return "2160p"
+	case width >= 1800 || height >= 1000:
+		return "1080p"
+	case width >= 1200 || height >= 700:
+		return "720p"
+	case height >= 560:
+		return "576p"
+	case height > 0:
+		return "480p"
+	}
+	return ""
+}
+
+func videoCodecName(codec string) string {
+	switch codec {
+	case "hevc":
+		return "x265"
+	case "h264":
+		return "x264"
+	case "av1":
+		return "AV1"
+	case "vp9":
+		return "VP9"
+	case "mpeg4":
+		return "XviD"
+	}
+	return ""
+}
+
+func audioFormatName(codec, profile string) string {
+	switch codec {
+	case "truehd":
+		return "TrueHD"
+	case "eac3":
+		return "DD+"
+	case "ac3":
+		return "AC3"
+	case "dts":
+		switch {
+		case strings.Contains(profile, "MA"):
+			return "DTS-HD MA"
+		case strings.Contains(profile, "HD"):
+			return "DTS-HD"
+		case strings.Contains(profile, "X"):
+			return "DTS-X"
+		}
+		return "DTS"
+	case "aac":
+		return "AAC"
+	case "mp3":
+		return "MP3"
+	case "flac":
+		return "FLAC"
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package localmedia
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// watchMask covers finished writes, moves in/out, deletions and new directories
+const watchMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM |
+	syscall.IN_DELETE | syscall.IN_CREATE | syscall.IN_DELETE_SELF
+
+// Watch uses inotify to index files as soon as they are written or moved into a
+// configured directory, and to drop them when deleted. Blocks until ctx is done.
+// Network filesystems (NFS/SMB) do not deliver inotify events for remote changes,
+// so periodic scans are still needed for those mounts.
+func (l *Library) Watch(ctx context.Context) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("inotify init: %w", err)
+	}
+	// A non-blocking fd is registered with the runtime poller, so Close unblocks Read
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	w := &inotifyWatcher{fd: fd, dirs: make(map[int32]string)}
+	for _, root := range l.Paths() {
+		w.addTree(root)
+	}
+	log.Printf("[LOCAL] Watching %d directories for new media", len(w.dirs))
+
+	go func() {
+		<-ctx.Done()
+		file.Close()
+	}()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("inotify read: %w", err)
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			offset = nameEnd
+			if nameEnd > n {
+				break
+			}
+
+			dir, ok := w.dirs[event.Wd]
+			if !ok {
+				continue
+			}
+			if event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_IGNORED) != 0 {
+				delete(w.dirs, event.Wd)
+				continue
+			}
+
+			name := strings.TrimRight(string(buf[nameStart:nameEnd]), "\x00")
+			if name == "" {
+				continue
+			}
+			path := filepath.Join(dir, name)
+
+			isDir := event.Mask&syscall.IN_ISDIR != 0
+			switch {
+			case isDir && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+				// New folder: watch it and pick up anything already moved in with it
+				w.addTree(path)
+				l.ProcessPath(ctx, path)
+			case event.Mask&(syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO) != 0:
+				if isVideoFile(path) {
+					log.Printf("[LOCAL] New file: %s", path)
+					l.ProcessPath(ctx, path)
+				}
+			case event.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+				if isDir || isVideoFile(path) {
+					l.ProcessPath(ctx, path)
+				}
+			}
+		}
+	}
+}
+
+type inotifyWatcher struct {
+	fd   int
+	dirs map[int32]string
+}
+
+// addTree watches a directory and every directory below it
+func (w *inotifyWatcher) addTree(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, watchMask)
+		if err != nil {
+			log.Printf("[LOCAL] ⚠️ Cannot watch %s: %v", path, err)
+			return nil
+		}
+		w.dirs[int32(wd)] = path
+		return nil
+	})
+}
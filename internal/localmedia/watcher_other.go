@@ -0,0 +1,31 @@
+//go:build !linux
+
+package localmedia
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// pollInterval is how often directories are rescanned where inotify is unavailable
+const pollInterval = 5 * time.Minute
+
+// Watch rescans the configured directories periodically on platforms without inotify.
+// Blocks until ctx is done.
+func (l *Library) Watch(ctx context.Context) error {
+	log.Printf("[LOCAL] inotify unavailable, polling media directories every %v", pollInterval)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := l.Scan(ctx); err != nil {
+				log.Printf("[LOCAL] ❌ Scan failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bufferSize is how many recent lines are kept in memory for the log API
+const bufferSize = 5000
+
+// Filter selects log entries; zero values match everything except Level, which is info
+type Filter struct {
+	Level      slog.Level // Minimum level
+	Subsystems []string
+	Since      time.Time
+	Until      time.Time // Exclusive
+	Search     string    // Case-insensitive text in the message
+	AfterID    int64
+}
+
+// Match reports whether e passes the filter
+func (f Filter) Match(e *Entry) bool {
+	if e.Level < f.Level || e.ID <= f.AfterID {
+		return false
+	}
+	if len(f.Subsystems) > 0 && !slices.Contains(f.Subsystems, e.Subsystem) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return f.Search == "" || strings.Contains(strings.ToLower(e.Message), strings.ToLower(f.Search))
+}
+
+// Buffer keeps the most recent entries and hands new ones to subscribers
+type Buffer struct {
+	mu          sync.RWMutex
+	entries     []*Entry // Ring, oldest at next once full
+	next        int
+	lastID      int64
+	subsystems  map[string]bool
+	subscribers map[chan *Entry]struct{}
+}
+
+// NewBuffer creates a buffer holding up to size entries
+func NewBuffer(size int) *Buffer {
+	return &Buffer{
+		entries:     make([]*Entry, 0, size),
+		subsystems:  make(map[string]bool),
+		subscribers: make(map[chan *Entry]struct{}),
+	}
+}
+
+func (b *Buffer) add(e *Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e.ID = b.lastID
+	if len(b.entries) < cap(b.entries) {
+		b.entries = append(b.entries, e)
+	} else {
+		b.entries[b.next] = e
+		b.next = (b.next + 1) % len(b.entries)
+	}
+	b.subsystems[e.Subsystem] = true
+	for ch := range b.subscribers {
+		// A subscriber that can't keep up misses lines rather than slowing down logging
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Query returns the newest limit entries matching f, oldest first
+func (b *Buffer) Query(f Filter, limit int) []*Entry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var out []*Entry
+	for i := len(b.entries) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		e := b.entries[(b.next+i)%len(b.entries)]
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// LastID is the ID of the newest entry; IDs start again at 1 when the process restarts
+func (b *Buffer) LastID() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.lastID
+}
+
+// Subscribe returns a channel receiving every new entry until cancel is called
+func (b *Buffer) Subscribe() (<-chan *Entry, func()) {
+	ch := make(chan *Entry, 256)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Subsystems lists the subsystems seen so far
+func (b *Buffer) Subsystems() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	names := make([]string, 0, len(b.subsystems))
+	for name := range b.subsystems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Recent is the buffer of recent lines behind the log API
+func Recent() *Buffer {
+	return global.buffer
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSubsystem is the subsystem of lines that name none
+	DefaultSubsystem = "app"
+	// SubsystemKey is the attribute that sets a line's subsystem, as in logger.With(SubsystemKey, "epg")
+	SubsystemKey = "subsystem"
+
+	timeFormat = "2006/01/02 15:04:05"
+)
+
+// Entry is one log line
+type Entry struct {
+	ID        int64             `json:"id"`
+	Time      time.Time         `json:"time"`
+	Level     slog.Level        `json:"-"`
+	Subsystem string            `json:"subsystem"`
+	Message   string            `json:"message"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
+}
+
+// MarshalJSON writes the level by name
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	return json.Marshal(struct {
+		plain
+		Level string `json:"level"`
+	}{plain(e), LevelName(e.Level)})
+}
+
+func (e *Entry) addAttr(group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if group != "" {
+		key = group + "." + key
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			e.addAttr(key, ga)
+		}
+		return
+	}
+	if e.Attrs == nil {
+		e.Attrs = make(map[string]string)
+	}
+	e.Attrs[key] = a.Value.String()
+}
+
+// consoleLine formats an entry like the standard logger did, with its level and, unless the
+// message starts with a [TAG] of its own, its subsystem
+func (e *Entry) consoleLine() string {
+	var b strings.Builder
+	b.WriteString(e.Time.Format(timeFormat))
+	b.WriteByte(' ')
+	b.WriteString(e.Level.String())
+	b.WriteByte(' ')
+	if !strings.HasPrefix(e.Message, "[") {
+		b.WriteString("[" + e.Subsystem + "] ")
+	}
+	b.WriteString(e.Message)
+	keys := make([]string, 0, len(e.Attrs))
+	for k := range e.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := e.Attrs[k]
+		if strings.ContainsAny(v, " \t\"=") {
+			v = `"` + strings.ReplaceAll(v, `"`, `\"`) + `"`
+		}
+		b.WriteString(" " + k + "=" + v)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
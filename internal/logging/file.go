@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rotatingFile appends entries as JSON lines and rotates the file once it reaches maxSize:
+// streamarr.log becomes streamarr.log.1, streamarr.log.1 becomes .2 and so on, up to maxFiles
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	f        *os.File
+	size     int64
+}
+
+// configure switches to path, closing the current file when the path changed
+func (rf *rotatingFile) configure(path string, maxSize int64, maxFiles int) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.maxSize, rf.maxFiles = maxSize, maxFiles
+	if path == rf.path && (rf.f != nil || path == "") {
+		return nil
+	}
+	if rf.f != nil {
+		rf.f.Close()
+		rf.f = nil
+	}
+	rf.path = path
+	if path == "" {
+		return nil
+	}
+	return rf.openLocked()
+}
+
+func (rf *rotatingFile) openLocked() error {
+	if err := os.MkdirAll(filepath.Dir(rf.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f, rf.size = f, info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) write(e Entry) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	if rf.size > 0 && rf.size+int64(len(line)) > rf.maxSize {
+		if err := rf.rotateLocked(); err != nil {
+			fmt.Fprintf(global.console, "%s ERROR [logging] Cannot rotate %s: %v\n", e.Time.Format(timeFormat), rf.path, err)
+			if rf.f == nil {
+				return
+			}
+			// Try again after another maxSize bytes rather than on every line
+			rf.size = 0
+		}
+	}
+	n, _ := rf.f.Write(line)
+	rf.size += int64(n)
+}
+
+func (rf *rotatingFile) rotateLocked() error {
+	rf.f.Close()
+	rf.f = nil
+	os.Remove(fmt.Sprintf("%s.%d", rf.path, rf.maxFiles))
+	for i := rf.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+	}
+	var err error
+	if rf.maxFiles > 0 {
+		err = os.Rename(rf.path, rf.path+".1")
+	} else {
+		err = os.Remove(rf.path)
+	}
+	// Keep appending to the same file when it couldn't be moved away
+	if openErr := rf.openLocked(); openErr != nil {
+		return openErr
+	}
+	return err
+}
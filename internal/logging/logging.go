@@ -0,0 +1,238 @@
+// Package logging sends every log line — from the standard log package, slog and the subsystem
+// loggers — through one pipeline that applies per-subsystem levels and redacts secrets, then
+// writes it to the console, keeps it in memory for the log API and appends it to a rotating file.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures logging
+type Options struct {
+	Level     slog.Level            // Level of subsystems without one of their own
+	Levels    map[string]slog.Level // Per subsystem, e.g. "providers": debug
+	File      string                // JSON lines log file; "" disables it
+	MaxSizeMB int                   // Size at which the file is rotated
+	MaxFiles  int                   // Rotated files kept besides the current one
+	Secrets   []string              // Values replaced with [redacted] wherever they appear
+}
+
+// optionsTTL is how long options are reused before reading the settings again
+const optionsTTL = 5 * time.Second
+
+var global = struct {
+	mu         sync.Mutex
+	getOptions func() Options
+	opts       Options
+	loadedAt   time.Time
+	refreshing bool
+	secrets    *strings.Replacer
+	console    io.Writer // Never changed, so read without the lock
+	file       *rotatingFile
+	buffer     *Buffer
+}{
+	opts:    Options{Level: slog.LevelInfo},
+	console: os.Stderr,
+	file:    &rotatingFile{},
+	buffer:  NewBuffer(bufferSize),
+}
+
+// Init reads options from getOptions and makes log and slog.Default write through this package.
+// Lines logged before Init are kept at info level without redaction of configured secrets.
+func Init(getOptions func() Options) {
+	global.mu.Lock()
+	global.getOptions = getOptions
+	global.mu.Unlock()
+	Refresh()
+
+	slog.SetDefault(Logger(DefaultSubsystem))
+	// After slog.SetDefault, which would otherwise send log output to the slog handler as info
+	log.SetFlags(0)
+	log.SetOutput(stdWriter{})
+}
+
+// Refresh reads the options again now, e.g. after the log levels were changed
+func Refresh() {
+	global.mu.Lock()
+	getOptions := global.getOptions
+	global.mu.Unlock()
+	if getOptions != nil {
+		apply(getOptions())
+	}
+}
+
+func apply(opts Options) {
+	if opts.MaxSizeMB <= 0 {
+		opts.MaxSizeMB = 10
+	}
+	if opts.MaxFiles < 0 {
+		opts.MaxFiles = 0
+	}
+	// Longest first, so a secret containing another is replaced whole
+	secrets := make([]string, 0, len(opts.Secrets))
+	for _, s := range opts.Secrets {
+		if len(s) >= minSecretLength {
+			secrets = append(secrets, s)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		pairs = append(pairs, s, redacted)
+	}
+
+	global.mu.Lock()
+	global.opts = opts
+	global.loadedAt = time.Now()
+	global.refreshing = false
+	global.secrets = strings.NewReplacer(pairs...)
+	global.mu.Unlock()
+
+	if err := global.file.configure(opts.File, int64(opts.MaxSizeMB)<<20, opts.MaxFiles); err != nil {
+		fmt.Fprintf(global.console, "%s ERROR [logging] Cannot open log file %s: %v\n", time.Now().Format(timeFormat), opts.File, err)
+	}
+}
+
+// options returns the current options. Stale options are refreshed in the background: a line may
+// be logged while the settings lock is held, so reading the settings here could deadlock.
+func options() Options {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	if global.getOptions != nil && !global.refreshing && time.Since(global.loadedAt) > optionsTTL {
+		global.refreshing = true
+		go Refresh()
+	}
+	return global.opts
+}
+
+// Enabled reports whether a subsystem logs at level
+func Enabled(subsystem string, level slog.Level) bool {
+	opts := options()
+	if l, ok := opts.Levels[subsystem]; ok {
+		return level >= l
+	}
+	return level >= opts.Level
+}
+
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// ParseLevels reads per-subsystem levels, skipping any that aren't valid
+func ParseLevels(levels map[string]string) map[string]slog.Level {
+	parsed := make(map[string]slog.Level, len(levels))
+	for subsystem, name := range levels {
+		if level, err := ParseLevel(name); err == nil {
+			parsed[strings.ToLower(subsystem)] = level
+		}
+	}
+	return parsed
+}
+
+// LevelName is the lowercase name ParseLevel reads
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// Logger returns a logger whose lines are tagged with subsystem and filtered by its level
+func Logger(subsystem string) *slog.Logger {
+	return slog.New(&handler{subsystem: subsystem})
+}
+
+// handler is the slog.Handler behind Logger
+type handler struct {
+	subsystem string
+	attrs     []slog.Attr
+	group     string
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return Enabled(h.subsystem, level)
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	e := Entry{Time: r.Time, Level: r.Level, Subsystem: h.subsystem, Message: r.Message}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, a := range h.attrs {
+		e.addAttr("", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		e.addAttr(h.group, a)
+		return true
+	})
+	emit(e)
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		if a.Key == SubsystemKey {
+			c.subsystem = a.Value.String()
+			continue
+		}
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		c.attrs = append(c.attrs, a)
+	}
+	return &c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	if c.group != "" {
+		name = c.group + "." + name
+	}
+	c.group = name
+	return &c
+}
+
+// stdWriter receives the standard log package's output, one line per Write
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	subsystem, level := Classify(msg)
+	if Enabled(subsystem, level) {
+		emit(Entry{Time: time.Now(), Level: level, Subsystem: subsystem, Message: msg})
+	}
+	return len(p), nil
+}
+
+// emit redacts an entry and writes it everywhere
+func emit(e Entry) {
+	e.Message = Redact(e.Message)
+	for k, v := range e.Attrs {
+		e.Attrs[k] = Redact(v)
+	}
+	global.buffer.add(&e)
+	io.WriteString(global.console, e.consoleLine())
+	global.file.write(e)
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// tagSubsystems groups the [TAG]s and "Prefix:" labels of log.Printf lines into subsystems.
+// Tags not listed become a subsystem of their own, lowercased.
+var tagSubsystems = map[string]string{
+	"provider": "providers", "fetch": "providers", "proxy": "providers", "dmm api": "providers",
+	"year-filter": "providers", "year-extract": "providers", "sort": "providers", "sort-ui": "providers",
+	"filter": "providers", "episode-filter": "providers", "stream-fetch": "providers",
+	"stream-detail": "providers", "torznab": "providers", "retry": "providers",
+
+	"rd": "debrid", "rd-check": "debrid", "usenet": "debrid", "cached-check": "debrid",
+
+	"xtream": "xtream", "xtream api": "xtream", "play": "xtream", "resolve": "xtream", "prefetch": "xtream",
+
+	"stremio": "stremio",
+
+	"cache-scanner": "cache", "cache-phase1": "cache", "cache-write": "cache", "cache-hit": "cache",
+	"link-cache": "cache",
+
+	"local": "library", "strm-export": "library", "collection sync": "library", "episode scan": "library",
+	"mdblistsync": "library", "mdblist": "library", "balkanvod": "library", "iptv vod import": "library",
+	"adult vod import": "library", "adult vod stats": "library", "xtream vod": "library",
+	"xtream series": "library", "cleanup": "library",
+
+	"live tv": "livetv", "hdhr": "livetv", "epg": "livetv", "validation": "livetv", "playlist": "livetv",
+
+	"auth": "auth", "audit": "auth", "updateprofile": "auth", "net": "network",
+
+	"jobs": "jobs", "settings": "settings", "subtitles": "subtitles", "http": "http",
+	"update": "system", "admin": "system", "api": "system", "tracing": "system", "notify": "system",
+	"activity": "system", "streams": "streams",
+}
+
+// Subsystems lists the subsystems log lines are grouped into, for choosing levels
+func Subsystems() []string {
+	seen := map[string]bool{DefaultSubsystem: true}
+	names := []string{DefaultSubsystem}
+	for _, name := range tagSubsystems {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range global.buffer.Subsystems() {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Classify finds the subsystem and level of a log.Printf line from its "[TAG]" or "Prefix:" and
+// markers such as ❌, ⚠️, "Error" or a -DEBUG tag
+func Classify(msg string) (string, slog.Level) {
+	subsystem, tag := DefaultSubsystem, ""
+	text := strings.TrimSpace(msg)
+	if strings.HasPrefix(text, "[") {
+		if end := strings.IndexByte(text, ']'); end > 0 {
+			tag = strings.ToLower(text[1:end])
+			text = strings.TrimSpace(text[end+1:])
+		}
+	} else if colon := strings.IndexByte(text, ':'); colon > 0 && colon <= 20 && !strings.ContainsAny(text[:colon], "/[(") &&
+		!strings.HasPrefix(text[colon:], "://") {
+		label := strings.ToLower(text[:colon])
+		if _, ok := tagSubsystems[label]; ok || label == "debug" || label == "error" || label == "warning" {
+			tag = label
+			text = strings.TrimSpace(text[colon+1:])
+		}
+	}
+
+	level := slog.LevelInfo
+	switch tag {
+	case "debug":
+		level, tag = slog.LevelDebug, ""
+	case "error":
+		level, tag = slog.LevelError, ""
+	case "warning", "warn":
+		level, tag = slog.LevelWarn, ""
+	}
+	if base, ok := strings.CutSuffix(tag, "-debug"); ok {
+		level, tag = slog.LevelDebug, base
+	}
+	if tag != "" {
+		if name, ok := tagSubsystems[tag]; ok {
+			subsystem = name
+		} else if base, _, _ := strings.Cut(tag, "-"); tagSubsystems[base] != "" {
+			// PLAY-CACHE, RESOLVE-PROXY, ...
+			subsystem = tagSubsystems[base]
+		} else {
+			subsystem = tag
+		}
+	}
+
+	if level == slog.LevelInfo {
+		switch {
+		case strings.Contains(text, "❌"), strings.HasPrefix(text, "Error"), strings.HasPrefix(text, "ERROR"),
+			strings.HasPrefix(text, "Failed"), strings.HasPrefix(text, "FATAL"):
+			level = slog.LevelError
+		case strings.Contains(text, "⚠️"), strings.HasPrefix(text, "Warning"), strings.HasPrefix(text, "WARNING"):
+			level = slog.LevelWarn
+		}
+	}
+	return subsystem, level
+}
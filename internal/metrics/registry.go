@@ -0,0 +1,55 @@
+// Package metrics defines StreamArr's Prometheus metrics and serves them with the client library's
+// default registry, which also carries the Go runtime and process metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefBuckets are latency buckets in seconds, from 5ms to a minute
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Handler serves every registered metric in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// NewGaugeFunc registers a gauge whose value is read by fn on every scrape
+func NewGaugeFunc(name, help string, fn func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+}
+
+// NewGaugeVecFunc registers a gauge with one label whose values are read by fn on every scrape
+func NewGaugeVecFunc(name, help, label string, fn func() map[string]float64) {
+	prometheus.MustRegister(&gaugeVecFunc{desc: prometheus.NewDesc(name, help, []string{label}, nil), read: fn})
+}
+
+// gaugeVecFunc is the collector behind NewGaugeVecFunc
+type gaugeVecFunc struct {
+	desc *prometheus.Desc
+	read func() map[string]float64
+}
+
+func (g *gaugeVecFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *gaugeVecFunc) Collect(ch chan<- prometheus.Metric) {
+	for label, value := range g.read() {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, value, label)
+	}
+}
+
+// counterValue reads the current value of a counter
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
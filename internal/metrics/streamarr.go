@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// StreamArr's metrics; gauges read from other packages are registered where they are wired (cmd/server)
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "streamarr_http_request_duration_seconds",
+		Help:    "HTTP request latency by route template",
+		Buckets: DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	ProviderRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamarr_provider_requests_total",
+		Help: "Stream provider lookups by provider and result (ok, empty, error)",
+	}, []string{"provider", "kind", "result"})
+	ProviderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "streamarr_provider_request_duration_seconds",
+		Help:    "Stream provider lookup latency",
+		Buckets: DefBuckets,
+	}, []string{"provider", "kind"})
+
+	DebridRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamarr_debrid_requests_total",
+		Help: "Debrid and usenet API calls by service, endpoint and status code (error when no response)",
+	}, []string{"service", "endpoint", "status"})
+	DebridDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "streamarr_debrid_request_duration_seconds",
+		Help:    "Debrid and usenet API call latency",
+		Buckets: DefBuckets,
+	}, []string{"service", "endpoint"})
+
+	LinkCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamarr_link_cache_lookups_total",
+		Help: "Resolved-link cache lookups by result (hit, miss)",
+	}, []string{"result"})
+
+	StreamChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamarr_stream_checks_total",
+		Help: "Cached streams checked by the stream checker, by outcome (cached, replaced, unavailable, error)",
+	}, []string{"outcome"})
+	StreamUpgrades = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "streamarr_stream_upgrades_total",
+		Help: "Cached streams replaced by the stream checker, by reason (better_quality, expired)",
+	}, []string{"reason"})
+
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "streamarr_job_duration_seconds",
+		Help:    "Background job run time by job and final status",
+		Buckets: []float64{1, 5, 15, 60, 300, 900, 1800, 3600, 7200},
+	}, []string{"job", "status"})
+)
+
+func init() {
+	NewGaugeFunc("streamarr_link_cache_hit_ratio", "Share of resolved-link cache lookups served from the cache since start", func() float64 {
+		hits, misses := counterValue(LinkCacheLookups.WithLabelValues("hit")), counterValue(LinkCacheLookups.WithLabelValues("miss"))
+		if hits+misses == 0 {
+			return 0
+		}
+		return hits / (hits + misses)
+	})
+}
+
+// Since is the seconds elapsed since start, for Observe
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Transport records DebridRequests and DebridDuration, and a client span, for every call made
+// through base (http.DefaultTransport when nil)
+func Transport(service string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{service: service, base: base}
+}
+
+type transport struct {
+	service string
+	base    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := Endpoint(req.URL.Path)
+	_, span := tracing.Start(req.Context(), t.service+" "+req.Method+" "+endpoint, tracing.KindClient)
+	span.Set("http.request.method", req.Method)
+	span.Set("server.address", req.URL.Host)
+	span.Set("url.template", endpoint)
+	defer span.End()
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	DebridDuration.WithLabelValues(t.service, endpoint).Observe(Since(start))
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+		span.Set("http.response.status_code", resp.StatusCode)
+	}
+	span.Fail(err)
+	DebridRequests.WithLabelValues(t.service, endpoint, status).Inc()
+	return resp, err
+}
+
+// Endpoint turns a URL path into a low-cardinality label by replacing IDs, hashes and UUIDs with {id}
+func Endpoint(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range parts {
+		if isID(p) {
+			parts[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// isID reports whether a path segment is a number, a UUID, or a hash or token: 12 or more letters
+// and digits with at least one of each. Words such as instantAvailability stay as they are.
+func isID(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	if isUUID(segment) {
+		return true
+	}
+	letters, digits := 0, 0
+	for _, r := range segment {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letters++
+		default:
+			return false
+		}
+	}
+	return letters == 0 || (len(segment) >= 12 && digits > 0)
+}
+
+// isUUID reports whether s looks like 8-4-4-4-12 hex digits
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/rest/1.0/torrents/info/ABCDEF1234567":          "/rest/1.0/torrents/info/{id}",
+		"/rest/1.0/torrents/instantAvailability":         "/rest/1.0/torrents/instantAvailability",
+		"/rest/1.0/torrents/addMagnet":                   "/rest/1.0/torrents/addMagnet",
+		"/api/torrents/mylist":                           "/api/torrents/mylist",
+		"/api/torrents/requestdl":                        "/api/torrents/requestdl",
+		"/dl/3/8f4e2a9c1b7d6e5f3a2b1c0d9e8f7a6b5c4d3e2f": "/dl/{id}/{id}",
+		"/files/123e4567-e89b-12d3-a456-426614174000":    "/files/{id}",
+		"/2.0/user":                "/2.0/user",
+		"/api/v1/subscriptions":    "/api/v1/subscriptions",
+		"/torrents/selectFiles/42": "/torrents/selectFiles/{id}",
+	}
+	for path, want := range cases {
+		if got := Endpoint(path); got != want {
+			t.Errorf("Endpoint(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestTransportRecordsCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport("testservice", nil)}
+	resp, err := client.Get(server.URL + "/torrents/info/ABCDEF1234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	want := `streamarr_debrid_requests_total{endpoint="/torrents/info/{id}",service="testservice",status="418"} 1`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("metrics output lacks %s", want)
+	}
+}
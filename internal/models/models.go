@@ -133,6 +133,8 @@ type ActivityLog struct {
 	ContentID   int64     `json:"content_id"`
 	Message     string    `json:"message"`
 	Data        string    `json:"data"`
+	UserID      *int      `json:"user_id,omitempty"` // Nil for background services
+	Username    string    `json:"username,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -143,10 +145,14 @@ type QualityProfile struct {
 	Cutoff    string   `json:"cutoff"`
 }
 
-// CachedStream represents a cached debrid stream for a movie
+// CachedStream represents a cached debrid stream for a movie or a series episode
+// Movie streams set MovieID; episode streams set SeriesID, Season and Episode
 type CachedStream struct {
 	ID               int       `json:"id"`
 	MovieID          int       `json:"movie_id"`
+	SeriesID         int       `json:"series_id,omitempty"`
+	Season           int       `json:"season,omitempty"`
+	Episode          int       `json:"episode,omitempty"`
 	StreamURL        string    `json:"stream_url"`
 	StreamHash       string    `json:"stream_hash"`
 	QualityScore     int       `json:"quality_score"`
@@ -167,6 +173,11 @@ type CachedStream struct {
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// IsEpisode reports whether the cached stream belongs to a series episode
+func (c *CachedStream) IsEpisode() bool {
+	return c.SeriesID != 0
+}
+
 // TorrentStream represents a torrent stream with quality metadata for Phase 1
 type TorrentStream struct {
 	Hash         string  `json:"hash"`
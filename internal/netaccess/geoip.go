@@ -0,0 +1,129 @@
+package netaccess
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// geoIPCheckInterval is how often the database file is checked for changes
+const geoIPCheckInterval = time.Minute
+
+type countryRange struct {
+	start, end net.IP // 16-byte form
+	country    string
+}
+
+// countryDB maps IP ranges to ISO 3166 country codes, loaded from a local CSV file with lines of
+// "start,end,CC" (e.g. DB-IP's IP to Country Lite) or "cidr,CC"
+type countryDB struct {
+	path      string
+	modTime   time.Time
+	checkedAt time.Time
+	ranges    []countryRange // Sorted by start
+}
+
+// current returns the database for path, reloading it when the path or the file changed.
+// When loading fails the previous data is kept and the error is only returned once per check interval.
+func (db *countryDB) current(path string) (*countryDB, error) {
+	now := time.Now()
+	if db != nil && db.path == path && now.Sub(db.checkedAt) < geoIPCheckInterval {
+		return db, nil
+	}
+	if db == nil || db.path != path {
+		db = &countryDB{path: path}
+	}
+	db.checkedAt = now
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return db, err
+	}
+	if info.ModTime().Equal(db.modTime) {
+		return db, nil
+	}
+	ranges, err := loadCountryRanges(path)
+	if err != nil {
+		return db, err
+	}
+	log.Printf("[NET] Loaded %d GeoIP ranges from %s", len(ranges), path)
+	return &countryDB{path: path, modTime: info.ModTime(), checkedAt: now, ranges: ranges}, nil
+}
+
+// lookup returns the country code for ip, or "" when it isn't in the database
+func (db *countryDB) lookup(ip net.IP) string {
+	if db == nil {
+		return ""
+	}
+	ip = ip.To16()
+	// The last range starting at or before ip
+	i := sort.Search(len(db.ranges), func(i int) bool { return bytes.Compare(db.ranges[i].start, ip) > 0 }) - 1
+	if i >= 0 && bytes.Compare(ip, db.ranges[i].end) <= 0 {
+		return db.ranges[i].country
+	}
+	return ""
+}
+
+func loadCountryRanges(path string) ([]countryRange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ranges []countryRange
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		for i := range fields {
+			fields[i] = strings.Trim(strings.TrimSpace(fields[i]), `"`)
+		}
+		var r countryRange
+		switch {
+		case len(fields) >= 2 && strings.Contains(fields[0], "/"):
+			_, network, err := net.ParseCIDR(fields[0])
+			if err != nil {
+				continue
+			}
+			r.start = network.IP.To16()
+			r.end = make(net.IP, net.IPv6len)
+			copy(r.end, r.start)
+			mask := network.Mask
+			if len(mask) == net.IPv4len {
+				mask = append(net.CIDRMask(96, 128)[:12], mask...)
+			}
+			for i := range r.end {
+				r.end[i] |= ^mask[i]
+			}
+			r.country = fields[1]
+		case len(fields) >= 3:
+			r.start, r.end = net.ParseIP(fields[0]).To16(), net.ParseIP(fields[1]).To16()
+			if r.start == nil || r.end == nil {
+				continue // Header row or a format we don't know
+			}
+			r.country = fields[2]
+		default:
+			continue
+		}
+		r.country = strings.ToUpper(r.country)
+		ranges = append(ranges, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no IP ranges found")
+	}
+	sort.Slice(ranges, func(i, j int) bool { return bytes.Compare(ranges[i].start, ranges[j].start) < 0 })
+	return ranges, nil
+}
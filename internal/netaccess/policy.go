@@ -0,0 +1,306 @@
+// Package netaccess decides which client addresses may reach each part of StreamArr, resolving the
+// real client address behind trusted reverse proxies.
+package netaccess
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+)
+
+// Surface is a part of StreamArr with its own access rules
+type Surface string
+
+const (
+	SurfaceWeb       Surface = "web"        // Web UI and REST API
+	SurfaceXtream    Surface = "xtream"     // Xtream Codes API, M3U playlists and VOD playback
+	SurfaceStremio   Surface = "stremio"    // Stremio addon
+	SurfaceLiveProxy Surface = "live_proxy" // Live TV streams: the channel proxy, Xtream live and HDHomeRun tuners
+)
+
+// Surfaces lists every surface
+var Surfaces = []Surface{SurfaceWeb, SurfaceXtream, SurfaceStremio, SurfaceLiveProxy}
+
+// Rule is the access policy of one surface
+type Rule struct {
+	Allow          []*net.IPNet // When set, other addresses are refused
+	Deny           []*net.IPNet
+	AllowCountries []string // Upper-case ISO 3166 codes
+	DenyCountries  []string
+}
+
+// Options configures the network access policy
+type Options struct {
+	Enabled        bool
+	TrustedProxies []*net.IPNet // Forwarding headers are only believed from these peers
+	GeoIPDatabase  string
+	Rules          map[Surface]Rule
+	LegacyAllow    []*net.IPNet // STREAMARR_IP_WHITELIST; applies to every surface even when the policy is off
+}
+
+// Decision is how the policy treats a client address on a surface
+type Decision struct {
+	IP      string  `json:"ip"`
+	Surface Surface `json:"surface"`
+	Allowed bool    `json:"allowed"`
+	Country string  `json:"country,omitempty"`
+	Reason  string  `json:"reason"`
+}
+
+// optionsTTL is how long the middleware reuses options before reading the settings again
+const optionsTTL = 5 * time.Second
+
+// Policy enforces per-surface allow and deny lists. Settings changes apply within a few seconds,
+// without a restart. A nil policy allows everything.
+type Policy struct {
+	getOptions func() Options
+
+	mu       sync.Mutex
+	opts     Options
+	loadedAt time.Time
+	geoIP    *countryDB
+}
+
+// NewPolicy creates a network access policy
+func NewPolicy(getOptions func() Options) *Policy {
+	return &Policy{getOptions: getOptions}
+}
+
+// Middleware resolves each request's client address for auth.ClientIP, marks requests from trusted
+// proxies for auth.FromTrustedProxy and refuses addresses the policy doesn't allow on the requested surface
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	if p == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := p.options()
+		ctx := r.Context()
+		if peer := peerIP(r); peer != nil && contains(opts.TrustedProxies, peer) {
+			ctx = auth.WithTrustedProxy(ctx)
+		}
+		ip := clientIP(r, opts.TrustedProxies)
+		if ip != nil {
+			ctx = auth.WithClientIP(ctx, ip.String())
+		}
+		r = r.WithContext(ctx)
+		if !opts.Enabled && len(opts.LegacyAllow) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		decision := p.decide(ip, SurfaceFor(r.URL.Path), opts)
+		if !decision.Allowed {
+			log.Printf("[NET] 🚫 Blocked %s from %s on %s: %s", r.URL.Path, decision.IP, decision.Surface, decision.Reason)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// options returns the options, read again from the settings once they are optionsTTL old
+func (p *Policy) options() Options {
+	p.mu.Lock()
+	if time.Since(p.loadedAt) < optionsTTL {
+		defer p.mu.Unlock()
+		return p.opts
+	}
+	p.mu.Unlock()
+
+	opts := p.getOptions()
+	p.mu.Lock()
+	p.opts, p.loadedAt = opts, time.Now()
+	p.mu.Unlock()
+	return opts
+}
+
+// Evaluate returns how the current policy treats ip on a surface
+func (p *Policy) Evaluate(ip net.IP, surface Surface) Decision {
+	opts := p.getOptions()
+	decision := p.decide(ip, surface, opts)
+	if !opts.Enabled && len(opts.LegacyAllow) == 0 {
+		decision.Allowed, decision.Reason = true, "network access policy is off"
+	}
+	return decision
+}
+
+// ResolveClientIP returns the client address of a request, following forwarding headers from trusted proxies
+func (p *Policy) ResolveClientIP(r *http.Request) net.IP {
+	return clientIP(r, p.getOptions().TrustedProxies)
+}
+
+// decide applies, in order: the legacy allow list, the deny list, the allow list (an explicit allow
+// skips country rules), then country rules. Private and loopback addresses have no country and are
+// exempt from country rules; loopback can always reach the web UI, so a bad rule can be undone locally.
+func (p *Policy) decide(ip net.IP, surface Surface, opts Options) Decision {
+	d := Decision{Surface: surface}
+	if ip == nil {
+		d.Reason = "unknown client address"
+		return d
+	}
+	d.IP = ip.String()
+
+	if len(opts.LegacyAllow) > 0 && !contains(opts.LegacyAllow, ip) {
+		d.Reason = "not in STREAMARR_IP_WHITELIST"
+		return d
+	}
+	if !opts.Enabled {
+		d.Allowed, d.Reason = true, "in STREAMARR_IP_WHITELIST"
+		return d
+	}
+	if surface == SurfaceWeb && ip.IsLoopback() {
+		d.Allowed, d.Reason = true, "loopback can always reach the web UI"
+		return d
+	}
+
+	rule := opts.Rules[surface]
+	if contains(rule.Deny, ip) {
+		d.Reason = "in deny list"
+		return d
+	}
+	if len(rule.Allow) > 0 {
+		if contains(rule.Allow, ip) {
+			d.Allowed, d.Reason = true, "in allow list"
+		} else {
+			d.Reason = "not in allow list"
+		}
+		return d
+	}
+
+	if len(rule.AllowCountries) > 0 || len(rule.DenyCountries) > 0 {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+			d.Allowed, d.Reason = true, "local address"
+			return d
+		}
+		d.Country = p.country(opts.GeoIPDatabase, ip)
+		if slices.Contains(rule.DenyCountries, d.Country) && d.Country != "" {
+			d.Reason = "country " + d.Country + " is denied"
+			return d
+		}
+		if len(rule.AllowCountries) > 0 && !slices.Contains(rule.AllowCountries, d.Country) {
+			if d.Country == "" {
+				d.Reason = "country unknown"
+			} else {
+				d.Reason = "country " + d.Country + " is not allowed"
+			}
+			return d
+		}
+	}
+	d.Allowed, d.Reason = true, "no rule refuses it"
+	return d
+}
+
+// country looks ip up in the GeoIP database, loading it on first use and after it changes
+func (p *Policy) country(path string, ip net.IP) string {
+	if path == "" {
+		return ""
+	}
+	p.mu.Lock()
+	db, err := p.geoIP.current(path)
+	if err != nil {
+		log.Printf("[NET] ⚠️ Cannot load GeoIP database %s: %v", path, err)
+	}
+	p.geoIP = db
+	p.mu.Unlock()
+	return db.lookup(ip)
+}
+
+// SurfaceFor returns the surface a request path belongs to
+func SurfaceFor(path string) Surface {
+	switch {
+	case strings.HasPrefix(path, "/stremio/"):
+		return SurfaceStremio
+	case path == "/api/v1/channels/proxy",
+		strings.HasPrefix(path, "/api/v1/channels/") && strings.HasSuffix(path, "/stream"),
+		strings.HasPrefix(path, "/live/"),
+		strings.HasPrefix(path, "/hdhr/"):
+		return SurfaceLiveProxy
+	case path == "/player_api.php", path == "/panel_api.php", path == "/xmltv.php",
+		path == "/play.php", path == "/get.php",
+		strings.HasPrefix(path, "/movie/"), strings.HasPrefix(path, "/series/"),
+		strings.HasPrefix(path, "/local/"), strings.HasPrefix(path, "/usenet/"):
+		return SurfaceXtream
+	case strings.HasPrefix(path, "/api/"), strings.HasPrefix(path, "/assets/"), strings.HasPrefix(path, "/subtitles/"):
+		return SurfaceWeb
+	}
+	// Xtream direct play: /{username}/{password}/{id}.{ext}
+	if parts := strings.Split(strings.TrimPrefix(path, "/"), "/"); len(parts) == 3 && strings.Contains(parts[2], ".") {
+		return SurfaceXtream
+	}
+	return SurfaceWeb
+}
+
+// clientIP returns the peer address, or for a trusted proxy the address it forwarded for: the last
+// X-Forwarded-For entry that isn't itself a trusted proxy, else X-Real-IP
+func clientIP(r *http.Request, trusted []*net.IPNet) net.IP {
+	peer := peerIP(r)
+	if peer == nil || !contains(trusted, peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		client = hop
+		if !contains(trusted, hop) {
+			return hop
+		}
+	}
+	if len(hops) == 0 {
+		if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+			return realIP
+		}
+	}
+	return client
+}
+
+// peerIP returns the address of the host the request came straight from
+func peerIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRule converts comma-separated address and country lists into a rule
+func ParseRule(allow, deny, allowCountries, denyCountries string) Rule {
+	return Rule{
+		Allow:          auth.ParseNetworks(allow),
+		Deny:           auth.ParseNetworks(deny),
+		AllowCountries: parseCountries(allowCountries),
+		DenyCountries:  parseCountries(denyCountries),
+	}
+}
+
+func parseCountries(value string) []string {
+	var codes []string
+	for _, code := range strings.Split(value, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
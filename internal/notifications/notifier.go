@@ -0,0 +1,103 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Options configures where notifications go; read on every call so settings changes apply without a restart
+type Options struct {
+	Enabled           bool
+	DiscordWebhookURL string
+	TelegramBotToken  string
+	TelegramChatID    string
+}
+
+// Notifier sends alerts to the configured Discord webhook and Telegram chat
+type Notifier struct {
+	getOptions func() Options
+	http       *http.Client
+}
+
+// NewNotifier creates a notifier
+func NewNotifier(getOptions func() Options) *Notifier {
+	return &Notifier{
+		getOptions: getOptions,
+		http:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify sends an alert in the background; failures are logged. A nil notifier does nothing.
+func (n *Notifier) Notify(title, message string) {
+	if n == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := n.Send(ctx, title, message); err != nil {
+			log.Printf("[NOTIFY] ⚠️ %v", err)
+		}
+	}()
+}
+
+// Send delivers an alert to every configured channel
+func (n *Notifier) Send(ctx context.Context, title, message string) error {
+	opts := n.getOptions()
+	if !opts.Enabled {
+		return nil
+	}
+
+	var firstErr error
+	if opts.DiscordWebhookURL != "" {
+		err := n.post(ctx, opts.DiscordWebhookURL, map[string]interface{}{
+			"username": "StreamArr",
+			"embeds": []map[string]interface{}{{
+				"title":       title,
+				"description": message,
+				"timestamp":   time.Now().UTC().Format(time.RFC3339),
+			}},
+		})
+		if err != nil {
+			firstErr = fmt.Errorf("discord: %w", err)
+		}
+	}
+	if opts.TelegramBotToken != "" && opts.TelegramChatID != "" {
+		err := n.post(ctx, "https://api.telegram.org/bot"+opts.TelegramBotToken+"/sendMessage", map[string]interface{}{
+			"chat_id": opts.TelegramChatID,
+			"text":    title + "\n\n" + message,
+		})
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("telegram: %w", err)
+		}
+	}
+	return firstErr
+}
+
+// post sends a JSON payload; errors leave out the URL, which contains the Telegram bot token
+func (n *Notifier) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package playlist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultArtifactDir is where generated playlists are written
+const DefaultArtifactDir = "cache/playlists"
+
+// keepArtifactVersions is how many versioned files are kept next to the latest
+const keepArtifactVersions = 3
+
+// maxRenderedVariants bounds the per-request variants kept in memory for one version
+const maxRenderedVariants = 64
+
+// Artifact is a generated library playlist. get.php renders per-user variants from it
+// instead of querying the library on every request.
+type Artifact struct {
+	Version     string            `json:"version"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Movies      []ArtifactMovie   `json:"movies"`
+	Series      []ArtifactSeries  `json:"series"`
+	Live        []ArtifactChannel `json:"live"`
+}
+
+type ArtifactMovie struct {
+	TMDBID    int64  `json:"tmdb_id"`
+	Title     string `json:"title"`
+	Year      int    `json:"year,omitempty"`
+	Logo      string `json:"logo,omitempty"`
+	Monitored bool   `json:"monitored"`
+	Cached    bool   `json:"cached"`   // Has a stream in the Stream Cache Monitor
+	Released  bool   `json:"released"` // Release date is in the past
+}
+
+type ArtifactSeries struct {
+	TMDBID    int64             `json:"tmdb_id"`
+	IMDBID    string            `json:"imdb_id,omitempty"`
+	Title     string            `json:"title"`
+	Year      int               `json:"year,omitempty"`
+	Logo      string            `json:"logo,omitempty"`
+	Monitored bool              `json:"monitored"`
+	Cached    bool              `json:"cached"`
+	Episodes  []ArtifactEpisode `json:"episodes"`
+}
+
+type ArtifactEpisode struct {
+	ID       int64  `json:"id"` // TMDB episode ID, used as the Xtream episode ID
+	Season   int    `json:"season"`
+	Episode  int    `json:"episode"`
+	Title    string `json:"title,omitempty"`
+	Cached   bool   `json:"cached"`
+	Released bool   `json:"released"`
+}
+
+type ArtifactChannel struct {
+	Num   int    `json:"num"` // Position in the channel list, used as the Xtream live stream ID
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Logo  string `json:"logo,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+// Variant selects what a rendered playlist contains and who it is for
+type Variant struct {
+	ServerURL    string
+	Username     string
+	Password     string
+	Format       string   // "m3u", "m3u_plus" or "json"
+	Content      []string // Any of "movies", "series", "live"; empty = all
+	OnlyCached   bool
+	OnlyReleased bool
+}
+
+// Rendered is a playlist variant ready to serve
+type Rendered struct {
+	Body        []byte
+	Gzip        []byte
+	ETag        string
+	ContentType string
+}
+
+// ArtifactStore writes playlist artifacts atomically to disk and serves rendered variants.
+// The latest artifact is reloaded when the file changes, so a separate worker process can write it.
+type ArtifactStore struct {
+	dir string
+
+	mu       sync.Mutex
+	current  *Artifact
+	loadedAt time.Time // mtime of the loaded file
+	rendered map[string]*Rendered
+}
+
+// NewArtifactStore creates a store writing to dir
+func NewArtifactStore(dir string) *ArtifactStore {
+	return &ArtifactStore{
+		dir:      dir,
+		rendered: make(map[string]*Rendered),
+	}
+}
+
+func (s *ArtifactStore) latestPath() string {
+	return filepath.Join(s.dir, "library.json")
+}
+
+// Write stores a new artifact version and makes it the latest
+func (s *ArtifactStore) Write(a *Artifact) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create playlist dir: %w", err)
+	}
+
+	// The version is a content hash, so regenerating an unchanged library keeps ETags valid
+	generatedAt := a.GeneratedAt
+	a.Version, a.GeneratedAt = "", time.Time{}
+	content, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to marshal playlist: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	a.Version, a.GeneratedAt = hex.EncodeToString(sum[:8]), generatedAt
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal playlist: %w", err)
+	}
+
+	versioned := filepath.Join(s.dir, fmt.Sprintf("library-%s.json", a.Version))
+	if err := writeFileAtomic(versioned, data); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.latestPath(), data); err != nil {
+		return err
+	}
+
+	m3u := RenderM3U(a, Variant{Format: "m3u_plus", ServerURL: "{SERVER}", Username: "{USERNAME}", Password: "{PASSWORD}"})
+	if err := writeFileAtomic(filepath.Join(s.dir, fmt.Sprintf("library-%s.m3u8", a.Version)), m3u); err != nil {
+		return err
+	}
+
+	s.pruneVersions()
+
+	s.mu.Lock()
+	s.setCurrent(a, time.Now())
+	s.mu.Unlock()
+
+	log.Printf("[PLAYLIST] Wrote playlist version %s (%d movies, %d series, %d channels)", a.Version, len(a.Movies), len(a.Series), len(a.Live))
+	return nil
+}
+
+// Current returns the latest artifact, reloading it if the file changed; nil if none was generated yet
+func (s *ArtifactStore) Current() *Artifact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.latestPath())
+	if err != nil {
+		return s.current
+	}
+	if s.current != nil && !info.ModTime().After(s.loadedAt) {
+		return s.current
+	}
+
+	data, err := os.ReadFile(s.latestPath())
+	if err != nil {
+		log.Printf("[PLAYLIST] Failed to read playlist: %v", err)
+		return s.current
+	}
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		log.Printf("[PLAYLIST] Failed to parse playlist: %v", err)
+		return s.current
+	}
+	s.setCurrent(&a, info.ModTime())
+	return s.current
+}
+
+// Render returns a variant of the current artifact, rendering and compressing it once per version
+func (s *ArtifactStore) Render(v Variant) (*Rendered, bool) {
+	a := s.Current()
+	if a == nil {
+		return nil, false
+	}
+
+	sort.Strings(v.Content)
+	key := fmt.Sprintf("%s|%s|%s|%s|%s|%s|%v|%v", a.Version, v.ServerURL, v.Username, v.Password, v.Format, strings.Join(v.Content, ","), v.OnlyCached, v.OnlyReleased)
+
+	s.mu.Lock()
+	if r, ok := s.rendered[key]; ok {
+		s.mu.Unlock()
+		return r, true
+	}
+	s.mu.Unlock()
+
+	r := &Rendered{ContentType: "audio/x-mpegurl"}
+	if v.Format == "json" {
+		r.ContentType = "application/json"
+		r.Body, _ = json.Marshal(Filter(a, v))
+	} else {
+		r.Body = RenderM3U(a, v)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(r.Body)
+	zw.Close()
+	r.Gzip = buf.Bytes()
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	r.ETag = fmt.Sprintf("\"%s-%x\"", a.Version, h.Sum64())
+
+	s.mu.Lock()
+	if len(s.rendered) >= maxRenderedVariants {
+		s.rendered = make(map[string]*Rendered)
+	}
+	s.rendered[key] = r
+	s.mu.Unlock()
+
+	return r, true
+}
+
+// setCurrent replaces the loaded artifact; callers hold s.mu
+func (s *ArtifactStore) setCurrent(a *Artifact, loadedAt time.Time) {
+	if s.current == nil || s.current.Version != a.Version {
+		s.rendered = make(map[string]*Rendered)
+	}
+	s.current = a
+	s.loadedAt = loadedAt
+}
+
+// pruneVersions removes all but the newest versioned files
+func (s *ArtifactStore) pruneVersions() {
+	for _, pattern := range []string{"library-*.json", "library-*.m3u8"} {
+		files, err := filepath.Glob(filepath.Join(s.dir, pattern))
+		if err != nil || len(files) <= keepArtifactVersions {
+			continue
+		}
+		sort.Slice(files, func(i, j int) bool {
+			fi, _ := os.Stat(files[i])
+			fj, _ := os.Stat(files[j])
+			if fi == nil || fj == nil {
+				return false
+			}
+			return fi.ModTime().After(fj.ModTime())
+		})
+		for _, f := range files[keepArtifactVersions:] {
+			os.Remove(f)
+		}
+	}
+}
+
+// Filter returns the part of an artifact a variant includes
+func Filter(a *Artifact, v Variant) *Artifact {
+	out := &Artifact{Version: a.Version, GeneratedAt: a.GeneratedAt}
+
+	if v.wants("movies") {
+		for _, m := range a.Movies {
+			if v.OnlyCached && !m.Cached || !v.OnlyCached && !m.Monitored {
+				continue
+			}
+			if v.OnlyReleased && !m.Released {
+				continue
+			}
+			out.Movies = append(out.Movies, m)
+		}
+	}
+
+	if v.wants("series") {
+		for _, s := range a.Series {
+			if v.OnlyCached && !s.Cached || !v.OnlyCached && !s.Monitored {
+				continue
+			}
+			series := s
+			series.Episodes = nil
+			for _, e := range s.Episodes {
+				if v.OnlyCached && !e.Cached || v.OnlyReleased && !e.Released {
+					continue
+				}
+				series.Episodes = append(series.Episodes, e)
+			}
+			if len(series.Episodes) > 0 {
+				out.Series = append(out.Series, series)
+			}
+		}
+	}
+
+	if v.wants("live") {
+		out.Live = a.Live
+	}
+
+	return out
+}
+
+func (v Variant) wants(content string) bool {
+	if len(v.Content) == 0 {
+		return true
+	}
+	for _, c := range v.Content {
+		if c == content {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderM3U renders a variant as an M3U playlist with one entry per movie, episode and channel
+func RenderM3U(a *Artifact, v Variant) []byte {
+	f := Filter(a, v)
+	plus := v.Format != "m3u"
+
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n")
+
+	extinf := func(id, name, logo, group string) {
+		if plus {
+			fmt.Fprintf(&sb, "#EXTINF:-1 tvg-id=\"%s\" tvg-name=\"%s\" tvg-logo=\"%s\" group-title=\"%s\",%s\n", id, name, logo, group, name)
+		} else {
+			fmt.Fprintf(&sb, "#EXTINF:-1,%s\n", name)
+		}
+	}
+
+	for _, m := range f.Movies {
+		name := m.Title
+		if m.Year > 0 {
+			name = fmt.Sprintf("%s (%d)", m.Title, m.Year)
+		}
+		extinf(fmt.Sprintf("movie_%d", m.TMDBID), name, m.Logo, "Movies")
+		fmt.Fprintf(&sb, "%s/movie/%s/%s/%d.mp4\n", v.ServerURL, v.Username, v.Password, m.TMDBID)
+	}
+
+	for _, s := range f.Series {
+		for _, e := range s.Episodes {
+			name := fmt.Sprintf("%s S%02dE%02d", s.Title, e.Season, e.Episode)
+			if e.Title != "" {
+				name += " - " + e.Title
+			}
+			extinf(fmt.Sprintf("series_%d_%d_%d", s.TMDBID, e.Season, e.Episode), name, s.Logo, s.Title)
+			fmt.Fprintf(&sb, "%s/series/%s/%s/%d.mp4\n", v.ServerURL, v.Username, v.Password, e.ID)
+		}
+	}
+
+	for _, ch := range f.Live {
+		group := "Live TV"
+		if ch.Group != "" {
+			group = ch.Group
+		}
+		extinf(ch.ID, ch.Name, ch.Logo, group)
+		fmt.Fprintf(&sb, "%s/live/%s/%s/%d.m3u8\n", v.ServerURL, v.Username, v.Password, ch.Num)
+	}
+
+	return []byte(sb.String())
+}
+
+// writeFileAtomic writes to a temp file in the same directory and renames it into place,
+// so readers never see a partially written playlist
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -6,11 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/Zerr0-C00L/StreamArr/internal/config"
+	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
 	"github.com/Zerr0-C00L/StreamArr/internal/models"
 	"github.com/Zerr0-C00L/StreamArr/internal/providers"
 	"github.com/Zerr0-C00L/StreamArr/internal/services"
@@ -25,6 +25,8 @@ type EnhancedGenerator struct {
 	enableVariants  bool
 	qualityVariants []string
 	streamCache     map[string][]StreamInfo // IMDB ID -> streams
+	artifacts       *ArtifactStore          // nil disables the library playlist
+	channelManager  *livetv.ChannelManager
 }
 
 type StreamInfo struct {
@@ -167,6 +169,11 @@ func (eg *EnhancedGenerator) GenerateComplete(ctx context.Context) error {
 		}
 	}
 	
+	// Library playlist served by get.php
+	if err := eg.GenerateLibrary(ctx); err != nil {
+		log.Printf("Error saving library playlist: %v", err)
+	}
+	
 	log.Println("🎉 Playlist generation complete!")
 	return nil
 }
@@ -419,7 +426,7 @@ func (eg *EnhancedGenerator) SaveMoviePlaylist(entries []MoviePlaylistEntry) err
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 	
-	if err := os.WriteFile("playlist.json", jsonData, 0644); err != nil {
+	if err := writeFileAtomic("playlist.json", jsonData); err != nil {
 		return fmt.Errorf("failed to write JSON: %w", err)
 	}
 	
@@ -430,7 +437,7 @@ func (eg *EnhancedGenerator) SaveMoviePlaylist(entries []MoviePlaylistEntry) err
 			entry.Group, entry.Name, entry.StreamIcon, entry.Name, entry.DirectSource)
 	}
 	
-	if err := os.WriteFile("playlist.m3u8", []byte(m3u8Content), 0644); err != nil {
+	if err := writeFileAtomic("playlist.m3u8", []byte(m3u8Content)); err != nil {
 		return fmt.Errorf("failed to write M3U8: %w", err)
 	}
 	
@@ -444,7 +451,7 @@ func (eg *EnhancedGenerator) SaveTVPlaylist(entries []SeriesEntry) error {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 	
-	if err := os.WriteFile("tv_playlist.json", jsonData, 0644); err != nil {
+	if err := writeFileAtomic("tv_playlist.json", jsonData); err != nil {
 		return fmt.Errorf("failed to write JSON: %w", err)
 	}
 	
@@ -165,8 +165,10 @@ func (pg *PlaylistGenerator) SavePlaylistJSON(entries []SimplePlaylistEntry, fil
 		return fmt.Errorf("marshal JSON: %w", err)
 	}
 	
-	// Would write to file here
-	log.Printf("Would save %d bytes to %s", len(data), filename)
+	if err := writeFileAtomic(filename, data); err != nil {
+		return err
+	}
+	log.Printf("Saved %d playlist entries to %s", len(entries), filename)
 	return nil
 }
 
@@ -185,6 +187,9 @@ func (pg *PlaylistGenerator) SavePlaylistM3U8(entries []SimplePlaylistEntry, fil
 		}
 	}
 	
-	log.Printf("Would save M3U8 with %d entries to %s", len(entries), filename)
+	if err := writeFileAtomic(filename, []byte(sb.String())); err != nil {
+		return err
+	}
+	log.Printf("Saved M3U8 with %d entries to %s", len(entries), filename)
 	return nil
 }
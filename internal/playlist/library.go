@@ -0,0 +1,203 @@
+package playlist
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+)
+
+// SetArtifactStore enables writing the library playlist served by get.php
+func (eg *EnhancedGenerator) SetArtifactStore(store *ArtifactStore) {
+	eg.artifacts = store
+}
+
+// SetChannelManager includes Live TV channels in the library playlist
+func (eg *EnhancedGenerator) SetChannelManager(cm *livetv.ChannelManager) {
+	eg.channelManager = cm
+}
+
+// GenerateLibrary builds the library playlist (movies, series with per-episode entries, live)
+// and writes it as a new artifact version
+func (eg *EnhancedGenerator) GenerateLibrary(ctx context.Context) error {
+	if eg.artifacts == nil {
+		return nil
+	}
+
+	movies, err := eg.libraryMovies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load movies: %w", err)
+	}
+	series, err := eg.librarySeries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load series: %w", err)
+	}
+
+	return eg.artifacts.Write(&Artifact{
+		GeneratedAt: time.Now(),
+		Movies:      movies,
+		Series:      series,
+		Live:        eg.liveChannels(),
+	})
+}
+
+// RefreshLive rewrites the current artifact with the current channel list.
+// Live stream IDs are channel positions, so the playlist must follow channel reloads.
+func (eg *EnhancedGenerator) RefreshLive() error {
+	if eg.artifacts == nil {
+		return nil
+	}
+	current := eg.artifacts.Current()
+	if current == nil {
+		return nil
+	}
+
+	updated := *current
+	updated.GeneratedAt = time.Now()
+	updated.Live = eg.liveChannels()
+	return eg.artifacts.Write(&updated)
+}
+
+func (eg *EnhancedGenerator) liveChannels() []ArtifactChannel {
+	if eg.channelManager == nil {
+		return nil
+	}
+
+	channels := eg.channelManager.GetAllChannels()
+	live := make([]ArtifactChannel, 0, len(channels))
+	for i, ch := range channels {
+		live = append(live, ArtifactChannel{
+			Num:   i + 1,
+			ID:    ch.ID,
+			Name:  ch.Name,
+			Logo:  ch.Logo,
+			Group: ch.Category,
+		})
+	}
+	return live
+}
+
+func (eg *EnhancedGenerator) libraryMovies(ctx context.Context) ([]ArtifactMovie, error) {
+	// Movies without a release date count as released once their year has passed
+	query := `
+		SELECT m.tmdb_id, m.title, m.year, m.metadata, COALESCE(m.monitored, false),
+			EXISTS(SELECT 1 FROM media_streams ms WHERE ms.movie_id = m.id),
+			COALESCE(NULLIF(m.metadata->>'release_date', '')::date <= CURRENT_DATE, m.year < EXTRACT(YEAR FROM CURRENT_DATE), false)
+		FROM library_movies m
+		ORDER BY m.title
+	`
+	rows, err := eg.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movies []ArtifactMovie
+	for rows.Next() {
+		var m ArtifactMovie
+		var year sql.NullInt64
+		var metadataJSON []byte
+		if err := rows.Scan(&m.TMDBID, &m.Title, &year, &metadataJSON, &m.Monitored, &m.Cached, &m.Released); err != nil {
+			continue
+		}
+		if !m.Monitored && !m.Cached {
+			continue
+		}
+		m.Year = int(year.Int64)
+		m.Logo = posterFromMetadata(metadataJSON)
+		movies = append(movies, m)
+	}
+	return movies, rows.Err()
+}
+
+func (eg *EnhancedGenerator) librarySeries(ctx context.Context) ([]ArtifactSeries, error) {
+	query := `
+		SELECT s.id, s.tmdb_id, COALESCE(s.imdb_id, ''), s.title, s.year, s.metadata, COALESCE(s.monitored, false),
+			EXISTS(SELECT 1 FROM media_streams ms WHERE ms.series_id = s.id)
+		FROM library_series s
+		ORDER BY s.title
+	`
+	rows, err := eg.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var series []ArtifactSeries
+	index := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var s ArtifactSeries
+		var year sql.NullInt64
+		var metadataJSON []byte
+		if err := rows.Scan(&id, &s.TMDBID, &s.IMDBID, &s.Title, &year, &metadataJSON, &s.Monitored, &s.Cached); err != nil {
+			continue
+		}
+		if !s.Monitored && !s.Cached {
+			continue
+		}
+		s.Year = int(year.Int64)
+		s.Logo = posterFromMetadata(metadataJSON)
+		if s.IMDBID == "" {
+			s.IMDBID = imdbFromMetadata(metadataJSON)
+		}
+		index[id] = len(series)
+		series = append(series, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	episodeQuery := `
+		SELECT e.series_id, e.tmdb_id, e.season_number, e.episode_number, COALESCE(e.title, ''),
+			EXISTS(SELECT 1 FROM media_streams ms
+				WHERE ms.series_id = e.series_id AND ms.season = e.season_number AND ms.episode = e.episode_number),
+			COALESCE(e.air_date <= CURRENT_DATE, false)
+		FROM library_episodes e
+		WHERE e.season_number > 0 AND e.tmdb_id IS NOT NULL
+		ORDER BY e.series_id, e.season_number, e.episode_number
+	`
+	episodeRows, err := eg.db.QueryContext(ctx, episodeQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer episodeRows.Close()
+
+	for episodeRows.Next() {
+		var seriesID int64
+		var e ArtifactEpisode
+		if err := episodeRows.Scan(&seriesID, &e.ID, &e.Season, &e.Episode, &e.Title, &e.Cached, &e.Released); err != nil {
+			continue
+		}
+		if i, ok := index[seriesID]; ok {
+			series[i].Episodes = append(series[i].Episodes, e)
+		}
+	}
+	return series, episodeRows.Err()
+}
+
+func posterFromMetadata(metadataJSON []byte) string {
+	var metadata map[string]interface{}
+	json.Unmarshal(metadataJSON, &metadata)
+	if poster, ok := metadata["poster_path"].(string); ok && poster != "" {
+		return "https://image.tmdb.org/t/p/w500" + poster
+	}
+	return ""
+}
+
+func imdbFromMetadata(metadataJSON []byte) string {
+	var metadata map[string]interface{}
+	json.Unmarshal(metadataJSON, &metadata)
+	if extIDs, ok := metadata["external_ids"].(map[string]interface{}); ok {
+		if id, ok := extIDs["imdb_id"].(string); ok && id != "" {
+			return id
+		}
+	}
+	if id, ok := metadata["imdb_id"].(string); ok {
+		return id
+	}
+	return ""
+}
@@ -10,8 +10,9 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
 )
 
 // DMMDirectProvider queries DMM API for pre-scraped torrents
@@ -184,8 +185,10 @@ func (d *DMMDirectProvider) queryDMMAPI(url, mediaType string) ([]TorrentioStrea
 			Size:     int64(result.FileSize * 1024 * 1024), // Convert MB to bytes
 		}
 		
-		// Extract quality from title
-		stream.Quality = extractQualityFromTitle(result.Title)
+		stream.Quality = release.Parse(result.Title).Resolution
+		if stream.Quality == "" {
+			stream.Quality = "Unknown"
+		}
 
 		streams = append(streams, stream)
 	}
@@ -204,17 +207,3 @@ func (d *DMMDirectProvider) generateDMMToken() (string, string) {
 	
 	return timestamp, hashStr
 }
-
-// extractQualityFromTitle extracts quality info from title
-func extractQualityFromTitle(title string) string {
-	title = strings.ToUpper(title)
-	
-	qualities := []string{"2160P", "4K", "UHD", "1080P", "720P", "480P"}
-	for _, q := range qualities {
-		if strings.Contains(title, q) {
-			return q
-		}
-	}
-	
-	return "Unknown"
-}
@@ -1,13 +1,16 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"regexp"
-	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
 	"github.com/Zerr0-C00L/StreamArr/internal/services"
+	"github.com/Zerr0-C00L/StreamArr/internal/tracing"
 )
 
 // TorrentioStream represents a stream from Stremio addons (kept for compatibility)
@@ -101,6 +104,13 @@ func NewMultiProviderWithConfig(rdAPIKey string, addons []StremioAddon, tmdbClie
 	return mp
 }
 
+// AddProvider registers an additional stream provider (e.g. a Torznab indexer)
+func (mp *MultiProvider) AddProvider(name string, provider StreamProvider) {
+	mp.Providers = append(mp.Providers, provider)
+	mp.ProviderNames = append(mp.ProviderNames, name)
+	log.Printf("Loaded stream provider: %s", name)
+}
+
 // SetSortSettings configures the sorting behavior dynamically
 func (mp *MultiProvider) SetSortSettings(getSortOrder, getSortPrefer func() string) {
 	mp.getSortOrder = getSortOrder
@@ -124,7 +134,7 @@ type StreamResponse struct {
 
 // GetStreams is the main method that follows Stremio SDK pattern
 // It handles both movies and series with proper type validation
-func (mp *MultiProvider) GetStreams(req StreamRequest) ([]TorrentioStream, error) {
+func (mp *MultiProvider) GetStreams(ctx context.Context, req StreamRequest) ([]TorrentioStream, error) {
 	// Validate request
 	if req.Type != "movie" && req.Type != "series" {
 		return nil, fmt.Errorf("invalid type: %s, must be 'movie' or 'series'", req.Type)
@@ -136,20 +146,20 @@ func (mp *MultiProvider) GetStreams(req StreamRequest) ([]TorrentioStream, error
 	
 	// Route to appropriate handler
 	if req.Type == "movie" {
-		return mp.GetMovieStreamsWithYear(req.ID, req.ReleaseYear)
+		return mp.GetMovieStreamsWithYear(ctx, req.ID, req.ReleaseYear)
 	} else if req.Type == "series" {
 		if req.Season < 0 || req.Episode < 0 {
 			return nil, fmt.Errorf("season and episode must be >= 0 for series")
 		}
-		return mp.GetSeriesStreams(req.ID, req.Season, req.Episode)
+		return mp.GetSeriesStreams(ctx, req.ID, req.Season, req.Episode)
 	}
 	
 	return nil, fmt.Errorf("unknown type: %s", req.Type)
 }
 
 // GetMovieStreamsWithYear fetches movie streams and filters by release year
-func (mp *MultiProvider) GetMovieStreamsWithYear(imdbID string, releaseYear int) ([]TorrentioStream, error) {
-	streams, err := mp.GetMovieStreams(imdbID)
+func (mp *MultiProvider) GetMovieStreamsWithYear(ctx context.Context, imdbID string, releaseYear int) ([]TorrentioStream, error) {
+	streams, err := mp.GetMovieStreams(ctx, imdbID)
 	if err != nil {
 		return nil, err
 	}
@@ -187,7 +197,7 @@ func (mp *MultiProvider) GetMovieStreamsWithYear(imdbID string, releaseYear int)
 	return filtered, nil
 }
 
-func (mp *MultiProvider) GetMovieStreams(imdbID string) ([]TorrentioStream, error) {
+func (mp *MultiProvider) GetMovieStreams(ctx context.Context, imdbID string) ([]TorrentioStream, error) {
 	var lastErr error
 	var allStreams []TorrentioStream
 	
@@ -196,7 +206,9 @@ func (mp *MultiProvider) GetMovieStreams(imdbID string) ([]TorrentioStream, erro
 	for i, provider := range mp.Providers {
 		providerName := mp.ProviderNames[i]
 		
-		streams, err := provider.GetMovieStreams(imdbID)
+		streams, err := mp.lookup(ctx, providerName, "movie", func() ([]TorrentioStream, error) {
+			return provider.GetMovieStreams(imdbID)
+		})
 		if err != nil {
 			log.Printf("[PROVIDER] %s failed for movie %s: %v", providerName, imdbID, err)
 			lastErr = err
@@ -225,17 +237,19 @@ func (mp *MultiProvider) GetMovieStreams(imdbID string) ([]TorrentioStream, erro
 		return nil, fmt.Errorf("all providers failed, last error: %w", lastErr)
 	}
 	
-	return allStreams, nil
+	return localFirst(allStreams), nil
 }
 
-func (mp *MultiProvider) GetSeriesStreams(imdbID string, season, episode int) ([]TorrentioStream, error) {
+func (mp *MultiProvider) GetSeriesStreams(ctx context.Context, imdbID string, season, episode int) ([]TorrentioStream, error) {
 	var lastErr error
 	var allStreams []TorrentioStream
 	
 	for i, provider := range mp.Providers {
 		providerName := mp.ProviderNames[i]
 		
-		streams, err := provider.GetSeriesStreams(imdbID, season, episode)
+		streams, err := mp.lookup(ctx, providerName, "series", func() ([]TorrentioStream, error) {
+			return provider.GetSeriesStreams(imdbID, season, episode)
+		})
 		if err != nil {
 			log.Printf("Provider %s failed for series %s S%02dE%02d: %v", providerName, imdbID, season, episode, err)
 			lastErr = err
@@ -252,17 +266,42 @@ func (mp *MultiProvider) GetSeriesStreams(imdbID string, season, episode int) ([
 		return nil, fmt.Errorf("all providers failed, last error: %w", lastErr)
 	}
 	
-	return allStreams, nil
+	return localFirst(allStreams), nil
+}
+
+// lookup asks one provider for streams, recording its latency and result in the metrics and,
+// within a traced request or job, a span
+func (mp *MultiProvider) lookup(ctx context.Context, name, kind string, fetch func() ([]TorrentioStream, error)) ([]TorrentioStream, error) {
+	_, span := tracing.Start(ctx, "provider "+name, tracing.KindClient)
+	span.Set("provider.name", name)
+	span.Set("provider.kind", kind)
+	defer span.End()
+
+	start := time.Now()
+	streams, err := fetch()
+	metrics.ProviderDuration.WithLabelValues(name, kind).Observe(metrics.Since(start))
+
+	result := "ok"
+	switch {
+	case err != nil:
+		result = "error"
+	case len(streams) == 0:
+		result = "empty"
+	}
+	metrics.ProviderRequests.WithLabelValues(name, kind, result).Inc()
+	span.Set("provider.streams", len(streams))
+	span.Fail(err)
+	return streams, err
 }
 
-func (mp *MultiProvider) GetBestStream(imdbID string, season, episode *int, maxQuality int) (*TorrentioStream, error) {
+func (mp *MultiProvider) GetBestStream(ctx context.Context, imdbID string, season, episode *int, maxQuality int) (*TorrentioStream, error) {
 	var streams []TorrentioStream
 	var err error
 	
 	if season != nil && episode != nil {
-		streams, err = mp.GetSeriesStreams(imdbID, *season, *episode)
+		streams, err = mp.GetSeriesStreams(ctx, imdbID, *season, *episode)
 	} else {
-		streams, err = mp.GetMovieStreams(imdbID)
+		streams, err = mp.GetMovieStreams(ctx, imdbID)
 	}
 	
 	if err != nil {
@@ -273,6 +312,12 @@ func (mp *MultiProvider) GetBestStream(imdbID string, season, episode *int, maxQ
 		return nil, fmt.Errorf("no streams found")
 	}
 	
+	// Local files always win over remote streams (largest file first)
+	if streams[0].IsLocal() {
+		log.Printf("[SORT] Using local file: %s", streams[0].Title)
+		return &streams[0], nil
+	}
+	
 	// Prioritize cached streams, then accept uncached
 	filteredStreams := make([]TorrentioStream, 0)
 	for _, s := range streams {
@@ -458,44 +503,25 @@ func filterMovieStreams(streams []TorrentioStream) []TorrentioStream {
 
 // isEpisodeStream checks if a stream appears to be from a TV series episode
 func isEpisodeStream(stream TorrentioStream) bool {
-	// Combine all text fields to search
-	fullText := strings.ToLower(fmt.Sprintf("%s %s %s", stream.Name, stream.Title, stream.Source))
-	
-	// Check for season/episode patterns
-	episodePatterns := []string{
-		"s\\d+e\\d+",     // S01E01
-		"\\d+x\\d+",      // 1x01
-		"season.*episode", // Season 1 Episode 1
-		"ep\\d+",         // EP01
-		": s\\d+:",       // : S01:
-	}
-	
-	for _, pattern := range episodePatterns {
-		if matched, _ := regexp.MatchString(pattern, fullText); matched {
+	for _, text := range []string{stream.Title, stream.Name, stream.Source} {
+		if release.Parse(text).IsEpisode() {
 			return true
 		}
 	}
-	
 	return false
 }
 
 // extractYearFromStream extracts the year from stream title/filename
 func extractYearFromStream(stream TorrentioStream) int {
-	// Search in title and name for 4-digit year
-	fullText := fmt.Sprintf("%s %s", stream.Title, stream.Name)
-	
-	// Look for years between 1900 and 2100
-	yearPattern := regexp.MustCompile(`\b(19|20)\d{2}\b`)
-	matches := yearPattern.FindAllString(fullText, -1)
-	
-	if len(matches) > 0 {
-		// Return the first year found
-		if year, err := strconv.Atoi(matches[0]); err == nil {
-			log.Printf("[YEAR-EXTRACT] Found year %d in stream: %s", year, stream.Title)
-			return year
-		}
+	year := release.Parse(stream.Title).Year
+	if year == 0 {
+		year = release.Parse(stream.Name).Year
 	}
 	
-	log.Printf("[YEAR-EXTRACT] No year found in stream: %s", stream.Title)
-	return 0
+	if year > 0 {
+		log.Printf("[YEAR-EXTRACT] Found year %d in stream: %s", year, stream.Title)
+	} else {
+		log.Printf("[YEAR-EXTRACT] No year found in stream: %s", stream.Title)
+	}
+	return year
 }
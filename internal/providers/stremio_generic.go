@@ -11,10 +11,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
 )
 
 // GenericStremioProvider works with any Stremio addon that follows the standard protocol
@@ -421,53 +422,5 @@ func (g *GenericStremioProvider) convertToTorrentioStreams(genericStreams []Gene
 
 // parseSizeFromDescription extracts file size from description text like "💾 1.5 GB" or "💾 500 MB"
 func parseSizeFromDescription(desc string) int64 {
-	// Look for size pattern in description
-	// Common formats: "💾 1.5 GB", "💾 500 MB", "Size: 1.5GB"
-	desc = strings.ToUpper(desc)
-	
-	// Find GB pattern
-	if idx := strings.Index(desc, "GB"); idx > 0 {
-		// Look backwards for the number
-		numStr := extractNumberBefore(desc, idx)
-		if num, err := strconv.ParseFloat(numStr, 64); err == nil {
-			return int64(num * 1024 * 1024 * 1024)
-		}
-	}
-	
-	// Find MB pattern
-	if idx := strings.Index(desc, "MB"); idx > 0 {
-		numStr := extractNumberBefore(desc, idx)
-		if num, err := strconv.ParseFloat(numStr, 64); err == nil {
-			return int64(num * 1024 * 1024)
-		}
-	}
-	
-	return 0
-}
-
-// extractNumberBefore extracts a number from the string before the given index
-func extractNumberBefore(s string, idx int) string {
-	// Go backwards from idx to find the number
-	end := idx
-	for end > 0 && s[end-1] == ' ' {
-		end--
-	}
-	
-	start := end
-	hasDecimal := false
-	for start > 0 {
-		c := s[start-1]
-		if c >= '0' && c <= '9' {
-			start--
-		} else if c == '.' && !hasDecimal {
-			hasDecimal = true
-			start--
-		} else if c == ' ' || c == ':' || c == 160 { // 160 is non-breaking space
-			break
-		} else {
-			break
-		}
-	}
-	
-	return strings.TrimSpace(s[start:end])
+	return release.ParseSize(desc)
 }
@@ -0,0 +1,330 @@
+package providers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
+	"github.com/Zerr0-C00L/StreamArr/internal/services"
+	"github.com/Zerr0-C00L/StreamArr/internal/services/debrid"
+)
+
+// TorznabProvider queries a Torznab-compatible indexer (Jackett, Prowlarr) directly
+type TorznabProvider struct {
+	Name       string
+	BaseURL    string // Full Torznab endpoint, e.g. http://jackett:9117/api/v2.0/indexers/all/results/torznab
+	APIKey     string
+	Client     *http.Client
+	debrid     debrid.DebridService
+	tmdbClient *services.TMDBClient
+}
+
+// torznabFeed is the RSS document returned by Torznab search endpoints. Errors come back as an
+// <error code="..." description="..."/> document instead, read into Code and Description.
+type torznabFeed struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []torznabItem `xml:"item"`
+	} `xml:"channel"`
+	Code        int    `xml:"code,attr"`
+	Description string `xml:"description,attr"`
+}
+
+type torznabItem struct {
+	Title     string `xml:"title"`
+	GUID      string `xml:"guid"`
+	Link      string `xml:"link"`
+	Size      int64  `xml:"size"`
+	Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+	} `xml:"enclosure"`
+	Attrs []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"attr"`
+}
+
+// NewTorznabProvider creates a provider for one Torznab indexer
+// debridService and tmdbClient are optional; without debrid no result is marked cached,
+// without TMDB only IMDB ID searches are made
+func NewTorznabProvider(name, baseURL, apiKey string, debridService debrid.DebridService, tmdbClient *services.TMDBClient) *TorznabProvider {
+	return &TorznabProvider{
+		Name:    name,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		debrid:     debridService,
+		tmdbClient: tmdbClient,
+	}
+}
+
+// GetMovieStreams searches the indexer by IMDB ID, falling back to title and year
+func (t *TorznabProvider) GetMovieStreams(imdbID string) ([]TorrentioStream, error) {
+	params := url.Values{}
+	params.Set("t", "movie")
+	params.Set("imdbid", strings.TrimPrefix(imdbID, "tt"))
+
+	items, err := t.search(params)
+	if err != nil {
+		log.Printf("[TORZNAB] %s: IMDB search failed for %s: %v", t.Name, imdbID, err)
+	}
+
+	if len(items) == 0 {
+		if title, year := lookupTitle(t.tmdbClient, imdbID, "movie"); title != "" {
+			params = url.Values{}
+			params.Set("t", "movie")
+			params.Set("q", strings.TrimSpace(fmt.Sprintf("%s %s", title, yearString(year))))
+			items, err = t.search(params)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	streams := t.convertItems(items)
+	log.Printf("[TORZNAB] %s returned %d streams for movie %s", t.Name, len(streams), imdbID)
+	return t.markCached(streams), nil
+}
+
+// GetSeriesStreams searches for the episode and for season packs containing it
+func (t *TorznabProvider) GetSeriesStreams(imdbID string, season, episode int) ([]TorrentioStream, error) {
+	var items []torznabItem
+	var lastErr error
+
+	// Episode first, then the whole season so packs are included
+	for _, withEpisode := range []bool{true, false} {
+		params := url.Values{}
+		params.Set("t", "tvsearch")
+		params.Set("imdbid", strings.TrimPrefix(imdbID, "tt"))
+		params.Set("season", strconv.Itoa(season))
+		if withEpisode {
+			params.Set("ep", strconv.Itoa(episode))
+		}
+
+		found, err := t.search(params)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		items = append(items, found...)
+	}
+
+	if len(items) == 0 {
+		if title, _ := lookupTitle(t.tmdbClient, imdbID, "tv"); title != "" {
+			params := url.Values{}
+			params.Set("t", "tvsearch")
+			params.Set("q", title)
+			params.Set("season", strconv.Itoa(season))
+			found, err := t.search(params)
+			if err != nil {
+				lastErr = err
+			}
+			items = append(items, found...)
+		}
+	}
+
+	if len(items) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	// Keep only releases that actually contain the requested episode
+	var streams []TorrentioStream
+	seen := make(map[string]bool)
+	for _, stream := range t.convertItems(items) {
+		if seen[stream.InfoHash] {
+			continue
+		}
+		seen[stream.InfoHash] = true
+		if release.Parse(stream.Title).HasEpisode(season, episode) {
+			streams = append(streams, stream)
+		}
+	}
+
+	log.Printf("[TORZNAB] %s returned %d streams for series %s S%02dE%02d", t.Name, len(streams), imdbID, season, episode)
+	return t.markCached(streams), nil
+}
+
+// search runs a Torznab query and returns the raw items
+func (t *TorznabProvider) search(params url.Values) ([]torznabItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	defer cancel()
+
+	if t.APIKey != "" {
+		params.Set("apikey", t.APIKey)
+	}
+
+	endpoint := t.BaseURL
+	if !strings.HasSuffix(endpoint, "/api") && !strings.Contains(endpoint, "/torznab") {
+		endpoint += "/api"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("indexer returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var feed torznabFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to decode torznab response: %w", err)
+	}
+
+	if feed.XMLName.Local == "error" {
+		return nil, fmt.Errorf("torznab error %d: %s", feed.Code, feed.Description)
+	}
+
+	return feed.Channel.Items, nil
+}
+
+// convertItems normalises Torznab items into TorrentioStream. Streams carry only the info hash, which
+// playback resolves through debrid; a magnet is no use to a player. Items without an info hash (a
+// .torrent download link only) are dropped.
+func (t *TorznabProvider) convertItems(items []torznabItem) []TorrentioStream {
+	streams := make([]TorrentioStream, 0, len(items))
+	var skipped []string
+
+	for _, item := range items {
+		attrs := make(map[string]string, len(item.Attrs))
+		for _, attr := range item.Attrs {
+			attrs[strings.ToLower(attr.Name)] = attr.Value
+		}
+
+		hash := strings.ToLower(attrs["infohash"])
+		for _, link := range []string{attrs["magneturl"], item.Link, item.Enclosure.URL} {
+			if hash == "" && strings.HasPrefix(link, "magnet:") {
+				hash = MagnetInfoHash(link)
+			}
+		}
+		if hash == "" {
+			skipped = append(skipped, item.Title)
+			continue
+		}
+
+		size := item.Size
+		if size == 0 {
+			size, _ = strconv.ParseInt(attrs["size"], 10, 64)
+		}
+		if size == 0 {
+			size = item.Enclosure.Length
+		}
+
+		seeders, _ := strconv.Atoi(attrs["seeders"])
+
+		info := release.Parse(item.Title)
+		quality := info.Resolution
+		if quality == "" {
+			quality = "Unknown"
+		}
+
+		streams = append(streams, TorrentioStream{
+			Name:     fmt.Sprintf("%s\n%s", t.Name, quality),
+			Title:    item.Title,
+			InfoHash: hash,
+			Quality:  quality,
+			Size:     size,
+			Seeders:  seeders,
+			Source:   t.Name,
+		})
+	}
+
+	if len(skipped) > 0 {
+		log.Printf("[TORZNAB] %s: skipped %d results without an info hash, e.g. %q", t.Name, len(skipped), skipped[0])
+	}
+	return streams
+}
+
+// markCached checks all hashes against the debrid service in one call
+func (t *TorznabProvider) markCached(streams []TorrentioStream) []TorrentioStream {
+	if t.debrid == nil || len(streams) == 0 {
+		return streams
+	}
+
+	hashes := make([]string, len(streams))
+	for i, s := range streams {
+		hashes[i] = s.InfoHash
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cached, err := t.debrid.CheckCache(ctx, hashes)
+	if err != nil {
+		log.Printf("[TORZNAB] %s: debrid cache check failed: %v", t.Name, err)
+		return streams
+	}
+
+	for i := range streams {
+		streams[i].Cached = cached[streams[i].InfoHash]
+	}
+	return streams
+}
+
+// lookupTitle resolves an IMDB ID to a title and year via TMDB for providers that search by title
+func lookupTitle(tmdbClient *services.TMDBClient, imdbID, mediaType string) (string, int) {
+	if tmdbClient == nil {
+		return "", 0
+	}
+
+	tmdbID, err := tmdbClient.IMDBToTMDB(imdbID, mediaType)
+	if err != nil {
+		return "", 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if mediaType == "movie" {
+		movie, err := tmdbClient.GetMovie(ctx, tmdbID)
+		if err != nil {
+			return "", 0
+		}
+		return movie.Title, movie.Year
+	}
+
+	series, err := tmdbClient.GetSeries(ctx, tmdbID)
+	if err != nil {
+		return "", 0
+	}
+	return series.Title, series.Year
+}
+
+// MagnetInfoHash extracts the BTIH info hash from a magnet link; "" when it names none
+func MagnetInfoHash(magnet string) string {
+	idx := strings.Index(strings.ToLower(magnet), "urn:btih:")
+	if idx < 0 {
+		return ""
+	}
+	hash := magnet[idx+len("urn:btih:"):]
+	if end := strings.IndexAny(hash, "&"); end >= 0 {
+		hash = hash[:end]
+	}
+	return strings.ToLower(hash)
+}
+
+func yearString(year int) string {
+	if year <= 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
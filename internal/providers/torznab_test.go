@@ -0,0 +1,195 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/services/debrid"
+)
+
+const (
+	hashA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	hashB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	hashC = "cccccccccccccccccccccccccccccccccccccccc"
+)
+
+// torznabStandIn is a local Torznab indexer answering every search with a fixed feed per query type
+type torznabStandIn struct {
+	mu      sync.Mutex
+	queries []url.Values
+	feeds   func(q url.Values) string
+}
+
+func (s *torznabStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.queries = append(s.queries, r.URL.Query())
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/rss+xml")
+	fmt.Fprint(w, s.feeds(r.URL.Query()))
+}
+
+func torznabFeedXML(items ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed"><channel>` + strings.Join(items, "") + `</channel></rss>`
+}
+
+func torznabItemXML(title, hash string, size int64, seeders int) string {
+	return fmt.Sprintf(`<item><title>%s</title><size>%d</size>
+<torznab:attr name="infohash" value="%s"/><torznab:attr name="seeders" value="%d"/></item>`, title, size, hash, seeders)
+}
+
+// cacheStandIn is a debrid service that reports a fixed set of hashes as cached
+type cacheStandIn struct {
+	cached map[string]bool
+	asked  []string
+}
+
+func (c *cacheStandIn) CheckCache(ctx context.Context, hashes []string) (map[string]bool, error) {
+	c.asked = append(c.asked, hashes...)
+	result := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		result[h] = c.cached[h]
+	}
+	return result, nil
+}
+
+func (c *cacheStandIn) GetStreamURL(ctx context.Context, hash string, fileIndex int) (string, error) {
+	return "", nil
+}
+
+func (c *cacheStandIn) GetAvailableFiles(ctx context.Context, hash string) ([]debrid.TorrentFile, error) {
+	return nil, nil
+}
+
+func (c *cacheStandIn) GetServiceName() string { return "Stand-in" }
+
+func (c *cacheStandIn) IsAuthenticated(ctx context.Context) bool { return true }
+
+func TestTorznabMovieSearch(t *testing.T) {
+	indexer := &torznabStandIn{feeds: func(q url.Values) string {
+		return torznabFeedXML(
+			torznabItemXML("Heat.1995.1080p.BluRay.x264-GROUP", hashA, 8<<30, 120),
+			torznabItemXML("Heat.1995.2160p.UHD.BluRay.REMUX-GROUP", strings.ToUpper(hashB), 60<<30, 30),
+			// No info hash and no magnet: dropped
+			`<item><title>Heat.1995.720p.WEB</title><link>http://indexer/download/1</link></item>`,
+		)
+	}}
+	server := httptest.NewServer(indexer)
+	defer server.Close()
+
+	cache := &cacheStandIn{cached: map[string]bool{hashA: true}}
+	provider := NewTorznabProvider("Jackett", server.URL+"/api/v2.0/indexers/all/results/torznab", "secret", cache, nil)
+	streams, err := provider.GetMovieStreams("tt0113277")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(indexer.queries) != 1 {
+		t.Fatalf("made %d searches, want 1", len(indexer.queries))
+	}
+	q := indexer.queries[0]
+	if q.Get("t") != "movie" || q.Get("imdbid") != "0113277" || q.Get("apikey") != "secret" {
+		t.Errorf("search query = %v", q)
+	}
+
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want 2", len(streams))
+	}
+	if s := streams[0]; s.InfoHash != hashA || s.Quality != "1080p" || s.Seeders != 120 || s.Size != 8<<30 || !s.Cached || s.Source != "Jackett" {
+		t.Errorf("first stream = %+v", s)
+	}
+	if s := streams[1]; s.InfoHash != hashB || s.Quality != "2160p" || s.Cached {
+		t.Errorf("second stream = %+v", s)
+	}
+	// Playback resolves the hash through debrid; a magnet is never handed out as the stream URL
+	if streams[0].URL != "" || streams[1].URL != "" {
+		t.Errorf("stream URLs = %q, %q; want none", streams[0].URL, streams[1].URL)
+	}
+	if len(cache.asked) != 2 {
+		t.Errorf("cache checked %d hashes, want both in one call", len(cache.asked))
+	}
+}
+
+func TestTorznabSeriesKeepsMatchingEpisodesAndPacks(t *testing.T) {
+	indexer := &torznabStandIn{feeds: func(q url.Values) string {
+		if q.Get("ep") != "" {
+			return torznabFeedXML(
+				torznabItemXML("Show.S02E05.1080p.WEB-DL-GROUP", hashA, 2<<30, 50),
+				torznabItemXML("Show.S02E06.1080p.WEB-DL-GROUP", hashC, 2<<30, 50),
+			)
+		}
+		return torznabFeedXML(
+			torznabItemXML("Show.S02.1080p.BluRay.x265-GROUP", hashB, 30<<30, 10),
+			// The same release again from the season search
+			torznabItemXML("Show.S02E05.1080p.WEB-DL-GROUP", hashA, 2<<30, 50),
+		)
+	}}
+	server := httptest.NewServer(indexer)
+	defer server.Close()
+
+	provider := NewTorznabProvider("Prowlarr", server.URL, "", nil, nil)
+	streams, err := provider.GetSeriesStreams("tt1234567", 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(indexer.queries) != 2 {
+		t.Fatalf("made %d searches, want the episode and the season", len(indexer.queries))
+	}
+	for _, q := range indexer.queries {
+		if q.Get("t") != "tvsearch" || q.Get("season") != "2" || q.Has("apikey") {
+			t.Errorf("search query = %v", q)
+		}
+	}
+
+	var hashes []string
+	for _, s := range streams {
+		hashes = append(hashes, s.InfoHash)
+	}
+	if got := strings.Join(hashes, ","); got != hashA+","+hashB {
+		t.Errorf("streams = %s, want S02E05 and the season pack once each", got)
+	}
+}
+
+func TestTorznabErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"status", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad key", http.StatusUnauthorized)
+		}, "status 401"},
+		{"torznab error", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><error code="100" description="Invalid API Key"/>`)
+		}, "torznab error 100: Invalid API Key"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			provider := NewTorznabProvider("Broken", server.URL, "", nil, nil)
+			_, err := provider.GetSeriesStreams("tt1234567", 1, 1)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestHashFromMagnet(t *testing.T) {
+	magnet := "magnet:?xt=urn:btih:" + strings.ToUpper(hashA) + "&dn=Heat&tr=udp://tracker"
+	if got := MagnetInfoHash(magnet); got != hashA {
+		t.Errorf("MagnetInfoHash = %q", got)
+	}
+	if got := MagnetInfoHash("http://example.com/file.torrent"); got != "" {
+		t.Errorf("MagnetInfoHash without btih = %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+package providers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
+	"github.com/Zerr0-C00L/StreamArr/internal/services"
+	"github.com/Zerr0-C00L/StreamArr/internal/services/debrid"
+)
+
+// UsenetSource marks streams from NZB-based services
+const UsenetSource = "usenet"
+
+// usenetResultTTL is how long a search result can be resolved after it was listed
+const usenetResultTTL = 6 * time.Hour
+
+// UsenetProvider searches an NZB-based service and returns its releases as streams
+// Stream URLs are server-relative paths resolved on playback, so services that
+// import NZBs only do so for the release actually played
+type UsenetProvider struct {
+	service    debrid.UsenetService
+	tmdbClient *services.TMDBClient
+	secret     []byte
+
+	mu      sync.Mutex
+	results map[string]usenetEntry // token -> result
+}
+
+type usenetEntry struct {
+	result  debrid.NZBResult
+	addedAt time.Time
+}
+
+// NewUsenetProvider creates a provider for one usenet service
+// tmdbClient is needed by services that search by title; secret signs playback tokens
+func NewUsenetProvider(service debrid.UsenetService, tmdbClient *services.TMDBClient, secret string) *UsenetProvider {
+	return &UsenetProvider{
+		service:    service,
+		tmdbClient: tmdbClient,
+		secret:     []byte(secret),
+		results:    make(map[string]usenetEntry),
+	}
+}
+
+// Slug is the URL-safe service name used in playback paths
+func (p *UsenetProvider) Slug() string {
+	return strings.ToLower(strings.ReplaceAll(p.service.GetServiceName(), " ", "-"))
+}
+
+// GetMovieStreams searches the service for a movie
+func (p *UsenetProvider) GetMovieStreams(imdbID string) ([]TorrentioStream, error) {
+	query := debrid.UsenetQuery{IMDBID: imdbID}
+	query.Title, query.Year = lookupTitle(p.tmdbClient, imdbID, "movie")
+
+	results, err := p.search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Title searches return other films with similar names; keep matching years
+	var filtered []debrid.NZBResult
+	for _, r := range results {
+		info := release.Parse(r.Title)
+		if info.IsEpisode() || (query.Year > 0 && info.Year > 0 && info.Year != query.Year) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+
+	streams := p.convertResults(filtered)
+	log.Printf("[USENET] %s returned %d streams for movie %s", p.service.GetServiceName(), len(streams), imdbID)
+	return streams, nil
+}
+
+// GetSeriesStreams searches the service for an episode
+func (p *UsenetProvider) GetSeriesStreams(imdbID string, season, episode int) ([]TorrentioStream, error) {
+	query := debrid.UsenetQuery{IMDBID: imdbID, Season: season, Episode: episode}
+	query.Title, query.Year = lookupTitle(p.tmdbClient, imdbID, "tv")
+
+	results, err := p.search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []debrid.NZBResult
+	for _, r := range results {
+		if release.Parse(r.Title).HasEpisode(season, episode) {
+			filtered = append(filtered, r)
+		}
+	}
+
+	streams := p.convertResults(filtered)
+	log.Printf("[USENET] %s returned %d streams for series %s S%02dE%02d", p.service.GetServiceName(), len(streams), imdbID, season, episode)
+	return streams, nil
+}
+
+// Resolve returns the playable URL for a token from an earlier stream listing
+func (p *UsenetProvider) Resolve(ctx context.Context, token string) (string, error) {
+	p.mu.Lock()
+	entry, ok := p.results[token]
+	p.mu.Unlock()
+
+	if !ok || time.Since(entry.addedAt) > usenetResultTTL {
+		return "", fmt.Errorf("unknown or expired usenet stream")
+	}
+	return p.service.GetStreamURL(ctx, entry.result)
+}
+
+// search runs the query and fills in availability for results the search did not mark
+func (p *UsenetProvider) search(query debrid.UsenetQuery) ([]debrid.NZBResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	defer cancel()
+
+	results, err := p.service.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+	for _, r := range results {
+		if !r.Available {
+			unknown = append(unknown, r.ID)
+		}
+	}
+	if len(unknown) == 0 {
+		return results, nil
+	}
+
+	available, err := p.service.CheckAvailability(ctx, unknown)
+	if err != nil {
+		log.Printf("[USENET] %s: availability check failed: %v", p.service.GetServiceName(), err)
+		return results, nil
+	}
+	for i := range results {
+		if available[results[i].ID] {
+			results[i].Available = true
+		}
+	}
+	return results, nil
+}
+
+func (p *UsenetProvider) convertResults(results []debrid.NZBResult) []TorrentioStream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Drop expired tokens so the map does not grow without bound
+	for token, entry := range p.results {
+		if time.Since(entry.addedAt) > usenetResultTTL {
+			delete(p.results, token)
+		}
+	}
+
+	name := p.service.GetServiceName()
+	streams := make([]TorrentioStream, 0, len(results))
+	for _, r := range results {
+		quality := release.Parse(r.Title).Resolution
+		if quality == "" {
+			quality = "Unknown"
+		}
+
+		ext := strings.TrimPrefix(path.Ext(r.FileName), ".")
+		if ext == "" {
+			ext = "mkv"
+		}
+
+		token := p.token(r.ID)
+		p.results[token] = usenetEntry{result: r, addedAt: time.Now()}
+
+		stream := TorrentioStream{
+			Name:    fmt.Sprintf("%s\n%s", name, quality),
+			Title:   r.Title,
+			URL:     fmt.Sprintf("/usenet/%s/%s.%s", p.Slug(), token, ext),
+			Quality: quality,
+			Size:    r.Size,
+			Cached:  r.Available,
+			Source:  UsenetSource,
+		}
+		stream.BehaviorHints.Filename = r.FileName
+		stream.BehaviorHints.VideoSize = r.Size
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+// token derives an unguessable playback token from the service and result ID
+func (p *UsenetProvider) token(id string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	fmt.Fprintf(mac, "usenet:%s:%s", p.Slug(), id)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,40 @@
+package release
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// videoExtensions are the file types considered playable
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".avi": true, ".m4v": true, ".mov": true,
+	".wmv": true, ".ts": true, ".m2ts": true, ".webm": true,
+}
+
+// extrasRe matches sample and bonus files that should never be mapped to an episode or movie
+var extrasRe = regexp.MustCompile(`(?i)(^|[/ ._\-\[(])(sample|trailer|featurette|extras?|behind[ ._-]the[ ._-]scenes|deleted[ ._-]scenes)([/ ._\-\])]|$)`)
+
+// leadingEpisodeRe matches bare episode numbers like "01 - Pilot.mkv" inside a season folder
+var leadingEpisodeRe = regexp.MustCompile(`^(\d{1,3})(?:[ .\-_]|$)`)
+
+// IsVideoFile reports whether a file path has a playable video extension
+func IsVideoFile(filePath string) bool {
+	return videoExtensions[strings.ToLower(path.Ext(filePath))]
+}
+
+// IsExtra reports whether a file path names a sample, trailer or other bonus file
+func IsExtra(filePath string) bool {
+	return extrasRe.MatchString(filePath)
+}
+
+// LeadingEpisode reads a bare episode number from the start of a file name, as in "01 - Pilot.mkv"
+func LeadingEpisode(name string) (int, bool) {
+	m := leadingEpisodeRe.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	return n, err == nil
+}
@@ -0,0 +1,31 @@
+package release
+
+import "testing"
+
+func TestFileHelpers(t *testing.T) {
+	for _, tt := range []struct {
+		path  string
+		video bool
+		extra bool
+	}{
+		{"Show.S01E01.1080p.MKV", true, false},
+		{"Show.S01/Sample/show.s01e01.sample.mkv", true, true},
+		{"Movie.2020.Trailer.mp4", true, true},
+		{"Movie.2020.nfo", false, false},
+		{"The Extraordinary.2020.mkv", true, false},
+	} {
+		if got := IsVideoFile(tt.path); got != tt.video {
+			t.Errorf("IsVideoFile(%q) = %v, want %v", tt.path, got, tt.video)
+		}
+		if got := IsExtra(tt.path); got != tt.extra {
+			t.Errorf("IsExtra(%q) = %v, want %v", tt.path, got, tt.extra)
+		}
+	}
+
+	if n, ok := LeadingEpisode("05 - Pilot.mkv"); !ok || n != 5 {
+		t.Errorf("LeadingEpisode = %d, %v; want 5", n, ok)
+	}
+	if _, ok := LeadingEpisode("2020 Movie.mkv"); ok {
+		t.Error("LeadingEpisode read a year as an episode")
+	}
+}
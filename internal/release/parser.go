@@ -0,0 +1,519 @@
+package release
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Info contains everything that can be parsed out of a release name
+// Example: "Show.Name.S01E01-E03.2160p.WEB-DL.DDP5.1.Atmos.DV.HEVC-GROUP"
+type Info struct {
+	Title      string
+	Year       int
+	Seasons    []int
+	Episodes   []int
+	Complete   bool // Season pack or complete series
+	Resolution string
+	Source     string
+	Codec      string
+	HDR        string
+	Audio      string
+	Channels   string
+	Languages  []string
+	Group      string
+	Edition    string
+	Repack     bool
+	Proper     bool
+	ThreeD     bool
+	Size       int64 // Bytes, 0 if not present in the name
+}
+
+// IsEpisode reports whether the release looks like TV content
+func (i Info) IsEpisode() bool {
+	return len(i.Seasons) > 0 || len(i.Episodes) > 0
+}
+
+// IsSeasonPack reports whether the release covers whole seasons rather than single episodes
+func (i Info) IsSeasonPack() bool {
+	return len(i.Seasons) > 0 && (len(i.Episodes) == 0 || i.Complete)
+}
+
+// IsMultiEpisode reports whether the release covers more than one episode
+func (i Info) IsMultiEpisode() bool {
+	return len(i.Episodes) > 1
+}
+
+// HasEpisode reports whether the release contains the given season/episode,
+// either as an explicit episode or as part of a season pack
+func (i Info) HasEpisode(season, episode int) bool {
+	if !containsInt(i.Seasons, season) {
+		// Episode-only names (e.g. "Ep05") carry no season
+		if len(i.Seasons) > 0 || season != 1 {
+			return false
+		}
+	}
+	if len(i.Episodes) == 0 {
+		return len(i.Seasons) > 0
+	}
+	return containsInt(i.Episodes, episode)
+}
+
+// marker is a tag match found in the release name; the title ends at the first one
+type marker struct {
+	re    *regexp.Regexp
+	apply func(info *Info, match []string)
+}
+
+var (
+	extensionRe  = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|m4v|mov|wmv|ts|m2ts|iso|webm|flv|mpg|mpeg)$`)
+	sitePrefixRe = regexp.MustCompile(`^\s*(?:\[[^\]]*\]|\([^)]*www\.[^)]*\)|www\.[^\s]+)\s*-?\s*`)
+	bracketTagRe = regexp.MustCompile(`\s*\[[^\]]*\]\s*$`)
+	yearRe       = regexp.MustCompile(`\b((?:19|20)\d{2})\b`)
+	sizeRe       = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(TB|TIB|GB|GIB|MB|MIB)\b`)
+	groupRe      = regexp.MustCompile(`-\s?([A-Za-z0-9][A-Za-z0-9@_]*?)$`)
+	channelsRe   = regexp.MustCompile(`(?i)(?:^|[^0-9])([1-9])[ .]([0-2])(?:[^0-9]|$)`)
+
+	// Season/episode forms, most specific first. A bare "E" only counts after a season marker
+	// (S01E02, 1x02), so "WALL-E.2008" stays a movie
+	seasonEpisodeRe = regexp.MustCompile(`(?i)\bS(\d{1,2})[ .]?((?:E\d{1,4}(?:[ .]?-[ .]?E?\d{1,4})?)+)`)
+	crossEpisodeRe  = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{1,3})(?:-(?:\d{1,2}x)?(\d{1,3}))?\b`)
+	seasonRangeRe   = regexp.MustCompile(`(?i)\bS(\d{1,2})[ .]?-[ .]?S?(\d{1,2})\b`)
+	seasonOnlyRe    = regexp.MustCompile(`(?i)\bS(\d{1,2})\b`)
+	seasonWordRe    = regexp.MustCompile(`(?i)\bSeasons?[ .]?(\d{1,2})(?:[ .]?(?:-|to|&)[ .]?(\d{1,2}))?\b`)
+	episodeWordRe   = regexp.MustCompile(`(?i)\b(?:Episode|Ep)[ .]?(\d{1,4})(?:[ .]?-[ .]?(?:Ep?)?(\d{1,4}))?\b`)
+	completeRe      = regexp.MustCompile(`(?i)\b(?:Complete(?:[ .]Series)?|Full[ .]Season|Season[ .]Pack)\b`)
+	episodeNumsRe   = regexp.MustCompile(`(?i)E?(\d{1,4})`)
+
+	dottedWordRe      = regexp.MustCompile(`\.(\S)`)
+	resolutionTokenRe = regexp.MustCompile(`(?i)\b\d{3,4}[pi]\b`)
+)
+
+var markers = []marker{
+	{regexp.MustCompile(`(?i)\b(2160p|4k|uhd)\b`), func(i *Info, m []string) { setOnce(&i.Resolution, "2160p") }},
+	{regexp.MustCompile(`(?i)\b(1440p)\b`), func(i *Info, m []string) { setOnce(&i.Resolution, "1440p") }},
+	{regexp.MustCompile(`(?i)\b(1080[pi])\b`), func(i *Info, m []string) { setOnce(&i.Resolution, "1080p") }},
+	{regexp.MustCompile(`(?i)\b(720p)\b`), func(i *Info, m []string) { setOnce(&i.Resolution, "720p") }},
+	{regexp.MustCompile(`(?i)\b(576p)\b`), func(i *Info, m []string) { setOnce(&i.Resolution, "576p") }},
+	{regexp.MustCompile(`(?i)\b(480p)\b`), func(i *Info, m []string) { setOnce(&i.Resolution, "480p") }},
+	{regexp.MustCompile(`(?i)\b(360p)\b`), func(i *Info, m []string) { setOnce(&i.Resolution, "360p") }},
+
+	{regexp.MustCompile(`(?i)\b((?:BD|UHD|BluRay|Blu-Ray)?[ .]?Remux)\b`), func(i *Info, m []string) { setOnce(&i.Source, "REMUX") }},
+	{regexp.MustCompile(`(?i)\b(Blu-?Ray|BDRip|BRRip|BDMV|BD25|BD50)\b`), func(i *Info, m []string) { setOnce(&i.Source, "BluRay") }},
+	{regexp.MustCompile(`(?i)\b(WEB[ .-]?DL|WEBDL)\b`), func(i *Info, m []string) { setOnce(&i.Source, "WEB-DL") }},
+	{regexp.MustCompile(`\b(AMZN|NF|DSNP|ATVP|HMAX|HULU|PCOK)\b`), func(i *Info, m []string) { setOnce(&i.Source, "WEB-DL") }},
+	{regexp.MustCompile(`(?i)\b(WEB[ .-]?Rip)\b`), func(i *Info, m []string) { setOnce(&i.Source, "WEBRip") }},
+	{regexp.MustCompile(`\b(WEB)\b`), func(i *Info, m []string) { setOnce(&i.Source, "WEBRip") }},
+	{regexp.MustCompile(`(?i)\b(HDTV|PDTV|SDTV|HDTVRip)\b`), func(i *Info, m []string) { setOnce(&i.Source, "HDTV") }},
+	{regexp.MustCompile(`(?i)\b(DVDRip|DVD[ .-]?R|DVD5|DVD9|DVDSCR)\b`), func(i *Info, m []string) { setOnce(&i.Source, "DVDRip") }},
+	{regexp.MustCompile(`(?i)\b(HD-?CAM|CAM(?:Rip)?)\b`), func(i *Info, m []string) { setOnce(&i.Source, "CAM") }},
+	{regexp.MustCompile(`(?i)\b(HD-?TS|TSRip|TELESYNC|PDVD)\b`), func(i *Info, m []string) { setOnce(&i.Source, "TS") }},
+	{regexp.MustCompile(`\b(TS)\b`), func(i *Info, m []string) { setOnce(&i.Source, "TS") }},
+	{regexp.MustCompile(`(?i)\b(HD-?TC|TELECINE)\b`), func(i *Info, m []string) { setOnce(&i.Source, "TC") }},
+	{regexp.MustCompile(`\b(TC)\b`), func(i *Info, m []string) { setOnce(&i.Source, "TC") }},
+
+	{regexp.MustCompile(`(?i)\b(x\.?265|h\.?265|HEVC)\b`), func(i *Info, m []string) { setOnce(&i.Codec, "HEVC") }},
+	{regexp.MustCompile(`(?i)\b(x\.?264|h\.?264)\b|\b(AVC)\b`), func(i *Info, m []string) { setOnce(&i.Codec, "AVC") }},
+	{regexp.MustCompile(`(?i)\b(AV1)\b`), func(i *Info, m []string) { setOnce(&i.Codec, "AV1") }},
+	{regexp.MustCompile(`(?i)\b(VP9)\b`), func(i *Info, m []string) { setOnce(&i.Codec, "VP9") }},
+	{regexp.MustCompile(`(?i)\b(XviD|DivX)\b`), func(i *Info, m []string) { setOnce(&i.Codec, "XviD") }},
+
+	{regexp.MustCompile(`(?i)\b(REPACK\d?|RERIP)\b`), func(i *Info, m []string) { i.Repack = true }},
+	{regexp.MustCompile(`(?i)\b(PROPER)\b`), func(i *Info, m []string) { i.Proper = true }},
+	{regexp.MustCompile(`(?i)\b(3D|H-?SBS|H-?OU|Half-SBS|Half-OU)\b`), func(i *Info, m []string) { i.ThreeD = true }},
+	{regexp.MustCompile(`(?i)\b(MULTi|DUAL(?:[ .-]?Audio)?|DUBBED|SUBBED)\b`), nil},
+	{regexp.MustCompile(`(?i)\b(10[ .-]?bit|8[ .-]?bit|HYBRID|iNTERNAL|LIMITED)\b`), nil},
+}
+
+// hdrPatterns are checked in order; Dolby Vision wins over HDR10+ over HDR10 over HDR
+var hdrPatterns = []struct {
+	re    *regexp.Regexp
+	value string
+}{
+	{regexp.MustCompile(`\b(DV|DoVi)\b|(?i)\b(Dolby[ .]?Vision)\b`), "DV"},
+	{regexp.MustCompile(`(?i)\b(HDR10\+|HDR10Plus|HDR10P)`), "HDR10+"},
+	{regexp.MustCompile(`(?i)\b(HDR10)\b`), "HDR10"},
+	{regexp.MustCompile(`(?i)\b(HDR|HLG)\b`), "HDR"},
+}
+
+// audioPatterns are checked in order from best to worst
+var audioPatterns = []struct {
+	re    *regexp.Regexp
+	value string
+}{
+	{regexp.MustCompile(`(?i)\b(Atmos)\b`), "Atmos"},
+	{regexp.MustCompile(`(?i)\b(TrueHD)`), "TrueHD"},
+	{regexp.MustCompile(`(?i)\b(DTS[ .-]?HD[ .-]?MA|DTS-MA)`), "DTS-HD MA"},
+	{regexp.MustCompile(`(?i)\b(DTS[ .-]?HD)`), "DTS-HD"},
+	{regexp.MustCompile(`(?i)\b(DTS[ .-]?X)\b`), "DTS-X"},
+	{regexp.MustCompile(`(?i)\b(DD\+|DDP|E-?AC-?3)`), "DD+"},
+	{regexp.MustCompile(`(?i)\b(AC-?3)|\b(DD)(?:[ .]?[1-7][ .][01])?\b`), "AC3"},
+	{regexp.MustCompile(`(?i)\b(DTS)`), "DTS"},
+	{regexp.MustCompile(`(?i)\b(FLAC)`), "FLAC"},
+	{regexp.MustCompile(`(?i)\b(Opus)`), "Opus"},
+	{regexp.MustCompile(`(?i)\b(AAC)`), "AAC"},
+	{regexp.MustCompile(`(?i)\b(MP3)\b`), "MP3"},
+}
+
+// editionPatterns map edition tags to a display name
+var editionPatterns = []struct {
+	re    *regexp.Regexp
+	value string
+}{
+	{regexp.MustCompile(`(?i)\b(Director'?s[ .]?Cut)\b`), "Director's Cut"},
+	{regexp.MustCompile(`(?i)\b(Extended(?:[ .](?:Cut|Edition))?)\b`), "Extended"},
+	{regexp.MustCompile(`(?i)\b(Theatrical(?:[ .](?:Cut|Edition))?)\b`), "Theatrical"},
+	{regexp.MustCompile(`(?i)\b(Final[ .]Cut)\b`), "Final Cut"},
+	{regexp.MustCompile(`(?i)\b(Ultimate[ .]Edition)\b`), "Ultimate Edition"},
+	{regexp.MustCompile(`(?i)\b(Special[ .]Edition)\b`), "Special Edition"},
+	{regexp.MustCompile(`(?i)\b(Criterion(?:[ .]Collection)?)\b`), "Criterion"},
+	{regexp.MustCompile(`(?i)\b(IMAX(?:[ .]Enhanced)?)\b`), "IMAX"},
+	{regexp.MustCompile(`(?i)\b(Remastered)\b`), "Remastered"},
+	{regexp.MustCompile(`(?i)\b(Unrated)\b`), "Unrated"},
+	{regexp.MustCompile(`(?i)\b(Uncut)\b`), "Uncut"},
+}
+
+// languagePatterns map language tags to ISO 639-1 codes
+var languagePatterns = []struct {
+	re   *regexp.Regexp
+	code string
+}{
+	{regexp.MustCompile(`(?i)\b(MULTi(?:[ .-]?(?:Audio|Lang|Subs))?)\b`), "multi"},
+	{regexp.MustCompile(`(?i)\b(DUAL(?:[ .-]?Audio)?)\b`), "dual"},
+	{regexp.MustCompile(`(?i)\b(English|ENG)\b`), "en"},
+	{regexp.MustCompile(`(?i)\b(French|TRUEFRENCH|VFF|VFQ|VOSTFR|FRE)\b`), "fr"},
+	{regexp.MustCompile(`(?i)\b(German|Deutsch|GER)\b`), "de"},
+	{regexp.MustCompile(`(?i)\b(Spanish|Castellano|ESP|SPA)\b`), "es"},
+	{regexp.MustCompile(`(?i)\b(Latino|LATAM)\b`), "es-419"},
+	{regexp.MustCompile(`(?i)\b(Italian|ITA)\b`), "it"},
+	{regexp.MustCompile(`(?i)\b(Portuguese|POR|PT-?BR)\b`), "pt"},
+	{regexp.MustCompile(`(?i)\b(Russian|RUS)\b`), "ru"},
+	{regexp.MustCompile(`(?i)\b(Polish|PLDUB|PL)\b`), "pl"},
+	{regexp.MustCompile(`(?i)\b(Dutch|NLD)\b`), "nl"},
+	{regexp.MustCompile(`(?i)\b(Hindi|HIN)\b`), "hi"},
+	{regexp.MustCompile(`(?i)\b(Tamil|TAM)\b`), "ta"},
+	{regexp.MustCompile(`(?i)\b(Telugu|TEL)\b`), "te"},
+	{regexp.MustCompile(`(?i)\b(Japanese|JAP|JPN)\b`), "ja"},
+	{regexp.MustCompile(`(?i)\b(Korean|KOR)\b`), "ko"},
+	{regexp.MustCompile(`(?i)\b(Chinese|CHS|CHT|Mandarin)\b`), "zh"},
+	{regexp.MustCompile(`(?i)\b(Turkish|TUR)\b`), "tr"},
+	{regexp.MustCompile(`(?i)\b(Arabic|ARA)\b`), "ar"},
+	{regexp.MustCompile(`(?i)\b(Swedish|SWE)\b`), "sv"},
+	{regexp.MustCompile(`(?i)\b(Danish|DAN)\b`), "da"},
+	{regexp.MustCompile(`(?i)\b(Norwegian|NOR)\b`), "no"},
+	{regexp.MustCompile(`(?i)\b(Finnish|FIN)\b`), "fi"},
+	{regexp.MustCompile(`(?i)\b(Nordic)\b`), "nordic"},
+	{regexp.MustCompile(`(?i)\b(Croatian|HRV|Serbian|SRB|Bosnian|EXYU)\b`), "sh"},
+}
+
+// groupBlacklist contains trailing tokens that look like release groups but aren't
+var groupBlacklist = map[string]bool{
+	"DL": true, "RIP": true, "SBS": true, "OU": true, "HD": true, "MA": true,
+	"X": true, "AUDIO": true, "RAY": true, "CUT": true,
+}
+
+// Parse extracts release metadata from a torrent, file or stream name
+func Parse(name string) Info {
+	info := Info{}
+
+	raw := strings.TrimSpace(name)
+	if raw == "" {
+		return info
+	}
+
+	// Stremio addons put the filename on the first line and seeders/size below it
+	if idx := strings.Index(raw, "\n"); idx >= 0 {
+		raw = strings.TrimSpace(raw[:idx])
+	}
+
+	info.Size = ParseSize(name)
+
+	raw = extensionRe.ReplaceAllString(raw, "")
+	raw = sitePrefixRe.ReplaceAllString(raw, "")
+	raw = bracketTagRe.ReplaceAllString(raw, "")
+
+	// Normalise separators so word boundaries work ("_" is a word character in RE2)
+	text := strings.ReplaceAll(raw, "_", " ")
+
+	// titleEnd is the earliest position of any tag; everything before it is the title
+	titleEnd := len(text)
+	mark := func(pos int) {
+		if pos >= 0 && pos < titleEnd {
+			titleEnd = pos
+		}
+	}
+
+	parseEpisodes(text, &info, mark)
+
+	for _, m := range markers {
+		loc := m.re.FindStringIndex(text)
+		// A leading tag is part of the title ("4K Restoration", "3D Rising")
+		if loc == nil || loc[0] == 0 {
+			continue
+		}
+		mark(loc[0])
+		if m.apply != nil {
+			m.apply(&info, []string{text[loc[0]:loc[1]]})
+		}
+	}
+
+	for _, p := range hdrPatterns {
+		if loc := p.re.FindStringIndex(text); loc != nil && loc[0] > 0 {
+			info.HDR = p.value
+			mark(loc[0])
+			break
+		}
+	}
+
+	for _, p := range audioPatterns {
+		if loc := p.re.FindStringIndex(text); loc != nil && loc[0] > 0 {
+			info.Audio = p.value
+			mark(loc[0])
+			break
+		}
+	}
+
+	for _, p := range editionPatterns {
+		if loc := p.re.FindStringIndex(text); loc != nil && loc[0] > 0 {
+			info.Edition = p.value
+			mark(loc[0])
+			break
+		}
+	}
+
+	// Year: the last year before the other tags, else the first one after them.
+	// A year that starts the name is part of the title ("2001 A Space Odyssey")
+	yearPos := -1
+	for _, loc := range yearRe.FindAllStringSubmatchIndex(text, -1) {
+		if loc[2] == 0 {
+			continue
+		}
+		if loc[2] > titleEnd && yearPos >= 0 {
+			break
+		}
+		info.Year, _ = strconv.Atoi(text[loc[2]:loc[3]])
+		yearPos = loc[2]
+		if loc[2] > titleEnd {
+			break
+		}
+	}
+	if yearPos >= 0 {
+		mark(yearPos)
+	}
+
+	// Languages and channels are only read from the tag section so titles like "French Kiss" survive
+	tail := text[titleEnd:]
+	seen := map[string]bool{}
+	for _, p := range languagePatterns {
+		if p.re.MatchString(tail) && !seen[p.code] {
+			seen[p.code] = true
+			info.Languages = append(info.Languages, p.code)
+		}
+	}
+
+	if info.Audio != "" || strings.Contains(strings.ToUpper(tail), "CH") {
+		if m := channelsRe.FindStringSubmatch(stripResolutions(tail)); m != nil {
+			info.Channels = m[1] + "." + m[2]
+		}
+	}
+
+	info.Group = parseGroup(text, titleEnd)
+	info.Title = cleanTitle(text[:titleEnd])
+
+	return info
+}
+
+// parseEpisodes fills seasons, episodes and pack flags and reports where they start
+func parseEpisodes(text string, info *Info, mark func(int)) {
+	if m := completeRe.FindStringIndex(text); m != nil && m[0] > 0 {
+		info.Complete = true
+		mark(m[0])
+	}
+
+	if locs := seasonEpisodeRe.FindAllStringSubmatchIndex(text, -1); locs != nil {
+		mark(locs[0][0])
+		for _, loc := range locs {
+			season, _ := strconv.Atoi(text[loc[2]:loc[3]])
+			addInt(&info.Seasons, season)
+			addEpisodeRange(info, text[loc[4]:loc[5]])
+		}
+		return
+	}
+
+	if loc := crossEpisodeRe.FindStringSubmatchIndex(text); loc != nil && loc[0] > 0 {
+		mark(loc[0])
+		season, _ := strconv.Atoi(text[loc[2]:loc[3]])
+		first, _ := strconv.Atoi(text[loc[4]:loc[5]])
+		last := first
+		if loc[6] >= 0 {
+			last, _ = strconv.Atoi(text[loc[6]:loc[7]])
+		}
+		addInt(&info.Seasons, season)
+		addRange(&info.Episodes, first, last)
+		return
+	}
+
+	if loc := seasonRangeRe.FindStringSubmatchIndex(text); loc != nil {
+		mark(loc[0])
+		first, _ := strconv.Atoi(text[loc[2]:loc[3]])
+		last, _ := strconv.Atoi(text[loc[4]:loc[5]])
+		addRange(&info.Seasons, first, last)
+	} else if loc := seasonWordRe.FindStringSubmatchIndex(text); loc != nil {
+		mark(loc[0])
+		first, _ := strconv.Atoi(text[loc[2]:loc[3]])
+		last := first
+		if loc[4] >= 0 {
+			last, _ = strconv.Atoi(text[loc[4]:loc[5]])
+		}
+		addRange(&info.Seasons, first, last)
+	} else if locs := seasonOnlyRe.FindAllStringSubmatchIndex(text, -1); locs != nil {
+		mark(locs[0][0])
+		for _, loc := range locs {
+			season, _ := strconv.Atoi(text[loc[2]:loc[3]])
+			addInt(&info.Seasons, season)
+		}
+	}
+
+	if loc := episodeWordRe.FindStringSubmatchIndex(text); loc != nil && loc[0] > 0 && !isYear(text[loc[2]:loc[3]]) {
+		mark(loc[0])
+		first, _ := strconv.Atoi(text[loc[2]:loc[3]])
+		last := first
+		if loc[4] >= 0 {
+			last, _ = strconv.Atoi(text[loc[4]:loc[5]])
+		}
+		addRange(&info.Episodes, first, last)
+	}
+
+	if info.Complete && len(info.Seasons) == 0 && len(info.Episodes) == 0 {
+		// "Complete Series" without explicit seasons; the caller decides what it covers
+		return
+	}
+}
+
+// addEpisodeRange parses the episode part of an SxxEyy match ("E01E02", "E01-E03", "E01-03")
+func addEpisodeRange(info *Info, part string) {
+	if strings.Contains(part, "-") {
+		bounds := strings.SplitN(part, "-", 2)
+		firstNums := episodeNumsRe.FindAllStringSubmatch(bounds[0], -1)
+		lastNums := episodeNumsRe.FindAllStringSubmatch(bounds[1], -1)
+		if len(firstNums) > 0 && len(lastNums) > 0 {
+			for _, n := range firstNums[:len(firstNums)-1] {
+				ep, _ := strconv.Atoi(n[1])
+				addInt(&info.Episodes, ep)
+			}
+			first, _ := strconv.Atoi(firstNums[len(firstNums)-1][1])
+			last, _ := strconv.Atoi(lastNums[0][1])
+			addRange(&info.Episodes, first, last)
+			return
+		}
+	}
+	for _, n := range episodeNumsRe.FindAllStringSubmatch(part, -1) {
+		ep, _ := strconv.Atoi(n[1])
+		addInt(&info.Episodes, ep)
+	}
+}
+
+// parseGroup returns the release group after the final hyphen, if any
+func parseGroup(text string, titleEnd int) string {
+	if titleEnd >= len(text) {
+		return ""
+	}
+	tail := strings.TrimSpace(text[titleEnd:])
+	m := groupRe.FindStringSubmatch(tail)
+	if m == nil {
+		return ""
+	}
+	group := m[1]
+	if groupBlacklist[strings.ToUpper(group)] {
+		return ""
+	}
+	if _, err := strconv.Atoi(group); err == nil {
+		return ""
+	}
+	return group
+}
+
+// cleanTitle turns a dotted/underscored title segment into a readable title
+func cleanTitle(s string) string {
+	if !strings.Contains(strings.TrimSpace(s), " ") {
+		s = strings.ReplaceAll(s, ".", " ")
+	} else {
+		// "Mr. Robot" keeps its dot, "Mr.Robot.2015" does not
+		s = dottedWordRe.ReplaceAllString(s, " $1")
+	}
+	s = strings.Trim(s, " -([{.")
+	s = strings.TrimSuffix(s, " -")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// stripResolutions removes resolution tokens so "1080p" isn't mistaken for channel info
+func stripResolutions(s string) string {
+	return resolutionTokenRe.ReplaceAllString(s, " ")
+}
+
+// ParseSize extracts a size in bytes from text like "💾 1.5 GB", "500MB" or "1,2 GiB"
+func ParseSize(text string) int64 {
+	m := sizeRe.FindStringSubmatch(text)
+	if m == nil {
+		return 0
+	}
+	num, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToUpper(m[2]) {
+	case "TB", "TIB":
+		return int64(num * 1024 * 1024 * 1024 * 1024)
+	case "GB", "GIB":
+		return int64(num * 1024 * 1024 * 1024)
+	case "MB", "MIB":
+		return int64(num * 1024 * 1024)
+	}
+	return 0
+}
+
+// SplitTitleYear returns a clean title and year (0 if none) for catalog lookups
+// Example: "Dune (2021) 1080p" -> "Dune", 2021
+func SplitTitleYear(name string) (string, int) {
+	info := Parse(name)
+	return info.Title, info.Year
+}
+
+// isYear reports whether a number reads as a year (1900-2099) rather than an episode
+func isYear(digits string) bool {
+	n, err := strconv.Atoi(digits)
+	return err == nil && len(digits) == 4 && n >= 1900 && n <= 2099
+}
+
+// setOnce keeps the first (most specific) match for a field
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+func addInt(list *[]int, n int) {
+	if !containsInt(*list, n) {
+		*list = append(*list, n)
+		sort.Ints(*list)
+	}
+}
+
+func addRange(list *[]int, first, last int) {
+	if last < first || last-first > 500 {
+		last = first
+	}
+	for n := first; n <= last; n++ {
+		addInt(list, n)
+	}
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+)
+
+// parseCase checks title, year, seasons, episodes and Complete always, and the tag fields when set
+type parseCase struct {
+	name string
+	want Info
+}
+
+func checkParse(t *testing.T, cases []parseCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Parse(tc.name)
+			w := tc.want
+			if got.Title != w.Title {
+				t.Errorf("Title = %q, want %q", got.Title, w.Title)
+			}
+			if got.Year != w.Year {
+				t.Errorf("Year = %d, want %d", got.Year, w.Year)
+			}
+			if !reflect.DeepEqual(got.Seasons, w.Seasons) {
+				t.Errorf("Seasons = %v, want %v", got.Seasons, w.Seasons)
+			}
+			if !reflect.DeepEqual(got.Episodes, w.Episodes) {
+				t.Errorf("Episodes = %v, want %v", got.Episodes, w.Episodes)
+			}
+			if got.Complete != w.Complete {
+				t.Errorf("Complete = %v, want %v", got.Complete, w.Complete)
+			}
+			fields := []struct{ field, got, want string }{
+				{"Resolution", got.Resolution, w.Resolution},
+				{"Source", got.Source, w.Source},
+				{"Codec", got.Codec, w.Codec},
+				{"HDR", got.HDR, w.HDR},
+				{"Audio", got.Audio, w.Audio},
+				{"Channels", got.Channels, w.Channels},
+				{"Group", got.Group, w.Group},
+				{"Edition", got.Edition, w.Edition},
+			}
+			for _, f := range fields {
+				if f.want != "" && f.got != f.want {
+					t.Errorf("%s = %q, want %q", f.field, f.got, f.want)
+				}
+			}
+			if w.Languages != nil && !reflect.DeepEqual(got.Languages, w.Languages) {
+				t.Errorf("Languages = %v, want %v", got.Languages, w.Languages)
+			}
+			if w.Repack && !got.Repack {
+				t.Error("Repack = false, want true")
+			}
+			if w.Proper && !got.Proper {
+				t.Error("Proper = false, want true")
+			}
+			if w.ThreeD && !got.ThreeD {
+				t.Error("ThreeD = false, want true")
+			}
+		})
+	}
+}
+
+func TestParseMovies(t *testing.T) {
+	checkParse(t, []parseCase{
+		{"The.Matrix.1999.1080p.BluRay.x264-SPARKS", Info{
+			Title: "The Matrix", Year: 1999, Resolution: "1080p", Source: "BluRay", Codec: "AVC", Group: "SPARKS",
+		}},
+		{"Dune.Part.Two.2024.2160p.WEB-DL.DDP5.1.Atmos.DV.HDR10.HEVC-FLUX", Info{
+			Title: "Dune Part Two", Year: 2024, Resolution: "2160p", Source: "WEB-DL", Codec: "HEVC",
+			HDR: "DV", Audio: "Atmos", Channels: "5.1", Group: "FLUX",
+		}},
+		{"Oppenheimer (2023) [2160p] [4K] [WEB] [5.1] [YTS.MX]", Info{
+			Title: "Oppenheimer", Year: 2023, Resolution: "2160p",
+		}},
+		{"Blade.Runner.1982.Final.Cut.REMASTERED.1080p.BluRay.REMUX.AVC.TrueHD.7.1-FraMeSToR", Info{
+			Title: "Blade Runner", Year: 1982, Source: "REMUX", Audio: "TrueHD", Channels: "7.1", Edition: "Final Cut",
+		}},
+		{"Aliens.1986.Directors.Cut.720p.BRRip.XviD.AC3-ViSiON", Info{
+			Title: "Aliens", Year: 1986, Resolution: "720p", Source: "BluRay", Codec: "XviD", Audio: "AC3", Edition: "Director's Cut",
+		}},
+		{"Avatar.2009.Extended.Collectors.Edition.1080p.BluRay.DTS-HD.MA.5.1-FGT", Info{
+			Title: "Avatar", Year: 2009, Audio: "DTS-HD MA", Channels: "5.1", Edition: "Extended", Group: "FGT",
+		}},
+		{"Gravity.2013.3D.HSBS.1080p.BluRay.x264-YIFY", Info{
+			Title: "Gravity", Year: 2013, Resolution: "1080p", ThreeD: true,
+		}},
+		{"Movie.Name.2019.REPACK.1080p.WEB.H264-GROUP", Info{
+			Title: "Movie Name", Year: 2019, Source: "WEBRip", Codec: "AVC", Repack: true, Group: "GROUP",
+		}},
+		{"Movie.Name.2019.PROPER.720p.HDTV.x264-GROUP", Info{
+			Title: "Movie Name", Year: 2019, Source: "HDTV", Proper: true,
+		}},
+		{"Amelie.2001.FRENCH.1080p.BluRay.x264.AAC-GROUP", Info{
+			Title: "Amelie", Year: 2001, Audio: "AAC", Languages: []string{"fr"},
+		}},
+		{"Parasite.2019.MULTi.KOR.ENG.2160p.UHD.BluRay.HDR.HEVC.DTS-HD.MA.5.1", Info{
+			Title: "Parasite", Year: 2019, HDR: "HDR", Languages: []string{"multi", "en", "ko"},
+		}},
+		{"Some.Movie.2020.HDCAM.x264", Info{Title: "Some Movie", Year: 2020, Source: "CAM"}},
+		{"Inception.2010.mkv", Info{Title: "Inception", Year: 2010}},
+		{"[www.site.org] - Heat (1995) 1080p", Info{Title: "Heat", Year: 1995, Resolution: "1080p"}},
+		{"Mr. Robot 2015 720p", Info{Title: "Mr. Robot", Year: 2015}},
+		{"2001.A.Space.Odyssey.1968.1080p.BluRay", Info{Title: "2001 A Space Odyssey", Year: 1968}},
+	})
+}
+
+// TestParseMovieFalsePositives covers titles that look like episode or tag markers
+func TestParseMovieFalsePositives(t *testing.T) {
+	checkParse(t, []parseCase{
+		{"WALL-E.2008.1080p.BluRay.x264-GRP", Info{Title: "WALL-E", Year: 2008, Group: "GRP"}},
+		{"WALL-E (2008) 720p", Info{Title: "WALL-E", Year: 2008}},
+		{"Se7en.1995.REMASTERED.1080p.BluRay.x264-GROUP", Info{Title: "Se7en", Year: 1995, Edition: "Remastered"}},
+		{"2012.2009.1080p.BluRay.x264-GROUP", Info{Title: "2012", Year: 2009}},
+		{"1917.2019.2160p.UHD.BluRay.x265-GROUP", Info{Title: "1917", Year: 2019}},
+		{"Ep.2019.1080p.WEB-DL", Info{Title: "Ep", Year: 2019}},
+		{"Episode.2015.720p.WEBRip", Info{Title: "Episode", Year: 2015}},
+		{"E.T.the.Extra-Terrestrial.1982.1080p.BluRay", Info{Title: "E T the Extra-Terrestrial", Year: 1982}},
+		{"French.Kiss.1995.720p.BluRay", Info{Title: "French Kiss", Year: 1995}},
+		{"Blade.Runner.2049.2017.1080p.BluRay", Info{Title: "Blade Runner 2049", Year: 2017}},
+	})
+
+	for _, name := range []string{
+		"WALL-E.2008.1080p.BluRay.x264-GRP",
+		"Se7en.1995.1080p.BluRay",
+		"2012.2009.1080p.BluRay",
+		"Ep.2019.1080p.WEB-DL",
+	} {
+		if Parse(name).IsEpisode() {
+			t.Errorf("Parse(%q).IsEpisode() = true, want false", name)
+		}
+	}
+	if langs := Parse("French.Kiss.1995.720p.BluRay").Languages; len(langs) != 0 {
+		t.Errorf("Languages from a title = %v, want none", langs)
+	}
+}
+
+func TestParseEpisodes(t *testing.T) {
+	checkParse(t, []parseCase{
+		{"Breaking.Bad.S05E14.1080p.WEB-DL.DD5.1.H.264-GROUP", Info{
+			Title: "Breaking Bad", Seasons: []int{5}, Episodes: []int{14}, Source: "WEB-DL", Codec: "AVC", Group: "GROUP",
+		}},
+		{"Show.Name.S01E01-E03.2160p.WEB-DL.DDP5.1.Atmos.DV.HEVC-GROUP", Info{
+			Title: "Show Name", Seasons: []int{1}, Episodes: []int{1, 2, 3}, HDR: "DV", Audio: "Atmos",
+		}},
+		{"Show.Name.S02E05E06.720p.HDTV.x264", Info{Title: "Show Name", Seasons: []int{2}, Episodes: []int{5, 6}}},
+		{"Show.Name.S02E05-07.720p", Info{Title: "Show Name", Seasons: []int{2}, Episodes: []int{5, 6, 7}}},
+		{"Show Name 1x05 HDTV", Info{Title: "Show Name", Seasons: []int{1}, Episodes: []int{5}}},
+		{"Show Name 3x01-3x03", Info{Title: "Show Name", Seasons: []int{3}, Episodes: []int{1, 2, 3}}},
+		{"Anime.Show.Episode.12.1080p", Info{Title: "Anime Show", Episodes: []int{12}}},
+		{"Anime Show Ep 05-08 720p", Info{Title: "Anime Show", Episodes: []int{5, 6, 7, 8}}},
+		{"The.Office.US.2005.S03E10.720p", Info{Title: "The Office US", Year: 2005, Seasons: []int{3}, Episodes: []int{10}}},
+		{"Doctor.Who.2005.S01E01.Rose.1080p", Info{Title: "Doctor Who", Year: 2005, Seasons: []int{1}, Episodes: []int{1}}},
+	})
+
+	if !Parse("Show.S01E02.1080p").HasEpisode(1, 2) {
+		t.Error("HasEpisode(1, 2) = false for S01E02")
+	}
+	if Parse("Show.S01E02.1080p").HasEpisode(1, 3) {
+		t.Error("HasEpisode(1, 3) = true for S01E02")
+	}
+	if !Parse("Anime.Ep.05.1080p").HasEpisode(1, 5) {
+		t.Error("HasEpisode(1, 5) = false for an episode-only name")
+	}
+}
+
+func TestParseSeasonPacks(t *testing.T) {
+	checkParse(t, []parseCase{
+		{"The.Wire.S01.1080p.BluRay.x264-GROUP", Info{Title: "The Wire", Seasons: []int{1}}},
+		{"The.Wire.S01-S05.1080p.BluRay", Info{Title: "The Wire", Seasons: []int{1, 2, 3, 4, 5}}},
+		{"The Wire Season 2 1080p", Info{Title: "The Wire", Seasons: []int{2}}},
+		{"The Wire Seasons 1-3 720p", Info{Title: "The Wire", Seasons: []int{1, 2, 3}}},
+		{"The.Wire.S01.S02.720p", Info{Title: "The Wire", Seasons: []int{1, 2}}},
+		{"The.Wire.Complete.Series.1080p", Info{Title: "The Wire", Complete: true}},
+		{"The.Wire.S03.Complete.720p", Info{Title: "The Wire", Seasons: []int{3}, Complete: true}},
+	})
+
+	pack := Parse("The.Wire.S01.1080p.BluRay")
+	if !pack.IsSeasonPack() || !pack.HasEpisode(1, 7) || pack.HasEpisode(2, 1) {
+		t.Errorf("S01 pack: IsSeasonPack=%v HasEpisode(1,7)=%v HasEpisode(2,1)=%v",
+			pack.IsSeasonPack(), pack.HasEpisode(1, 7), pack.HasEpisode(2, 1))
+	}
+	if Parse("Show.S01E01.1080p").IsSeasonPack() {
+		t.Error("single episode reported as a season pack")
+	}
+	if !Parse("Show.S01E01-E03.1080p").IsMultiEpisode() {
+		t.Error("episode range not reported as multi-episode")
+	}
+}
+
+func TestParseResolutionAndSource(t *testing.T) {
+	checkParse(t, []parseCase{
+		{"Movie.2020.4K.WEBRip", Info{Title: "Movie", Year: 2020, Resolution: "2160p", Source: "WEBRip"}},
+		{"Movie.2020.UHD.BluRay.REMUX", Info{Title: "Movie", Year: 2020, Resolution: "2160p", Source: "REMUX"}},
+		{"Movie.2020.1080i.HDTV", Info{Title: "Movie", Year: 2020, Resolution: "1080p", Source: "HDTV"}},
+		{"Movie.2020.480p.DVDRip.XviD", Info{Title: "Movie", Year: 2020, Resolution: "480p", Source: "DVDRip", Codec: "XviD"}},
+		{"Movie.2020.1080p.AMZN.WEB-DL.DDP5.1.H.264", Info{Title: "Movie", Year: 2020, Source: "WEB-DL", Audio: "DD+", Channels: "5.1"}},
+		{"Movie.2020.HDTS.x264", Info{Title: "Movie", Year: 2020, Source: "TS"}},
+		{"Movie.2020.1080p.WEB.AV1.Opus", Info{Title: "Movie", Year: 2020, Codec: "AV1", Audio: "Opus"}},
+		{"Movie.2020.2160p.WEB-DL.HDR10Plus.HEVC", Info{Title: "Movie", Year: 2020, HDR: "HDR10+"}},
+		{"Movie.2020.1080p.BluRay.FLAC.2.0.x264", Info{Title: "Movie", Year: 2020, Audio: "FLAC", Channels: "2.0"}},
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"💾 1.5 GB":     1610612736,
+		"500MB":        524288000,
+		"1,2 GiB":      1288490188,
+		"no size here": 0,
+	}
+	for text, want := range cases {
+		if got := ParseSize(text); got != want {
+			t.Errorf("ParseSize(%q) = %d, want %d", text, got, want)
+		}
+	}
+	if got := Parse("Movie.2020.1080p\n👤 12 💾 2 GB").Size; got != 2<<30 {
+		t.Errorf("Size from a Stremio title = %d, want %d", got, int64(2<<30))
+	}
+}
+
+func TestSplitTitleYear(t *testing.T) {
+	title, year := SplitTitleYear("Dune (2021) 1080p")
+	if title != "Dune" || year != 2021 {
+		t.Errorf("SplitTitleYear = %q, %d", title, year)
+	}
+}
@@ -0,0 +1,237 @@
+// Package secrets encrypts settings such as API keys and provider passwords before they are stored,
+// with AES-256-GCM under a master key from STREAMARR_SECRET_KEY or a key file.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultKeyFile is where the master key is kept when neither STREAMARR_SECRET_KEY nor
+// STREAMARR_SECRET_KEY_FILE is set; it is created on first start
+const DefaultKeyFile = "cache/secret.key"
+
+// prefix marks an encrypted value: enc:v1:<key id>:<base64 nonce and ciphertext>
+const prefix = "enc:v1:"
+
+// ErrUnknownKey means a value was encrypted with a master key that isn't configured
+var ErrUnknownKey = errors.New("encrypted with a master key that is not configured")
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring encrypts with its first key and decrypts with any of them, so values encrypted
+// before a key rotation can still be read
+type Keyring struct {
+	keys []key
+	raw  []string
+}
+
+// NewKeyring creates a keyring from encoded keys (see ParseKey), current key first
+func NewKeyring(encoded ...string) (*Keyring, error) {
+	k := &Keyring{}
+	for _, e := range encoded {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		secret, err := ParseKey(e)
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(secret)
+		k.keys = append(k.keys, key{id: hex.EncodeToString(sum[:4]), aead: aead})
+		k.raw = append(k.raw, e)
+	}
+	if len(k.keys) == 0 {
+		return nil, errors.New("no master key")
+	}
+	return k, nil
+}
+
+// ParseKey decodes a 32-byte master key written as base64 or hex
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if b, err := hex.DecodeString(encoded); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(encoded); err == nil && len(b) == 32 {
+			return b, nil
+		}
+	}
+	return nil, errors.New("master key must be 32 bytes, base64 or hex encoded (generate one with: openssl rand -base64 32)")
+}
+
+// GenerateKey returns a new random master key, base64 encoded
+func GenerateKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// Keys returns the encoded keys, current key first
+func (k *Keyring) Keys() []string {
+	return append([]string(nil), k.raw...)
+}
+
+// KeyID identifies the current key without revealing it
+func (k *Keyring) KeyID() string {
+	return k.keys[0].id
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts value with the current key. Empty and already encrypted values are returned as they are.
+func (k *Keyring) Encrypt(value string) (string, error) {
+	if value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+	current := k.keys[0]
+	nonce := make([]byte, current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := current.aead.Seal(nonce, nonce, []byte(value), []byte(current.id))
+	return prefix + current.id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plain text of an encrypted value; values that aren't encrypted are returned as they are
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, data, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	for _, candidate := range k.keys {
+		if candidate.id != id {
+			continue
+		}
+		sealed, err := base64.RawStdEncoding.DecodeString(data)
+		if err != nil || len(sealed) < candidate.aead.NonceSize() {
+			return "", errors.New("malformed encrypted value")
+		}
+		nonce, ciphertext := sealed[:candidate.aead.NonceSize()], sealed[candidate.aead.NonceSize():]
+		plain, err := candidate.aead.Open(nil, nonce, ciphertext, []byte(id))
+		if err != nil {
+			return "", fmt.Errorf("decrypt with key %s: %w", id, err)
+		}
+		return string(plain), nil
+	}
+	return "", fmt.Errorf("key %s: %w", id, ErrUnknownKey)
+}
+
+// Load returns the keyring configured by the environment and where it came from:
+// STREAMARR_SECRET_KEY (comma-separated, current key first), else the key file named by
+// STREAMARR_SECRET_KEY_FILE or DefaultKeyFile (one key per line), which is created when missing
+func Load() (*Keyring, string, error) {
+	if env := os.Getenv("STREAMARR_SECRET_KEY"); env != "" {
+		k, err := NewKeyring(strings.Split(env, ",")...)
+		if err != nil {
+			return nil, "", fmt.Errorf("STREAMARR_SECRET_KEY: %w", err)
+		}
+		return k, "STREAMARR_SECRET_KEY", nil
+	}
+	path := KeyFile()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := createKeyFile(path); err != nil {
+			return nil, "", err
+		}
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("read master key: %w", err)
+	}
+	k, err := NewKeyring(strings.Split(string(data), "\n")...)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return k, path, nil
+}
+
+// KeyFile returns the path of the key file
+func KeyFile() string {
+	if path := os.Getenv("STREAMARR_SECRET_KEY_FILE"); path != "" {
+		return path
+	}
+	return DefaultKeyFile
+}
+
+// createKeyFile writes a new key to path unless another process got there first
+func createKeyFile(path string) error {
+	encoded, err := GenerateKey()
+	if err != nil {
+		return err
+	}
+	tmp, err := writeTemp(path, encoded+"\n")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	// Link fails when the file exists, so a key written concurrently by another process wins
+	if err := os.Link(tmp, path); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("create master key: %w", err)
+	}
+	return nil
+}
+
+// WriteKeyFile replaces the key file with keys, current key first
+func WriteKeyFile(path string, keys ...string) error {
+	tmp, err := writeTemp(path, strings.Join(keys, "\n")+"\n")
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write master key: %w", err)
+	}
+	return nil
+}
+
+func writeTemp(path, content string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("create key directory: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".secret-key-*")
+	if err != nil {
+		return "", fmt.Errorf("write master key: %w", err)
+	}
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0600)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write master key: %w", err)
+	}
+	return f.Name(), nil
+}
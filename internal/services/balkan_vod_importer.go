@@ -10,7 +10,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/activity"
 	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
 	"github.com/Zerr0-C00L/StreamArr/internal/models"
 	"github.com/Zerr0-C00L/StreamArr/internal/settings"
 )
@@ -21,6 +23,7 @@ type BalkanVODImporter struct {
 	seriesStore *database.SeriesStore
 	tmdb        *TMDBClient
 	cfg         *settings.Settings
+	activity    *activity.Recorder // Records finished imports in the activity feed
 }
 
 // BalkanMovieEntry represents a movie from baubau-content.json
@@ -153,6 +156,11 @@ func NewBalkanVODImporter(movieStore *database.MovieStore, seriesStore *database
 	}
 }
 
+// SetActivity records finished imports in the activity feed
+func (b *BalkanVODImporter) SetActivity(recorder *activity.Recorder) {
+	b.activity = recorder
+}
+
 // fetchBalkanData fetches content from Balkan On Demand GitHub repo
 func fetchBalkanData() (*BalkanContentDatabase, error) {
 	log.Printf("[BalkanVOD] Fetching from GitHub: %s", balkanRepoURL)
@@ -245,7 +253,7 @@ func (b *BalkanVODImporter) ImportBalkanVOD(ctx context.Context) error {
 	}
 
 	log.Println("[BalkanVOD] Starting import from GitHub repo...")
-	GlobalScheduler.UpdateProgress(ServiceBalkanVODSync, 0, 0, "Fetching content from GitHub...")
+	jobs.Progress(ctx, 0, 0, "Fetching content from GitHub...")
 	
 	// Fetch content from Balkan On Demand repo
 	content, err := fetchBalkanData()
@@ -255,7 +263,7 @@ func (b *BalkanVODImporter) ImportBalkanVOD(ctx context.Context) error {
 
 	totalItems := len(content.Movies) + len(content.Series)
 	log.Printf("[BalkanVOD] Fetched %d movies and %d series", len(content.Movies), len(content.Series))
-	GlobalScheduler.UpdateProgress(ServiceBalkanVODSync, 0, totalItems, 
+	jobs.Progress(ctx, 0, totalItems, 
 		fmt.Sprintf("Found %d movies, %d series", len(content.Movies), len(content.Series)))
 
 	// Get selected categories from settings
@@ -275,9 +283,12 @@ func (b *BalkanVODImporter) ImportBalkanVOD(ctx context.Context) error {
 	processedCount := 0
 
 	for i, movie := range content.Movies {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		processedCount++
 		if i%25 == 0 {
-			GlobalScheduler.UpdateProgress(ServiceBalkanVODSync, processedCount, totalItems, 
+			jobs.Progress(ctx, processedCount, totalItems, 
 				fmt.Sprintf("Importing movie: %s", movie.Name))
 		}
 		
@@ -314,9 +325,12 @@ func (b *BalkanVODImporter) ImportBalkanVOD(ctx context.Context) error {
 	// Import series (all series are domestic)
 	log.Printf("[BalkanVOD] Starting series import: %d series to process", len(content.Series))
 	for i, series := range content.Series {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		processedCount++
 		if i%10 == 0 {
-			GlobalScheduler.UpdateProgress(ServiceBalkanVODSync, processedCount, totalItems, 
+			jobs.Progress(ctx, processedCount, totalItems, 
 				fmt.Sprintf("Importing series: %s", series.Name))
 		}
 		log.Printf("[BalkanVOD] Processing series: %s (ID: %s, Seasons: %d)", series.Name, series.ID, len(series.Seasons))
@@ -331,10 +345,16 @@ func (b *BalkanVODImporter) ImportBalkanVOD(ctx context.Context) error {
 		}
 	}
 	
-	GlobalScheduler.UpdateProgress(ServiceBalkanVODSync, totalItems, totalItems, 
+	jobs.Progress(ctx, totalItems, totalItems, 
 		fmt.Sprintf("Complete: %d new, %d updated, %d failed", imported, updated, failed))
 
 	log.Printf("[BalkanVOD] Import complete: %d new, %d updated, %d skipped (%d by category filter, %d not domestic), %d failed", imported, updated, skipped, skippedByCategory, skippedByDomestic, failed)
+	b.activity.Record(ctx, activity.Event{
+		Type:        database.EventImport,
+		ContentType: "balkan_vod",
+		Message:     fmt.Sprintf("Balkan VOD import: %d new, %d updated, %d failed", imported, updated, failed),
+		Data:        map[string]interface{}{"imported": imported, "updated": updated, "skipped": skipped, "failed": failed},
+	})
 	return nil
 }
 
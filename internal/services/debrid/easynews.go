@@ -0,0 +1,209 @@
+package debrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
+)
+
+const (
+	easynewsBaseURL = "https://members.easynews.com"
+)
+
+// Easynews implements UsenetService for Easynews-style search-and-stream accounts
+// Everything within retention streams directly, so there is no cache to check
+type Easynews struct {
+	username   string
+	password   string
+	baseURL    string
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+// easynewsSearchResponse is the subset of the solr-search response we use
+// Result fields are keyed by position: "0" is the file hash, "10" the name, "11" the extension
+type easynewsSearchResponse struct {
+	Data []struct {
+		Hash      string      `json:"0"`
+		PostedAt  string      `json:"5"`
+		Name      string      `json:"10"`
+		Extension string      `json:"11"`
+		RawSize   json.Number `json:"rawSize"`
+		Type      string      `json:"type"`
+		Password  bool        `json:"passwd"`
+		Virus     bool        `json:"virus"`
+	} `json:"data"`
+	DLFarm string      `json:"dlFarm"`
+	DLPort interface{} `json:"dlPort"`
+}
+
+// NewEasynews creates a new Easynews service instance
+func NewEasynews(username, password string, logger *slog.Logger) *Easynews {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Easynews{
+		username: username,
+		password: password,
+		baseURL:  easynewsBaseURL,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: metrics.Transport("easynews", nil),
+		},
+		logger: logger,
+	}
+}
+
+// SetBaseURL points the client at another host (e.g. a local stand-in)
+func (e *Easynews) SetBaseURL(baseURL string) {
+	e.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// Search runs a text search; Easynews has no IMDB index so Title is required
+func (e *Easynews) Search(ctx context.Context, query UsenetQuery) ([]NZBResult, error) {
+	if query.Title == "" {
+		return nil, fmt.Errorf("easynews search needs a title")
+	}
+
+	text := query.Title
+	if query.IsEpisode() {
+		text = fmt.Sprintf("%s S%02dE%02d", query.Title, query.Season, query.Episode)
+	} else if query.Year > 0 {
+		text = fmt.Sprintf("%s %d", query.Title, query.Year)
+	}
+
+	params := url.Values{}
+	params.Set("gps", text)
+	params.Set("fty[]", "VIDEO")
+	params.Set("st", "adv")
+	params.Set("sb", "1")
+	params.Set("pno", "1")
+	params.Set("pby", "100")
+	params.Set("s1", "dsize")
+	params.Set("s1d", "-")
+	params.Set("u", "1")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", e.baseURL+"/2.0/search/solr-search/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.SetBasicAuth(e.username, e.password)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("easynews API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result easynewsSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	farm := result.DLFarm
+	if farm == "" {
+		farm = "auto"
+	}
+	port := easynewsPort(result.DLPort)
+	if port == "" {
+		port = "443"
+	}
+
+	results := make([]NZBResult, 0, len(result.Data))
+	for _, item := range result.Data {
+		if item.Hash == "" || item.Password || item.Virus || (item.Type != "" && item.Type != "VIDEO") {
+			continue
+		}
+
+		ext := item.Extension
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		size, _ := item.RawSize.Int64()
+		posted, _ := time.Parse("2006-01-02 15:04:05", item.PostedAt)
+
+		results = append(results, NZBResult{
+			// The ID is the download path below /dl, so streaming needs no extra lookup
+			ID:        fmt.Sprintf("%s/%s/%s%s/%s%s", farm, port, item.Hash, ext, url.PathEscape(item.Name), ext),
+			Title:     item.Name,
+			FileName:  item.Name + ext,
+			Size:      size,
+			PostedAt:  posted,
+			Available: true,
+		})
+	}
+
+	e.logger.Debug("Easynews search complete", "query", text, "results", len(results))
+	return results, nil
+}
+
+// CheckAvailability reports every ID as available; Easynews streams anything in retention
+func (e *Easynews) CheckAvailability(ctx context.Context, ids []string) (map[string]bool, error) {
+	available := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		available[id] = true
+	}
+	return available, nil
+}
+
+// GetStreamURL returns the authenticated download URL for a search result
+func (e *Easynews) GetStreamURL(ctx context.Context, result NZBResult) (string, error) {
+	if result.ID == "" {
+		return "", fmt.Errorf("result has no easynews ID")
+	}
+
+	base, err := url.Parse(e.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	// Players cannot send basic auth headers, so credentials go in the URL
+	base.User = url.UserPassword(e.username, e.password)
+	return base.String() + "/dl/" + result.ID, nil
+}
+
+// GetServiceName returns the service name
+func (e *Easynews) GetServiceName() string {
+	return "Easynews"
+}
+
+// IsAuthenticated checks the credentials with a minimal search
+func (e *Easynews) IsAuthenticated(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, "GET", e.baseURL+"/2.0/search/solr-search/?gps=test&pby=1", nil)
+	if err != nil {
+		return false
+	}
+	req.SetBasicAuth(e.username, e.password)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// easynewsPort normalises the dlPort field, which is sent as either a number or a string
+func easynewsPort(v interface{}) string {
+	switch p := v.(type) {
+	case float64:
+		return strconv.Itoa(int(p))
+	case string:
+		return p
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package debrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEasynewsSearch(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "hunter2" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/2.0/search/solr-search/" {
+			http.NotFound(w, r)
+			return
+		}
+		gotQuery = r.URL.Query().Get("gps")
+		fmt.Fprint(w, `{
+			"dlFarm": "iad",
+			"dlPort": 443,
+			"data": [
+				{"0": "abc123", "5": "2024-05-01 12:00:00", "10": "Show.S01E02.1080p.WEB-DL", "11": "mkv", "rawSize": 2147483648, "type": "VIDEO"},
+				{"0": "locked", "10": "Show.S01E02.720p", "11": "mkv", "rawSize": 1, "type": "VIDEO", "passwd": true},
+				{"0": "infected", "10": "Show.S01E02.480p", "11": "mkv", "rawSize": 1, "type": "VIDEO", "virus": true},
+				{"0": "", "10": "No hash", "11": "mkv", "rawSize": 1}
+			]
+		}`)
+	}))
+	defer server.Close()
+
+	easynews := NewEasynews("alice", "hunter2", nil)
+	easynews.SetBaseURL(server.URL)
+
+	results, err := easynews.Search(context.Background(), UsenetQuery{Title: "Show", Season: 1, Episode: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "Show S01E02" {
+		t.Errorf("searched for %q, want %q", gotQuery, "Show S01E02")
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want passworded, infected and hashless posts skipped", len(results))
+	}
+	r := results[0]
+	if r.ID != "iad/443/abc123.mkv/Show.S01E02.1080p.WEB-DL.mkv" || r.FileName != "Show.S01E02.1080p.WEB-DL.mkv" || r.Size != 2<<30 || !r.Available || r.PostedAt.IsZero() {
+		t.Errorf("result = %+v", r)
+	}
+
+	link, err := easynews.GetStreamURL(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(server.URL, "http://", "http://alice:hunter2@", 1) + "/dl/" + r.ID
+	if link != want {
+		t.Errorf("stream URL = %q, want %q", link, want)
+	}
+}
+
+func TestEasynewsSearchNeedsTitle(t *testing.T) {
+	if _, err := NewEasynews("alice", "hunter2", nil).Search(context.Background(), UsenetQuery{IMDBID: "tt0113277"}); err == nil {
+		t.Error("search without a title succeeded")
+	}
+}
+
+func TestEasynewsAuthentication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pass, _ := r.BasicAuth(); pass != "right" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"data": []}`)
+	}))
+	defer server.Close()
+
+	for pass, want := range map[string]bool{"right": true, "wrong": false} {
+		easynews := NewEasynews("alice", pass, nil)
+		easynews.SetBaseURL(server.URL)
+		if got := easynews.IsAuthenticated(context.Background()); got != want {
+			t.Errorf("IsAuthenticated with %s password = %v, want %v", pass, got, want)
+		}
+	}
+}
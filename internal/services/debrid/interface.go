@@ -11,6 +11,7 @@ type DebridService interface {
 
 	// GetStreamURL returns the direct streaming URL for a cached torrent hash
 	// This URL can be used for instant playback without downloading
+	// A fileIndex of 0 streams the whole torrent; otherwise it is a file Index from GetAvailableFiles
 	GetStreamURL(ctx context.Context, hash string, fileIndex int) (string, error)
 
 	// GetAvailableFiles returns list of files available in a cached torrent
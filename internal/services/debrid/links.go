@@ -0,0 +1,28 @@
+package debrid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/cache"
+)
+
+// cachedLinks routes GetStreamURL of a debrid service through the link cache
+type cachedLinks struct {
+	DebridService
+	links *cache.LinkCache
+}
+
+// WithLinkCache wraps a debrid service so resolved stream URLs are reused until they expire
+func WithLinkCache(service DebridService, links *cache.LinkCache) DebridService {
+	return &cachedLinks{DebridService: service, links: links}
+}
+
+// GetStreamURL returns the cached URL for the hash and file, resolving it through the service when needed
+func (c *cachedLinks) GetStreamURL(ctx context.Context, hash string, fileIndex int) (string, error) {
+	fileID := fmt.Sprintf("%s:%d", c.GetServiceName(), fileIndex)
+	return c.links.Get(ctx, strings.ToLower(hash), fileID, func(ctx context.Context) (string, error) {
+		return c.DebridService.GetStreamURL(ctx, hash, fileIndex)
+	})
+}
@@ -6,9 +6,15 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
 )
 
 const (
@@ -27,7 +33,8 @@ func NewRealDebrid(apiKey string, logger *slog.Logger) *RealDebrid {
 	return &RealDebrid{
 		apiKey: apiKey,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: metrics.Transport("realdebrid", nil),
 		},
 		logger: logger,
 	}
@@ -121,9 +128,13 @@ func (rd *RealDebrid) GetStreamURL(ctx context.Context, hash string, fileIndex i
 		return "", fmt.Errorf("decode add magnet response: %w", err)
 	}
 
-	// Step 2: Select files (all files)
+	// Step 2: Select files (a single file for season packs, otherwise all files)
+	selection := "all"
+	if fileIndex > 0 {
+		selection = strconv.Itoa(fileIndex)
+	}
 	selectURL := fmt.Sprintf("%s/torrents/selectFiles/%s", realDebridBaseURL, addResult.ID)
-	selectReq, err := http.NewRequestWithContext(ctx, "POST", selectURL, strings.NewReader("files=all"))
+	selectReq, err := http.NewRequestWithContext(ctx, "POST", selectURL, strings.NewReader("files="+selection))
 	if err != nil {
 		return "", fmt.Errorf("create select files request: %w", err)
 	}
@@ -191,20 +202,68 @@ func (rd *RealDebrid) GetStreamURL(ctx context.Context, hash string, fileIndex i
 }
 
 // GetAvailableFiles returns list of files in a cached torrent
+// Files are merged across all cached variants; Index is Real-Debrid's 1-based file ID
 func (rd *RealDebrid) GetAvailableFiles(ctx context.Context, hash string) ([]TorrentFile, error) {
-	// For instant availability, we need to check the cache status first
-	cached, err := rd.CheckCache(ctx, []string{hash})
+	url := fmt.Sprintf("%s/torrents/instantAvailability/%s", realDebridBaseURL, hash)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+rd.apiKey)
+
+	resp, err := rd.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("check cache: %w", err)
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("real-debrid API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// Format: { "hash": { "rd": [ { "1": { "filename": "...", "filesize": 123 }, ... } ] } }
+	var availability map[string]map[string][]map[string]struct {
+		Filename string `json:"filename"`
+		Filesize int64  `json:"filesize"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&availability); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
-	if !cached[hash] {
+	variants := availability[strings.ToLower(hash)]["rd"]
+	if len(variants) == 0 {
 		return nil, fmt.Errorf("torrent not cached")
 	}
 
-	// Note: Full implementation would parse the instant availability response
-	// to get detailed file information. For now, return basic info.
-	return []TorrentFile{}, nil
+	seen := make(map[int]bool)
+	var files []TorrentFile
+	for _, variant := range variants {
+		for id, file := range variant {
+			index, err := strconv.Atoi(id)
+			if err != nil || seen[index] {
+				continue
+			}
+			seen[index] = true
+			files = append(files, TorrentFile{
+				Index:    index,
+				Path:     file.Filename,
+				Size:     file.Filesize,
+				Selected: true,
+				MimeType: mime.TypeByExtension(path.Ext(file.Filename)),
+			})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Index < files[j].Index })
+
+	rd.logger.Info("Listed Real-Debrid cached files",
+		"hash", hash,
+		"files", len(files))
+
+	return files, nil
 }
 
 // GetServiceName returns the service name
@@ -0,0 +1,90 @@
+package debrid
+
+import (
+	"path"
+	"strings"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
+)
+
+// EpisodeFile maps a file inside a season pack to the episode it contains
+type EpisodeFile struct {
+	Season  int
+	Episode int
+	File    TorrentFile
+}
+
+// MapEpisodeFiles parses every video file in a torrent and returns the episodes it contains
+// When several files map to the same episode the largest one wins
+func MapEpisodeFiles(files []TorrentFile) []EpisodeFile {
+	best := make(map[[2]int]EpisodeFile)
+	var order [][2]int
+
+	for _, file := range files {
+		if !release.IsVideoFile(file.Path) || release.IsExtra(file.Path) {
+			continue
+		}
+
+		for _, ep := range episodesForPath(file.Path) {
+			key := [2]int{ep[0], ep[1]}
+			existing, ok := best[key]
+			if !ok {
+				order = append(order, key)
+			}
+			if !ok || file.Size > existing.File.Size {
+				best[key] = EpisodeFile{Season: ep[0], Episode: ep[1], File: file}
+			}
+		}
+	}
+
+	result := make([]EpisodeFile, 0, len(order))
+	for _, key := range order {
+		result = append(result, best[key])
+	}
+	return result
+}
+
+// FindEpisodeFile returns the file for season/episode from a mapping
+func FindEpisodeFile(mapping []EpisodeFile, season, episode int) (TorrentFile, bool) {
+	for _, ep := range mapping {
+		if ep.Season == season && ep.Episode == episode {
+			return ep.File, true
+		}
+	}
+	return TorrentFile{}, false
+}
+
+// episodesForPath returns the season/episode pairs contained in a file path
+// The file name is tried first, then the parent folder supplies a missing season
+func episodesForPath(filePath string) [][2]int {
+	clean := strings.ReplaceAll(filePath, "\\", "/")
+	base := path.Base(clean)
+	dir := path.Dir(clean)
+
+	info := release.Parse(base)
+	seasons := info.Seasons
+	episodes := info.Episodes
+
+	if len(seasons) == 0 && dir != "." && dir != "/" {
+		seasons = release.Parse(path.Base(dir)).Seasons
+	}
+	if len(episodes) == 0 && len(seasons) == 1 {
+		if n, ok := release.LeadingEpisode(base); ok {
+			episodes = []int{n}
+		}
+	}
+
+	// Episode-only names inside a pack with no season anywhere are treated as season 1
+	if len(seasons) == 0 && len(episodes) > 0 {
+		seasons = []int{1}
+	}
+	if len(seasons) != 1 || len(episodes) == 0 {
+		return nil
+	}
+
+	pairs := make([][2]int, 0, len(episodes))
+	for _, ep := range episodes {
+		pairs = append(pairs, [2]int{seasons[0], ep})
+	}
+	return pairs
+}
@@ -0,0 +1,274 @@
+package debrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
+)
+
+const (
+	torboxBaseURL   = "https://api.torbox.app/v1/api"
+	torboxSearchURL = "https://search-api.torbox.app"
+)
+
+// TorBox implements UsenetService for TorBox-style usenet debrid
+// NZBs are imported into the account and streamed once the service has them cached
+type TorBox struct {
+	apiKey     string
+	baseURL    string
+	searchURL  string
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+// torboxResponse is the envelope around every TorBox API response
+type torboxResponse struct {
+	Success bool            `json:"success"`
+	Error   string          `json:"error"`
+	Detail  string          `json:"detail"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type torboxNZB struct {
+	Hash     string `json:"hash"`
+	RawTitle string `json:"raw_title"`
+	Title    string `json:"title"`
+	Size     int64  `json:"size"`
+	NZB      string `json:"nzb"`
+	Cached   bool   `json:"cached"`
+	Age      string `json:"age"`
+}
+
+type torboxDownload struct {
+	ID              int64 `json:"id"`
+	DownloadPresent bool  `json:"download_present"`
+	Files           []struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimetype"`
+	} `json:"files"`
+}
+
+// NewTorBox creates a new TorBox service instance
+func NewTorBox(apiKey string, logger *slog.Logger) *TorBox {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &TorBox{
+		apiKey:    apiKey,
+		baseURL:   torboxBaseURL,
+		searchURL: torboxSearchURL,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: metrics.Transport("torbox", nil),
+		},
+		logger: logger,
+	}
+}
+
+// SetBaseURLs points the client at other hosts (e.g. local stand-ins)
+func (t *TorBox) SetBaseURLs(apiURL, searchURL string) {
+	t.baseURL = strings.TrimRight(apiURL, "/")
+	t.searchURL = strings.TrimRight(searchURL, "/")
+}
+
+// Search looks up NZBs by IMDB ID with cache status included
+func (t *TorBox) Search(ctx context.Context, query UsenetQuery) ([]NZBResult, error) {
+	if query.IMDBID == "" {
+		return nil, fmt.Errorf("torbox search needs an IMDB ID")
+	}
+
+	params := url.Values{}
+	params.Set("check_cache", "true")
+	if query.IsEpisode() {
+		params.Set("season", strconv.Itoa(query.Season))
+		params.Set("episode", strconv.Itoa(query.Episode))
+	}
+
+	var data struct {
+		NZBs []torboxNZB `json:"nzbs"`
+	}
+	endpoint := fmt.Sprintf("%s/usenet/imdb:%s?%s", t.searchURL, url.PathEscape(query.IMDBID), params.Encode())
+	if err := t.do(ctx, "GET", endpoint, nil, &data); err != nil {
+		return nil, err
+	}
+
+	results := make([]NZBResult, 0, len(data.NZBs))
+	for _, nzb := range data.NZBs {
+		if nzb.Hash == "" || nzb.NZB == "" {
+			continue
+		}
+		title := nzb.RawTitle
+		if title == "" {
+			title = nzb.Title
+		}
+		results = append(results, NZBResult{
+			ID:        strings.ToLower(nzb.Hash),
+			Title:     title,
+			Size:      nzb.Size,
+			PostedAt:  torboxPostedAt(nzb.Age),
+			NZBURL:    nzb.NZB,
+			Available: nzb.Cached,
+		})
+	}
+
+	t.logger.Debug("TorBox search complete", "imdb_id", query.IMDBID, "results", len(results))
+	return results, nil
+}
+
+// CheckAvailability checks which NZB hashes are cached on TorBox
+func (t *TorBox) CheckAvailability(ctx context.Context, ids []string) (map[string]bool, error) {
+	available := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return available, nil
+	}
+
+	params := url.Values{}
+	params.Set("hash", strings.Join(ids, ","))
+	params.Set("format", "object")
+
+	var data map[string]json.RawMessage
+	if err := t.do(ctx, "GET", t.baseURL+"/usenet/checkcached?"+params.Encode(), nil, &data); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		_, ok := data[strings.ToLower(id)]
+		available[id] = ok
+	}
+	return available, nil
+}
+
+// GetStreamURL imports the NZB into the account and returns a link to its largest video file
+func (t *TorBox) GetStreamURL(ctx context.Context, result NZBResult) (string, error) {
+	if result.NZBURL == "" {
+		return "", fmt.Errorf("result has no NZB link")
+	}
+
+	form := url.Values{}
+	form.Set("link", result.NZBURL)
+	if result.Title != "" {
+		form.Set("name", result.Title)
+	}
+
+	var created struct {
+		ID int64 `json:"usenetdownload_id"`
+	}
+	if err := t.do(ctx, "POST", t.baseURL+"/usenet/createusenetdownload", form, &created); err != nil {
+		return "", fmt.Errorf("create usenet download: %w", err)
+	}
+
+	var download torboxDownload
+	if err := t.do(ctx, "GET", fmt.Sprintf("%s/usenet/mylist?id=%d", t.baseURL, created.ID), nil, &download); err != nil {
+		return "", fmt.Errorf("get usenet download: %w", err)
+	}
+	if !download.DownloadPresent {
+		return "", fmt.Errorf("usenet download %d is not cached yet", created.ID)
+	}
+
+	fileID := -1
+	var largest int64
+	for _, file := range download.Files {
+		if !release.IsVideoFile(file.Name) && !strings.HasPrefix(file.MimeType, "video/") {
+			continue
+		}
+		if file.Size > largest {
+			largest = file.Size
+			fileID = file.ID
+		}
+	}
+	if fileID < 0 {
+		return "", fmt.Errorf("no video file in usenet download %d", created.ID)
+	}
+
+	params := url.Values{}
+	params.Set("token", t.apiKey)
+	params.Set("usenet_id", strconv.FormatInt(created.ID, 10))
+	params.Set("file_id", strconv.Itoa(fileID))
+
+	var link string
+	if err := t.do(ctx, "GET", t.baseURL+"/usenet/requestdl?"+params.Encode(), nil, &link); err != nil {
+		return "", fmt.Errorf("request download link: %w", err)
+	}
+	return link, nil
+}
+
+// GetServiceName returns the service name
+func (t *TorBox) GetServiceName() string {
+	return "TorBox"
+}
+
+// IsAuthenticated checks if the API key is valid
+func (t *TorBox) IsAuthenticated(ctx context.Context) bool {
+	var user map[string]interface{}
+	return t.do(ctx, "GET", t.baseURL+"/user/me", nil, &user) == nil
+}
+
+// do sends an authenticated request and decodes the data field of the response envelope
+// A non-nil form is sent as a POST body
+func (t *TorBox) do(ctx context.Context, method, endpoint string, form url.Values, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	var envelope torboxResponse
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return fmt.Errorf("torbox API error (status %d): %s", resp.StatusCode, string(raw))
+	}
+	if resp.StatusCode != http.StatusOK || !envelope.Success {
+		msg := envelope.Detail
+		if msg == "" {
+			msg = envelope.Error
+		}
+		return fmt.Errorf("torbox API error (status %d): %s", resp.StatusCode, msg)
+	}
+
+	if out == nil || len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// torboxPostedAt converts an age like "12d" into a posting time
+func torboxPostedAt(age string) time.Time {
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(age), "d"))
+	if err != nil || days < 0 {
+		return time.Time{}
+	}
+	return time.Now().AddDate(0, 0, -days)
+}
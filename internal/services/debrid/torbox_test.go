@@ -0,0 +1,114 @@
+package debrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// torboxStandIn serves the TorBox API and search API endpoints the client uses
+func torboxStandIn(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /usenet/imdb:tt0944947", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("season") != "1" || r.URL.Query().Get("episode") != "2" {
+			t.Errorf("episode search query = %v", r.URL.Query())
+		}
+		fmt.Fprint(w, `{"success": true, "data": {"nzbs": [
+			{"hash": "ABC", "raw_title": "Show.S01E02.1080p", "size": 100, "nzb": "https://indexer/abc.nzb", "cached": true, "age": "3d"},
+			{"hash": "DEF", "title": "Show S01E02 720p", "size": 50, "nzb": "https://indexer/def.nzb", "cached": false},
+			{"hash": "GHI", "raw_title": "No NZB link"}
+		]}}`)
+	})
+	mux.HandleFunc("GET /usenet/checkcached", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "data": {"abc": {"name": "Show.S01E02.1080p"}}}`)
+	})
+	mux.HandleFunc("POST /usenet/createusenetdownload", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("link") != "https://indexer/abc.nzb" {
+			t.Errorf("imported %q", r.FormValue("link"))
+		}
+		fmt.Fprint(w, `{"success": true, "data": {"usenetdownload_id": 77}}`)
+	})
+	mux.HandleFunc("GET /usenet/mylist", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "data": {"id": 77, "download_present": true, "files": [
+			{"id": 1, "name": "Show.S01E02.1080p/sample.mkv", "size": 10},
+			{"id": 2, "name": "Show.S01E02.1080p/Show.S01E02.1080p.mkv", "size": 1000},
+			{"id": 3, "name": "Show.S01E02.1080p/Show.nfo", "size": 5000}
+		]}}`)
+	})
+	mux.HandleFunc("GET /usenet/requestdl", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("usenet_id") != "77" || q.Get("file_id") != "2" || q.Get("token") != "key" {
+			t.Errorf("requestdl query = %v", q)
+		}
+		fmt.Fprint(w, `{"success": true, "data": "https://cdn.torbox/stream/77/2"}`)
+	})
+	mux.HandleFunc("GET /user/me", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"success": false, "error": "BAD_TOKEN", "detail": "Invalid API key"}`)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("%s %s sent Authorization %q", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func TestTorBoxSearchAndStream(t *testing.T) {
+	server := torboxStandIn(t)
+	defer server.Close()
+
+	torbox := NewTorBox("key", nil)
+	torbox.SetBaseURLs(server.URL, server.URL)
+	ctx := context.Background()
+
+	results, err := torbox.Search(ctx, UsenetQuery{IMDBID: "tt0944947", Season: 1, Episode: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want the one without an NZB link skipped", len(results))
+	}
+	if r := results[0]; r.ID != "abc" || r.Title != "Show.S01E02.1080p" || !r.Available || r.PostedAt.IsZero() {
+		t.Errorf("first result = %+v", r)
+	}
+	if r := results[1]; r.ID != "def" || r.Title != "Show S01E02 720p" || r.Available {
+		t.Errorf("second result = %+v", r)
+	}
+
+	available, err := torbox.CheckAvailability(ctx, []string{"abc", "def"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !available["abc"] || available["def"] {
+		t.Errorf("availability = %v", available)
+	}
+
+	link, err := torbox.GetStreamURL(ctx, results[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://cdn.torbox/stream/77/2" {
+		t.Errorf("stream URL = %q", link)
+	}
+}
+
+func TestTorBoxErrors(t *testing.T) {
+	server := torboxStandIn(t)
+	defer server.Close()
+
+	torbox := NewTorBox("key", nil)
+	torbox.SetBaseURLs(server.URL, server.URL)
+	if torbox.IsAuthenticated(context.Background()) {
+		t.Error("IsAuthenticated succeeded with a rejected key")
+	}
+	if _, err := torbox.Search(context.Background(), UsenetQuery{Title: "Show"}); err == nil {
+		t.Error("search without an IMDB ID succeeded")
+	}
+	if _, err := torbox.GetStreamURL(context.Background(), NZBResult{ID: "abc"}); err == nil {
+		t.Error("streaming a result without an NZB link succeeded")
+	}
+}
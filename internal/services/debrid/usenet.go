@@ -0,0 +1,53 @@
+package debrid
+
+import (
+	"context"
+	"time"
+)
+
+// UsenetService defines the interface for NZB-based sources
+// Supports Easynews-style search-and-stream services and TorBox-style usenet debrid
+type UsenetService interface {
+	// Search finds NZB releases for a movie (Season 0) or an episode
+	Search(ctx context.Context, query UsenetQuery) ([]NZBResult, error)
+
+	// CheckAvailability reports which result IDs can be streamed immediately
+	// Returns a map of ID -> isAvailable
+	CheckAvailability(ctx context.Context, ids []string) (map[string]bool, error)
+
+	// GetStreamURL returns a direct streaming URL for a search result
+	// Services that need to fetch the NZB first may take a while on uncached results
+	GetStreamURL(ctx context.Context, result NZBResult) (string, error)
+
+	// GetServiceName returns the name of the usenet service (e.g., "Easynews")
+	GetServiceName() string
+
+	// IsAuthenticated checks if the service has valid authentication
+	IsAuthenticated(ctx context.Context) bool
+}
+
+// UsenetQuery describes what to search for
+// Services without IMDB search use Title and Year instead
+type UsenetQuery struct {
+	IMDBID  string
+	Title   string
+	Year    int
+	Season  int // 0 for movies
+	Episode int
+}
+
+// IsEpisode reports whether the query is for a series episode
+func (q UsenetQuery) IsEpisode() bool {
+	return q.Season > 0 || q.Episode > 0
+}
+
+// NZBResult is a single release found on a usenet service
+type NZBResult struct {
+	ID        string    // Service-specific ID used for availability and streaming
+	Title     string    // Release name
+	FileName  string    // Video file name, when known
+	Size      int64     // Bytes
+	PostedAt  time.Time // Zero if unknown
+	NZBURL    string    // Link to the NZB, for services that import NZBs
+	Available bool      // Instantly streamable
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
+)
+
+// ScanEpisodes fetches episode metadata from TMDB for all series in the library
+func ScanEpisodes(ctx context.Context, seriesStore *database.SeriesStore, episodeStore *database.EpisodeStore, tmdbClient *TMDBClient) error {
+	log.Println("[Episode Scan] Starting episode scan for all series...")
+	jobs.Progress(ctx, 0, 0, "Loading series list...")
+
+	// Get all series from the library
+	allSeries, err := seriesStore.List(ctx, 0, 10000, nil)
+	if err != nil {
+		log.Printf("[Episode Scan] Failed to list series: %v", err)
+		return err
+	}
+
+	totalSeries := len(allSeries)
+	if totalSeries == 0 {
+		log.Println("[Episode Scan] No series in library")
+		jobs.Progress(ctx, 0, 0, "No series in library")
+		return nil
+	}
+
+	log.Printf("[Episode Scan] Found %d series to scan", totalSeries)
+	jobs.Progress(ctx, 0, totalSeries, fmt.Sprintf("Scanning %d series...", totalSeries))
+
+	totalEpisodes := 0
+	seriesProcessed := 0
+	errors := 0
+
+	for i, series := range allSeries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		jobs.Progress(ctx, i+1, totalSeries, fmt.Sprintf("Scanning: %s", series.Title))
+
+		// Get series details from TMDB to get number of seasons
+		tmdbSeries, err := tmdbClient.GetSeries(ctx, series.TMDBID)
+		if err != nil {
+			log.Printf("[Episode Scan] Failed to get details for '%s' (TMDB:%d): %v", series.Title, series.TMDBID, err)
+			errors++
+			continue
+		}
+
+		numSeasons := tmdbSeries.Seasons
+		if numSeasons == 0 {
+			log.Printf("[Episode Scan] '%s' has 0 seasons, skipping", series.Title)
+			continue
+		}
+
+		// Get all episodes for this series
+		episodes, err := tmdbClient.GetEpisodes(ctx, series.ID, series.TMDBID, numSeasons)
+		if err != nil {
+			log.Printf("[Episode Scan] Failed to get episodes for '%s': %v", series.Title, err)
+			errors++
+			continue
+		}
+
+		// Set the series ID for all episodes
+		for _, ep := range episodes {
+			ep.SeriesID = series.ID
+			ep.Monitored = series.Monitored
+		}
+
+		// Add episodes to database (batch insert)
+		if len(episodes) > 0 {
+			if err := episodeStore.AddBatch(ctx, episodes); err != nil {
+				// Duplicates are expected on rescans
+				if !containsDuplicateError(err.Error()) {
+					log.Printf("[Episode Scan] Failed to add episodes for '%s': %v", series.Title, err)
+					errors++
+				}
+			} else {
+				totalEpisodes += len(episodes)
+				log.Printf("[Episode Scan] Added %d episodes for '%s'", len(episodes), series.Title)
+			}
+		}
+
+		seriesProcessed++
+
+		// Rate limit TMDB requests (40 per 10s limit)
+		time.Sleep(300 * time.Millisecond)
+	}
+
+	jobs.Progress(ctx, totalSeries, totalSeries,
+		fmt.Sprintf("Complete: %d episodes from %d series", totalEpisodes, seriesProcessed))
+
+	log.Printf("[Episode Scan] Complete: %d episodes added from %d series (%d errors)",
+		totalEpisodes, seriesProcessed, errors)
+	return nil
+}
+
+// containsDuplicateError checks if an error message indicates a duplicate key violation
+func containsDuplicateError(errMsg string) bool {
+	return strings.Contains(errMsg, "duplicate key") || strings.Contains(errMsg, "UNIQUE constraint")
+}
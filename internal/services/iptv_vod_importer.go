@@ -7,6 +7,7 @@ import (
     "errors"
     "fmt"
     "io"
+    "log"
     "net/http"
     "regexp"
     "sort"
@@ -14,7 +15,9 @@ import (
     "time"
 
     "github.com/Zerr0-C00L/StreamArr/internal/database"
+    "github.com/Zerr0-C00L/StreamArr/internal/jobs"
     "github.com/Zerr0-C00L/StreamArr/internal/models"
+    "github.com/Zerr0-C00L/StreamArr/internal/release"
     isettings "github.com/Zerr0-C00L/StreamArr/internal/settings"
 )
 
@@ -49,7 +52,7 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
         }
     }
     
-    GlobalScheduler.UpdateProgress(ServiceIPTVVODSync, 0, totalSources, "Starting IPTV VOD import...")
+    jobs.Progress(ctx, 0, totalSources, "Starting IPTV VOD import...")
     currentSource := 0
 
     // Process Xtream sources using API
@@ -57,8 +60,11 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
         if !xs.Enabled || strings.TrimSpace(xs.ServerURL) == "" || strings.TrimSpace(xs.Username) == "" || strings.TrimSpace(xs.Password) == "" {
             continue
         }
+        if err := ctx.Err(); err != nil {
+            return summary, err
+        }
         currentSource++
-        GlobalScheduler.UpdateProgress(ServiceIPTVVODSync, currentSource, totalSources, 
+        jobs.Progress(ctx, currentSource, totalSources, 
             fmt.Sprintf("Scanning Xtream: %s", xs.Name))
         
         server := strings.TrimSuffix(xs.ServerURL, "/")
@@ -68,8 +74,11 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
         if err == nil {
             summary.ItemsFound += len(items)
             for i, it := range items {
+                if ctx.Err() != nil {
+                    break
+                }
                 if i%50 == 0 {
-                    GlobalScheduler.UpdateProgress(ServiceIPTVVODSync, currentSource, totalSources, 
+                    jobs.Progress(ctx, currentSource, totalSources, 
                         fmt.Sprintf("%s: Importing movies (%d/%d)", xs.Name, i, len(items)))
                 }
                 key := fmt.Sprintf("%s:%s:%d", it.Kind, normalizeTitle(it.Title), it.Year)
@@ -89,7 +98,7 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
                     if errors.Is(importErr, ErrBlockedBollywood) {
                         summary.Skipped++
                     } else {
-                        fmt.Printf("Error importing movie '%s' (%d): %v\n", it.Title, it.Year, importErr)
+                        log.Printf("Error importing movie '%s' (%d): %v", it.Title, it.Year, importErr)
                         summary.Errors++
                     }
                 } else {
@@ -105,8 +114,11 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
         if err == nil {
             summary.ItemsFound += len(seriesItems)
             for i, it := range seriesItems {
+                if ctx.Err() != nil {
+                    break
+                }
                 if i%50 == 0 {
-                    GlobalScheduler.UpdateProgress(ServiceIPTVVODSync, currentSource, totalSources, 
+                    jobs.Progress(ctx, currentSource, totalSources, 
                         fmt.Sprintf("%s: Importing series (%d/%d)", xs.Name, i, len(seriesItems)))
                 }
                 key := fmt.Sprintf("%s:%s:%d", it.Kind, normalizeTitle(it.Title), it.Year)
@@ -126,7 +138,7 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
                     if errors.Is(importErr, ErrBlockedBollywood) {
                         summary.Skipped++
                     } else {
-                        fmt.Printf("Error importing series '%s' (%d): %v\n", it.Title, it.Year, importErr)
+                        log.Printf("Error importing series '%s' (%d): %v", it.Title, it.Year, importErr)
                         summary.Errors++
                     }
                 } else {
@@ -153,8 +165,11 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
     }
 
     for _, s := range m3uURLs {
+        if err := ctx.Err(); err != nil {
+            return summary, err
+        }
         currentSource++
-        GlobalScheduler.UpdateProgress(ServiceIPTVVODSync, currentSource, totalSources, 
+        jobs.Progress(ctx, currentSource, totalSources, 
             fmt.Sprintf("Scanning M3U: %s", s.name))
         
         req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
@@ -179,8 +194,11 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
             summary.ItemsFound += len(items)
             // Import each item
             for i, it := range items {
+                if ctx.Err() != nil {
+                    break
+                }
                 if i%50 == 0 {
-                    GlobalScheduler.UpdateProgress(ServiceIPTVVODSync, currentSource, totalSources, 
+                    jobs.Progress(ctx, currentSource, totalSources, 
                         fmt.Sprintf("%s: Importing (%d/%d)", s.name, i, len(items)))
                 }
                 key := fmt.Sprintf("%s:%s:%d", it.Kind, normalizeTitle(it.Title), it.Year)
@@ -222,8 +240,11 @@ func ImportIPTVVOD(ctx context.Context, cfg *isettings.Settings, tmdb *TMDBClien
         }()
         summary.SourcesChecked++
     }
+    if err := ctx.Err(); err != nil {
+        return summary, err
+    }
     
-    GlobalScheduler.UpdateProgress(ServiceIPTVVODSync, totalSources, totalSources, 
+    jobs.Progress(ctx, totalSources, totalSources, 
         fmt.Sprintf("Complete: %d movies, %d series imported", summary.MoviesImported, summary.SeriesImported))
 
     return summary, nil
@@ -334,8 +355,6 @@ func extractVODItems(r io.Reader, sourceName string, selectedCategories []string
     return items
 }
 
-var yearRe = regexp.MustCompile(`(?i)\b(19|20)\d{2}\b`)
-
 func splitTitleYear(name string) (string, int) {
     // Strip common prefixes first (language codes, channel names, etc.)
     prefixes := []string{"EX - ", "EN - ", "US - ", "UK - ", "EXYU - ", "|EXYU| ", "HD - "}
@@ -346,20 +365,7 @@ func splitTitleYear(name string) (string, int) {
         }
     }
     
-    year := 0
-    // Prefer a year at end or in parentheses
-    // e.g., "Dune (2021)" or "Dune 2021"
-    if m := yearRe.FindAllString(name, -1); len(m) > 0 {
-        last := m[len(m)-1]
-        if len(last) == 4 {
-            // parse simple int
-            year = atoiSafe(last)
-            // remove from title for cleaner TMDB search
-            name = strings.ReplaceAll(name, last, "")
-        }
-    }
-    title := strings.TrimSpace(stripQualityTags(name))
-    return title, year
+    return release.SplitTitleYear(name)
 }
 
 func atoiSafe(s string) int {
@@ -372,18 +378,6 @@ func atoiSafe(s string) int {
     return n
 }
 
-// stripQualityTags removes common quality/extra tags from titles
-func stripQualityTags(s string) string {
-    lower := strings.ToLower(s)
-    tags := []string{"1080p", "720p", "2160p", "480p", "hdr", "x264", "x265", "h264", "h265", "webrip", "web-dl", "dvdrip", "bluray", "remux", "aac", "dts", "atmos"}
-    for _, t := range tags {
-        lower = strings.ReplaceAll(lower, t, "")
-    }
-    // collapse whitespace back onto original case-preserving form where possible
-    fields := strings.Fields(lower)
-    return strings.Join(fields, " ")
-}
-
 func normalizeTitle(s string) string {
     s = strings.ToLower(strings.TrimSpace(s))
     s = strings.NewReplacer(":", " ", "-", " ", ".", " ", "_", " ", "(", " ", ")", " ").Replace(s)
@@ -751,7 +745,7 @@ func fetchXtreamVODMovies(ctx context.Context, client *http.Client, server, user
                 for _, cat := range categories {
                     categoryIDToName[cat.CategoryID] = cat.CategoryName
                 }
-                fmt.Printf("Xtream VOD: Loaded %d category mappings\n", len(categoryIDToName))
+                log.Printf("Xtream VOD: Loaded %d category mappings", len(categoryIDToName))
             }
         }
     }
@@ -792,9 +786,9 @@ func fetchXtreamVODMovies(ctx context.Context, client *http.Client, server, user
         return nil, err
     }
     
-    fmt.Printf("Xtream VOD: Fetched %d total movies from %s\n", len(streams), sourceName)
+    log.Printf("Xtream VOD: Fetched %d total movies from %s", len(streams), sourceName)
     if len(selectedCategories) > 0 {
-        fmt.Printf("Xtream VOD: Selected categories: %v\n", selectedCategories)
+        log.Printf("Xtream VOD: Selected categories: %v", selectedCategories)
     }
     
     matchedCount := 0
@@ -830,7 +824,7 @@ func fetchXtreamVODMovies(ctx context.Context, client *http.Client, server, user
     }
     
     if len(selectedCategories) > 0 {
-        fmt.Printf("Xtream VOD: Matched %d movies after category filtering\n", matchedCount)
+        log.Printf("Xtream VOD: Matched %d movies after category filtering", matchedCount)
     }
     
     return items, nil
@@ -865,7 +859,7 @@ func fetchXtreamSeries(ctx context.Context, client *http.Client, server, usernam
                 for _, cat := range categories {
                     categoryIDToName[cat.CategoryID] = cat.CategoryName
                 }
-                fmt.Printf("Xtream Series: Loaded %d category mappings\n", len(categoryIDToName))
+                log.Printf("Xtream Series: Loaded %d category mappings", len(categoryIDToName))
             }
         }
     }
@@ -906,9 +900,9 @@ func fetchXtreamSeries(ctx context.Context, client *http.Client, server, usernam
         return nil, err
     }
     
-    fmt.Printf("Xtream Series: Fetched %d total series from %s\n", len(series), sourceName)
+    log.Printf("Xtream Series: Fetched %d total series from %s", len(series), sourceName)
     if len(selectedCategories) > 0 {
-        fmt.Printf("Xtream Series: Selected categories: %v\n", selectedCategories)
+        log.Printf("Xtream Series: Selected categories: %v", selectedCategories)
     }
     
     matchedCount := 0
@@ -945,7 +939,7 @@ func fetchXtreamSeries(ctx context.Context, client *http.Client, server, usernam
     }
     
     if len(selectedCategories) > 0 {
-        fmt.Printf("Xtream Series: Matched %d series after category filtering\n", matchedCount)
+        log.Printf("Xtream Series: Matched %d series after category filtering", matchedCount)
     }
     
     return items, nil
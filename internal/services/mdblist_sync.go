@@ -10,6 +10,8 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/jobs"
 )
 
 // MDBListSyncService handles syncing MDBList lists to the database
@@ -68,14 +70,17 @@ func (s *MDBListSyncService) SyncAllLists(ctx context.Context) error {
 	}
 
 	log.Printf("📋 Syncing %d MDBList lists...", len(enabledLists))
-	GlobalScheduler.UpdateProgress(ServiceMDBListSync, 0, len(enabledLists), "Starting MDBList sync...")
+	jobs.Progress(ctx, 0, len(enabledLists), "Starting MDBList sync...")
 
 	totalMovies := 0
 	totalSeries := 0
 
 	for listIdx, listConfig := range enabledLists {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		log.Printf("  → Fetching: %s", listConfig.Name)
-		GlobalScheduler.UpdateProgress(ServiceMDBListSync, listIdx, len(enabledLists), 
+		jobs.Progress(ctx, listIdx, len(enabledLists), 
 			fmt.Sprintf("Fetching: %s", listConfig.Name))
 
 		// Parse username and slug from URL
@@ -101,7 +106,7 @@ func (s *MDBListSyncService) SyncAllLists(ctx context.Context) error {
 		moviesAdded := 0
 		for _, item := range result.Movies {
 			processedItems++
-			GlobalScheduler.UpdateProgress(ServiceMDBListSync, listIdx, len(enabledLists), 
+			jobs.Progress(ctx, listIdx, len(enabledLists), 
 				fmt.Sprintf("%s: Importing %s (%d/%d)", listConfig.Name, item.Title, processedItems, totalItems))
 
 			if err := s.importMovie(ctx, item, listConfig.Name); err != nil {
@@ -123,7 +128,7 @@ func (s *MDBListSyncService) SyncAllLists(ctx context.Context) error {
 		seriesAdded := 0
 		for _, item := range result.Series {
 			processedItems++
-			GlobalScheduler.UpdateProgress(ServiceMDBListSync, listIdx, len(enabledLists), 
+			jobs.Progress(ctx, listIdx, len(enabledLists), 
 				fmt.Sprintf("%s: Importing %s (%d/%d)", listConfig.Name, item.Title, processedItems, totalItems))
 
 			if err := s.importSeries(ctx, item, listConfig.Name); err != nil {
@@ -141,7 +146,7 @@ func (s *MDBListSyncService) SyncAllLists(ctx context.Context) error {
 		}
 		totalSeries += seriesAdded
 		
-		GlobalScheduler.UpdateProgress(ServiceMDBListSync, listIdx+1, len(enabledLists), 
+		jobs.Progress(ctx, listIdx+1, len(enabledLists), 
 			fmt.Sprintf("Completed: %s (+%d movies, +%d series)", listConfig.Name, moviesAdded, seriesAdded))
 
 		log.Printf("    ✅ Added %d movies, %d series", moviesAdded, seriesAdded)
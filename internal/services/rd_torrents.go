@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
+	"github.com/Zerr0-C00L/StreamArr/internal/services/debrid"
+)
+
+// Real-Debrid torrent statuses
+const (
+	rdStatusWaitingFiles = "waiting_files_selection"
+	rdStatusDownloaded   = "downloaded"
+)
+
+// rdFailedStatuses are terminal states; polling stops as soon as one is seen
+var rdFailedStatuses = map[string]bool{
+	"magnet_error": true,
+	"error":        true,
+	"virus":        true,
+	"dead":         true,
+}
+
+const (
+	// rdPollTimeout bounds how long a torrent may take to reach the wanted status;
+	// cached torrents get there in well under a second
+	rdPollTimeout = 15 * time.Second
+	// rdAccountListTTL is how long the account torrent list is reused for lookups by hash
+	rdAccountListTTL = time.Minute
+)
+
+// RDTorrentManager manages the torrents StreamArr adds to a Real-Debrid account.
+// Torrents already in the account are reused instead of being added again, new ones
+// are tracked in rd_torrents, and CollectGarbage removes stale tracked torrents.
+type RDTorrentManager struct {
+	client *RealDebridClient
+	store  *database.RDTorrentStore // nil disables tracking and garbage collection
+	limits func() (quota int, staleAfter time.Duration)
+
+	// Backoff between status polls, doubling from pollMin up to pollMax
+	pollMin time.Duration
+	pollMax time.Duration
+
+	hashMu sync.Mutex
+	locks  map[string]*sync.Mutex // per-hash locks so concurrent plays add a torrent once
+
+	listMu   sync.Mutex
+	listing  []rdTorrentInfo
+	listedAt time.Time
+}
+
+// NewRDTorrentManager creates a torrent manager for a client
+// A nil store disables tracking; torrents are still reused when found in the account
+func NewRDTorrentManager(client *RealDebridClient, store *database.RDTorrentStore) *RDTorrentManager {
+	return &RDTorrentManager{
+		client:  client,
+		store:   store,
+		limits:  func() (int, time.Duration) { return 0, 0 },
+		pollMin: 250 * time.Millisecond,
+		pollMax: 2 * time.Second,
+		locks:   make(map[string]*sync.Mutex),
+	}
+}
+
+// SetStore enables tracking of added torrents in the database
+func (m *RDTorrentManager) SetStore(store *database.RDTorrentStore) {
+	m.store = store
+}
+
+// SetLimits sets how garbage collection bounds the account: at most quota tracked
+// torrents are kept, and tracked torrents unused for staleAfter are removed (0 disables either)
+func (m *RDTorrentManager) SetLimits(limits func() (quota int, staleAfter time.Duration)) {
+	m.limits = limits
+}
+
+// StreamURL returns a direct link to a video file in a torrent.
+// With season 0 the largest video file is used; otherwise the file for the episode.
+func (m *RDTorrentManager) StreamURL(ctx context.Context, hash string, season, episode int) (string, error) {
+	hash = strings.ToLower(hash)
+	if hash == "" {
+		return "", fmt.Errorf("no infohash")
+	}
+	unlock := m.lockHash(hash)
+	defer unlock()
+
+	if info, fileID := m.findExisting(ctx, hash, season, episode); info != nil {
+		url, err := m.unrestrictFile(ctx, info, fileID)
+		if err == nil {
+			log.Printf("[RD] Reused torrent %s for %s", info.ID, hash)
+			return url, nil
+		}
+		log.Printf("[RD] Reusing torrent %s failed, adding a new one: %v", info.ID, err)
+	}
+
+	return m.addAndStream(ctx, hash, season, episode)
+}
+
+// addAndStream adds the magnet, selects the wanted file and returns its link.
+// Only the torrent added here is deleted on failure; torrents found in the account are left alone.
+func (m *RDTorrentManager) addAndStream(ctx context.Context, hash string, season, episode int) (string, error) {
+	torrentID, err := m.client.AddMagnet(ctx, "magnet:?xt=urn:btih:"+hash)
+	if err != nil {
+		return "", err
+	}
+	m.track(ctx, torrentID, hash, "", 0)
+
+	info, err := m.waitForStatus(ctx, torrentID, rdStatusWaitingFiles, rdStatusDownloaded)
+	if err != nil {
+		m.discard(ctx, torrentID)
+		return "", err
+	}
+
+	file, ok := pickFile(info.Files, season, episode)
+	if !ok {
+		m.discard(ctx, torrentID)
+		return "", fmt.Errorf("no matching video file in torrent %s", hash)
+	}
+
+	if info.Status == rdStatusWaitingFiles {
+		if err := m.client.SelectFiles(ctx, torrentID, []int{file.ID}); err != nil {
+			m.discard(ctx, torrentID)
+			return "", err
+		}
+
+		// A cached torrent is downloaded as soon as files are selected
+		info, err = m.waitForStatus(ctx, torrentID, rdStatusDownloaded)
+		if err != nil {
+			m.discard(ctx, torrentID)
+			return "", fmt.Errorf("torrent not cached on Real-Debrid: %w", err)
+		}
+	}
+	m.track(ctx, torrentID, hash, info.Filename, info.Bytes)
+
+	url, err := m.unrestrictFile(ctx, info, file.ID)
+	if err != nil {
+		m.discard(ctx, torrentID)
+		return "", err
+	}
+
+	m.remember(info)
+
+	log.Printf("[RD] Added torrent %s for %s (file %d: %s)", torrentID, hash, file.ID, file.Path)
+	return url, nil
+}
+
+// findExisting looks for a downloaded torrent with the wanted file already selected,
+// first among tracked torrents and then in the whole account
+func (m *RDTorrentManager) findExisting(ctx context.Context, hash string, season, episode int) (*rdTorrentInfo, int) {
+	seen := make(map[string]bool)
+	var candidates []string
+
+	if m.store != nil {
+		tracked, err := m.store.FindByHash(ctx, hash)
+		if err != nil {
+			log.Printf("[RD] Failed to look up tracked torrents: %v", err)
+		}
+		for _, t := range tracked {
+			seen[t.TorrentID] = true
+			candidates = append(candidates, t.TorrentID)
+		}
+	}
+
+	account, err := m.accountTorrents(ctx)
+	if err != nil {
+		log.Printf("[RD] Failed to list account torrents: %v", err)
+	}
+	for _, t := range account {
+		if strings.EqualFold(t.Hash, hash) && t.Status == rdStatusDownloaded && !seen[t.ID] {
+			seen[t.ID] = true
+			candidates = append(candidates, t.ID)
+		}
+	}
+
+	for _, id := range candidates {
+		info, err := m.client.GetTorrentInfo(ctx, id)
+		if err != nil {
+			// Removed from the account outside StreamArr
+			m.untrack(ctx, id)
+			continue
+		}
+		if info.Status != rdStatusDownloaded {
+			continue
+		}
+
+		file, ok := pickFile(info.Files, season, episode)
+		if ok && file.Selected == 1 {
+			return info, file.ID
+		}
+	}
+
+	return nil, 0
+}
+
+// accountTorrents returns the account's torrent list, reusing a recent listing
+func (m *RDTorrentManager) accountTorrents(ctx context.Context) ([]rdTorrentInfo, error) {
+	m.listMu.Lock()
+	defer m.listMu.Unlock()
+
+	if m.listing != nil && time.Since(m.listedAt) < rdAccountListTTL {
+		return m.listing, nil
+	}
+
+	torrents, err := m.client.ListTorrents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.listing = torrents
+	m.listedAt = time.Now()
+	return torrents, nil
+}
+
+// remember adds a torrent to the cached account listing so later lookups find it
+func (m *RDTorrentManager) remember(info *rdTorrentInfo) {
+	m.listMu.Lock()
+	defer m.listMu.Unlock()
+
+	if m.listing != nil {
+		m.listing = append(m.listing, *info)
+	}
+}
+
+// waitForStatus polls a torrent with backoff until it reaches one of the wanted statuses
+func (m *RDTorrentManager) waitForStatus(ctx context.Context, torrentID string, wanted ...string) (*rdTorrentInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, rdPollTimeout)
+	defer cancel()
+
+	delay := m.pollMin
+	for {
+		info, err := m.client.GetTorrentInfo(ctx, torrentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, status := range wanted {
+			if info.Status == status {
+				return info, nil
+			}
+		}
+		if rdFailedStatuses[info.Status] {
+			return nil, fmt.Errorf("torrent %s failed (status: %s)", torrentID, info.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("torrent %s did not become ready (status: %s, progress: %.0f%%)", torrentID, info.Status, info.Progress)
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > m.pollMax {
+			delay = m.pollMax
+		}
+	}
+}
+
+// unrestrictFile returns the direct link for a selected file of a downloaded torrent
+func (m *RDTorrentManager) unrestrictFile(ctx context.Context, info *rdTorrentInfo, fileID int) (string, error) {
+	link, err := linkForFile(info, fileID)
+	if err != nil {
+		return "", err
+	}
+
+	unrestricted, err := m.client.UnrestrictLink(ctx, link)
+	if err != nil {
+		return "", err
+	}
+
+	if m.store != nil {
+		if err := m.store.Touch(ctx, info.ID); err != nil {
+			log.Printf("[RD] %v", err)
+		}
+	}
+
+	if unrestricted.Download != "" {
+		return unrestricted.Download, nil
+	}
+	return unrestricted.Link, nil
+}
+
+// CollectGarbage deletes tracked torrents that are stale or over the account quota,
+// least recently used first. Torrents the user added themselves are never touched.
+func (m *RDTorrentManager) CollectGarbage(ctx context.Context) (int, error) {
+	if m.store == nil {
+		return 0, nil
+	}
+	quota, staleAfter := m.limits()
+
+	tracked, err := m.store.ListLeastRecentlyUsed(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	account, err := m.client.ListTorrents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	inAccount := make(map[string]bool, len(account))
+	for _, t := range account {
+		inAccount[t.ID] = true
+	}
+
+	total := len(account)
+	deleted := 0
+	for _, t := range tracked {
+		if !inAccount[t.TorrentID] {
+			// Already removed from the account
+			m.untrack(ctx, t.TorrentID)
+			continue
+		}
+
+		stale := staleAfter > 0 && time.Since(t.LastUsedAt) > staleAfter
+		overQuota := quota > 0 && total > quota
+		if !stale && !overQuota {
+			continue
+		}
+
+		if err := m.client.DeleteTorrent(ctx, t.TorrentID); err != nil {
+			log.Printf("[RD] Failed to delete torrent %s: %v", t.TorrentID, err)
+			continue
+		}
+		m.untrack(ctx, t.TorrentID)
+		total--
+		deleted++
+	}
+
+	m.listMu.Lock()
+	m.listing = nil
+	m.listMu.Unlock()
+
+	log.Printf("[RD] Garbage collection removed %d torrents (%d in account, quota %d)", deleted, total, quota)
+	return deleted, nil
+}
+
+// discard deletes a torrent added by StreamArr that could not be streamed
+func (m *RDTorrentManager) discard(ctx context.Context, torrentID string) {
+	if err := m.client.DeleteTorrent(ctx, torrentID); err != nil {
+		log.Printf("[RD] Failed to delete torrent %s: %v", torrentID, err)
+	}
+	m.untrack(ctx, torrentID)
+}
+
+func (m *RDTorrentManager) track(ctx context.Context, torrentID, hash, filename string, bytes int64) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Track(ctx, torrentID, hash, filename, bytes); err != nil {
+		log.Printf("[RD] %v", err)
+	}
+}
+
+func (m *RDTorrentManager) untrack(ctx context.Context, torrentID string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Delete(ctx, torrentID); err != nil {
+		log.Printf("[RD] %v", err)
+	}
+}
+
+// lockHash serialises work on one infohash and returns the unlock function
+func (m *RDTorrentManager) lockHash(hash string) func() {
+	m.hashMu.Lock()
+	lock, ok := m.locks[hash]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[hash] = lock
+	}
+	m.hashMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// pickFile chooses the file to stream: the episode's file when season is set,
+// otherwise the largest video file
+func pickFile(files []rdTorrentFile, season, episode int) (rdTorrentFile, bool) {
+	var videos []rdTorrentFile
+	for _, f := range files {
+		if release.IsVideoFile(f.Path) {
+			videos = append(videos, f)
+		}
+	}
+	if len(videos) == 0 {
+		return rdTorrentFile{}, false
+	}
+
+	if season > 0 {
+		torrentFiles := make([]debrid.TorrentFile, len(videos))
+		for i, f := range videos {
+			torrentFiles[i] = debrid.TorrentFile{Index: f.ID, Path: f.Path, Size: f.Bytes}
+		}
+		match, ok := debrid.FindEpisodeFile(debrid.MapEpisodeFiles(torrentFiles), season, episode)
+		if ok {
+			for _, f := range videos {
+				if f.ID == match.Index {
+					return f, true
+				}
+			}
+		}
+		// A single-video torrent found for an episode is that episode
+		if len(videos) == 1 {
+			return videos[0], true
+		}
+		return rdTorrentFile{}, false
+	}
+
+	largest := videos[0]
+	for _, f := range videos[1:] {
+		if f.Bytes > largest.Bytes {
+			largest = f
+		}
+	}
+	return largest, true
+}
+
+// linkForFile maps a file ID to its link; links are listed per selected file in file ID order
+func linkForFile(info *rdTorrentInfo, fileID int) (string, error) {
+	var selected []int
+	for _, f := range info.Files {
+		if f.Selected == 1 {
+			selected = append(selected, f.ID)
+		}
+	}
+	sort.Ints(selected)
+
+	if len(selected) != len(info.Links) {
+		// Archived torrents have a single link for all files
+		if len(info.Links) == 1 {
+			return info.Links[0], nil
+		}
+		return "", fmt.Errorf("torrent %s has %d links for %d selected files", info.ID, len(info.Links), len(selected))
+	}
+
+	for i, id := range selected {
+		if id == fileID {
+			return info.Links[i], nil
+		}
+	}
+	return "", fmt.Errorf("file %d is not selected in torrent %s", fileID, info.ID)
+}
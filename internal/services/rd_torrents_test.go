@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	rdMovieHash = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" // already in the account
+	rdPackHash  = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb" // season pack, added on demand
+	rdBadHash   = "cccccccccccccccccccccccccccccccccccccccc" // fails with magnet_error
+)
+
+// rdStandIn keeps an in-memory Real-Debrid account and mimics the torrent endpoints the manager uses
+type rdStandIn struct {
+	mu       sync.Mutex
+	torrents map[string]*rdTorrentInfo
+	nextID   int
+	added    int
+	deleted  []string
+}
+
+func newRDStandIn() *rdStandIn {
+	s := &rdStandIn{torrents: make(map[string]*rdTorrentInfo), nextID: 1}
+	s.torrents["EXISTING1"] = &rdTorrentInfo{
+		ID: "EXISTING1", Filename: "Heat.1995.1080p.BluRay.x264", Hash: rdMovieHash, Status: rdStatusDownloaded, Progress: 100,
+		Files: []rdTorrentFile{
+			{ID: 1, Path: "/Heat.1995.1080p.BluRay.x264/Sample.mkv", Bytes: 50 << 20},
+			{ID: 2, Path: "/Heat.1995.1080p.BluRay.x264/Heat.1995.1080p.BluRay.x264.mkv", Bytes: 8 << 30, Selected: 1},
+		},
+		Links: []string{"https://real-debrid.com/d/EXISTING1-2"},
+	}
+	return s
+}
+
+func (s *rdStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer test-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	respond := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	r.ParseForm()
+
+	switch path := r.URL.Path; {
+	case path == "/torrents":
+		if r.URL.Query().Get("page") != "1" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		list := []*rdTorrentInfo{}
+		for _, t := range s.torrents {
+			list = append(list, t)
+		}
+		respond(list)
+	case path == "/torrents/addMagnet":
+		hash := strings.TrimPrefix(r.PostForm.Get("magnet"), "magnet:?xt=urn:btih:")
+		id := fmt.Sprintf("NEW%d", s.nextID)
+		s.nextID++
+		s.added++
+		t := &rdTorrentInfo{ID: id, Hash: hash, Status: "magnet_error"}
+		if hash == rdPackHash {
+			t.Status, t.Filename = rdStatusWaitingFiles, "Show.S01.1080p.WEB-DL"
+			for ep := 1; ep <= 3; ep++ {
+				t.Files = append(t.Files, rdTorrentFile{ID: ep, Path: fmt.Sprintf("/Show.S01.1080p.WEB-DL/Show.S01E%02d.1080p.WEB-DL.mkv", ep), Bytes: 2 << 30})
+			}
+			t.Files = append(t.Files, rdTorrentFile{ID: 4, Path: "/Show.S01.1080p.WEB-DL/Show.S01.nfo", Bytes: 1024})
+		}
+		s.torrents[id] = t
+		w.WriteHeader(http.StatusCreated)
+		respond(map[string]string{"id": id})
+	case strings.HasPrefix(path, "/torrents/info/"):
+		t, ok := s.torrents[strings.TrimPrefix(path, "/torrents/info/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		respond(t)
+	case strings.HasPrefix(path, "/torrents/selectFiles/"):
+		t, ok := s.torrents[strings.TrimPrefix(path, "/torrents/selectFiles/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		selected := make(map[string]bool)
+		for _, id := range strings.Split(r.PostForm.Get("files"), ",") {
+			selected[id] = true
+		}
+		for i := range t.Files {
+			if selected[fmt.Sprint(t.Files[i].ID)] {
+				t.Files[i].Selected = 1
+				t.Links = append(t.Links, fmt.Sprintf("https://real-debrid.com/d/%s-%d", t.ID, t.Files[i].ID))
+			}
+		}
+		t.Status, t.Progress = rdStatusDownloaded, 100
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(path, "/torrents/delete/"):
+		id := strings.TrimPrefix(path, "/torrents/delete/")
+		delete(s.torrents, id)
+		s.deleted = append(s.deleted, id)
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/unrestrict/link":
+		link := r.PostForm.Get("link")
+		respond(map[string]string{"link": link, "download": strings.Replace(link, "real-debrid.com/d/", "download.real-debrid.com/", 1)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestRDClient(t *testing.T) (*RealDebridClient, *rdStandIn) {
+	standIn := newRDStandIn()
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	client := NewRealDebridClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.Torrents().pollMin, client.Torrents().pollMax = time.Millisecond, time.Millisecond
+	return client, standIn
+}
+
+func TestRDReusesTorrentInAccount(t *testing.T) {
+	client, standIn := newTestRDClient(t)
+
+	url, err := client.GetStreamURL(context.Background(), strings.ToUpper(rdMovieHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://download.real-debrid.com/EXISTING1-2" {
+		t.Errorf("stream URL = %q", url)
+	}
+	if standIn.added != 0 {
+		t.Errorf("added %d torrents for a hash already in the account", standIn.added)
+	}
+}
+
+func TestRDSeasonPackEpisodes(t *testing.T) {
+	client, standIn := newTestRDClient(t)
+	ctx := context.Background()
+
+	for _, step := range []struct {
+		episode   int
+		wantURL   string
+		wantAdded int
+	}{
+		{2, "https://download.real-debrid.com/NEW1-2", 1},
+		{2, "https://download.real-debrid.com/NEW1-2", 1}, // Reused: the file is already selected
+		{3, "https://download.real-debrid.com/NEW2-3", 2}, // Another file needs a torrent of its own
+	} {
+		url, err := client.GetEpisodeStreamURL(ctx, rdPackHash, 1, step.episode)
+		if err != nil {
+			t.Fatalf("S01E%02d: %v", step.episode, err)
+		}
+		if url != step.wantURL || standIn.added != step.wantAdded {
+			t.Errorf("S01E%02d = %q with %d torrents added, want %q with %d", step.episode, url, standIn.added, step.wantURL, step.wantAdded)
+		}
+	}
+
+	if _, err := client.GetEpisodeStreamURL(ctx, rdPackHash, 1, 9); err == nil {
+		t.Error("an episode missing from the pack streamed")
+	}
+	if n := len(standIn.deleted); n != 1 {
+		t.Errorf("deleted %d torrents, want only the one added for the missing episode", n)
+	}
+}
+
+func TestRDFailedTorrentIsRemoved(t *testing.T) {
+	client, standIn := newTestRDClient(t)
+
+	if _, err := client.GetStreamURL(context.Background(), rdBadHash); err == nil || !strings.Contains(err.Error(), "magnet_error") {
+		t.Fatalf("error = %v, want magnet_error", err)
+	}
+	if len(standIn.torrents) != 1 || len(standIn.deleted) != 1 {
+		t.Errorf("account has %d torrents after the failure (deleted %v), want only the existing one", len(standIn.torrents), standIn.deleted)
+	}
+}
+
+func TestRDGarbageCollectionNeedsStore(t *testing.T) {
+	client, standIn := newTestRDClient(t)
+	client.Torrents().SetLimits(func() (int, time.Duration) { return 0, time.Nanosecond })
+
+	deleted, err := client.Torrents().CollectGarbage(context.Background())
+	if err != nil || deleted != 0 || len(standIn.deleted) != 0 {
+		t.Errorf("without a store CollectGarbage deleted %d (%v), want nothing touched", deleted, err)
+	}
+}
+
+func TestLinkForFile(t *testing.T) {
+	info := &rdTorrentInfo{
+		ID: "T",
+		Files: []rdTorrentFile{
+			{ID: 3, Selected: 1}, {ID: 1, Selected: 1}, {ID: 2},
+		},
+		Links: []string{"link-1", "link-3"},
+	}
+	for fileID, want := range map[int]string{1: "link-1", 3: "link-3"} {
+		if got, err := linkForFile(info, fileID); err != nil || got != want {
+			t.Errorf("linkForFile(%d) = %q, %v; want %q", fileID, got, err, want)
+		}
+	}
+	if _, err := linkForFile(info, 2); err == nil {
+		t.Error("an unselected file got a link")
+	}
+
+	// Archived torrents have one link for every file
+	info.Links = []string{"archive"}
+	if got, err := linkForFile(info, 3); err != nil || got != "archive" {
+		t.Errorf("archived linkForFile = %q, %v", got, err)
+	}
+}
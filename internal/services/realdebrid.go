@@ -9,6 +9,9 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/cache"
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
 )
 
 const (
@@ -17,18 +20,30 @@ const (
 
 type RealDebridClient struct {
 	apiKey     string
+	baseURL    string
 	httpClient *http.Client
+	torrents   *RDTorrentManager
+	links      *cache.LinkCache // nil resolves on every call
 }
 
 type rdTorrentInfo struct {
-	ID          string   `json:"id"`
-	Filename    string   `json:"filename"`
-	Hash        string   `json:"hash"`
-	Bytes       int64    `json:"bytes"`
-	Host        string   `json:"host"`
-	Status      string   `json:"status"`
-	Added       string   `json:"added"`
-	Links       []string `json:"links"`
+	ID       string          `json:"id"`
+	Filename string          `json:"filename"`
+	Hash     string          `json:"hash"`
+	Bytes    int64           `json:"bytes"`
+	Host     string          `json:"host"`
+	Status   string          `json:"status"`
+	Progress float64         `json:"progress"`
+	Added    string          `json:"added"`
+	Files    []rdTorrentFile `json:"files"` // Only returned by torrents/info
+	Links    []string        `json:"links"` // One per selected file, in file ID order
+}
+
+type rdTorrentFile struct {
+	ID       int    `json:"id"`
+	Path     string `json:"path"`
+	Bytes    int64  `json:"bytes"`
+	Selected int    `json:"selected"`
 }
 
 type rdInstantAvailability struct {
@@ -46,12 +61,31 @@ type rdUnrestrictLink struct {
 }
 
 func NewRealDebridClient(apiKey string) *RealDebridClient {
-	return &RealDebridClient{
-		apiKey: apiKey,
+	c := &RealDebridClient{
+		apiKey:  apiKey,
+		baseURL: rdBaseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: metrics.Transport("realdebrid", nil),
 		},
 	}
+	c.torrents = NewRDTorrentManager(c, nil)
+	return c
+}
+
+// SetBaseURL points the client at another host (e.g. a local stand-in)
+func (c *RealDebridClient) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetLinkCache reuses resolved stream URLs until they expire
+func (c *RealDebridClient) SetLinkCache(links *cache.LinkCache) {
+	c.links = links
+}
+
+// Torrents returns the manager for torrents in the account
+func (c *RealDebridClient) Torrents() *RDTorrentManager {
+	return c.torrents
 }
 
 // CheckInstantAvailability checks if torrents are instantly available
@@ -71,7 +105,7 @@ func (c *RealDebridClient) CheckInstantAvailability(ctx context.Context, infoHas
 		}
 		batch := infoHashes[i:end]
 
-		endpoint := fmt.Sprintf("%s/torrents/instantAvailability/%s", rdBaseURL, strings.Join(batch, "/"))
+		endpoint := fmt.Sprintf("%s/torrents/instantAvailability/%s", c.baseURL, strings.Join(batch, "/"))
 		
 		data, err := c.makeRequest(ctx, "GET", endpoint, nil, nil)
 		if err != nil {
@@ -104,7 +138,7 @@ func (c *RealDebridClient) CheckInstantAvailability(ctx context.Context, infoHas
 
 // AddMagnet adds a magnet link to Real-Debrid
 func (c *RealDebridClient) AddMagnet(ctx context.Context, magnetLink string) (string, error) {
-	endpoint := fmt.Sprintf("%s/torrents/addMagnet", rdBaseURL)
+	endpoint := fmt.Sprintf("%s/torrents/addMagnet", c.baseURL)
 	
 	params := url.Values{}
 	params.Set("magnet", magnetLink)
@@ -127,7 +161,7 @@ func (c *RealDebridClient) AddMagnet(ctx context.Context, magnetLink string) (st
 
 // SelectFiles selects all files from a torrent
 func (c *RealDebridClient) SelectFiles(ctx context.Context, torrentID string, fileIDs []int) error {
-	endpoint := fmt.Sprintf("%s/torrents/selectFiles/%s", rdBaseURL, torrentID)
+	endpoint := fmt.Sprintf("%s/torrents/selectFiles/%s", c.baseURL, torrentID)
 	
 	// Convert file IDs to comma-separated string
 	fileIDStrs := make([]string, len(fileIDs))
@@ -148,7 +182,7 @@ func (c *RealDebridClient) SelectFiles(ctx context.Context, torrentID string, fi
 
 // GetTorrentInfo retrieves information about a torrent
 func (c *RealDebridClient) GetTorrentInfo(ctx context.Context, torrentID string) (*rdTorrentInfo, error) {
-	endpoint := fmt.Sprintf("%s/torrents/info/%s", rdBaseURL, torrentID)
+	endpoint := fmt.Sprintf("%s/torrents/info/%s", c.baseURL, torrentID)
 	
 	data, err := c.makeRequest(ctx, "GET", endpoint, nil, nil)
 	if err != nil {
@@ -165,7 +199,7 @@ func (c *RealDebridClient) GetTorrentInfo(ctx context.Context, torrentID string)
 
 // UnrestrictLink converts a Real-Debrid link to a direct download link
 func (c *RealDebridClient) UnrestrictLink(ctx context.Context, link string) (*rdUnrestrictLink, error) {
-	endpoint := fmt.Sprintf("%s/unrestrict/link", rdBaseURL)
+	endpoint := fmt.Sprintf("%s/unrestrict/link", c.baseURL)
 	
 	params := url.Values{}
 	params.Set("link", link)
@@ -183,91 +217,59 @@ func (c *RealDebridClient) UnrestrictLink(ctx context.Context, link string) (*rd
 	return &result, nil
 }
 
-// GetStreamURL gets a direct streaming URL for a torrent
+// GetStreamURL gets a direct streaming URL for the main video file of a torrent
+// Torrents already in the account are reused; see RDTorrentManager
 func (c *RealDebridClient) GetStreamURL(ctx context.Context, infoHash string) (string, error) {
+	return c.GetEpisodeStreamURL(ctx, infoHash, 0, 0)
+}
 
-	
-	// Build magnet link
-	magnetLink := fmt.Sprintf("magnet:?xt=urn:btih:%s", infoHash)
-
-	// Add magnet to Real-Debrid
-	torrentID, err := c.AddMagnet(ctx, magnetLink)
-	if err != nil {
-		return "", fmt.Errorf("failed to add magnet: %w", err)
+// GetEpisodeStreamURL gets a direct streaming URL for one episode of a torrent (e.g. a season pack)
+func (c *RealDebridClient) GetEpisodeStreamURL(ctx context.Context, infoHash string, season, episode int) (string, error) {
+	resolve := func(ctx context.Context) (string, error) {
+		return c.torrents.StreamURL(ctx, infoHash, season, episode)
 	}
-	fmt.Printf("[RD-DEBUG] Added magnet, torrent ID: %s\n", torrentID)
-
-	// Wait for torrent to be ready (cached torrents are instant)
-	time.Sleep(2 * time.Second)
-
-	// Get torrent info to find the largest file
-	info, err := c.GetTorrentInfo(ctx, torrentID)
-	if err != nil {
-		// Clean up the torrent if we can't get info
-		_ = c.DeleteTorrent(ctx, torrentID)
-		return "", fmt.Errorf("failed to get torrent info: %w", err)
+	if c.links == nil {
+		return resolve(ctx)
 	}
-	fmt.Printf("[RD-DEBUG] Torrent status: %s, Links: %d, Bytes: %d\n", info.Status, len(info.Links), info.Bytes)
+	return c.links.Resolve(ctx, strings.ToLower(infoHash), fmt.Sprintf("rd:s%de%d", season, episode), resolve)
+}
 
-	// Check if torrent is ready - should be "downloaded" for cached torrents
-	if info.Status != "downloaded" && info.Status != "waiting_files_selection" {
-		_ = c.DeleteTorrent(ctx, torrentID)
-		return "", fmt.Errorf("torrent not cached (status: %s)", info.Status)
-	}
+// ListTorrents returns every torrent in the account
+func (c *RealDebridClient) ListTorrents(ctx context.Context) ([]rdTorrentInfo, error) {
+	const pageSize = 1000
+	var torrents []rdTorrentInfo
 
-	// Select all files
-	if info.Status == "waiting_files_selection" {
-		fmt.Printf("[RD-DEBUG] Selecting files for torrent %s\n", torrentID)
-		if err := c.SelectFiles(ctx, torrentID, []int{1}); err != nil {
-			_ = c.DeleteTorrent(ctx, torrentID)
-			return "", fmt.Errorf("failed to select files: %w", err)
-		}
-		time.Sleep(1 * time.Second)
-		
-		// Refresh info
-		info, err = c.GetTorrentInfo(ctx, torrentID)
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("page", fmt.Sprintf("%d", page))
+		params.Set("limit", fmt.Sprintf("%d", pageSize))
+
+		data, err := c.makeRequest(ctx, "GET", fmt.Sprintf("%s/torrents", c.baseURL), params, nil)
 		if err != nil {
-			_ = c.DeleteTorrent(ctx, torrentID)
-			return "", fmt.Errorf("failed to refresh torrent info: %w", err)
+			return nil, fmt.Errorf("failed to list torrents: %w", err)
 		}
-		fmt.Printf("[RD-DEBUG] After file selection - Status: %s, Links: %d\n", info.Status, len(info.Links))
-		
-		// If still not downloaded after selection, it's not cached - delete it
-		if info.Status != "downloaded" {
-			fmt.Printf("[RD-DEBUG] Torrent not instantly cached (status: %s), deleting...\n", info.Status)
-			_ = c.DeleteTorrent(ctx, torrentID)
-			return "", fmt.Errorf("torrent not cached on RD (status: %s)", info.Status)
+		// An empty page comes back as 204 No Content
+		if len(data) == 0 {
+			break
 		}
-	}
 
-	// Get the first link
-	if len(info.Links) == 0 {
-		_ = c.DeleteTorrent(ctx, torrentID)
-		return "", fmt.Errorf("no download links available (status: %s)", info.Status)
-	}
+		var batch []rdTorrentInfo
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal torrent list: %w", err)
+		}
+		torrents = append(torrents, batch...)
 
-	fmt.Printf("[RD-DEBUG] Unrestricting link: %s\n", info.Links[0])
-	// Unrestrict the link to get direct download URL
-	unrestricted, err := c.UnrestrictLink(ctx, info.Links[0])
-	if err != nil {
-		_ = c.DeleteTorrent(ctx, torrentID)
-		return "", fmt.Errorf("failed to unrestrict link: %w", err)
+		if len(batch) < pageSize {
+			break
+		}
 	}
 
-	// Return the direct download URL for streaming
-	// Use the 'download' field which is the actual streaming URL
-	if unrestricted.Download != "" {
-		fmt.Printf("[RD-DEBUG] Got download URL: %s\n", unrestricted.Download)
-		return unrestricted.Download, nil
-	}
-	
-	fmt.Printf("[RD-DEBUG] Got link URL: %s\n", unrestricted.Link)
-	return unrestricted.Link, nil
+	return torrents, nil
 }
 
 // DeleteTorrent removes a torrent from Real-Debrid
 func (c *RealDebridClient) DeleteTorrent(ctx context.Context, torrentID string) error {
-	endpoint := fmt.Sprintf("%s/torrents/delete/%s", rdBaseURL, torrentID)
+	endpoint := fmt.Sprintf("%s/torrents/delete/%s", c.baseURL, torrentID)
 	
 	_, err := c.makeRequest(ctx, "DELETE", endpoint, nil, nil)
 	if err != nil {
@@ -349,7 +351,7 @@ func (c *RealDebridClient) makeRequest(ctx context.Context, method, endpoint str
 
 // TestConnection tests the Real-Debrid API connection
 func (c *RealDebridClient) TestConnection(ctx context.Context) error {
-	endpoint := fmt.Sprintf("%s/user", rdBaseURL)
+	endpoint := fmt.Sprintf("%s/user", c.baseURL)
 	
 	_, err := c.makeRequest(ctx, "GET", endpoint, nil, nil)
 	if err != nil {
@@ -7,7 +7,9 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/activity"
 	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/metrics"
 	"github.com/Zerr0-C00L/StreamArr/internal/services/debrid"
 )
 
@@ -40,7 +42,9 @@ type StreamChecker struct {
 	logger              *slog.Logger
 	stopChan            chan struct{}
 	indexerFunc         func(ctx context.Context, mediaID int) ([]models.TorrentStream, error) // Function to search indexers
+	episodeIndexerFunc  func(ctx context.Context, seriesID, season, episode int) ([]models.TorrentStream, error) // Function to search indexers for an episode
 	settingsGetter      func() (excludedGroups, excludedQualities, excludedLanguages string, filtersEnabled bool) // Get filter settings
+	activity            *activity.Recorder // Records upgrades and replacements in the activity feed
 }
 
 // NewStreamChecker creates a new stream checker
@@ -73,6 +77,17 @@ func (c *StreamChecker) SetSettingsGetter(getter func() (string, string, string,
 	c.settingsGetter = getter
 }
 
+// SetEpisodeIndexerFunc sets the function used to search for episode streams
+// Without it, expired episode streams are marked unavailable and never upgraded
+func (c *StreamChecker) SetEpisodeIndexerFunc(fn func(ctx context.Context, seriesID, season, episode int) ([]models.TorrentStream, error)) {
+	c.episodeIndexerFunc = fn
+}
+
+// SetActivity records upgrades and replacements in the activity feed
+func (c *StreamChecker) SetActivity(recorder *activity.Recorder) {
+	c.activity = recorder
+}
+
 // GetConfig returns the checker configuration
 func (c *StreamChecker) GetConfig() CheckerConfig {
 	return c.config
@@ -135,11 +150,14 @@ func (c *StreamChecker) RunCheck(ctx context.Context) error {
 		"batch_size", c.config.BatchSize)
 	
 	// Extract hashes for batch debrid check
-	hashes := make([]string, len(streams))
-	hashToStream := make(map[string]*models.CachedStream)
-	for i, stream := range streams {
-		hashes[i] = stream.StreamHash
-		hashToStream[stream.StreamHash] = stream
+	// Movies and episodes share one batch; a season pack can back several episodes
+	hashes := make([]string, 0, len(streams))
+	hashToStreams := make(map[string][]*models.CachedStream)
+	for _, stream := range streams {
+		if _, ok := hashToStreams[stream.StreamHash]; !ok {
+			hashes = append(hashes, stream.StreamHash)
+		}
+		hashToStreams[stream.StreamHash] = append(hashToStreams[stream.StreamHash], stream)
 	}
 	
 	// Batch check debrid cache status
@@ -153,59 +171,57 @@ func (c *StreamChecker) RunCheck(ctx context.Context) error {
 	var stillCached, expired, upgraded, upgradeAvailable int
 	
 	for hash, isCached := range cached {
-		stream := hashToStream[hash]
-		
-		if isCached {
-			// Stream still cached on debrid
-			stillCached++
-			
-			// Check for better quality version
-			if c.config.AutoUpgrade {
-				if err := c.checkForUpgrade(ctx, stream); err != nil {
-					c.logger.Error("Upgrade check failed",
-						"media_id", stream.MovieID,
-						"error", err)
-				} else if stream.UpgradeAvailable {
-					upgradeAvailable++
+		for _, stream := range hashToStreams[hash] {
+			if isCached {
+				// Stream still cached on debrid
+				stillCached++
+				metrics.StreamChecks.WithLabelValues("cached").Inc()
+				
+				// Check for better quality version
+				if c.config.AutoUpgrade {
+					if err := c.checkForUpgrade(ctx, stream); err != nil {
+						c.logger.Error("Upgrade check failed",
+							append(mediaAttrs(stream), "error", err)...)
+					} else if stream.UpgradeAvailable {
+						upgradeAvailable++
+					}
 				}
-			}
-			
-			// Schedule next check in 7 days
-			if err := c.cacheStore.UpdateNextCheck(ctx, stream.MovieID, 7); err != nil {
-				c.logger.Error("Failed to update next check",
-					"media_id", stream.MovieID,
-					"error", err)
-			}
-			
-		} else {
-			// Stream expired from debrid cache
-			expired++
-			c.logger.Warn("Stream expired from debrid cache",
-				"media_id", stream.MovieID,
-				"title", stream.StreamURL)
-			
-			// Try to find replacement
-			replaced, err := c.findReplacement(ctx, stream)
-			if err != nil {
-				c.logger.Error("Failed to find replacement",
-					"media_id", stream.MovieID,
-					"error", err)
-				// Mark as unavailable, retry tomorrow
-				if err := c.cacheStore.MarkUnavailable(ctx, stream.MovieID); err != nil {
-					c.logger.Error("Failed to mark unavailable",
-						"media_id", stream.MovieID,
-						"error", err)
+				
+				// Schedule next check in 7 days
+				if err := c.updateNextCheck(ctx, stream, 7); err != nil {
+					c.logger.Error("Failed to update next check",
+						append(mediaAttrs(stream), "error", err)...)
 				}
-			} else if replaced {
-				upgraded++
-				c.logger.Info("Found replacement stream",
-					"media_id", stream.MovieID)
+				
 			} else {
-				// No replacement available
-				if err := c.cacheStore.MarkUnavailable(ctx, stream.MovieID); err != nil {
-					c.logger.Error("Failed to mark unavailable",
-						"media_id", stream.MovieID,
-						"error", err)
+				// Stream expired from debrid cache
+				expired++
+				c.logger.Warn("Stream expired from debrid cache",
+					append(mediaAttrs(stream), "title", stream.StreamURL)...)
+				
+				// Try to find replacement
+				replaced, err := c.findReplacement(ctx, stream)
+				if err != nil {
+					metrics.StreamChecks.WithLabelValues("error").Inc()
+					c.logger.Error("Failed to find replacement",
+						append(mediaAttrs(stream), "error", err)...)
+					// Mark as unavailable, retry tomorrow
+					if err := c.markUnavailable(ctx, stream); err != nil {
+						c.logger.Error("Failed to mark unavailable",
+							append(mediaAttrs(stream), "error", err)...)
+					}
+				} else if replaced {
+					upgraded++
+					metrics.StreamChecks.WithLabelValues("replaced").Inc()
+					c.logger.Info("Found replacement stream",
+						mediaAttrs(stream)...)
+				} else {
+					// No replacement available
+					metrics.StreamChecks.WithLabelValues("unavailable").Inc()
+					if err := c.markUnavailable(ctx, stream); err != nil {
+						c.logger.Error("Failed to mark unavailable",
+							append(mediaAttrs(stream), "error", err)...)
+					}
 				}
 			}
 		}
@@ -225,29 +241,20 @@ func (c *StreamChecker) RunCheck(ctx context.Context) error {
 
 // checkForUpgrade checks if a better quality stream is available
 func (c *StreamChecker) checkForUpgrade(ctx context.Context, current *models.CachedStream) error {
-	// Skip if indexer function not provided
-	if c.indexerFunc == nil {
-		return nil
-	}
-	
 	// Search indexers for this media
-	results, err := c.indexerFunc(ctx, current.MovieID)
+	results, err := c.search(ctx, current)
 	if err != nil {
 		return fmt.Errorf("indexer search failed: %w", err)
 	}
 	
 	if len(results) == 0 {
-		return nil // No results
+		return nil // No results (or no indexer function provided)
 	}
 	
 	// Accept all streams from addon - filtering handled at addon URL level
 	c.logger.Info("Received streams from addon (addon-level filtering already applied)",
 		"stream_count", len(results))
 	
-	if len(results) == 0 {
-		return nil // All streams filtered out
-	}
-	
 	// Find best cached stream
 	best, err := c.streamSvc.FindBestCachedStream(ctx, results)
 	if err != nil {
@@ -275,40 +282,35 @@ func (c *StreamChecker) checkForUpgrade(ctx context.Context, current *models.Cac
 		sizeIncrease := best.SizeGB - current.FileSizeGB
 		if sizeIncrease > float64(c.config.MaxUpgradeSizeGB) {
 			c.logger.Info("Upgrade available but size increase too large",
-				"media_id", current.MovieID,
-				"current_size_gb", current.FileSizeGB,
-				"new_size_gb", best.SizeGB,
-				"increase_gb", sizeIncrease)
+				append(mediaAttrs(current),
+					"current_size_gb", current.FileSizeGB,
+					"new_size_gb", best.SizeGB,
+					"increase_gb", sizeIncrease)...)
 			
 			// Mark upgrade available but don't auto-upgrade
-			return c.cacheStore.MarkUpgradeAvailable(ctx, current.MovieID, true)
-		}
-		
-		// Get stream URL from debrid
-		streamURL, err := c.debrid.GetStreamURL(ctx, best.Hash, 0)
-		if err != nil {
-			return fmt.Errorf("failed to get stream URL: %w", err)
+			return c.markUpgradeAvailable(ctx, current)
 		}
 		
 		// Upgrade to better stream
-		if err := c.cacheStore.CacheStream(ctx, current.MovieID, *best, streamURL); err != nil {
+		if err := c.cacheBest(ctx, current, *best); err != nil {
 			return fmt.Errorf("failed to cache upgraded stream: %w", err)
 		}
 		
 		c.logger.Info("Auto-upgraded to better stream",
-			"media_id", current.MovieID,
-			"old_score", current.QualityScore,
-			"new_score", best.QualityScore,
-			"improvement", best.QualityScore-current.QualityScore,
-			"old_resolution", current.Resolution,
-			"new_resolution", best.Resolution)
+			append(mediaAttrs(current),
+				"old_score", current.QualityScore,
+				"new_score", best.QualityScore,
+				"improvement", best.QualityScore-current.QualityScore,
+				"old_resolution", current.Resolution,
+				"new_resolution", best.Resolution)...)
+		c.recordUpgrade(ctx, current, *best, "better_quality")
 		
 		return nil
 	}
 	
 	// Check if there's a slight improvement worth flagging
 	if best.QualityScore > current.QualityScore+10 {
-		return c.cacheStore.MarkUpgradeAvailable(ctx, current.MovieID, true)
+		return c.markUpgradeAvailable(ctx, current)
 	}
 	
 	return nil
@@ -316,20 +318,15 @@ func (c *StreamChecker) checkForUpgrade(ctx context.Context, current *models.Cac
 
 // findReplacement tries to find a replacement stream when current expires
 func (c *StreamChecker) findReplacement(ctx context.Context, expired *models.CachedStream) (bool, error) {
-	// Skip if indexer function not provided
-	if c.indexerFunc == nil {
-		return false, nil
-	}
-	
 	// Search indexers for this media
-	results, err := c.indexerFunc(ctx, expired.MovieID)
+	results, err := c.search(ctx, expired)
 	if err != nil {
 		return false, fmt.Errorf("indexer search failed: %w", err)
 	}
 	
 	if len(results) == 0 {
 		c.logger.Warn("No replacement streams found",
-			"media_id", expired.MovieID)
+			mediaAttrs(expired)...)
 		return false, nil
 	}
 	
@@ -341,30 +338,131 @@ func (c *StreamChecker) findReplacement(ctx context.Context, expired *models.Cac
 	
 	if best == nil {
 		c.logger.Warn("No cached replacement available",
-			"media_id", expired.MovieID)
+			mediaAttrs(expired)...)
 		return false, nil
 	}
 	
-	// Get stream URL from debrid
-	streamURL, err := c.debrid.GetStreamURL(ctx, best.Hash, 0)
-	if err != nil {
-		return false, fmt.Errorf("failed to get stream URL: %w", err)
-	}
-	
 	// Cache replacement stream
-	if err := c.cacheStore.CacheStream(ctx, expired.MovieID, *best, streamURL); err != nil {
+	if err := c.cacheBest(ctx, expired, *best); err != nil {
 		return false, fmt.Errorf("failed to cache replacement: %w", err)
 	}
 	
 	c.logger.Info("Cached replacement stream",
-		"media_id", expired.MovieID,
-		"old_score", expired.QualityScore,
-		"new_score", best.QualityScore,
-		"resolution", best.Resolution)
+		append(mediaAttrs(expired),
+			"old_score", expired.QualityScore,
+			"new_score", best.QualityScore,
+			"resolution", best.Resolution)...)
+	c.recordUpgrade(ctx, expired, *best, "expired")
 	
 	return true, nil
 }
 
+// search runs the indexer function for the movie or episode a cached stream belongs to
+// Returns no results when the matching indexer function is not provided
+func (c *StreamChecker) search(ctx context.Context, stream *models.CachedStream) ([]models.TorrentStream, error) {
+	if stream.IsEpisode() {
+		if c.episodeIndexerFunc == nil {
+			return nil, nil
+		}
+		return c.episodeIndexerFunc(ctx, stream.SeriesID, stream.Season, stream.Episode)
+	}
+	
+	if c.indexerFunc == nil {
+		return nil, nil
+	}
+	return c.indexerFunc(ctx, stream.MovieID)
+}
+
+// cacheBest resolves a debrid link for best and stores it in place of the current stream
+// Episode links point at the episode's file, which matters when best is a season pack
+func (c *StreamChecker) cacheBest(ctx context.Context, current *models.CachedStream, best models.TorrentStream) error {
+	if !current.IsEpisode() {
+		streamURL, err := c.debrid.GetStreamURL(ctx, best.Hash, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get stream URL: %w", err)
+		}
+		return c.cacheStore.CacheStream(ctx, current.MovieID, best, streamURL)
+	}
+	
+	files, err := c.debrid.GetAvailableFiles(ctx, best.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to list torrent files: %w", err)
+	}
+	
+	file, ok := debrid.FindEpisodeFile(debrid.MapEpisodeFiles(files), current.Season, current.Episode)
+	if !ok {
+		return fmt.Errorf("S%02dE%02d not found in torrent %s", current.Season, current.Episode, best.Hash)
+	}
+	
+	streamURL, err := c.debrid.GetStreamURL(ctx, best.Hash, file.Index)
+	if err != nil {
+		return fmt.Errorf("failed to get stream URL: %w", err)
+	}
+	return c.cacheStore.CacheEpisodeStream(ctx, current.SeriesID, current.Season, current.Episode, best, streamURL)
+}
+
+// markUnavailable marks a cached movie or episode stream as unavailable
+func (c *StreamChecker) markUnavailable(ctx context.Context, stream *models.CachedStream) error {
+	if stream.IsEpisode() {
+		return c.cacheStore.MarkEpisodeUnavailable(ctx, stream.SeriesID, stream.Season, stream.Episode)
+	}
+	return c.cacheStore.MarkUnavailable(ctx, stream.MovieID)
+}
+
+// markUpgradeAvailable flags a cached movie or episode stream as having a better version
+func (c *StreamChecker) markUpgradeAvailable(ctx context.Context, stream *models.CachedStream) error {
+	if stream.IsEpisode() {
+		return c.cacheStore.MarkEpisodeUpgradeAvailable(ctx, stream.SeriesID, stream.Season, stream.Episode, true)
+	}
+	return c.cacheStore.MarkUpgradeAvailable(ctx, stream.MovieID, true)
+}
+
+// updateNextCheck schedules the next check for a cached movie or episode stream
+func (c *StreamChecker) updateNextCheck(ctx context.Context, stream *models.CachedStream, days int) error {
+	if stream.IsEpisode() {
+		return c.cacheStore.UpdateEpisodeNextCheck(ctx, stream.SeriesID, stream.Season, stream.Episode, days)
+	}
+	return c.cacheStore.UpdateNextCheck(ctx, stream.MovieID, days)
+}
+
+// mediaAttrs returns the log attributes identifying what a cached stream belongs to
+// recordUpgrade adds a stream replacement to the activity feed; reason is "better_quality" or "expired"
+func (c *StreamChecker) recordUpgrade(ctx context.Context, old *models.CachedStream, best models.TorrentStream, reason string) {
+	metrics.StreamUpgrades.WithLabelValues(reason).Inc()
+	e := activity.Event{
+		Type:        database.EventStreamUpgrade,
+		ContentType: "movie",
+		ContentID:   int64(old.MovieID),
+		Data: map[string]interface{}{
+			"reason":         reason,
+			"old_score":      old.QualityScore,
+			"new_score":      best.QualityScore,
+			"old_resolution": old.Resolution,
+			"new_resolution": best.Resolution,
+			"new_size_gb":    best.SizeGB,
+		},
+	}
+	what := fmt.Sprintf("movie %d", old.MovieID)
+	if old.IsEpisode() {
+		e.ContentType, e.ContentID = "series", int64(old.SeriesID)
+		e.Data["season"], e.Data["episode"] = old.Season, old.Episode
+		what = fmt.Sprintf("series %d S%02dE%02d", old.SeriesID, old.Season, old.Episode)
+	}
+	if reason == "expired" {
+		e.Message = fmt.Sprintf("Replaced expired stream of %s with %s", what, best.Resolution)
+	} else {
+		e.Message = fmt.Sprintf("Upgraded %s from %s to %s", what, old.Resolution, best.Resolution)
+	}
+	c.activity.Record(ctx, e)
+}
+
+func mediaAttrs(stream *models.CachedStream) []interface{} {
+	if stream.IsEpisode() {
+		return []interface{}{"series_id", stream.SeriesID, "season", stream.Season, "episode", stream.Episode}
+	}
+	return []interface{}{"media_id", stream.MovieID}
+}
+
 // CheckSpecificStream forces a check for a specific media item
 func (c *StreamChecker) CheckSpecificStream(ctx context.Context, mediaID int) error {
 	cached, err := c.cacheStore.GetCachedStream(ctx, mediaID)
@@ -1,10 +1,9 @@
 package streams
 
 import (
-	"fmt"
 	"math"
-	"regexp"
-	"strings"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
 )
 
 // StreamQuality contains parsed quality attributes from a torrent name
@@ -16,8 +15,16 @@ type StreamQuality struct {
 	Codec        string
 	SizeGB       float64
 	Seeders      int
+	Usenet       bool // NZB release; has no seeders
 }
 
+// UsenetIndexer is the indexer/source name of streams from usenet services
+const UsenetIndexer = "usenet"
+
+// usenetAvailabilityScore stands in for the seeders score of usenet releases,
+// which download at full speed regardless of popularity (~1000 seeders)
+const usenetAvailabilityScore = 6
+
 // QualityScore represents the calculated score breakdown
 type QualityScore struct {
 	TotalScore       int
@@ -32,6 +39,7 @@ type QualityScore struct {
 // CalculateScore computes quality score using pure mathematical formula (no AI)
 // Formula: Resolution(40) + HDR(15) + Audio(15) + Source(20) + log(seeders)*2 - SizePenalty
 // Max theoretical score: 40+15+15+20+~10 = ~100 points
+// Usenet releases get a fixed availability score in place of log(seeders)
 func CalculateScore(quality StreamQuality) QualityScore {
 	score := QualityScore{}
 	
@@ -48,7 +56,11 @@ func CalculateScore(quality StreamQuality) QualityScore {
 	score.SourceScore = getSourceScore(quality.Source)
 	
 	// Seeders scoring (log scale, ~10 points realistic max)
-	score.SeedersScore = getSeedersScore(quality.Seeders)
+	if quality.Usenet {
+		score.SeedersScore = usenetAvailabilityScore
+	} else {
+		score.SeedersScore = getSeedersScore(quality.Seeders)
+	}
 	
 	// Size penalty (deduct points for bloated files)
 	score.SizePenalty = getSizePenalty(quality.SizeGB, quality.Resolution)
@@ -68,23 +80,23 @@ func CalculateScore(quality StreamQuality) QualityScore {
 // ParseQualityFromTorrentName extracts quality attributes from torrent name
 // Example: "Movie.Name.2024.2160p.DV.HDR10.TrueHD.Atmos.7.1.REMUX-GROUP"
 func ParseQualityFromTorrentName(torrentName string) StreamQuality {
-	quality := StreamQuality{}
-	upperName := strings.ToUpper(torrentName)
+	info := release.Parse(torrentName)
 	
-	// Parse resolution
-	quality.Resolution = parseResolution(upperName)
-	
-	// Parse HDR type
-	quality.HDRType = parseHDRType(upperName)
-	
-	// Parse audio format
-	quality.AudioFormat = parseAudioFormat(upperName)
-	
-	// Parse source
-	quality.Source = parseSource(upperName)
+	quality := StreamQuality{
+		Resolution:  info.Resolution,
+		HDRType:     info.HDR,
+		AudioFormat: info.Audio,
+		Source:      info.Source,
+		Codec:       info.Codec,
+	}
 	
-	// Parse codec
-	quality.Codec = parseCodec(upperName)
+	// Unknown resolution and HDR are scored as SD and SDR
+	if quality.Resolution == "" {
+		quality.Resolution = "SD"
+	}
+	if quality.HDRType == "" {
+		quality.HDRType = "SDR"
+	}
 	
 	return quality
 }
@@ -205,161 +217,8 @@ func getSizePenalty(sizeGB float64, resolution string) int {
 	return 0
 }
 
-// parseResolution extracts resolution from torrent name
-func parseResolution(upperName string) string {
-	if strings.Contains(upperName, "2160P") || strings.Contains(upperName, "4K") || strings.Contains(upperName, "UHD") {
-		return "2160p"
-	}
-	if strings.Contains(upperName, "1080P") {
-		return "1080p"
-	}
-	if strings.Contains(upperName, "720P") {
-		return "720p"
-	}
-	if strings.Contains(upperName, "576P") {
-		return "576p"
-	}
-	if strings.Contains(upperName, "480P") {
-		return "480p"
-	}
-	return "SD"
-}
-
-// parseHDRType extracts HDR technology from torrent name
-func parseHDRType(upperName string) string {
-	// Check for Dolby Vision first (most specific)
-	if strings.Contains(upperName, "DV") || strings.Contains(upperName, "DOLBY.VISION") || strings.Contains(upperName, "DOLBYVISION") {
-		return "DV"
-	}
-	// HDR10+ before HDR10
-	if strings.Contains(upperName, "HDR10+") || strings.Contains(upperName, "HDR10PLUS") {
-		return "HDR10+"
-	}
-	if strings.Contains(upperName, "HDR10") {
-		return "HDR10"
-	}
-	if strings.Contains(upperName, "HDR") {
-		return "HDR"
-	}
-	return "SDR"
-}
-
-// parseAudioFormat extracts audio format from torrent name
-func parseAudioFormat(upperName string) string {
-	// Check for Atmos first
-	if strings.Contains(upperName, "ATMOS") {
-		return "Atmos"
-	}
-	if strings.Contains(upperName, "TRUEHD") {
-		return "TrueHD"
-	}
-	if strings.Contains(upperName, "DTS-HD.MA") || strings.Contains(upperName, "DTS-HD MA") {
-		return "DTS-HD MA"
-	}
-	if strings.Contains(upperName, "DTS-HD") {
-		return "DTS-HD"
-	}
-	if strings.Contains(upperName, "DTS-X") || strings.Contains(upperName, "DTSX") {
-		return "DTS-X"
-	}
-	if strings.Contains(upperName, "DD+") || strings.Contains(upperName, "EAC3") || strings.Contains(upperName, "E-AC3") {
-		return "DD+"
-	}
-	if strings.Contains(upperName, "AC3") || strings.Contains(upperName, "DD") {
-		return "AC3"
-	}
-	if strings.Contains(upperName, "DTS") {
-		return "DTS"
-	}
-	if strings.Contains(upperName, "AAC") {
-		return "AAC"
-	}
-	if strings.Contains(upperName, "MP3") {
-		return "MP3"
-	}
-	return ""
-}
-
-// parseSource extracts source type from torrent name
-func parseSource(upperName string) string {
-	if strings.Contains(upperName, "REMUX") {
-		return "REMUX"
-	}
-	if strings.Contains(upperName, "BLURAY") || strings.Contains(upperName, "BLU-RAY") || strings.Contains(upperName, "BDRIP") {
-		return "BluRay"
-	}
-	if strings.Contains(upperName, "WEB-DL") || strings.Contains(upperName, "WEBDL") {
-		return "WEB-DL"
-	}
-	if strings.Contains(upperName, "WEBRIP") || strings.Contains(upperName, "WEB") {
-		return "WEBRip"
-	}
-	if strings.Contains(upperName, "HDTV") {
-		return "HDTV"
-	}
-	if strings.Contains(upperName, "DVDRIP") {
-		return "DVDRip"
-	}
-	if strings.Contains(upperName, "CAM") || strings.Contains(upperName, "HDCAM") {
-		return "CAM"
-	}
-	if strings.Contains(upperName, "TS") || strings.Contains(upperName, "TELESYNC") {
-		return "TS"
-	}
-	if strings.Contains(upperName, "TC") || strings.Contains(upperName, "TELECINE") {
-		return "TC"
-	}
-	return ""
-}
-
-// parseCodec extracts video codec from torrent name
-func parseCodec(upperName string) string {
-	if strings.Contains(upperName, "H.265") || strings.Contains(upperName, "H265") || strings.Contains(upperName, "HEVC") {
-		return "HEVC"
-	}
-	if strings.Contains(upperName, "H.264") || strings.Contains(upperName, "H264") || strings.Contains(upperName, "AVC") {
-		return "AVC"
-	}
-	if strings.Contains(upperName, "AV1") {
-		return "AV1"
-	}
-	if strings.Contains(upperName, "VP9") {
-		return "VP9"
-	}
-	if strings.Contains(upperName, "XVID") {
-		return "XviD"
-	}
-	return ""
-}
-
 // ExtractSizeFromTorrentName attempts to parse file size from torrent name
 // Example: "Movie.2024.2160p.50GB.REMUX" -> 50.0
 func ExtractSizeFromTorrentName(torrentName string) float64 {
-	// Regex to match size patterns like "50GB", "12.5GB", "1.2TB"
-	sizeRegex := regexp.MustCompile(`(\d+(?:\.\d+)?)\s?(GB|TB|MB)`)
-	matches := sizeRegex.FindStringSubmatch(strings.ToUpper(torrentName))
-	
-	if len(matches) >= 3 {
-		var size float64
-		size, _ = parseFloat(matches[1])
-		
-		unit := matches[2]
-		switch unit {
-		case "TB":
-			return size * 1024
-		case "GB":
-			return size
-		case "MB":
-			return size / 1024
-		}
-	}
-	
-	return 0 // Size not found
-}
-
-// parseFloat parses float from string
-func parseFloat(s string) (float64, error) {
-	var f float64
-	_, err := fmt.Sscanf(s, "%f", &f)
-	return f, err
+	return float64(release.ParseSize(torrentName)) / (1024 * 1024 * 1024)
 }
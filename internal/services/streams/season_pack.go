@@ -0,0 +1,118 @@
+package streams
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/cache"
+	"github.com/Zerr0-C00L/StreamArr/internal/release"
+)
+
+// seasonPackProvider marks episode cache entries that point into a season pack
+const seasonPackProvider = "season-pack"
+
+// EpisodeLinker returns a direct link to one episode's file in a torrent. services.RealDebridClient
+// goes through its torrent manager, which reuses the torrents it added and cleans them up.
+type EpisodeLinker interface {
+	GetEpisodeStreamURL(ctx context.Context, infoHash string, season, episode int) (string, error)
+}
+
+// SeasonPackResolver plays episodes from cached season packs and remembers which pack each
+// played episode came from, so the next play doesn't re-query providers
+type SeasonPackResolver struct {
+	linker       EpisodeLinker
+	episodeCache *cache.EpisodeCache
+	logger       *slog.Logger
+}
+
+// NewSeasonPackResolver creates a new season pack resolver
+func NewSeasonPackResolver(linker EpisodeLinker, episodeCache *cache.EpisodeCache, logger *slog.Logger) *SeasonPackResolver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &SeasonPackResolver{
+		linker:       linker,
+		episodeCache: episodeCache,
+		logger:       logger,
+	}
+}
+
+// IsSeasonPack reports whether a torrent name covers whole seasons or several episodes
+func IsSeasonPack(names ...string) bool {
+	for _, name := range names {
+		info := release.Parse(name)
+		if info.IsSeasonPack() || info.IsMultiEpisode() || (info.Complete && !info.IsEpisode()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup resolves an episode from a season pack it was played from before
+// Returns ok=false when no pack is known for this episode
+func (r *SeasonPackResolver) Lookup(ctx context.Context, imdbID string, season, episode int) (string, bool) {
+	data, err := r.episodeCache.Get(imdbID, season, episode)
+	if err != nil || data == nil {
+		return "", false
+	}
+
+	for _, stream := range data.Streams {
+		if stream.Provider != seasonPackProvider || stream.Hash == "" {
+			continue
+		}
+
+		url, err := r.linker.GetEpisodeStreamURL(ctx, stream.Hash, season, episode)
+		if err != nil {
+			r.logger.Warn("Season pack stream failed",
+				"imdb_id", imdbID, "season", season, "episode", episode,
+				"hash", stream.Hash, "error", err)
+			continue
+		}
+
+		r.logger.Info("Resolved episode from cached season pack",
+			"imdb_id", imdbID, "season", season, "episode", episode,
+			"hash", stream.Hash)
+		return url, true
+	}
+
+	return "", false
+}
+
+// Resolve returns the stream URL for an episode in a cached pack and remembers the pack for it
+func (r *SeasonPackResolver) Resolve(ctx context.Context, imdbID, hash, torrentName string, season, episode int) (string, error) {
+	url, err := r.linker.GetEpisodeStreamURL(ctx, hash, season, episode)
+	if err != nil {
+		return "", err
+	}
+	r.remember(imdbID, hash, torrentName, season, episode)
+	return url, nil
+}
+
+// remember puts the pack first among the episode's cached streams, keeping the others
+func (r *SeasonPackResolver) remember(imdbID, hash, torrentName string, season, episode int) {
+	data := &cache.EpisodeData{IMDBID: imdbID, Season: season, Episode: episode}
+	if existing, err := r.episodeCache.Get(imdbID, season, episode); err == nil && existing != nil {
+		copied := *existing
+		data = &copied
+	}
+
+	streams := []cache.StreamData{{
+		Hash:     hash,
+		Title:    torrentName,
+		Quality:  release.Parse(torrentName).Resolution,
+		Cached:   true,
+		Provider: seasonPackProvider,
+	}}
+	for _, stream := range data.Streams {
+		if stream.Provider != seasonPackProvider || stream.Hash != hash {
+			streams = append(streams, stream)
+		}
+	}
+	data.Streams = streams
+
+	if err := r.episodeCache.Set(data); err != nil {
+		r.logger.Warn("Failed to cache season pack episode",
+			"imdb_id", imdbID, "season", season, "episode", episode, "error", err)
+	}
+}
@@ -83,6 +83,7 @@ func (s *StreamService) ScoreAndRankStreams(streams []models.TorrentStream) []mo
 			Codec:       streams[i].Codec,
 			SizeGB:      streams[i].SizeGB,
 			Seeders:     streams[i].Seeders,
+			Usenet:      streams[i].Indexer == UsenetIndexer,
 		}
 		
 		scoreBreakdown := CalculateScore(quality)
@@ -4,7 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
 	"sync"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/secrets"
 )
 
 // M3USource represents a custom M3U playlist source for Live TV
@@ -18,6 +22,7 @@ type M3USource struct {
 
 // XtreamSource represents an Xtream Codes compatible IPTV provider
 type XtreamSource struct {
+	ID                 string   `json:"id"` // Assigned on save; names the source's password while its URL or username changes
 	Name               string   `json:"name"`
 	ServerURL          string   `json:"server_url"`
 	Username           string   `json:"username"`
@@ -26,6 +31,23 @@ type XtreamSource struct {
 	SelectedCategories []string `json:"selected_categories,omitempty"`
 }
 
+// HDHomeRunTuner is a virtual HDHomeRun device exposing a subset of live channels
+type HDHomeRunTuner struct {
+	ID         string   `json:"id"`                   // URL path segment: /hdhr/{id}/discover.json
+	Name       string   `json:"name"`                 // Device name shown in Plex/Jellyfin
+	Categories []string `json:"categories,omitempty"` // Channel categories on this tuner (empty = all enabled categories)
+	TunerCount int      `json:"tuner_count"`          // Concurrent streams allowed
+	Enabled    bool     `json:"enabled"`
+}
+
+// NetworkAccessRule limits which client addresses may use one surface (web UI/API, Xtream, Stremio, live proxy)
+type NetworkAccessRule struct {
+	Allow          string `json:"allow"`           // Comma-separated IPs/CIDRs; when set, other addresses are refused
+	Deny           string `json:"deny"`            // Comma-separated IPs/CIDRs; always refused
+	AllowCountries string `json:"allow_countries"` // Comma-separated ISO 3166 codes; needs the GeoIP database
+	DenyCountries  string `json:"deny_countries"`  // Comma-separated ISO 3166 codes
+}
+
 // StremioAddon represents a custom Stremio addon for content providers
 type StremioAddon struct {
 	Name    string `json:"name"`    // Display name (e.g., "Torrentio", "Comet")
@@ -33,6 +55,14 @@ type StremioAddon struct {
 	Enabled bool   `json:"enabled"` // Whether this addon is active
 }
 
+// TorznabIndexer represents a Torznab-compatible indexer (Jackett, Prowlarr) queried directly
+type TorznabIndexer struct {
+	Name    string `json:"name"`    // Display name (e.g., "Jackett", "Prowlarr")
+	URL     string `json:"url"`     // Torznab endpoint (e.g., "http://jackett:9117/api/v2.0/indexers/all/results/torznab")
+	APIKey  string `json:"api_key"` // Indexer API key
+	Enabled bool   `json:"enabled"` // Whether this indexer is active
+}
+
 // StremioCatalogConfig represents configuration for a single catalog
 type StremioCatalogConfig struct {
 	ID      string `json:"id"`      // Catalog ID (movies, series, etc.)
@@ -50,6 +80,8 @@ type StremioAddonConfig struct {
 	PerUserTokens    bool                   `json:"per_user_tokens"`   // Use per-user tokens instead of shared
 	Catalogs         []StremioCatalogConfig `json:"catalogs"`          // Configured catalogs
 	CatalogPlacement string                 `json:"catalog_placement"` // "home", "discovery", or "both"
+	LiveTVCatalog    bool                   `json:"livetv_catalog"`    // Offer Live TV channels as a "tv" catalog
+	StreamProxy      string                 `json:"stream_proxy"`      // "off" (addon URLs as-is), "redirect" or "proxy" through StreamArr
 }
 
 type Settings struct {
@@ -111,11 +143,89 @@ type Settings struct {
 	LiveTVEnablePlutoTV   bool        `json:"livetv_enable_plutotv"`     // Enable built-in Pluto TV channels
 	LiveTVValidateStreams bool        `json:"livetv_validate_streams"`   // Validate stream URLs before loading channels
 	
+	// HDHomeRun Emulation (Plex/Jellyfin Live TV)
+	HDHomeRunEnabled      bool             `json:"hdhomerun_enabled"`       // Serve virtual HDHomeRun tuners under /hdhr/{id}/
+	HDHomeRunSSDP         bool             `json:"hdhomerun_ssdp"`          // Announce tuners over SSDP so media servers find them automatically
+	HDHomeRunAdvertiseURL string           `json:"hdhomerun_advertise_url"` // URL announced over SSDP (default: detected LAN address and server port)
+	HDHomeRunTuners       []HDHomeRunTuner `json:"hdhomerun_tuners"`
+	
 	
 	// Provider Settings
 	UseRealDebrid      bool            `json:"use_realdebrid"`
 	UsePremiumize      bool            `json:"use_premiumize"`
 	StremioAddons      []StremioAddon  `json:"stremio_addons"` // Custom Stremio addons for content providers
+	TorznabIndexers    []TorznabIndexer `json:"torznab_indexers"` // Torznab indexers queried directly
+	RDTorrentQuota     int              `json:"rd_torrent_quota"`      // Max torrents in the Real-Debrid account before StreamArr's oldest are removed (0 = no limit)
+	RDTorrentStaleDays int              `json:"rd_torrent_stale_days"` // Remove torrents StreamArr added that haven't been played for this many days (0 = keep)
+	
+	// Local Media Settings
+	LocalMediaEnabled  bool     `json:"local_media_enabled"`  // Scan local/mounted directories and serve matching files
+	LocalMediaPaths    []string `json:"local_media_paths"`    // Directories to scan (local disk, NFS/SMB mounts)
+	LocalMediaWatch    bool     `json:"local_media_watch"`    // Watch directories for new files instead of only periodic scans
+	
+	// Library Export Settings
+	StrmExportEnabled   bool   `json:"strm_export_enabled"`    // Write the library as .strm/NFO folders for Jellyfin, Emby and Kodi
+	StrmExportPath      string `json:"strm_export_path"`       // Folder the export is written to (add Movies/ and TV/ inside it as media server libraries)
+	StrmExportServerURL string `json:"strm_export_server_url"` // URL media servers use to reach StreamArr (default: host and port of this server)
+	StrmExportArtwork   bool   `json:"strm_export_artwork"`    // Download poster and fanart from TMDB next to the .strm files
+	
+	// Subtitle Settings
+	SubtitlesEnabled    bool   `json:"subtitles_enabled"`     // Offer OpenSubtitles subtitles in Stremio, the web player and Xtream VOD info
+	OpenSubtitlesURL    string `json:"opensubtitles_url"`     // OpenSubtitles REST API, or a compatible mirror
+	OpenSubtitlesAPIKey string `json:"opensubtitles_api_key"` // Consumer API key from opensubtitles.com
+	SubtitleLanguages   string `json:"subtitle_languages"`    // Comma-separated ISO 639-1 codes in preference order, for users without their own
+	SubtitleCachePath   string `json:"subtitle_cache_path"`   // Folder downloaded subtitle files are kept in
+	SubtitleCacheHours  int    `json:"subtitle_cache_hours"`  // How long search results are reused before searching again
+	
+	// Single Sign-On Settings
+	OIDCEnabled           bool   `json:"oidc_enabled"`             // Offer "Sign in with SSO" through an OpenID Connect provider (Authelia, Authentik, Keycloak, ...)
+	OIDCProviderName      string `json:"oidc_provider_name"`       // Shown on the sign-in button
+	OIDCIssuerURL         string `json:"oidc_issuer_url"`          // Endpoints are discovered from <issuer>/.well-known/openid-configuration
+	OIDCClientID          string `json:"oidc_client_id"`
+	OIDCClientSecret      string `json:"oidc_client_secret"`       // Empty for public clients, which rely on PKCE alone
+	OIDCRedirectURL       string `json:"oidc_redirect_url"`        // Callback registered with the provider (default: this server's /api/v1/auth/oidc/callback)
+	OIDCScopes            string `json:"oidc_scopes"`              // Space-separated; must include openid
+	OIDCUsernameClaim     string `json:"oidc_username_claim"`      // Claim used as the StreamArr username
+	OIDCGroupsClaim       string `json:"oidc_groups_claim"`        // Claim listing the user's groups
+	ProxyAuthEnabled      bool   `json:"proxy_auth_enabled"`       // Trust the user header set by an authenticating reverse proxy (Authelia, Authentik outpost, oauth2-proxy)
+	ProxyAuthHeader       string `json:"proxy_auth_header"`        // Header carrying the username
+	ProxyAuthGroupsHeader string `json:"proxy_auth_groups_header"` // Header carrying comma-separated groups
+	SSORoleMapping        string `json:"sso_role_mapping"`         // Comma-separated group=role pairs, e.g. "streamarr-admins=admin,family=requester"
+	SSODefaultRole        string `json:"sso_default_role"`         // Role for users in no mapped group; empty refuses them
+	SSOAutoProvision      bool   `json:"sso_auto_provision"`       // Create StreamArr users on their first single sign-on
+	SSOLinkVerifiedEmail  bool   `json:"sso_link_verified_email"`  // Link an OIDC account to the existing user with its provider-verified email
+	
+	// Network Access Settings
+	NetworkAccessEnabled  bool                         `json:"network_access_enabled"`  // Enforce the per-surface allow/deny rules
+	NetworkTrustedProxies string                       `json:"network_trusted_proxies"` // Comma-separated IPs/CIDRs of reverse proxies whose forwarding and proxy sign-on headers are believed
+	NetworkGeoIPDatabase  string                       `json:"network_geoip_database"`  // CSV of IP ranges to country codes ("start,end,CC" or "cidr,CC"), e.g. DB-IP's country lite
+	NetworkAccessRules    map[string]NetworkAccessRule `json:"network_access_rules"`    // Keyed by surface: web, xtream, stremio, live_proxy
+	
+	// Activity Settings
+	ActivityRetentionDays       int            `json:"activity_retention_days"`         // Days to keep the activity feed; 0 keeps it forever
+	ActivityRetentionDaysByType map[string]int `json:"activity_retention_days_by_type"` // Per event type, e.g. {"playback": 30, "audit": 365}
+	ActivityRecordPlayback      bool           `json:"activity_record_playback"`        // Record playback starts
+	
+	// Tracing Settings
+	TracingEnabled      bool    `json:"tracing_enabled"`       // Export OpenTelemetry traces of requests, provider lookups, debrid calls and jobs
+	TracingOTLPEndpoint string  `json:"tracing_otlp_endpoint"` // OTLP/HTTP collector, e.g. http://localhost:4318 (Jaeger, Tempo, an OpenTelemetry Collector)
+	TracingOTLPHeaders  string  `json:"tracing_otlp_headers"`  // Comma-separated key=value headers sent to the collector, e.g. an API key
+	TracingServiceName  string  `json:"tracing_service_name"`  // service.name of the exported spans
+	TracingSampleRatio  float64 `json:"tracing_sample_ratio"`  // Share of traces recorded, 0 to 1; traces started upstream follow the caller's decision
+	
+	// Logging Settings
+	LogLevel         string            `json:"log_level"`            // debug, info, warn or error; debug mode always logs debug
+	LogLevels        map[string]string `json:"log_levels"`           // Per subsystem, e.g. {"providers": "debug", "xtream": "warn"}
+	LogFile          string            `json:"log_file"`             // JSON lines log file; empty logs to the console only
+	LogFileMaxSizeMB int               `json:"log_file_max_size_mb"` // Size at which the log file is rotated
+	LogFileMaxFiles  int               `json:"log_file_max_files"`   // Rotated log files kept
+	
+	// Usenet Settings
+	EasynewsEnabled    bool   `json:"easynews_enabled"`    // Search Easynews and stream NZB releases
+	EasynewsUsername   string `json:"easynews_username"`
+	EasynewsPassword   string `json:"easynews_password"`
+	TorBoxEnabled      bool   `json:"torbox_enabled"`      // Search TorBox usenet and stream cached NZBs
+	TorBoxAPIKey       string `json:"torbox_api_key"`
 	
 	// Comet Provider Settings
 	CometEnabled           bool   `json:"comet_enabled"`            // Enable Comet torrent provider
@@ -178,10 +288,14 @@ type Settings struct {
 	XtreamPassword string `json:"xtream_password"`
 }
 
+// legacyTrustedProxies is the network_trusted_proxies default of earlier versions
+const legacyTrustedProxies = "127.0.0.0/8,::1,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"
+
 type Manager struct {
 	db       *sql.DB
 	settings *Settings
 	mu       sync.RWMutex
+	keyring  *secrets.Keyring // Encrypts secrets at rest; nil stores them as plain text
 	
 	// Callbacks for when settings change
 	onBalkanVODDisabled func() error // Called when Balkan VOD is disabled
@@ -246,6 +360,51 @@ func getDefaultSettings() *Settings {
 		CometPriorityLanguages: "",    // No priority languages by default
 		CometMaxSize:           "",    // No size limit by default
 		StremioAddons:          []StremioAddon{}, // Empty by default - users should configure their own addons
+		TorznabIndexers:        []TorznabIndexer{}, // Empty by default
+		RDTorrentQuota:         250, // Leave headroom below Real-Debrid's account torrent limit
+		RDTorrentStaleDays:     14,
+		LocalMediaEnabled:      false,
+		LocalMediaPaths:        []string{},
+		LocalMediaWatch:        true,
+		StrmExportEnabled:      false,
+		StrmExportPath:         "cache/strm",
+		StrmExportServerURL:    "",
+		StrmExportArtwork:      true,
+		SubtitlesEnabled:       false,
+		OpenSubtitlesURL:       "https://api.opensubtitles.com/api/v1",
+		SubtitleLanguages:      "en",
+		SubtitleCachePath:      "cache/subtitles",
+		SubtitleCacheHours:     72,
+		OIDCEnabled:            false,
+		OIDCProviderName:       "SSO",
+		OIDCScopes:             "openid profile email groups",
+		OIDCUsernameClaim:      "preferred_username",
+		OIDCGroupsClaim:        "groups",
+		ProxyAuthEnabled:       false,
+		ProxyAuthHeader:        "Remote-User",
+		ProxyAuthGroupsHeader:  "Remote-Groups",
+		SSODefaultRole:         "requester",
+		SSOAutoProvision:       true,
+		NetworkAccessEnabled:   false,
+		NetworkTrustedProxies:  "127.0.0.0/8,::1", // Reverse proxies on the same host; add others explicitly
+		NetworkAccessRules:     map[string]NetworkAccessRule{},
+		ActivityRetentionDays:  90,
+		ActivityRetentionDaysByType: map[string]int{"playback": 30, "audit": 365},
+		ActivityRecordPlayback: true,
+		TracingEnabled:         false,
+		TracingOTLPEndpoint:    "http://localhost:4318",
+		TracingServiceName:     "streamarr",
+		TracingSampleRatio:     1.0,
+		LogLevel:               "info",
+		LogLevels:              map[string]string{},
+		LogFile:                "logs/streamarr.log",
+		LogFileMaxSizeMB:       10,
+		LogFileMaxFiles:        5,
+		HDHomeRunEnabled:       false,
+		HDHomeRunSSDP:          true,
+		HDHomeRunTuners: []HDHomeRunTuner{
+			{ID: "streamarr", Name: "StreamArr", TunerCount: 4, Enabled: true},
+		},
 		StremioAddon: StremioAddonConfig{
 			Enabled:         true, // Enabled by default for built-in addon
 			PublicServerURL: "",
@@ -265,6 +424,8 @@ func getDefaultSettings() *Settings {
 				{ID: "streamarr_coming_soon", Type: "movie", Name: "Coming Soon", Enabled: true},
 			},
 			CatalogPlacement: "both",
+			LiveTVCatalog:    true,
+			StreamProxy:      "off",
 		},
 		UseHTTPProxy:           false,
 		HTTPProxies:            []string{}, // Empty by default
@@ -336,9 +497,54 @@ func (m *Manager) Load() error {
 		m.settings.OnlyCachedStreams = onlyCachedStr == "true"
 	}
 	
+	if err := m.openSecretsLocked(); err != nil {
+		return fmt.Errorf("decrypt settings: %w", err)
+	}
+	
+	// The old default trusted every private network, so any LAN client could forge X-Forwarded-For
+	migrated := false
+	if m.settings.NetworkTrustedProxies == legacyTrustedProxies {
+		m.settings.NetworkTrustedProxies = getDefaultSettings().NetworkTrustedProxies
+		log.Printf("[Settings] ⚠️ network_trusted_proxies now trusts loopback only; add your reverse proxy's address if it runs elsewhere")
+		migrated = true
+	}
+	// Proxy sign-on had a trusted proxy list of its own; network_trusted_proxies now covers it
+	var legacy struct {
+		ProxyAuthTrustedProxies string `json:"proxy_auth_trusted_proxies"`
+	}
+	if json.Unmarshal([]byte(settingsJSON), &legacy) == nil && strings.TrimSpace(legacy.ProxyAuthTrustedProxies) != "" {
+		m.settings.NetworkTrustedProxies = mergeList(m.settings.NetworkTrustedProxies, legacy.ProxyAuthTrustedProxies)
+		log.Printf("[Settings] proxy_auth_trusted_proxies merged into network_trusted_proxies")
+		migrated = true
+	}
+	if m.settings.assignSourceIDs() {
+		migrated = true
+	}
+	if migrated {
+		if err := m.saveToDBLocked(); err != nil {
+			return fmt.Errorf("save settings: %w", err)
+		}
+	}
+	
 	return nil
 }
 
+// mergeList appends the comma-separated entries of extra that list doesn't have yet
+func mergeList(list, extra string) string {
+	entries := strings.Split(list, ",")
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		seen[strings.TrimSpace(entry)] = true
+	}
+	for _, entry := range strings.Split(extra, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" && !seen[entry] {
+			seen[entry] = true
+			list = strings.TrimSuffix(list, ",") + "," + entry
+		}
+	}
+	return strings.TrimPrefix(list, ",")
+}
+
 func (m *Manager) Get() *Settings {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -365,6 +571,8 @@ func (m *Manager) Update(newSettings *Settings) error {
 		isDisablingBalkan = true
 	}
 	
+	// Secrets sent back masked keep their stored values
+	newSettings.keepMaskedSecrets(m.settings.secretValues())
 	m.settings = newSettings
 	if err := m.saveToDBLocked(); err != nil {
 		m.mu.Unlock()
@@ -399,6 +607,8 @@ func (m *Manager) UpdatePartial(updates map[string]interface{}) error {
 		}
 	}
 	
+	stored := m.settings.secretValues()
+	
 	// Convert current settings to map
 	settingsJSON, err := json.Marshal(m.settings)
 	if err != nil {
@@ -428,6 +638,7 @@ func (m *Manager) UpdatePartial(updates map[string]interface{}) error {
 		m.mu.Unlock()
 		return err
 	}
+	m.settings.keepMaskedSecrets(stored)
 	
 	if err := m.saveToDBLocked(); err != nil {
 		m.mu.Unlock()
@@ -449,7 +660,12 @@ func (m *Manager) UpdatePartial(updates map[string]interface{}) error {
 }
 
 func (m *Manager) saveToDBLocked() error {
-	settingsJSON, err := json.Marshal(m.settings)
+	m.settings.assignSourceIDs()
+	sealed, err := m.sealedLocked()
+	if err != nil {
+		return err
+	}
+	settingsJSON, err := json.Marshal(sealed)
 	if err != nil {
 		return fmt.Errorf("marshal settings: %w", err)
 	}
@@ -464,8 +680,7 @@ func (m *Manager) saveToDBLocked() error {
 		return err
 	}
 	
-	// Also save Xtream credentials as individual keys for backward compatibility
-	// (the Xtream handler reads these directly from the database)
+	// Also save the Xtream username as an individual key for backward compatibility
 	_, err = m.db.Exec(`
 		INSERT INTO settings (key, value, type, updated_at)
 		VALUES ('xtream_username', $1, 'string', NOW())
@@ -475,13 +690,10 @@ func (m *Manager) saveToDBLocked() error {
 		return fmt.Errorf("save xtream_username: %w", err)
 	}
 	
-	_, err = m.db.Exec(`
-		INSERT INTO settings (key, value, type, updated_at)
-		VALUES ('xtream_password', $1, 'string', NOW())
-		ON CONFLICT (key) DO UPDATE SET value = $1, updated_at = NOW()
-	`, m.settings.XtreamPassword)
+	// The password is encrypted with the other secrets; earlier versions kept a plain text copy here
+	_, err = m.db.Exec(`DELETE FROM settings WHERE key = 'xtream_password'`)
 	if err != nil {
-		return fmt.Errorf("save xtream_password: %w", err)
+		return fmt.Errorf("remove plain text xtream_password: %w", err)
 	}
 	
 	return nil
@@ -596,16 +808,16 @@ func (m *Manager) IsDebugEnabled() bool {
 	return m.settings.Debug
 }
 
-// GetAll returns all settings as a map
+// GetAll returns all settings as a map, with secrets masked
 func (m *Manager) GetAll() (map[string]interface{}, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	
 	return map[string]interface{}{
-		"tmdb_api_key":                 m.settings.TMDBAPIKey,
-		"realdebrid_token":             m.settings.RealDebridAPIKey,
-		"premiumize_api_key":           m.settings.PremiumizeAPIKey,
-		"mdblist_api_key":              m.settings.MDBListAPIKey,
+		"tmdb_api_key":                 MaskSecret(m.settings.TMDBAPIKey),
+		"realdebrid_token":             MaskSecret(m.settings.RealDebridAPIKey),
+		"premiumize_api_key":           MaskSecret(m.settings.PremiumizeAPIKey),
+		"mdblist_api_key":              MaskSecret(m.settings.MDBListAPIKey),
 		"use_realdebrid":               m.settings.UseRealDebrid,
 		"use_premiumize":               m.settings.UsePremiumize,
 		"comet_enabled":                m.settings.CometEnabled,
@@ -630,28 +842,28 @@ func (m *Manager) GetAll() (map[string]interface{}, error) {
 		"server_port":                  m.settings.ServerPort,
 		"host":                         m.settings.Host,
 		"enable_notifications":         m.settings.EnableNotifications,
-		"discord_webhook_url":          m.settings.DiscordWebhookURL,
-		"telegram_bot_token":           m.settings.TelegramBotToken,
+		"discord_webhook_url":          MaskSecret(m.settings.DiscordWebhookURL),
+		"telegram_bot_token":           MaskSecret(m.settings.TelegramBotToken),
 		"telegram_chat_id":             m.settings.TelegramChatID,
 	}, nil
 }
 
-// SetAll updates all settings from a map
+// SetAll updates all settings from a map; secrets sent as SecretMask are left unchanged
 func (m *Manager) SetAll(updates map[string]interface{}) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
 	// Update settings fields from map
-	if v, ok := updates["tmdb_api_key"].(string); ok {
+	if v, ok := updates["tmdb_api_key"].(string); ok && v != SecretMask {
 		m.settings.TMDBAPIKey = v
 	}
-	if v, ok := updates["realdebrid_token"].(string); ok {
+	if v, ok := updates["realdebrid_token"].(string); ok && v != SecretMask {
 		m.settings.RealDebridAPIKey = v
 	}
-	if v, ok := updates["premiumize_api_key"].(string); ok {
+	if v, ok := updates["premiumize_api_key"].(string); ok && v != SecretMask {
 		m.settings.PremiumizeAPIKey = v
 	}
-	if v, ok := updates["mdblist_api_key"].(string); ok {
+	if v, ok := updates["mdblist_api_key"].(string); ok && v != SecretMask {
 		m.settings.MDBListAPIKey = v
 	}
 	if v, ok := updates["use_realdebrid"].(bool); ok {
@@ -743,10 +955,10 @@ func (m *Manager) SetAll(updates map[string]interface{}) error {
 	if v, ok := updates["enable_notifications"].(bool); ok {
 		m.settings.EnableNotifications = v
 	}
-	if v, ok := updates["discord_webhook_url"].(string); ok {
+	if v, ok := updates["discord_webhook_url"].(string); ok && v != SecretMask {
 		m.settings.DiscordWebhookURL = v
 	}
-	if v, ok := updates["telegram_bot_token"].(string); ok {
+	if v, ok := updates["telegram_bot_token"].(string); ok && v != SecretMask {
 		m.settings.TelegramBotToken = v
 	}
 	if v, ok := updates["telegram_chat_id"].(string); ok {
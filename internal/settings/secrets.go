@@ -0,0 +1,188 @@
+package settings
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/secrets"
+)
+
+// SecretMask stands in for a stored secret in API responses. Secrets are write-only: sending the
+// mask back keeps the stored value, an empty string clears it and anything else replaces it.
+const SecretMask = "********"
+
+type secretField struct {
+	name  string
+	value *string
+}
+
+// secretFields returns the settings that are encrypted at rest and masked in API responses.
+// List entries are named by what identifies them, so reordering or editing a list keeps their secrets.
+func (s *Settings) secretFields() []secretField {
+	fields := []secretField{
+		{"xtream_password", &s.XtreamPassword},
+		{"tmdb_api_key", &s.TMDBAPIKey},
+		{"realdebrid_api_key", &s.RealDebridAPIKey},
+		{"premiumize_api_key", &s.PremiumizeAPIKey},
+		{"mdblist_api_key", &s.MDBListAPIKey},
+		{"opensubtitles_api_key", &s.OpenSubtitlesAPIKey},
+		{"oidc_client_secret", &s.OIDCClientSecret},
+		{"easynews_password", &s.EasynewsPassword},
+		{"torbox_api_key", &s.TorBoxAPIKey},
+		{"discord_webhook_url", &s.DiscordWebhookURL},
+		{"telegram_bot_token", &s.TelegramBotToken},
+		{"tracing_otlp_headers", &s.TracingOTLPHeaders},
+		{"stremio_addon/shared_token", &s.StremioAddon.SharedToken},
+	}
+	for i := range s.XtreamSources {
+		src := &s.XtreamSources[i]
+		fields = append(fields, secretField{"xtream_sources/" + src.ID, &src.Password})
+	}
+	for i := range s.TorznabIndexers {
+		indexer := &s.TorznabIndexers[i]
+		fields = append(fields, secretField{"torznab_indexers/" + indexer.URL, &indexer.APIKey})
+	}
+	return fields
+}
+
+// clone copies the settings deeply enough that secrets can be changed without touching s
+func (s *Settings) clone() *Settings {
+	c := *s
+	c.XtreamSources = append([]XtreamSource(nil), s.XtreamSources...)
+	c.TorznabIndexers = append([]TorznabIndexer(nil), s.TorznabIndexers...)
+	return &c
+}
+
+// Masked returns a copy of the settings with every secret that is set replaced by SecretMask
+func (s *Settings) Masked() *Settings {
+	c := s.clone()
+	for _, f := range c.secretFields() {
+		*f.value = MaskSecret(*f.value)
+	}
+	return c
+}
+
+// MaskSecret returns SecretMask for a secret that is set, and "" for one that isn't
+func MaskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return SecretMask
+}
+
+func (s *Settings) secretValues() map[string]string {
+	values := make(map[string]string)
+	for _, f := range s.secretFields() {
+		values[f.name] = *f.value
+	}
+	return values
+}
+
+// SecretValues returns every secret that is set, so they can be kept out of logs
+func (s *Settings) SecretValues() []string {
+	var values []string
+	for _, f := range s.secretFields() {
+		if *f.value != "" {
+			values = append(values, *f.value)
+		}
+	}
+	return values
+}
+
+// keepMaskedSecrets puts back the stored value of every secret that was sent as SecretMask
+func (s *Settings) keepMaskedSecrets(stored map[string]string) {
+	for _, f := range s.secretFields() {
+		if *f.value == SecretMask {
+			*f.value = stored[f.name]
+		}
+	}
+}
+
+// assignSourceIDs gives every Xtream source without an ID one, so its password stays attached
+// when the server URL or username is edited. It reports whether any ID was assigned.
+func (s *Settings) assignSourceIDs() bool {
+	assigned := false
+	for i := range s.XtreamSources {
+		if s.XtreamSources[i].ID != "" {
+			continue
+		}
+		b := make([]byte, 8)
+		rand.Read(b)
+		s.XtreamSources[i].ID = hex.EncodeToString(b)
+		assigned = true
+	}
+	return assigned
+}
+
+// SetKeyring sets the keyring secrets are encrypted with; without one they are stored as plain text.
+// Call it before Load.
+func (m *Manager) SetKeyring(keyring *secrets.Keyring) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keyring = keyring
+}
+
+// Rekey switches to keyring and stores every secret again encrypted with its current key
+func (m *Manager) Rekey(keyring *secrets.Keyring) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range m.settings.secretFields() {
+		if secrets.IsEncrypted(*f.value) {
+			return fmt.Errorf("%s could not be decrypted when settings were loaded", f.name)
+		}
+	}
+	m.keyring = keyring
+	return m.saveToDBLocked()
+}
+
+// sealedLocked returns a copy of the settings with secrets encrypted for storage
+func (m *Manager) sealedLocked() (*Settings, error) {
+	if m.keyring == nil {
+		return m.settings, nil
+	}
+	sealed := m.settings.clone()
+	for _, f := range sealed.secretFields() {
+		encrypted, err := m.keyring.Encrypt(*f.value)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt %s: %w", f.name, err)
+		}
+		*f.value = encrypted
+	}
+	return sealed, nil
+}
+
+// openSecretsLocked decrypts the loaded secrets and stores any found in plain text encrypted.
+// A secret that can't be decrypted is kept encrypted, so saving the settings doesn't lose it.
+func (m *Manager) openSecretsLocked() error {
+	var plain int
+	var errs []error
+	for _, f := range m.settings.secretFields() {
+		if *f.value == "" {
+			continue
+		}
+		if !secrets.IsEncrypted(*f.value) {
+			plain++
+			continue
+		}
+		if m.keyring == nil {
+			errs = append(errs, fmt.Errorf("%s is encrypted but no master key is configured", f.name))
+			continue
+		}
+		decrypted, err := m.keyring.Decrypt(*f.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+			continue
+		}
+		*f.value = decrypted
+	}
+	if plain > 0 && m.keyring != nil {
+		if err := m.saveToDBLocked(); err != nil {
+			return fmt.Errorf("encrypt stored secrets: %w", err)
+		}
+		log.Printf("[Settings] 🔒 Encrypted %d secrets that were stored as plain text", plain)
+	}
+	return errors.Join(errs...)
+}
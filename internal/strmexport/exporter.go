@@ -0,0 +1,499 @@
+package strmexport
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+)
+
+const (
+	// manifestFile records what the exporter wrote so later syncs only touch changed files
+	// and never delete anything the user put in the export folder
+	manifestFile = ".streamarr-export.json"
+	imageBaseURL = "https://image.tmdb.org/t/p/original"
+	listPageSize = 500
+)
+
+// Options configures an export; read before every sync so settings changes apply without a restart
+type Options struct {
+	Path      string // Export root; Movies/ and TV/ are created inside it
+	ServerURL string // Base URL media servers use to reach StreamArr's Xtream play endpoints
+	Username  string // Xtream credentials embedded in the .strm URLs
+	Password  string
+	Artwork   bool // Download poster.jpg/fanart.jpg from TMDB
+}
+
+// Exporter writes the library as a Jellyfin/Emby/Kodi compatible folder tree of
+// .strm files pointing at StreamArr's play URLs, with NFO metadata and artwork
+type Exporter struct {
+	movieStore     *database.MovieStore
+	seriesStore    *database.SeriesStore
+	episodeStore   *database.EpisodeStore
+	blacklistStore *database.BlacklistStore
+	getOptions     func() Options
+	client         *http.Client
+
+	syncMu sync.Mutex
+}
+
+// SyncResult summarises an export sync
+type SyncResult struct {
+	Movies    int `json:"movies"`
+	Series    int `json:"series"`
+	Episodes  int `json:"episodes"`
+	Written   int `json:"written"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+}
+
+// manifest maps item keys ("movie:<tmdb>", "series:<tmdb>") to the files written for them
+type manifest struct {
+	Items map[string]*manifestItem `json:"items"`
+}
+
+type manifestItem struct {
+	Dir   string            `json:"dir"`   // Item folder relative to the export root
+	Files map[string]string `json:"files"` // Path relative to the export root -> content hash (or artwork source URL)
+}
+
+// exportItem is the desired state of one library item on disk
+type exportItem struct {
+	key      string
+	dir      string
+	files    map[string][]byte
+	artwork  map[string]string // path -> TMDB image URL
+	episodes int
+}
+
+// NewExporter creates a library exporter
+func NewExporter(movieStore *database.MovieStore, seriesStore *database.SeriesStore, episodeStore *database.EpisodeStore, blacklistStore *database.BlacklistStore, getOptions func() Options) *Exporter {
+	return &Exporter{
+		movieStore:     movieStore,
+		seriesStore:    seriesStore,
+		episodeStore:   episodeStore,
+		blacklistStore: blacklistStore,
+		getOptions:     getOptions,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Sync brings the export folder in line with the library: new items are written, changed
+// items rewritten, and items deleted or blacklisted since the last sync are removed
+func (e *Exporter) Sync(ctx context.Context) (*SyncResult, error) {
+	e.syncMu.Lock()
+	defer e.syncMu.Unlock()
+
+	opts := e.getOptions()
+	if opts.Path == "" {
+		return nil, fmt.Errorf("export path is not configured")
+	}
+	if opts.ServerURL == "" {
+		return nil, fmt.Errorf("server URL is not configured")
+	}
+	opts.ServerURL = strings.TrimRight(opts.ServerURL, "/")
+
+	if err := os.MkdirAll(opts.Path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export path: %w", err)
+	}
+
+	start := time.Now()
+	log.Printf("[STRM-EXPORT] Syncing library to %s", opts.Path)
+
+	blacklisted, err := e.blacklisted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{}
+	var items []*exportItem
+
+	movieItems, err := e.movieItems(ctx, opts, blacklisted)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, movieItems...)
+	result.Movies = len(movieItems)
+
+	seriesItems, err := e.seriesItems(ctx, opts, blacklisted)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, seriesItems...)
+	result.Series = len(seriesItems)
+	for _, item := range seriesItems {
+		result.Episodes += item.episodes
+	}
+
+	previous := loadManifest(opts.Path)
+	current := &manifest{Items: make(map[string]*manifestItem)}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			// Keep what was already recorded so the next sync can still clean up
+			for key, old := range previous.Items {
+				if _, ok := current.Items[key]; !ok {
+					current.Items[key] = old
+				}
+			}
+			saveManifest(opts.Path, current)
+			return result, ctx.Err()
+		}
+		current.Items[item.key] = e.writeItem(ctx, opts.Path, item, previous.Items[item.key], result)
+	}
+
+	// Items no longer in the library (deleted, blacklisted or renamed away)
+	for key, old := range previous.Items {
+		if _, ok := current.Items[key]; ok {
+			continue
+		}
+		removeItem(opts.Path, old)
+		result.Removed++
+		log.Printf("[STRM-EXPORT] Removed %s", old.Dir)
+	}
+
+	if err := saveManifest(opts.Path, current); err != nil {
+		return result, err
+	}
+
+	log.Printf("[STRM-EXPORT] Sync complete in %v: %d movies, %d series (%d episodes), %d files written, %d unchanged, %d items removed",
+		time.Since(start).Round(time.Millisecond), result.Movies, result.Series, result.Episodes, result.Written, result.Unchanged, result.Removed)
+	return result, nil
+}
+
+func (e *Exporter) blacklisted(ctx context.Context) (map[string]bool, error) {
+	set := make(map[string]bool)
+	if e.blacklistStore == nil {
+		return set, nil
+	}
+	for offset := 0; ; offset += listPageSize {
+		entries, _, err := e.blacklistStore.List(ctx, listPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			set[fmt.Sprintf("%s:%d", entry.ItemType, entry.TMDBID)] = true
+		}
+		if len(entries) < listPageSize {
+			return set, nil
+		}
+	}
+}
+
+func (e *Exporter) movieItems(ctx context.Context, opts Options, blacklisted map[string]bool) ([]*exportItem, error) {
+	var items []*exportItem
+	used := make(map[string]bool)
+
+	for offset := 0; ; offset += listPageSize {
+		movies, err := e.movieStore.List(ctx, offset, listPageSize, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, movie := range movies {
+			key := fmt.Sprintf("movie:%d", movie.TMDBID)
+			if movie.TMDBID == 0 || blacklisted[key] {
+				continue
+			}
+
+			name := sanitizeName(movie.Title)
+			if movie.Year > 0 {
+				name = fmt.Sprintf("%s (%d)", name, movie.Year)
+			}
+			if used[strings.ToLower(name)] {
+				name = fmt.Sprintf("%s [tmdbid-%d]", name, movie.TMDBID)
+			}
+			used[strings.ToLower(name)] = true
+
+			dir := filepath.Join("Movies", name)
+			item := &exportItem{
+				key:     key,
+				dir:     dir,
+				files:   make(map[string][]byte),
+				artwork: make(map[string]string),
+			}
+			item.files[filepath.Join(dir, name+".strm")] = []byte(fmt.Sprintf("%s/movie/%s/%s/%d.mp4\n", opts.ServerURL, opts.Username, opts.Password, movie.TMDBID))
+			item.files[filepath.Join(dir, name+".nfo")] = movieNFO(movie)
+			if opts.Artwork {
+				addArtwork(item.artwork, dir, movie.PosterPath, movie.BackdropPath)
+			}
+			items = append(items, item)
+		}
+		if len(movies) < listPageSize {
+			return items, nil
+		}
+	}
+}
+
+func (e *Exporter) seriesItems(ctx context.Context, opts Options, blacklisted map[string]bool) ([]*exportItem, error) {
+	var items []*exportItem
+	used := make(map[string]bool)
+	today := time.Now()
+
+	for offset := 0; ; offset += listPageSize {
+		seriesList, err := e.seriesStore.List(ctx, offset, listPageSize, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, series := range seriesList {
+			key := fmt.Sprintf("series:%d", series.TMDBID)
+			if series.TMDBID == 0 || blacklisted[key] {
+				continue
+			}
+
+			episodes, err := e.episodeStore.ListBySeries(ctx, series.ID)
+			if err != nil {
+				log.Printf("[STRM-EXPORT] Skipping %s: %v", series.Title, err)
+				continue
+			}
+
+			name := sanitizeName(series.Title)
+			if used[strings.ToLower(name)] {
+				name = fmt.Sprintf("%s [tmdbid-%d]", name, series.TMDBID)
+			}
+			used[strings.ToLower(name)] = true
+
+			dir := filepath.Join("TV", name)
+			item := &exportItem{
+				key:     key,
+				dir:     dir,
+				files:   make(map[string][]byte),
+				artwork: make(map[string]string),
+			}
+			item.files[filepath.Join(dir, "tvshow.nfo")] = seriesNFO(series)
+			if opts.Artwork {
+				addArtwork(item.artwork, dir, series.PosterPath, series.BackdropPath)
+			}
+
+			for _, ep := range episodes {
+				// Specials and unaired episodes have nothing to play yet; they appear once aired
+				if ep.SeasonNumber == 0 || ep.TMDBID == 0 || ep.AirDate == nil || ep.AirDate.After(today) {
+					continue
+				}
+				seasonDir := filepath.Join(dir, fmt.Sprintf("Season %02d", ep.SeasonNumber))
+				base := fmt.Sprintf("%s S%02dE%02d", name, ep.SeasonNumber, ep.EpisodeNumber)
+				item.files[filepath.Join(seasonDir, base+".strm")] = []byte(fmt.Sprintf("%s/series/%s/%s/%d.mp4\n", opts.ServerURL, opts.Username, opts.Password, ep.TMDBID))
+				item.files[filepath.Join(seasonDir, base+".nfo")] = episodeNFO(series, ep)
+				item.episodes++
+			}
+			items = append(items, item)
+		}
+		if len(seriesList) < listPageSize {
+			return items, nil
+		}
+	}
+}
+
+// writeItem writes the files of one item that are missing or changed and removes files
+// that the previous sync wrote but the item no longer has
+func (e *Exporter) writeItem(ctx context.Context, root string, item *exportItem, old *manifestItem, result *SyncResult) *manifestItem {
+	written := &manifestItem{Dir: item.dir, Files: make(map[string]string)}
+
+	// A renamed item (e.g. corrected title or year) moves to a new folder
+	if old != nil && old.Dir != item.dir {
+		removeItem(root, old)
+		old = nil
+	}
+	oldFiles := map[string]string{}
+	if old != nil {
+		oldFiles = old.Files
+	}
+
+	for rel, content := range item.files {
+		sum := contentHash(content)
+		path := filepath.Join(root, rel)
+		if oldFiles[rel] == sum && fileExists(path) {
+			written.Files[rel] = sum
+			result.Unchanged++
+			continue
+		}
+		if err := writeFileAtomic(path, content); err != nil {
+			log.Printf("[STRM-EXPORT] Failed to write %s: %v", rel, err)
+			continue
+		}
+		written.Files[rel] = sum
+		result.Written++
+	}
+
+	for rel, imageURL := range item.artwork {
+		path := filepath.Join(root, rel)
+		if oldFiles[rel] == imageURL && fileExists(path) {
+			written.Files[rel] = imageURL
+			result.Unchanged++
+			continue
+		}
+		if err := e.download(ctx, imageURL, path); err != nil {
+			log.Printf("[STRM-EXPORT] Failed to download %s: %v", rel, err)
+			continue
+		}
+		written.Files[rel] = imageURL
+		result.Written++
+	}
+
+	for rel := range oldFiles {
+		if _, ok := written.Files[rel]; ok {
+			continue
+		}
+		if _, desired := item.files[rel]; desired {
+			continue
+		}
+		if _, desired := item.artwork[rel]; desired {
+			continue
+		}
+		os.Remove(filepath.Join(root, rel))
+		removeEmptyDirs(root, filepath.Dir(rel))
+	}
+
+	return written
+}
+
+func (e *Exporter) download(ctx context.Context, imageURL, path string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func addArtwork(artwork map[string]string, dir, posterPath, backdropPath string) {
+	if posterPath != "" {
+		artwork[filepath.Join(dir, "poster.jpg")] = imageBaseURL + posterPath
+	}
+	if backdropPath != "" {
+		artwork[filepath.Join(dir, "fanart.jpg")] = imageBaseURL + backdropPath
+	}
+}
+
+// removeItem deletes the files the exporter wrote for an item, leaving anything else in its folder
+func removeItem(root string, item *manifestItem) {
+	dirs := make(map[string]bool)
+	for rel := range item.Files {
+		os.Remove(filepath.Join(root, rel))
+		dirs[filepath.Dir(rel)] = true
+	}
+	for dir := range dirs {
+		removeEmptyDirs(root, dir)
+	}
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty, stopping at the
+// top-level Movies/TV folder
+func removeEmptyDirs(root, dir string) {
+	for dir != "." && filepath.Dir(dir) != "." {
+		if err := os.Remove(filepath.Join(root, dir)); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func loadManifest(root string) *manifest {
+	m := &manifest{Items: make(map[string]*manifestItem)}
+	data, err := os.ReadFile(filepath.Join(root, manifestFile))
+	if err != nil {
+		return m
+	}
+	if err := json.Unmarshal(data, m); err != nil || m.Items == nil {
+		log.Printf("[STRM-EXPORT] Ignoring unreadable manifest: %v", err)
+		return &manifest{Items: make(map[string]*manifestItem)}
+	}
+	return m
+}
+
+func saveManifest(root string, m *manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(root, manifestFile), data); err != nil {
+		return fmt.Errorf("failed to save export manifest: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes via a temp file so media servers scanning the folder never see partial files
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func contentHash(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sanitizeName strips characters that are invalid in Windows/SMB file names
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '<', '>', ':', '"', '/', '\\', '|', '?', '*':
+			return -1
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
+
+// metadataString reads a string field from item metadata
+func metadataString(metadata models.Metadata, key string) string {
+	if v, ok := metadata[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package strmexport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+)
+
+// Kodi-style NFO documents; Jellyfin and Emby read the same format
+
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+type movieNFODoc struct {
+	XMLName       xml.Name      `xml:"movie"`
+	Title         string        `xml:"title"`
+	OriginalTitle string        `xml:"originaltitle,omitempty"`
+	Year          int           `xml:"year,omitempty"`
+	Plot          string        `xml:"plot,omitempty"`
+	Runtime       int           `xml:"runtime,omitempty"`
+	Rating        float64       `xml:"rating,omitempty"`
+	Premiered     string        `xml:"premiered,omitempty"`
+	Genres        []string      `xml:"genre"`
+	UniqueIDs     []nfoUniqueID `xml:"uniqueid"`
+}
+
+type tvShowNFODoc struct {
+	XMLName       xml.Name      `xml:"tvshow"`
+	Title         string        `xml:"title"`
+	OriginalTitle string        `xml:"originaltitle,omitempty"`
+	Year          int           `xml:"year,omitempty"`
+	Plot          string        `xml:"plot,omitempty"`
+	Premiered     string        `xml:"premiered,omitempty"`
+	Status        string        `xml:"status,omitempty"`
+	Genres        []string      `xml:"genre"`
+	UniqueIDs     []nfoUniqueID `xml:"uniqueid"`
+}
+
+type episodeNFODoc struct {
+	XMLName   xml.Name      `xml:"episodedetails"`
+	Title     string        `xml:"title"`
+	ShowTitle string        `xml:"showtitle"`
+	Season    int           `xml:"season"`
+	Episode   int           `xml:"episode"`
+	Plot      string        `xml:"plot,omitempty"`
+	Aired     string        `xml:"aired,omitempty"`
+	UniqueIDs []nfoUniqueID `xml:"uniqueid"`
+}
+
+func movieNFO(movie *models.Movie) []byte {
+	doc := movieNFODoc{
+		Title:         movie.Title,
+		OriginalTitle: movie.OriginalTitle,
+		Year:          movie.Year,
+		Plot:          movie.Overview,
+		Runtime:       movie.Runtime,
+		Rating:        movie.VoteAverage,
+		Premiered:     formatDate(movie.ReleaseDate),
+		Genres:        movie.Genres,
+		UniqueIDs:     uniqueIDs(movie.TMDBID, metadataString(movie.Metadata, "imdb_id")),
+	}
+	return marshalNFO(doc)
+}
+
+func seriesNFO(series *models.Series) []byte {
+	doc := tvShowNFODoc{
+		Title:         series.Title,
+		OriginalTitle: metadataString(series.Metadata, "original_title"),
+		Year:          series.Year,
+		Plot:          series.Overview,
+		Status:        metadataString(series.Metadata, "status"),
+		Genres:        metadataStrings(series.Metadata, "genres"),
+		UniqueIDs:     uniqueIDs(series.TMDBID, series.IMDBID),
+	}
+	if t, err := time.Parse(time.RFC3339, metadataString(series.Metadata, "first_air_date")); err == nil {
+		doc.Premiered = t.Format("2006-01-02")
+	}
+	return marshalNFO(doc)
+}
+
+func episodeNFO(series *models.Series, ep *models.Episode) []byte {
+	doc := episodeNFODoc{
+		Title:     ep.Title,
+		ShowTitle: series.Title,
+		Season:    ep.SeasonNumber,
+		Episode:   ep.EpisodeNumber,
+		Plot:      ep.Overview,
+		Aired:     formatDate(ep.AirDate),
+		UniqueIDs: uniqueIDs(ep.TMDBID, ""),
+	}
+	if doc.Title == "" {
+		doc.Title = fmt.Sprintf("Episode %d", ep.EpisodeNumber)
+	}
+	return marshalNFO(doc)
+}
+
+func uniqueIDs(tmdbID int, imdbID string) []nfoUniqueID {
+	ids := []nfoUniqueID{{Type: "tmdb", Default: true, Value: fmt.Sprint(tmdbID)}}
+	if imdbID != "" {
+		ids = append(ids, nfoUniqueID{Type: "imdb", Value: imdbID})
+	}
+	return ids
+}
+
+func marshalNFO(doc interface{}) []byte {
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return append([]byte(xml.Header), append(data, '\n')...)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func metadataStrings(metadata models.Metadata, key string) []string {
+	values, ok := metadata[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+package subtitles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the public OpenSubtitles REST API
+const DefaultAPIURL = "https://api.opensubtitles.com/api/v1"
+
+const userAgent = "StreamArr v1.0"
+
+// Client talks to an OpenSubtitles-compatible REST API
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient creates a client for baseURL (DefaultAPIURL when empty)
+func NewClient(baseURL, apiKey string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// SearchParams identifies the video subtitles are wanted for
+type SearchParams struct {
+	IMDBID    string // "tt1234567"; the series ID for episodes
+	Season    int    // Episode lookups only
+	Episode   int
+	MovieHash string   // OpenSubtitles hash of the video file, when known
+	Languages []string // ISO 639-1 codes
+}
+
+// Result is one subtitle file returned by a search
+type Result struct {
+	FileID          int64   `json:"file_id"`
+	Language        string  `json:"language"`
+	Release         string  `json:"release"`
+	FileName        string  `json:"file_name"`
+	Downloads       int     `json:"downloads"`
+	HashMatch       bool    `json:"hash_match"`
+	HearingImpaired bool    `json:"hearing_impaired"`
+	FPS             float64 `json:"fps,omitempty"`
+}
+
+type searchResponse struct {
+	Data []struct {
+		Attributes struct {
+			Language        string  `json:"language"`
+			DownloadCount   int     `json:"download_count"`
+			HearingImpaired bool    `json:"hearing_impaired"`
+			FPS             float64 `json:"fps"`
+			Release         string  `json:"release"`
+			MovieHashMatch  bool    `json:"moviehash_match"`
+			Files           []struct {
+				FileID   int64  `json:"file_id"`
+				FileName string `json:"file_name"`
+			} `json:"files"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// Search returns the subtitle files matching params
+func (c *Client) Search(ctx context.Context, params SearchParams) ([]Result, error) {
+	imdbNum, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(params.IMDBID), "tt"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMDB ID %q", params.IMDBID)
+	}
+	imdb := strconv.Itoa(imdbNum) // The API wants IMDB IDs without leading zeros
+
+	// The API redirects requests whose parameters aren't lowercase and alphabetically sorted
+	query := url.Values{}
+	if params.Season > 0 || params.Episode > 0 {
+		query.Set("parent_imdb_id", imdb)
+		query.Set("season_number", strconv.Itoa(params.Season))
+		query.Set("episode_number", strconv.Itoa(params.Episode))
+	} else {
+		query.Set("imdb_id", imdb)
+	}
+	if params.MovieHash != "" {
+		query.Set("moviehash", strings.ToLower(params.MovieHash))
+	}
+	if len(params.Languages) > 0 {
+		langs := make([]string, len(params.Languages))
+		for i, l := range params.Languages {
+			langs[i] = strings.ToLower(l)
+		}
+		sort.Strings(langs)
+		query.Set("languages", strings.Join(langs, ","))
+	}
+
+	rawQuery := strings.ReplaceAll(query.Encode(), "%2C", ",")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/subtitles?"+rawQuery, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp searchResponse
+	if err := c.do(req, &resp); err != nil {
+		return nil, fmt.Errorf("subtitle search failed: %w", err)
+	}
+
+	var results []Result
+	for _, item := range resp.Data {
+		a := item.Attributes
+		for _, f := range a.Files {
+			results = append(results, Result{
+				FileID:          f.FileID,
+				Language:        a.Language,
+				Release:         a.Release,
+				FileName:        f.FileName,
+				Downloads:       a.DownloadCount,
+				HashMatch:       a.MovieHashMatch,
+				HearingImpaired: a.HearingImpaired,
+				FPS:             a.FPS,
+			})
+		}
+	}
+	return results, nil
+}
+
+// DownloadLink returns a temporary link to a subtitle file; each call counts against the API key's daily quota
+func (c *Client) DownloadLink(ctx context.Context, fileID int64) (string, error) {
+	body, _ := json.Marshal(map[string]int64{"file_id": fileID})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/download", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		Link    string `json:"link"`
+		Message string `json:"message"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return "", fmt.Errorf("subtitle download failed: %w", err)
+	}
+	if resp.Link == "" {
+		return "", fmt.Errorf("subtitle download failed: %s", nonEmpty(resp.Message, "no link returned"))
+	}
+	return resp.Link, nil
+}
+
+// Fetch downloads a subtitle file from a link returned by DownloadLink
+func (c *Client) Fetch(ctx context.Context, link string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("subtitle fetch returned status %d", resp.StatusCode)
+	}
+	// Subtitle files are small; anything larger is not a subtitle
+	return io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+}
+
+func (c *Client) do(req *http.Request, dest interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if c.apiKey != "" {
+		req.Header.Set("Api-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
+
+func nonEmpty(s, fallback string) string {
+	if s != "" {
+		return s
+	}
+	return fallback
+}
@@ -0,0 +1,136 @@
+package subtitles
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// openSubtitlesStandIn mimics the OpenSubtitles search and download endpoints and serves the files
+type openSubtitlesStandIn struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	queries   []string
+	downloads int
+	files     map[int64]string
+}
+
+func newOpenSubtitlesStandIn(t *testing.T) *openSubtitlesStandIn {
+	s := &openSubtitlesStandIn{files: map[int64]string{
+		101: "1\r\n00:00:01,500 --> 00:00:03,000\r\nHello\r\n",
+		202: "WEBVTT\n\n00:01.000 --> 00:02.500 align:start\n<v Bob>Hi</v>\n",
+	}}
+	s.server = httptest.NewServer(s)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *openSubtitlesStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/files/") {
+		var id int64
+		fmt.Sscanf(r.URL.Path, "/files/%d", &id)
+		s.mu.Lock()
+		data, ok := s.files[id]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+		return
+	}
+	if r.Header.Get("Api-Key") != "test-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"You cannot consume this service"}`))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/subtitles":
+		s.queries = append(s.queries, r.URL.RawQuery)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []interface{}{
+			searchItem("en", "Heat.1995.720p.BluRay", 900, false, 101),
+			searchItem("en", "Heat.1995.1080p.BluRay.x264", 50, false, 102),
+			searchItem("fr", "Heat.1995.FRENCH", 300, false, 103),
+			searchItem("de", "Heat.1995.German", 100, true, 104),
+		}})
+	case "/download":
+		var req struct {
+			FileID int64 `json:"file_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := s.files[req.FileID]; !ok {
+			json.NewEncoder(w).Encode(map[string]string{"message": "File not found"})
+			return
+		}
+		s.downloads++
+		json.NewEncoder(w).Encode(map[string]string{"link": fmt.Sprintf("%s/files/%d", s.server.URL, req.FileID)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func searchItem(lang, release string, downloads int, hashMatch bool, fileID int64) map[string]interface{} {
+	return map[string]interface{}{"attributes": map[string]interface{}{
+		"language":        lang,
+		"release":         release,
+		"download_count":  downloads,
+		"moviehash_match": hashMatch,
+		"files":           []interface{}{map[string]interface{}{"file_id": fileID, "file_name": release + ".srt"}},
+	}}
+}
+
+func TestClientSearchQuery(t *testing.T) {
+	standIn := newOpenSubtitlesStandIn(t)
+	client := NewClient(standIn.server.URL+"/", "test-key")
+
+	results, err := client.Search(context.Background(), SearchParams{IMDBID: "tt0113277", MovieHash: "ABCDEF", Languages: []string{"FR", "en"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results[3].FileID != 104 || !results[3].HashMatch {
+		t.Errorf("results = %+v", results)
+	}
+	if _, err := client.Search(context.Background(), SearchParams{IMDBID: "tt0903747", Season: 2, Episode: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		// Lowercase and sorted, with the IMDB ID's leading zeros dropped and the commas left unescaped
+		"imdb_id=113277&languages=en,fr&moviehash=abcdef",
+		"episode_number=5&parent_imdb_id=903747&season_number=2",
+	}
+	if strings.Join(standIn.queries, " ") != strings.Join(want, " ") {
+		t.Errorf("queries = %q, want %q", standIn.queries, want)
+	}
+
+	if _, err := client.Search(context.Background(), SearchParams{IMDBID: "none"}); err == nil {
+		t.Error("an invalid IMDB ID was searched")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	standIn := newOpenSubtitlesStandIn(t)
+
+	_, err := NewClient(standIn.server.URL, "wrong-key").Search(context.Background(), SearchParams{IMDBID: "tt0113277"})
+	if err == nil || !strings.Contains(err.Error(), "status 401") || !strings.Contains(err.Error(), "cannot consume") {
+		t.Errorf("search error = %v, want the 401 and its message", err)
+	}
+
+	_, err = NewClient(standIn.server.URL, "test-key").DownloadLink(context.Background(), 999)
+	if err == nil || !strings.Contains(err.Error(), "File not found") {
+		t.Errorf("download error = %v, want the API's message", err)
+	}
+
+	if _, err := NewClient(standIn.server.URL, "test-key").Fetch(context.Background(), standIn.server.URL+"/files/999"); err == nil {
+		t.Error("fetching a missing file succeeded")
+	}
+}
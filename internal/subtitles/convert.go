@@ -0,0 +1,110 @@
+package subtitles
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// timestampRe matches SRT (00:01:02,345) and VTT (00:01:02.345 or 01:02.345) cue times
+	timestampRe = regexp.MustCompile(`(?:(\d+):)?(\d{1,2}):(\d{2})[,.](\d{1,3})`)
+	// vttOnlyTagRe matches WebVTT markup SRT players don't understand (classes, voices, karaoke times)
+	vttOnlyTagRe = regexp.MustCompile(`</?(?:c|v|lang|ruby|rt)(?:[.\s][^>]*)?>|<\d[\d:.]*>`)
+)
+
+// SRTToVTT converts a SubRip file to WebVTT for browser and Stremio players
+func SRTToVTT(data []byte) []byte {
+	text := normalizeText(data)
+	if strings.HasPrefix(text, "WEBVTT") {
+		return []byte(text)
+	}
+
+	var out strings.Builder
+	out.WriteString("WEBVTT\n\n")
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, "-->") {
+			line = timestampRe.ReplaceAllStringFunc(line, func(ts string) string {
+				return formatTimestamp(ts, ".")
+			})
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return []byte(out.String())
+}
+
+// VTTToSRT converts a WebVTT file to SubRip for Xtream players, dropping styling blocks and cue settings
+func VTTToSRT(data []byte) []byte {
+	text := normalizeText(data)
+	if !strings.HasPrefix(text, "WEBVTT") {
+		return []byte(text)
+	}
+
+	var out strings.Builder
+	cue := 0
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		// Header, NOTE, STYLE and REGION blocks have no timing line
+		if timing < 0 {
+			continue
+		}
+
+		times := timestampRe.FindAllString(lines[timing], 2)
+		if len(times) != 2 {
+			continue
+		}
+		cue++
+		fmt.Fprintf(&out, "%d\n%s --> %s\n", cue, formatTimestamp(times[0], ","), formatTimestamp(times[1], ","))
+		for _, line := range lines[timing+1:] {
+			out.WriteString(vttOnlyTagRe.ReplaceAllString(line, ""))
+			out.WriteByte('\n')
+		}
+		out.WriteByte('\n')
+	}
+	return []byte(out.String())
+}
+
+// formatTimestamp rewrites a cue time as HH:MM:SS<sep>mmm
+func formatTimestamp(ts, sep string) string {
+	m := timestampRe.FindStringSubmatch(ts)
+	if m == nil {
+		return ts
+	}
+	hours, minutes := m[1], m[2]
+	for len(hours) < 2 {
+		hours = "0" + hours
+	}
+	if len(minutes) < 2 {
+		minutes = "0" + minutes
+	}
+	// The fraction is milliseconds, so ",5" is 500ms
+	millis := (m[4] + "00")[:3]
+	return hours + ":" + minutes + ":" + m[3] + sep + millis
+}
+
+// normalizeText strips the BOM, converts Latin-1 files to UTF-8 and unifies line endings
+func normalizeText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	text := string(data)
+	if !utf8.Valid(data) {
+		// Most non-UTF-8 subtitles are Windows-1252/Latin-1; mapping bytes to runes keeps accents readable
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.TrimSpace(text) + "\n"
+}
@@ -0,0 +1,47 @@
+package subtitles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// hashChunkSize is how much of each end of the file the OpenSubtitles hash reads
+const hashChunkSize = 64 * 1024
+
+// HashFile returns the OpenSubtitles hash and size of a local video file
+func HashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	hash, err := Hash(f, info.Size())
+	return hash, info.Size(), err
+}
+
+// Hash computes the OpenSubtitles hash: the file size plus the little-endian uint64
+// sums of the first and last 64 KiB, as 16 hex digits
+func Hash(r io.ReaderAt, size int64) (string, error) {
+	if size < hashChunkSize {
+		return "", fmt.Errorf("file too small to hash (%d bytes)", size)
+	}
+
+	sum := uint64(size)
+	buf := make([]byte, hashChunkSize)
+	for _, offset := range []int64{0, size - hashChunkSize} {
+		if _, err := r.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return "", err
+		}
+		for i := 0; i < hashChunkSize; i += 8 {
+			sum += binary.LittleEndian.Uint64(buf[i:])
+		}
+	}
+	return fmt.Sprintf("%016x", sum), nil
+}
@@ -0,0 +1,67 @@
+package subtitles
+
+import "strings"
+
+// iso6392 maps OpenSubtitles language codes (ISO 639-1, plus regional variants) to the
+// ISO 639-2 codes Stremio expects
+var iso6392 = map[string]string{
+	"ar": "ara", "bg": "bul", "bs": "bos", "ca": "cat", "cs": "cze", "da": "dan",
+	"de": "ger", "el": "gre", "en": "eng", "es": "spa", "et": "est", "eu": "baq",
+	"fa": "per", "fi": "fin", "fr": "fre", "he": "heb", "hi": "hin", "hr": "hrv",
+	"hu": "hun", "id": "ind", "is": "ice", "it": "ita", "ja": "jpn", "ko": "kor",
+	"lt": "lit", "lv": "lav", "mk": "mac", "ms": "may", "nl": "dut", "no": "nor",
+	"pl": "pol", "pt": "por", "pt-pt": "por", "pt-br": "pob", "ro": "rum", "ru": "rus",
+	"sk": "slo", "sl": "slv", "sq": "alb", "sr": "scc", "sv": "swe", "th": "tha",
+	"tr": "tur", "uk": "ukr", "vi": "vie", "zh-cn": "chi", "zh-tw": "zht",
+}
+
+// terminologyCodes maps ISO 639-2/T codes (deu, fra, ...) that differ from the bibliographic ones above
+var terminologyCodes = map[string]string{
+	"ces": "cs", "deu": "de", "ell": "el", "eus": "eu", "fas": "fa", "fra": "fr", "isl": "is",
+	"mkd": "mk", "msa": "ms", "nld": "nl", "ron": "ro", "slk": "sk", "sqi": "sq", "srp": "sr", "zho": "zh-cn",
+}
+
+// ToISO6392 returns the three-letter code for an OpenSubtitles language (the input when unknown)
+func ToISO6392(code string) string {
+	code = strings.ToLower(code)
+	if long, ok := iso6392[code]; ok {
+		return long
+	}
+	return code
+}
+
+// ParseLanguages splits a comma-separated language list into OpenSubtitles codes, accepting
+// ISO 639-1 and 639-2 input and keeping the given order
+func ParseLanguages(list string) []string {
+	var langs []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(list, ",") {
+		code := strings.ToLower(strings.TrimSpace(part))
+		if len(code) == 3 {
+			code = fromISO6392(code)
+		}
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		langs = append(langs, code)
+	}
+	return langs
+}
+
+// fromISO6392 returns the OpenSubtitles code for a three-letter code, preferring the unregional one
+func fromISO6392(code string) string {
+	if short, ok := terminologyCodes[code]; ok {
+		return short
+	}
+	best := ""
+	for short, long := range iso6392 {
+		if long == code && (best == "" || len(short) < len(best)) {
+			best = short
+		}
+	}
+	if best == "" {
+		return code
+	}
+	return best
+}
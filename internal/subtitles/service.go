@@ -0,0 +1,272 @@
+package subtitles
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
+)
+
+// ErrDisabled is returned when subtitle search is turned off or has no API key
+var ErrDisabled = errors.New("subtitles are disabled")
+
+// maxPerLanguage caps how many files are offered per language so players' pickers stay usable
+const maxPerLanguage = 5
+
+// Options configures the service; read on every call so settings changes apply without a restart
+type Options struct {
+	Enabled   bool
+	APIURL    string // OpenSubtitles-compatible REST API (DefaultAPIURL when empty)
+	APIKey    string
+	Languages []string      // Used when the caller has no language preference
+	CacheDir  string        // Downloaded files are kept here, so each file costs one API download
+	CacheTTL  time.Duration // How long search results are reused
+}
+
+// Query identifies a library movie or episode
+type Query struct {
+	IMDBID    string // Movie or series IMDB ID
+	Season    int
+	Episode   int
+	Hash      string   // OpenSubtitles hash of the file being played, when known
+	Filename  string   // Release name of the file being played, used to rank matching subtitles first
+	Languages []string // Preferred languages in order (Options.Languages when empty)
+}
+
+// Service searches, caches and serves subtitles
+type Service struct {
+	store      *database.SubtitleStore
+	getOptions func() Options
+	secret     []byte
+
+	mu       sync.Mutex
+	fetching map[int64]*sync.Mutex
+}
+
+// NewService creates a subtitle service; secret signs file URLs so only offered files can be downloaded
+func NewService(store *database.SubtitleStore, getOptions func() Options, secret string) *Service {
+	return &Service{
+		store:      store,
+		getOptions: getOptions,
+		secret:     []byte(secret),
+		fetching:   make(map[int64]*sync.Mutex),
+	}
+}
+
+// Enabled reports whether searches will be attempted
+func (s *Service) Enabled() bool {
+	opts := s.getOptions()
+	return opts.Enabled && opts.APIKey != ""
+}
+
+// Search returns subtitles for a movie or episode in preference order: requested language order,
+// then files matching the played file's hash or release name, then popularity
+func (s *Service) Search(ctx context.Context, q Query) ([]Result, error) {
+	opts := s.getOptions()
+	if !opts.Enabled || opts.APIKey == "" {
+		return nil, ErrDisabled
+	}
+	langs := q.Languages
+	if len(langs) == 0 {
+		langs = opts.Languages
+	}
+
+	params := SearchParams{
+		IMDBID:    q.IMDBID,
+		Season:    q.Season,
+		Episode:   q.Episode,
+		MovieHash: q.Hash,
+		Languages: langs,
+	}
+	key := cacheKey(params)
+
+	var results []Result
+	found := false
+	if s.store != nil && opts.CacheTTL > 0 {
+		var err error
+		if found, err = s.store.GetResults(ctx, key, opts.CacheTTL, &results); err != nil {
+			log.Printf("[SUBTITLES] ⚠️ Cache lookup failed: %v", err)
+		}
+	}
+	if !found {
+		var err error
+		results, err = NewClient(opts.APIURL, opts.APIKey).Search(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if s.store != nil {
+			if err := s.store.SaveResults(ctx, key, results); err != nil {
+				log.Printf("[SUBTITLES] ⚠️ Cache save failed: %v", err)
+			}
+		}
+	}
+
+	return rankResults(results, langs, q.Filename), nil
+}
+
+// FilePath returns the signed, server-relative URL path of a subtitle file; format is "vtt" or "srt"
+func (s *Service) FilePath(fileID int64, format string) string {
+	return fmt.Sprintf("/subtitles/%d/%s.%s", fileID, s.sign(fileID), format)
+}
+
+// Open validates a signed file reference and returns the file in the requested format,
+// downloading it once and serving later requests from disk
+func (s *Service) Open(ctx context.Context, idStr, signature, format string) ([]byte, error) {
+	fileID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file id")
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(fileID))) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	srt, err := s.load(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if format == "vtt" {
+		return SRTToVTT(srt), nil
+	}
+	return srt, nil
+}
+
+// load returns a file as SRT from the disk cache, downloading it on first use
+func (s *Service) load(ctx context.Context, fileID int64) ([]byte, error) {
+	opts := s.getOptions()
+	path := filepath.Join(opts.CacheDir, strconv.FormatInt(fileID, 10)+".srt")
+
+	// Serialise downloads of the same file so concurrent players spend one API download
+	lock := s.fileLock(fileID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if data, err := os.ReadFile(path); err == nil {
+		return data, nil
+	}
+	if !opts.Enabled || opts.APIKey == "" {
+		return nil, ErrDisabled
+	}
+
+	client := NewClient(opts.APIURL, opts.APIKey)
+	link, err := client.DownloadLink(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := client.Fetch(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	// Files are stored as SRT; some uploads are WebVTT already
+	data = VTTToSRT(data)
+
+	if err := os.MkdirAll(opts.CacheDir, 0755); err != nil {
+		log.Printf("[SUBTITLES] ⚠️ Cannot create cache folder %s: %v", opts.CacheDir, err)
+		return data, nil
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Printf("[SUBTITLES] ⚠️ Cannot cache subtitle %d: %v", fileID, err)
+	}
+	return data, nil
+}
+
+func (s *Service) fileLock(fileID int64) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.fetching[fileID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.fetching[fileID] = lock
+	}
+	return lock
+}
+
+func (s *Service) sign(fileID int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "subtitle:%d", fileID)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func cacheKey(p SearchParams) string {
+	langs := append([]string(nil), p.Languages...)
+	sort.Strings(langs)
+	return fmt.Sprintf("%s:%d:%d:%s:%s", strings.ToLower(p.IMDBID), p.Season, p.Episode, strings.ToLower(p.MovieHash), strings.Join(langs, ","))
+}
+
+// rankResults orders results by language preference, file match and downloads, keeping a few per language
+func rankResults(results []Result, langs []string, filename string) []Result {
+	langRank := make(map[string]int, len(langs))
+	for i, l := range langs {
+		langRank[strings.ToLower(l)] = i
+	}
+	rank := func(r Result) int {
+		if i, ok := langRank[strings.ToLower(r.Language)]; ok {
+			return i
+		}
+		return len(langs)
+	}
+	release := stripExtension(filename)
+	matches := func(r Result) bool {
+		if r.HashMatch {
+			return true
+		}
+		return release != "" && (strings.EqualFold(r.Release, release) ||
+			strings.EqualFold(stripExtension(r.FileName), release))
+	}
+
+	sorted := append([]Result(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if ra, rb := rank(a), rank(b); ra != rb {
+			return ra < rb
+		}
+		if ma, mb := matches(a), matches(b); ma != mb {
+			return ma
+		}
+		return a.Downloads > b.Downloads
+	})
+
+	perLanguage := map[string]int{}
+	ranked := sorted[:0]
+	for _, r := range sorted {
+		lang := strings.ToLower(r.Language)
+		if perLanguage[lang] >= maxPerLanguage {
+			continue
+		}
+		perLanguage[lang]++
+		ranked = append(ranked, r)
+	}
+	return ranked
+}
+
+// stripExtension removes a video or subtitle extension; release names are full of dots
+// (".WEB-DL", ".x264") that filepath.Ext would mistake for one
+func stripExtension(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mkv", ".mp4", ".m4v", ".avi", ".ts", ".webm", ".srt", ".vtt", ".sub", ".ass", ".ssa":
+		return strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name
+}
+
+// HashLocalFile returns the hash and file name of the first local file that can be hashed,
+// so subtitles synced to the exact release rank first
+func HashLocalFile(files []*database.LocalMediaFile) (string, string) {
+	for _, f := range files {
+		if hash, _, err := HashFile(f.Path); err == nil {
+			return hash, filepath.Base(f.Path)
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,101 @@
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestService(t *testing.T, opts Options) (*Service, *Options) {
+	current := &opts
+	return NewService(nil, func() Options { return *current }, "test-secret"), current
+}
+
+func TestServiceSearchRanking(t *testing.T) {
+	standIn := newOpenSubtitlesStandIn(t)
+	service, _ := newTestService(t, Options{Enabled: true, APIURL: standIn.server.URL, APIKey: "test-key", Languages: []string{"fr", "en"}})
+
+	results, err := service.Search(context.Background(), Query{IMDBID: "tt0113277", Filename: "Heat.1995.1080p.BluRay.x264.mkv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// French first, then English with the played release ahead of the more popular file, then the rest
+	var got []int64
+	for _, r := range results {
+		got = append(got, r.FileID)
+	}
+	if want := []int64{103, 102, 101, 104}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("file order = %v, want %v", got, want)
+	}
+}
+
+func TestServiceDisabled(t *testing.T) {
+	standIn := newOpenSubtitlesStandIn(t)
+	service, _ := newTestService(t, Options{Enabled: true, APIURL: standIn.server.URL})
+
+	if _, err := service.Search(context.Background(), Query{IMDBID: "tt0113277"}); !errors.Is(err, ErrDisabled) {
+		t.Errorf("search without an API key = %v, want ErrDisabled", err)
+	}
+	if len(standIn.queries) != 0 {
+		t.Error("the API was called while disabled")
+	}
+}
+
+func TestServiceOpen(t *testing.T) {
+	standIn := newOpenSubtitlesStandIn(t)
+	cacheDir := filepath.Join(t.TempDir(), "subtitles")
+	service, opts := newTestService(t, Options{Enabled: true, APIURL: standIn.server.URL, APIKey: "test-key", CacheDir: cacheDir})
+	ctx := context.Background()
+
+	path := service.FilePath(101, "vtt")
+	parts := strings.Split(strings.TrimPrefix(path, "/subtitles/"), "/")
+	signature := strings.TrimSuffix(parts[1], ".vtt")
+
+	vtt, err := service.Open(ctx, parts[0], signature, "vtt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "WEBVTT\n\n1\n00:00:01.500 --> 00:00:03.000\nHello\n\n"; string(vtt) != want {
+		t.Errorf("vtt = %q, want %q", vtt, want)
+	}
+
+	// Served from disk afterwards, even once subtitles are turned off
+	opts.Enabled = false
+	srt, err := service.Open(ctx, "101", signature, "srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(srt), "1\n00:00:01,500 --> 00:00:03,000\n") || standIn.downloads != 1 {
+		t.Errorf("cached srt = %q after %d downloads", srt, standIn.downloads)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "101.srt")); err != nil {
+		t.Errorf("file not cached: %v", err)
+	}
+
+	if _, err := service.Open(ctx, "102", signature, "srt"); err == nil {
+		t.Error("a signature was accepted for another file")
+	}
+}
+
+func TestServiceOpenConvertsWebVTT(t *testing.T) {
+	standIn := newOpenSubtitlesStandIn(t)
+	service, _ := newTestService(t, Options{Enabled: true, APIURL: standIn.server.URL, APIKey: "test-key", CacheDir: t.TempDir()})
+
+	srt, err := service.Open(context.Background(), "202", service.sign(202), "srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\n00:00:01,000 --> 00:00:02,500\nHi\n\n"; string(srt) != want {
+		t.Errorf("srt = %q, want %q", srt, want)
+	}
+}
+
+func TestParseLanguages(t *testing.T) {
+	got := strings.Join(ParseLanguages(" EN, fre,eng,deu,,pt-br "), ",")
+	if got != "en,fr,de,pt-br" {
+		t.Errorf("ParseLanguages = %q", got)
+	}
+}
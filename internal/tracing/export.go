@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"log"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// optionsTTL is how long options are reused before reading the settings again
+const optionsTTL = 5 * time.Second
+
+// shutdownTimeout bounds how long exporting the last spans may take
+const shutdownTimeout = 10 * time.Second
+
+// global holds the tracer provider built from the current options; it is rebuilt when they change,
+// so settings apply without a restart
+var global struct {
+	mu         sync.Mutex
+	getOptions func() Options
+	opts       Options
+	loadedAt   time.Time
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	stopped    bool
+}
+
+// Init sets where the options come from; spans are only recorded after Init and while Options.Enabled
+func Init(getOptions func() Options) {
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Printf("[TRACING] ⚠️ %v", err)
+	}))
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	global.getOptions = getOptions
+	global.loadedAt = time.Time{}
+	global.stopped = false
+}
+
+// Run keeps tracing going until ctx is done, then exports the spans that are left
+func Run(ctx context.Context) {
+	<-ctx.Done()
+	global.mu.Lock()
+	provider := global.provider
+	global.provider, global.tracer, global.stopped = nil, nil, true
+	global.mu.Unlock()
+	shutdown(provider)
+}
+
+// currentTracer returns the tracer for the current options, or nil while tracing is off
+func currentTracer() trace.Tracer {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	if global.getOptions == nil || global.stopped {
+		return nil
+	}
+	if time.Since(global.loadedAt) > optionsTTL {
+		opts := global.getOptions()
+		global.loadedAt = time.Now()
+		if global.provider == nil || !sameOptions(opts, global.opts) {
+			old := global.provider
+			global.provider, global.tracer = nil, nil
+			if opts.Enabled {
+				if provider, err := newProvider(opts); err != nil {
+					log.Printf("[TRACING] ⚠️ Cannot export traces: %v", err)
+				} else {
+					global.provider, global.tracer = provider, provider.Tracer("github.com/Zerr0-C00L/StreamArr")
+				}
+			}
+			global.opts = opts
+			go shutdown(old)
+		}
+	}
+	return global.tracer
+}
+
+func sameOptions(a, b Options) bool {
+	return a.Enabled == b.Enabled && a.Endpoint == b.Endpoint && a.ServiceName == b.ServiceName &&
+		a.SampleRatio == b.SampleRatio && maps.Equal(a.Headers, b.Headers)
+}
+
+// newProvider exports to the OTLP/HTTP collector in opts, in batches. Traces continued from a
+// caller follow its sampling decision; new ones are kept at opts.SampleRatio.
+func newProvider(opts Options) (*sdktrace.TracerProvider, error) {
+	endpoint := strings.TrimSuffix(opts.Endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(endpoint),
+		otlptracehttp.WithHeaders(opts.Headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = "streamarr"
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	), nil
+}
+
+// shutdown exports the provider's remaining spans and stops it
+func shutdown(provider *sdktrace.TracerProvider) {
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Printf("[TRACING] ⚠️ Dropped spans on shutdown: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package tracing records spans of work — an Xtream play request, the provider lookups and debrid
+// calls it makes — with the OpenTelemetry SDK and exports them to a collector over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configures tracing
+type Options struct {
+	Enabled     bool
+	Endpoint    string            // OTLP/HTTP collector, e.g. http://localhost:4318
+	Headers     map[string]string // Sent with every export, e.g. an API key for a hosted collector
+	ServiceName string
+	SampleRatio float64 // Share of new traces recorded, 0 to 1
+}
+
+// Span kinds
+const (
+	KindInternal = trace.SpanKindInternal
+	KindServer   = trace.SpanKindServer
+	KindClient   = trace.SpanKindClient
+)
+
+// ParseHeaders reads comma-separated key=value pairs, e.g. "x-api-key=abc,x-tenant=home"
+func ParseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" {
+			headers[key] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
+
+// Span is one timed operation. A nil Span, returned when tracing is off or the trace isn't sampled,
+// ignores every call, so callers never need to check.
+type Span struct {
+	span trace.Span
+}
+
+// FromContext returns the recording span in ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return nil
+	}
+	return &Span{span}
+}
+
+// Start begins a span as a child of the span in ctx. Without a parent it starts a new trace,
+// sampled by Options.SampleRatio, except for client spans: outgoing calls are only traced as part
+// of a request or job. End the span when the work is done.
+func Start(ctx context.Context, name string, kind trace.SpanKind) (context.Context, *Span) {
+	if kind == KindClient && !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+	tracer := currentTracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	if !span.IsRecording() {
+		return ctx, nil
+	}
+	return ctx, &Span{span}
+}
+
+// StartRequest begins the server span of an HTTP request, continuing the caller's trace when the
+// request carries a W3C traceparent header
+func StartRequest(r *http.Request, name string) (context.Context, *Span) {
+	ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return Start(ctx, name, KindServer)
+}
+
+// Set adds an attribute; values are strings, bools, integers or floats
+func (s *Span) Set(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(keyValue(key, value))
+}
+
+// Event records something that happened during the span, with key/value pairs as attributes
+func (s *Span) Event(name string, kv ...interface{}) {
+	if s == nil {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		attrs = append(attrs, keyValue(fmt.Sprint(kv[i]), kv[i+1]))
+	}
+	s.span.AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// Fail marks the span as failed
+func (s *Span) Fail(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span and hands it to the exporter
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+func keyValue(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	}
+	return attribute.String(key, fmt.Sprint(value))
+}
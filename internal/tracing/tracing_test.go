@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// collector is a stand-in OTLP/HTTP collector recording what it receives
+type collector struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.requests = append(c.requests, r)
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestSpansAreExportedOnShutdown(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	Init(func() Options {
+		return Options{
+			Enabled:     true,
+			Endpoint:    server.URL,
+			Headers:     map[string]string{"x-api-key": "secret"},
+			SampleRatio: 1,
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx)
+		close(done)
+	}()
+
+	req := httptest.NewRequest("GET", "/player_api.php", nil)
+	reqCtx, span := StartRequest(req, "GET /player_api.php")
+	if span == nil {
+		t.Fatal("StartRequest returned no span while tracing is on")
+	}
+	_, child := Start(reqCtx, "provider torrentio", KindClient)
+	if child == nil {
+		t.Fatal("a client span inside a request was not recorded")
+	}
+	child.Set("provider.streams", 3)
+	child.Fail(errors.New("timeout"))
+	child.End()
+	span.End()
+
+	cancel()
+	<-done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) == 0 {
+		t.Fatal("no spans reached the collector")
+	}
+	got := c.requests[0]
+	if got.URL.Path != "/v1/traces" {
+		t.Errorf("exported to %s, want /v1/traces", got.URL.Path)
+	}
+	if got.Header.Get("x-api-key") != "secret" {
+		t.Errorf("configured headers were not sent")
+	}
+}
+
+func TestClientSpansNeedAParent(t *testing.T) {
+	Init(func() Options { return Options{Enabled: true, Endpoint: "http://127.0.0.1:1", SampleRatio: 1} })
+	if _, span := Start(context.Background(), "realdebrid GET /torrents", KindClient); span != nil {
+		t.Error("a client span without a parent started a trace")
+	}
+}
+
+func TestTraceparentFollowsCallerDecision(t *testing.T) {
+	Init(func() Options { return Options{Enabled: true, Endpoint: "http://127.0.0.1:1", SampleRatio: 1} })
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	if _, span := StartRequest(req, "GET /"); span != nil {
+		t.Error("a trace the caller didn't sample was recorded")
+	}
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	_, span := StartRequest(req, "GET /")
+	if span == nil {
+		t.Fatal("a trace the caller sampled was not recorded")
+	}
+	if got := span.span.SpanContext().TraceID().String(); got != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("trace ID = %s, want the caller's", got)
+	}
+}
+
+func TestDisabledRecordsNothing(t *testing.T) {
+	Init(func() Options { return Options{} })
+	if _, span := Start(context.Background(), "job sync", KindInternal); span != nil {
+		t.Error("a span was recorded while tracing is off")
+	}
+	// A nil span ignores every call
+	var span *Span
+	span.Set("k", "v")
+	span.Event("e")
+	span.Fail(errors.New("x"))
+	span.End()
+}
+
+func TestParseHeaders(t *testing.T) {
+	got := ParseHeaders(" x-api-key = abc ,x-tenant=home,broken,=empty")
+	if len(got) != 2 || got["x-api-key"] != "abc" || got["x-tenant"] != "home" {
+		t.Errorf("ParseHeaders = %v", got)
+	}
+}
@@ -2,6 +2,7 @@ package xtream
 
 import (
 	"context"
+	"crypto/subtle"
 	"crypto/tls"
 	"database/sql"
 	"encoding/base64"
@@ -18,11 +19,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Zerr0-C00L/StreamArr/internal/activity"
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+	"github.com/Zerr0-C00L/StreamArr/internal/cache"
 	"github.com/Zerr0-C00L/StreamArr/internal/config"
+	"github.com/Zerr0-C00L/StreamArr/internal/database"
 	"github.com/Zerr0-C00L/StreamArr/internal/epg"
 	"github.com/Zerr0-C00L/StreamArr/internal/livetv"
+	"github.com/Zerr0-C00L/StreamArr/internal/localmedia"
+	"github.com/Zerr0-C00L/StreamArr/internal/models"
+	"github.com/Zerr0-C00L/StreamArr/internal/playlist"
 	"github.com/Zerr0-C00L/StreamArr/internal/providers"
 	"github.com/Zerr0-C00L/StreamArr/internal/services"
+	"github.com/Zerr0-C00L/StreamArr/internal/services/streams"
+	"github.com/Zerr0-C00L/StreamArr/internal/subtitles"
+	"github.com/Zerr0-C00L/StreamArr/internal/tracing"
 	"github.com/gorilla/mux"
 )
 
@@ -68,6 +79,31 @@ type XtreamHandler struct {
 	// Sorting settings
 	getSortOrder     func() string
 	getSortPrefer    func() string
+	// Season pack file mapping (nil when no debrid service is configured)
+	seasonPacks      *streams.SeasonPackResolver
+	// Local media library (nil when local media is disabled)
+	localMedia       *localmedia.Library
+	// Usenet providers by slug, resolved lazily on playback
+	usenetProviders  map[string]*providers.UsenetProvider
+	// Per-episode stream cache for instant starts (nil when stream caching is disabled)
+	streamCache      *database.StreamCacheStore
+	// Resolved-link cache (nil resolves on every play)
+	links            *cache.LinkCache
+	// Next episodes currently being prefetched
+	prefetching      sync.Map
+	// Generated playlists for get.php (nil renders from the library per request)
+	playlists        *playlist.ArtifactStore
+	seededPlaylist   string // playlist version whose episodes are in episodeCache
+	// Subtitle search for get_vod_info (nil when not configured)
+	subtitles        *subtitles.Service
+	// Lockout after repeated bad credentials, shared with web sign-ins (nil disables)
+	loginGuard       *auth.LoginGuard
+	// Records failed credential checks in the login history (nil disables)
+	authService      *auth.Service
+	// Records playback starts in the activity feed (nil disables)
+	activity         *activity.Recorder
+	// Configured Xtream API username and password (nil accepts the defaults)
+	getCredentials   func() (username, password string)
 }
 
 func NewXtreamHandler(cfg *config.Config, db *sql.DB, tmdb *services.TMDBClient, rdClient *services.RealDebridClient, channelManager *livetv.ChannelManager, epgManager *epg.Manager, stremioAddons []providers.StremioAddon, proxies []string) *XtreamHandler {
@@ -101,14 +137,59 @@ func NewXtreamHandlerWithProvider(cfg *config.Config, db *sql.DB, tmdb *services
 	return h
 }
 
-// resolveStremioURL attempts to resolve a Stremio addon URL to an actual playable video URL
-func (h *XtreamHandler) resolveStremioURL(addonURL string) (string, error) {
-	// Check if this looks like a Stio addon URL
-	if !strings.Contains(addonURL, "torrentsdb.com") && !strings.Contains(addonURL, "/realdebrid/") {
+// isAddonResolveURL reports whether a stream URL is an addon endpoint that redirects to the debrid link
+func isAddonResolveURL(addonURL string) bool {
+	return strings.Contains(addonURL, "torrentsdb.com") || strings.Contains(addonURL, "/realdebrid/")
+}
+
+// resolveStremioURL resolves a Stremio addon URL to an actual playable video URL,
+// reusing a previously resolved link while it is still valid
+func (h *XtreamHandler) resolveStremioURL(ctx context.Context, addonURL string) (string, error) {
+	if !isAddonResolveURL(addonURL) {
 		// Not a Stremio addon URL, return as-is
 		return addonURL, nil
 	}
 	
+	ctx, span := tracing.Start(ctx, "addon resolve", tracing.KindClient)
+	defer span.End()
+	
+	resolve := func(ctx context.Context) (string, error) {
+		return h.fetchAddonRedirect(addonURL)
+	}
+	var link string
+	var err error
+	if h.links == nil {
+		link, err = resolve(ctx)
+	} else {
+		link, err = h.links.Resolve(ctx, cache.URLKey(addonURL), "addon", resolve)
+	}
+	if u, parseErr := url.Parse(link); parseErr == nil {
+		span.Set("server.address", u.Host)
+	}
+	span.Fail(err)
+	return link, err
+}
+
+// redirectToStream sends the player to a resolved stream, noting the redirect in the request's trace
+func redirectToStream(w http.ResponseWriter, r *http.Request, target string) {
+	if u, err := url.Parse(target); err == nil {
+		tracing.FromContext(r.Context()).Event("redirect", "server.address", u.Host)
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// prefetchStremioURL resolves an addon URL in the background so the next play is served from the link cache
+func (h *XtreamHandler) prefetchStremioURL(addonURL string) {
+	if h.links == nil || !isAddonResolveURL(addonURL) {
+		return
+	}
+	h.links.Prefetch(cache.URLKey(addonURL), "addon", func(ctx context.Context) (string, error) {
+		return h.fetchAddonRedirect(addonURL)
+	})
+}
+
+// fetchAddonRedirect requests an addon resolve URL and returns the redirect target
+func (h *XtreamHandler) fetchAddonRedirect(addonURL string) (string, error) {
 	log.Printf("[RESOLVE] Attempting to resolve Stremio addon URL...")
 	
 	// Get list of proxies if Torrentio URL
@@ -282,24 +363,403 @@ func (h *XtreamHandler) SetSortSettings(getSortOrder, getSortPrefer func() strin
 	}
 }
 
-// ValidateXtreamCredentials checks if the provided username/password match the configured Xtream API credentials
-func (h *XtreamHandler) ValidateXtreamCredentials(username, password string) bool {
-	// Query settings from database
-	var storedUsername, storedPassword string
+// SetSeasonPackResolver enables playing episodes from cached season packs
+func (h *XtreamHandler) SetSeasonPackResolver(resolver *streams.SeasonPackResolver) {
+	h.seasonPacks = resolver
+}
+
+// playInfoHash redirects to the debrid link for a stream that has only an info hash (Torznab, DMM).
+// Season 0 plays the torrent's main video file; otherwise the episode's file.
+func (h *XtreamHandler) playInfoHash(w http.ResponseWriter, r *http.Request, infoHash string, seasonNum, episodeNum int, startTime time.Time) {
+	if h.rdClient == nil {
+		log.Printf("[PLAY] ❌ Stream %s needs a debrid service", infoHash)
+		http.Error(w, "Stream URL not available", http.StatusNotFound)
+		return
+	}
+	link, err := h.rdClient.GetEpisodeStreamURL(r.Context(), infoHash, seasonNum, episodeNum)
+	if err != nil {
+		log.Printf("[PLAY] ❌ Failed to resolve %s through debrid after %.2fs: %v", infoHash, time.Since(startTime).Seconds(), err)
+		http.Error(w, fmt.Sprintf("Stream resolution failed: %v", err), http.StatusBadGateway)
+		return
+	}
+	log.Printf("[PLAY] ✓ Redirecting to debrid stream (%.2fs)", time.Since(startTime).Seconds())
+	redirectToStream(w, r, link)
+}
+
+// playFromSeasonPack redirects to an episode inside a season pack.
+// With a nil stream only packs the episode was played from before are used; otherwise the
+// stream is used if it is a cached season pack. Returns false if nothing was played.
+func (h *XtreamHandler) playFromSeasonPack(w http.ResponseWriter, r *http.Request, imdbID string, seasonNum, episodeNum int, stream *providers.TorrentioStream) bool {
+	if h.seasonPacks == nil {
+		return false
+	}
+	
+	ctx, cancel := context.WithTimeout(r.Context(), 45*time.Second)
+	defer cancel()
+	
+	if stream == nil {
+		url, ok := h.seasonPacks.Lookup(ctx, imdbID, seasonNum, episodeNum)
+		if !ok {
+			return false
+		}
+		log.Printf("[PLAY-PACK] ✓ S%02dE%02d resolved from cached season pack", seasonNum, episodeNum)
+		redirectToStream(w, r, url)
+		return true
+	}
+	
+	if stream.InfoHash == "" || !stream.Cached || !streams.IsSeasonPack(stream.Name, stream.Title) {
+		return false
+	}
+	
+	url, err := h.seasonPacks.Resolve(ctx, imdbID, stream.InfoHash, stream.Title, seasonNum, episodeNum)
+	if err != nil {
+		log.Printf("[PLAY-PACK] ⚠️ Season pack mapping failed for %s S%02dE%02d: %v", imdbID, seasonNum, episodeNum, err)
+		return false
+	}
+	
+	log.Printf("[PLAY-PACK] ✓ Resolved S%02dE%02d from season pack %s", seasonNum, episodeNum, stream.InfoHash)
+	redirectToStream(w, r, url)
+	return true
+}
+
+// SetLinkCache enables reuse of resolved stream links and next-episode prefetching
+func (h *XtreamHandler) SetLinkCache(links *cache.LinkCache) {
+	h.links = links
+}
+
+// SetSubtitleService adds subtitles to get_vod_info responses
+func (h *XtreamHandler) SetSubtitleService(service *subtitles.Service) {
+	h.subtitles = service
+}
+
+// SetCredentials sets where the configured Xtream API username and password are read from
+func (h *XtreamHandler) SetCredentials(getter func() (username, password string)) {
+	h.getCredentials = getter
+}
+
+// SetLoginGuard locks out clients that keep sending wrong credentials and records their attempts
+func (h *XtreamHandler) SetLoginGuard(guard *auth.LoginGuard, authService *auth.Service) {
+	h.loginGuard = guard
+	h.authService = authService
+}
+
+// vodSubtitles returns SRT subtitles for a library movie in the server's default languages
+func (h *XtreamHandler) vodSubtitles(r *http.Request, imdbID string) []map[string]interface{} {
+	subs := []map[string]interface{}{}
+	if h.subtitles == nil || imdbID == "" || !h.subtitles.Enabled() {
+		return subs
+	}
+
+	// Players wait on get_vod_info before showing the movie, so don't hold it up for long
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	q := subtitles.Query{IMDBID: imdbID}
+	if h.localMedia != nil {
+		if files, err := h.localMedia.MovieFiles(ctx, imdbID); err == nil {
+			q.Hash, q.Filename = subtitles.HashLocalFile(files)
+		}
+	}
+	results, err := h.subtitles.Search(ctx, q)
+	if err != nil {
+		log.Printf("[SUBTITLES] ⚠️ Search for %s failed: %v", imdbID, err)
+		return subs
+	}
+
+	serverURL := fmt.Sprintf("http://%s", r.Host)
+	for _, res := range results {
+		subs = append(subs, map[string]interface{}{
+			"id":       res.FileID,
+			"language": subtitles.ToISO6392(res.Language),
+			"name":     res.Release,
+			"url":      serverURL + h.subtitles.FilePath(res.FileID, "srt"),
+		})
+	}
+	return subs
+}
+
+// prefetchNextEpisode resolves the link for the episode after the one being played, in the background.
+// It follows the order playback uses: stream cache, mapped season pack, then providers.
+func (h *XtreamHandler) prefetchNextEpisode(imdbID string, seriesID int64, seasonNum, episodeNum int) {
+	if h.links == nil {
+		return
+	}
+	next := episodeNum + 1
+	key := fmt.Sprintf("%s:%d:%d", imdbID, seasonNum, next)
+	if _, busy := h.prefetching.LoadOrStore(key, true); busy {
+		return
+	}
 	
-	// Get stored Xtream username
-	err := h.db.QueryRow("SELECT value FROM settings WHERE key = 'xtream_username'").Scan(&storedUsername)
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error querying xtream_username: %v", err)
+	go func() {
+		defer h.prefetching.Delete(key)
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		
+		if h.streamCache != nil && seriesID != 0 {
+			cached, err := h.streamCache.GetCachedEpisodeStream(ctx, int(seriesID), seasonNum, next)
+			if err == nil && cached != nil && cached.IsAvailable && cached.StreamURL != "" {
+				h.prefetchStremioURL(cached.StreamURL)
+				return
+			}
+		}
+		
+		// Resolving through the season pack resolver warms the link cache for the pack file
+		if h.seasonPacks != nil {
+			if _, ok := h.seasonPacks.Lookup(ctx, imdbID, seasonNum, next); ok {
+				return
+			}
+		}
+		
+		stream, err := h.multiProvider.GetBestStream(ctx, imdbID, &seasonNum, &next, h.cfg.MaxResolution)
+		if err != nil {
+			return
+		}
+		if streams.IsSeasonPack(stream.Name, stream.Title) {
+			// Remembers the pack for the episode and warms the link cache for its file
+			if h.seasonPacks != nil && stream.InfoHash != "" && stream.Cached {
+				if _, err := h.seasonPacks.Resolve(ctx, imdbID, stream.InfoHash, stream.Title, seasonNum, next); err == nil {
+					log.Printf("[PREFETCH] Pre-resolved %s S%02dE%02d from season pack", imdbID, seasonNum, next)
+				}
+			}
+			return
+		}
+		if stream.URL == "" {
+			return
+		}
+		h.cacheEpisodeStream(seriesID, seasonNum, next, stream)
+		h.prefetchStremioURL(stream.URL)
+		log.Printf("[PREFETCH] Pre-resolved %s S%02dE%02d", imdbID, seasonNum, next)
+	}()
+}
+
+// SetStreamCacheStore enables serving episodes from the stream cache
+// SetActivity records playback starts in the activity feed
+func (h *XtreamHandler) SetActivity(recorder *activity.Recorder) {
+	h.activity = recorder
+}
+
+// recordPlayback adds a playback start to the activity feed under the Xtream username.
+// HEAD requests are players probing the stream, not playback.
+func (h *XtreamHandler) recordPlayback(r *http.Request, contentType string, contentID int64, title string) {
+	if h.activity == nil || r.Method == http.MethodHead {
+		return
+	}
+	viewer := mux.Vars(r)["username"]
+	if viewer == "" {
+		viewer = r.URL.Query().Get("username")
+	}
+	if viewer == "" {
+		viewer = "xtream"
+	}
+	h.activity.Playback(r.Context(), activity.Event{
+		ContentType: contentType,
+		ContentID:   contentID,
+		Message:     fmt.Sprintf("Started %s %q", contentType, title),
+		Data: map[string]interface{}{
+			"client":     "xtream",
+			"title":      title,
+			"remote_ip":  auth.ClientIP(r),
+			"user_agent": r.UserAgent(),
+		},
+		Username: viewer,
+	})
+}
+
+func (h *XtreamHandler) SetStreamCacheStore(store *database.StreamCacheStore) {
+	h.streamCache = store
+}
+
+// playCachedEpisode redirects to the cached stream for an episode.
+// Returns false if the episode has no usable cached stream.
+func (h *XtreamHandler) playCachedEpisode(w http.ResponseWriter, r *http.Request, seriesID int64, seasonNum, episodeNum int) bool {
+	if h.streamCache == nil || seriesID == 0 {
+		return false
+	}
+	
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	
+	cached, err := h.streamCache.GetCachedEpisodeStream(ctx, int(seriesID), seasonNum, episodeNum)
+	if err != nil || cached == nil || !cached.IsAvailable || cached.StreamURL == "" {
+		return false
+	}
+	
+	finalURL := cached.StreamURL
+	if !strings.HasPrefix(finalURL, "/") {
+		finalURL, err = h.resolveStremioURL(r.Context(), cached.StreamURL)
+		if err != nil {
+			log.Printf("[PLAY-CACHE] ⚠️ Cached stream for series %d S%02dE%02d failed to resolve: %v", seriesID, seasonNum, episodeNum, err)
+			// Let the stream checker look for a replacement on its next run
+			if err := h.streamCache.MarkEpisodeUnavailable(r.Context(), int(seriesID), seasonNum, episodeNum); err != nil {
+				log.Printf("[PLAY-CACHE] Failed to mark cached stream unavailable: %v", err)
+			}
+			return false
+		}
+	}
+	
+	log.Printf("[PLAY-CACHE] ⚡ S%02dE%02d served from stream cache (quality: %d, checked: %v ago)",
+		seasonNum, episodeNum, cached.QualityScore, time.Since(cached.LastChecked).Round(time.Minute))
+	redirectToStream(w, r, finalURL)
+	return true
+}
+
+// seriesIDForIMDB returns the library ID of a series, or 0 if it is not in the library
+func (h *XtreamHandler) seriesIDForIMDB(imdbID string) int64 {
+	var id int64
+	query := `
+		SELECT id FROM library_series
+		WHERE imdb_id = $1 OR metadata->>'imdb_id' = $1 OR metadata->'external_ids'->>'imdb_id' = $1
+		LIMIT 1
+	`
+	if err := h.db.QueryRow(query, imdbID).Scan(&id); err != nil {
+		return 0
+	}
+	return id
+}
+
+// cacheEpisodeStream stores a debrid-cached provider stream so the next play of the episode starts instantly.
+// Runs in the background. Streams without an infohash (local, usenet) cannot be re-checked and are skipped,
+// as are season packs, whose URL covers the whole pack.
+func (h *XtreamHandler) cacheEpisodeStream(seriesID int64, seasonNum, episodeNum int, stream *providers.TorrentioStream) {
+	if h.streamCache == nil || seriesID == 0 || !stream.Cached || stream.URL == "" || stream.InfoHash == "" {
+		return
+	}
+	if streams.IsSeasonPack(stream.Name, stream.Title) {
+		return
+	}
+	
+	quality := streams.ParseQualityFromTorrentName(stream.Title)
+	quality.SizeGB = float64(stream.Size) / (1024 * 1024 * 1024)
+	
+	cached := models.TorrentStream{
+		Hash:         stream.InfoHash,
+		Title:        stream.Name,
+		TorrentName:  stream.Title,
+		Resolution:   quality.Resolution,
+		HDRType:      quality.HDRType,
+		AudioFormat:  quality.AudioFormat,
+		Source:       quality.Source,
+		Codec:        quality.Codec,
+		SizeGB:       quality.SizeGB,
+		QualityScore: streams.CalculateScore(quality).TotalScore,
+		Indexer:      stream.Source,
+	}
+	
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := h.streamCache.CacheEpisodeStream(ctx, int(seriesID), seasonNum, episodeNum, cached, stream.URL); err != nil {
+			log.Printf("[CACHE-WRITE] ❌ Failed to cache stream for series %d S%02dE%02d: %v", seriesID, seasonNum, episodeNum, err)
+		}
+	}()
+}
+
+// SetLocalMediaLibrary enables serving matched files from local disk
+func (h *XtreamHandler) SetLocalMediaLibrary(library *localmedia.Library) {
+	h.localMedia = library
+}
+
+// playLocal serves the largest local file for a movie (nil season/episode) or episode.
+// Returns false if the item has no local file.
+func (h *XtreamHandler) playLocal(w http.ResponseWriter, r *http.Request, imdbID string, seasonNum, episodeNum *int) bool {
+	if h.localMedia == nil {
+		return false
+	}
+	
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	
+	var files []*database.LocalMediaFile
+	var err error
+	if seasonNum != nil && episodeNum != nil {
+		files, err = h.localMedia.EpisodeFiles(ctx, imdbID, *seasonNum, *episodeNum)
+	} else {
+		files, err = h.localMedia.MovieFiles(ctx, imdbID)
+	}
+	if err != nil || len(files) == 0 {
 		return false
 	}
 	
-	// Get stored Xtream password
-	err = h.db.QueryRow("SELECT value FROM settings WHERE key = 'xtream_password'").Scan(&storedPassword)
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error querying xtream_password: %v", err)
+	log.Printf("[PLAY-LOCAL] ✓ Serving local file: %s", files[0].Path)
+	h.localMedia.Serve(w, r, files[0])
+	return true
+}
+
+// handleLocalPlay serves a local file by its signed stream path (used by Stremio streams)
+func (h *XtreamHandler) handleLocalPlay(w http.ResponseWriter, r *http.Request) {
+	if h.localMedia == nil {
+		http.NotFound(w, r)
+		return
+	}
+	
+	vars := mux.Vars(r)
+	file, err := h.localMedia.Open(r.Context(), vars["id"], vars["sig"])
+	if err != nil {
+		log.Printf("[PLAY-LOCAL] ❌ Rejected local file request %s: %v", vars["id"], err)
+		http.NotFound(w, r)
+		return
+	}
+	
+	h.localMedia.Serve(w, r, file)
+}
+
+// SetUsenetProviders enables playback of usenet stream paths
+func (h *XtreamHandler) SetUsenetProviders(usenetProviders ...*providers.UsenetProvider) {
+	h.usenetProviders = make(map[string]*providers.UsenetProvider, len(usenetProviders))
+	for _, p := range usenetProviders {
+		h.usenetProviders[p.Slug()] = p
+	}
+}
+
+// handleUsenetPlay resolves a usenet stream token and redirects to the service's stream
+func (h *XtreamHandler) handleUsenetPlay(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	provider, ok := h.usenetProviders[vars["service"]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	
+	resolve := func(ctx context.Context) (string, error) {
+		return provider.Resolve(ctx, vars["token"])
+	}
+	var streamURL string
+	var err error
+	if h.links != nil {
+		streamURL, err = h.links.Resolve(ctx, cache.URLKey(vars["token"]), "usenet:"+vars["service"], resolve)
+	} else {
+		streamURL, err = resolve(ctx)
+	}
+	if err != nil {
+		log.Printf("[PLAY-USENET] ❌ %s: %v", vars["service"], err)
+		http.Error(w, "Stream not available", http.StatusNotFound)
+		return
+	}
+	
+	log.Printf("[PLAY-USENET] ✓ Redirecting to %s stream", vars["service"])
+	http.Redirect(w, r, streamURL, http.StatusFound)
+}
+
+// ValidateXtreamCredentials checks if the provided username/password match the configured Xtream API credentials.
+// Clients that keep sending wrong credentials are locked out like web sign-ins; valid credentials aren't
+// rate limited since IPTV apps re-authenticate constantly. Every player shares the one Xtream account,
+// so the lockout is per client address: bad guesses from one address don't lock out the others.
+func (h *XtreamHandler) ValidateXtreamCredentials(r *http.Request, username, password string) bool {
+	ip := auth.ClientIP(r)
+	account := "xtream:" + username + "@" + ip
+	if wait := h.loginGuard.Locked(ip, account); wait > 0 {
+		log.Printf("[XTREAM] 🚫 Rejected credentials from %s: locked out for %v", ip, wait.Round(time.Second))
 		return false
 	}
+
+	// The password is encrypted in the database, so it comes from the decrypted settings
+	var storedUsername, storedPassword string
+	if h.getCredentials != nil {
+		storedUsername, storedPassword = h.getCredentials()
+	}
 	
 	// If no credentials are set, use default "streamarr"/"streamarr"
 	if storedUsername == "" {
@@ -310,7 +770,24 @@ func (h *XtreamHandler) ValidateXtreamCredentials(username, password string) boo
 	}
 	
 	// Check if provided credentials match
-	return username == storedUsername && password == storedPassword
+	usernameOK := subtle.ConstantTimeCompare([]byte(username), []byte(storedUsername)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(storedPassword)) == 1
+	if usernameOK && passwordOK {
+		h.loginGuard.Success(account)
+		return true
+	}
+
+	h.loginGuard.Failure(ip, account)
+	if h.authService != nil {
+		h.authService.RecordLogin(context.WithoutCancel(r.Context()), &database.LoginAttempt{
+			Username:      username,
+			Source:        database.LoginSourceXtream,
+			IP:            ip,
+			UserAgent:     r.UserAgent(),
+			FailureReason: database.LoginBadPassword,
+		})
+	}
+	return false
 }
 
 // loadEpisodeCache loads the episode cache from disk
@@ -439,6 +916,10 @@ func (h *XtreamHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/series/{username}/{password}/{id}.{ext}", h.handleSeriesPlay).Methods("GET", "HEAD")
 	r.HandleFunc("/live/{username}/{password}/{id}.{ext}", h.handleLivePlay).Methods("GET", "HEAD")
 	
+	// Local media files (signed path, must be registered before the direct VOD format)
+	r.HandleFunc("/local/{id:[0-9]+}/{sig:[0-9a-f]+}.{ext}", h.handleLocalPlay).Methods("GET", "HEAD")
+	r.HandleFunc("/usenet/{service}/{token:[0-9a-f]+}.{ext}", h.handleUsenetPlay).Methods("GET", "HEAD")
+	
 	// Direct VOD format (some apps use this without /movie/ prefix)
 	r.HandleFunc("/{username}/{password}/{id}.{ext}", h.handleDirectPlay).Methods("GET", "HEAD")
 }
@@ -478,7 +959,7 @@ func (h *XtreamHandler) getServerInfo(w http.ResponseWriter, r *http.Request) {
 	password := r.URL.Query().Get("password")
 	
 	// Validate credentials
-	if !h.ValidateXtreamCredentials(username, password) {
+	if !h.ValidateXtreamCredentials(r, username, password) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"user_info": map[string]interface{}{
@@ -1018,6 +1499,7 @@ func (h *XtreamHandler) getVODInfo(w http.ResponseWriter, r *http.Request) {
 			"bitrate":         0,
 			"backdrop_path":   []string{backdropPath},
 			"cover":           posterPath,
+			"subtitles":       h.vodSubtitles(r, imdbIDStr),
 		},
 		"movie_data": map[string]interface{}{
 			"stream_id":           tmdbID,
@@ -1921,9 +2403,28 @@ func (h *XtreamHandler) playEpisodeByIMDB(w http.ResponseWriter, r *http.Request
 	log.Printf("[PLAY] Episode request: IMDB %s S%02dE%02d from IP %s", imdbID, seasonNum, episodeNum, r.RemoteAddr)
 	startTime := time.Now()
 	
+	seriesID := h.seriesIDForIMDB(imdbID)
+	h.recordPlayback(r, "series", seriesID, fmt.Sprintf("%s S%02dE%02d", imdbID, seasonNum, episodeNum))
+	
+	if h.playLocal(w, r, imdbID, &seasonNum, &episodeNum) {
+		return
+	}
+	
+	// Once this episode is served, get the next one ready
+	defer h.prefetchNextEpisode(imdbID, seriesID, seasonNum, episodeNum)
+	
+	if h.playCachedEpisode(w, r, seriesID, seasonNum, episodeNum) {
+		return
+	}
+	
+	// A season pack mapped for an earlier episode avoids re-querying providers
+	if h.playFromSeasonPack(w, r, imdbID, seasonNum, episodeNum, nil) {
+		return
+	}
+	
 	// Get stream from providers
 	log.Printf("[PLAY] Fetching streams for %s S%02dE%02d...", imdbID, seasonNum, episodeNum)
-	stream, err := h.multiProvider.GetBestStream(imdbID, &seasonNum, &episodeNum, h.cfg.MaxResolution)
+	stream, err := h.multiProvider.GetBestStream(r.Context(), imdbID, &seasonNum, &episodeNum, h.cfg.MaxResolution)
 	elapsed := time.Since(startTime)
 	
 	if err != nil {
@@ -1948,6 +2449,12 @@ func (h *XtreamHandler) playEpisodeByIMDB(w http.ResponseWriter, r *http.Request
 		}
 	}
 	
+	if h.playFromSeasonPack(w, r, imdbID, seasonNum, episodeNum, stream) {
+		return
+	}
+	
+	h.cacheEpisodeStream(seriesID, seasonNum, episodeNum, stream)
+	
 	// DISABLED: RD direct API unreliable - Torrentio handles RD internally via resolve URL
 	// if stream.InfoHash != "" && h.rdClient != nil {
 	// 	log.Printf("[PLAY-RD] Attempting to get cached stream from Real-Debrid: %s", stream.InfoHash)
@@ -1964,9 +2471,14 @@ func (h *XtreamHandler) playEpisodeByIMDB(w http.ResponseWriter, r *http.Request
 	// 	log.Printf("[PLAY-RD] ⚠️ Failed to get RD stream URL: %v", err)
 	// }
 	
+	if stream.URL == "" && stream.InfoHash != "" {
+		h.playInfoHash(w, r, stream.InfoHash, seasonNum, episodeNum, startTime)
+		return
+	}
+	
 	// Fallback: Resolve stream URL if needed (Stremio addon URLs)
 	if stream.URL != "" {
-		finalURL, err := h.resolveStremioURL(stream.URL)
+		finalURL, err := h.resolveStremioURL(r.Context(), stream.URL)
 		if err != nil {
 			log.Printf("[PLAY] ❌ Failed to resolve stream URL after %.2fs: %v", time.Since(startTime).Seconds(), err)
 			http.Error(w, fmt.Sprintf("Stream resolution failed: %v", err), http.StatusBadGateway)
@@ -1975,7 +2487,7 @@ func (h *XtreamHandler) playEpisodeByIMDB(w http.ResponseWriter, r *http.Request
 		
 		elapsed = time.Since(startTime)
 		log.Printf("[PLAY] ✓ Redirecting to addon stream (%.2fs): %s", elapsed.Seconds(), finalURL)
-		http.Redirect(w, r, finalURL, http.StatusFound)
+		redirectToStream(w, r, finalURL)
 	} else {
 		log.Printf("[PLAY] ❌ No stream URL or infohash available after %.2fs", elapsed.Seconds())
 		http.Error(w, "Stream URL not available", http.StatusNotFound)
@@ -2008,6 +2520,7 @@ func (h *XtreamHandler) handleLivePlay(w http.ResponseWriter, r *http.Request) {
 	
 	channel := channels[id-1]
 	if channel.StreamURL != "" {
+		h.recordPlayback(r, "channel", int64(id), channel.Name)
 		http.Redirect(w, r, channel.StreamURL, http.StatusFound)
 	} else {
 		http.Error(w, "Stream URL not available", http.StatusNotFound)
@@ -2029,21 +2542,24 @@ func (h *XtreamHandler) playMovie(w http.ResponseWriter, r *http.Request, vodID
 	tmdbID, _ := strconv.ParseInt(vodID, 10, 64)
 	
 	// Get IMDB ID from database by TMDB ID first - try both imdb_id column and metadata
+	var dbID int64
+	var title string
 	var imdbID sql.NullString
 	var metadataJSON []byte
 	
-	query := `SELECT imdb_id, metadata FROM library_movies WHERE tmdb_id = $1`
-	err := h.db.QueryRow(query, tmdbID).Scan(&imdbID, &metadataJSON)
+	query := `SELECT id, title, imdb_id, metadata FROM library_movies WHERE tmdb_id = $1`
+	err := h.db.QueryRow(query, tmdbID).Scan(&dbID, &title, &imdbID, &metadataJSON)
 	if err != nil {
 		// Try by database ID as fallback
-		query = `SELECT imdb_id, metadata FROM library_movies WHERE id = $1`
-		err = h.db.QueryRow(query, tmdbID).Scan(&imdbID, &metadataJSON)
+		query = `SELECT id, title, imdb_id, metadata FROM library_movies WHERE id = $1`
+		err = h.db.QueryRow(query, tmdbID).Scan(&dbID, &title, &imdbID, &metadataJSON)
 		if err != nil {
 			log.Printf("[PLAY] ❌ Movie not found in database: %s", vodID)
 			http.Error(w, "Movie not found", http.StatusNotFound)
 			return
 		}
 	}
+	h.recordPlayback(r, "movie", dbID, title)
 	
 	// If imdb_id column is empty, try to get from metadata JSON
 	if !imdbID.Valid || imdbID.String == "" {
@@ -2062,10 +2578,14 @@ func (h *XtreamHandler) playMovie(w http.ResponseWriter, r *http.Request, vodID
 		return
 	}
 	
+	if h.playLocal(w, r, imdbID.String, nil, nil) {
+		return
+	}
+	
 	log.Printf("[PLAY] Fetching streams for movie TMDB %d, IMDB %s...", tmdbID, imdbID.String)
 	
 	// Get stream from providers
-	stream, err := h.multiProvider.GetBestStream(imdbID.String, nil, nil, h.cfg.MaxResolution)
+	stream, err := h.multiProvider.GetBestStream(r.Context(), imdbID.String, nil, nil, h.cfg.MaxResolution)
 	elapsed := time.Since(startTime)
 	
 	if err != nil {
@@ -2074,9 +2594,14 @@ func (h *XtreamHandler) playMovie(w http.ResponseWriter, r *http.Request, vodID
 		return
 	}
 	
+	if stream.URL == "" && stream.InfoHash != "" {
+		h.playInfoHash(w, r, stream.InfoHash, 0, 0, startTime)
+		return
+	}
+	
 	// Resolve stream URL from addon (Torrentio has built-in RD support)
 	if stream.URL != "" {
-		finalURL, err := h.resolveStremioURL(stream.URL)
+		finalURL, err := h.resolveStremioURL(r.Context(), stream.URL)
 		if err != nil {
 			log.Printf("[PLAY] ❌ Failed to resolve stream URL after %.2fs: %v", time.Since(startTime).Seconds(), err)
 			http.Error(w, fmt.Sprintf("Stream resolution failed: %v", err), http.StatusBadGateway)
@@ -2085,7 +2610,7 @@ func (h *XtreamHandler) playMovie(w http.ResponseWriter, r *http.Request, vodID
 		
 		elapsed = time.Since(startTime)
 		log.Printf("[PLAY] ✓ Redirecting to addon stream (%.2fs): %s", elapsed.Seconds(), finalURL)
-		http.Redirect(w, r, finalURL, http.StatusFound)
+		redirectToStream(w, r, finalURL)
 	} else {
 		log.Printf("[PLAY] ❌ No stream URL or infohash available after %.2fs", elapsed.Seconds())
 		http.Error(w, "Stream URL not available", http.StatusNotFound)
@@ -2098,15 +2623,16 @@ func (h *XtreamHandler) playEpisode(w http.ResponseWriter, r *http.Request, seri
 	episodeNum, _ := strconv.Atoi(episodeStr)
 	
 	// Get IMDB ID from database by TMDB ID first - try both imdb_id column and metadata
+	var dbID int64
 	var imdbID sql.NullString
 	var metadataJSON []byte
 	
-	query := `SELECT imdb_id, metadata FROM library_series WHERE tmdb_id = $1`
-	err := h.db.QueryRow(query, tmdbID).Scan(&imdbID, &metadataJSON)
+	query := `SELECT id, imdb_id, metadata FROM library_series WHERE tmdb_id = $1`
+	err := h.db.QueryRow(query, tmdbID).Scan(&dbID, &imdbID, &metadataJSON)
 	if err != nil {
 		// Try by database ID as fallback
-		query = `SELECT imdb_id, metadata FROM library_series WHERE id = $1`
-		err = h.db.QueryRow(query, tmdbID).Scan(&imdbID, &metadataJSON)
+		query = `SELECT id, imdb_id, metadata FROM library_series WHERE id = $1`
+		err = h.db.QueryRow(query, tmdbID).Scan(&dbID, &imdbID, &metadataJSON)
 		if err != nil {
 			http.Error(w, "Series not found", http.StatusNotFound)
 			return
@@ -2140,23 +2666,144 @@ func (h *XtreamHandler) playEpisode(w http.ResponseWriter, r *http.Request, seri
 	
 	log.Printf("Playing series TMDB ID %d, IMDB ID %s, S%02dE%02d", tmdbID, imdbID.String, seasonNum, episodeNum)
 	
+	if h.playLocal(w, r, imdbID.String, &seasonNum, &episodeNum) {
+		return
+	}
+	
+	// Once this episode is served, get the next one ready
+	defer h.prefetchNextEpisode(imdbID.String, dbID, seasonNum, episodeNum)
+	
+	if h.playCachedEpisode(w, r, dbID, seasonNum, episodeNum) {
+		return
+	}
+	
+	if h.playFromSeasonPack(w, r, imdbID.String, seasonNum, episodeNum, nil) {
+		return
+	}
+	
 	// Get stream from providers
-	stream, err := h.multiProvider.GetBestStream(imdbID.String, &seasonNum, &episodeNum, h.cfg.MaxResolution)
+	stream, err := h.multiProvider.GetBestStream(r.Context(), imdbID.String, &seasonNum, &episodeNum, h.cfg.MaxResolution)
 	if err != nil {
 		log.Printf("Error getting stream: %v", err)
 		http.Error(w, "Stream not available", http.StatusNotFound)
 		return
 	}
 	
+	if h.playFromSeasonPack(w, r, imdbID.String, seasonNum, episodeNum, stream) {
+		return
+	}
+	
+	h.cacheEpisodeStream(dbID, seasonNum, episodeNum, stream)
+	
 	// Redirect to stream URL
 	if stream.URL != "" {
-		log.Printf("Redirecting to episode stream: %s", stream.URL)
-		http.Redirect(w, r, stream.URL, http.StatusFound)
+		finalURL, err := h.resolveStremioURL(r.Context(), stream.URL)
+		if err != nil {
+			log.Printf("Error resolving stream URL: %v", err)
+			http.Error(w, fmt.Sprintf("Stream resolution failed: %v", err), http.StatusBadGateway)
+			return
+		}
+		log.Printf("Redirecting to episode stream: %s", finalURL)
+		redirectToStream(w, r, finalURL)
 	} else {
 		http.Error(w, "Stream URL not available", http.StatusNotFound)
 	}
 }
 
+// SetPlaylistStore serves get.php from playlists written by the playlist worker
+func (h *XtreamHandler) SetPlaylistStore(store *playlist.ArtifactStore) {
+	h.playlists = store
+}
+
+// playlistFilters reads the "only cached streams" and "only released content" settings
+func (h *XtreamHandler) playlistFilters() (onlyIncludeCached, onlyReleasedContent bool) {
+	if h.getSettings == nil {
+		return false, false
+	}
+	if settings := h.getSettings(); settings != nil {
+		if settingsMap, ok := settings.(map[string]interface{}); ok {
+			// Check for the setting - it might be named different variations
+			if oc, ok := settingsMap["only_cached_streams"].(bool); ok {
+				onlyIncludeCached = oc
+			} else if oc, ok := settingsMap["only_include_cached_streams"].(bool); ok {
+				onlyIncludeCached = oc
+			}
+			if orc, ok := settingsMap["only_released_content"].(bool); ok {
+				onlyReleasedContent = orc
+			}
+		}
+	}
+	return onlyIncludeCached, onlyReleasedContent
+}
+
+// servePlaylistArtifact writes a rendered variant of the generated playlist, honouring
+// If-None-Match and gzip. Returns false if no playlist has been generated yet.
+func (h *XtreamHandler) servePlaylistArtifact(w http.ResponseWriter, r *http.Request, variant playlist.Variant) bool {
+	if h.playlists == nil {
+		return false
+	}
+	current := h.playlists.Current()
+	if current == nil {
+		return false
+	}
+	rendered, ok := h.playlists.Render(variant)
+	if !ok {
+		return false
+	}
+	h.seedEpisodeLookups(current)
+	
+	w.Header().Set("ETag", rendered.ETag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Vary", "Accept-Encoding")
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, rendered.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	
+	w.Header().Set("Content-Type", rendered.ContentType)
+	if variant.Format != "json" {
+		w.Header().Set("Content-Disposition", "attachment; filename=\"playlist.m3u\"")
+	}
+	body := rendered.Body
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		body = rendered.Gzip
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+	
+	log.Printf("[XTREAM] get.php: served playlist %s (%s, %d bytes)", current.Version, variant.Format, len(body))
+	return true
+}
+
+// seedEpisodeLookups registers the playlist's episode IDs so episode plays skip the database lookup
+func (h *XtreamHandler) seedEpisodeLookups(a *playlist.Artifact) {
+	h.episodeMu.Lock()
+	defer h.episodeMu.Unlock()
+	
+	if h.seededPlaylist == a.Version {
+		return
+	}
+	for _, s := range a.Series {
+		if s.IMDBID == "" {
+			continue
+		}
+		for _, e := range s.Episodes {
+			key := fmt.Sprintf("%d", e.ID)
+			if _, ok := h.episodeCache[key]; ok {
+				continue
+			}
+			h.episodeCache[key] = EpisodeLookup{
+				SeriesID: fmt.Sprintf("%d", s.TMDBID),
+				Season:   e.Season,
+				Episode:  e.Episode,
+				IMDBID:   s.IMDBID,
+			}
+		}
+	}
+	h.seededPlaylist = a.Version
+}
+
 func (h *XtreamHandler) handleGetPlaylist(w http.ResponseWriter, r *http.Request) {
 	outputType := r.URL.Query().Get("type")
 	if outputType == "" {
@@ -2179,32 +2826,31 @@ func (h *XtreamHandler) handleGetPlaylist(w http.ResponseWriter, r *http.Request
 		password = "pass"
 	}
 
+	onlyIncludeCached, onlyReleasedContent := h.playlistFilters()
+	
+	// Serve the pre-generated playlist when the worker has written one
+	var content []string
+	if c := r.URL.Query().Get("content"); c != "" {
+		content = strings.Split(c, ",")
+	}
+	variant := playlist.Variant{
+		ServerURL:    serverURL,
+		Username:     username,
+		Password:     password,
+		Format:       outputType,
+		Content:      content,
+		OnlyCached:   onlyIncludeCached,
+		OnlyReleased: onlyReleasedContent,
+	}
+	if h.servePlaylistArtifact(w, r, variant) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "audio/x-mpegurl")
 	w.Header().Set("Content-Disposition", "attachment; filename=\"playlist.m3u\"")
 
 	fmt.Fprintf(w, "#EXTM3U\n")
 
-	// Check if "Only Include Media with Cached Streams" setting is enabled
-	var onlyIncludeCached bool
-	var onlyReleasedContent bool
-	log.Printf("[XTREAM] handleGetPlaylist: Checking 'Only Include Media with Cached Streams' setting")
-	if h.getSettings != nil {
-		if settings := h.getSettings(); settings != nil {
-			if settingsMap, ok := settings.(map[string]interface{}); ok {
-				// Check for the setting - it might be named different variations
-				if oc, ok := settingsMap["only_cached_streams"].(bool); ok {
-					onlyIncludeCached = oc
-				} else if oc, ok := settingsMap["only_include_cached_streams"].(bool); ok {
-					onlyIncludeCached = oc
-				}
-				if orc, ok := settingsMap["only_released_content"].(bool); ok {
-					onlyReleasedContent = orc
-				}
-				log.Printf("[XTREAM] handleGetPlaylist: only_cached_streams=%v, only_released_content=%v", onlyIncludeCached, onlyReleasedContent)
-			}
-		}
-	}
-
 	// Add VOD streams (movies) based on cache setting
 	if onlyIncludeCached {
 		// ONLY show cached streams from Stream Cache Monitor
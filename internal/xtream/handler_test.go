@@ -0,0 +1,37 @@
+package xtream
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zerr0-C00L/StreamArr/internal/auth"
+)
+
+func TestXtreamLockoutIsPerClient(t *testing.T) {
+	h := &XtreamHandler{
+		loginGuard:     auth.NewLoginGuard(),
+		getCredentials: func() (string, string) { return "family", "s3cret" },
+	}
+	request := func(ip string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/player_api.php", nil)
+		r.RemoteAddr = ip + ":40000"
+		return r
+	}
+
+	// Someone guessing from one address locks only themselves out
+	for i := 0; i < 20; i++ {
+		h.ValidateXtreamCredentials(request("203.0.113.7"), "family", "guess")
+	}
+	if h.ValidateXtreamCredentials(request("203.0.113.7"), "family", "s3cret") {
+		t.Error("the guessing client isn't locked out")
+	}
+	if !h.ValidateXtreamCredentials(request("192.168.1.20"), "family", "s3cret") {
+		t.Error("another client is locked out of the shared account")
+	}
+
+	if h.ValidateXtreamCredentials(request("192.168.1.20"), "family", "s3cre") ||
+		h.ValidateXtreamCredentials(request("192.168.1.20"), "Family", "s3cret") {
+		t.Error("wrong credentials accepted")
+	}
+}
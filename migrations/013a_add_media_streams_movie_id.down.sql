@@ -0,0 +1,4 @@
+-- Migration: 013a_add_media_streams_movie_id.down.sql
+-- Rollback: drop the movie_id column
+
+ALTER TABLE media_streams DROP COLUMN IF EXISTS movie_id;
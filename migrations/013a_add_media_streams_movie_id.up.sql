@@ -0,0 +1,23 @@
+-- Migration: 013a_add_media_streams_movie_id.up.sql
+-- Add the movie_id column 014 indexes and constrains; 013 created media_streams without it.
+-- Sorts between 013 and 014 so fresh databases get past 014.
+
+ALTER TABLE media_streams ADD COLUMN IF NOT EXISTS movie_id BIGINT REFERENCES library_movies(id) ON DELETE CASCADE;
+
+DO $$
+BEGIN
+    -- Databases stuck before 014 still hold rows keyed by (media_type, media_id) only. Movies are
+    -- carried over; the rest would fail 014's check constraint and are dropped, the next cache scan finds them again.
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'media_streams' AND column_name = 'series_id'
+    ) THEN
+        UPDATE media_streams
+        SET movie_id = media_id
+        WHERE movie_id IS NULL
+          AND media_type = 'movie'
+          AND media_id IN (SELECT id FROM library_movies);
+
+        DELETE FROM media_streams WHERE movie_id IS NULL;
+    END IF;
+END $$;
@@ -0,0 +1,4 @@
+-- Migration: 016_add_local_media.down.sql
+-- Rollback local media files table
+
+DROP TABLE IF EXISTS local_media_files;
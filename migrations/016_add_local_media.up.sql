@@ -0,0 +1,26 @@
+-- Migration: 016_add_local_media.up.sql
+-- Local media files found on disk and matched to library movies/episodes
+
+CREATE TABLE IF NOT EXISTS local_media_files (
+    id              BIGSERIAL PRIMARY KEY,
+    path            TEXT NOT NULL UNIQUE,
+    media_type      VARCHAR(10) NOT NULL CHECK (media_type IN ('movie', 'series')),
+    movie_id        BIGINT REFERENCES library_movies(id) ON DELETE CASCADE,
+    series_id       BIGINT REFERENCES library_series(id) ON DELETE CASCADE,
+    season_number   INTEGER,
+    episode_number  INTEGER,
+    size_bytes      BIGINT NOT NULL DEFAULT 0,
+    container       VARCHAR(10),        -- mkv, mp4, avi
+    resolution      VARCHAR(20),        -- 2160p, 1080p, 720p
+    codec           VARCHAR(20),        -- x265, x264, AV1
+    source_type     VARCHAR(20),        -- REMUX, BluRay, WEB-DL
+    hdr_type        VARCHAR(20),        -- DV, HDR10, HDR
+    audio_format    VARCHAR(50),        -- Atmos, TrueHD, DTS-HD MA
+    modified_at     TIMESTAMPTZ,
+    scanned_at      TIMESTAMPTZ DEFAULT NOW(),
+    created_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Lookups on playback
+CREATE INDEX IF NOT EXISTS idx_local_media_movie ON local_media_files (movie_id) WHERE movie_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_local_media_episode ON local_media_files (series_id, season_number, episode_number) WHERE series_id IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Migration: 017_fix_media_streams_keys.down.sql
+-- Rollback to the (media_type, media_id) key being required. movie_id and the unique indexes belong to 013a and 014.
+-- Episode rows can't be stored under the original key, so they are removed
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'media_streams' AND column_name = 'media_id'
+    ) THEN
+        -- Movie rows written by the stream cache only have movie_id
+        UPDATE media_streams
+        SET media_type = 'movie', media_id = movie_id
+        WHERE media_id IS NULL
+          AND movie_id IS NOT NULL;
+
+        DELETE FROM media_streams WHERE media_type IS NULL OR media_id IS NULL;
+
+        ALTER TABLE media_streams ALTER COLUMN media_type SET NOT NULL;
+        ALTER TABLE media_streams ALTER COLUMN media_id SET NOT NULL;
+    END IF;
+END $$;
@@ -0,0 +1,28 @@
+-- Migration: 017_fix_media_streams_keys.up.sql
+-- Reconcile media_streams with the movie_id / (series_id, season, episode) keys used by the stream cache
+-- 013 created the table keyed by (media_type, media_id); rows are now keyed by movie_id or series_id
+
+ALTER TABLE media_streams ADD COLUMN IF NOT EXISTS movie_id BIGINT REFERENCES library_movies(id) ON DELETE CASCADE;
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'media_streams' AND column_name = 'media_id'
+    ) THEN
+        -- Carry over movie rows written against the original key
+        UPDATE media_streams
+        SET movie_id = media_id
+        WHERE movie_id IS NULL
+          AND series_id IS NULL
+          AND media_type = 'movie'
+          AND media_id IN (SELECT id FROM library_movies);
+
+        -- Episode rows have no media_id, so the original key can no longer be required
+        ALTER TABLE media_streams ALTER COLUMN media_type DROP NOT NULL;
+        ALTER TABLE media_streams ALTER COLUMN media_id DROP NOT NULL;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_movie_stream_idx ON media_streams (movie_id) WHERE movie_id IS NOT NULL AND series_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_episode_stream_idx ON media_streams (series_id, season, episode) WHERE series_id IS NOT NULL;
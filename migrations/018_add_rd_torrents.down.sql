@@ -0,0 +1,4 @@
+-- Migration: 018_add_rd_torrents.down.sql
+-- Rollback Real-Debrid torrent tracking table
+
+DROP TABLE IF EXISTS rd_torrents;
@@ -0,0 +1,18 @@
+-- Migration: 018_add_rd_torrents.up.sql
+-- Torrents StreamArr added to the Real-Debrid account, so they can be reused and garbage-collected
+
+CREATE TABLE IF NOT EXISTS rd_torrents (
+    id              BIGSERIAL PRIMARY KEY,
+    torrent_id      VARCHAR(64) NOT NULL UNIQUE,   -- Real-Debrid torrent ID
+    hash            VARCHAR(64) NOT NULL,          -- Lowercase infohash
+    filename        TEXT,
+    bytes           BIGINT NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at    TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Reuse lookups on playback
+CREATE INDEX IF NOT EXISTS idx_rd_torrents_hash ON rd_torrents (hash);
+
+-- Garbage collection evicts least recently used first
+CREATE INDEX IF NOT EXISTS idx_rd_torrents_last_used ON rd_torrents (last_used_at);
@@ -0,0 +1,5 @@
+-- Migration: 019_add_library_genre_indexes.down.sql
+-- Rollback library genre indexes
+
+DROP INDEX IF EXISTS idx_series_genres;
+DROP INDEX IF EXISTS idx_movies_genres;
@@ -0,0 +1,5 @@
+-- Migration: 019_add_library_genre_indexes.up.sql
+-- Genre filtering for Stremio catalogs matches metadata->'genres' with the jsonb ? operator
+
+CREATE INDEX IF NOT EXISTS idx_movies_genres ON library_movies USING GIN ((metadata->'genres'));
+CREATE INDEX IF NOT EXISTS idx_series_genres ON library_series USING GIN ((metadata->'genres'));
@@ -0,0 +1,6 @@
+-- Migration: 020_add_stremio_user_tokens.down.sql
+-- Rollback per-user Stremio tokens
+
+DROP TABLE IF EXISTS stremio_user_library;
+DROP TABLE IF EXISTS stremio_user_preferences;
+DROP TABLE IF EXISTS stremio_tokens;
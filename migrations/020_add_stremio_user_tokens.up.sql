@@ -0,0 +1,36 @@
+-- Migration: 020_add_stremio_user_tokens.up.sql
+-- Per-user Stremio addon tokens, stream ranking preferences, and the watchlist / continue-watching state
+-- behind each user's personal catalogs (keyed on library IDs, unlike the legacy user_watchlist table)
+
+CREATE TABLE IF NOT EXISTS stremio_tokens (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    token           VARCHAR(64) NOT NULL UNIQUE,
+    name            VARCHAR(255) NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at    TIMESTAMPTZ,
+    revoked_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_stremio_tokens_user ON stremio_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS stremio_user_preferences (
+    user_id         INTEGER PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    preferences     JSONB NOT NULL DEFAULT '{}',
+    updated_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS stremio_user_library (
+    user_id         INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    media_type      VARCHAR(10) NOT NULL,          -- 'movie' or 'series'
+    media_id        BIGINT NOT NULL,               -- library_movies.id or library_series.id
+    in_watchlist    BOOLEAN NOT NULL DEFAULT FALSE,
+    added_at        TIMESTAMPTZ,
+    last_watched_at TIMESTAMPTZ,
+    season          INTEGER NOT NULL DEFAULT 0,    -- Last episode requested (series)
+    episode         INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, media_type, media_id)
+);
+
+-- Continue watching lists by most recent stream request
+CREATE INDEX IF NOT EXISTS idx_stremio_user_library_watched ON stremio_user_library (user_id, last_watched_at DESC);
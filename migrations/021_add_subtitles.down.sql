@@ -0,0 +1,5 @@
+-- Migration: 021_add_subtitles.down.sql
+-- Rollback subtitle support
+
+ALTER TABLE users DROP COLUMN IF EXISTS subtitle_languages;
+DROP TABLE IF EXISTS subtitle_search_cache;
@@ -0,0 +1,13 @@
+-- Migration: 021_add_subtitles.up.sql
+-- Cached OpenSubtitles search results and per-user subtitle languages
+
+CREATE TABLE IF NOT EXISTS subtitle_search_cache (
+    cache_key       VARCHAR(255) PRIMARY KEY,
+    results         JSONB NOT NULL DEFAULT '[]',
+    fetched_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subtitle_search_cache_fetched ON subtitle_search_cache (fetched_at);
+
+-- Comma-separated ISO 639-1 codes in preference order; empty uses the server default
+ALTER TABLE users ADD COLUMN IF NOT EXISTS subtitle_languages VARCHAR(255);
@@ -0,0 +1,5 @@
+-- Migration: 022_add_job_queue.down.sql
+-- Rollback the persistent job queue
+
+DROP TABLE IF EXISTS job_runs;
+DROP TABLE IF EXISTS jobs;
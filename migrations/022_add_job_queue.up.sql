@@ -0,0 +1,40 @@
+-- Migration: 022_add_job_queue.up.sql
+-- Persistent background job queue shared by the server and worker binaries, with run history
+
+CREATE TABLE IF NOT EXISTS jobs (
+    name             VARCHAR(100) PRIMARY KEY,
+    description      TEXT NOT NULL DEFAULT '',
+    schedule         VARCHAR(100) NOT NULL,          -- Cron expression ("0 */6 * * *") or "@every 6h"
+    enabled          BOOLEAN NOT NULL DEFAULT TRUE,  -- Disabled jobs only run when triggered manually
+    next_run_at      TIMESTAMPTZ DEFAULT NOW(),
+    locked_by        VARCHAR(255),                   -- Process holding the lease while the job runs
+    locked_until     TIMESTAMPTZ,                    -- Lease expiry; renewed by the runner's heartbeat
+    current_run_id   BIGINT,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at       TIMESTAMPTZ DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id               BIGSERIAL PRIMARY KEY,
+    job_name         VARCHAR(100) NOT NULL REFERENCES jobs(name) ON DELETE CASCADE,
+    trigger          VARCHAR(20) NOT NULL,           -- schedule, manual
+    worker           VARCHAR(255),
+    status           VARCHAR(20) NOT NULL,           -- queued, running, succeeded, failed, cancelled
+    queued_at        TIMESTAMPTZ DEFAULT NOW(),
+    started_at       TIMESTAMPTZ,
+    finished_at      TIMESTAMPTZ,
+    duration_ms      BIGINT,
+    progress         INTEGER NOT NULL DEFAULT 0,
+    progress_message TEXT NOT NULL DEFAULT '',
+    items_processed  INTEGER NOT NULL DEFAULT 0,
+    items_total      INTEGER NOT NULL DEFAULT 0,
+    error            TEXT,
+    logs             TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job ON job_runs (job_name, id DESC);
+
+-- A job is never queued twice or run twice at once, whichever process asks
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_one_queued ON job_runs (job_name) WHERE status = 'queued';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_one_running ON job_runs (job_name) WHERE status = 'running';
@@ -0,0 +1,5 @@
+-- Migration: 023_add_user_roles.down.sql
+-- Rollback role-based access control
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+UPDATE users SET role = 'user' WHERE role IN ('manager', 'requester', 'viewer');
@@ -0,0 +1,6 @@
+-- Migration: 023_add_user_roles.up.sql
+-- Role-based access control: users get one of the admin, manager, requester or viewer roles
+
+-- Users created before roles existed keep browsing, requesting and playback
+UPDATE users SET role = 'requester' WHERE role IS NULL OR role = 'user';
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'requester';
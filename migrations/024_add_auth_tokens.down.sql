@@ -0,0 +1,7 @@
+-- Migration: 024_add_auth_tokens.down.sql
+-- Rollback signing keys, refresh tokens and API keys
+
+ALTER TABLE users DROP COLUMN IF EXISTS sessions_revoked_at;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS auth_refresh_tokens;
+DROP TABLE IF EXISTS auth_signing_keys;
@@ -0,0 +1,44 @@
+-- Migration: 024_add_auth_tokens.up.sql
+-- Persisted JWT signing keys, server-side refresh tokens, per-user API keys and "sign out all sessions"
+
+CREATE TABLE IF NOT EXISTS auth_signing_keys (
+    id              VARCHAR(32) PRIMARY KEY,       -- JWT "kid" header
+    secret          BYTEA NOT NULL,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    retired_at      TIMESTAMPTZ                    -- No longer signs; still verifies until pruned
+);
+
+CREATE TABLE IF NOT EXISTS auth_refresh_tokens (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    token_hash      VARCHAR(64) NOT NULL UNIQUE,   -- SHA-256 of the token; the token itself is never stored
+    remember_me     BOOLEAN NOT NULL DEFAULT FALSE,
+    user_agent      TEXT NOT NULL DEFAULT '',
+    ip              VARCHAR(64) NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at    TIMESTAMPTZ,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    revoked_at      TIMESTAMPTZ,
+    replaced_by     BIGINT                         -- Set when the token was rotated by a refresh
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_refresh_tokens_user ON auth_refresh_tokens (user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_auth_refresh_tokens_expires ON auth_refresh_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name            VARCHAR(255) NOT NULL,
+    prefix          VARCHAR(16) NOT NULL,          -- Shown in lists so keys can be told apart
+    key_hash        VARCHAR(64) NOT NULL UNIQUE,   -- SHA-256 of the key
+    scopes          TEXT[] NOT NULL DEFAULT '{}',  -- Permissions the key may use, within the owner's role
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at    TIMESTAMPTZ,
+    expires_at      TIMESTAMPTZ,
+    revoked_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id);
+
+-- Access tokens issued before this time are rejected
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMPTZ;
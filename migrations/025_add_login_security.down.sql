@@ -0,0 +1,8 @@
+-- Migration: 025_add_login_security.down.sql
+-- Rollback two-factor authentication and login history
+
+DROP TABLE IF EXISTS login_history;
+DROP TABLE IF EXISTS auth_recovery_codes;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
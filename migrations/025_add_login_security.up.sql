@@ -0,0 +1,33 @@
+-- Migration: 025_add_login_security.up.sql
+-- TOTP two-factor authentication with recovery codes, and a login history for web and Xtream sign-ins
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;                        -- Base32, encrypted with the secrets keyring; set during enrolment
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;                   -- Last accepted time step; codes can't be replayed
+
+CREATE TABLE IF NOT EXISTS auth_recovery_codes (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    code_hash       VARCHAR(64) NOT NULL,          -- SHA-256 of the normalised code
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    used_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_recovery_codes_user ON auth_recovery_codes (user_id) WHERE used_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS login_history (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         INTEGER REFERENCES users(user_id) ON DELETE SET NULL,  -- NULL for unknown usernames and Xtream
+    username        VARCHAR(255) NOT NULL DEFAULT '',
+    source          VARCHAR(16) NOT NULL DEFAULT 'web',                  -- web, xtream
+    ip              VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent      TEXT NOT NULL DEFAULT '',
+    success         BOOLEAN NOT NULL,
+    failure_reason  VARCHAR(32) NOT NULL DEFAULT '',                     -- unknown_user, bad_password, bad_code
+    suspicious      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_history_user ON login_history (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_history_username ON login_history (username, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_history_created ON login_history (created_at);
@@ -0,0 +1,4 @@
+-- Migration: 026_add_sso_identities.down.sql
+-- Rollback single sign-on identities
+
+DROP TABLE IF EXISTS auth_identities;
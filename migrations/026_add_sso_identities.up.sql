@@ -0,0 +1,13 @@
+-- Migration: 026_add_sso_identities.up.sql
+-- Links OpenID Connect accounts to StreamArr users, so a renamed account at the provider stays the same user
+
+CREATE TABLE IF NOT EXISTS auth_identities (
+    issuer          TEXT NOT NULL,                 -- OIDC issuer URL
+    subject         TEXT NOT NULL,                 -- "sub" claim; stable for the account at that issuer
+    user_id         INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_login_at   TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_identities_user ON auth_identities (user_id);
@@ -0,0 +1,7 @@
+-- Migration: 027_add_activity_feed.down.sql
+-- Rollback activity feed user columns
+
+DROP INDEX IF EXISTS idx_activity_content;
+DROP INDEX IF EXISTS idx_activity_user;
+ALTER TABLE activity_log DROP COLUMN IF EXISTS username;
+ALTER TABLE activity_log DROP COLUMN IF EXISTS user_id;
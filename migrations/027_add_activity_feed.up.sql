@@ -0,0 +1,8 @@
+-- Migration: 027_add_activity_feed.up.sql
+-- Records who performed each activity, so the activity feed can be filtered by user
+
+ALTER TABLE activity_log ADD COLUMN IF NOT EXISTS user_id INTEGER;   -- No foreign key: history outlives deleted users
+ALTER TABLE activity_log ADD COLUMN IF NOT EXISTS username TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_activity_user ON activity_log (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_activity_content ON activity_log (content_type, content_id);
@@ -0,0 +1,5 @@
+-- Migration: 028_add_signing_key_purpose.down.sql
+-- Rollback signing key purposes
+
+DELETE FROM auth_signing_keys WHERE purpose <> 'token';
+ALTER TABLE auth_signing_keys DROP COLUMN IF EXISTS purpose;
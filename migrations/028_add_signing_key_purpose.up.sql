@@ -0,0 +1,5 @@
+-- Migration: 028_add_signing_key_purpose.up.sql
+-- Signing keys for purposes other than access tokens, e.g. the key that signs stream and download URLs.
+-- Secrets are encrypted with the settings master key from now on; plain ones are encrypted on startup.
+
+ALTER TABLE auth_signing_keys ADD COLUMN IF NOT EXISTS purpose VARCHAR(16) NOT NULL DEFAULT 'token';
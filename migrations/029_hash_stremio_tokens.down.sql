@@ -0,0 +1,11 @@
+-- Migration: 029_hash_stremio_tokens.down.sql
+-- Rollback hashed Stremio tokens. The tokens can't be recovered from their hashes, so every token is revoked.
+
+ALTER TABLE stremio_tokens ADD COLUMN IF NOT EXISTS token VARCHAR(64);
+UPDATE stremio_tokens SET token = token_hash, revoked_at = COALESCE(revoked_at, NOW());
+ALTER TABLE stremio_tokens ALTER COLUMN token SET NOT NULL;
+ALTER TABLE stremio_tokens ADD CONSTRAINT stremio_tokens_token_key UNIQUE (token);
+
+DROP INDEX IF EXISTS idx_stremio_tokens_hash;
+ALTER TABLE stremio_tokens DROP COLUMN IF EXISTS prefix;
+ALTER TABLE stremio_tokens DROP COLUMN IF EXISTS token_hash;
@@ -0,0 +1,12 @@
+-- Migration: 029_hash_stremio_tokens.up.sql
+-- Per-user Stremio tokens are stored as SHA-256 hashes, like API keys; a token is only shown when it's created.
+-- Installed addons keep working, since their tokens hash to the stored values.
+
+ALTER TABLE stremio_tokens ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64);
+ALTER TABLE stremio_tokens ADD COLUMN IF NOT EXISTS prefix VARCHAR(16) NOT NULL DEFAULT '';   -- Shown in lists so tokens can be told apart
+
+UPDATE stremio_tokens SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'), prefix = left(token, 8);
+
+ALTER TABLE stremio_tokens ALTER COLUMN token_hash SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stremio_tokens_hash ON stremio_tokens (token_hash);
+ALTER TABLE stremio_tokens DROP COLUMN IF EXISTS token;
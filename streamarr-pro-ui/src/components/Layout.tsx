@@ -2,21 +2,13 @@ import { useState, useEffect, type ReactNode } from 'react';
 import { Link, useLocation, Outlet } from 'react-router-dom';
 import { Settings, Home, Compass, Radio, Library, LogOut, Menu, X, User, ChevronDown, RotateCw } from 'lucide-react';
 import axios from 'axios';
+import { logout, withSession } from '../services/session';
 
 const api = axios.create({
   baseURL: '/api/v1',
 });
 
-api.interceptors.request.use(
-  (config) => {
-    const token = localStorage.getItem('auth_token');
-    if (token) {
-      config.headers.Authorization = `Bearer ${token}`;
-    }
-    return config;
-  },
-  (error) => Promise.reject(error)
-);
+withSession(api);
 
 interface LayoutProps {
   children?: ReactNode;
@@ -32,11 +24,9 @@ export default function Layout({ children }: LayoutProps) {
   const [profilePicture, setProfilePicture] = useState<string | null>(null);
   const username = localStorage.getItem('username') || 'User';
 
-  const handleLogout = () => {
-    localStorage.removeItem('auth_token');
-    localStorage.removeItem('username');
-    localStorage.removeItem('is_admin');
+  const handleLogout = async () => {
     localStorage.removeItem('profile_picture');
+    await logout();
     window.location.href = '/login';
   };
 
@@ -47,7 +37,7 @@ export default function Layout({ children }: LayoutProps) {
     
     try {
       setShowUserMenu(false);
-      await axios.post('/api/v1/admin/restart');
+      await api.post('/admin/restart');
       // Show success message
       alert('Server restarting...');
     } catch (error) {
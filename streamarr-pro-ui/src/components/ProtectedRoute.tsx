@@ -1,5 +1,6 @@
 import { Navigate, useLocation } from 'react-router-dom';
 import { useEffect, useState } from 'react';
+import { clearSession, refreshSession, startProxySession } from '../services/session';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || '/api/v1';
 
@@ -19,7 +20,7 @@ export default function ProtectedRoute({ children }: ProtectedRouteProps) {
     const token = localStorage.getItem('auth_token');
     
     if (!token) {
-      setIsAuthenticated(false);
+      setIsAuthenticated(await startProxySession());
       return;
     }
 
@@ -32,16 +33,17 @@ export default function ProtectedRoute({ children }: ProtectedRouteProps) {
 
       if (response.ok) {
         setIsAuthenticated(true);
-      } else {
-        localStorage.removeItem('auth_token');
-        localStorage.removeItem('username');
-        localStorage.removeItem('is_admin');
-        setIsAuthenticated(false);
+        return;
+      }
+      // The access token is short-lived; renew it with the refresh token before giving up
+      if (await refreshSession()) {
+        setIsAuthenticated(true);
+        return;
       }
+      clearSession();
+      setIsAuthenticated(await startProxySession());
     } catch {
-      localStorage.removeItem('auth_token');
-      localStorage.removeItem('username');
-      localStorage.removeItem('is_admin');
+      clearSession();
       setIsAuthenticated(false);
     }
   };
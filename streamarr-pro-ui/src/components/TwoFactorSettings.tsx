@@ -0,0 +1,216 @@
+import { useState, useEffect } from 'react';
+import { Shield, KeyRound, AlertCircle, CheckCircle } from 'lucide-react';
+import { streamarrApi } from '../services/api';
+
+interface LoginAttempt {
+  id: number;
+  source: string;
+  ip: string;
+  user_agent: string;
+  success: boolean;
+  failure_reason?: string;
+  suspicious: boolean;
+  created_at: string;
+}
+
+const inputClass = 'w-full px-3 py-2 bg-[#2a2a2a] border border-white/10 rounded-lg text-white focus:outline-none focus:border-blue-500';
+
+// Two-factor enrolment, recovery codes and recent sign-ins for the signed-in user
+export default function TwoFactorSettings() {
+  const [enabled, setEnabled] = useState(false);
+  const [codesLeft, setCodesLeft] = useState(0);
+  const [setup, setSetup] = useState<{ secret: string; otpauth_url: string } | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [code, setCode] = useState('');
+  const [password, setPassword] = useState('');
+  const [history, setHistory] = useState<LoginAttempt[]>([]);
+  const [message, setMessage] = useState('');
+
+  useEffect(() => {
+    load();
+  }, []);
+
+  const load = async () => {
+    try {
+      const [status, logins] = await Promise.all([
+        streamarrApi.getTwoFactorStatus(),
+        streamarrApi.getLoginHistory(10),
+      ]);
+      setEnabled(status.data.enabled);
+      setCodesLeft(status.data.recovery_codes_left);
+      setHistory(logins.data);
+    } catch {
+      // Older servers without two-factor support
+    }
+  };
+
+  const flash = (text: string) => {
+    setMessage(text);
+    setTimeout(() => setMessage(''), 4000);
+  };
+
+  const run = async (action: () => Promise<void>, failure: string) => {
+    try {
+      await action();
+    } catch (error: any) {
+      flash(`❌ ${error.response?.data?.error || failure}`);
+    }
+  };
+
+  const startSetup = () => run(async () => {
+    const response = await streamarrApi.setupTwoFactor();
+    setSetup(response.data);
+    setRecoveryCodes([]);
+    setCode('');
+  }, 'Failed to start setup');
+
+  const confirmSetup = () => run(async () => {
+    const response = await streamarrApi.enableTwoFactor(code.trim());
+    setRecoveryCodes(response.data.recovery_codes);
+    setSetup(null);
+    setCode('');
+    flash('✅ Two-factor authentication enabled');
+    await load();
+  }, 'Invalid code');
+
+  const disable = () => run(async () => {
+    await streamarrApi.disableTwoFactor(password, code.trim());
+    setPassword('');
+    setCode('');
+    setRecoveryCodes([]);
+    flash('✅ Two-factor authentication disabled');
+    await load();
+  }, 'Failed to disable two-factor authentication');
+
+  const regenerate = () => run(async () => {
+    const response = await streamarrApi.regenerateRecoveryCodes(password, code.trim());
+    setRecoveryCodes(response.data.recovery_codes);
+    setPassword('');
+    setCode('');
+    await load();
+  }, 'Failed to generate recovery codes');
+
+  return (
+    <div className="pt-6 border-t border-white/10">
+      <h3 className="text-md font-medium text-slate-300 mb-4 flex items-center gap-2">
+        <Shield className="w-4 h-4" />
+        Two-Factor Authentication
+      </h3>
+      <div className="max-w-md space-y-4">
+        {message && <div className="text-sm text-slate-300">{message}</div>}
+
+        <div className="flex items-center gap-2 text-sm">
+          {enabled ? (
+            <>
+              <CheckCircle className="w-4 h-4 text-green-400" />
+              <span className="text-green-400">Enabled</span>
+              <span className="text-slate-500">· {codesLeft} recovery codes left</span>
+            </>
+          ) : (
+            <>
+              <AlertCircle className="w-4 h-4 text-yellow-400" />
+              <span className="text-yellow-400">Not enabled</span>
+            </>
+          )}
+        </div>
+
+        {recoveryCodes.length > 0 && (
+          <div className="bg-yellow-500/10 border border-yellow-500/30 rounded-lg p-4">
+            <p className="text-sm text-yellow-300 mb-2">
+              Save these recovery codes somewhere safe. Each one signs you in once if you lose your device. They won't be shown again.
+            </p>
+            <div className="grid grid-cols-2 gap-1 font-mono text-sm text-white">
+              {recoveryCodes.map((c) => <span key={c}>{c}</span>)}
+            </div>
+          </div>
+        )}
+
+        {!enabled && !setup && (
+          <button
+            onClick={startSetup}
+            className="flex items-center gap-2 px-4 py-2 bg-red-600 text-white rounded-lg hover:bg-red-700 transition-colors"
+          >
+            <KeyRound className="w-4 h-4" />
+            Set Up Authenticator App
+          </button>
+        )}
+
+        {!enabled && setup && (
+          <div className="space-y-3">
+            <p className="text-sm text-slate-400">
+              Add this key to your authenticator app (or open the link on your phone), then enter the 6-digit code it shows.
+            </p>
+            <div className="font-mono text-sm text-white break-all bg-[#2a2a2a] rounded-lg p-3">{setup.secret}</div>
+            <a href={setup.otpauth_url} className="text-sm text-blue-400 hover:underline">Open in authenticator app</a>
+            <input
+              type="text"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              className={inputClass}
+              placeholder="6-digit code"
+              autoComplete="one-time-code"
+            />
+            <button
+              onClick={confirmSetup}
+              className="flex items-center gap-2 px-4 py-2 bg-red-600 text-white rounded-lg hover:bg-red-700 transition-colors"
+            >
+              <CheckCircle className="w-4 h-4" />
+              Enable
+            </button>
+          </div>
+        )}
+
+        {enabled && (
+          <div className="space-y-3">
+            <input
+              type="password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              className={inputClass}
+              placeholder="Current password"
+            />
+            <input
+              type="text"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              className={inputClass}
+              placeholder="Authenticator or recovery code"
+              autoComplete="one-time-code"
+            />
+            <div className="flex gap-2">
+              <button
+                onClick={regenerate}
+                className="px-4 py-2 bg-[#2a2a2a] text-white rounded-lg hover:bg-[#333] transition-colors"
+              >
+                New Recovery Codes
+              </button>
+              <button
+                onClick={disable}
+                className="px-4 py-2 bg-red-600 text-white rounded-lg hover:bg-red-700 transition-colors"
+              >
+                Disable
+              </button>
+            </div>
+          </div>
+        )}
+      </div>
+
+      {history.length > 0 && (
+        <div className="mt-6">
+          <h4 className="text-sm font-medium text-slate-300 mb-2">Recent Sign-ins</h4>
+          <div className="space-y-1 text-sm">
+            {history.map((a) => (
+              <div key={a.id} className="flex justify-between gap-4">
+                <span className={a.success ? (a.suspicious ? 'text-yellow-400' : 'text-slate-300') : 'text-red-400'}>
+                  {a.success ? (a.suspicious ? 'Unusual sign-in' : 'Signed in') : `Failed (${a.failure_reason?.replace('_', ' ')})`}
+                  {' · '}{a.ip}
+                </span>
+                <span className="text-slate-500">{new Date(a.created_at).toLocaleString()}</span>
+              </div>
+            ))}
+          </div>
+        </div>
+      )}
+    </div>
+  );
+}
@@ -1,6 +1,7 @@
 import { useState, useEffect } from 'react';
 import { Database, Clock, TrendingUp, XCircle, CheckCircle, RefreshCw, Film, Calendar } from 'lucide-react';
 import axios from 'axios';
+import { withSession } from '../services/session';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || '/api/v1';
 
@@ -11,16 +12,7 @@ const api = axios.create({
   },
 });
 
-api.interceptors.request.use(
-  (config) => {
-    const token = localStorage.getItem('auth_token');
-    if (token) {
-      config.headers.Authorization = `Bearer ${token}`;
-    }
-    return config;
-  },
-  (error) => Promise.reject(error)
-);
+withSession(api);
 
 interface CacheStats {
   total_cached: number;
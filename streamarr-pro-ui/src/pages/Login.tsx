@@ -1,6 +1,7 @@
 import { useState, useEffect, type FormEvent } from 'react';
 import { useNavigate } from 'react-router-dom';
-import { Play, Lock, User, Shield } from 'lucide-react';
+import { Play, Lock, User, Shield, KeyRound, LogIn } from 'lucide-react';
+import { storeSession, startSSOSession } from '../services/session';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || '/api/v1';
 
@@ -13,18 +14,47 @@ export default function Login() {
   const [checkingStatus, setCheckingStatus] = useState(true);
   const [setupRequired, setSetupRequired] = useState(false);
   const [error, setError] = useState('');
+  // Set when the account has two-factor authentication and the password was accepted
+  const [challengeToken, setChallengeToken] = useState('');
+  const [code, setCode] = useState('');
+  // Name of the OpenID Connect provider when single sign-on is enabled
+  const [ssoProvider, setSsoProvider] = useState('');
   const navigate = useNavigate();
 
   useEffect(() => {
+    finishSingleSignOn();
     checkAuthStatus();
   }, []);
 
+  // The single sign-on callback redirects here with the session or an error in the URL fragment
+  const finishSingleSignOn = async () => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const token = params.get('sso_token');
+    const ssoError = params.get('sso_error');
+    if (!token && !ssoError) {
+      return;
+    }
+    window.history.replaceState(null, '', window.location.pathname);
+    if (ssoError) {
+      setError(ssoError);
+      return;
+    }
+    if (token && await startSSOSession(token)) {
+      navigate('/');
+    } else {
+      setError('Single sign-on failed, try again');
+    }
+  };
+
   const checkAuthStatus = async () => {
     try {
       const response = await fetch(`${API_BASE_URL}/auth/status`);
       if (response.ok) {
         const data = await response.json();
         setSetupRequired(data.setup_required);
+        if (data.oidc_enabled) {
+          setSsoProvider(data.oidc_provider_name || 'SSO');
+        }
       }
     } catch {
       // If status check fails, assume login is required
@@ -74,9 +104,7 @@ export default function Login() {
       }
 
       const data = await loginResponse.json();
-      localStorage.setItem('auth_token', data.token);
-      localStorage.setItem('username', data.username);
-      localStorage.setItem('is_admin', data.is_admin);
+      storeSession(data);
       navigate('/');
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Setup failed');
@@ -103,11 +131,14 @@ export default function Login() {
       }
 
       const data = await response.json();
-      
+
+      if (data.two_factor_required) {
+        setChallengeToken(data.challenge_token);
+        return;
+      }
+
       // Store token
-      localStorage.setItem('auth_token', data.token);
-      localStorage.setItem('username', data.username);
-      localStorage.setItem('is_admin', data.is_admin);
+      storeSession(data);
 
       // Redirect to dashboard
       navigate('/');
@@ -118,6 +149,37 @@ export default function Login() {
     }
   };
 
+  const handleTwoFactor = async (e: FormEvent) => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+
+    try {
+      const response = await fetch(`${API_BASE_URL}/auth/login/2fa`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ challenge_token: challengeToken, code }),
+      });
+
+      if (!response.ok) {
+        const data = await response.json().catch(() => ({}));
+        if (response.status === 401 && data.error !== 'invalid code') {
+          // The challenge expired; start over with the password
+          setChallengeToken('');
+          setCode('');
+        }
+        throw new Error(data.error || 'Verification failed');
+      }
+
+      storeSession(await response.json());
+      navigate('/');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Verification failed');
+    } finally {
+      setLoading(false);
+    }
+  };
+
   if (checkingStatus) {
     return (
       <div className="min-h-screen flex items-center justify-center bg-[#141414]">
@@ -160,7 +222,7 @@ export default function Login() {
             </div>
           )}
 
-          <form onSubmit={setupRequired ? handleSetup : handleLogin} className="space-y-6">
+          <form onSubmit={setupRequired ? handleSetup : challengeToken ? handleTwoFactor : handleLogin} className="space-y-6">
             {error && (
               <div className="bg-red-500/10 border border-red-500/30 text-red-400 px-4 py-3 rounded-lg text-sm flex items-center gap-2">
                 <div className="w-2 h-2 bg-red-500 rounded-full animate-pulse" />
@@ -168,6 +230,33 @@ export default function Login() {
               </div>
             )}
 
+            {challengeToken ? (
+              <div>
+                <label htmlFor="code" className="block text-sm font-medium text-slate-300 mb-2">
+                  Authentication code
+                </label>
+                <div className="relative group">
+                  <div className="absolute inset-y-0 left-0 pl-4 flex items-center pointer-events-none">
+                    <KeyRound className="h-5 w-5 text-slate-500 group-focus-within:text-red-500 transition-colors" />
+                  </div>
+                  <input
+                    id="code"
+                    type="text"
+                    required
+                    autoFocus
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    className="block w-full pl-12 pr-4 py-4 border border-white/10 rounded-xl bg-[#2a2a2a] text-white placeholder-slate-500 focus:outline-none focus:ring-2 focus:ring-red-500/50 focus:border-red-500/50 transition-all tracking-widest"
+                    placeholder="6-digit code or recovery code"
+                    autoComplete="one-time-code"
+                    inputMode="text"
+                  />
+                </div>
+                <p className="mt-2 text-xs text-slate-500">
+                  Enter the code from your authenticator app, or one of your recovery codes.
+                </p>
+              </div>
+            ) : (
             <div className="space-y-5">
               <div>
                 <label htmlFor="username" className="block text-sm font-medium text-slate-300 mb-2">
@@ -234,8 +323,9 @@ export default function Login() {
                 </div>
               )}
             </div>
+            )}
 
-            {!setupRequired && (
+            {!setupRequired && !challengeToken && (
               <div className="flex items-center">
                 <input
                   id="remember-me"
@@ -261,7 +351,7 @@ export default function Login() {
                     <circle className="opacity-25" cx="12" cy="12" r="10" stroke="currentColor" strokeWidth="4"></circle>
                     <path className="opacity-75" fill="currentColor" d="M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z"></path>
                   </svg>
-                  {setupRequired ? 'Creating account...' : 'Signing in...'}
+                  {setupRequired ? 'Creating account...' : challengeToken ? 'Verifying...' : 'Signing in...'}
                 </span>
               ) : (
                 <>
@@ -270,6 +360,11 @@ export default function Login() {
                       <Shield className="w-5 h-5 mr-2" />
                       Create Admin Account
                     </>
+                  ) : challengeToken ? (
+                    <>
+                      <KeyRound className="w-5 h-5 mr-2" />
+                      Verify
+                    </>
                   ) : (
                     <>
                       <Play className="w-5 h-5 mr-2 fill-white" />
@@ -280,6 +375,23 @@ export default function Login() {
               )}
             </button>
           </form>
+
+          {ssoProvider && !setupRequired && !challengeToken && (
+            <div className="mt-6">
+              <div className="flex items-center gap-3 text-xs text-slate-500 mb-6">
+                <div className="flex-1 h-px bg-white/10" />
+                or
+                <div className="flex-1 h-px bg-white/10" />
+              </div>
+              <a
+                href={`${API_BASE_URL}/auth/oidc/login`}
+                className="w-full flex justify-center items-center py-4 px-6 rounded-xl text-base font-bold text-white bg-[#2a2a2a] border border-white/10 hover:bg-[#333] transition-all"
+              >
+                <LogIn className="w-5 h-5 mr-2" />
+                Sign in with {ssoProvider}
+              </a>
+            </div>
+          )}
         </div>
 
         {/* Footer */}
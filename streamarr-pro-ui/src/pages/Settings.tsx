@@ -1,6 +1,8 @@
 import { useState, useEffect } from 'react';
 import { Save, Layers, Settings as SettingsIcon, Code, Plus, X, Tv, Activity, Play, Clock, RefreshCw, Filter, Database, Trash2, Info, Github, Download, ExternalLink, CheckCircle, AlertCircle, Film, User, Camera, Loader, Search } from 'lucide-react';
 import axios from 'axios';
+import { withSession } from '../services/session';
+import TwoFactorSettings from '../components/TwoFactorSettings';
 
 // v1.2.1 - Added manual IP configuration
 const API_BASE_URL = import.meta.env.VITE_API_URL || '/api/v1';
@@ -13,17 +15,8 @@ const api = axios.create({
   },
 });
 
-// Add auth token to all requests
-api.interceptors.request.use(
-  (config) => {
-    const token = localStorage.getItem('auth_token');
-    if (token) {
-      config.headers.Authorization = `Bearer ${token}`;
-    }
-    return config;
-  },
-  (error) => Promise.reject(error)
-);
+// Add auth token to all requests, renewing it when it expires
+withSession(api);
 
 interface SettingsData {
   tmdb_api_key: string;
@@ -121,6 +114,7 @@ interface M3USource {
 }
 
 interface XtreamSource {
+  id?: string; // Assigned by the server on save
   name: string;
   server_url: string;
   username: string;
@@ -1348,13 +1342,15 @@ export default function Settings() {
                 </div>
               </div>
 
+              <TwoFactorSettings />
+
               {/* Account Info Section */}
               <div className="pt-6 border-t border-white/10">
                 <h3 className="text-md font-medium text-slate-300 mb-4">Account Information</h3>
                 <div className="space-y-2 text-sm">
                   <div className="flex justify-between">
                     <span className="text-slate-400">Role:</span>
-                    <span className="text-slate-300">{localStorage.getItem('is_admin') === 'true' ? 'Administrator' : 'User'}</span>
+                    <span className="text-slate-300 capitalize">{localStorage.getItem('role') || (localStorage.getItem('is_admin') === 'true' ? 'admin' : 'requester')}</span>
                   </div>
                   <div className="flex justify-between">
                     <span className="text-slate-400">Account Status:</span>
@@ -1731,7 +1727,7 @@ export default function Settings() {
                           Generate Token
                         </button>
                       </div>
-                      <p className="text-xs text-slate-500 mt-1">Secure token for addon authentication. Keep this private!</p>
+                      <p className="text-xs text-slate-500 mt-1">Secure token for addon authentication. Keep this private! It is only shown when generated; use Copy Manifest URL to install the addon.</p>
                     </div>
 
                     {/* Catalog Configuration */}
@@ -3250,16 +3246,19 @@ export default function Settings() {
                             readOnly
                             className="flex-1 p-3 bg-gray-700 border border-gray-600 rounded-lg text-white font-mono text-sm"
                           />
-                          <button
-                            onClick={() => {
-                              navigator.clipboard.writeText(settings?.xtream_password || 'streamarr');
-                              setMessage('Password copied to clipboard');
-                              setTimeout(() => setMessage(''), 2000);
-                            }}
-                            className="px-3 py-2 bg-red-600 text-white rounded-lg hover:bg-red-700"
-                          >
-                            Copy
-                          </button>
+                          {/* A saved password is only sent back masked */}
+                          {settings?.xtream_password !== '********' && (
+                            <button
+                              onClick={() => {
+                                navigator.clipboard.writeText(settings?.xtream_password || 'streamarr');
+                                setMessage('Password copied to clipboard');
+                                setTimeout(() => setMessage(''), 2000);
+                              }}
+                              className="px-3 py-2 bg-red-600 text-white rounded-lg hover:bg-red-700"
+                            >
+                              Copy
+                            </button>
+                          )}
                         </div>
                       </div>
                     </div>
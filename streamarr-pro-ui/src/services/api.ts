@@ -1,4 +1,5 @@
 import axios from 'axios';
+import { clearSession, refreshSession } from './session';
 import type { Movie, Series, AddMovieRequest, SearchResult, Stream, Episode, Channel, EPGProgram, CalendarEntry, TVGuideResponse, Video } from '../types';
 
 // Use relative URL so Vite proxy can intercept /api requests in development
@@ -27,18 +28,26 @@ api.interceptors.request.use(
   }
 );
 
-// Handle 401 responses (redirect to login)
+// Handle 401 responses (renew the session, otherwise redirect to login)
 api.interceptors.response.use(
   (response) => {
     console.log('API Response:', response.config.url, response.status, response.data);
     return response;
   },
-  (error) => {
+  async (error) => {
     console.error('API Response Error:', error.config?.url, error.response?.status, error.message);
     if (error.response?.status === 401) {
-      localStorage.removeItem('auth_token');
-      localStorage.removeItem('username');
-      localStorage.removeItem('is_admin');
+      // Renew the expired access token once and retry before sending the user to the login page
+      const original = error.config;
+      if (original && !original._retried) {
+        original._retried = true;
+        const token = await refreshSession();
+        if (token) {
+          original.headers.Authorization = `Bearer ${token}`;
+          return api(original);
+        }
+      }
+      clearSession();
       window.location.href = '/login';
     }
     return Promise.reject(error);
@@ -200,6 +209,25 @@ export const streamarrApi = {
   
   clearBlacklist: () =>
     api.post('/blacklist/clear'),
+
+  // Two-factor authentication and sign-in history
+  getTwoFactorStatus: () =>
+    api.get('/auth/2fa'),
+
+  setupTwoFactor: () =>
+    api.post('/auth/2fa/setup'),
+
+  enableTwoFactor: (code: string) =>
+    api.post('/auth/2fa/enable', { code }),
+
+  disableTwoFactor: (password: string, code: string) =>
+    api.post('/auth/2fa/disable', { password, code }),
+
+  regenerateRecoveryCodes: (password: string, code: string) =>
+    api.post('/auth/2fa/recovery-codes', { password, code }),
+
+  getLoginHistory: (limit = 20) =>
+    api.get('/auth/login-history', { params: { limit } }),
 };
 
 // Trending item type from TMDB
@@ -0,0 +1,127 @@
+import type { AxiosInstance, InternalAxiosRequestConfig } from 'axios';
+
+const API_BASE_URL = import.meta.env.VITE_API_URL || '/api/v1';
+
+interface LoginResponse {
+  token: string;
+  refresh_token: string;
+  username: string;
+  is_admin: boolean;
+  role: string;
+}
+
+// Access tokens last 15 minutes; the refresh token renews them
+export function storeSession(data: LoginResponse) {
+  localStorage.setItem('auth_token', data.token);
+  localStorage.setItem('refresh_token', data.refresh_token);
+  localStorage.setItem('username', data.username);
+  localStorage.setItem('is_admin', String(data.is_admin));
+  localStorage.setItem('role', data.role);
+}
+
+export function clearSession() {
+  localStorage.removeItem('auth_token');
+  localStorage.removeItem('refresh_token');
+  localStorage.removeItem('username');
+  localStorage.removeItem('is_admin');
+  localStorage.removeItem('role');
+}
+
+// Ends the session on the server too, so its refresh token stops working
+export async function logout() {
+  const refreshToken = localStorage.getItem('refresh_token');
+  clearSession();
+  if (refreshToken) {
+    await fetch(`${API_BASE_URL}/auth/logout`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    }).catch(() => undefined);
+  }
+}
+
+// Behind an authenticating reverse proxy the user is already signed in; ask the server for a session
+export async function startProxySession(): Promise<boolean> {
+  try {
+    const response = await fetch(`${API_BASE_URL}/auth/proxy/session`, { method: 'POST' });
+    if (!response.ok) {
+      return false;
+    }
+    storeSession(await response.json());
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+// Exchanges the single-use refresh token from a single sign-on redirect for a session
+export async function startSSOSession(refreshToken: string): Promise<boolean> {
+  localStorage.setItem('refresh_token', refreshToken);
+  return (await refreshSession()) !== null;
+}
+
+let refreshing: Promise<string | null> | null = null;
+
+// Renews the access token; concurrent callers share one request because refresh tokens are single-use
+export function refreshSession(): Promise<string | null> {
+  if (!refreshing) {
+    refreshing = doRefresh().finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+}
+
+async function doRefresh(): Promise<string | null> {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    return null;
+  }
+  try {
+    const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+    if (!response.ok) {
+      // Another tab may have renewed the session in the meantime
+      const latest = localStorage.getItem('refresh_token');
+      return latest && latest !== refreshToken ? localStorage.getItem('auth_token') : null;
+    }
+    const data: LoginResponse = await response.json();
+    storeSession(data);
+    return data.token;
+  } catch {
+    return null;
+  }
+}
+
+// Adds the access token to an axios instance's requests and retries once with a renewed token on 401
+export function withSession(instance: AxiosInstance) {
+  instance.interceptors.request.use(
+    (config) => {
+      const token = localStorage.getItem('auth_token');
+      if (token) {
+        config.headers.Authorization = `Bearer ${token}`;
+      }
+      return config;
+    },
+    (error) => Promise.reject(error)
+  );
+
+  instance.interceptors.response.use(
+    (response) => response,
+    async (error) => {
+      const original = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined;
+      if (error.response?.status === 401 && original && !original._retried) {
+        original._retried = true;
+        const token = await refreshSession();
+        if (token) {
+          original.headers.Authorization = `Bearer ${token}`;
+          return instance(original);
+        }
+      }
+      return Promise.reject(error);
+    }
+  );
+}